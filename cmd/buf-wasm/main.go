@@ -0,0 +1,45 @@
+//go:build js && wasm
+// +build js,wasm
+
+// Command buf-wasm is the buf WebAssembly module.
+//
+// Build with GOOS=js GOARCH=wasm and load with the wasm_exec.js of the Go
+// distribution. The module sets the global function bufLint, which takes a JSON
+// request as a string and returns a Promise of the JSON response as a string.
+// See the bufwasm package for the request and response formats.
+package main
+
+import (
+	"syscall/js"
+
+	"github.com/bufbuild/buf/internal/buf/cmd/bufwasm"
+)
+
+func main() {
+	js.Global().Set("bufLint", newPromiseFunc(bufwasm.Lint))
+	// the functions must be available for the lifetime of the page
+	select {}
+}
+
+// newPromiseFunc returns a JavaScript function that calls f with its string
+// argument in a new goroutine and returns a Promise of the string result.
+//
+// The work is done in a new goroutine so that the JavaScript event loop is not blocked.
+func newPromiseFunc(f func([]byte) []byte) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		var request string
+		if len(args) > 0 {
+			request = args[0].String()
+		}
+		var executor js.Func
+		executor = js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
+			executor.Release()
+			resolve := promiseArgs[0]
+			go func() {
+				resolve.Invoke(string(f([]byte(request))))
+			}()
+			return nil
+		})
+		return js.Global().Get("Promise").New(executor)
+	})
+}
@@ -0,0 +1,45 @@
+// Command libbuf is the buf shared library.
+//
+// Build with go build -buildmode=c-shared. Each exported function takes a JSON
+// request as a C string and returns a JSON response as a C string, which must be
+// freed with BufFree. See the libbuf package for the request and response formats.
+package main
+
+// #include <stdlib.h>
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/bufbuild/buf/internal/buf/cmd/libbuf"
+)
+
+// BufBuild builds an image.
+//
+//export BufBuild
+func BufBuild(request *C.char) *C.char {
+	return C.CString(string(libbuf.Build([]byte(C.GoString(request)))))
+}
+
+// BufLint runs the lint checks.
+//
+//export BufLint
+func BufLint(request *C.char) *C.char {
+	return C.CString(string(libbuf.Lint([]byte(C.GoString(request)))))
+}
+
+// BufBreaking runs the breaking change checks.
+//
+//export BufBreaking
+func BufBreaking(request *C.char) *C.char {
+	return C.CString(string(libbuf.Breaking([]byte(C.GoString(request)))))
+}
+
+// BufFree frees a response returned by the other functions.
+//
+//export BufFree
+func BufFree(response *C.char) {
+	C.free(unsafe.Pointer(response))
+}
+
+func main() {}
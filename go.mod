@@ -3,9 +3,11 @@ module github.com/bufbuild/buf
 go 1.13
 
 require (
+	github.com/aws/aws-sdk-go v1.29.0
 	github.com/bufbuild/cli v0.0.0-20200130190020-2009ccb4e7a8
 	github.com/golang/protobuf v1.3.3
 	github.com/jhump/protoreflect v1.6.0
+	github.com/klauspost/compress v1.10.3
 	github.com/sergi/go-diff v1.1.0 // indirect
 	github.com/spf13/cobra v0.0.5
 	github.com/spf13/pflag v1.0.5
@@ -13,7 +15,8 @@ require (
 	go.uber.org/multierr v1.4.0
 	go.uber.org/zap v1.13.0
 	golang.org/x/crypto v0.0.0-20200128174031-69ecbb4d6d5d
-	golang.org/x/net v0.0.0-20200114155413-6afb5195e5aa // indirect
+	golang.org/x/net v0.0.0-20200202094626-16171245cfb2
+	golang.org/x/oauth2 v0.0.0-20200107190931-bf48bf16ab8d
 	golang.org/x/sys v0.0.0-20200124204421-9fbb57f87de9 // indirect
 	golang.org/x/tools v0.0.0-20200131211209-ecb101ed6550 // indirect
 	google.golang.org/genproto v0.0.0-20200128133413-58ce757ed39b // indirect
@@ -0,0 +1,224 @@
+// Package bufassert runs the assertions of the tests section of the config against images.
+package bufassert
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bufbuild/buf/internal/buf/ext/extimage"
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// ExternalAssertion is an external assertion.
+//
+// Names in the contains fields are relative to the package if the package is set,
+// and are fully-qualified otherwise. Nested names such as Foo.Bar are allowed.
+//
+// Should only be used outside this package for testing.
+type ExternalAssertion struct {
+	// Name is the name of the assertion.
+	//
+	// Required.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Package limits the assertion to the files in the package.
+	//
+	// If not set, the assertion applies to all files.
+	Package          string   `json:"package,omitempty" yaml:"package,omitempty"`
+	ContainsServices []string `json:"contains_services,omitempty" yaml:"contains_services,omitempty"`
+	ContainsMessages []string `json:"contains_messages,omitempty" yaml:"contains_messages,omitempty"`
+	ContainsEnums    []string `json:"contains_enums,omitempty" yaml:"contains_enums,omitempty"`
+	MaxMessageFields int      `json:"max_message_fields,omitempty" yaml:"max_message_fields,omitempty"`
+	MaxServiceRPCs   int      `json:"max_service_rpcs,omitempty" yaml:"max_service_rpcs,omitempty"`
+	MaxEnumValues    int      `json:"max_enum_values,omitempty" yaml:"max_enum_values,omitempty"`
+}
+
+// Assertion is a validated assertion.
+type Assertion struct {
+	external ExternalAssertion
+}
+
+// Name returns the name of the assertion.
+func (a *Assertion) Name() string {
+	return a.external.Name
+}
+
+// NewAssertions validates the external assertions and returns the assertions.
+//
+// Every assertion must have a unique name and at least one check.
+func NewAssertions(externalAssertions []ExternalAssertion) ([]*Assertion, error) {
+	assertions := make([]*Assertion, 0, len(externalAssertions))
+	seenNames := make(map[string]struct{}, len(externalAssertions))
+	for _, externalAssertion := range externalAssertions {
+		if externalAssertion.Name == "" {
+			return nil, errors.New("tests: name must be set")
+		}
+		if _, ok := seenNames[externalAssertion.Name]; ok {
+			return nil, fmt.Errorf("tests: duplicate name %q", externalAssertion.Name)
+		}
+		seenNames[externalAssertion.Name] = struct{}{}
+		if externalAssertion.MaxMessageFields < 0 || externalAssertion.MaxServiceRPCs < 0 || externalAssertion.MaxEnumValues < 0 {
+			return nil, fmt.Errorf("tests: %q: maximums must not be negative", externalAssertion.Name)
+		}
+		if len(externalAssertion.ContainsServices) == 0 &&
+			len(externalAssertion.ContainsMessages) == 0 &&
+			len(externalAssertion.ContainsEnums) == 0 &&
+			externalAssertion.MaxMessageFields == 0 &&
+			externalAssertion.MaxServiceRPCs == 0 &&
+			externalAssertion.MaxEnumValues == 0 {
+			return nil, fmt.Errorf("tests: %q: at least one check must be set", externalAssertion.Name)
+		}
+		assertions = append(assertions, &Assertion{external: externalAssertion})
+	}
+	return assertions, nil
+}
+
+// Result is the result of an assertion.
+type Result struct {
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Failures are the reasons the assertion failed.
+	//
+	// The assertion passed if there are no failures.
+	Failures []string `json:"failures,omitempty" yaml:"failures,omitempty"`
+}
+
+// Passed returns true if the assertion passed.
+func (r *Result) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// Run runs the assertions against the image, returning a Result for each
+// assertion in order.
+//
+// Imports are not checked.
+func Run(assertions []*Assertion, image *imagev1beta1.Image) ([]*Result, error) {
+	image, err := extimage.ImageWithoutImports(image)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*Result, 0, len(assertions))
+	for _, assertion := range assertions {
+		results = append(results, runAssertion(assertion.external, image.GetFile()))
+	}
+	return results, nil
+}
+
+func runAssertion(externalAssertion ExternalAssertion, files []*descriptor.FileDescriptorProto) *Result {
+	result := &Result{
+		Name: externalAssertion.Name,
+	}
+	addFailure := func(format string, args ...interface{}) {
+		result.Failures = append(result.Failures, fmt.Sprintf(format, args...))
+	}
+	symbols := newSymbols()
+	packageDefined := false
+	for _, file := range files {
+		if externalAssertion.Package != "" && file.GetPackage() != externalAssertion.Package {
+			continue
+		}
+		packageDefined = true
+		symbols.addFile(file)
+	}
+	if externalAssertion.Package != "" && !packageDefined {
+		addFailure("Package %q is not defined.", externalAssertion.Package)
+		return result
+	}
+	for _, name := range externalAssertion.ContainsServices {
+		if fullName := getFullName(externalAssertion.Package, name); symbols.services[fullName] == nil {
+			addFailure("Service %q is not defined.", fullName)
+		}
+	}
+	for _, name := range externalAssertion.ContainsMessages {
+		if fullName := getFullName(externalAssertion.Package, name); symbols.messages[fullName] == nil {
+			addFailure("Message %q is not defined.", fullName)
+		}
+	}
+	for _, name := range externalAssertion.ContainsEnums {
+		if fullName := getFullName(externalAssertion.Package, name); symbols.enums[fullName] == nil {
+			addFailure("Enum %q is not defined.", fullName)
+		}
+	}
+	if max := externalAssertion.MaxMessageFields; max > 0 {
+		for _, fullName := range symbols.messageFullNames {
+			if numFields := len(symbols.messages[fullName].GetField()); numFields > max {
+				addFailure("Message %q has %d fields, which is more than the maximum of %d.", fullName, numFields, max)
+			}
+		}
+	}
+	if max := externalAssertion.MaxServiceRPCs; max > 0 {
+		for _, fullName := range symbols.serviceFullNames {
+			if numRPCs := len(symbols.services[fullName].GetMethod()); numRPCs > max {
+				addFailure("Service %q has %d RPCs, which is more than the maximum of %d.", fullName, numRPCs, max)
+			}
+		}
+	}
+	if max := externalAssertion.MaxEnumValues; max > 0 {
+		for _, fullName := range symbols.enumFullNames {
+			if numValues := len(symbols.enums[fullName].GetValue()); numValues > max {
+				addFailure("Enum %q has %d values, which is more than the maximum of %d.", fullName, numValues, max)
+			}
+		}
+	}
+	return result
+}
+
+// symbols are the services, messages, and enums of files by full name.
+//
+// The full names are kept in the order of the files so that failures are deterministic.
+type symbols struct {
+	services         map[string]*descriptor.ServiceDescriptorProto
+	serviceFullNames []string
+	messages         map[string]*descriptor.DescriptorProto
+	messageFullNames []string
+	enums            map[string]*descriptor.EnumDescriptorProto
+	enumFullNames    []string
+}
+
+func newSymbols() *symbols {
+	return &symbols{
+		services: make(map[string]*descriptor.ServiceDescriptorProto),
+		messages: make(map[string]*descriptor.DescriptorProto),
+		enums:    make(map[string]*descriptor.EnumDescriptorProto),
+	}
+}
+
+func (s *symbols) addFile(file *descriptor.FileDescriptorProto) {
+	for _, service := range file.GetService() {
+		fullName := getFullName(file.GetPackage(), service.GetName())
+		s.services[fullName] = service
+		s.serviceFullNames = append(s.serviceFullNames, fullName)
+	}
+	for _, message := range file.GetMessageType() {
+		s.addMessage(getFullName(file.GetPackage(), message.GetName()), message)
+	}
+	for _, enum := range file.GetEnumType() {
+		s.addEnum(getFullName(file.GetPackage(), enum.GetName()), enum)
+	}
+}
+
+func (s *symbols) addMessage(fullName string, message *descriptor.DescriptorProto) {
+	if message.GetOptions().GetMapEntry() {
+		// map entries are generated and not part of the schema as written
+		return
+	}
+	s.messages[fullName] = message
+	s.messageFullNames = append(s.messageFullNames, fullName)
+	for _, nestedMessage := range message.GetNestedType() {
+		s.addMessage(getFullName(fullName, nestedMessage.GetName()), nestedMessage)
+	}
+	for _, enum := range message.GetEnumType() {
+		s.addEnum(getFullName(fullName, enum.GetName()), enum)
+	}
+}
+
+func (s *symbols) addEnum(fullName string, enum *descriptor.EnumDescriptorProto) {
+	s.enums[fullName] = enum
+	s.enumFullNames = append(s.enumFullNames, fullName)
+}
+
+func getFullName(scope string, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
@@ -0,0 +1,155 @@
+package bufassert
+
+import (
+	"testing"
+
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAssertionsErrors(t *testing.T) {
+	t.Parallel()
+	for _, externalAssertions := range [][]ExternalAssertion{
+		{{ContainsServices: []string{"Foo"}}},
+		{{Name: "a", ContainsServices: []string{"Foo"}}, {Name: "a", MaxEnumValues: 1}},
+		{{Name: "a"}},
+		{{Name: "a", Package: "foo.v1"}},
+		{{Name: "a", MaxMessageFields: -1}},
+	} {
+		_, err := NewAssertions(externalAssertions)
+		assert.Error(t, err, externalAssertions)
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+	image := &imagev1beta1.Image{
+		File: []*descriptor.FileDescriptorProto{
+			{
+				Name:    proto.String("foo/v1/foo.proto"),
+				Package: proto.String("foo.v1"),
+				MessageType: []*descriptor.DescriptorProto{
+					{
+						Name: proto.String("Foo"),
+						Field: []*descriptor.FieldDescriptorProto{
+							{Name: proto.String("one")},
+							{Name: proto.String("two")},
+							{Name: proto.String("three")},
+						},
+						NestedType: []*descriptor.DescriptorProto{
+							{
+								Name: proto.String("Bar"),
+								Field: []*descriptor.FieldDescriptorProto{
+									{Name: proto.String("one")},
+								},
+							},
+							{
+								Name: proto.String("ThreeEntry"),
+								Field: []*descriptor.FieldDescriptorProto{
+									{Name: proto.String("key")},
+									{Name: proto.String("value")},
+									{Name: proto.String("extra")},
+								},
+								Options: &descriptor.MessageOptions{
+									MapEntry: proto.Bool(true),
+								},
+							},
+						},
+						EnumType: []*descriptor.EnumDescriptorProto{
+							{
+								Name: proto.String("Kind"),
+								Value: []*descriptor.EnumValueDescriptorProto{
+									{Name: proto.String("KIND_UNSPECIFIED")},
+									{Name: proto.String("KIND_ONE")},
+								},
+							},
+						},
+					},
+				},
+				Service: []*descriptor.ServiceDescriptorProto{
+					{
+						Name: proto.String("FooService"),
+						Method: []*descriptor.MethodDescriptorProto{
+							{Name: proto.String("GetFoo")},
+							{Name: proto.String("ListFoos")},
+						},
+					},
+				},
+			},
+			{
+				Name:    proto.String("bar/v1/bar.proto"),
+				Package: proto.String("bar.v1"),
+				MessageType: []*descriptor.DescriptorProto{
+					{
+						Name: proto.String("Bar"),
+					},
+				},
+			},
+		},
+	}
+	assertions, err := NewAssertions(
+		[]ExternalAssertion{
+			{
+				Name:             "contains",
+				Package:          "foo.v1",
+				ContainsServices: []string{"FooService"},
+				ContainsMessages: []string{"Foo", "Foo.Bar", "Bar"},
+				ContainsEnums:    []string{"Foo.Kind", "Kind"},
+			},
+			{
+				Name:             "max",
+				MaxMessageFields: 2,
+				MaxServiceRPCs:   1,
+				MaxEnumValues:    1,
+			},
+			{
+				Name:             "bar",
+				Package:          "bar.v1",
+				MaxMessageFields: 2,
+			},
+			{
+				Name:             "missing",
+				Package:          "baz.v1",
+				ContainsMessages: []string{"Baz"},
+			},
+		},
+	)
+	require.NoError(t, err)
+	results, err := Run(assertions, image)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]*Result{
+			{
+				Name: "contains",
+				Failures: []string{
+					`Message "foo.v1.Bar" is not defined.`,
+					`Enum "foo.v1.Kind" is not defined.`,
+				},
+			},
+			{
+				Name: "max",
+				Failures: []string{
+					`Message "foo.v1.Foo" has 3 fields, which is more than the maximum of 2.`,
+					`Service "foo.v1.FooService" has 2 RPCs, which is more than the maximum of 1.`,
+					`Enum "foo.v1.Foo.Kind" has 2 values, which is more than the maximum of 1.`,
+				},
+			},
+			{
+				Name: "bar",
+			},
+			{
+				Name: "missing",
+				Failures: []string{
+					`Package "baz.v1" is not defined.`,
+				},
+			},
+		},
+		results,
+	)
+	assert.True(t, results[2].Passed())
+	assert.False(t, results[3].Passed())
+}
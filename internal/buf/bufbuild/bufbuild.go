@@ -99,6 +99,17 @@ type BuildOptions struct {
 	//
 	// If the bucket is already a memory bucket, this will result in a no-op.
 	CopyToMemory bool
+	// DependencyImages are precompiled images used only to resolve imports
+	// that cannot be found within the roots.
+	//
+	// Files within these images are not compiled or checked.
+	DependencyImages []*imagev1beta1.Image
+	// DependencyBuckets are buckets used only to resolve imports that cannot be
+	// found within the roots or the dependency images.
+	//
+	// Only files imported from these buckets are compiled, and they are never checked.
+	// The paths within these buckets are used as the import paths.
+	DependencyBuckets []storage.ReadBucket
 }
 
 // FilesOptions are options for Files.
@@ -142,6 +153,22 @@ func NewHandler(logger *zap.Logger) Handler {
 	return newHandler(logger)
 }
 
+// MarshalProtoFileSetJSON marshals the ProtoFileSet to JSON.
+//
+// The result contains the roots and the mapping from root file path to real file path,
+// and can be read back with UnmarshalProtoFileSetJSON to skip walking the bucket.
+func MarshalProtoFileSetJSON(protoFileSet ProtoFileSet) ([]byte, error) {
+	return marshalProtoFileSetJSON(protoFileSet)
+}
+
+// UnmarshalProtoFileSetJSON unmarshals a ProtoFileSet produced by MarshalProtoFileSetJSON.
+//
+// The paths are normalized and validated, and every real file path must be within
+// exactly one root. The files are not checked for existence.
+func UnmarshalProtoFileSetJSON(data []byte) (ProtoFileSet, error) {
+	return unmarshalProtoFileSetJSON(data)
+}
+
 // FixFileAnnotationPaths attempts to make all paths into real file paths.
 //
 // If the resolver is nil, this does nothing.
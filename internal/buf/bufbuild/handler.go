@@ -56,6 +56,8 @@ func (h *handler) Build(
 		protoFileSet,
 		options.IncludeImports,
 		options.IncludeSourceInfo,
+		options.DependencyImages,
+		options.DependencyBuckets,
 	)
 	if err != nil {
 		return nil, nil, err
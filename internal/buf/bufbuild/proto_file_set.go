@@ -1,11 +1,14 @@
 package bufbuild
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"sort"
 
 	"github.com/bufbuild/buf/internal/pkg/storage/storagepath"
+	"github.com/bufbuild/buf/internal/pkg/util/utilencoding"
+	"github.com/bufbuild/buf/internal/pkg/util/utilstring"
 )
 
 type protoFileSet struct {
@@ -112,3 +115,101 @@ type rootRealFilePath struct {
 	rootFilePath string
 	realFilePath string
 }
+
+type externalProtoFileSet struct {
+	Roots []string                    `json:"roots,omitempty"`
+	Files []*externalProtoFileSetFile `json:"files,omitempty"`
+}
+
+type externalProtoFileSetFile struct {
+	RootFilePath string `json:"root_file_path,omitempty"`
+	RealFilePath string `json:"real_file_path,omitempty"`
+}
+
+func marshalProtoFileSetJSON(protoFileSet ProtoFileSet) ([]byte, error) {
+	rootFilePaths := protoFileSet.RootFilePaths()
+	realFilePaths := protoFileSet.RealFilePaths()
+	if len(rootFilePaths) != len(realFilePaths) {
+		// this is a system error
+		return nil, fmt.Errorf("got %d root file paths and %d real file paths", len(rootFilePaths), len(realFilePaths))
+	}
+	externalFiles := make([]*externalProtoFileSetFile, len(rootFilePaths))
+	for i, rootFilePath := range rootFilePaths {
+		externalFiles[i] = &externalProtoFileSetFile{
+			RootFilePath: rootFilePath,
+			RealFilePath: realFilePaths[i],
+		}
+	}
+	return json.Marshal(
+		&externalProtoFileSet{
+			Roots: protoFileSet.Roots(),
+			Files: externalFiles,
+		},
+	)
+}
+
+func unmarshalProtoFileSetJSON(data []byte) (*protoFileSet, error) {
+	external := &externalProtoFileSet{}
+	if err := utilencoding.UnmarshalJSONStrict(data, external); err != nil {
+		return nil, err
+	}
+	if len(external.Roots) == 0 {
+		return nil, errors.New("file set has no roots")
+	}
+	if len(external.Files) == 0 {
+		return nil, errors.New("file set has no files")
+	}
+	roots := make([]string, len(external.Roots))
+	for i, root := range external.Roots {
+		normalizedRoot, err := storagepath.NormalizeAndValidate(root)
+		if err != nil {
+			return nil, err
+		}
+		roots[i] = normalizedRoot
+	}
+	rootMap := utilstring.SliceToMap(roots)
+	if len(rootMap) != len(roots) {
+		return nil, fmt.Errorf("file set has duplicate roots %v", roots)
+	}
+	rootFilePathToRealFilePath := make(map[string]string, len(external.Files))
+	for _, externalFile := range external.Files {
+		if externalFile == nil || externalFile.RootFilePath == "" || externalFile.RealFilePath == "" {
+			return nil, errors.New("file set has a file without a root file path and real file path")
+		}
+		rootFilePath, err := storagepath.NormalizeAndValidate(externalFile.RootFilePath)
+		if err != nil {
+			return nil, err
+		}
+		realFilePath, err := storagepath.NormalizeAndValidate(externalFile.RealFilePath)
+		if err != nil {
+			return nil, err
+		}
+		if storagepath.Ext(realFilePath) != ".proto" {
+			return nil, fmt.Errorf("file set file %s is not a .proto file", realFilePath)
+		}
+		// the root file path must be the real file path relative to its root
+		var expectedRootFilePaths []string
+		for matchingRoot := range storagepath.MapMatches(rootMap, realFilePath) {
+			expectedRootFilePath, err := storagepath.Rel(matchingRoot, realFilePath)
+			if err != nil {
+				return nil, err
+			}
+			expectedRootFilePaths = append(expectedRootFilePaths, expectedRootFilePath)
+		}
+		switch len(expectedRootFilePaths) {
+		case 0:
+			return nil, fmt.Errorf("file set file %s is not within any root %v", realFilePath, roots)
+		case 1:
+			if expectedRootFilePaths[0] != rootFilePath {
+				return nil, fmt.Errorf("file set file %s has root file path %s but expected %s", realFilePath, rootFilePath, expectedRootFilePaths[0])
+			}
+		default:
+			return nil, fmt.Errorf("file set file %s is within multiple roots", realFilePath)
+		}
+		if otherRealFilePath, ok := rootFilePathToRealFilePath[rootFilePath]; ok {
+			return nil, fmt.Errorf("file set file with path %s is within multiple roots at %v", rootFilePath, []string{otherRealFilePath, realFilePath})
+		}
+		rootFilePathToRealFilePath[rootFilePath] = realFilePath
+	}
+	return newProtoFileSet(roots, rootFilePathToRealFilePath)
+}
@@ -170,6 +170,26 @@ func TestNewProtoFileSetError1(t *testing.T) {
 	)
 }
 
+func TestUnmarshalProtoFileSetJSONError(t *testing.T) {
+	t.Parallel()
+	for _, data := range []string{
+		``,
+		`{}`,
+		`{"roots":["proto"]}`,
+		`{"files":[{"root_file_path":"a/1.proto","real_file_path":"proto/a/1.proto"}]}`,
+		`{"roots":["proto"],"files":[{"root_file_path":"a/1.proto"}]}`,
+		`{"roots":["proto"],"files":[{"root_file_path":"a/1.proto","real_file_path":"other/a/1.proto"}]}`,
+		`{"roots":["proto"],"files":[{"root_file_path":"1.proto","real_file_path":"proto/a/1.proto"}]}`,
+		`{"roots":["proto"],"files":[{"root_file_path":"a/1.txt","real_file_path":"proto/a/1.txt"}]}`,
+		`{"roots":["proto"],"files":[{"root_file_path":"../a/1.proto","real_file_path":"proto/../../a/1.proto"}]}`,
+		`{"roots":["proto","other"],"files":[{"root_file_path":"a/1.proto","real_file_path":"proto/a/1.proto"},{"root_file_path":"a/1.proto","real_file_path":"other/a/1.proto"}]}`,
+		`{"roots":["proto"],"files":[],"unknown":true}`,
+	} {
+		_, err := UnmarshalProtoFileSetJSON([]byte(data))
+		assert.Error(t, err, data)
+	}
+}
+
 func testNewProtoFileSet(
 	t *testing.T,
 	relDir string,
@@ -194,6 +214,13 @@ func testNewProtoFileSet(
 		expectedRelFiles,
 		set.RealFilePaths(),
 	)
+	data, err := MarshalProtoFileSetJSON(set)
+	require.NoError(t, err)
+	unmarshaledSet, err := UnmarshalProtoFileSetJSON(data)
+	require.NoError(t, err)
+	assert.Equal(t, set.Roots(), unmarshaledSet.Roots())
+	assert.Equal(t, set.RootFilePaths(), unmarshaledSet.RootFilePaths())
+	assert.Equal(t, set.RealFilePaths(), unmarshaledSet.RealFilePaths())
 	if len(expectedRelFiles) > 1 {
 		expectedRelFiles = expectedRelFiles[:len(expectedRelFiles)-1]
 		set, err := newProvider(zap.NewNop()).GetProtoFileSetForRealFilePaths(
@@ -1,6 +1,8 @@
 package bufbuild
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -16,12 +18,15 @@ import (
 	"github.com/bufbuild/buf/internal/pkg/util/utillog"
 	"github.com/bufbuild/buf/internal/pkg/util/utilstring"
 	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/jhump/protoreflect/desc"
 	"github.com/jhump/protoreflect/desc/protoparse"
 	"go.uber.org/multierr"
 	"go.uber.org/zap"
 )
 
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
 type runner struct {
 	logger *zap.Logger
 }
@@ -46,6 +51,8 @@ func (r *runner) Run(
 	protoFileSet ProtoFileSet,
 	includeImports bool,
 	includeSourceInfo bool,
+	dependencyImages []*imagev1beta1.Image,
+	dependencyBuckets []storage.ReadBucket,
 ) (_ *imagev1beta1.Image, _ []*filev1beta1.FileAnnotation, retErr error) {
 	roots := protoFileSet.Roots()
 	rootFilePaths := protoFileSet.RootFilePaths()
@@ -62,6 +69,10 @@ func (r *runner) Run(
 		// this is a system error, we should have verified this elsewhere
 		return nil, nil, errors.New("rootFilePaths has duplicate values")
 	}
+	lookupImport, err := getLookupImport(dependencyImages)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	results := r.parse(
 		ctx,
@@ -70,6 +81,8 @@ func (r *runner) Run(
 		rootFilePaths,
 		includeImports,
 		includeSourceInfo,
+		lookupImport,
+		dependencyBuckets,
 	)
 
 	var resultErr error
@@ -126,11 +139,28 @@ func (r *runner) parse(
 	rootFilePaths []string,
 	includeImports bool,
 	includeSourceInfo bool,
+	lookupImport func(string) (*desc.FileDescriptor, error),
+	dependencyBuckets []storage.ReadBucket,
 ) []*result {
 	defer utillog.Defer(r.logger, "parse", zap.Int("num_files", len(rootFilePaths)))()
 
 	accessor := func(filename string) (io.ReadCloser, error) {
-		return bucket.Get(ctx, filename)
+		readObject, err := bucket.Get(ctx, filename)
+		if err == nil {
+			return newSkipUTF8BOMReadCloser(readObject), nil
+		}
+		if !storage.IsNotExist(err) {
+			return nil, err
+		}
+		// fall back to the dependency buckets for imports
+		for _, dependencyBucket := range dependencyBuckets {
+			if readObject, dependencyErr := dependencyBucket.Get(ctx, filename); dependencyErr == nil {
+				return newSkipUTF8BOMReadCloser(readObject), nil
+			} else if !storage.IsNotExist(dependencyErr) {
+				return nil, dependencyErr
+			}
+		}
+		return nil, err
 	}
 	var results []*result
 	chunks := utilstring.SliceToChunks(rootFilePaths, len(rootFilePaths)/runtime.NumCPU())
@@ -145,6 +175,7 @@ func (r *runner) parse(
 				roots,
 				rootFilePaths,
 				includeSourceInfo,
+				lookupImport,
 			)
 		}()
 	}
@@ -166,6 +197,7 @@ func (r *runner) getResult(
 	roots []string,
 	rootFilePaths []string,
 	includeSourceInfo bool,
+	lookupImport func(string) (*desc.FileDescriptor, error),
 ) *result {
 	// DO NOT NEED THIS ANYMORE
 	// TODO: test ResolveFilenames in protofile against the output
@@ -181,6 +213,7 @@ func (r *runner) getResult(
 		ImportPaths:           roots,
 		IncludeSourceCodeInfo: includeSourceInfo,
 		Accessor:              accessor,
+		LookupImport:          lookupImport,
 		ErrorReporter: func(errorWithPos protoparse.ErrorWithPos) error {
 			// protoparse isn't concurrent right now but just to be safe
 			// for the future
@@ -213,6 +246,42 @@ func (r *runner) getResult(
 	return newResult(rootFilePaths, descFileDescriptors, nil, nil)
 }
 
+// getLookupImport returns a protoparse LookupImport function that resolves
+// imports against the files in the dependency images.
+//
+// Files within the dependency images are never compiled or checked, they are only
+// used for import resolution when an import cannot be found within the roots.
+// If the same file name is present in multiple dependency images, the first wins.
+//
+// Returns nil if there are no dependency images.
+func getLookupImport(dependencyImages []*imagev1beta1.Image) (func(string) (*desc.FileDescriptor, error), error) {
+	if len(dependencyImages) == 0 {
+		return nil, nil
+	}
+	var fileDescriptorProtos []*descriptor.FileDescriptorProto
+	seen := make(map[string]struct{})
+	for _, dependencyImage := range dependencyImages {
+		for _, file := range dependencyImage.GetFile() {
+			if _, ok := seen[file.GetName()]; ok {
+				continue
+			}
+			seen[file.GetName()] = struct{}{}
+			fileDescriptorProtos = append(fileDescriptorProtos, file)
+		}
+	}
+	nameToDescFileDescriptor, err := desc.CreateFileDescriptors(fileDescriptorProtos)
+	if err != nil {
+		return nil, fmt.Errorf("could not use dependency images: %v", err)
+	}
+	return func(filename string) (*desc.FileDescriptor, error) {
+		descFileDescriptor, ok := nameToDescFileDescriptor[filename]
+		if !ok {
+			return nil, fmt.Errorf("%s not found in dependency images", filename)
+		}
+		return descFileDescriptor, nil
+	}, nil
+}
+
 func getFileAnnotation(errorWithPos protoparse.ErrorWithPos) (*filev1beta1.FileAnnotation, error) {
 	fileAnnotation := &filev1beta1.FileAnnotation{
 		Type: "COMPILE",
@@ -411,3 +480,22 @@ func newResult(
 		Err:                 err,
 	}
 }
+
+// skipUTF8BOMReadCloser skips a UTF-8 byte order mark at the start of the file.
+//
+// protoc ignores the byte order mark, while the parser fails on it.
+type skipUTF8BOMReadCloser struct {
+	*bufio.Reader
+	io.Closer
+}
+
+func newSkipUTF8BOMReadCloser(readCloser io.ReadCloser) *skipUTF8BOMReadCloser {
+	reader := bufio.NewReader(readCloser)
+	if prefix, err := reader.Peek(len(utf8BOM)); err == nil && bytes.Equal(prefix, utf8BOM) {
+		_, _ = reader.Discard(len(utf8BOM))
+	}
+	return &skipUTF8BOMReadCloser{
+		Reader: reader,
+		Closer: readCloser,
+	}
+}
@@ -197,6 +197,8 @@ func testBuild(t *testing.T, includeSourceInfo bool, bucket storage.ReadBucket,
 		protoFileSet,
 		true,
 		includeSourceInfo,
+		nil,
+		nil,
 	)
 	require.NoError(t, err)
 	return image, fileAnnotations
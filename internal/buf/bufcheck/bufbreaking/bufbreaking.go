@@ -5,12 +5,14 @@ package bufbreaking
 
 import (
 	"context"
+	"time"
 
 	"github.com/bufbuild/buf/internal/buf/bufcheck"
 	"github.com/bufbuild/buf/internal/buf/bufcheck/internal"
 	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
 	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
 	"github.com/bufbuild/buf/internal/pkg/protodesc"
+	"github.com/bufbuild/buf/internal/pkg/util/utilstring"
 	"go.uber.org/zap"
 )
 
@@ -31,6 +33,51 @@ type Handler interface {
 		previousImage *imagev1beta1.Image,
 		image *imagev1beta1.Image,
 	) ([]*filev1beta1.FileAnnotation, error)
+	// BreakingCheckStream runs the breaking checks, calling the function with each
+	// FileAnnotation as soon as the checker that produced it completes.
+	//
+	// The FileAnnotations are the same as those of BreakingCheck, but are not sorted, so
+	// that they do not need to be held in memory. The function is only called from the
+	// goroutine that called BreakingCheckStream.
+	BreakingCheckStream(
+		ctx context.Context,
+		breakingConfig *Config,
+		previousImage *imagev1beta1.Image,
+		image *imagev1beta1.Image,
+		fileAnnotationFunc func(*filev1beta1.FileAnnotation) error,
+	) error
+	// BreakingCheckWithReport runs the breaking checks, skipping packages that have
+	// not changed on either image since previousReport was created.
+	//
+	// FileAnnotations for skipped packages are copied from previousReport. If
+	// previousReport is nil or was created with a different Config, all packages
+	// are checked.
+	//
+	// Returns the FileAnnotations and a new Report for this run. The Report should be
+	// created before FixFileAnnotationPaths is called on the FileAnnotations.
+	BreakingCheckWithReport(
+		ctx context.Context,
+		breakingConfig *Config,
+		previousImage *imagev1beta1.Image,
+		image *imagev1beta1.Image,
+		previousReport *Report,
+	) ([]*filev1beta1.FileAnnotation, *Report, error)
+	// ApplyEnumValueDeleteGrace returns the FileAnnotations without the enum value
+	// deletions that are allowed by the EnumValueDeleteGraceIDToReleases of the Config.
+	//
+	// A deleted enum value is allowed if the previousManifest says it was deprecated for
+	// at least the number of releases of the checker. The FileAnnotations must be the
+	// result of a breaking check of image against previousImage with the Config, and
+	// previousManifest must be the Manifest of previousImage. The FileAnnotations are
+	// returned as-is if previousManifest is nil.
+	ApplyEnumValueDeleteGrace(
+		ctx context.Context,
+		breakingConfig *Config,
+		previousManifest *Manifest,
+		previousImage *imagev1beta1.Image,
+		image *imagev1beta1.Image,
+		fileAnnotations []*filev1beta1.FileAnnotation,
+	) ([]*filev1beta1.FileAnnotation, error)
 }
 
 // NewHandler returns a new Handler.
@@ -62,11 +109,31 @@ type Runner interface {
 	// they will be relative to the roots. This should be fixed for linter outputs if image
 	// mode is not used.
 	Check(context.Context, *Config, []protodesc.File, []protodesc.File) ([]*filev1beta1.FileAnnotation, error)
+	// CheckStream runs the breaking checkers, calling the function with each FileAnnotation
+	// as soon as the checker that produced it completes, and returning a system error if
+	// any system error occurs.
+	//
+	// FileAnnotations are not sorted, and Paths are relative to the roots as with Check.
+	// The function is only called from the goroutine that called CheckStream.
+	CheckStream(context.Context, *Config, []protodesc.File, []protodesc.File, func(*filev1beta1.FileAnnotation) error) error
+}
+
+// RunnerOption is an option for a new Runner.
+type RunnerOption func(*runner)
+
+// RunnerWithTimingFunc returns a new RunnerOption that calls the function with
+// the checker ID and the execution time of the checker for every run of a checker.
+//
+// The function is only called from the goroutine that called Check.
+func RunnerWithTimingFunc(timingFunc func(string, time.Duration)) RunnerOption {
+	return func(runner *runner) {
+		runner.timingFunc = timingFunc
+	}
 }
 
 // NewRunner returns a new Runner.
-func NewRunner(logger *zap.Logger) Runner {
-	return newRunner(logger)
+func NewRunner(logger *zap.Logger, options ...RunnerOption) Runner {
+	return newRunner(logger, options...)
 }
 
 // Config is the check config.
@@ -78,6 +145,15 @@ type Config struct {
 	Checkers            []Checker
 	IgnoreIDToRootPaths map[string]map[string]struct{}
 	IgnoreRootPaths     map[string]struct{}
+	// EnumValueDeleteGraceIDToReleases is a map from the IDs of enum value deletion
+	// checkers to the number of consecutive releases an enum value must have been
+	// deprecated for before the checker allows it to be deleted.
+	//
+	// See Handler.ApplyEnumValueDeleteGrace.
+	EnumValueDeleteGraceIDToReleases map[string]int
+
+	// the custom options of the ConfigBuilder this Config was created from, if any
+	customOptions []string
 }
 
 // GetCheckers returns the checkers for the given categories.
@@ -95,6 +171,16 @@ type ConfigBuilder struct {
 	Except                        []string
 	IgnoreIDOrCategoryToRootPaths map[string][]string
 	IgnoreRootPaths               []string
+	// CustomOptions are the names of the options checked by the *_SAME_CUSTOM_OPTIONS
+	// checkers, such as deprecated or google.api.http.
+	CustomOptions []string
+	// EnumValueDeleteGraceReleases is a map from the IDs of enum value deletion
+	// checkers or categories to the number of consecutive releases an enum value
+	// must have been deprecated for before it can be deleted.
+	//
+	// If a checker is covered by multiple entries, an entry for its ID wins over
+	// entries for its categories, and otherwise the largest number of releases wins.
+	EnumValueDeleteGraceReleases map[string]int
 }
 
 // NewConfig returns a new Config.
@@ -104,6 +190,7 @@ func (b ConfigBuilder) NewConfig() (*Config, error) {
 		Except:                        b.Except,
 		IgnoreIDOrCategoryToRootPaths: b.IgnoreIDOrCategoryToRootPaths,
 		IgnoreRootPaths:               b.IgnoreRootPaths,
+		CustomOptions:                 b.CustomOptions,
 	}.NewConfig(
 		v1CheckerBuilders,
 		v1IDToCategories,
@@ -112,7 +199,21 @@ func (b ConfigBuilder) NewConfig() (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
-	return internalConfigToConfig(internalConfig), nil
+	enumValueDeleteGraceIDToReleases, err := getEnumValueDeleteGraceIDToReleases(b.EnumValueDeleteGraceReleases)
+	if err != nil {
+		return nil, err
+	}
+	config := internalConfigToConfig(internalConfig)
+	config.EnumValueDeleteGraceIDToReleases = enumValueDeleteGraceIDToReleases
+	config.customOptions = utilstring.SliceToUniqueSortedSliceFilterEmptyStrings(b.CustomOptions)
+	return config, nil
+}
+
+// GetDefaultCategories returns the categories used if use is not set.
+//
+// Should only be used for printing.
+func GetDefaultCategories() []string {
+	return append([]string{}, v1DefaultCategories...)
 }
 
 // GetAllCheckers gets all known checkers for the given categories.
@@ -130,6 +231,13 @@ func GetAllCheckers(categories ...string) ([]bufcheck.Checker, error) {
 	return checkersToBufcheckCheckers(config.Checkers, categories)
 }
 
+// GetCheckerDoc gets the documentation for the checker with the given ID.
+//
+// Returns nil if there is no checker with the ID.
+func GetCheckerDoc(id string) *bufcheck.CheckerDoc {
+	return v1IDToCheckerDoc[id]
+}
+
 func internalConfigToConfig(internalConfig *internal.Config) *Config {
 	return &Config{
 		Checkers:            internalCheckersToCheckers(internalConfig.Checkers),
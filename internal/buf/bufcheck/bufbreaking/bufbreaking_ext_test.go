@@ -20,6 +20,31 @@ import (
 	"go.uber.org/zap"
 )
 
+func TestRunBreakingCustomOptions(t *testing.T) {
+	testBreaking(
+		t,
+		"breaking_custom_options",
+		extfiletesting.NewFileAnnotation("1.proto", 7, 1, 7, 29, "FILE_SAME_CUSTOM_OPTIONS"),
+		extfiletesting.NewFileAnnotation("1.proto", 9, 1, 15, 2, "MESSAGE_SAME_CUSTOM_OPTIONS"),
+		extfiletesting.NewFileAnnotation("1.proto", 12, 3, 12, 48, "FIELD_SAME_CUSTOM_OPTIONS"),
+		extfiletesting.NewFileAnnotation("1.proto", 13, 19, 13, 47, "FIELD_SAME_CUSTOM_OPTIONS"),
+		extfiletesting.NewFileAnnotation("1.proto", 18, 3, 18, 28, "MESSAGE_SAME_CUSTOM_OPTIONS"),
+		extfiletesting.NewFileAnnotation("1.proto", 20, 19, 20, 36, "FIELD_SAME_CUSTOM_OPTIONS"),
+		extfiletesting.NewFileAnnotation("1.proto", 27, 3, 29, 4, "RPC_SAME_CUSTOM_OPTIONS"),
+		extfiletesting.NewFileAnnotation("1.proto", 31, 5, 31, 30, "RPC_SAME_CUSTOM_OPTIONS"),
+	)
+}
+
+func TestRunBreakingCustomOptionsNotConfigured(t *testing.T) {
+	testBreakingExternalConfigModifier(
+		t,
+		"breaking_custom_options",
+		func(externalConfig *bufconfig.ExternalConfig) {
+			externalConfig.Breaking.CustomOptions = nil
+		},
+	)
+}
+
 func TestRunBreakingEnumNoDelete(t *testing.T) {
 	testBreaking(
 		t,
@@ -30,6 +55,15 @@ func TestRunBreakingEnumNoDelete(t *testing.T) {
 	)
 }
 
+func TestRunBreakingEnumSameAllowAlias(t *testing.T) {
+	testBreaking(
+		t,
+		"breaking_enum_same_allow_alias",
+		extfiletesting.NewFileAnnotation("1.proto", 5, 1, 8, 2, "ENUM_SAME_ALLOW_ALIAS"),
+		extfiletesting.NewFileAnnotation("1.proto", 11, 3, 11, 29, "ENUM_SAME_ALLOW_ALIAS"),
+	)
+}
+
 func TestRunBreakingEnumValueNoDelete(t *testing.T) {
 	testBreaking(
 		t,
@@ -443,6 +477,17 @@ func TestRunBreakingReservedEnumNoDelete(t *testing.T) {
 	)
 }
 
+func TestRunBreakingNoDeleteUnlessReserved(t *testing.T) {
+	testBreaking(
+		t,
+		"breaking_no_delete_unless_reserved",
+		extfiletesting.NewFileAnnotation("1.proto", 5, 1, 9, 2, "FIELD_NO_DELETE_UNLESS_RESERVED"),
+		extfiletesting.NewFileAnnotation("1.proto", 5, 1, 9, 2, "FIELD_NO_DELETE_UNLESS_RESERVED"),
+		extfiletesting.NewFileAnnotation("1.proto", 11, 1, 15, 2, "ENUM_VALUE_NO_DELETE_UNLESS_RESERVED"),
+		extfiletesting.NewFileAnnotation("1.proto", 11, 1, 15, 2, "ENUM_VALUE_NO_DELETE_UNLESS_RESERVED"),
+	)
+}
+
 func TestRunBreakingReservedMessageNoDelete(t *testing.T) {
 	testBreaking(
 		t,
@@ -470,6 +515,18 @@ func TestRunBreakingReservedMessageNoDelete(t *testing.T) {
 	)
 }
 
+func TestRunBreakingReservedNoReuse(t *testing.T) {
+	testBreaking(
+		t,
+		"breaking_reserved_no_reuse",
+		extfiletesting.NewFileAnnotation("1.proto", 9, 9, 9, 14, "RESERVED_MESSAGE_NO_REUSE"),
+		extfiletesting.NewFileAnnotation("1.proto", 9, 17, 9, 18, "RESERVED_MESSAGE_NO_REUSE"),
+		extfiletesting.NewFileAnnotation("1.proto", 10, 17, 10, 18, "RESERVED_MESSAGE_NO_REUSE"),
+		extfiletesting.NewFileAnnotation("1.proto", 15, 3, 15, 10, "RESERVED_ENUM_NO_REUSE"),
+		extfiletesting.NewFileAnnotation("1.proto", 16, 15, 16, 16, "RESERVED_ENUM_NO_REUSE"),
+	)
+}
+
 func TestRunBreakingRPCNoDelete(t *testing.T) {
 	testBreaking(
 		t,
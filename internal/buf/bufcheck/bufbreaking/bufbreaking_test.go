@@ -1,9 +1,18 @@
 package bufbreaking
 
 import (
+	"context"
 	"testing"
 
 	"github.com/bufbuild/buf/internal/buf/bufcheck/internal/internaltesting"
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/bufbuild/buf/internal/pkg/protodesc"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 )
 
 func TestDefaultConfigBuilder(t *testing.T) {
@@ -25,3 +34,424 @@ func TestCheckerBuilders(t *testing.T) {
 		v1AllCategories,
 	)
 }
+
+func TestCheckerDocs(t *testing.T) {
+	t.Parallel()
+	internaltesting.RunTestCheckerDocs(
+		t,
+		v1CheckerBuilders,
+		v1IDToCheckerDoc,
+	)
+}
+
+func TestNewManifest(t *testing.T) {
+	t.Parallel()
+	image := &imagev1beta1.Image{
+		File: []*descriptor.FileDescriptorProto{
+			{
+				Name:    proto.String("a/a.proto"),
+				Package: proto.String("a"),
+			},
+			{
+				Name:    proto.String("b/b.proto"),
+				Package: proto.String("b"),
+			},
+		},
+	}
+	manifest, err := NewManifest(nil, image, image, nil)
+	require.NoError(t, err)
+	require.Len(t, manifest.Packages, 2)
+	assert.Equal(t, "a", manifest.Packages[0].Name)
+	assert.Equal(t, 1, manifest.Packages[0].Version)
+	assert.Equal(t, "b", manifest.Packages[1].Name)
+	assert.Equal(t, 1, manifest.Packages[1].Version)
+
+	// no breaking changes, stamps should not change
+	newManifest, err := NewManifest(manifest, image, image, nil)
+	require.NoError(t, err)
+	assert.Equal(t, manifest, newManifest)
+
+	// breaking changes in a, only the stamp for a should change
+	newManifest, err = NewManifest(
+		manifest,
+		image,
+		image,
+		[]*filev1beta1.FileAnnotation{
+			{
+				Path:    "a/a.proto",
+				Type:    "FIELD_NO_DELETE",
+				Message: "Deleted.",
+			},
+		},
+	)
+	require.NoError(t, err)
+	require.Len(t, newManifest.Packages, 2)
+	assert.NotEqual(t, manifest.Packages[0].Stamp, newManifest.Packages[0].Stamp)
+	assert.Equal(t, 2, newManifest.Packages[0].Version)
+	assert.Equal(t, manifest.Packages[1], newManifest.Packages[1])
+}
+
+func TestGetFullNameForPath(t *testing.T) {
+	t.Parallel()
+	file := &descriptor.FileDescriptorProto{
+		Name:    proto.String("a/a.proto"),
+		Package: proto.String("a"),
+		MessageType: []*descriptor.DescriptorProto{
+			{
+				Name: proto.String("Foo"),
+				Field: []*descriptor.FieldDescriptorProto{
+					{Name: proto.String("one")},
+				},
+				NestedType: []*descriptor.DescriptorProto{
+					{Name: proto.String("Bar")},
+				},
+				EnumType: []*descriptor.EnumDescriptorProto{
+					{
+						Name: proto.String("Baz"),
+						Value: []*descriptor.EnumValueDescriptorProto{
+							{Name: proto.String("BAZ_ONE")},
+						},
+					},
+				},
+				OneofDecl: []*descriptor.OneofDescriptorProto{
+					{Name: proto.String("bat")},
+				},
+			},
+		},
+		Service: []*descriptor.ServiceDescriptorProto{
+			{
+				Name: proto.String("FooService"),
+				Method: []*descriptor.MethodDescriptorProto{
+					{Name: proto.String("Get")},
+				},
+			},
+		},
+	}
+	assert.Equal(t, "", getFullNameForPath(file, nil))
+	assert.Equal(t, "", getFullNameForPath(file, []int32{2}))
+	assert.Equal(t, "a.Foo", getFullNameForPath(file, []int32{4, 0}))
+	assert.Equal(t, "a.Foo", getFullNameForPath(file, []int32{4, 0, 7}))
+	assert.Equal(t, "a.Foo.one", getFullNameForPath(file, []int32{4, 0, 2, 0}))
+	// the type of the field
+	assert.Equal(t, "a.Foo.one", getFullNameForPath(file, []int32{4, 0, 2, 0, 5}))
+	assert.Equal(t, "a.Foo.Bar", getFullNameForPath(file, []int32{4, 0, 3, 0}))
+	assert.Equal(t, "a.Foo.Baz", getFullNameForPath(file, []int32{4, 0, 4, 0}))
+	assert.Equal(t, "a.Foo.BAZ_ONE", getFullNameForPath(file, []int32{4, 0, 4, 0, 2, 0}))
+	assert.Equal(t, "a.Foo.bat", getFullNameForPath(file, []int32{4, 0, 8, 0}))
+	assert.Equal(t, "a.FooService", getFullNameForPath(file, []int32{6, 0}))
+	assert.Equal(t, "a.FooService.Get", getFullNameForPath(file, []int32{6, 0, 2, 0}))
+	assert.Equal(t, "", getFullNameForPath(file, []int32{4, 1}))
+}
+
+func TestGetDetails(t *testing.T) {
+	t.Parallel()
+	config, err := ConfigBuilder{
+		Use: []string{"FIELD_NO_DELETE", "FIELD_SAME_NAME", "FIELD_SAME_LABEL"},
+	}.NewConfig()
+	require.NoError(t, err)
+	image := &imagev1beta1.Image{
+		File: []*descriptor.FileDescriptorProto{
+			{
+				Name:    proto.String("a/a.proto"),
+				Package: proto.String("a"),
+				MessageType: []*descriptor.DescriptorProto{
+					{
+						Name: proto.String("Foo"),
+						Field: []*descriptor.FieldDescriptorProto{
+							{Name: proto.String("one")},
+						},
+					},
+				},
+				SourceCodeInfo: &descriptor.SourceCodeInfo{
+					Location: []*descriptor.SourceCodeInfo_Location{
+						{Path: []int32{}, Span: []int32{0, 0, 5, 1}},
+						{Path: []int32{4, 0}, Span: []int32{2, 0, 4, 1}},
+						{Path: []int32{4, 0, 2, 0}, Span: []int32{3, 2, 17}},
+					},
+				},
+			},
+		},
+	}
+	details, err := GetDetails(
+		config,
+		image,
+		[]*filev1beta1.FileAnnotation{
+			{Path: "a/a.proto", StartLine: 3, StartColumn: 1, EndLine: 5, EndColumn: 2, Type: "FIELD_NO_DELETE"},
+			{Path: "a/a.proto", StartLine: 4, StartColumn: 3, EndLine: 4, EndColumn: 18, Type: "FIELD_SAME_NAME"},
+			{Path: "a/a.proto", StartLine: 4, StartColumn: 3, EndLine: 4, EndColumn: 18, Type: "FIELD_SAME_LABEL"},
+			{Path: "a/a.proto", Type: "FIELD_SAME_LABEL"},
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]*Details{
+			{Categories: []string{"FILE", "PACKAGE"}, Impact: ImpactSource, FullName: "a.Foo"},
+			{Categories: []string{"FILE", "PACKAGE", "WIRE_JSON"}, Impact: ImpactJSON, FullName: "a.Foo.one"},
+			{Categories: []string{"FILE", "PACKAGE", "WIRE_JSON", "WIRE"}, Impact: ImpactWire, FullName: "a.Foo.one"},
+			{Categories: []string{"FILE", "PACKAGE", "WIRE_JSON", "WIRE"}, Impact: ImpactWire},
+		},
+		details,
+	)
+	_, err = GetDetails(config, image, []*filev1beta1.FileAnnotation{{Type: "FIELD_SAME_CTYPE"}})
+	assert.Error(t, err)
+}
+
+func TestNewManifestDeprecatedEnumValues(t *testing.T) {
+	t.Parallel()
+	image := testNewEnumImage(
+		&descriptor.EnumValueDescriptorProto{
+			Name:   proto.String("FOO_UNSPECIFIED"),
+			Number: proto.Int32(0),
+		},
+		&descriptor.EnumValueDescriptorProto{
+			Name:    proto.String("FOO_ONE"),
+			Number:  proto.Int32(1),
+			Options: &descriptor.EnumValueOptions{Deprecated: proto.Bool(true)},
+		},
+	)
+	manifest, err := NewManifest(nil, image, image, nil)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]*ManifestDeprecatedEnumValue{
+			{
+				Name:     "a.Foo.FOO_ONE",
+				Releases: 1,
+			},
+		},
+		manifest.DeprecatedEnumValues,
+	)
+	manifest, err = NewManifest(manifest, image, image, nil)
+	require.NoError(t, err)
+	require.Len(t, manifest.DeprecatedEnumValues, 1)
+	assert.Equal(t, 2, manifest.DeprecatedEnumValues[0].Releases)
+
+	// no longer deprecated, the count starts over
+	image = testNewEnumImage(
+		&descriptor.EnumValueDescriptorProto{
+			Name:   proto.String("FOO_UNSPECIFIED"),
+			Number: proto.Int32(0),
+		},
+	)
+	manifest, err = NewManifest(manifest, image, image, nil)
+	require.NoError(t, err)
+	assert.Empty(t, manifest.DeprecatedEnumValues)
+}
+
+func TestConfigBuilderEnumValueDeleteGraceReleases(t *testing.T) {
+	t.Parallel()
+	config, err := ConfigBuilder{
+		EnumValueDeleteGraceReleases: map[string]int{
+			"FILE":                 2,
+			"WIRE_JSON":            1,
+			"WIRE":                 3,
+			"ENUM_VALUE_NO_DELETE": 4,
+		},
+	}.NewConfig()
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		map[string]int{
+			"ENUM_VALUE_NO_DELETE":                        4,
+			"ENUM_VALUE_NO_DELETE_UNLESS_NAME_RESERVED":   1,
+			"ENUM_VALUE_NO_DELETE_UNLESS_NUMBER_RESERVED": 3,
+		},
+		config.EnumValueDeleteGraceIDToReleases,
+	)
+	_, err = ConfigBuilder{
+		EnumValueDeleteGraceReleases: map[string]int{
+			"FIELD_NO_DELETE": 1,
+		},
+	}.NewConfig()
+	assert.Error(t, err)
+	_, err = ConfigBuilder{
+		EnumValueDeleteGraceReleases: map[string]int{
+			"FILE": -1,
+		},
+	}.NewConfig()
+	assert.Error(t, err)
+}
+
+func TestApplyEnumValueDeleteGrace(t *testing.T) {
+	t.Parallel()
+	previousImage := testNewEnumImage(
+		&descriptor.EnumValueDescriptorProto{
+			Name:   proto.String("FOO_UNSPECIFIED"),
+			Number: proto.Int32(0),
+		},
+		&descriptor.EnumValueDescriptorProto{
+			Name:    proto.String("FOO_ONE"),
+			Number:  proto.Int32(1),
+			Options: &descriptor.EnumValueOptions{Deprecated: proto.Bool(true)},
+		},
+		&descriptor.EnumValueDescriptorProto{
+			Name:   proto.String("FOO_TWO"),
+			Number: proto.Int32(2),
+		},
+	)
+	image := testNewEnumImage(
+		&descriptor.EnumValueDescriptorProto{
+			Name:   proto.String("FOO_UNSPECIFIED"),
+			Number: proto.Int32(0),
+		},
+	)
+	config, err := ConfigBuilder{
+		Use: []string{"ENUM_VALUE_NO_DELETE"},
+		EnumValueDeleteGraceReleases: map[string]int{
+			"ENUM_VALUE_NO_DELETE": 2,
+		},
+	}.NewConfig()
+	require.NoError(t, err)
+	ctx := context.Background()
+	handler := NewHandler(zap.NewNop(), NewRunner(zap.NewNop()))
+	fileAnnotations, err := handler.BreakingCheck(ctx, config, previousImage, image)
+	require.NoError(t, err)
+	require.Len(t, fileAnnotations, 2)
+
+	// no manifest, nothing is graced
+	gracedFileAnnotations, err := handler.ApplyEnumValueDeleteGrace(ctx, config, nil, previousImage, image, fileAnnotations)
+	require.NoError(t, err)
+	assert.Equal(t, fileAnnotations, gracedFileAnnotations)
+
+	// not deprecated for long enough
+	manifest := &Manifest{
+		DeprecatedEnumValues: []*ManifestDeprecatedEnumValue{
+			{
+				Name:     "a.Foo.FOO_ONE",
+				Releases: 1,
+			},
+		},
+	}
+	gracedFileAnnotations, err = handler.ApplyEnumValueDeleteGrace(ctx, config, manifest, previousImage, image, fileAnnotations)
+	require.NoError(t, err)
+	assert.Len(t, gracedFileAnnotations, 2)
+
+	// only the deprecated value is graced
+	manifest.DeprecatedEnumValues[0].Releases = 2
+	gracedFileAnnotations, err = handler.ApplyEnumValueDeleteGrace(ctx, config, manifest, previousImage, image, fileAnnotations)
+	require.NoError(t, err)
+	require.Len(t, gracedFileAnnotations, 1)
+	assert.Contains(t, gracedFileAnnotations[0].GetMessage(), `"2"`)
+}
+
+func TestBreakingCheckWithReport(t *testing.T) {
+	t.Parallel()
+	previousImage := testNewPackagesImage("a", "b")
+	image := testNewPackagesImage("a", "b")
+	config, err := ConfigBuilder{Use: []string{"FILE"}}.NewConfig()
+	require.NoError(t, err)
+	ctx := context.Background()
+	runner := &testRecordingRunner{}
+	handler := NewHandler(zap.NewNop(), runner)
+
+	// without a previous report, all packages are checked
+	fileAnnotations, report, err := handler.BreakingCheckWithReport(ctx, config, previousImage, image, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/a.proto", "b/b.proto"}, runner.filePaths)
+	assert.Equal(t, []string{"a/a.proto", "b/b.proto"}, testFileAnnotationPaths(fileAnnotations))
+	require.Len(t, report.Packages, 2)
+
+	// only the changed package is checked, the FileAnnotations of the unchanged package are reused
+	image.File[1].MessageType = []*descriptor.DescriptorProto{{Name: proto.String("Foo")}}
+	runner.filePaths = nil
+	fileAnnotations, _, err = handler.BreakingCheckWithReport(ctx, config, previousImage, image, report)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b/b.proto"}, runner.filePaths)
+	assert.Equal(t, []string{"a/a.proto", "b/b.proto"}, testFileAnnotationPaths(fileAnnotations))
+
+	// the report is not used if the config changed
+	otherConfig, err := ConfigBuilder{Use: []string{"WIRE"}}.NewConfig()
+	require.NoError(t, err)
+	runner.filePaths = nil
+	_, _, err = handler.BreakingCheckWithReport(ctx, otherConfig, previousImage, image, report)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/a.proto", "b/b.proto"}, runner.filePaths)
+}
+
+// testRecordingRunner is a Runner that records the paths of the files it checks
+// and returns one FileAnnotation per file.
+type testRecordingRunner struct {
+	filePaths []string
+}
+
+func (r *testRecordingRunner) Check(
+	_ context.Context,
+	_ *Config,
+	_ []protodesc.File,
+	files []protodesc.File,
+) ([]*filev1beta1.FileAnnotation, error) {
+	var fileAnnotations []*filev1beta1.FileAnnotation
+	for _, file := range files {
+		r.filePaths = append(r.filePaths, file.FilePath())
+		fileAnnotations = append(
+			fileAnnotations,
+			&filev1beta1.FileAnnotation{
+				Path: file.FilePath(),
+				Type: "FILE_SAME_PACKAGE",
+			},
+		)
+	}
+	return fileAnnotations, nil
+}
+
+func (r *testRecordingRunner) CheckStream(
+	ctx context.Context,
+	config *Config,
+	previousFiles []protodesc.File,
+	files []protodesc.File,
+	fileAnnotationFunc func(*filev1beta1.FileAnnotation) error,
+) error {
+	fileAnnotations, err := r.Check(ctx, config, previousFiles, files)
+	if err != nil {
+		return err
+	}
+	for _, fileAnnotation := range fileAnnotations {
+		if err := fileAnnotationFunc(fileAnnotation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// testNewPackagesImage returns an image with one file per package.
+func testNewPackagesImage(pkgs ...string) *imagev1beta1.Image {
+	image := &imagev1beta1.Image{}
+	for _, pkg := range pkgs {
+		image.File = append(
+			image.File,
+			&descriptor.FileDescriptorProto{
+				Name:    proto.String(pkg + "/" + pkg + ".proto"),
+				Package: proto.String(pkg),
+				Syntax:  proto.String("proto3"),
+			},
+		)
+	}
+	return image
+}
+
+func testFileAnnotationPaths(fileAnnotations []*filev1beta1.FileAnnotation) []string {
+	paths := make([]string, len(fileAnnotations))
+	for i, fileAnnotation := range fileAnnotations {
+		paths[i] = fileAnnotation.GetPath()
+	}
+	return paths
+}
+
+func testNewEnumImage(values ...*descriptor.EnumValueDescriptorProto) *imagev1beta1.Image {
+	return &imagev1beta1.Image{
+		File: []*descriptor.FileDescriptorProto{
+			{
+				Name:    proto.String("a/a.proto"),
+				Package: proto.String("a"),
+				Syntax:  proto.String("proto3"),
+				EnumType: []*descriptor.EnumDescriptorProto{
+					{
+						Name:  proto.String("Foo"),
+						Value: values,
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,595 @@
+package bufbreaking
+
+import (
+	"fmt"
+
+	"github.com/bufbuild/buf/internal/buf/bufcheck"
+)
+
+const (
+	fileOptionRationale = `File options control the generated code for their language. Changing the value
+moves or renames the generated code, which breaks code that imports or uses it.`
+	wireRationale = `Clients and servers built against different versions of the schema decode the
+binary encoding of each other's messages incorrectly.`
+	jsonRationale = `Clients and servers built against different versions of the schema cannot
+decode each other's JSON encoding.`
+	rpcRationale = `Clients built against the previous version of the schema send requests that
+servers built against the new version no longer accept, or the reverse.`
+	customOptionsRationale = `Custom options are read by plugins and frameworks, for example to route HTTP
+requests or to mark elements as deprecated, so changing them changes behavior
+without changing the generated types. These checks do nothing unless the options
+are listed in the custom_options option of the breaking configuration.`
+	enumValueDeleteGraceRationale = `
+The deletion of values that were deprecated for a number of releases can be allowed
+with the enum_value_delete_grace_releases option of the breaking configuration.`
+)
+
+var (
+	// v1IDToCheckerDoc are the revision 1 ID to CheckerDoc.
+	v1IDToCheckerDoc = map[string]*bufcheck.CheckerDoc{
+		"ENUM_NO_DELETE": {
+			Description: "Enums, including nested enums, must not be deleted from a file.",
+			Rationale:   newDeleteRationale("enum"),
+			Examples: newBreakingExamples(
+				`enum Foo {
+  FOO_UNSPECIFIED = 0;
+}`,
+				``,
+			),
+		},
+		"ENUM_SAME_ALLOW_ALIAS": {
+			Description: "Enums must have the same value for the allow_alias option, where an unset option is false.",
+			Rationale: `Disabling allow_alias breaks code that uses the aliases, and enabling it changes
+the generated code in some languages, for example Java generates static fields
+instead of enum constants for aliases, and switch statements over the values of
+the enum stop compiling in languages that require unique cases.`,
+			Examples: newBreakingExamples(
+				`enum Foo {
+  option allow_alias = true;
+  FOO_UNSPECIFIED = 0;
+  FOO_ONE = 1;
+}`,
+				`enum Foo {
+  FOO_UNSPECIFIED = 0;
+  FOO_ONE = 1;
+}`,
+			),
+		},
+		"ENUM_VALUE_NO_DELETE": {
+			Description: "Enum values must not be deleted from an enum.",
+			Rationale:   newDeleteRationale("enum value") + enumValueDeleteGraceRationale,
+			Examples: newBreakingExamples(
+				`enum Foo {
+  FOO_UNSPECIFIED = 0;
+  FOO_ONE = 1;
+}`,
+				`enum Foo {
+  FOO_UNSPECIFIED = 0;
+}`,
+			),
+		},
+		"ENUM_VALUE_NO_DELETE_UNLESS_RESERVED": {
+			Description: "Enum values must not be deleted from an enum unless both the number and the name of the deleted value are reserved.",
+			Rationale: wireRationale + `
+Reserving both the number and the name prevents either from being reused, which
+keeps both the binary and JSON encodings compatible.` + enumValueDeleteGraceRationale,
+			Examples: newReservedBreakingExamples(
+				`enum Foo {
+  FOO_UNSPECIFIED = 0;
+  FOO_ONE = 1;
+}`,
+				`enum Foo {
+  reserved 1;
+  FOO_UNSPECIFIED = 0;
+}`,
+				`enum Foo {
+  reserved 1;
+  reserved "FOO_ONE";
+  FOO_UNSPECIFIED = 0;
+}`,
+			),
+		},
+		"ENUM_VALUE_NO_DELETE_UNLESS_NAME_RESERVED": {
+			Description: "Enum values must not be deleted from an enum unless the name of the deleted value is reserved.",
+			Rationale: jsonRationale + `
+Reserving the name prevents it from being reused with a different number.` + enumValueDeleteGraceRationale,
+			Examples: newReservedBreakingExamples(
+				`enum Foo {
+  FOO_UNSPECIFIED = 0;
+  FOO_ONE = 1;
+}`,
+				`enum Foo {
+  FOO_UNSPECIFIED = 0;
+}`,
+				`enum Foo {
+  reserved "FOO_ONE";
+  FOO_UNSPECIFIED = 0;
+}`,
+			),
+		},
+		"ENUM_VALUE_NO_DELETE_UNLESS_NUMBER_RESERVED": {
+			Description: "Enum values must not be deleted from an enum unless the number of the deleted value is reserved.",
+			Rationale: wireRationale + `
+Reserving the number prevents it from being reused with a different meaning.` + enumValueDeleteGraceRationale,
+			Examples: newReservedBreakingExamples(
+				`enum Foo {
+  FOO_UNSPECIFIED = 0;
+  FOO_ONE = 1;
+}`,
+				`enum Foo {
+  FOO_UNSPECIFIED = 0;
+}`,
+				`enum Foo {
+  reserved 1;
+  FOO_UNSPECIFIED = 0;
+}`,
+			),
+		},
+		"ENUM_VALUE_SAME_NAME": {
+			Description: "Enum values with a given number must have the same name. If the enum allows aliases, the set of names for the number must be the same.",
+			Rationale:   jsonRationale + "\nThe generated code for the enum value is also renamed.",
+			Examples: newBreakingExamples(
+				`enum Foo {
+  FOO_UNSPECIFIED = 0;
+  FOO_ONE = 1;
+}`,
+				`enum Foo {
+  FOO_UNSPECIFIED = 0;
+  FOO_UNO = 1;
+}`,
+			),
+		},
+		"EXTENSION_MESSAGE_NO_DELETE": {
+			Description: "Extension ranges must not be deleted from a message.",
+			Rationale:   "Extensions of the message that use the deleted range no longer compile.",
+			Examples: newBreakingExamples(
+				`message Foo {
+  extensions 100 to 200;
+}`,
+				`message Foo {}`,
+			),
+		},
+		"FIELD_NO_DELETE": {
+			Description: "Fields must not be deleted from a message.",
+			Rationale:   newDeleteRationale("field"),
+			Examples: newBreakingExamples(
+				`message Foo {
+  string name = 1;
+}`,
+				`message Foo {}`,
+			),
+		},
+		"FIELD_NO_DELETE_UNLESS_NAME_RESERVED": {
+			Description: "Fields must not be deleted from a message unless the name of the deleted field is reserved.",
+			Rationale: jsonRationale + `
+Reserving the name prevents it from being reused with a different type.`,
+			Examples: newReservedBreakingExamples(
+				`message Foo {
+  string name = 1;
+}`,
+				`message Foo {}`,
+				`message Foo {
+  reserved "name";
+}`,
+			),
+		},
+		"FIELD_NO_DELETE_UNLESS_NUMBER_RESERVED": {
+			Description: "Fields must not be deleted from a message unless the number of the deleted field is reserved.",
+			Rationale: wireRationale + `
+Reserving the number prevents it from being reused with a different type.`,
+			Examples: newReservedBreakingExamples(
+				`message Foo {
+  string name = 1;
+}`,
+				`message Foo {}`,
+				`message Foo {
+  reserved 1;
+}`,
+			),
+		},
+		"FIELD_NO_DELETE_UNLESS_RESERVED": {
+			Description: "Fields must not be deleted from a message unless both the number and the name of the deleted field are reserved.",
+			Rationale: wireRationale + `
+Reserving both the number and the name prevents either from being reused, which
+keeps both the binary and JSON encodings compatible.`,
+			Examples: newReservedBreakingExamples(
+				`message Foo {
+  string name = 1;
+}`,
+				`message Foo {
+  reserved 1;
+}`,
+				`message Foo {
+  reserved 1;
+  reserved "name";
+}`,
+			),
+		},
+		"FIELD_SAME_CTYPE": {
+			Description: "Fields must have the same value for the ctype option, including whether it is set.",
+			Rationale:   "The ctype option changes the type of the generated C++ accessors.",
+			Examples: newBreakingExamples(
+				`message Foo {
+  string name = 1;
+}`,
+				`message Foo {
+  string name = 1 [ctype = CORD];
+}`,
+			),
+		},
+		"FIELD_SAME_CUSTOM_OPTIONS": {
+			Description: "Fields must have the same values for the configured custom options, including whether they are set.",
+			Rationale:   customOptionsRationale,
+			Examples: newBreakingExamples(
+				`message Foo {
+  string name = 1 [deprecated = true];
+}`,
+				`message Foo {
+  string name = 1;
+}`,
+			),
+		},
+		"FIELD_SAME_JSON_NAME": {
+			Description: "Fields must have the same value for the json_name option, including whether it is set.",
+			Rationale:   jsonRationale,
+			Examples: newBreakingExamples(
+				`message Foo {
+  string name = 1;
+}`,
+				`message Foo {
+  string name = 1 [json_name = "fooName"];
+}`,
+			),
+		},
+		"FIELD_SAME_JSTYPE": {
+			Description: "Fields must have the same value for the jstype option, including whether it is set.",
+			Rationale:   "The jstype option changes the type of the generated JavaScript accessors.",
+			Examples: newBreakingExamples(
+				`message Foo {
+  int64 id = 1;
+}`,
+				`message Foo {
+  int64 id = 1 [jstype = JS_STRING];
+}`,
+			),
+		},
+		"FIELD_SAME_LABEL": {
+			Description: "Fields must have the same label, that is, optional, required, or repeated.",
+			Rationale:   wireRationale + "\nThe generated accessors for the field also change type.",
+			Examples: newBreakingExamples(
+				`message Foo {
+  string name = 1;
+}`,
+				`message Foo {
+  repeated string name = 1;
+}`,
+			),
+		},
+		"FIELD_SAME_NAME": {
+			Description: "Fields with a given number must have the same name.",
+			Rationale:   jsonRationale + "\nThe generated accessors for the field are also renamed.",
+			Examples: newBreakingExamples(
+				`message Foo {
+  string name = 1;
+}`,
+				`message Foo {
+  string foo_name = 1;
+}`,
+			),
+		},
+		"FIELD_SAME_ONEOF": {
+			Description: "Fields must be in the same oneof, or must remain outside of any oneof.",
+			Rationale:   wireRationale + "\nSetting one field of a oneof clears the others, so moving fields into or out of a oneof changes which fields can be set together.",
+			Examples: newBreakingExamples(
+				`message Foo {
+  string name = 1;
+}`,
+				`message Foo {
+  oneof value {
+    string name = 1;
+  }
+}`,
+			),
+		},
+		"FIELD_SAME_TYPE": {
+			Description: "Fields must have the same type. For message and enum fields, the fully-qualified type name must be the same.",
+			Rationale:   wireRationale + "\nThe generated accessors for the field also change type.",
+			Examples: newBreakingExamples(
+				`message Foo {
+  int32 id = 1;
+}`,
+				`message Foo {
+  string id = 1;
+}`,
+			),
+		},
+		"FILE_NO_DELETE": {
+			Description: "Files must not be deleted.",
+			Rationale:   "Files that import the deleted file, and code that imports the generated code for the file, no longer compile.",
+		},
+		"FILE_SAME_CSHARP_NAMESPACE": newFileOptionCheckerDoc(`option csharp_namespace = "Foo.V1";`, `option csharp_namespace = "Foo";`),
+		"FILE_SAME_CUSTOM_OPTIONS": {
+			Description: "Files must have the same values for the configured custom options, including whether they are set.",
+			Rationale:   customOptionsRationale,
+			Examples: newBreakingExamples(
+				`option (foo.v1.owner) = "team-a";`,
+				`option (foo.v1.owner) = "team-b";`,
+			),
+		},
+		"FILE_SAME_GO_PACKAGE":             newFileOptionCheckerDoc(`option go_package = "foov1";`, `option go_package = "foo";`),
+		"FILE_SAME_JAVA_MULTIPLE_FILES":    newFileOptionCheckerDoc(`option java_multiple_files = true;`, `option java_multiple_files = false;`),
+		"FILE_SAME_JAVA_OUTER_CLASSNAME":   newFileOptionCheckerDoc(`option java_outer_classname = "FooProto";`, `option java_outer_classname = "Foo";`),
+		"FILE_SAME_JAVA_PACKAGE":           newFileOptionCheckerDoc(`option java_package = "com.foo.v1";`, `option java_package = "com.foo";`),
+		"FILE_SAME_JAVA_STRING_CHECK_UTF8": newFileOptionCheckerDoc(`option java_string_check_utf8 = false;`, `option java_string_check_utf8 = true;`),
+		"FILE_SAME_OBJC_CLASS_PREFIX":      newFileOptionCheckerDoc(`option objc_class_prefix = "FXX";`, `option objc_class_prefix = "FOO";`),
+		"FILE_SAME_PACKAGE": {
+			Description: "Files must declare the same package.",
+			Rationale:   "Every type in the file is renamed, which breaks all references to the types and the generated code for them.",
+			Examples: newBreakingExamples(
+				`package foo.v1;`,
+				`package bar.v1;`,
+			),
+		},
+		"FILE_SAME_PHP_CLASS_PREFIX":       newFileOptionCheckerDoc(`option php_class_prefix = "Foo";`, `option php_class_prefix = "Bar";`),
+		"FILE_SAME_PHP_METADATA_NAMESPACE": newFileOptionCheckerDoc(`option php_metadata_namespace = "Foo\\V1\\Metadata";`, `option php_metadata_namespace = "Foo\\Metadata";`),
+		"FILE_SAME_PHP_NAMESPACE":          newFileOptionCheckerDoc(`option php_namespace = "Foo\\V1";`, `option php_namespace = "Foo";`),
+		"FILE_SAME_RUBY_PACKAGE":           newFileOptionCheckerDoc(`option ruby_package = "Foo::V1";`, `option ruby_package = "Foo";`),
+		"FILE_SAME_SWIFT_PREFIX":           newFileOptionCheckerDoc(`option swift_prefix = "FooV1";`, `option swift_prefix = "Foo";`),
+		"FILE_SAME_OPTIMIZE_FOR":           newFileOptionCheckerDoc(`option optimize_for = SPEED;`, `option optimize_for = LITE_RUNTIME;`),
+		"FILE_SAME_CC_GENERIC_SERVICES":    newFileOptionCheckerDoc(`option cc_generic_services = true;`, `option cc_generic_services = false;`),
+		"FILE_SAME_JAVA_GENERIC_SERVICES":  newFileOptionCheckerDoc(`option java_generic_services = true;`, `option java_generic_services = false;`),
+		"FILE_SAME_PY_GENERIC_SERVICES":    newFileOptionCheckerDoc(`option py_generic_services = true;`, `option py_generic_services = false;`),
+		"FILE_SAME_PHP_GENERIC_SERVICES":   newFileOptionCheckerDoc(`option php_generic_services = true;`, `option php_generic_services = false;`),
+		"FILE_SAME_CC_ENABLE_ARENAS":       newFileOptionCheckerDoc(`option cc_enable_arenas = true;`, `option cc_enable_arenas = false;`),
+		"FILE_SAME_SYNTAX": {
+			Description: "Files must have the same syntax, that is, proto2 or proto3.",
+			Rationale:   "The syntax changes the semantics of fields, such as whether field presence is tracked, which changes the generated code.",
+			Examples: newBreakingExamples(
+				`syntax = "proto2";`,
+				`syntax = "proto3";`,
+			),
+		},
+		"MESSAGE_NO_DELETE": {
+			Description: "Messages, including nested messages, must not be deleted from a file.",
+			Rationale:   newDeleteRationale("message"),
+			Examples: newBreakingExamples(
+				`message Foo {}`,
+				``,
+			),
+		},
+		"MESSAGE_NO_REMOVE_STANDARD_DESCRIPTOR_ACCESSOR": {
+			Description: "Messages must not change the no_standard_descriptor_accessor option from false or unset to true.",
+			Rationale:   "Setting the option removes the generated descriptor accessor, which breaks code that uses it.",
+			Examples: newBreakingExamples(
+				`message Foo {}`,
+				`message Foo {
+  option no_standard_descriptor_accessor = true;
+}`,
+			),
+		},
+		"MESSAGE_SAME_CUSTOM_OPTIONS": {
+			Description: "Messages must have the same values for the configured custom options, including whether they are set.",
+			Rationale:   customOptionsRationale,
+			Examples: newBreakingExamples(
+				`message Foo {
+  option deprecated = true;
+}`,
+				`message Foo {}`,
+			),
+		},
+		"MESSAGE_SAME_MESSAGE_SET_WIRE_FORMAT": {
+			Description: "Messages must have the same value for the message_set_wire_format option, including whether it is set.",
+			Rationale:   wireRationale,
+			Examples: newBreakingExamples(
+				`message Foo {
+  extensions 4 to max;
+}`,
+				`message Foo {
+  option message_set_wire_format = true;
+  extensions 4 to max;
+}`,
+			),
+		},
+		"ONEOF_NO_DELETE": {
+			Description: "Oneofs must not be deleted from a message.",
+			Rationale:   newDeleteRationale("oneof"),
+			Examples: newBreakingExamples(
+				`message Foo {
+  oneof value {
+    string name = 1;
+  }
+}`,
+				`message Foo {
+  string name = 1;
+}`,
+			),
+		},
+		"PACKAGE_ENUM_NO_DELETE": {
+			Description: "Enums, including nested enums, must not be deleted from a package. Enums may move between files within the package.",
+			Rationale:   newDeleteRationale("enum"),
+		},
+		"PACKAGE_MESSAGE_NO_DELETE": {
+			Description: "Messages, including nested messages, must not be deleted from a package. Messages may move between files within the package.",
+			Rationale:   newDeleteRationale("message"),
+		},
+		"PACKAGE_NO_DELETE": {
+			Description: "Packages must not be deleted, that is, at least one file must still declare each package.",
+			Rationale:   newDeleteRationale("package"),
+		},
+		"PACKAGE_SERVICE_NO_DELETE": {
+			Description: "Services must not be deleted from a package. Services may move between files within the package.",
+			Rationale:   newDeleteRationale("service"),
+		},
+		"RESERVED_ENUM_NO_DELETE": {
+			Description: "Reserved ranges and names must not be deleted from an enum.",
+			Rationale:   "Deleting a reservation allows the number or name to be reused with a different meaning, which old clients and servers misinterpret.",
+			Examples: newBreakingExamples(
+				`enum Foo {
+  reserved 1;
+  FOO_UNSPECIFIED = 0;
+}`,
+				`enum Foo {
+  FOO_UNSPECIFIED = 0;
+}`,
+			),
+		},
+		"RESERVED_ENUM_NO_REUSE": {
+			Description: "Enum values must not use numbers or names that were reserved in the previous version of the enum.",
+			Rationale:   "Reserved numbers and names belonged to deleted enum values, so reusing them gives them a different meaning, which old clients and servers misinterpret.",
+			Examples: newBreakingExamples(
+				`enum Foo {
+  reserved 1;
+  FOO_UNSPECIFIED = 0;
+}`,
+				`enum Foo {
+  FOO_UNSPECIFIED = 0;
+  FOO_TWO = 1;
+}`,
+			),
+		},
+		"RESERVED_MESSAGE_NO_DELETE": {
+			Description: "Reserved ranges and names must not be deleted from a message.",
+			Rationale:   "Deleting a reservation allows the number or name to be reused with a different type, which old clients and servers misinterpret.",
+			Examples: newBreakingExamples(
+				`message Foo {
+  reserved 1;
+  reserved "name";
+}`,
+				`message Foo {
+  reserved 1;
+}`,
+			),
+		},
+		"RESERVED_MESSAGE_NO_REUSE": {
+			Description: "Fields must not use numbers or names that were reserved in the previous version of the message.",
+			Rationale:   "Reserved numbers and names belonged to deleted fields, so reusing them gives them a different type, which old clients and servers misinterpret.",
+			Examples: newBreakingExamples(
+				`message Foo {
+  reserved 1;
+}`,
+				`message Foo {
+  int64 id = 1;
+}`,
+			),
+		},
+		"RPC_NO_DELETE": {
+			Description: "RPCs must not be deleted from a service.",
+			Rationale:   newDeleteRationale("RPC") + "\nClients calling the RPC receive an unimplemented error.",
+			Examples: newBreakingExamples(
+				`service FooService {
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse);
+}`,
+				`service FooService {}`,
+			),
+		},
+		"RPC_SAME_CLIENT_STREAMING": {
+			Description: "RPCs must remain client streaming, or must remain not client streaming.",
+			Rationale:   rpcRationale,
+			Examples: newBreakingExamples(
+				`rpc Upload(UploadRequest) returns (UploadResponse);`,
+				`rpc Upload(stream UploadRequest) returns (UploadResponse);`,
+			),
+		},
+		"RPC_SAME_CUSTOM_OPTIONS": {
+			Description: "RPCs must have the same values for the configured custom options, including whether they are set.",
+			Rationale:   customOptionsRationale,
+			Examples: newBreakingExamples(
+				`rpc GetFoo(GetFooRequest) returns (GetFooResponse) {
+  option (google.api.http).get = "/v1/foos/{id}";
+}`,
+				`rpc GetFoo(GetFooRequest) returns (GetFooResponse) {
+  option (google.api.http).get = "/v1/foo/{id}";
+}`,
+			),
+		},
+		"RPC_SAME_IDEMPOTENCY_LEVEL": {
+			Description: "RPCs must have the same value for the idempotency_level option, including whether it is set.",
+			Rationale:   "Some RPC frameworks use the idempotency level to decide whether to retry requests or to allow HTTP GET requests.",
+			Examples: newBreakingExamples(
+				`rpc GetFoo(GetFooRequest) returns (GetFooResponse) {
+  option idempotency_level = NO_SIDE_EFFECTS;
+}`,
+				`rpc GetFoo(GetFooRequest) returns (GetFooResponse);`,
+			),
+		},
+		"RPC_SAME_REQUEST_TYPE": {
+			Description: "RPCs must have the same fully-qualified request type.",
+			Rationale:   rpcRationale,
+			Examples: newBreakingExamples(
+				`rpc GetFoo(GetFooRequest) returns (GetFooResponse);`,
+				`rpc GetFoo(GetBarRequest) returns (GetFooResponse);`,
+			),
+		},
+		"RPC_SAME_RESPONSE_TYPE": {
+			Description: "RPCs must have the same fully-qualified response type.",
+			Rationale:   rpcRationale,
+			Examples: newBreakingExamples(
+				`rpc GetFoo(GetFooRequest) returns (GetFooResponse);`,
+				`rpc GetFoo(GetFooRequest) returns (GetBarResponse);`,
+			),
+		},
+		"RPC_SAME_SERVER_STREAMING": {
+			Description: "RPCs must remain server streaming, or must remain not server streaming.",
+			Rationale:   rpcRationale,
+			Examples: newBreakingExamples(
+				`rpc Download(DownloadRequest) returns (DownloadResponse);`,
+				`rpc Download(DownloadRequest) returns (stream DownloadResponse);`,
+			),
+		},
+		"SERVICE_NO_DELETE": {
+			Description: "Services must not be deleted from a file.",
+			Rationale:   newDeleteRationale("service") + "\nClients calling the service receive an unimplemented error.",
+			Examples: newBreakingExamples(
+				`service FooService {}`,
+				``,
+			),
+		},
+	}
+)
+
+func newDeleteRationale(elementName string) string {
+	return fmt.Sprintf("Generated code that references the deleted %s no longer compiles.", elementName)
+}
+
+// newBreakingExamples returns examples for content before and after a breaking change.
+//
+// If afterContent is empty, the content was deleted.
+func newBreakingExamples(beforeContent string, afterContent string) []*bufcheck.CheckerExampleDoc {
+	examples := []*bufcheck.CheckerExampleDoc{
+		{
+			Description: "This is a breaking change from:",
+			Content:     beforeContent,
+		},
+	}
+	if afterContent == "" {
+		examples[0].Description = "Deleting this is a breaking change:"
+		return examples
+	}
+	return append(
+		examples,
+		&bufcheck.CheckerExampleDoc{
+			Description: "To:",
+			Content:     afterContent,
+		},
+	)
+}
+
+// newReservedBreakingExamples returns examples for a deletion that is a breaking change
+// unless reservedContent is used instead.
+func newReservedBreakingExamples(beforeContent string, afterContent string, reservedContent string) []*bufcheck.CheckerExampleDoc {
+	return append(
+		newBreakingExamples(beforeContent, afterContent),
+		&bufcheck.CheckerExampleDoc{
+			Description: "This is not a breaking change:",
+			Content:     reservedContent,
+		},
+	)
+}
+
+func newFileOptionCheckerDoc(beforeOption string, afterOption string) *bufcheck.CheckerDoc {
+	return &bufcheck.CheckerDoc{
+		Description: "Files must have the same value for the option, including whether it is set.",
+		Rationale:   fileOptionRationale,
+		Examples:    newBreakingExamples(beforeOption, afterOption),
+	}
+}
@@ -0,0 +1,103 @@
+package bufbreaking
+
+import (
+	"fmt"
+
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+const (
+	// ImpactWire says the change breaks the binary encoding.
+	ImpactWire = "wire"
+	// ImpactJSON says the change breaks the JSON encoding, but not the binary encoding.
+	ImpactJSON = "json"
+	// ImpactSource says the change only breaks generated code.
+	ImpactSource = "source"
+)
+
+// Details are the details of a FileAnnotation produced by a breaking check.
+type Details struct {
+	// Categories are the categories of the checker that produced the FileAnnotation.
+	Categories []string `json:"categories,omitempty" yaml:"categories,omitempty"`
+	// Impact is one of ImpactWire, ImpactJSON, ImpactSource.
+	Impact string `json:"impact,omitempty" yaml:"impact,omitempty"`
+	// FullName is the fully-qualified name of the changed element, if known.
+	//
+	// For deleted elements, this is the element they were deleted from.
+	FullName string `json:"full_name,omitempty" yaml:"full_name,omitempty"`
+}
+
+// GetDetails returns the Details for each FileAnnotation.
+//
+// The FileAnnotations must be the result of a breaking check with the config
+// against the image, and must use the image file paths of the image, that is
+// FixFileAnnotationPaths must not have been called. The full names are only set
+// if the image includes source info.
+func GetDetails(
+	config *Config,
+	image *imagev1beta1.Image,
+	fileAnnotations []*filev1beta1.FileAnnotation,
+) ([]*Details, error) {
+	getDetails := NewGetDetailsFunc(config, image)
+	details := make([]*Details, len(fileAnnotations))
+	for i, fileAnnotation := range fileAnnotations {
+		iDetails, err := getDetails(fileAnnotation)
+		if err != nil {
+			return nil, err
+		}
+		details[i] = iDetails
+	}
+	return details, nil
+}
+
+// NewGetDetailsFunc returns a function that returns the Details of a FileAnnotation.
+//
+// This is the same as GetDetails for a single FileAnnotation, for FileAnnotations
+// that are not all known up front.
+func NewGetDetailsFunc(
+	config *Config,
+	image *imagev1beta1.Image,
+) func(*filev1beta1.FileAnnotation) (*Details, error) {
+	idToCategories := make(map[string][]string, len(config.Checkers))
+	for _, checker := range config.Checkers {
+		idToCategories[checker.ID()] = checker.Categories()
+	}
+	pathToFile := make(map[string]*descriptor.FileDescriptorProto, len(image.GetFile()))
+	for _, file := range image.GetFile() {
+		pathToFile[file.GetName()] = file
+	}
+	return func(fileAnnotation *filev1beta1.FileAnnotation) (*Details, error) {
+		categories, ok := idToCategories[fileAnnotation.GetType()]
+		if !ok {
+			return nil, fmt.Errorf("unknown checker id: %q", fileAnnotation.GetType())
+		}
+		details := &Details{
+			Categories: categories,
+			Impact:     getImpact(categories),
+		}
+		if file, ok := pathToFile[fileAnnotation.GetPath()]; ok && fileAnnotation.GetStartLine() > 0 {
+			details.FullName = getFullNameAtPosition(
+				file,
+				int32(fileAnnotation.GetStartLine())-1,
+				int32(fileAnnotation.GetStartColumn())-1,
+			)
+		}
+		return details, nil
+	}
+}
+
+// getImpact returns the impact of a checker with the categories.
+func getImpact(categories []string) string {
+	impact := ImpactSource
+	for _, category := range categories {
+		switch category {
+		case "WIRE":
+			return ImpactWire
+		case "WIRE_JSON":
+			impact = ImpactJSON
+		}
+	}
+	return impact
+}
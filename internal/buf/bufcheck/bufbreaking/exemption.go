@@ -0,0 +1,254 @@
+package bufbreaking
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/bufbuild/buf/internal/pkg/util/utilencoding"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// exemptionExpiresLayout is the layout of Exemption.Expires.
+const exemptionExpiresLayout = "2006-01-02"
+
+// Exemptions are time-boxed exemptions for specific breaking changes.
+//
+// Violations that are exempted are warnings until the exemption expires,
+// after which they are errors again.
+type Exemptions struct {
+	Exemptions []*Exemption `json:"exemptions,omitempty" yaml:"exemptions,omitempty"`
+}
+
+// Exemption exempts the violations of a checker for an element until a date.
+type Exemption struct {
+	// ID is the checker ID.
+	ID string `json:"id,omitempty" yaml:"id,omitempty"`
+	// Name is the fully-qualified name of the element the violation is reported on,
+	// such as foo.v1.Bar for a message or foo.v1.Bar.baz for a field.
+	//
+	// Violations for deleted elements are reported on their parent, for example a
+	// deleted field is reported on its message. Enum values use the protobuf scoping
+	// rules, that is foo.v1.BAR_ONE instead of foo.v1.Bar.BAR_ONE.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Expires is the last day the exemption applies in UTC, in the form YYYY-MM-DD.
+	Expires string `json:"expires,omitempty" yaml:"expires,omitempty"`
+	// Reason is an optional reason for the exemption.
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+
+	expiresTime time.Time
+}
+
+// ParseExemptions parses and validates the JSON or YAML Exemptions data.
+func ParseExemptions(data []byte) (*Exemptions, error) {
+	exemptions := &Exemptions{}
+	if err := utilencoding.UnmarshalJSONOrYAMLStrict(data, exemptions); err != nil {
+		return nil, err
+	}
+	for i, exemption := range exemptions.Exemptions {
+		if exemption == nil {
+			return nil, fmt.Errorf("exemption %d is empty", i+1)
+		}
+		if exemption.ID == "" {
+			return nil, fmt.Errorf("exemption %d has no id", i+1)
+		}
+		if _, ok := v1IDToCategories[exemption.ID]; !ok {
+			return nil, fmt.Errorf("exemption %d has unknown id %q", i+1, exemption.ID)
+		}
+		if exemption.Name == "" {
+			return nil, fmt.Errorf("exemption %d has no name", i+1)
+		}
+		if exemption.Expires == "" {
+			return nil, fmt.Errorf("exemption %d has no expires", i+1)
+		}
+		expiresTime, err := time.Parse(exemptionExpiresLayout, exemption.Expires)
+		if err != nil {
+			return nil, fmt.Errorf("exemption %d has invalid expires %q, must be in the form YYYY-MM-DD", i+1, exemption.Expires)
+		}
+		exemption.expiresTime = expiresTime
+	}
+	return exemptions, nil
+}
+
+// ShouldFail returns true if any of the FileAnnotations are not exempted at the given time.
+//
+// A FileAnnotation is exempted if there is an Exemption with the same checker ID and the
+// fully-qualified name of the element at its location that has not expired. FileAnnotations
+// without a location, such as deleted files and packages, cannot be exempted.
+//
+// The FileAnnotations must use the image file paths of the image, that is
+// FixFileAnnotationPaths must not have been called. The image must include source info.
+// If the Exemptions are nil, this returns true if there are any FileAnnotations.
+func (e *Exemptions) ShouldFail(
+	image *imagev1beta1.Image,
+	fileAnnotations []*filev1beta1.FileAnnotation,
+	now time.Time,
+) (bool, error) {
+	if len(fileAnnotations) == 0 {
+		return false, nil
+	}
+	if e == nil {
+		return true, nil
+	}
+	if image == nil {
+		return false, errors.New("image is nil")
+	}
+	pathToFile := make(map[string]*descriptor.FileDescriptorProto, len(image.GetFile()))
+	for _, file := range image.GetFile() {
+		pathToFile[file.GetName()] = file
+	}
+	for _, fileAnnotation := range fileAnnotations {
+		file, ok := pathToFile[fileAnnotation.GetPath()]
+		if !ok || fileAnnotation.GetStartLine() == 0 {
+			return true, nil
+		}
+		name := getFullNameAtPosition(file, int32(fileAnnotation.GetStartLine())-1, int32(fileAnnotation.GetStartColumn())-1)
+		if name == "" || !e.isExempt(fileAnnotation.GetType(), name, now) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (e *Exemptions) isExempt(id string, name string, now time.Time) bool {
+	for _, exemption := range e.Exemptions {
+		if exemption.ID != id || exemption.Name != name {
+			continue
+		}
+		// the exemption applies through the entire expiry day
+		if now.UTC().Before(exemption.expiresTime.AddDate(0, 0, 1)) {
+			return true
+		}
+	}
+	return false
+}
+
+// getFullNameAtPosition returns the fully-qualified name of the innermost element
+// whose span contains the zero-based line and column.
+//
+// Returns empty if there is no such element.
+func getFullNameAtPosition(file *descriptor.FileDescriptorProto, line int32, column int32) string {
+	var innermostPath []int32
+	for _, location := range file.GetSourceCodeInfo().GetLocation() {
+		if len(location.GetPath()) <= len(innermostPath) {
+			continue
+		}
+		if spanContains(location.GetSpan(), line, column) {
+			innermostPath = location.GetPath()
+		}
+	}
+	return getFullNameForPath(file, innermostPath)
+}
+
+// spanContains returns true if the span contains the zero-based line and column.
+//
+// Spans are either [startLine, startColumn, endLine, endColumn] or
+// [startLine, startColumn, endColumn], with an exclusive end.
+func spanContains(span []int32, line int32, column int32) bool {
+	var startLine, startColumn, endLine, endColumn int32
+	switch len(span) {
+	case 3:
+		startLine, startColumn, endLine, endColumn = span[0], span[1], span[0], span[2]
+	case 4:
+		startLine, startColumn, endLine, endColumn = span[0], span[1], span[2], span[3]
+	default:
+		return false
+	}
+	if line < startLine || (line == startLine && column < startColumn) {
+		return false
+	}
+	if line > endLine || (line == endLine && column >= endColumn) {
+		return false
+	}
+	return true
+}
+
+// getFullNameForPath returns the fully-qualified name of the innermost named element
+// within the source code info path.
+//
+// See https://github.com/protocolbuffers/protobuf/blob/master/src/google/protobuf/descriptor.proto
+// for the field numbers. Returns empty if the path does not start with a named element.
+func getFullNameForPath(file *descriptor.FileDescriptorProto, path []int32) string {
+	scope := file.GetPackage()
+	if len(path) < 2 {
+		return ""
+	}
+	index := path[1]
+	switch path[0] {
+	case 4:
+		if index < 0 || int(index) >= len(file.GetMessageType()) {
+			return ""
+		}
+		return getMessageFullNameForPath(file.GetMessageType()[index], scope, path[2:])
+	case 5:
+		if index < 0 || int(index) >= len(file.GetEnumType()) {
+			return ""
+		}
+		return getEnumFullNameForPath(file.GetEnumType()[index], scope, path[2:])
+	case 6:
+		if index < 0 || int(index) >= len(file.GetService()) {
+			return ""
+		}
+		service := file.GetService()[index]
+		serviceName := joinFullName(scope, service.GetName())
+		if len(path) >= 4 && path[2] == 2 && path[3] >= 0 && int(path[3]) < len(service.GetMethod()) {
+			return joinFullName(serviceName, service.GetMethod()[path[3]].GetName())
+		}
+		return serviceName
+	case 7:
+		if index < 0 || int(index) >= len(file.GetExtension()) {
+			return ""
+		}
+		return joinFullName(scope, file.GetExtension()[index].GetName())
+	default:
+		return ""
+	}
+}
+
+func getMessageFullNameForPath(message *descriptor.DescriptorProto, scope string, path []int32) string {
+	messageName := joinFullName(scope, message.GetName())
+	if len(path) < 2 {
+		return messageName
+	}
+	index := path[1]
+	switch path[0] {
+	case 2:
+		if index >= 0 && int(index) < len(message.GetField()) {
+			return joinFullName(messageName, message.GetField()[index].GetName())
+		}
+	case 3:
+		if index >= 0 && int(index) < len(message.GetNestedType()) {
+			return getMessageFullNameForPath(message.GetNestedType()[index], messageName, path[2:])
+		}
+	case 4:
+		if index >= 0 && int(index) < len(message.GetEnumType()) {
+			return getEnumFullNameForPath(message.GetEnumType()[index], messageName, path[2:])
+		}
+	case 6:
+		if index >= 0 && int(index) < len(message.GetExtension()) {
+			return joinFullName(messageName, message.GetExtension()[index].GetName())
+		}
+	case 8:
+		if index >= 0 && int(index) < len(message.GetOneofDecl()) {
+			return joinFullName(messageName, message.GetOneofDecl()[index].GetName())
+		}
+	}
+	return messageName
+}
+
+func getEnumFullNameForPath(enum *descriptor.EnumDescriptorProto, scope string, path []int32) string {
+	if len(path) >= 2 && path[0] == 2 && path[1] >= 0 && int(path[1]) < len(enum.GetValue()) {
+		// enum values are siblings of their enum
+		return joinFullName(scope, enum.GetValue()[path[1]].GetName())
+	}
+	return joinFullName(scope, enum.GetName())
+}
+
+func joinFullName(scope string, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
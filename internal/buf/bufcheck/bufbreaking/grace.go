@@ -0,0 +1,234 @@
+package bufbreaking
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/bufbuild/buf/internal/pkg/ext/extfile"
+	"github.com/bufbuild/buf/internal/pkg/protodesc"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// enumValueDeleteIDs are the IDs of the checkers that can have a grace for deleting
+// deprecated enum values.
+var enumValueDeleteIDs = []string{
+	"ENUM_VALUE_NO_DELETE",
+	"ENUM_VALUE_NO_DELETE_UNLESS_NAME_RESERVED",
+	"ENUM_VALUE_NO_DELETE_UNLESS_NUMBER_RESERVED",
+	"ENUM_VALUE_NO_DELETE_UNLESS_RESERVED",
+}
+
+func (h *handler) ApplyEnumValueDeleteGrace(
+	ctx context.Context,
+	breakingConfig *Config,
+	previousManifest *Manifest,
+	previousImage *imagev1beta1.Image,
+	image *imagev1beta1.Image,
+	fileAnnotations []*filev1beta1.FileAnnotation,
+) ([]*filev1beta1.FileAnnotation, error) {
+	if previousManifest == nil || len(previousManifest.DeprecatedEnumValues) == 0 {
+		return fileAnnotations, nil
+	}
+	releasesToCheckers := make(map[int][]Checker)
+	for _, checker := range breakingConfig.Checkers {
+		if releases, ok := breakingConfig.EnumValueDeleteGraceIDToReleases[checker.ID()]; ok {
+			releasesToCheckers[releases] = append(releasesToCheckers[releases], checker)
+		}
+	}
+	if len(releasesToCheckers) == 0 {
+		return fileAnnotations, nil
+	}
+	nameToReleases := make(map[string]int, len(previousManifest.DeprecatedEnumValues))
+	for _, deprecatedEnumValue := range previousManifest.DeprecatedEnumValues {
+		nameToReleases[deprecatedEnumValue.Name] = deprecatedEnumValue.Releases
+	}
+	files, err := protodesc.NewFilesUnstable(ctx, image.GetFile()...)
+	if err != nil {
+		return nil, err
+	}
+	gracedIDs := make(map[string]struct{})
+	var gracedFileAnnotations []*filev1beta1.FileAnnotation
+	for releases, checkers := range releasesToCheckers {
+		previousFiles, err := protodesc.NewFilesUnstable(
+			ctx,
+			getFilesWithoutDeletedEnumValues(previousImage, image, nameToReleases, releases)...,
+		)
+		if err != nil {
+			return nil, err
+		}
+		checkerFileAnnotations, err := h.breakingRunner.Check(
+			ctx,
+			&Config{
+				Checkers:            checkers,
+				IgnoreIDToRootPaths: breakingConfig.IgnoreIDToRootPaths,
+				IgnoreRootPaths:     breakingConfig.IgnoreRootPaths,
+			},
+			previousFiles,
+			files,
+		)
+		if err != nil {
+			return nil, err
+		}
+		for _, checker := range checkers {
+			gracedIDs[checker.ID()] = struct{}{}
+		}
+		gracedFileAnnotations = append(gracedFileAnnotations, checkerFileAnnotations...)
+	}
+	for _, fileAnnotation := range fileAnnotations {
+		if _, ok := gracedIDs[fileAnnotation.GetType()]; !ok {
+			gracedFileAnnotations = append(gracedFileAnnotations, fileAnnotation)
+		}
+	}
+	extfile.SortFileAnnotations(gracedFileAnnotations)
+	return gracedFileAnnotations, nil
+}
+
+// getEnumValueDeleteGraceIDToReleases resolves the IDs and categories to the
+// IDs of the enum value deletion checkers.
+func getEnumValueDeleteGraceIDToReleases(idOrCategoryToReleases map[string]int) (map[string]int, error) {
+	if len(idOrCategoryToReleases) == 0 {
+		return nil, nil
+	}
+	idToReleases := make(map[string]int)
+	categoryIDToReleases := make(map[string]int)
+	for idOrCategory, releases := range idOrCategoryToReleases {
+		if releases < 0 {
+			return nil, fmt.Errorf("enum value delete grace for %q must not be negative: %d", idOrCategory, releases)
+		}
+		found := false
+		for _, id := range enumValueDeleteIDs {
+			if idOrCategory == id {
+				idToReleases[id] = releases
+				found = true
+				continue
+			}
+			for _, category := range v1IDToCategories[id] {
+				if idOrCategory == category {
+					if existingReleases, ok := categoryIDToReleases[id]; !ok || releases > existingReleases {
+						categoryIDToReleases[id] = releases
+					}
+					found = true
+				}
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%q is not an enum value deletion checker ID or a category containing one", idOrCategory)
+		}
+	}
+	for id, releases := range categoryIDToReleases {
+		if _, ok := idToReleases[id]; !ok {
+			idToReleases[id] = releases
+		}
+	}
+	return idToReleases, nil
+}
+
+// getFilesWithoutDeletedEnumValues returns the files of the previous image without
+// the enum values that were deprecated for at least the given number of releases
+// and whose numbers are no longer in the enum of the same name in the image.
+//
+// The returned files that had enum values removed do not have source info.
+func getFilesWithoutDeletedEnumValues(
+	previousImage *imagev1beta1.Image,
+	image *imagev1beta1.Image,
+	nameToReleases map[string]int,
+	releases int,
+) []*descriptor.FileDescriptorProto {
+	enumNameToNumbers := make(map[string]map[int32]struct{})
+	for _, file := range image.GetFile() {
+		forEachEnum(file, func(enumName string, enum *descriptor.EnumDescriptorProto) {
+			numbers := make(map[int32]struct{}, len(enum.GetValue()))
+			for _, value := range enum.GetValue() {
+				numbers[value.GetNumber()] = struct{}{}
+			}
+			enumNameToNumbers[enumName] = numbers
+		})
+	}
+	isDeletedAfterGrace := func(enumName string, value *descriptor.EnumValueDescriptorProto) bool {
+		valueReleases, ok := nameToReleases[enumName+"."+value.GetName()]
+		if !ok || valueReleases < releases {
+			return false
+		}
+		numbers, ok := enumNameToNumbers[enumName]
+		if !ok {
+			// the deletion of the enum is checked by ENUM_NO_DELETE
+			return false
+		}
+		_, ok = numbers[value.GetNumber()]
+		return !ok
+	}
+	previousFiles := make([]*descriptor.FileDescriptorProto, len(previousImage.GetFile()))
+	for i, file := range previousImage.GetFile() {
+		previousFiles[i] = file
+		hasDeletedAfterGrace := false
+		forEachEnum(file, func(enumName string, enum *descriptor.EnumDescriptorProto) {
+			for _, value := range enum.GetValue() {
+				if isDeletedAfterGrace(enumName, value) {
+					hasDeletedAfterGrace = true
+				}
+			}
+		})
+		if !hasDeletedAfterGrace {
+			continue
+		}
+		newFile := proto.Clone(file).(*descriptor.FileDescriptorProto)
+		// value indexes change, so the source info is no longer valid
+		newFile.SourceCodeInfo = nil
+		forEachEnum(newFile, func(enumName string, enum *descriptor.EnumDescriptorProto) {
+			values := make([]*descriptor.EnumValueDescriptorProto, 0, len(enum.GetValue()))
+			for _, value := range enum.GetValue() {
+				if !isDeletedAfterGrace(enumName, value) {
+					values = append(values, value)
+				}
+			}
+			enum.Value = values
+		})
+		previousFiles[i] = newFile
+	}
+	return previousFiles
+}
+
+// getDeprecatedEnumValueNames returns the sorted names of the deprecated enum
+// values in the image, in the form enum full name, a dot, and the value name.
+func getDeprecatedEnumValueNames(image *imagev1beta1.Image) []string {
+	nameMap := make(map[string]struct{})
+	for _, file := range image.GetFile() {
+		forEachEnum(file, func(enumName string, enum *descriptor.EnumDescriptorProto) {
+			for _, value := range enum.GetValue() {
+				if value.GetOptions().GetDeprecated() {
+					nameMap[enumName+"."+value.GetName()] = struct{}{}
+				}
+			}
+		})
+	}
+	names := make([]string, 0, len(nameMap))
+	for name := range nameMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// forEachEnum calls f for each enum in the file, including nested enums,
+// with the full name of the enum.
+func forEachEnum(file *descriptor.FileDescriptorProto, f func(string, *descriptor.EnumDescriptorProto)) {
+	for _, enum := range file.GetEnumType() {
+		f(joinFullName(file.GetPackage(), enum.GetName()), enum)
+	}
+	for _, message := range file.GetMessageType() {
+		forEachMessageEnum(joinFullName(file.GetPackage(), message.GetName()), message, f)
+	}
+}
+
+func forEachMessageEnum(messageName string, message *descriptor.DescriptorProto, f func(string, *descriptor.EnumDescriptorProto)) {
+	for _, enum := range message.GetEnumType() {
+		f(joinFullName(messageName, enum.GetName()), enum)
+	}
+	for _, nestedMessage := range message.GetNestedType() {
+		forEachMessageEnum(joinFullName(messageName, nestedMessage.GetName()), nestedMessage, f)
+	}
+}
@@ -40,3 +40,49 @@ func (h *handler) BreakingCheck(
 	}
 	return h.breakingRunner.Check(ctx, breakingConfig, previousFiles, files)
 }
+
+func (h *handler) BreakingCheckStream(
+	ctx context.Context,
+	breakingConfig *Config,
+	previousImage *imagev1beta1.Image,
+	image *imagev1beta1.Image,
+	fileAnnotationFunc func(*filev1beta1.FileAnnotation) error,
+) error {
+	previousFiles, err := protodesc.NewFilesUnstable(ctx, previousImage.GetFile()...)
+	if err != nil {
+		return err
+	}
+	files, err := protodesc.NewFilesUnstable(ctx, image.GetFile()...)
+	if err != nil {
+		return err
+	}
+	return h.breakingRunner.CheckStream(ctx, breakingConfig, previousFiles, files, fileAnnotationFunc)
+}
+
+func (h *handler) BreakingCheckWithReport(
+	ctx context.Context,
+	breakingConfig *Config,
+	previousImage *imagev1beta1.Image,
+	image *imagev1beta1.Image,
+	previousReport *Report,
+) ([]*filev1beta1.FileAnnotation, *Report, error) {
+	reportState, err := newReportState(breakingConfig, previousImage, image, previousReport)
+	if err != nil {
+		return nil, nil, err
+	}
+	h.logger.Debug("skipping_unchanged_packages", zap.Int("num_packages", len(reportState.unchangedPackages)))
+	previousFiles, err := protodesc.NewFilesUnstable(ctx, reportState.filterFiles(previousImage.GetFile())...)
+	if err != nil {
+		return nil, nil, err
+	}
+	files, err := protodesc.NewFilesUnstable(ctx, reportState.filterFiles(image.GetFile())...)
+	if err != nil {
+		return nil, nil, err
+	}
+	fileAnnotations, err := h.breakingRunner.Check(ctx, breakingConfig, previousFiles, files)
+	if err != nil {
+		return nil, nil, err
+	}
+	fileAnnotations, report := reportState.newReport(fileAnnotations)
+	return fileAnnotations, report, nil
+}
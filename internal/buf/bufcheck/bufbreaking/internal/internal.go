@@ -1,13 +1,14 @@
 package internal
 
 import (
-	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
 	"github.com/bufbuild/buf/internal/pkg/protodesc"
 	"github.com/bufbuild/buf/internal/pkg/util/utilstring"
+	protobufdescriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
 )
 
 // CheckEnumNoDelete is a check function.
@@ -35,6 +36,18 @@ func checkEnumNoDelete(add addFunc, previousFile protodesc.File, file protodesc.
 	return nil
 }
 
+// CheckEnumSameAllowAlias is a check function.
+var CheckEnumSameAllowAlias = newEnumPairCheckFunc(checkEnumSameAllowAlias)
+
+func checkEnumSameAllowAlias(add addFunc, previousEnum protodesc.Enum, enum protodesc.Enum) error {
+	previous := strconv.FormatBool(previousEnum.AllowAlias())
+	current := strconv.FormatBool(enum.AllowAlias())
+	if previous != current {
+		add(enum, withBackupLocation(enum.AllowAliasLocation(), enum.Location()), `Enum option "allow_alias" changed from %q to %q.`, previous, current)
+	}
+	return nil
+}
+
 // CheckEnumValueNoDelete is a check function.
 var CheckEnumValueNoDelete = newEnumPairCheckFunc(checkEnumValueNoDelete)
 
@@ -49,6 +62,13 @@ func checkEnumValueNoDeleteUnlessNumberReserved(add addFunc, previousEnum protod
 	return checkEnumValueNoDeleteWithRules(add, previousEnum, enum, true, false)
 }
 
+// CheckEnumValueNoDeleteUnlessReserved is a check function.
+var CheckEnumValueNoDeleteUnlessReserved = newEnumPairCheckFunc(checkEnumValueNoDeleteUnlessReserved)
+
+func checkEnumValueNoDeleteUnlessReserved(add addFunc, previousEnum protodesc.Enum, enum protodesc.Enum) error {
+	return checkEnumValueNoDeleteWithRules(add, previousEnum, enum, true, true)
+}
+
 // CheckEnumValueNoDeleteUnlessNameReserved is a check function.
 var CheckEnumValueNoDeleteUnlessNameReserved = newEnumPairCheckFunc(checkEnumValueNoDeleteUnlessNameReserved)
 
@@ -68,21 +88,26 @@ func checkEnumValueNoDeleteWithRules(add addFunc, previousEnum protodesc.Enum, e
 	for previousNumber, previousNameToEnumValue := range previousNumberToNameToEnumValue {
 		if _, ok := numberToNameToEnumValue[previousNumber]; !ok {
 			if !isDeletedEnumValueAllowedWithRules(previousNumber, previousNameToEnumValue, enum, allowIfNumberReserved, allowIfNameReserved) {
-				suffix := ""
-				if allowIfNumberReserved && allowIfNameReserved {
-					return errors.New("both allowIfNumberReserved and allowIfNameReserved set")
-				}
-				if allowIfNumberReserved {
-					suffix = fmt.Sprintf(` without reserving the number "%d"`, previousNumber)
+				var unreserved []string
+				if allowIfNumberReserved && !protodesc.NumberInReservedRanges(previousNumber, enum.ReservedRanges()...) {
+					unreserved = append(unreserved, fmt.Sprintf(`the number "%d"`, previousNumber))
 				}
 				if allowIfNameReserved {
-					nameSuffix := ""
-					if len(previousNameToEnumValue) > 1 {
-						nameSuffix = "s"
+					var unreservedNames []string
+					for _, previousName := range getSortedEnumValueNames(previousNameToEnumValue) {
+						if !protodesc.NameInReservedNames(previousName, enum.ReservedNames()...) {
+							unreservedNames = append(unreservedNames, previousName)
+						}
+					}
+					if len(unreservedNames) > 0 {
+						nameSuffix := ""
+						if len(unreservedNames) > 1 {
+							nameSuffix = "s"
+						}
+						unreserved = append(unreserved, fmt.Sprintf(`the name%s %s`, nameSuffix, utilstring.JoinSliceQuoted(unreservedNames, ", ")))
 					}
-					suffix = fmt.Sprintf(` without reserving the name%s %s`, nameSuffix, utilstring.JoinSliceQuoted(getSortedEnumValueNames(previousNameToEnumValue), ", "))
 				}
-				add(enum, enum.Location(), `Previously present enum value "%d" on enum %q was deleted%s.`, previousNumber, enum.Name(), suffix)
+				add(enum, enum.Location(), `Previously present enum value "%d" on enum %q was deleted%s.`, previousNumber, enum.Name(), getUnreservedSuffix(unreserved))
 			}
 		}
 	}
@@ -90,8 +115,11 @@ func checkEnumValueNoDeleteWithRules(add addFunc, previousEnum protodesc.Enum, e
 }
 
 func isDeletedEnumValueAllowedWithRules(previousNumber int, previousNameToEnumValue map[string]protodesc.EnumValue, enum protodesc.Enum, allowIfNumberReserved bool, allowIfNameReserved bool) bool {
-	if allowIfNumberReserved {
-		return protodesc.NumberInReservedRanges(previousNumber, enum.ReservedRanges()...)
+	if !allowIfNumberReserved && !allowIfNameReserved {
+		return false
+	}
+	if allowIfNumberReserved && !protodesc.NumberInReservedRanges(previousNumber, enum.ReservedRanges()...) {
+		return false
 	}
 	if allowIfNameReserved {
 		// if true for all names, then ok
@@ -100,9 +128,8 @@ func isDeletedEnumValueAllowedWithRules(previousNumber int, previousNameToEnumVa
 				return false
 			}
 		}
-		return true
 	}
-	return false
+	return true
 }
 
 // CheckEnumValueSameName is a check function.
@@ -153,6 +180,13 @@ func checkFieldNoDeleteUnlessNumberReserved(add addFunc, previousMessage protode
 	return checkFieldNoDeleteWithRules(add, previousMessage, message, true, false)
 }
 
+// CheckFieldNoDeleteUnlessReserved is a check function.
+var CheckFieldNoDeleteUnlessReserved = newMessagePairCheckFunc(checkFieldNoDeleteUnlessReserved)
+
+func checkFieldNoDeleteUnlessReserved(add addFunc, previousMessage protodesc.Message, message protodesc.Message) error {
+	return checkFieldNoDeleteWithRules(add, previousMessage, message, true, true)
+}
+
 // CheckFieldNoDeleteUnlessNameReserved is a check function.
 var CheckFieldNoDeleteUnlessNameReserved = newMessagePairCheckFunc(checkFieldNoDeleteUnlessNameReserved)
 
@@ -174,17 +208,14 @@ func checkFieldNoDeleteWithRules(add addFunc, previousMessage protodesc.Message,
 			if !isDeletedFieldAllowedWithRules(previousField, message, allowIfNumberReserved, allowIfNameReserved) {
 				// otherwise prints as hex
 				previousNumberString := strconv.FormatInt(int64(previousNumber), 10)
-				suffix := ""
-				if allowIfNumberReserved && allowIfNameReserved {
-					return errors.New("both allowIfNumberReserved and allowIfNameReserved set")
+				var unreserved []string
+				if allowIfNumberReserved && !protodesc.NumberInReservedRanges(previousField.Number(), message.ReservedRanges()...) {
+					unreserved = append(unreserved, fmt.Sprintf(`the number "%d"`, previousField.Number()))
 				}
-				if allowIfNumberReserved {
-					suffix = fmt.Sprintf(` without reserving the number "%d"`, previousField.Number())
+				if allowIfNameReserved && !protodesc.NameInReservedNames(previousField.Name(), message.ReservedNames()...) {
+					unreserved = append(unreserved, fmt.Sprintf(`the name %q`, previousField.Name()))
 				}
-				if allowIfNameReserved {
-					suffix = fmt.Sprintf(` without reserving the name %q`, previousField.Name())
-				}
-				add(message, message.Location(), `Previously present field %q with name %q on message %q was deleted%s.`, previousNumberString, previousField.Name(), message.Name(), suffix)
+				add(message, message.Location(), `Previously present field %q with name %q on message %q was deleted%s.`, previousNumberString, previousField.Name(), message.Name(), getUnreservedSuffix(unreserved))
 			}
 		}
 	}
@@ -192,8 +223,9 @@ func checkFieldNoDeleteWithRules(add addFunc, previousMessage protodesc.Message,
 }
 
 func isDeletedFieldAllowedWithRules(previousField protodesc.Field, message protodesc.Message, allowIfNumberReserved bool, allowIfNameReserved bool) bool {
-	return (allowIfNumberReserved && protodesc.NumberInReservedRanges(previousField.Number(), message.ReservedRanges()...)) ||
-		(allowIfNameReserved && protodesc.NameInReservedNames(previousField.Name(), message.ReservedNames()...))
+	return (allowIfNumberReserved || allowIfNameReserved) &&
+		(!allowIfNumberReserved || protodesc.NumberInReservedRanges(previousField.Number(), message.ReservedRanges()...)) &&
+		(!allowIfNameReserved || protodesc.NameInReservedNames(previousField.Name(), message.ReservedNames()...))
 }
 
 // CheckFieldSameCType is a check function.
@@ -208,6 +240,23 @@ func checkFieldSameCType(add addFunc, previousField protodesc.Field, field proto
 	return nil
 }
 
+// CheckFieldSameCustomOptions is a check function.
+var CheckFieldSameCustomOptions = func(id string, previousFiles []protodesc.File, files []protodesc.File, optionNames []string) ([]*filev1beta1.FileAnnotation, error) {
+	optionNumberToName := getOptionNumberToName(&protobufdescriptor.FieldOptions{}, optionNames, previousFiles, files)
+	return newFieldPairCheckFunc(
+		func(add addFunc, previousField protodesc.Field, field protodesc.Field) error {
+			return checkFieldSameCustomOptions(add, previousField, field, optionNumberToName)
+		},
+	)(id, previousFiles, files)
+}
+
+func checkFieldSameCustomOptions(add addFunc, previousField protodesc.Field, field protodesc.Field, optionNumberToName map[int]string) error {
+	// otherwise prints as hex
+	numberString := strconv.FormatInt(int64(field.Number()), 10)
+	checkSameCustomOptions(add, field, field.Location(), previousField, field, optionNumberToName, fmt.Sprintf(`Field %q with name %q on message %q`, numberString, field.Name(), field.Message().Name()))
+	return nil
+}
+
 // CheckFieldSameJSONName is a check function.
 var CheckFieldSameJSONName = newFieldPairCheckFunc(checkFieldSameJSONName)
 
@@ -373,6 +422,21 @@ func checkFileSameCsharpNamespace(add addFunc, previousFile protodesc.File, file
 	return checkFileSameValue(add, previousFile.CsharpNamespace(), file.CsharpNamespace(), file, file.CsharpNamespaceLocation(), `option "csharp_namespace"`)
 }
 
+// CheckFileSameCustomOptions is a check function.
+var CheckFileSameCustomOptions = func(id string, previousFiles []protodesc.File, files []protodesc.File, optionNames []string) ([]*filev1beta1.FileAnnotation, error) {
+	optionNumberToName := getOptionNumberToName(&protobufdescriptor.FileOptions{}, optionNames, previousFiles, files)
+	return newFilePairCheckFunc(
+		func(add addFunc, previousFile protodesc.File, file protodesc.File) error {
+			return checkFileSameCustomOptions(add, previousFile, file, optionNumberToName)
+		},
+	)(id, previousFiles, files)
+}
+
+func checkFileSameCustomOptions(add addFunc, previousFile protodesc.File, file protodesc.File, optionNumberToName map[int]string) error {
+	checkSameCustomOptions(add, file, nil, previousFile, file, optionNumberToName, "File")
+	return nil
+}
+
 // CheckFileSameGoPackage is a check function.
 var CheckFileSameGoPackage = newFilePairCheckFunc(checkFileSameGoPackage)
 
@@ -546,6 +610,21 @@ func checkMessageNoRemoveStandardDescriptorAccessor(add addFunc, previousMessage
 	return nil
 }
 
+// CheckMessageSameCustomOptions is a check function.
+var CheckMessageSameCustomOptions = func(id string, previousFiles []protodesc.File, files []protodesc.File, optionNames []string) ([]*filev1beta1.FileAnnotation, error) {
+	optionNumberToName := getOptionNumberToName(&protobufdescriptor.MessageOptions{}, optionNames, previousFiles, files)
+	return newMessagePairCheckFunc(
+		func(add addFunc, previousMessage protodesc.Message, message protodesc.Message) error {
+			return checkMessageSameCustomOptions(add, previousMessage, message, optionNumberToName)
+		},
+	)(id, previousFiles, files)
+}
+
+func checkMessageSameCustomOptions(add addFunc, previousMessage protodesc.Message, message protodesc.Message, optionNumberToName map[int]string) error {
+	checkSameCustomOptions(add, message, message.Location(), previousMessage, message, optionNumberToName, fmt.Sprintf(`Message %q`, message.Name()))
+	return nil
+}
+
 // CheckMessageSameMessageSetWireFormat is a check function.
 var CheckMessageSameMessageSetWireFormat = newMessagePairCheckFunc(checkMessageSameMessageSetWireFormat)
 
@@ -769,6 +848,36 @@ func checkReservedMessageNoDelete(add addFunc, previousMessage protodesc.Message
 	return nil
 }
 
+// CheckReservedEnumNoReuse is a check function.
+var CheckReservedEnumNoReuse = newEnumPairCheckFunc(checkReservedEnumNoReuse)
+
+func checkReservedEnumNoReuse(add addFunc, previousEnum protodesc.Enum, enum protodesc.Enum) error {
+	for _, enumValue := range enum.Values() {
+		if protodesc.NumberInReservedRanges(enumValue.Number(), previousEnum.ReservedRanges()...) {
+			add(enumValue, enumValue.NumberLocation(), `Enum value %q on enum %q uses the previously reserved number "%d".`, enumValue.Name(), enum.Name(), enumValue.Number())
+		}
+		if protodesc.NameInReservedNames(enumValue.Name(), previousEnum.ReservedNames()...) {
+			add(enumValue, enumValue.NameLocation(), `Enum value "%d" on enum %q uses the previously reserved name %q.`, enumValue.Number(), enum.Name(), enumValue.Name())
+		}
+	}
+	return nil
+}
+
+// CheckReservedMessageNoReuse is a check function.
+var CheckReservedMessageNoReuse = newMessagePairCheckFunc(checkReservedMessageNoReuse)
+
+func checkReservedMessageNoReuse(add addFunc, previousMessage protodesc.Message, message protodesc.Message) error {
+	for _, field := range message.Fields() {
+		if protodesc.NumberInReservedRanges(field.Number(), previousMessage.ReservedRanges()...) {
+			add(field, field.NumberLocation(), `Field %q on message %q uses the previously reserved number "%d".`, field.Name(), message.Name(), field.Number())
+		}
+		if protodesc.NameInReservedNames(field.Name(), previousMessage.ReservedNames()...) {
+			add(field, field.NameLocation(), `Field "%d" on message %q uses the previously reserved name %q.`, field.Number(), message.Name(), field.Name())
+		}
+	}
+	return nil
+}
+
 // CheckRPCNoDelete is a check function.
 var CheckRPCNoDelete = newServicePairCheckFunc(checkRPCNoDelete)
 
@@ -805,6 +914,21 @@ func checkRPCSameClientStreaming(add addFunc, previousMethod protodesc.Method, m
 	return nil
 }
 
+// CheckRPCSameCustomOptions is a check function.
+var CheckRPCSameCustomOptions = func(id string, previousFiles []protodesc.File, files []protodesc.File, optionNames []string) ([]*filev1beta1.FileAnnotation, error) {
+	optionNumberToName := getOptionNumberToName(&protobufdescriptor.MethodOptions{}, optionNames, previousFiles, files)
+	return newMethodPairCheckFunc(
+		func(add addFunc, previousMethod protodesc.Method, method protodesc.Method) error {
+			return checkRPCSameCustomOptions(add, previousMethod, method, optionNumberToName)
+		},
+	)(id, previousFiles, files)
+}
+
+func checkRPCSameCustomOptions(add addFunc, previousMethod protodesc.Method, method protodesc.Method, optionNumberToName map[int]string) error {
+	checkSameCustomOptions(add, method, method.Location(), previousMethod, method, optionNumberToName, fmt.Sprintf(`RPC %q on service %q`, method.Name(), method.Service().Name()))
+	return nil
+}
+
 // CheckRPCSameIdempotencyLevel is a check function.
 var CheckRPCSameIdempotencyLevel = newMethodPairCheckFunc(checkRPCSameIdempotencyLevel)
 
@@ -1,12 +1,15 @@
 package internal
 
 import (
+	"bytes"
+	"reflect"
 	"sort"
 	"strings"
 
 	"github.com/bufbuild/buf/internal/buf/bufcheck/internal"
 	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
 	"github.com/bufbuild/buf/internal/pkg/protodesc"
+	"github.com/golang/protobuf/proto"
 )
 
 // addFunc adds a FileAnnotation.
@@ -246,3 +249,100 @@ func withBackupLocation(primary protodesc.Location, secondary protodesc.Location
 	}
 	return secondary
 }
+
+// getOptionNumberToName resolves the option names to the field numbers of the
+// options message, such as google.protobuf.FieldOptions.
+//
+// Option names are either standard options such as deprecated, or fully-qualified
+// extensions such as google.api.http, optionally within parentheses. Extensions are
+// resolved within both the previous files and files. Option names that do not
+// resolve to a field of the options message are ignored, as the same option names
+// are used for files, messages, fields, and methods.
+func getOptionNumberToName(
+	options proto.Message,
+	optionNames []string,
+	previousFiles []protodesc.File,
+	files []protodesc.File,
+) map[int]string {
+	extendee := "." + proto.MessageName(options)
+	fullNameToExtension := make(map[string]protodesc.Field)
+	for _, file := range append(append([]protodesc.File{}, previousFiles...), files...) {
+		addFullNameToExtension(fullNameToExtension, extendee, file.Extensions(), file.Messages())
+	}
+	properties := proto.GetProperties(reflect.TypeOf(options).Elem())
+	optionNumberToName := make(map[int]string)
+	for _, optionName := range optionNames {
+		name := strings.TrimPrefix(strings.TrimSuffix(strings.TrimPrefix(optionName, "("), ")"), ".")
+		if extension, ok := fullNameToExtension[name]; ok {
+			optionNumberToName[extension.Number()] = "(" + name + ")"
+			continue
+		}
+		for _, property := range properties.Prop {
+			if property.OrigName != "" && property.OrigName == name {
+				optionNumberToName[property.Tag] = name
+			}
+		}
+	}
+	return optionNumberToName
+}
+
+func addFullNameToExtension(
+	fullNameToExtension map[string]protodesc.Field,
+	extendee string,
+	extensions []protodesc.Field,
+	messages []protodesc.Message,
+) {
+	for _, extension := range extensions {
+		if extension.Extendee() == extendee {
+			fullNameToExtension[extension.FullName()] = extension
+		}
+	}
+	for _, message := range messages {
+		addFullNameToExtension(fullNameToExtension, extendee, message.Extensions(), message.Messages())
+	}
+}
+
+// checkSameCustomOptions checks that the options with the given numbers have the
+// same values, including whether they are set.
+//
+// The values are compared as encoded, so a different encoding of an equal value,
+// such as a reordering of the fields of a message value, is also reported.
+func checkSameCustomOptions(
+	add addFunc,
+	descriptor protodesc.Descriptor,
+	location protodesc.Location,
+	previousOptionsDescriptor protodesc.OptionsDescriptor,
+	optionsDescriptor protodesc.OptionsDescriptor,
+	optionNumberToName map[int]string,
+	prefix string,
+) {
+	numbers := make([]int, 0, len(optionNumberToName))
+	for number := range optionNumberToName {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+	previousOptionNumberToData := previousOptionsDescriptor.OptionNumberToData()
+	optionNumberToData := optionsDescriptor.OptionNumberToData()
+	for _, number := range numbers {
+		name := optionNumberToName[number]
+		previousData, previousOK := previousOptionNumberToData[number]
+		data, ok := optionNumberToData[number]
+		switch {
+		case previousOK && !ok:
+			add(descriptor, location, `%s removed option %q.`, prefix, name)
+		case !previousOK && ok:
+			add(descriptor, withBackupLocation(optionsDescriptor.OptionLocation(number), location), `%s added option %q.`, prefix, name)
+		case previousOK && ok && !bytes.Equal(previousData, data):
+			add(descriptor, withBackupLocation(optionsDescriptor.OptionLocation(number), location), `%s changed the value of option %q.`, prefix, name)
+		}
+	}
+}
+
+// getUnreservedSuffix returns the suffix for a deleted field or enum value
+// given the descriptions of what was not reserved, i.e. `the number "1"`.
+func getUnreservedSuffix(unreserved []string) string {
+	if len(unreserved) == 0 {
+		return ""
+	}
+	return " without reserving " + strings.Join(unreserved, " and ")
+}
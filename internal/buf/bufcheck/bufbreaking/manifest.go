@@ -0,0 +1,146 @@
+package bufbreaking
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	"github.com/bufbuild/buf/internal/buf/ext/extimage"
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/bufbuild/buf/internal/pkg/ext/extfile"
+)
+
+// Manifest records a compatibility stamp per package.
+//
+// A stamp stays the same across runs as long as no breaking changes are detected
+// for the package, and changes when breaking changes are detected. Downstream
+// systems can compare stamps to detect breakage without running the checks.
+//
+// A Manifest also records for how many releases enum values have been deprecated,
+// see Handler.ApplyEnumValueDeleteGrace.
+type Manifest struct {
+	// Packages are the packages sorted by name.
+	Packages []*ManifestPackage `json:"packages,omitempty" yaml:"packages,omitempty"`
+	// DeprecatedEnumValues are the deprecated enum values sorted by name.
+	DeprecatedEnumValues []*ManifestDeprecatedEnumValue `json:"deprecated_enum_values,omitempty" yaml:"deprecated_enum_values,omitempty"`
+}
+
+// ManifestPackage is a package within a Manifest.
+type ManifestPackage struct {
+	Name  string `json:"name,omitempty" yaml:"name,omitempty"`
+	Stamp string `json:"stamp,omitempty" yaml:"stamp,omitempty"`
+	// Version starts at 1 and is incremented each time the stamp changes.
+	Version int `json:"version,omitempty" yaml:"version,omitempty"`
+}
+
+// ManifestDeprecatedEnumValue is a deprecated enum value within a Manifest.
+type ManifestDeprecatedEnumValue struct {
+	// Name is the full name of the enum, a dot, and the name of the value.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Releases is the number of consecutive Manifests the value was deprecated in,
+	// including this one.
+	Releases int `json:"releases,omitempty" yaml:"releases,omitempty"`
+}
+
+// NewManifest returns a new Manifest for the image.
+//
+// The FileAnnotations should be the result of a breaking check of image against
+// previousImage, before FixFileAnnotationPaths is called. Packages with FileAnnotations
+// get a new stamp derived from the stamp in previousManifest. Packages not present in
+// previousManifest get an initial stamp. Packages not present in image are dropped.
+//
+// Deprecated enum values are counted from their count in previousManifest, and
+// enum values that are not deprecated in image are dropped.
+//
+// previousManifest may be nil.
+func NewManifest(
+	previousManifest *Manifest,
+	previousImage *imagev1beta1.Image,
+	image *imagev1beta1.Image,
+	fileAnnotations []*filev1beta1.FileAnnotation,
+) (*Manifest, error) {
+	packageToDigest, err := extimage.ImagePackageDigests(image)
+	if err != nil {
+		return nil, err
+	}
+	filePathToPackage := make(map[string]string)
+	for _, file := range previousImage.GetFile() {
+		filePathToPackage[file.GetName()] = file.GetPackage()
+	}
+	for _, file := range image.GetFile() {
+		filePathToPackage[file.GetName()] = file.GetPackage()
+	}
+	packageToFileAnnotationStrings := make(map[string][]string)
+	for _, fileAnnotation := range fileAnnotations {
+		pkg, ok := filePathToPackage[fileAnnotation.GetPath()]
+		if !ok {
+			continue
+		}
+		packageToFileAnnotationStrings[pkg] = append(
+			packageToFileAnnotationStrings[pkg],
+			fileAnnotation.GetType()+":"+extfile.FileAnnotationToString(fileAnnotation),
+		)
+	}
+	previousPackageToManifestPackage := make(map[string]*ManifestPackage)
+	previousDeprecatedEnumValueNameToReleases := make(map[string]int)
+	if previousManifest != nil {
+		for _, manifestPackage := range previousManifest.Packages {
+			previousPackageToManifestPackage[manifestPackage.Name] = manifestPackage
+		}
+		for _, deprecatedEnumValue := range previousManifest.DeprecatedEnumValues {
+			previousDeprecatedEnumValueNameToReleases[deprecatedEnumValue.Name] = deprecatedEnumValue.Releases
+		}
+	}
+	manifest := &Manifest{
+		Packages: make([]*ManifestPackage, 0, len(packageToDigest)),
+	}
+	for pkg, digest := range packageToDigest {
+		previousManifestPackage, ok := previousPackageToManifestPackage[pkg]
+		if !ok || previousManifestPackage.Stamp == "" {
+			manifest.Packages = append(
+				manifest.Packages,
+				&ManifestPackage{
+					Name:    pkg,
+					Stamp:   newStamp(pkg, digest),
+					Version: 1,
+				},
+			)
+			continue
+		}
+		fileAnnotationStrings, ok := packageToFileAnnotationStrings[pkg]
+		if !ok {
+			manifest.Packages = append(manifest.Packages, previousManifestPackage)
+			continue
+		}
+		sort.Strings(fileAnnotationStrings)
+		manifest.Packages = append(
+			manifest.Packages,
+			&ManifestPackage{
+				Name:    pkg,
+				Stamp:   newStamp(append([]string{previousManifestPackage.Stamp}, fileAnnotationStrings...)...),
+				Version: previousManifestPackage.Version + 1,
+			},
+		)
+	}
+	sort.Slice(manifest.Packages, func(i int, j int) bool { return manifest.Packages[i].Name < manifest.Packages[j].Name })
+	for _, name := range getDeprecatedEnumValueNames(image) {
+		manifest.DeprecatedEnumValues = append(
+			manifest.DeprecatedEnumValues,
+			&ManifestDeprecatedEnumValue{
+				Name:     name,
+				Releases: previousDeprecatedEnumValueNameToReleases[name] + 1,
+			},
+		)
+	}
+	return manifest, nil
+}
+
+func newStamp(values ...string) string {
+	hash := sha256.New()
+	for _, value := range values {
+		_, _ = hash.Write([]byte(value))
+		_, _ = hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
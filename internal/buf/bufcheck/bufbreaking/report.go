@@ -0,0 +1,179 @@
+package bufbreaking
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"github.com/bufbuild/buf/internal/buf/ext/extimage"
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/bufbuild/buf/internal/pkg/ext/extfile"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// Report is the recorded result of a breaking change detection run.
+//
+// A Report can be passed to a later run so that packages that have not changed
+// since the Report was created are not checked again.
+type Report struct {
+	// ConfigDigest is the digest of the Config used for the run.
+	//
+	// If the Config changes, the Report is not used.
+	ConfigDigest string `json:"config_digest,omitempty" yaml:"config_digest,omitempty"`
+	// Packages are the packages that were checked, sorted by name.
+	Packages []*ReportPackage `json:"packages,omitempty" yaml:"packages,omitempty"`
+	// FileAnnotations are the sorted FileAnnotations from the run.
+	//
+	// Paths are the image file paths.
+	FileAnnotations []*filev1beta1.FileAnnotation `json:"file_annotations,omitempty" yaml:"file_annotations,omitempty"`
+}
+
+// ReportPackage is a package within a Report.
+type ReportPackage struct {
+	Name           string `json:"name,omitempty" yaml:"name,omitempty"`
+	Digest         string `json:"digest,omitempty" yaml:"digest,omitempty"`
+	PreviousDigest string `json:"previous_digest,omitempty" yaml:"previous_digest,omitempty"`
+}
+
+// reportState is the state needed to skip packages and create a new Report.
+type reportState struct {
+	configDigest            string
+	packageToDigest         map[string]string
+	previousPackageToDigest map[string]string
+	// unchangedPackages are the packages that have the same digests as the previous Report
+	unchangedPackages map[string]struct{}
+	// reusedFileAnnotations are the FileAnnotations from the previous Report for unchangedPackages
+	reusedFileAnnotations []*filev1beta1.FileAnnotation
+}
+
+func newReportState(
+	config *Config,
+	previousImage *imagev1beta1.Image,
+	image *imagev1beta1.Image,
+	previousReport *Report,
+) (*reportState, error) {
+	configDigest, err := getConfigDigest(config)
+	if err != nil {
+		return nil, err
+	}
+	packageToDigest, err := extimage.ImagePackageDigests(image)
+	if err != nil {
+		return nil, err
+	}
+	previousPackageToDigest, err := extimage.ImagePackageDigests(previousImage)
+	if err != nil {
+		return nil, err
+	}
+	reportState := &reportState{
+		configDigest:            configDigest,
+		packageToDigest:         packageToDigest,
+		previousPackageToDigest: previousPackageToDigest,
+		unchangedPackages:       make(map[string]struct{}),
+	}
+	if previousReport == nil || previousReport.ConfigDigest != configDigest {
+		return reportState, nil
+	}
+	for _, reportPackage := range previousReport.Packages {
+		if reportPackage.Digest == "" || reportPackage.PreviousDigest == "" {
+			continue
+		}
+		if packageToDigest[reportPackage.Name] == reportPackage.Digest &&
+			previousPackageToDigest[reportPackage.Name] == reportPackage.PreviousDigest {
+			reportState.unchangedPackages[reportPackage.Name] = struct{}{}
+		}
+	}
+	if len(reportState.unchangedPackages) == 0 {
+		return reportState, nil
+	}
+	filePathToPackage := make(map[string]string)
+	for _, file := range previousImage.File {
+		filePathToPackage[file.GetName()] = file.GetPackage()
+	}
+	for _, file := range image.File {
+		filePathToPackage[file.GetName()] = file.GetPackage()
+	}
+	for _, fileAnnotation := range previousReport.FileAnnotations {
+		pkg, ok := filePathToPackage[fileAnnotation.GetPath()]
+		if !ok {
+			continue
+		}
+		if _, ok := reportState.unchangedPackages[pkg]; ok {
+			reportState.reusedFileAnnotations = append(reportState.reusedFileAnnotations, fileAnnotation)
+		}
+	}
+	return reportState, nil
+}
+
+// filterFiles returns the files that are not in unchanged packages.
+func (r *reportState) filterFiles(files []*descriptor.FileDescriptorProto) []*descriptor.FileDescriptorProto {
+	if len(r.unchangedPackages) == 0 {
+		return files
+	}
+	filteredFiles := make([]*descriptor.FileDescriptorProto, 0, len(files))
+	for _, file := range files {
+		if _, ok := r.unchangedPackages[file.GetPackage()]; !ok {
+			filteredFiles = append(filteredFiles, file)
+		}
+	}
+	return filteredFiles
+}
+
+// newReport merges the reused FileAnnotations with the given FileAnnotations and
+// returns the merged FileAnnotations and the new Report.
+func (r *reportState) newReport(fileAnnotations []*filev1beta1.FileAnnotation) ([]*filev1beta1.FileAnnotation, *Report) {
+	fileAnnotations = append(fileAnnotations, r.reusedFileAnnotations...)
+	extfile.SortFileAnnotations(fileAnnotations)
+	packageNames := make(map[string]struct{}, len(r.packageToDigest))
+	for pkg := range r.packageToDigest {
+		packageNames[pkg] = struct{}{}
+	}
+	for pkg := range r.previousPackageToDigest {
+		packageNames[pkg] = struct{}{}
+	}
+	reportPackages := make([]*ReportPackage, 0, len(packageNames))
+	for pkg := range packageNames {
+		reportPackages = append(
+			reportPackages,
+			&ReportPackage{
+				Name:           pkg,
+				Digest:         r.packageToDigest[pkg],
+				PreviousDigest: r.previousPackageToDigest[pkg],
+			},
+		)
+	}
+	sort.Slice(reportPackages, func(i int, j int) bool { return reportPackages[i].Name < reportPackages[j].Name })
+	return fileAnnotations, &Report{
+		ConfigDigest:    r.configDigest,
+		Packages:        reportPackages,
+		FileAnnotations: fileAnnotations,
+	}
+}
+
+func getConfigDigest(config *Config) (string, error) {
+	checkerIDs := make([]string, len(config.Checkers))
+	for i, checker := range config.Checkers {
+		checkerIDs[i] = checker.ID()
+	}
+	sort.Strings(checkerIDs)
+	// encoding/json sorts map keys so this is deterministic
+	data, err := json.Marshal(
+		struct {
+			CheckerIDs          []string
+			IgnoreIDToRootPaths map[string]map[string]struct{}
+			IgnoreRootPaths     map[string]struct{}
+			CustomOptions       []string `json:",omitempty"`
+		}{
+			CheckerIDs:          checkerIDs,
+			IgnoreIDToRootPaths: config.IgnoreIDToRootPaths,
+			IgnoreRootPaths:     config.IgnoreRootPaths,
+			CustomOptions:       config.customOptions,
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(data)
+	return hex.EncodeToString(digest[:]), nil
+}
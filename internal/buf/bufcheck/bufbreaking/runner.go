@@ -2,6 +2,7 @@ package bufbreaking
 
 import (
 	"context"
+	"time"
 
 	"github.com/bufbuild/buf/internal/buf/bufcheck/internal"
 	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
@@ -10,15 +11,32 @@ import (
 )
 
 type runner struct {
-	delegate *internal.Runner
+	delegate   *internal.Runner
+	timingFunc func(string, time.Duration)
 }
 
-func newRunner(logger *zap.Logger) *runner {
-	return &runner{
-		delegate: internal.NewRunner(logger.Named("breaking")),
+func newRunner(logger *zap.Logger, options ...RunnerOption) *runner {
+	runner := &runner{}
+	for _, option := range options {
+		option(runner)
 	}
+	runner.delegate = internal.NewRunner(
+		logger.Named("breaking"),
+		internal.RunnerWithTimingFunc(runner.timingFunc),
+	)
+	return runner
 }
 
 func (r *runner) Check(ctx context.Context, config *Config, previousFiles []protodesc.File, files []protodesc.File) ([]*filev1beta1.FileAnnotation, error) {
 	return r.delegate.Check(ctx, configToInternalConfig(config), previousFiles, files)
 }
+
+func (r *runner) CheckStream(
+	ctx context.Context,
+	config *Config,
+	previousFiles []protodesc.File,
+	files []protodesc.File,
+	fileAnnotationFunc func(*filev1beta1.FileAnnotation) error,
+) error {
+	return r.delegate.CheckStream(ctx, configToInternalConfig(config), previousFiles, files, fileAnnotationFunc)
+}
@@ -3,21 +3,28 @@ package bufbreaking
 import (
 	"github.com/bufbuild/buf/internal/buf/bufcheck/bufbreaking/internal"
 	bufcheckinternal "github.com/bufbuild/buf/internal/buf/bufcheck/internal"
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+	"github.com/bufbuild/buf/internal/pkg/protodesc"
+	"github.com/bufbuild/buf/internal/pkg/util/utilstring"
 )
 
 var (
 	// v1CheckerBuilders are the checker builders.
 	v1CheckerBuilders = []*bufcheckinternal.CheckerBuilder{
 		v1EnumNoDeleteCheckerBuilder,
+		v1EnumSameAllowAliasCheckerBuilder,
 		v1EnumValueNoDeleteCheckerBuilder,
 		v1EnumValueNoDeleteUnlessNameReservedCheckerBuilder,
 		v1EnumValueNoDeleteUnlessNumberReservedCheckerBuilder,
+		v1EnumValueNoDeleteUnlessReservedCheckerBuilder,
 		v1EnumValueSameNameCheckerBuilder,
 		v1ExtensionMessageNoDeleteCheckerBuilder,
 		v1FieldNoDeleteCheckerBuilder,
 		v1FieldNoDeleteUnlessNameReservedCheckerBuilder,
 		v1FieldNoDeleteUnlessNumberReservedCheckerBuilder,
+		v1FieldNoDeleteUnlessReservedCheckerBuilder,
 		v1FieldSameCTypeCheckerBuilder,
+		v1FieldSameCustomOptionsCheckerBuilder,
 		v1FieldSameJSONNameCheckerBuilder,
 		v1FieldSameJSTypeCheckerBuilder,
 		v1FieldSameLabelCheckerBuilder,
@@ -26,6 +33,7 @@ var (
 		v1FieldSameTypeCheckerBuilder,
 		v1FileNoDeleteCheckerBuilder,
 		v1FileSameCsharpNamespaceCheckerBuilder,
+		v1FileSameCustomOptionsCheckerBuilder,
 		v1FileSameGoPackageCheckerBuilder,
 		v1FileSameJavaMultipleFilesCheckerBuilder,
 		v1FileSameJavaOuterClassnameCheckerBuilder,
@@ -47,6 +55,7 @@ var (
 		v1FileSameSyntaxCheckerBuilder,
 		v1MessageNoDeleteCheckerBuilder,
 		v1MessageNoRemoveStandardDescriptorAccessorCheckerBuilder,
+		v1MessageSameCustomOptionsCheckerBuilder,
 		v1MessageSameMessageSetWireFormatCheckerBuilder,
 		v1OneofNoDeleteCheckerBuilder,
 		v1PackageEnumNoDeleteCheckerBuilder,
@@ -54,9 +63,12 @@ var (
 		v1PackageNoDeleteCheckerBuilder,
 		v1PackageServiceNoDeleteCheckerBuilder,
 		v1ReservedEnumNoDeleteCheckerBuilder,
+		v1ReservedEnumNoReuseCheckerBuilder,
 		v1ReservedMessageNoDeleteCheckerBuilder,
+		v1ReservedMessageNoReuseCheckerBuilder,
 		v1RPCNoDeleteCheckerBuilder,
 		v1RPCSameClientStreamingCheckerBuilder,
+		v1RPCSameCustomOptionsCheckerBuilder,
 		v1RPCSameIdempotencyLevelCheckerBuilder,
 		v1RPCSameRequestTypeCheckerBuilder,
 		v1RPCSameResponseTypeCheckerBuilder,
@@ -74,12 +86,17 @@ var (
 		"PACKAGE",
 		"WIRE_JSON",
 		"WIRE",
+		"RESERVED",
 	}
 	// v1IDToCategories are the revision 1 ID to categories.
 	v1IDToCategories = map[string][]string{
 		"ENUM_NO_DELETE": {
 			"FILE",
 		},
+		"ENUM_SAME_ALLOW_ALIAS": {
+			"FILE",
+			"PACKAGE",
+		},
 		"ENUM_VALUE_NO_DELETE": {
 			"FILE",
 			"PACKAGE",
@@ -91,6 +108,9 @@ var (
 			"WIRE_JSON",
 			"WIRE",
 		},
+		"ENUM_VALUE_NO_DELETE_UNLESS_RESERVED": {
+			"RESERVED",
+		},
 		"ENUM_VALUE_SAME_NAME": {
 			"FILE",
 			"PACKAGE",
@@ -111,10 +131,17 @@ var (
 			"WIRE_JSON",
 			"WIRE",
 		},
+		"FIELD_NO_DELETE_UNLESS_RESERVED": {
+			"RESERVED",
+		},
 		"FIELD_SAME_CTYPE": {
 			"FILE",
 			"PACKAGE",
 		},
+		"FIELD_SAME_CUSTOM_OPTIONS": {
+			"FILE",
+			"PACKAGE",
+		},
 		"FIELD_SAME_JSON_NAME": {
 			"FILE",
 			"PACKAGE",
@@ -154,6 +181,10 @@ var (
 			"FILE",
 			"PACKAGE",
 		},
+		"FILE_SAME_CUSTOM_OPTIONS": {
+			"FILE",
+			"PACKAGE",
+		},
 		"FILE_SAME_GO_PACKAGE": {
 			"FILE",
 			"PACKAGE",
@@ -236,6 +267,10 @@ var (
 			"FILE",
 			"PACKAGE",
 		},
+		"MESSAGE_SAME_CUSTOM_OPTIONS": {
+			"FILE",
+			"PACKAGE",
+		},
 		"MESSAGE_SAME_MESSAGE_SET_WIRE_FORMAT": {
 			"FILE",
 			"PACKAGE",
@@ -263,12 +298,28 @@ var (
 			"PACKAGE",
 			"WIRE_JSON",
 			"WIRE",
+			"RESERVED",
+		},
+		"RESERVED_ENUM_NO_REUSE": {
+			"FILE",
+			"PACKAGE",
+			"WIRE_JSON",
+			"WIRE",
+			"RESERVED",
 		},
 		"RESERVED_MESSAGE_NO_DELETE": {
 			"FILE",
 			"PACKAGE",
 			"WIRE_JSON",
 			"WIRE",
+			"RESERVED",
+		},
+		"RESERVED_MESSAGE_NO_REUSE": {
+			"FILE",
+			"PACKAGE",
+			"WIRE_JSON",
+			"WIRE",
+			"RESERVED",
 		},
 		"RPC_NO_DELETE": {
 			"FILE",
@@ -280,6 +331,10 @@ var (
 			"WIRE_JSON",
 			"WIRE",
 		},
+		"RPC_SAME_CUSTOM_OPTIONS": {
+			"FILE",
+			"PACKAGE",
+		},
 		"RPC_SAME_IDEMPOTENCY_LEVEL": {
 			"FILE",
 			"PACKAGE",
@@ -314,6 +369,11 @@ var (
 		"enums are not deleted from a given file",
 		internal.CheckEnumNoDelete,
 	)
+	v1EnumSameAllowAliasCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+		"ENUM_SAME_ALLOW_ALIAS",
+		"enums have the same value for the allow_alias option",
+		internal.CheckEnumSameAllowAlias,
+	)
 	v1EnumValueNoDeleteCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"ENUM_VALUE_NO_DELETE",
 		"enum values are not deleted from a given enum",
@@ -329,6 +389,11 @@ var (
 		"enum values are not deleted from a given enum unless the number is reserved",
 		internal.CheckEnumValueNoDeleteUnlessNumberReserved,
 	)
+	v1EnumValueNoDeleteUnlessReservedCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+		"ENUM_VALUE_NO_DELETE_UNLESS_RESERVED",
+		"enum values are not deleted from a given enum unless both the number and name are reserved",
+		internal.CheckEnumValueNoDeleteUnlessReserved,
+	)
 	v1EnumValueSameNameCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"ENUM_VALUE_SAME_NAME",
 		"enum values have the same name",
@@ -354,11 +419,21 @@ var (
 		"fields are not deleted from a given message unless the number is reserved",
 		internal.CheckFieldNoDeleteUnlessNumberReserved,
 	)
+	v1FieldNoDeleteUnlessReservedCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+		"FIELD_NO_DELETE_UNLESS_RESERVED",
+		"fields are not deleted from a given message unless both the number and name are reserved",
+		internal.CheckFieldNoDeleteUnlessReserved,
+	)
 	v1FieldSameCTypeCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"FIELD_SAME_CTYPE",
 		"fields have the same value for the ctype option",
 		internal.CheckFieldSameCType,
 	)
+	v1FieldSameCustomOptionsCheckerBuilder = newCustomOptionsCheckerBuilder(
+		"FIELD_SAME_CUSTOM_OPTIONS",
+		"fields",
+		internal.CheckFieldSameCustomOptions,
+	)
 	v1FieldSameJSONNameCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"FIELD_SAME_JSON_NAME",
 		"fields have the same value for the json_name option",
@@ -399,6 +474,11 @@ var (
 		"files have the same value for the csharp_namespace option",
 		internal.CheckFileSameCsharpNamespace,
 	)
+	v1FileSameCustomOptionsCheckerBuilder = newCustomOptionsCheckerBuilder(
+		"FILE_SAME_CUSTOM_OPTIONS",
+		"files",
+		internal.CheckFileSameCustomOptions,
+	)
 	v1FileSameGoPackageCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"FILE_SAME_GO_PACKAGE",
 		"files have the same value for the go_package option",
@@ -504,6 +584,11 @@ var (
 		"messages do not change the no_standard_descriptor_accessor option from false or unset to true",
 		internal.CheckMessageNoRemoveStandardDescriptorAccessor,
 	)
+	v1MessageSameCustomOptionsCheckerBuilder = newCustomOptionsCheckerBuilder(
+		"MESSAGE_SAME_CUSTOM_OPTIONS",
+		"messages",
+		internal.CheckMessageSameCustomOptions,
+	)
 	v1MessageSameMessageSetWireFormatCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"MESSAGE_SAME_MESSAGE_SET_WIRE_FORMAT",
 		"messages have the same value for the message_set_wire_format option",
@@ -539,11 +624,21 @@ var (
 		"reserved ranges and names are not deleted from a given enum",
 		internal.CheckReservedEnumNoDelete,
 	)
+	v1ReservedEnumNoReuseCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+		"RESERVED_ENUM_NO_REUSE",
+		"previously reserved numbers and names are not used by enum values in a given enum",
+		internal.CheckReservedEnumNoReuse,
+	)
 	v1ReservedMessageNoDeleteCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"RESERVED_MESSAGE_NO_DELETE",
 		"reserved ranges and names are not deleted from a given message",
 		internal.CheckReservedMessageNoDelete,
 	)
+	v1ReservedMessageNoReuseCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+		"RESERVED_MESSAGE_NO_REUSE",
+		"previously reserved numbers and names are not used by fields in a given message",
+		internal.CheckReservedMessageNoReuse,
+	)
 	v1RPCNoDeleteCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"RPC_NO_DELETE",
 		"rpcs are not deleted from a given service",
@@ -554,6 +649,11 @@ var (
 		"rpcs have the same client streaming value",
 		internal.CheckRPCSameClientStreaming,
 	)
+	v1RPCSameCustomOptionsCheckerBuilder = newCustomOptionsCheckerBuilder(
+		"RPC_SAME_CUSTOM_OPTIONS",
+		"rpcs",
+		internal.CheckRPCSameCustomOptions,
+	)
 	v1RPCSameIdempotencyLevelCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"RPC_SAME_IDEMPOTENCY_LEVEL",
 		"rpcs have the same value for the idempotency_level option",
@@ -580,3 +680,32 @@ var (
 		internal.CheckServiceNoDelete,
 	)
 )
+
+// newCustomOptionsCheckerBuilder returns a new CheckerBuilder for the
+// custom_options config value.
+//
+// The checker does nothing if no custom options are configured.
+func newCustomOptionsCheckerBuilder(
+	id string,
+	descriptorsName string,
+	f func(string, []protodesc.File, []protodesc.File, []string) ([]*filev1beta1.FileAnnotation, error),
+) *bufcheckinternal.CheckerBuilder {
+	return bufcheckinternal.NewCheckerBuilder(
+		id,
+		func(configBuilder bufcheckinternal.ConfigBuilder) (string, error) {
+			if len(configBuilder.CustomOptions) == 0 {
+				return descriptorsName + " have the same values for the options set by the custom_options option (options are configurable)", nil
+			}
+			return descriptorsName + " have the same values for the options " + utilstring.JoinSliceQuoted(configBuilder.CustomOptions, ", ") + " (options are configurable)", nil
+		},
+		func(configBuilder bufcheckinternal.ConfigBuilder) (bufcheckinternal.CheckFunc, error) {
+			customOptions := configBuilder.CustomOptions
+			return bufcheckinternal.CheckFunc(func(id string, previousFiles []protodesc.File, files []protodesc.File) ([]*filev1beta1.FileAnnotation, error) {
+				if len(customOptions) == 0 {
+					return nil, nil
+				}
+				return f(id, previousFiles, files, customOptions)
+			}), nil
+		},
+	)
+}
@@ -0,0 +1,123 @@
+package buflint
+
+import (
+	"sort"
+
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+)
+
+// Baseline is a recorded set of lint violations that are suppressed.
+//
+// Violations are identified by their path, checker ID, and message instead of
+// their location, so that they stay suppressed when lines are added or removed
+// above them.
+type Baseline struct {
+	// Violations are sorted by Path, Type, and then Message.
+	Violations []*BaselineViolation `json:"violations,omitempty" yaml:"violations,omitempty"`
+}
+
+// BaselineViolation is a group of identical lint violations within a Baseline.
+type BaselineViolation struct {
+	// Path is the image file path.
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	// Type is the checker ID.
+	Type    string `json:"type,omitempty" yaml:"type,omitempty"`
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	// Count is the number of violations with the same path, type, and message.
+	Count int `json:"count,omitempty" yaml:"count,omitempty"`
+}
+
+// NewBaseline returns a new Baseline that suppresses the FileAnnotations.
+//
+// The FileAnnotations must use the image file paths, that is
+// FixFileAnnotationPaths must not have been called.
+func NewBaseline(fileAnnotations []*filev1beta1.FileAnnotation) *Baseline {
+	keyToCount := make(map[baselineKey]int)
+	for _, fileAnnotation := range fileAnnotations {
+		keyToCount[newBaselineKey(fileAnnotation)]++
+	}
+	violations := make([]*BaselineViolation, 0, len(keyToCount))
+	for key, count := range keyToCount {
+		violations = append(
+			violations,
+			&BaselineViolation{
+				Path:    key.path,
+				Type:    key.typ,
+				Message: key.message,
+				Count:   count,
+			},
+		)
+	}
+	sort.Slice(
+		violations,
+		func(i int, j int) bool {
+			one := violations[i]
+			two := violations[j]
+			if one.Path != two.Path {
+				return one.Path < two.Path
+			}
+			if one.Type != two.Type {
+				return one.Type < two.Type
+			}
+			return one.Message < two.Message
+		},
+	)
+	return &Baseline{
+		Violations: violations,
+	}
+}
+
+// Filter returns the FileAnnotations that are not suppressed by the Baseline.
+//
+// For each path, type, and message, up to Count FileAnnotations are suppressed,
+// so a new violation that is identical to a suppressed violation is still returned.
+// The FileAnnotations must use the image file paths.
+func (b *Baseline) Filter(fileAnnotations []*filev1beta1.FileAnnotation) []*filev1beta1.FileAnnotation {
+	isNotSuppressed := b.NewFilterFunc()
+	filteredFileAnnotations := make([]*filev1beta1.FileAnnotation, 0, len(fileAnnotations))
+	for _, fileAnnotation := range fileAnnotations {
+		if isNotSuppressed(fileAnnotation) {
+			filteredFileAnnotations = append(filteredFileAnnotations, fileAnnotation)
+		}
+	}
+	return filteredFileAnnotations
+}
+
+// NewFilterFunc returns a function that returns true if the FileAnnotation is not
+// suppressed by the Baseline.
+//
+// This is the same as Filter, for FileAnnotations that are not all known up front.
+// The function counts the suppressed FileAnnotations, so it must be called once for
+// each FileAnnotation.
+func (b *Baseline) NewFilterFunc() func(*filev1beta1.FileAnnotation) bool {
+	keyToRemaining := make(map[baselineKey]int, len(b.Violations))
+	for _, violation := range b.Violations {
+		keyToRemaining[baselineKey{
+			path:    violation.Path,
+			typ:     violation.Type,
+			message: violation.Message,
+		}] += violation.Count
+	}
+	return func(fileAnnotation *filev1beta1.FileAnnotation) bool {
+		key := newBaselineKey(fileAnnotation)
+		if keyToRemaining[key] > 0 {
+			keyToRemaining[key]--
+			return false
+		}
+		return true
+	}
+}
+
+type baselineKey struct {
+	path    string
+	typ     string
+	message string
+}
+
+func newBaselineKey(fileAnnotation *filev1beta1.FileAnnotation) baselineKey {
+	return baselineKey{
+		path:    fileAnnotation.Path,
+		typ:     fileAnnotation.Type,
+		message: fileAnnotation.Message,
+	}
+}
@@ -0,0 +1,48 @@
+package buflint
+
+import (
+	"testing"
+
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseline(t *testing.T) {
+	t.Parallel()
+	baseline := NewBaseline(
+		[]*filev1beta1.FileAnnotation{
+			{Path: "b.proto", StartLine: 3, Type: "FIELD_LOWER_SNAKE_CASE", Message: "b"},
+			{Path: "a.proto", StartLine: 5, Type: "FIELD_LOWER_SNAKE_CASE", Message: "a"},
+			{Path: "a.proto", StartLine: 6, Type: "FIELD_LOWER_SNAKE_CASE", Message: "a"},
+			{Path: "a.proto", StartLine: 1, Type: "PACKAGE_DIRECTORY_MATCH", Message: "a"},
+		},
+	)
+	assert.Equal(
+		t,
+		&Baseline{
+			Violations: []*BaselineViolation{
+				{Path: "a.proto", Type: "FIELD_LOWER_SNAKE_CASE", Message: "a", Count: 2},
+				{Path: "a.proto", Type: "PACKAGE_DIRECTORY_MATCH", Message: "a", Count: 1},
+				{Path: "b.proto", Type: "FIELD_LOWER_SNAKE_CASE", Message: "b", Count: 1},
+			},
+		},
+		baseline,
+	)
+	// lines moved, and one identical and one different violation were added
+	fileAnnotations := []*filev1beta1.FileAnnotation{
+		{Path: "a.proto", StartLine: 1, Type: "PACKAGE_DIRECTORY_MATCH", Message: "a"},
+		{Path: "a.proto", StartLine: 8, Type: "FIELD_LOWER_SNAKE_CASE", Message: "a"},
+		{Path: "a.proto", StartLine: 9, Type: "FIELD_LOWER_SNAKE_CASE", Message: "a"},
+		{Path: "a.proto", StartLine: 10, Type: "FIELD_LOWER_SNAKE_CASE", Message: "a"},
+		{Path: "a.proto", StartLine: 11, Type: "FIELD_LOWER_SNAKE_CASE", Message: "c"},
+	}
+	assert.Equal(
+		t,
+		[]*filev1beta1.FileAnnotation{
+			{Path: "a.proto", StartLine: 10, Type: "FIELD_LOWER_SNAKE_CASE", Message: "a"},
+			{Path: "a.proto", StartLine: 11, Type: "FIELD_LOWER_SNAKE_CASE", Message: "c"},
+		},
+		baseline.Filter(fileAnnotations),
+	)
+	assert.Empty(t, (&Baseline{}).Filter(nil))
+}
@@ -5,12 +5,18 @@ package buflint
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
 
 	"github.com/bufbuild/buf/internal/buf/bufcheck"
 	"github.com/bufbuild/buf/internal/buf/bufcheck/internal"
 	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
 	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
 	"github.com/bufbuild/buf/internal/pkg/protodesc"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagepath"
 	"go.uber.org/zap"
 )
 
@@ -29,6 +35,30 @@ type Handler interface {
 		lintConfig *Config,
 		image *imagev1beta1.Image,
 	) ([]*filev1beta1.FileAnnotation, error)
+	// LintCheckWithCache runs the lint checks, caching the results per file
+	// within the cache directory.
+	//
+	// Files whose cache key is unchanged since a previous run are not re-linted.
+	// The cache key for a file covers the Config, the file, and all files in
+	// the same package or directory.
+	LintCheckWithCache(
+		ctx context.Context,
+		lintConfig *Config,
+		image *imagev1beta1.Image,
+		cacheDirPath string,
+	) ([]*filev1beta1.FileAnnotation, error)
+	// LintCheckStream runs the lint checks, calling the function with each FileAnnotation
+	// as soon as the checker that produced it completes.
+	//
+	// The FileAnnotations are the same as those of LintCheck, but are not sorted, so that
+	// they do not need to be held in memory. The function is only called from the
+	// goroutine that called LintCheckStream.
+	LintCheckStream(
+		ctx context.Context,
+		lintConfig *Config,
+		image *imagev1beta1.Image,
+		fileAnnotationFunc func(*filev1beta1.FileAnnotation) error,
+	) error
 }
 
 // NewHandler returns a new Handler.
@@ -58,11 +88,44 @@ type Runner interface {
 	// they will be relative to the roots. This should be fixed for linter outputs if image
 	// mode is not used.
 	Check(context.Context, *Config, []protodesc.File) ([]*filev1beta1.FileAnnotation, error)
+	// CheckStream runs lint checkers, calling the function with each FileAnnotation as
+	// soon as the checker that produced it completes, and returning a system error if
+	// any system error occurs.
+	//
+	// FileAnnotations are not sorted, and Paths are relative to the roots as with Check.
+	// The function is only called from the goroutine that called CheckStream.
+	CheckStream(context.Context, *Config, []protodesc.File, func(*filev1beta1.FileAnnotation) error) error
+}
+
+// RunnerOption is an option for a new Runner.
+type RunnerOption func(*runner)
+
+// RunnerWithReadFile returns a new RunnerOption that reads the source of
+// the file with the given image file path.
+//
+// This is required for the checkers that check the source of files rather
+// than the descriptors, which otherwise do not produce any FileAnnotations.
+func RunnerWithReadFile(readFile func(string) ([]byte, error)) RunnerOption {
+	return func(runner *runner) {
+		runner.readFile = readFile
+	}
+}
+
+// RunnerWithTimingFunc returns a new RunnerOption that calls the function with
+// the checker ID and the execution time of the checker for every run of a checker.
+//
+// Checkers may be run more than once per Check, in which case the execution times
+// should be added together. The function is only called from the goroutine that
+// called Check.
+func RunnerWithTimingFunc(timingFunc func(string, time.Duration)) RunnerOption {
+	return func(runner *runner) {
+		runner.timingFunc = timingFunc
+	}
 }
 
 // NewRunner returns a new Runner.
-func NewRunner(logger *zap.Logger) Runner {
-	return newRunner(logger)
+func NewRunner(logger *zap.Logger, options ...RunnerOption) Runner {
+	return newRunner(logger, options...)
 }
 
 // Config is the check config.
@@ -74,6 +137,59 @@ type Config struct {
 	Checkers            []Checker
 	IgnoreIDToRootPaths map[string]map[string]struct{}
 	IgnoreRootPaths     map[string]struct{}
+	// AllowCommentIgnores says to honor comment ignore directives.
+	//
+	// See CommentIgnorePrefix.
+	AllowCommentIgnores bool
+	// Overrides are configs that apply to files within specific root paths.
+	//
+	// If multiple root paths contain a file, the most specific root path wins.
+	// Each override is run against all files so that package and directory checks
+	// have the full context, but only its FileAnnotations within its root path are kept.
+	Overrides []*ConfigOverride
+	// FailurePolicy says which lint violations fail a lint run.
+	//
+	// This is always set when created with ConfigBuilder.NewConfig.
+	FailurePolicy *FailurePolicy
+	// Baseline is the normalized and validated path of the baseline file
+	// relative to the input directory, if any.
+	//
+	// The baseline file contains a JSON Baseline.
+	Baseline string
+
+	// the digest of the ConfigBuilder this Config was created from, if any
+	builderDigest string
+	// used by Fix
+	fieldOrderedRequiredFirst bool
+	licenseHeader             string
+}
+
+// ConfigOverride is a Config that applies to the files within a root path.
+type ConfigOverride struct {
+	// RootPath is the normalized and validated root path.
+	RootPath string
+	Config   *Config
+}
+
+// NewConfigOverride returns a new ConfigOverride.
+//
+// The root path must be relative.
+// The Config cannot have overrides itself.
+func NewConfigOverride(rootPath string, config *Config) (*ConfigOverride, error) {
+	normalizedRootPath, err := storagepath.NormalizeAndValidate(rootPath)
+	if err != nil {
+		return nil, err
+	}
+	if normalizedRootPath == "." {
+		return nil, fmt.Errorf("override root path %q cannot be the root", rootPath)
+	}
+	if len(config.Overrides) > 0 {
+		return nil, fmt.Errorf("override for %q cannot contain overrides", rootPath)
+	}
+	return &ConfigOverride{
+		RootPath: normalizedRootPath,
+		Config:   config,
+	}, nil
 }
 
 // GetCheckers returns the checkers for the given categories.
@@ -96,6 +212,26 @@ type ConfigBuilder struct {
 	RPCAllowGoogleProtobufEmptyRequests  bool
 	RPCAllowGoogleProtobufEmptyResponses bool
 	ServiceSuffix                        string
+	NamingExceptions                     []string
+	MessageDuplicateSimilarityThreshold  float64
+	PackageVersionSuffixPattern          string
+	RestrictedImports                    map[string][]string
+	RPCRestrictedClientStreamingPatterns []string
+	MaxFileLines                         int
+	MaxLineLength                        int
+	FieldOrderedRequiredFirst            bool
+	LicenseHeader                        string
+	CommentTODOAllowedPatterns           []string
+	AllowCommentIgnores                  bool
+	// FailOn is parsed with ParseFailOn.
+	FailOn string
+	// Warn are the checker IDs or categories whose violations are warnings.
+	Warn []string
+	// FailureThresholds are the maximum number of violations for checker IDs
+	// or categories that are warnings.
+	FailureThresholds map[string]int
+	// Baseline is the path of the baseline file relative to the input directory.
+	Baseline string
 }
 
 // NewConfig returns a new Config.
@@ -110,6 +246,16 @@ func (b ConfigBuilder) NewConfig() (*Config, error) {
 		RPCAllowGoogleProtobufEmptyRequests:  b.RPCAllowGoogleProtobufEmptyRequests,
 		RPCAllowGoogleProtobufEmptyResponses: b.RPCAllowGoogleProtobufEmptyResponses,
 		ServiceSuffix:                        b.ServiceSuffix,
+		NamingExceptions:                     b.NamingExceptions,
+		MessageDuplicateSimilarityThreshold:  b.MessageDuplicateSimilarityThreshold,
+		PackageVersionSuffixPattern:          b.PackageVersionSuffixPattern,
+		RestrictedImports:                    b.RestrictedImports,
+		RPCRestrictedClientStreamingPatterns: b.RPCRestrictedClientStreamingPatterns,
+		MaxFileLines:                         b.MaxFileLines,
+		MaxLineLength:                        b.MaxLineLength,
+		FieldOrderedRequiredFirst:            b.FieldOrderedRequiredFirst,
+		LicenseHeader:                        b.LicenseHeader,
+		CommentTODOAllowedPatterns:           b.CommentTODOAllowedPatterns,
 	}.NewConfig(
 		v1CheckerBuilders,
 		v1IDToCategories,
@@ -118,7 +264,38 @@ func (b ConfigBuilder) NewConfig() (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
-	return internalConfigToConfig(internalConfig), nil
+	failurePolicy, err := newFailurePolicy(b.FailOn, b.Warn, b.FailureThresholds)
+	if err != nil {
+		return nil, err
+	}
+	var baseline string
+	if b.Baseline != "" {
+		baseline, err = storagepath.NormalizeAndValidate(b.Baseline)
+		if err != nil {
+			return nil, fmt.Errorf("baseline: %v", err)
+		}
+	}
+	// encoding/json sorts map keys so this is deterministic
+	data, err := json.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+	builderDigest := sha256.Sum256(data)
+	config := internalConfigToConfig(internalConfig)
+	config.AllowCommentIgnores = b.AllowCommentIgnores
+	config.FailurePolicy = failurePolicy
+	config.Baseline = baseline
+	config.fieldOrderedRequiredFirst = b.FieldOrderedRequiredFirst
+	config.licenseHeader = b.LicenseHeader
+	config.builderDigest = hex.EncodeToString(builderDigest[:])
+	return config, nil
+}
+
+// GetDefaultCategories returns the categories used if use is not set.
+//
+// Should only be used for printing.
+func GetDefaultCategories() []string {
+	return append([]string{}, v1DefaultCategories...)
 }
 
 // GetAllCheckers gets all known checkers for the given categories.
@@ -136,6 +313,13 @@ func GetAllCheckers(categories ...string) ([]bufcheck.Checker, error) {
 	return checkersToBufcheckCheckers(config.Checkers, categories)
 }
 
+// GetCheckerDoc gets the documentation for the checker with the given ID.
+//
+// Returns nil if there is no checker with the ID.
+func GetCheckerDoc(id string) *bufcheck.CheckerDoc {
+	return v1IDToCheckerDoc[id]
+}
+
 func internalConfigToConfig(internalConfig *internal.Config) *Config {
 	return &Config{
 		Checkers:            internalCheckersToCheckers(internalConfig.Checkers),
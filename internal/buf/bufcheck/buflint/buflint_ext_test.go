@@ -2,6 +2,7 @@ package buflint_test
 
 import (
 	"context"
+	"io/ioutil"
 	"path/filepath"
 	"testing"
 	"time"
@@ -134,6 +135,17 @@ func TestRunDirectorySamePackage(t *testing.T) {
 	)
 }
 
+func TestRunFileNoBOMCRLF(t *testing.T) {
+	testLint(
+		t,
+		"file_no_bom_crlf",
+		extfiletesting.NewFileAnnotation("a.proto", 1, 1, 1, 1, "FILE_NO_BOM_CRLF"),
+		extfiletesting.NewFileAnnotation("b.proto", 3, 1, 3, 1, "FILE_NO_BOM_CRLF"),
+		extfiletesting.NewFileAnnotation("c.proto", 1, 1, 1, 1, "FILE_NO_BOM_CRLF"),
+		extfiletesting.NewFileAnnotation("c.proto", 1, 1, 1, 1, "FILE_NO_BOM_CRLF"),
+	)
+}
+
 func TestRunImportNoPublic(t *testing.T) {
 	testLint(
 		t,
@@ -271,6 +283,83 @@ func TestRunFieldLowerSnakeCase(t *testing.T) {
 	)
 }
 
+func TestRunCommentIgnores(t *testing.T) {
+	testLint(
+		t,
+		"comment_ignores",
+		extfiletesting.NewFileAnnotation("a.proto", 8, 9, 8, 16, "FIELD_LOWER_SNAKE_CASE"),
+		extfiletesting.NewFileAnnotation("a.proto", 13, 9, 13, 12, "MESSAGE_PASCAL_CASE"),
+		extfiletesting.NewFileAnnotation("a.proto", 19, 9, 19, 17, "FIELD_LOWER_SNAKE_CASE"),
+	)
+}
+
+func TestRunCommentIgnoresDisallowed(t *testing.T) {
+	testLintExternalConfigModifier(
+		t,
+		"comment_ignores",
+		func(externalConfig *bufconfig.ExternalConfig) {
+			externalConfig.Lint.AllowCommentIgnores = false
+		},
+		extfiletesting.NewFileAnnotation("a.proto", 7, 9, 7, 16, "FIELD_LOWER_SNAKE_CASE"),
+		extfiletesting.NewFileAnnotation("a.proto", 8, 9, 8, 16, "FIELD_LOWER_SNAKE_CASE"),
+		extfiletesting.NewFileAnnotation("a.proto", 13, 9, 13, 12, "MESSAGE_PASCAL_CASE"),
+		extfiletesting.NewFileAnnotation("a.proto", 14, 9, 14, 18, "FIELD_LOWER_SNAKE_CASE"),
+		extfiletesting.NewFileAnnotation("a.proto", 18, 9, 18, 14, "MESSAGE_PASCAL_CASE"),
+		extfiletesting.NewFileAnnotation("a.proto", 19, 9, 19, 17, "FIELD_LOWER_SNAKE_CASE"),
+	)
+}
+
+func TestRunCommentNoTODO(t *testing.T) {
+	testLint(
+		t,
+		"comment_no_todo",
+		extfiletesting.NewFileAnnotation("v1/a.proto", 4, 1, 4, 14, "COMMENT_NO_TODO"),
+		extfiletesting.NewFileAnnotation("v1/a.proto", 9, 1, 15, 2, "COMMENT_NO_TODO"),
+		extfiletesting.NewFileAnnotation("v1/a.proto", 12, 3, 12, 17, "COMMENT_NO_TODO"),
+		extfiletesting.NewFileAnnotation("v1/a.proto", 20, 1, 20, 15, "COMMENT_NO_TODO"),
+	)
+}
+
+func TestRunLintOverrides(t *testing.T) {
+	testLint(
+		t,
+		"lint_overrides",
+		extfiletesting.NewFileAnnotation("a/a.proto", 5, 9, 5, 13, "MESSAGE_PASCAL_CASE"),
+		extfiletesting.NewFileAnnotation("a/a.proto", 6, 9, 6, 16, "FIELD_LOWER_SNAKE_CASE"),
+		extfiletesting.NewFileAnnotation("legacy/b/b.proto", 5, 9, 5, 13, "MESSAGE_PASCAL_CASE"),
+		extfiletesting.NewFileAnnotation("legacy/strict/c/c.proto", 6, 9, 6, 16, "FIELD_LOWER_SNAKE_CASE"),
+	)
+}
+
+func TestRunFieldLowerSnakeCaseNamingExceptions(t *testing.T) {
+	testLint(
+		t,
+		"field_lower_snake_case_naming_exceptions",
+		extfiletesting.NewFileAnnotation("a.proto", 10, 9, 10, 18, "FIELD_LOWER_SNAKE_CASE"),
+		extfiletesting.NewFileAnnotation("a.proto", 11, 9, 11, 19, "FIELD_LOWER_SNAKE_CASE"),
+		extfiletesting.NewFileAnnotation("a.proto", 12, 9, 12, 19, "FIELD_LOWER_SNAKE_CASE"),
+		extfiletesting.NewFileAnnotation("a.proto", 21, 13, 21, 20, "FIELD_LOWER_SNAKE_CASE"),
+		extfiletesting.NewFileAnnotation("a.proto", 22, 13, 22, 22, "FIELD_LOWER_SNAKE_CASE"),
+		extfiletesting.NewFileAnnotation("a.proto", 23, 13, 23, 23, "FIELD_LOWER_SNAKE_CASE"),
+		extfiletesting.NewFileAnnotation("a.proto", 24, 13, 24, 23, "FIELD_LOWER_SNAKE_CASE"),
+		extfiletesting.NewFileAnnotation("a.proto", 29, 11, 29, 18, "FIELD_LOWER_SNAKE_CASE"),
+		extfiletesting.NewFileAnnotation("a.proto", 30, 11, 30, 20, "FIELD_LOWER_SNAKE_CASE"),
+		extfiletesting.NewFileAnnotation("a.proto", 31, 11, 31, 21, "FIELD_LOWER_SNAKE_CASE"),
+		extfiletesting.NewFileAnnotation("a.proto", 32, 11, 32, 21, "FIELD_LOWER_SNAKE_CASE"),
+	)
+}
+
+func TestRunFieldNoJSONNameCollision(t *testing.T) {
+	testLint(
+		t,
+		"field_no_json_name_collision",
+		extfiletesting.NewFileAnnotation("a.proto", 7, 32, 7, 52, "FIELD_NO_JSON_NAME_COLLISION"),
+		extfiletesting.NewFileAnnotation("a.proto", 8, 19, 8, 27, "FIELD_NO_JSON_NAME_COLLISION"),
+		extfiletesting.NewFileAnnotation("a.proto", 15, 34, 15, 54, "FIELD_NO_JSON_NAME_COLLISION"),
+		extfiletesting.NewFileAnnotation("a.proto", 19, 28, 19, 45, "FIELD_NO_JSON_NAME_COLLISION"),
+	)
+}
+
 func TestRunFieldNoDescriptor(t *testing.T) {
 	testLint(
 		t,
@@ -310,6 +399,34 @@ func TestRunFieldNoDescriptor(t *testing.T) {
 	)
 }
 
+func TestRunFieldOrdered(t *testing.T) {
+	testLint(
+		t,
+		"field_ordered",
+		extfiletesting.NewFileAnnotation("a.proto", 8, 3, 8, 17, "FIELD_ORDERED"),
+		extfiletesting.NewFileAnnotation("a.proto", 11, 5, 11, 20, "FIELD_ORDERED"),
+		extfiletesting.NewFileAnnotation("a.proto", 16, 5, 16, 19, "FIELD_ORDERED"),
+	)
+}
+
+func TestRunFieldOrderedRequiredFirst(t *testing.T) {
+	testLint(
+		t,
+		"field_ordered_required_first",
+		extfiletesting.NewFileAnnotation("a.proto", 8, 3, 8, 26, "FIELD_ORDERED"),
+	)
+}
+
+func TestRunFileLicenseHeader(t *testing.T) {
+	testLint(
+		t,
+		"file_license_header",
+		extfiletesting.NewFileAnnotationNoLocation("b.proto", "FILE_LICENSE_HEADER"),
+		extfiletesting.NewFileAnnotationNoLocation("c.proto", "FILE_LICENSE_HEADER"),
+		extfiletesting.NewFileAnnotationNoLocation("f.proto", "FILE_LICENSE_HEADER"),
+	)
+}
+
 func TestRunFileLowerSnakeCase(t *testing.T) {
 	testLint(
 		t,
@@ -322,6 +439,26 @@ func TestRunFileLowerSnakeCase(t *testing.T) {
 	)
 }
 
+func TestRunFileMaxLineLength(t *testing.T) {
+	testLint(
+		t,
+		"file_max_line_length",
+		extfiletesting.NewFileAnnotation("a.proto", 10, 3, 10, 44, "FILE_MAX_LINE_LENGTH"),
+		extfiletesting.NewFileAnnotation("a.proto", 12, 3, 12, 49, "FILE_MAX_LINE_LENGTH"),
+		extfiletesting.NewFileAnnotation("a.proto", 13, 9, 13, 42, "FILE_MAX_LINE_LENGTH"),
+		extfiletesting.NewFileAnnotation("a.proto", 18, 6, 18, 42, "FILE_MAX_LINE_LENGTH"),
+		extfiletesting.NewFileAnnotation("a.proto", 19, 3, 19, 66, "FILE_MAX_LINE_LENGTH"),
+	)
+}
+
+func TestRunFileMaxLines(t *testing.T) {
+	testLint(
+		t,
+		"file_max_lines",
+		extfiletesting.NewFileAnnotationNoLocation("b.proto", "FILE_MAX_LINES"),
+	)
+}
+
 func TestRunMessagePascalCase(t *testing.T) {
 	testLint(
 		t,
@@ -339,6 +476,61 @@ func TestRunMessagePascalCase(t *testing.T) {
 	)
 }
 
+func TestRunImportNoRestricted(t *testing.T) {
+	testLint(
+		t,
+		"import_no_restricted",
+		extfiletesting.NewFileAnnotation("public/public.proto", 5, 1, 5, 33, "IMPORT_NO_RESTRICTED"),
+		extfiletesting.NewFileAnnotation("public/public.proto", 6, 1, 6, 29, "IMPORT_NO_RESTRICTED"),
+	)
+}
+
+func TestRunImportOrdered(t *testing.T) {
+	testLint(
+		t,
+		"import_ordered",
+		extfiletesting.NewFileAnnotation("a.proto", 6, 1, 6, 42, "IMPORT_ORDERED"),
+		extfiletesting.NewFileAnnotation("a.proto", 9, 1, 9, 18, "IMPORT_ORDERED"),
+		extfiletesting.NewFileAnnotation("b.proto", 9, 1, 9, 18, "IMPORT_ORDERED"),
+	)
+}
+
+func TestRunMessageNoCrossPackageDuplicate(t *testing.T) {
+	testLint(
+		t,
+		"message_no_cross_package_duplicate",
+		extfiletesting.NewFileAnnotation("a/a.proto", 5, 9, 5, 13, "MESSAGE_NO_CROSS_PACKAGE_DUPLICATE"),
+		extfiletesting.NewFileAnnotation("a/a.proto", 13, 9, 13, 16, "MESSAGE_NO_CROSS_PACKAGE_DUPLICATE"),
+		extfiletesting.NewFileAnnotation("b/b.proto", 5, 9, 5, 13, "MESSAGE_NO_CROSS_PACKAGE_DUPLICATE"),
+		extfiletesting.NewFileAnnotation("b/b.proto", 13, 9, 13, 16, "MESSAGE_NO_CROSS_PACKAGE_DUPLICATE"),
+	)
+}
+
+func TestRunMessageNoCrossPackageDuplicateCustom(t *testing.T) {
+	testLint(
+		t,
+		"message_no_cross_package_duplicate_custom",
+		extfiletesting.NewFileAnnotation("a/a.proto", 5, 9, 5, 13, "MESSAGE_NO_CROSS_PACKAGE_DUPLICATE"),
+		extfiletesting.NewFileAnnotation("a/a.proto", 13, 9, 13, 16, "MESSAGE_NO_CROSS_PACKAGE_DUPLICATE"),
+		extfiletesting.NewFileAnnotation("b/b.proto", 5, 9, 5, 13, "MESSAGE_NO_CROSS_PACKAGE_DUPLICATE"),
+		extfiletesting.NewFileAnnotation("b/b.proto", 13, 9, 13, 16, "MESSAGE_NO_CROSS_PACKAGE_DUPLICATE"),
+		extfiletesting.NewFileAnnotation("c/c.proto", 6, 11, 6, 15, "MESSAGE_NO_CROSS_PACKAGE_DUPLICATE"),
+	)
+}
+
+func TestRunNameNoGeneratedCollision(t *testing.T) {
+	testLint(
+		t,
+		"name_no_generated_collision",
+		extfiletesting.NewFileAnnotation("a.proto", 7, 19, 7, 25, "NAME_NO_GENERATED_COLLISION"),
+		extfiletesting.NewFileAnnotation("a.proto", 9, 19, 9, 27, "NAME_NO_GENERATED_COLLISION"),
+		extfiletesting.NewFileAnnotation("a.proto", 10, 9, 10, 16, "NAME_NO_GENERATED_COLLISION"),
+		extfiletesting.NewFileAnnotation("a.proto", 16, 8, 16, 14, "NAME_NO_GENERATED_COLLISION"),
+		extfiletesting.NewFileAnnotation("a.proto", 23, 9, 23, 12, "NAME_NO_GENERATED_COLLISION"),
+		extfiletesting.NewFileAnnotation("a.proto", 32, 7, 32, 10, "NAME_NO_GENERATED_COLLISION"),
+	)
+}
+
 func TestRunOneofLowerSnakeCase(t *testing.T) {
 	testLint(
 		t,
@@ -457,6 +649,16 @@ func TestRunPackageVersionSuffix(t *testing.T) {
 	)
 }
 
+func TestRunPackageVersionSuffixCustom(t *testing.T) {
+	testLint(
+		t,
+		"package_version_suffix_custom",
+		extfiletesting.NewFileAnnotation("foo_bar_v1.proto", 3, 1, 3, 20, "PACKAGE_VERSION_SUFFIX"),
+		extfiletesting.NewFileAnnotation("foo_bar_v20200101beta.proto", 3, 1, 3, 31, "PACKAGE_VERSION_SUFFIX"),
+		extfiletesting.NewFileAnnotation("v20200101.proto", 3, 1, 3, 19, "PACKAGE_VERSION_SUFFIX"),
+	)
+}
+
 func TestRunRPCNoStreaming(t *testing.T) {
 	testLint(
 		t,
@@ -468,6 +670,21 @@ func TestRunRPCNoStreaming(t *testing.T) {
 	)
 }
 
+func TestRunRPCStreaming(t *testing.T) {
+	testLint(
+		t,
+		"rpc_streaming",
+		extfiletesting.NewFileAnnotation("a.proto", 11, 3, 11, 81, "RPC_NO_RESTRICTED_CLIENT_STREAMING"),
+		extfiletesting.NewFileAnnotation("a.proto", 16, 3, 16, 78, "RPC_STREAMING_LIFECYCLE_DOCUMENTED"),
+		extfiletesting.NewFileAnnotation("a.proto", 19, 3, 19, 80, "RPC_STREAMING_LIFECYCLE_DOCUMENTED"),
+		extfiletesting.NewFileAnnotation("a.proto", 22, 3, 22, 77, "RPC_STREAMING_PAGINATION_DOCUMENTED"),
+		extfiletesting.NewFileAnnotation("a.proto", 23, 3, 23, 87, "RPC_NO_RESTRICTED_CLIENT_STREAMING"),
+		extfiletesting.NewFileAnnotation("a.proto", 23, 3, 23, 87, "RPC_STREAMING_LIFECYCLE_DOCUMENTED"),
+		extfiletesting.NewFileAnnotation("a.proto", 23, 3, 23, 87, "RPC_STREAMING_LIFECYCLE_DOCUMENTED"),
+		extfiletesting.NewFileAnnotation("a.proto", 23, 3, 23, 87, "RPC_STREAMING_PAGINATION_DOCUMENTED"),
+	)
+}
+
 func TestRunRPCPascalCase(t *testing.T) {
 	testLint(
 		t,
@@ -787,7 +1004,18 @@ func testLintExternalConfigModifier(
 
 	handler := buflint.NewHandler(
 		logger,
-		buflint.NewRunner(logger),
+		buflint.NewRunner(
+			logger,
+			buflint.RunnerWithReadFile(
+				func(rootFilePath string) ([]byte, error) {
+					realFilePath, err := protoFileSet.GetRealFilePath(rootFilePath)
+					if err != nil {
+						return nil, err
+					}
+					return ioutil.ReadFile(filepath.Join("testdata", dirPath, realFilePath))
+				},
+			),
+		),
 	)
 	fileAnnotations, err = handler.LintCheck(
 		ctx,
@@ -25,3 +25,12 @@ func TestCheckerBuilders(t *testing.T) {
 		v1AllCategories,
 	)
 }
+
+func TestCheckerDocs(t *testing.T) {
+	t.Parallel()
+	internaltesting.RunTestCheckerDocs(
+		t,
+		v1CheckerBuilders,
+		v1IDToCheckerDoc,
+	)
+}
@@ -0,0 +1,246 @@
+package buflint
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/bufbuild/buf/internal/buf/ext/extimage"
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/bufbuild/buf/internal/pkg/ext/extfile"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagepath"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"go.uber.org/zap"
+)
+
+// uncacheableCheckerIDs are the checkers that look across all files, for which
+// the results for a file cannot be derived from the file, its package, and its directory.
+var uncacheableCheckerIDs = map[string]struct{}{
+	"FILE_NO_BOM_CRLF":                   {},
+	"IMPORT_ORDERED":                     {},
+	"MESSAGE_NO_CROSS_PACKAGE_DUPLICATE": {},
+}
+
+func (h *handler) LintCheckWithCache(
+	ctx context.Context,
+	lintConfig *Config,
+	image *imagev1beta1.Image,
+	cacheDirPath string,
+) ([]*filev1beta1.FileAnnotation, error) {
+	if uncacheableCheckerID := getUncacheableCheckerID(lintConfig); uncacheableCheckerID != "" {
+		h.logger.Debug("lint_cache_disabled", zap.String("checker", uncacheableCheckerID))
+		return h.LintCheck(ctx, lintConfig, image)
+	}
+	fileToKey, err := getFileCacheKeys(lintConfig, image)
+	if err != nil {
+		return nil, err
+	}
+	var fileAnnotations []*filev1beta1.FileAnnotation
+	missedFiles := make(map[string]struct{})
+	for _, file := range image.GetFile() {
+		cachedFileAnnotations, ok, err := readCachedFileAnnotations(cacheDirPath, fileToKey[file.GetName()])
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			fileAnnotations = append(fileAnnotations, cachedFileAnnotations...)
+		} else {
+			missedFiles[file.GetName()] = struct{}{}
+		}
+	}
+	h.logger.Debug("lint_cache", zap.Int("num_files", len(image.GetFile())), zap.Int("num_missed", len(missedFiles)))
+	if len(missedFiles) == 0 {
+		extfile.SortFileAnnotations(fileAnnotations)
+		return fileAnnotations, nil
+	}
+
+	// package and directory checks need the other files in the package and
+	// directory to be present, but we only use the results for the missed files
+	contextPackages := make(map[string]struct{})
+	contextDirPaths := make(map[string]struct{})
+	for _, file := range image.GetFile() {
+		if _, ok := missedFiles[file.GetName()]; ok {
+			contextPackages[file.GetPackage()] = struct{}{}
+			contextDirPaths[storagepath.Dir(file.GetName())] = struct{}{}
+		}
+	}
+	var subsetFiles []*descriptor.FileDescriptorProto
+	for _, file := range image.GetFile() {
+		_, isContextPackage := contextPackages[file.GetPackage()]
+		_, isContextDirPath := contextDirPaths[storagepath.Dir(file.GetName())]
+		if isContextPackage || isContextDirPath {
+			subsetFiles = append(subsetFiles, file)
+		}
+	}
+	subsetFileAnnotations, err := h.LintCheck(ctx, lintConfig, &imagev1beta1.Image{File: subsetFiles})
+	if err != nil {
+		return nil, err
+	}
+	fileToFileAnnotations := make(map[string][]*filev1beta1.FileAnnotation)
+	for _, fileAnnotation := range subsetFileAnnotations {
+		if fileAnnotation.Path == "" {
+			// these cannot be attributed to a file, so are never cached
+			fileAnnotations = append(fileAnnotations, fileAnnotation)
+			continue
+		}
+		fileToFileAnnotations[fileAnnotation.Path] = append(fileToFileAnnotations[fileAnnotation.Path], fileAnnotation)
+	}
+	for missedFile := range missedFiles {
+		missedFileAnnotations := fileToFileAnnotations[missedFile]
+		if err := writeCachedFileAnnotations(cacheDirPath, fileToKey[missedFile], missedFileAnnotations); err != nil {
+			return nil, err
+		}
+		fileAnnotations = append(fileAnnotations, missedFileAnnotations...)
+	}
+	extfile.SortFileAnnotations(fileAnnotations)
+	return fileAnnotations, nil
+}
+
+// getUncacheableCheckerID returns the ID of the first uncacheable checker within
+// the config or its overrides, or empty if there is none.
+func getUncacheableCheckerID(config *Config) string {
+	for _, checker := range config.Checkers {
+		if _, ok := uncacheableCheckerIDs[checker.ID()]; ok {
+			return checker.ID()
+		}
+	}
+	for _, configOverride := range config.Overrides {
+		if uncacheableCheckerID := getUncacheableCheckerID(configOverride.Config); uncacheableCheckerID != "" {
+			return uncacheableCheckerID
+		}
+	}
+	return ""
+}
+
+// getFileCacheKeys gets the cache key for each file.
+//
+// The key for a file covers the config, the file, and all files in the same
+// package or directory, as package and directory checks depend on these.
+func getFileCacheKeys(config *Config, image *imagev1beta1.Image) (map[string]string, error) {
+	configDigest, err := getConfigDigest(config)
+	if err != nil {
+		return nil, err
+	}
+	fileToDigest, err := extimage.ImageFileDigests(image)
+	if err != nil {
+		return nil, err
+	}
+	packageToFiles := make(map[string][]string)
+	dirPathToFiles := make(map[string][]string)
+	for _, file := range image.GetFile() {
+		packageToFiles[file.GetPackage()] = append(packageToFiles[file.GetPackage()], file.GetName())
+		dirPath := storagepath.Dir(file.GetName())
+		dirPathToFiles[dirPath] = append(dirPathToFiles[dirPath], file.GetName())
+	}
+	fileToKey := make(map[string]string, len(image.GetFile()))
+	for _, file := range image.GetFile() {
+		dependentFiles := append(
+			append([]string{}, packageToFiles[file.GetPackage()]...),
+			dirPathToFiles[storagepath.Dir(file.GetName())]...,
+		)
+		sort.Strings(dependentFiles)
+		hash := sha256.New()
+		_, _ = hash.Write([]byte(configDigest))
+		_, _ = hash.Write([]byte(fileToDigest[file.GetName()]))
+		for _, dependentFile := range dependentFiles {
+			_, _ = hash.Write([]byte(fileToDigest[dependentFile]))
+		}
+		fileToKey[file.GetName()] = hex.EncodeToString(hash.Sum(nil))
+	}
+	return fileToKey, nil
+}
+
+func getConfigDigest(config *Config) (string, error) {
+	checkerIDs := make([]string, len(config.Checkers))
+	for i, checker := range config.Checkers {
+		checkerIDs[i] = checker.ID()
+	}
+	sort.Strings(checkerIDs)
+	overrideDigests := make(map[string]string, len(config.Overrides))
+	for _, configOverride := range config.Overrides {
+		overrideDigest, err := getConfigDigest(configOverride.Config)
+		if err != nil {
+			return "", err
+		}
+		overrideDigests[configOverride.RootPath] = overrideDigest
+	}
+	// encoding/json sorts map keys so this is deterministic
+	data, err := json.Marshal(
+		struct {
+			CheckerIDs          []string
+			IgnoreIDToRootPaths map[string]map[string]struct{}
+			IgnoreRootPaths     map[string]struct{}
+			AllowCommentIgnores bool
+			BuilderDigest       string
+			OverrideDigests     map[string]string
+		}{
+			CheckerIDs:          checkerIDs,
+			IgnoreIDToRootPaths: config.IgnoreIDToRootPaths,
+			IgnoreRootPaths:     config.IgnoreRootPaths,
+			AllowCommentIgnores: config.AllowCommentIgnores,
+			BuilderDigest:       config.builderDigest,
+			OverrideDigests:     overrideDigests,
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(data)
+	return hex.EncodeToString(digest[:]), nil
+}
+
+func readCachedFileAnnotations(cacheDirPath string, key string) ([]*filev1beta1.FileAnnotation, bool, error) {
+	data, err := ioutil.ReadFile(getCacheFilePath(cacheDirPath, key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	var fileAnnotations []*filev1beta1.FileAnnotation
+	if err := json.Unmarshal(data, &fileAnnotations); err != nil {
+		// treat a corrupt entry as a miss, it will be overwritten
+		return nil, false, nil
+	}
+	return fileAnnotations, true, nil
+}
+
+// writeCachedFileAnnotations writes to a temporary file that is renamed into place
+// so that concurrent runs never read partial entries.
+func writeCachedFileAnnotations(cacheDirPath string, key string, fileAnnotations []*filev1beta1.FileAnnotation) error {
+	if fileAnnotations == nil {
+		fileAnnotations = []*filev1beta1.FileAnnotation{}
+	}
+	data, err := json.Marshal(fileAnnotations)
+	if err != nil {
+		return err
+	}
+	cacheFilePath := getCacheFilePath(cacheDirPath, key)
+	if err := os.MkdirAll(filepath.Dir(cacheFilePath), 0755); err != nil {
+		return err
+	}
+	tmpFile, err := ioutil.TempFile(filepath.Dir(cacheFilePath), ".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmpFile.Write(data); err != nil {
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpFile.Name())
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		_ = os.Remove(tmpFile.Name())
+		return err
+	}
+	return os.Rename(tmpFile.Name(), cacheFilePath)
+}
+
+func getCacheFilePath(cacheDirPath string, key string) string {
+	return filepath.Join(cacheDirPath, key[:2], key+".json")
+}
@@ -0,0 +1,826 @@
+package buflint
+
+import (
+	"github.com/bufbuild/buf/internal/buf/bufcheck"
+)
+
+const (
+	commentRationale = `Comments are the only documentation most consumers of an API have. The
+generated code for most languages carries comments over, so commenting the
+Protobuf definition documents the API in every language at once.`
+	namingRationale = `Following the naming conventions of the Protobuf style guide results in
+generated code that follows the conventions of each target language, as the
+Protobuf plugins convert names with these conventions in mind.`
+	packageSameOptionRationale = `Files in the same package generate code into the same location for languages
+that use this option. Differing values split the package across locations,
+which breaks imports between files in the package.`
+)
+
+var (
+	namingExceptionsOptionDoc = &bufcheck.CheckerOptionDoc{
+		Name:        "naming_exceptions",
+		Description: "Names or fully-qualified names that are not checked.",
+	}
+	rpcAllowGoogleProtobufEmptyRequestsOptionDoc = &bufcheck.CheckerOptionDoc{
+		Name:        "rpc_allow_google_protobuf_empty_requests",
+		Description: "Allow google.protobuf.Empty as a request type.",
+	}
+	rpcAllowGoogleProtobufEmptyResponsesOptionDoc = &bufcheck.CheckerOptionDoc{
+		Name:        "rpc_allow_google_protobuf_empty_responses",
+		Description: "Allow google.protobuf.Empty as a response type.",
+	}
+
+	// v1IDToCheckerDoc are the revision 1 ID to CheckerDoc.
+	v1IDToCheckerDoc = map[string]*bufcheck.CheckerDoc{
+		"COMMENT_ENUM": {
+			Description: "Enums must have a leading comment that is not empty.",
+			Rationale:   commentRationale,
+			Examples: newLintExamples(
+				`enum Foo {
+  FOO_UNSPECIFIED = 0;
+}`,
+				`// Foo is a foo.
+enum Foo {
+  FOO_UNSPECIFIED = 0;
+}`,
+			),
+		},
+		"COMMENT_ENUM_VALUE": {
+			Description: "Enum values must have a leading comment that is not empty.",
+			Rationale:   commentRationale,
+			Examples: newLintExamples(
+				`enum Foo {
+  FOO_UNSPECIFIED = 0;
+}`,
+				`enum Foo {
+  // The zero value.
+  FOO_UNSPECIFIED = 0;
+}`,
+			),
+		},
+		"COMMENT_FIELD": {
+			Description: "Fields must have a leading comment that is not empty.",
+			Rationale:   commentRationale,
+			Examples: newLintExamples(
+				`message Foo {
+  string name = 1;
+}`,
+				`message Foo {
+  // The name of the foo.
+  string name = 1;
+}`,
+			),
+		},
+		"COMMENT_MESSAGE": {
+			Description: "Messages must have a leading comment that is not empty.",
+			Rationale:   commentRationale,
+			Examples: newLintExamples(
+				`message Foo {}`,
+				`// Foo is a foo.
+message Foo {}`,
+			),
+		},
+		"COMMENT_NO_TODO": {
+			Description: `Comments in files of stable packages, that is packages with a version suffix
+such as v1 that is not an alpha, beta, or test version, must not contain a TODO
+or FIXME marker. Lines of comments that match any of the regular expressions in
+comment_todo_allowed_patterns are not checked.`,
+			Rationale: `Comments are published with the schema and carried into the generated code,
+so unresolved notes in stable packages are seen by every consumer of the API.`,
+			Options: []*bufcheck.CheckerOptionDoc{
+				{
+					Name:        "comment_todo_allowed_patterns",
+					Description: "The regular expressions for the comment lines that may contain TODO or FIXME.",
+				},
+			},
+			Examples: []*bufcheck.CheckerExampleDoc{
+				{
+					Description: "In package foo.v1, this fails:",
+					Content: `// TODO: add pagination.
+rpc ListFoos(ListFoosRequest) returns (ListFoosResponse);`,
+				},
+				{
+					Description: "With comment_todo_allowed_patterns set to [\"TODO\\\\(#\\\\d+\\\\)\"], this passes:",
+					Content: `// TODO(#123): add pagination.
+rpc ListFoos(ListFoosRequest) returns (ListFoosResponse);`,
+				},
+			},
+		},
+		"COMMENT_ONEOF": {
+			Description: "Oneofs must have a leading comment that is not empty.",
+			Rationale:   commentRationale,
+			Examples: newLintExamples(
+				`message Foo {
+  oneof value {
+    string name = 1;
+  }
+}`,
+				`message Foo {
+  // The value of the foo.
+  oneof value {
+    string name = 1;
+  }
+}`,
+			),
+		},
+		"COMMENT_RPC": {
+			Description: "RPCs must have a leading comment that is not empty.",
+			Rationale:   commentRationale,
+			Examples: newLintExamples(
+				`service FooService {
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse);
+}`,
+				`service FooService {
+  // GetFoo gets a foo.
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse);
+}`,
+			),
+		},
+		"COMMENT_SERVICE": {
+			Description: "Services must have a leading comment that is not empty.",
+			Rationale:   commentRationale,
+			Examples: newLintExamples(
+				`service FooService {}`,
+				`// FooService manages foos.
+service FooService {}`,
+			),
+		},
+		"DIRECTORY_SAME_PACKAGE": {
+			Description: "All files within a directory relative to a root must declare the same package.",
+			Rationale: `Many languages generate code for a package into a single directory. Mixing
+packages within a directory results in generated code that either does not
+compile or is difficult to import.`,
+			Examples: []*bufcheck.CheckerExampleDoc{
+				{
+					Description: "This fails, as both files are in the directory foo/v1:",
+					Content: `// foo/v1/a.proto
+package foo.v1;
+
+// foo/v1/b.proto
+package bar.v1;`,
+				},
+			},
+		},
+		"ENUM_NO_ALLOW_ALIAS": {
+			Description: "Enums must not set the allow_alias option.",
+			Rationale: `Aliases result in multiple names for the same number, which most languages
+handle inconsistently, and which results in ambiguous JSON serialization.`,
+			Examples: newLintExamples(
+				`enum Foo {
+  option allow_alias = true;
+  FOO_UNSPECIFIED = 0;
+  FOO_ONE = 1;
+  FOO_UNO = 1;
+}`,
+				`enum Foo {
+  FOO_UNSPECIFIED = 0;
+  FOO_ONE = 1;
+}`,
+			),
+		},
+		"ENUM_PASCAL_CASE": {
+			Description: "Enum names must be PascalCase.",
+			Rationale:   namingRationale,
+			Options: []*bufcheck.CheckerOptionDoc{
+				namingExceptionsOptionDoc,
+			},
+			Examples: newLintExamples(
+				`enum foo_bar {}`,
+				`enum FooBar {}`,
+			),
+		},
+		"ENUM_VALUE_PREFIX": {
+			Description: "Enum value names must be prefixed with the enum name in UPPER_SNAKE_CASE followed by an underscore.",
+			Rationale: `Enum values are siblings of their enum, not children, so in languages such as
+C++ two enums in the same scope cannot have values with the same name.
+Prefixing values with the enum name prevents these collisions.`,
+			Examples: newLintExamples(
+				`enum FooBar {
+  UNSPECIFIED = 0;
+}`,
+				`enum FooBar {
+  FOO_BAR_UNSPECIFIED = 0;
+}`,
+			),
+		},
+		"ENUM_VALUE_UPPER_SNAKE_CASE": {
+			Description: "Enum value names must be UPPER_SNAKE_CASE.",
+			Rationale:   namingRationale,
+			Options: []*bufcheck.CheckerOptionDoc{
+				namingExceptionsOptionDoc,
+			},
+			Examples: newLintExamples(
+				`enum Foo {
+  fooUnspecified = 0;
+}`,
+				`enum Foo {
+  FOO_UNSPECIFIED = 0;
+}`,
+			),
+		},
+		"ENUM_ZERO_VALUE_SUFFIX": {
+			Description: "The enum value with number zero must be suffixed with the configured suffix, which is _UNSPECIFIED by default.",
+			Rationale: `The zero value is the default value of an enum field, and is what readers see
+when the field is not set. It should not have a meaning beyond "not set", so
+that callers can tell an unset field apart from a set one.`,
+			Options: []*bufcheck.CheckerOptionDoc{
+				{
+					Name:        "enum_zero_value_suffix",
+					Description: "The suffix for enum zero values. Defaults to _UNSPECIFIED.",
+				},
+			},
+			Examples: newLintExamples(
+				`enum Foo {
+  FOO_NONE = 0;
+}`,
+				`enum Foo {
+  FOO_UNSPECIFIED = 0;
+}`,
+			),
+		},
+		"FIELD_LOWER_SNAKE_CASE": {
+			Description: "Field names must be lower_snake_case.",
+			Rationale:   namingRationale,
+			Options: []*bufcheck.CheckerOptionDoc{
+				namingExceptionsOptionDoc,
+			},
+			Examples: newLintExamples(
+				`message Foo {
+  string fooName = 1;
+}`,
+				`message Foo {
+  string foo_name = 1;
+}`,
+			),
+		},
+		"FIELD_NO_DESCRIPTOR": {
+			Description: `Field names must not be any capitalization of "descriptor" with any number of leading or trailing underscores.`,
+			Rationale: `The generated code for some languages, such as Java and Python, has a
+descriptor accessor on each message, which a field with this name collides with.`,
+			Examples: newLintExamples(
+				`message Foo {
+  string descriptor = 1;
+}`,
+				`message Foo {
+  string foo_descriptor = 1;
+}`,
+			),
+		},
+		"FIELD_NO_JSON_NAME_COLLISION": {
+			Description: `Fields in a message must not have JSON names that are equal when compared
+case-insensitively, whether the JSON names are set with the json_name option or
+derived from the field names.`,
+			Rationale: `The JSON encoding uses the JSON names as keys, and several runtimes match keys
+case-insensitively when parsing, so colliding fields cannot be serialized or
+parsed correctly, even though the file compiles.`,
+			Examples: newLintExamples(
+				`message Foo {
+  string foo_bar = 1;
+  string foo_baz = 2 [json_name = "FooBar"];
+}`,
+				`message Foo {
+  string foo_bar = 1;
+  string foo_baz = 2;
+}`,
+			),
+		},
+		"FIELD_ORDERED": {
+			Description: `Fields must be declared in order of field number. Fields within a oneof are
+ordered separately from the other fields of the message. If
+field_ordered_required_first is set, required fields must be declared before
+all other fields. Violations can be fixed with lint --fix, which moves each
+field declaration together with its comments.`,
+			Rationale: `Declaring fields in order of field number keeps large messages reviewable,
+and makes it easy to see which field number is next.`,
+			Options: []*bufcheck.CheckerOptionDoc{
+				{
+					Name:        "field_ordered_required_first",
+					Description: "Require required fields to be declared before all other fields. Defaults to false.",
+				},
+			},
+			Examples: newLintExamples(
+				`message Foo {
+  string bar = 2;
+  string baz = 1;
+}`,
+				`message Foo {
+  string baz = 1;
+  string bar = 2;
+}`,
+			),
+		},
+		"FILE_LICENSE_HEADER": {
+			Description: `Files must start with a // comment containing the license header. Each line of
+the license header is expected as a // comment line, and {year} matches a year
+or a range of years such as 2019-2020. More comment lines can follow the license
+header. Nothing is checked if license_header is not set. Violations can be fixed
+with lint --fix, which inserts the license header with the current year into
+files that do not start with a comment.`,
+			Rationale: `Many organizations require every source file to carry a license or copyright
+notice, and checking this in lint keeps new files from missing it.`,
+			Options: []*bufcheck.CheckerOptionDoc{
+				{
+					Name:        "license_header",
+					Description: "The license header, without comment markers.",
+				},
+			},
+			Examples: []*bufcheck.CheckerExampleDoc{
+				{
+					Description: "With a license_header of \"Copyright {year} Acme, Inc.\", this fails:",
+					Content: `syntax = "proto3";
+
+package foo.v1;`,
+				},
+				{
+					Description: "This passes:",
+					Content: `// Copyright 2019-2020 Acme, Inc.
+
+syntax = "proto3";
+
+package foo.v1;`,
+				},
+			},
+		},
+		"FILE_LOWER_SNAKE_CASE": {
+			Description: "File names must be lower_snake_case.proto.",
+			Rationale: `Some languages derive generated file and class names from the Protobuf file
+name, and case-insensitive filesystems treat names differing only in case as the same file.`,
+			Examples: []*bufcheck.CheckerExampleDoc{
+				{
+					Description: "This fails:",
+					Content:     `// foo/v1/FooBar.proto`,
+				},
+				{
+					Description: "This passes:",
+					Content:     `// foo/v1/foo_bar.proto`,
+				},
+			},
+		},
+		"FILE_MAX_LINE_LENGTH": {
+			Description: `Lines must be at most max_line_length characters long. Lines that are part
+of an option declaration, such as a long go_package, are exempt. Only the
+Protobuf elements on a line are measured, so trailing comments do not count
+towards the line length.`,
+			Rationale: `Long lines are hard to read and review, especially in side-by-side diffs.`,
+			Options: []*bufcheck.CheckerOptionDoc{
+				{
+					Name:        "max_line_length",
+					Description: "The maximum line length. Defaults to 120.",
+				},
+			},
+		},
+		"FILE_MAX_LINES": {
+			Description: `Files must be at most max_file_lines lines long. A file ends at the last
+Protobuf element in the file, so comments and blank lines after the last
+element do not count towards the file length.`,
+			Rationale: `Very long files are hard to navigate, and usually contain definitions that
+belong in separate files.`,
+			Options: []*bufcheck.CheckerOptionDoc{
+				{
+					Name:        "max_file_lines",
+					Description: "The maximum number of lines in a file. Defaults to 1000.",
+				},
+			},
+		},
+		"FILE_NO_BOM_CRLF": {
+			Description: `Files must not start with a UTF-8 byte order mark, and must use LF line
+endings rather than CRLF line endings. This checker reads the source of files, so
+it only produces violations when the input is a directory, and results are never
+cached by lint --cache. Violations can be fixed with lint --fix, which removes the
+byte order mark and replaces CRLF line endings with LF line endings.`,
+			Rationale: `Byte order marks and CRLF line endings are usually introduced by editors on
+some machines, which results in spurious diffs when files are edited elsewhere, and
+some Protobuf plugins fail to parse files with a byte order mark.`,
+		},
+		"IMPORT_NO_PUBLIC": {
+			Description: "Imports must not be declared as public.",
+			Rationale: `Public imports are not supported consistently by Protobuf plugins, and make
+it unclear where a definition comes from.`,
+			Examples: newLintExamples(
+				`import public "foo/v1/foo.proto";`,
+				`import "foo/v1/foo.proto";`,
+			),
+		},
+		"IMPORT_NO_RESTRICTED": {
+			Description: `Imports must not match any of the import patterns in restricted_imports. If
+a pattern has a list of file patterns, the import is only restricted for files
+matching one of the file patterns. Patterns without glob characters match the
+path and everything within the directory of the path.`,
+			Rationale: `Restricting imports enforces layering between parts of a schema, such as
+internal definitions that public APIs must not depend on.`,
+			Options: []*bufcheck.CheckerOptionDoc{
+				{
+					Name:        "restricted_imports",
+					Description: "A map from import patterns to the file patterns the import is restricted for. An empty list of file patterns restricts the import for all files.",
+				},
+			},
+			Examples: []*bufcheck.CheckerExampleDoc{
+				{
+					Description: "With restricted_imports set to {\"internal\": [\"public\"]}, this fails in public/v1/foo.proto:",
+					Content:     `import "internal/v1/bar.proto";`,
+				},
+			},
+		},
+		"IMPORT_NO_WEAK": {
+			Description: "Imports must not be declared as weak.",
+			Rationale:   `Weak imports are an undocumented feature that is not supported by most Protobuf plugins.`,
+			Examples: newLintExamples(
+				`import weak "foo/v1/foo.proto";`,
+				`import "foo/v1/foo.proto";`,
+			),
+		},
+		"IMPORT_ORDERED": {
+			Description: `Imports must be sorted by path within groups, with the groups declared in
+order and separated by a single blank line. The first group is the Protobuf
+well-known types in google/protobuf, the second group is files outside of the
+files being linted such as dependencies, and the third group is the files being
+linted. This checker looks across all files, so results are never cached by
+lint --cache. Violations can be fixed with lint --fix, which moves each import
+declaration together with its comments.`,
+			Rationale: `Consistently ordered imports are easier to scan, and keep unrelated changes
+from producing merge conflicts, in the same way as goimports does for Go.`,
+			Examples: newLintExamples(
+				`import "foo/v1/foo.proto";
+import "google/protobuf/timestamp.proto";
+import "google/type/date.proto";`,
+				`import "google/protobuf/timestamp.proto";
+
+import "google/type/date.proto";
+
+import "foo/v1/foo.proto";`,
+			),
+		},
+		"MESSAGE_NO_CROSS_PACKAGE_DUPLICATE": {
+			Description: `Messages with the same name in different packages must not have similar
+fields. Fields are compared by name, number, label, and type, and two messages
+are similar if the number of matching fields divided by the number of distinct
+fields across both messages is at least the similarity threshold. Map entries and messages
+without fields are not checked. This checker looks across all files, so
+results are never cached by lint --cache.`,
+			Rationale: `Copying a message into another package, rather than importing it, results
+in definitions that drift apart over time.`,
+			Options: []*bufcheck.CheckerOptionDoc{
+				{
+					Name:        "message_duplicate_similarity_threshold",
+					Description: "The similarity at which messages are reported, greater than 0 and at most 1. Defaults to 0.8.",
+				},
+			},
+			Examples: []*bufcheck.CheckerExampleDoc{
+				{
+					Description: "This fails, as foo.v1.User and bar.v1.User have the same fields:",
+					Content: `// foo/v1/user.proto
+package foo.v1;
+message User {
+  string id = 1;
+  string name = 2;
+}
+
+// bar/v1/user.proto
+package bar.v1;
+message User {
+  string id = 1;
+  string name = 2;
+}`,
+				},
+			},
+		},
+		"MESSAGE_PASCAL_CASE": {
+			Description: "Message names must be PascalCase.",
+			Rationale:   namingRationale,
+			Options: []*bufcheck.CheckerOptionDoc{
+				namingExceptionsOptionDoc,
+			},
+			Examples: newLintExamples(
+				`message foo_bar {}`,
+				`message FooBar {}`,
+			),
+		},
+		"NAME_NO_GENERATED_COLLISION": {
+			Description: `Sibling names must not collide after they are converted to the names used in
+generated code. Fields, oneofs, and RPCs must not collide in Go or Java, where
+for example foo_bar and fooBar are both generated as FooBar. Nested messages
+and enums, and top-level messages, enums, and services within a file, must not
+collide in Go.`,
+			Rationale: `The names are distinct in the Protobuf definitions, so the file compiles, but the
+generated code declares the same identifier twice and does not compile.`,
+			Examples: newLintExamples(
+				`message Foo {
+  string foo_bar = 1;
+  string fooBar = 2;
+}`,
+				`message Foo {
+  string foo_bar = 1;
+  string foo_baz = 2;
+}`,
+			),
+		},
+		"ONEOF_LOWER_SNAKE_CASE": {
+			Description: "Oneof names must be lower_snake_case.",
+			Rationale:   namingRationale,
+			Options: []*bufcheck.CheckerOptionDoc{
+				namingExceptionsOptionDoc,
+			},
+			Examples: newLintExamples(
+				`message Foo {
+  oneof fooValue {
+    string name = 1;
+  }
+}`,
+				`message Foo {
+  oneof foo_value {
+    string name = 1;
+  }
+}`,
+			),
+		},
+		"PACKAGE_DEFINED": {
+			Description: "All files must declare a package.",
+			Rationale: `Definitions in files without a package are in the global namespace, and
+collide with definitions of the same name in any other file without a package.`,
+			Examples: newLintExamples(
+				`syntax = "proto3";
+
+message Foo {}`,
+				`syntax = "proto3";
+
+package foo.v1;
+
+message Foo {}`,
+			),
+		},
+		"PACKAGE_DIRECTORY_MATCH": {
+			Description: `All files must be within a directory relative to the root that matches their
+package, with each component of the package being a directory.`,
+			Rationale: `Matching directories to packages makes it possible to find the definition of
+a type from its name, and is required by the generated code layout of
+many languages.`,
+			Examples: []*bufcheck.CheckerExampleDoc{
+				{
+					Description: "This fails:",
+					Content: `// foo/foo.proto
+package foo.v1;`,
+				},
+				{
+					Description: "This passes:",
+					Content: `// foo/v1/foo.proto
+package foo.v1;`,
+				},
+			},
+		},
+		"PACKAGE_LOWER_SNAKE_CASE": {
+			Description: "Packages must be lower_snake.case, that is, each component of the package must be lower_snake_case.",
+			Rationale:   namingRationale,
+			Examples: newLintExamples(
+				`package fooBar.v1;`,
+				`package foo_bar.v1;`,
+			),
+		},
+		"PACKAGE_SAME_CSHARP_NAMESPACE": {
+			Description: "All files with the same package must have the same value for the csharp_namespace option, or all must leave it unset.",
+			Rationale:   packageSameOptionRationale,
+			Examples:    newPackageSameOptionExamples(`option csharp_namespace = "Foo.V1";`, `option csharp_namespace = "Foo.Other.V1";`),
+		},
+		"PACKAGE_SAME_DIRECTORY": {
+			Description: "All files with the same package must be in the same directory relative to the root.",
+			Rationale: `Many languages generate code for a package into a single directory. Splitting
+a package across directories splits the generated code.`,
+			Examples: []*bufcheck.CheckerExampleDoc{
+				{
+					Description: "This fails, as package foo.v1 is in two directories:",
+					Content: `// foo/v1/a.proto
+package foo.v1;
+
+// foo/v2/b.proto
+package foo.v1;`,
+				},
+			},
+		},
+		"PACKAGE_SAME_GO_PACKAGE": {
+			Description: "All files with the same package must have the same value for the go_package option, or all must leave it unset.",
+			Rationale:   packageSameOptionRationale,
+			Examples:    newPackageSameOptionExamples(`option go_package = "foov1";`, `option go_package = "foo";`),
+		},
+		"PACKAGE_SAME_JAVA_MULTIPLE_FILES": {
+			Description: "All files with the same package must have the same value for the java_multiple_files option, or all must leave it unset.",
+			Rationale:   packageSameOptionRationale,
+			Examples:    newPackageSameOptionExamples(`option java_multiple_files = true;`, `option java_multiple_files = false;`),
+		},
+		"PACKAGE_SAME_JAVA_PACKAGE": {
+			Description: "All files with the same package must have the same value for the java_package option, or all must leave it unset.",
+			Rationale:   packageSameOptionRationale,
+			Examples:    newPackageSameOptionExamples(`option java_package = "com.foo.v1";`, `option java_package = "com.foo";`),
+		},
+		"PACKAGE_SAME_PHP_NAMESPACE": {
+			Description: "All files with the same package must have the same value for the php_namespace option, or all must leave it unset.",
+			Rationale:   packageSameOptionRationale,
+			Examples:    newPackageSameOptionExamples(`option php_namespace = "Foo\\V1";`, `option php_namespace = "Foo";`),
+		},
+		"PACKAGE_SAME_RUBY_PACKAGE": {
+			Description: "All files with the same package must have the same value for the ruby_package option, or all must leave it unset.",
+			Rationale:   packageSameOptionRationale,
+			Examples:    newPackageSameOptionExamples(`option ruby_package = "Foo::V1";`, `option ruby_package = "Foo";`),
+		},
+		"PACKAGE_SAME_SWIFT_PREFIX": {
+			Description: "All files with the same package must have the same value for the swift_prefix option, or all must leave it unset.",
+			Rationale:   packageSameOptionRationale,
+			Examples:    newPackageSameOptionExamples(`option swift_prefix = "FooV1";`, `option swift_prefix = "Foo";`),
+		},
+		"PACKAGE_VERSION_SUFFIX": {
+			Description: `The last component of all packages must be a version. By default, this is of
+the form v1, v1test, v1alpha1, v1beta1, or v1p1beta1, where numbers are at
+least 1, and can be replaced by a custom pattern.`,
+			Rationale: `Versioned packages allow breaking changes to be made in a new version while
+the old version continues to be served.`,
+			Options: []*bufcheck.CheckerOptionDoc{
+				{
+					Name:        "package_version_suffix_pattern",
+					Description: "A regular expression the last component of all packages must match, replacing the default version forms.",
+				},
+			},
+			Examples: newLintExamples(
+				`package foo;`,
+				`package foo.v1;`,
+			),
+		},
+		"RPC_NO_CLIENT_STREAMING": {
+			Description: "RPCs must not be client streaming.",
+			Rationale: `Streaming RPCs are not supported by all RPC frameworks and proxies, and
+are more difficult to operate than unary RPCs.`,
+			Examples: newLintExamples(
+				`rpc Upload(stream UploadRequest) returns (UploadResponse);`,
+				`rpc Upload(UploadRequest) returns (UploadResponse);`,
+			),
+		},
+		"RPC_NO_RESTRICTED_CLIENT_STREAMING": {
+			Description: `RPCs with names matching any of the patterns in
+rpc_restricted_client_streaming_patterns must not be client streaming. Patterns
+are regular expressions that must match the entire RPC name. If no patterns are
+set, this checker does nothing.`,
+			Rationale: `Some kinds of RPCs, such as reads, should never accept a stream of requests
+per API guidelines, while client streaming is still allowed elsewhere.`,
+			Options: []*bufcheck.CheckerOptionDoc{
+				{
+					Name:        "rpc_restricted_client_streaming_patterns",
+					Description: "The regular expressions for the names of RPCs that must not be client streaming.",
+				},
+			},
+			Examples: []*bufcheck.CheckerExampleDoc{
+				{
+					Description: "With rpc_restricted_client_streaming_patterns set to [\"(Get|List).*\"], this fails:",
+					Content:     `rpc ListFoos(stream ListFoosRequest) returns (ListFoosResponse);`,
+				},
+			},
+		},
+		"RPC_NO_SERVER_STREAMING": {
+			Description: "RPCs must not be server streaming.",
+			Rationale: `Streaming RPCs are not supported by all RPC frameworks and proxies, and
+are more difficult to operate than unary RPCs.`,
+			Examples: newLintExamples(
+				`rpc Download(DownloadRequest) returns (stream DownloadResponse);`,
+				`rpc Download(DownloadRequest) returns (DownloadResponse);`,
+			),
+		},
+		"RPC_PASCAL_CASE": {
+			Description: "RPC names must be PascalCase.",
+			Rationale:   namingRationale,
+			Options: []*bufcheck.CheckerOptionDoc{
+				namingExceptionsOptionDoc,
+			},
+			Examples: newLintExamples(
+				`rpc get_foo(GetFooRequest) returns (GetFooResponse);`,
+				`rpc GetFoo(GetFooRequest) returns (GetFooResponse);`,
+			),
+		},
+		"RPC_REQUEST_RESPONSE_UNIQUE": {
+			Description: `Request and response types must each be used by only one RPC, and an RPC
+must not use the same type for its request and response.`,
+			Rationale: `Sharing request and response types between RPCs means that fields cannot be
+added for one RPC without affecting the others.`,
+			Options: []*bufcheck.CheckerOptionDoc{
+				{
+					Name:        "rpc_allow_same_request_response",
+					Description: "Allow an RPC to use the same type for its request and response.",
+				},
+				rpcAllowGoogleProtobufEmptyRequestsOptionDoc,
+				rpcAllowGoogleProtobufEmptyResponsesOptionDoc,
+			},
+			Examples: newLintExamples(
+				`rpc GetFoo(GetFooRequest) returns (Foo);
+rpc UpdateFoo(UpdateFooRequest) returns (Foo);`,
+				`rpc GetFoo(GetFooRequest) returns (GetFooResponse);
+rpc UpdateFoo(UpdateFooRequest) returns (UpdateFooResponse);`,
+			),
+		},
+		"RPC_REQUEST_STANDARD_NAME": {
+			Description: "Request types must be named RPCNameRequest or ServiceNameRPCNameRequest.",
+			Rationale: `Standard names make the request type of an RPC obvious, and keep request
+types from being confused with other messages.`,
+			Options: []*bufcheck.CheckerOptionDoc{
+				rpcAllowGoogleProtobufEmptyRequestsOptionDoc,
+			},
+			Examples: newLintExamples(
+				`rpc GetFoo(FooQuery) returns (GetFooResponse);`,
+				`rpc GetFoo(GetFooRequest) returns (GetFooResponse);`,
+			),
+		},
+		"RPC_RESPONSE_STANDARD_NAME": {
+			Description: "Response types must be named RPCNameResponse or ServiceNameRPCNameResponse.",
+			Rationale: `Standard names make the response type of an RPC obvious, and keep response
+types from being confused with other messages.`,
+			Options: []*bufcheck.CheckerOptionDoc{
+				rpcAllowGoogleProtobufEmptyResponsesOptionDoc,
+			},
+			Examples: newLintExamples(
+				`rpc GetFoo(GetFooRequest) returns (Foo);`,
+				`rpc GetFoo(GetFooRequest) returns (GetFooResponse);`,
+			),
+		},
+		"RPC_STREAMING_LIFECYCLE_DOCUMENTED": {
+			Description: `Client and server streaming RPCs must have leading comments that document
+their keepalive and cancellation behavior, that is comments that contain
+"keepalive", "keep-alive", or "keep alive", and comments that contain "cancel".
+Words are matched case-insensitively.`,
+			Rationale: `Streams are long-lived, so clients need to know how idle streams are kept
+alive and what happens to in-flight messages when either side cancels.`,
+			Examples: newLintExamples(
+				`// Watch watches for changes to foos.
+rpc Watch(WatchRequest) returns (stream WatchResponse);`,
+				`// Watch watches for changes to foos.
+//
+// The server sends a keepalive message every 30 seconds. Cancelling the
+// call stops the watch, and messages that were not yet sent are dropped.
+rpc Watch(WatchRequest) returns (stream WatchResponse);`,
+			),
+		},
+		"RPC_STREAMING_PAGINATION_DOCUMENTED": {
+			Description: `Server streaming RPCs must have leading comments that document a paginated
+alternative, that is comments that contain "paginated" or "pagination".
+Words are matched case-insensitively.`,
+			Rationale: `Not all clients can consume streams, such as browsers and clients behind
+proxies, so these clients need a paginated RPC that returns the same results.`,
+			Examples: newLintExamples(
+				`// StreamFoos streams all foos.
+rpc StreamFoos(StreamFoosRequest) returns (stream StreamFoosResponse);`,
+				`// StreamFoos streams all foos.
+//
+// ListFoos is the paginated alternative.
+rpc StreamFoos(StreamFoosRequest) returns (stream StreamFoosResponse);`,
+			),
+		},
+		"SERVICE_PASCAL_CASE": {
+			Description: "Service names must be PascalCase.",
+			Rationale:   namingRationale,
+			Options: []*bufcheck.CheckerOptionDoc{
+				namingExceptionsOptionDoc,
+			},
+			Examples: newLintExamples(
+				`service foo_service {}`,
+				`service FooService {}`,
+			),
+		},
+		"SERVICE_SUFFIX": {
+			Description: "Service names must be suffixed with the configured suffix, which is Service by default.",
+			Rationale: `A consistent suffix distinguishes services from messages in generated code,
+where both are often in the same namespace.`,
+			Options: []*bufcheck.CheckerOptionDoc{
+				{
+					Name:        "service_suffix",
+					Description: "The suffix for service names. Defaults to Service.",
+				},
+			},
+			Examples: newLintExamples(
+				`service Foo {}`,
+				`service FooService {}`,
+			),
+		},
+	}
+)
+
+// newLintExamples returns examples for content that fails and content that passes.
+func newLintExamples(failContent string, passContent string) []*bufcheck.CheckerExampleDoc {
+	return []*bufcheck.CheckerExampleDoc{
+		{
+			Description: "This fails:",
+			Content:     failContent,
+		},
+		{
+			Description: "This passes:",
+			Content:     passContent,
+		},
+	}
+}
+
+func newPackageSameOptionExamples(option string, otherOption string) []*bufcheck.CheckerExampleDoc {
+	return []*bufcheck.CheckerExampleDoc{
+		{
+			Description: "This fails, as the files are in the same package:",
+			Content: `// foo/v1/a.proto
+package foo.v1;
+` + option + `
+
+// foo/v1/b.proto
+package foo.v1;
+` + otherOption,
+		},
+	}
+}
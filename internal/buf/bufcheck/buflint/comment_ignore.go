@@ -0,0 +1,150 @@
+package buflint
+
+import (
+	"sort"
+	"strings"
+
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// CommentIgnorePrefix is the prefix of a comment ignore directive.
+//
+// A leading comment line of the form "buf:lint:ignore ID" ignores the lint checker
+// with the given ID for the commented element and any elements nested within it.
+// Comment ignores are only honored if AllowCommentIgnores is set on the Config.
+const CommentIgnorePrefix = "buf:lint:ignore"
+
+// CommentIgnore is a comment ignore directive.
+type CommentIgnore struct {
+	// Path is the root file path of the file containing the directive.
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	// Line is the line of the directive.
+	//
+	// This is exact for line comments, but approximate for block comments.
+	Line int `json:"line,omitempty" yaml:"line,omitempty"`
+	// ID is the ID of the ignored checker.
+	ID string `json:"id,omitempty" yaml:"id,omitempty"`
+
+	// the span of the commented element, not zero-indexed
+	startLine   int
+	startColumn int
+	endLine     int
+	endColumn   int
+}
+
+// GetCommentIgnores gets all comment ignore directives within the image.
+//
+// The image must have source code info.
+// The returned CommentIgnores are sorted by path, line, then ID.
+func GetCommentIgnores(image *imagev1beta1.Image) []*CommentIgnore {
+	var commentIgnores []*CommentIgnore
+	for _, file := range image.GetFile() {
+		for _, location := range file.GetSourceCodeInfo().GetLocation() {
+			commentIgnores = append(commentIgnores, getLocationCommentIgnores(file.GetName(), location)...)
+		}
+	}
+	sort.Slice(
+		commentIgnores,
+		func(i int, j int) bool {
+			one := commentIgnores[i]
+			two := commentIgnores[j]
+			if one.Path != two.Path {
+				return one.Path < two.Path
+			}
+			if one.Line != two.Line {
+				return one.Line < two.Line
+			}
+			return one.ID < two.ID
+		},
+	)
+	return commentIgnores
+}
+
+func getLocationCommentIgnores(path string, location *descriptor.SourceCodeInfo_Location) []*CommentIgnore {
+	leadingComments := location.GetLeadingComments()
+	if leadingComments == "" {
+		return nil
+	}
+	span := location.GetSpan()
+	// 3 elements means the span is on one line
+	if len(span) != 3 && len(span) != 4 {
+		return nil
+	}
+	startLine := int(span[0]) + 1
+	startColumn := int(span[1]) + 1
+	endLine := startLine
+	endColumn := int(span[2]) + 1
+	if len(span) == 4 {
+		endLine = int(span[2]) + 1
+		endColumn = int(span[3]) + 1
+	}
+	lines := strings.Split(strings.TrimSuffix(leadingComments, "\n"), "\n")
+	var commentIgnores []*CommentIgnore
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != CommentIgnorePrefix {
+			continue
+		}
+		commentIgnores = append(
+			commentIgnores,
+			&CommentIgnore{
+				Path: path,
+				// leading comments directly precede the element
+				Line:        startLine - len(lines) + i,
+				ID:          fields[1],
+				startLine:   startLine,
+				startColumn: startColumn,
+				endLine:     endLine,
+				endColumn:   endColumn,
+			},
+		)
+	}
+	return commentIgnores
+}
+
+// filterFileAnnotationsCommentIgnores removes the FileAnnotations that are
+// ignored by a comment ignore directive.
+func filterFileAnnotationsCommentIgnores(
+	fileAnnotations []*filev1beta1.FileAnnotation,
+	commentIgnores []*CommentIgnore,
+) []*filev1beta1.FileAnnotation {
+	if len(commentIgnores) == 0 {
+		return fileAnnotations
+	}
+	filteredFileAnnotations := make([]*filev1beta1.FileAnnotation, 0, len(fileAnnotations))
+	for _, fileAnnotation := range fileAnnotations {
+		if !isFileAnnotationCommentIgnored(fileAnnotation, commentIgnores) {
+			filteredFileAnnotations = append(filteredFileAnnotations, fileAnnotation)
+		}
+	}
+	return filteredFileAnnotations
+}
+
+func isFileAnnotationCommentIgnored(
+	fileAnnotation *filev1beta1.FileAnnotation,
+	commentIgnores []*CommentIgnore,
+) bool {
+	if fileAnnotation.Path == "" || fileAnnotation.StartLine == 0 {
+		return false
+	}
+	line := int(fileAnnotation.StartLine)
+	column := int(fileAnnotation.StartColumn)
+	for _, commentIgnore := range commentIgnores {
+		if commentIgnore.Path != fileAnnotation.Path || commentIgnore.ID != fileAnnotation.Type {
+			continue
+		}
+		if line < commentIgnore.startLine || line > commentIgnore.endLine {
+			continue
+		}
+		if line == commentIgnore.startLine && column < commentIgnore.startColumn {
+			continue
+		}
+		if line == commentIgnore.endLine && column > commentIgnore.endColumn {
+			continue
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,212 @@
+package buflint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+)
+
+// FailOn says which lint violations fail a lint run.
+type FailOn int
+
+const (
+	// FailOnError fails a lint run if there are any errors.
+	//
+	// This is the default.
+	FailOnError FailOn = iota + 1
+	// FailOnWarning fails a lint run if there are any errors or warnings.
+	FailOnWarning
+	// FailOnNever never fails a lint run because of lint violations.
+	FailOnNever
+)
+
+// String implements fmt.Stringer.
+func (f FailOn) String() string {
+	switch f {
+	case FailOnError:
+		return "error"
+	case FailOnWarning:
+		return "warning"
+	case FailOnNever:
+		return "never"
+	default:
+		return fmt.Sprintf("%d", int(f))
+	}
+}
+
+// ParseFailOn parses the FailOn.
+//
+// The empty string is parsed as FailOnError.
+func ParseFailOn(s string) (FailOn, error) {
+	switch t := strings.TrimSpace(strings.ToLower(s)); t {
+	case "error", "":
+		return FailOnError, nil
+	case "warning":
+		return FailOnWarning, nil
+	case "never":
+		return FailOnNever, nil
+	default:
+		return 0, fmt.Errorf("unknown fail_on value: %q", t)
+	}
+}
+
+// FailurePolicy says which lint violations fail a lint run.
+//
+// Each lint violation is either an error or a warning. Violations are errors
+// unless their checker is in WarningIDs, or every FailureThreshold that contains
+// their checker has not been exceeded.
+type FailurePolicy struct {
+	FailOn FailOn
+	// WarningIDs are the IDs of the checkers whose violations are always warnings.
+	WarningIDs map[string]struct{}
+	// Thresholds are sorted by IDOrCategory.
+	Thresholds []*FailureThreshold
+}
+
+// FailureThreshold is a number of violations allowed for a group of checkers
+// before their violations become errors.
+type FailureThreshold struct {
+	// IDOrCategory is the checker ID or category the threshold was configured for.
+	IDOrCategory string
+	// IDs are the IDs of the checkers within IDOrCategory.
+	IDs map[string]struct{}
+	// MaxViolations is the maximum number of violations of the checkers that
+	// are warnings. If there are more, all the violations are errors.
+	MaxViolations int
+}
+
+// ShouldFail returns true if the FileAnnotations should fail a lint run.
+//
+// If the FailurePolicy is nil, this returns true if there are any FileAnnotations.
+// FileAnnotations that are not from a lint checker, such as build errors, are always errors.
+func (p *FailurePolicy) ShouldFail(fileAnnotations []*filev1beta1.FileAnnotation) bool {
+	typeToCount := make(map[string]int)
+	for _, fileAnnotation := range fileAnnotations {
+		typeToCount[fileAnnotation.Type]++
+	}
+	return p.ShouldFailTypeCounts(typeToCount)
+}
+
+// ShouldFailTypeCounts returns true if FileAnnotations with the given number of
+// FileAnnotations per type should fail a lint run.
+//
+// This is the same as ShouldFail, for FileAnnotations that are not held in memory.
+func (p *FailurePolicy) ShouldFailTypeCounts(typeToCount map[string]int) bool {
+	total := 0
+	for _, count := range typeToCount {
+		total += count
+	}
+	if total == 0 {
+		return false
+	}
+	if p == nil {
+		return true
+	}
+	switch p.FailOn {
+	case FailOnNever:
+		return false
+	case FailOnWarning:
+		return true
+	}
+	thresholdExceeded := make([]bool, len(p.Thresholds))
+	for i, threshold := range p.Thresholds {
+		numViolations := 0
+		for id := range threshold.IDs {
+			numViolations += typeToCount[id]
+		}
+		thresholdExceeded[i] = numViolations > threshold.MaxViolations
+	}
+	for typ, count := range typeToCount {
+		if count > 0 && !p.isWarning(typ, thresholdExceeded) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *FailurePolicy) isWarning(typ string, thresholdExceeded []bool) bool {
+	if _, ok := p.WarningIDs[typ]; ok {
+		return true
+	}
+	inThreshold := false
+	for i, threshold := range p.Thresholds {
+		if _, ok := threshold.IDs[typ]; !ok {
+			continue
+		}
+		if thresholdExceeded[i] {
+			return false
+		}
+		inThreshold = true
+	}
+	return inThreshold
+}
+
+func newFailurePolicy(
+	failOnString string,
+	warn []string,
+	idOrCategoryToMaxViolations map[string]int,
+) (*FailurePolicy, error) {
+	failOn, err := ParseFailOn(failOnString)
+	if err != nil {
+		return nil, err
+	}
+	warningIDs := make(map[string]struct{})
+	for _, idOrCategory := range warn {
+		ids, err := getIDsForIDOrCategory(idOrCategory)
+		if err != nil {
+			return nil, err
+		}
+		for id := range ids {
+			warningIDs[id] = struct{}{}
+		}
+	}
+	thresholds := make([]*FailureThreshold, 0, len(idOrCategoryToMaxViolations))
+	for idOrCategory, maxViolations := range idOrCategoryToMaxViolations {
+		if maxViolations < 0 {
+			return nil, fmt.Errorf("threshold for %q cannot be negative", idOrCategory)
+		}
+		ids, err := getIDsForIDOrCategory(idOrCategory)
+		if err != nil {
+			return nil, err
+		}
+		thresholds = append(
+			thresholds,
+			&FailureThreshold{
+				IDOrCategory:  idOrCategory,
+				IDs:           ids,
+				MaxViolations: maxViolations,
+			},
+		)
+	}
+	sort.Slice(
+		thresholds,
+		func(i int, j int) bool {
+			return thresholds[i].IDOrCategory < thresholds[j].IDOrCategory
+		},
+	)
+	return &FailurePolicy{
+		FailOn:     failOn,
+		WarningIDs: warningIDs,
+		Thresholds: thresholds,
+	}, nil
+}
+
+func getIDsForIDOrCategory(idOrCategory string) (map[string]struct{}, error) {
+	if _, ok := v1IDToCategories[idOrCategory]; ok {
+		return map[string]struct{}{idOrCategory: {}}, nil
+	}
+	ids := make(map[string]struct{})
+	for id, categories := range v1IDToCategories {
+		for _, category := range categories {
+			if category == idOrCategory {
+				ids[id] = struct{}{}
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("%q is not a known id or category", idOrCategory)
+	}
+	return ids, nil
+}
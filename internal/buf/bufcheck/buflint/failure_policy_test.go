@@ -0,0 +1,94 @@
+package buflint
+
+import (
+	"testing"
+
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailurePolicy(t *testing.T) {
+	t.Parallel()
+	fileAnnotations := []*filev1beta1.FileAnnotation{
+		{Type: "PACKAGE_DIRECTORY_MATCH"},
+		{Type: "FIELD_LOWER_SNAKE_CASE"},
+		{Type: "FIELD_LOWER_SNAKE_CASE"},
+	}
+	for _, testCase := range []struct {
+		name                        string
+		failOn                      string
+		warn                        []string
+		idOrCategoryToMaxViolations map[string]int
+		expectedShouldFail          bool
+	}{
+		{
+			name:               "default",
+			expectedShouldFail: true,
+		},
+		{
+			name:               "never",
+			failOn:             "never",
+			expectedShouldFail: false,
+		},
+		{
+			name:               "warn_all",
+			warn:               []string{"FILE_LAYOUT", "FIELD_LOWER_SNAKE_CASE"},
+			expectedShouldFail: false,
+		},
+		{
+			name:               "warn_all_fail_on_warning",
+			failOn:             "warning",
+			warn:               []string{"FILE_LAYOUT", "FIELD_LOWER_SNAKE_CASE"},
+			expectedShouldFail: true,
+		},
+		{
+			name:               "warn_some",
+			warn:               []string{"FILE_LAYOUT"},
+			expectedShouldFail: true,
+		},
+		{
+			name:                        "thresholds_not_exceeded",
+			warn:                        []string{"FILE_LAYOUT"},
+			idOrCategoryToMaxViolations: map[string]int{"STYLE_BASIC": 2},
+			expectedShouldFail:          false,
+		},
+		{
+			name:                        "thresholds_exceeded",
+			warn:                        []string{"FILE_LAYOUT"},
+			idOrCategoryToMaxViolations: map[string]int{"STYLE_BASIC": 2, "FIELD_LOWER_SNAKE_CASE": 1},
+			expectedShouldFail:          true,
+		},
+		{
+			name:                        "thresholds_category",
+			idOrCategoryToMaxViolations: map[string]int{"BASIC": 3},
+			expectedShouldFail:          false,
+		},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			failurePolicy, err := newFailurePolicy(testCase.failOn, testCase.warn, testCase.idOrCategoryToMaxViolations)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedShouldFail, failurePolicy.ShouldFail(fileAnnotations))
+			assert.False(t, failurePolicy.ShouldFail(nil))
+		})
+	}
+	// nil policies fail on any FileAnnotation
+	var failurePolicy *FailurePolicy
+	assert.True(t, failurePolicy.ShouldFail(fileAnnotations))
+	// FileAnnotations not from a checker are always errors
+	failurePolicy, err := newFailurePolicy("", []string{"BASIC"}, nil)
+	require.NoError(t, err)
+	assert.True(t, failurePolicy.ShouldFail([]*filev1beta1.FileAnnotation{{Type: "COMPILE"}}))
+}
+
+func TestFailurePolicyError(t *testing.T) {
+	t.Parallel()
+	_, err := newFailurePolicy("sometimes", nil, nil)
+	assert.Error(t, err)
+	_, err = newFailurePolicy("", []string{"FOO"}, nil)
+	assert.Error(t, err)
+	_, err = newFailurePolicy("", nil, map[string]int{"BASIC": -1})
+	assert.Error(t, err)
+}
@@ -0,0 +1,159 @@
+package buflint
+
+import (
+	"time"
+
+	"github.com/bufbuild/buf/internal/buf/bufcheck/buflint/internal"
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/bufbuild/buf/internal/pkg/protodesc"
+)
+
+const (
+	// fieldOrderedID is the ID of the FIELD_ORDERED checker.
+	fieldOrderedID = "FIELD_ORDERED"
+	// importOrderedID is the ID of the IMPORT_ORDERED checker.
+	importOrderedID = "IMPORT_ORDERED"
+	// fileLicenseHeaderID is the ID of the FILE_LICENSE_HEADER checker.
+	fileLicenseHeaderID = "FILE_LICENSE_HEADER"
+	// fileNoBOMCRLFID is the ID of the FILE_NO_BOM_CRLF checker.
+	fileNoBOMCRLFID = "FILE_NO_BOM_CRLF"
+)
+
+// Fix fixes the FileAnnotations that can be fixed automatically.
+//
+// Only FIELD_ORDERED, IMPORT_ORDERED, FILE_LICENSE_HEADER, and FILE_NO_BOM_CRLF
+// FileAnnotations can currently be fixed. License headers are inserted with the
+// current year.
+//
+// The image must have source code info, and the FileAnnotations must use the
+// image file paths, that is FixFileAnnotationPaths must not have been called.
+// readFile returns the source of the file with the given image file path.
+//
+// Returns the fixed source of each changed file by image file path. A file is
+// only changed if all of the FileAnnotations within it can be fixed.
+func Fix(
+	lintConfig *Config,
+	image *imagev1beta1.Image,
+	fileAnnotations []*filev1beta1.FileAnnotation,
+	readFile func(string) ([]byte, error),
+) (map[string][]byte, error) {
+	pathToLocations := make(map[string]map[fileAnnotationLocation]struct{})
+	importOrderedPaths := make(map[string]struct{})
+	fileLicenseHeaderPaths := make(map[string]struct{})
+	fileNoBOMCRLFPaths := make(map[string]struct{})
+	for _, fileAnnotation := range fileAnnotations {
+		if fileAnnotation.Path == "" {
+			continue
+		}
+		if fileAnnotation.Type == importOrderedID {
+			importOrderedPaths[fileAnnotation.Path] = struct{}{}
+			continue
+		}
+		if fileAnnotation.Type == fileLicenseHeaderID {
+			fileLicenseHeaderPaths[fileAnnotation.Path] = struct{}{}
+			continue
+		}
+		if fileAnnotation.Type == fileNoBOMCRLFID {
+			fileNoBOMCRLFPaths[fileAnnotation.Path] = struct{}{}
+			continue
+		}
+		if fileAnnotation.Type != fieldOrderedID {
+			continue
+		}
+		locations, ok := pathToLocations[fileAnnotation.Path]
+		if !ok {
+			locations = make(map[fileAnnotationLocation]struct{})
+			pathToLocations[fileAnnotation.Path] = locations
+		}
+		locations[fileAnnotationLocation{
+			line:   int(fileAnnotation.StartLine),
+			column: int(fileAnnotation.StartColumn),
+		}] = struct{}{}
+	}
+	localFilePaths := make(map[string]struct{}, len(image.GetFile()))
+	for _, fileDescriptorProto := range image.GetFile() {
+		localFilePaths[fileDescriptorProto.GetName()] = struct{}{}
+	}
+	pathToData := make(map[string][]byte)
+	for _, fileDescriptorProto := range image.GetFile() {
+		locations := pathToLocations[fileDescriptorProto.GetName()]
+		_, fixImports := importOrderedPaths[fileDescriptorProto.GetName()]
+		_, fixLicenseHeader := fileLicenseHeaderPaths[fileDescriptorProto.GetName()]
+		_, fixBOMCRLF := fileNoBOMCRLFPaths[fileDescriptorProto.GetName()]
+		if len(locations) == 0 && !fixImports && !fixLicenseHeader && !fixBOMCRLF {
+			continue
+		}
+		file, err := protodesc.NewFile(fileDescriptorProto)
+		if err != nil {
+			return nil, err
+		}
+		data, err := readFile(file.FilePath())
+		if err != nil {
+			return nil, err
+		}
+		config := lintConfig
+		if rootPath := getConfigOverrideRootPath(lintConfig.Overrides, file.FilePath()); rootPath != "" {
+			for _, configOverride := range lintConfig.Overrides {
+				if configOverride.RootPath == rootPath {
+					config = configOverride.Config
+				}
+			}
+		}
+		// the byte order mark and line endings are fixed first, as the build
+		// ignores the byte order mark and this does not change the lines
+		fixedData := data
+		if fixBOMCRLF {
+			fixedData = internal.FixFileNoBOMCRLF(fixedData)
+		}
+		// the fields are fixed next, as this does not change the number of lines
+		// and the import locations stay valid
+		fixedData, ok, err := internal.FixFieldOrdered(
+			file,
+			fixedData,
+			config.fieldOrderedRequiredFirst,
+			func(field protodesc.Field) bool {
+				location := field.Location()
+				if location == nil {
+					return false
+				}
+				_, ok := locations[fileAnnotationLocation{
+					line:   location.StartLine(),
+					column: location.StartColumn(),
+				}]
+				return ok
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if fixImports {
+			fixedData, ok, err = internal.FixImportOrdered(file, fixedData, localFilePaths)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		// the license header is inserted last, as this moves every other line
+		if fixLicenseHeader {
+			fixedData, ok = internal.FixFileLicenseHeader(fixedData, config.licenseHeader, time.Now().Year())
+			if !ok {
+				continue
+			}
+		}
+		if string(fixedData) != string(data) {
+			pathToData[file.FilePath()] = fixedData
+		}
+	}
+	return pathToData, nil
+}
+
+type fileAnnotationLocation struct {
+	line   int
+	column int
+}
@@ -5,7 +5,9 @@ import (
 
 	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
 	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/bufbuild/buf/internal/pkg/ext/extfile"
 	"github.com/bufbuild/buf/internal/pkg/protodesc"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagepath"
 	"go.uber.org/zap"
 )
 
@@ -33,5 +35,126 @@ func (h *handler) LintCheck(
 	if err != nil {
 		return nil, err
 	}
-	return h.lintRunner.Check(ctx, lintConfig, files)
+	fileAnnotations, err := h.lintCheck(ctx, lintConfig, image, files)
+	if err != nil {
+		return nil, err
+	}
+	if len(lintConfig.Overrides) == 0 {
+		return fileAnnotations, nil
+	}
+	filteredFileAnnotations := filterFileAnnotationsForOverride(fileAnnotations, lintConfig.Overrides, "")
+	for _, configOverride := range lintConfig.Overrides {
+		overrideFileAnnotations, err := h.lintCheck(ctx, configOverride.Config, image, files)
+		if err != nil {
+			return nil, err
+		}
+		filteredFileAnnotations = append(
+			filteredFileAnnotations,
+			filterFileAnnotationsForOverride(overrideFileAnnotations, lintConfig.Overrides, configOverride.RootPath)...,
+		)
+	}
+	extfile.SortFileAnnotations(filteredFileAnnotations)
+	return filteredFileAnnotations, nil
+}
+
+func (h *handler) LintCheckStream(
+	ctx context.Context,
+	lintConfig *Config,
+	image *imagev1beta1.Image,
+	fileAnnotationFunc func(*filev1beta1.FileAnnotation) error,
+) error {
+	files, err := protodesc.NewFilesUnstable(ctx, image.GetFile()...)
+	if err != nil {
+		return err
+	}
+	if err := h.lintCheckStream(ctx, lintConfig, image, files, lintConfig.Overrides, "", fileAnnotationFunc); err != nil {
+		return err
+	}
+	for _, configOverride := range lintConfig.Overrides {
+		if err := h.lintCheckStream(ctx, configOverride.Config, image, files, lintConfig.Overrides, configOverride.RootPath, fileAnnotationFunc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *handler) lintCheck(
+	ctx context.Context,
+	lintConfig *Config,
+	image *imagev1beta1.Image,
+	files []protodesc.File,
+) ([]*filev1beta1.FileAnnotation, error) {
+	fileAnnotations, err := h.lintRunner.Check(ctx, lintConfig, files)
+	if err != nil {
+		return nil, err
+	}
+	if lintConfig.AllowCommentIgnores {
+		fileAnnotations = filterFileAnnotationsCommentIgnores(fileAnnotations, GetCommentIgnores(image))
+	}
+	return fileAnnotations, nil
+}
+
+// lintCheckStream is the streaming equivalent of lintCheck followed by
+// filterFileAnnotationsForOverride.
+func (h *handler) lintCheckStream(
+	ctx context.Context,
+	lintConfig *Config,
+	image *imagev1beta1.Image,
+	files []protodesc.File,
+	configOverrides []*ConfigOverride,
+	rootPath string,
+	fileAnnotationFunc func(*filev1beta1.FileAnnotation) error,
+) error {
+	var commentIgnores []*CommentIgnore
+	if lintConfig.AllowCommentIgnores {
+		commentIgnores = GetCommentIgnores(image)
+	}
+	return h.lintRunner.CheckStream(
+		ctx,
+		lintConfig,
+		files,
+		func(fileAnnotation *filev1beta1.FileAnnotation) error {
+			if getConfigOverrideRootPath(configOverrides, fileAnnotation.Path) != rootPath {
+				return nil
+			}
+			if isFileAnnotationCommentIgnored(fileAnnotation, commentIgnores) {
+				return nil
+			}
+			return fileAnnotationFunc(fileAnnotation)
+		},
+	)
+}
+
+// filterFileAnnotationsForOverride returns the FileAnnotations that the override with
+// the given root path applies to, where the empty root path is the base Config.
+//
+// FileAnnotations without a path only apply to the base Config.
+func filterFileAnnotationsForOverride(
+	fileAnnotations []*filev1beta1.FileAnnotation,
+	configOverrides []*ConfigOverride,
+	rootPath string,
+) []*filev1beta1.FileAnnotation {
+	filteredFileAnnotations := make([]*filev1beta1.FileAnnotation, 0, len(fileAnnotations))
+	for _, fileAnnotation := range fileAnnotations {
+		if getConfigOverrideRootPath(configOverrides, fileAnnotation.Path) == rootPath {
+			filteredFileAnnotations = append(filteredFileAnnotations, fileAnnotation)
+		}
+	}
+	return filteredFileAnnotations
+}
+
+// getConfigOverrideRootPath returns the most specific override root path
+// that contains the path, or empty if there is none.
+func getConfigOverrideRootPath(configOverrides []*ConfigOverride, path string) string {
+	if path == "" {
+		return ""
+	}
+	result := ""
+	for _, configOverride := range configOverrides {
+		if storagepath.MapContainsMatch(map[string]struct{}{configOverride.RootPath: {}}, path) &&
+			len(configOverride.RootPath) > len(result) {
+			result = configOverride.RootPath
+		}
+	}
+	return result
 }
@@ -0,0 +1,251 @@
+package internal
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/pkg/protodesc"
+)
+
+// FixFieldOrdered reorders the field declarations within the source of the
+// file so that the fields are ordered as FIELD_ORDERED expects.
+//
+// Only the fields in the same message or oneof as a field that shouldFix
+// returns true for are reordered. Fields are moved together with their
+// leading comments and trailing comments on the same line, and every other
+// declaration stays where it is.
+//
+// Returns false if any of the fields to reorder cannot be moved safely, for
+// example because they share a line with another declaration, in which case
+// the source is not changed.
+func FixFieldOrdered(
+	file protodesc.File,
+	data []byte,
+	requiredFirst bool,
+	shouldFix func(protodesc.Field) bool,
+) ([]byte, bool, error) {
+	lines := strings.Split(string(data), "\n")
+	var edits []*lineEdit
+	fixable := true
+	if err := protodesc.ForEachMessage(
+		func(message protodesc.Message) error {
+			if message.IsMapEntry() {
+				return nil
+			}
+			for _, fields := range getFieldOrderGroups(message) {
+				groupEdits, ok := getFieldOrderedEdits(lines, fields, requiredFirst, shouldFix)
+				if !ok {
+					fixable = false
+				}
+				edits = append(edits, groupEdits...)
+			}
+			return nil
+		},
+		file,
+	); err != nil {
+		return nil, false, err
+	}
+	if !fixable {
+		return nil, false, nil
+	}
+	if len(edits) == 0 {
+		return data, true, nil
+	}
+	// apply the edits from the bottom so that the line numbers of earlier edits stay valid
+	sort.Slice(edits, func(i int, j int) bool { return edits[i].startIndex > edits[j].startIndex })
+	for _, edit := range edits {
+		newLines := make([]string, 0, len(lines)-(edit.endIndex-edit.startIndex)+len(edit.lines))
+		newLines = append(newLines, lines[:edit.startIndex]...)
+		newLines = append(newLines, edit.lines...)
+		newLines = append(newLines, lines[edit.endIndex:]...)
+		lines = newLines
+	}
+	return []byte(strings.Join(lines, "\n")), true, nil
+}
+
+// getFieldOrderGroups returns the fields of the message that FIELD_ORDERED
+// orders relative to each other, in declaration order.
+//
+// Fields declared directly within the message are one group, and the fields
+// of each oneof are a group, as fields cannot be moved in or out of a oneof.
+func getFieldOrderGroups(message protodesc.Message) [][]protodesc.Field {
+	var messageFields []protodesc.Field
+	oneofFields := make([][]protodesc.Field, len(message.Oneofs()))
+	for _, field := range message.Fields() {
+		if oneofIndex, ok := field.OneofIndex(); ok && oneofIndex < len(oneofFields) {
+			oneofFields[oneofIndex] = append(oneofFields[oneofIndex], field)
+		} else {
+			messageFields = append(messageFields, field)
+		}
+	}
+	return append([][]protodesc.Field{messageFields}, oneofFields...)
+}
+
+// fieldOrderLess returns true if one should be declared before two.
+func fieldOrderLess(one protodesc.Field, two protodesc.Field, requiredFirst bool) bool {
+	if requiredFirst {
+		oneRequired := one.Label() == protodesc.FieldDescriptorProtoLabelRequired
+		twoRequired := two.Label() == protodesc.FieldDescriptorProtoLabelRequired
+		if oneRequired != twoRequired {
+			return oneRequired
+		}
+	}
+	return one.Number() < two.Number()
+}
+
+// lineEdit replaces the lines from startIndex to endIndex, exclusive.
+type lineEdit struct {
+	startIndex int
+	endIndex   int
+	lines      []string
+}
+
+// getFieldOrderedEdits returns the edits that reorder the fields, or false if
+// the fields need to be reordered but cannot be.
+func getFieldOrderedEdits(
+	lines []string,
+	fields []protodesc.Field,
+	requiredFirst bool,
+	shouldFix func(protodesc.Field) bool,
+) ([]*lineEdit, bool) {
+	sortedFields := make([]protodesc.Field, len(fields))
+	copy(sortedFields, fields)
+	sort.SliceStable(
+		sortedFields,
+		func(i int, j int) bool {
+			return fieldOrderLess(sortedFields[i], sortedFields[j], requiredFirst)
+		},
+	)
+	isOrdered := true
+	fix := false
+	for i, field := range fields {
+		if field != sortedFields[i] {
+			isOrdered = false
+		}
+		if shouldFix(field) {
+			fix = true
+		}
+	}
+	if isOrdered || !fix {
+		return nil, true
+	}
+	fieldToChunk := make(map[protodesc.Field]*lineEdit, len(fields))
+	previousEndIndex := 0
+	for _, field := range fields {
+		chunk, ok := getFieldChunk(lines, field, previousEndIndex)
+		if !ok {
+			return nil, false
+		}
+		fieldToChunk[field] = chunk
+		previousEndIndex = chunk.endIndex
+	}
+	edits := make([]*lineEdit, 0, len(fields))
+	for i, field := range fields {
+		if field == sortedFields[i] {
+			continue
+		}
+		chunk := fieldToChunk[field]
+		edits = append(
+			edits,
+			&lineEdit{
+				startIndex: chunk.startIndex,
+				endIndex:   chunk.endIndex,
+				lines:      fieldToChunk[sortedFields[i]].lines,
+			},
+		)
+	}
+	return edits, true
+}
+
+// getFieldChunk returns the lines that make up the field declaration,
+// including its leading comments and trailing comment on the same line.
+//
+// Returns false if the field does not start and end on lines of its own, or
+// if its leading comments start before minIndex.
+func getFieldChunk(lines []string, field protodesc.Field, minIndex int) (*lineEdit, bool) {
+	if field.Type() == protodesc.FieldDescriptorProtoTypeGroup {
+		return nil, false
+	}
+	return getDeclarationChunk(lines, field.Location(), minIndex)
+}
+
+// getDeclarationChunk returns the lines that make up the declaration at the
+// location, including its leading comments and trailing comment on the same line.
+//
+// Returns false if the declaration does not start and end on lines of its own,
+// or if its leading comments start before minIndex.
+func getDeclarationChunk(lines []string, location protodesc.Location, minIndex int) (*lineEdit, bool) {
+	if location == nil {
+		return nil, false
+	}
+	startIndex := location.StartLine() - 1
+	endIndex := location.EndLine()
+	if startIndex < minIndex || endIndex > len(lines) {
+		return nil, false
+	}
+	startOffset, ok := columnToOffset(lines[startIndex], location.StartColumn()-1)
+	if !ok || strings.TrimSpace(lines[startIndex][:startOffset]) != "" {
+		return nil, false
+	}
+	endOffset, ok := columnToOffset(lines[endIndex-1], location.EndColumn()-1)
+	if !ok {
+		return nil, false
+	}
+	if rest := strings.TrimSpace(lines[endIndex-1][endOffset:]); rest != "" && !strings.HasPrefix(rest, "//") {
+		return nil, false
+	}
+	// include the comment lines directly above the declaration
+	for startIndex > minIndex {
+		previousLine := strings.TrimSpace(lines[startIndex-1])
+		if strings.HasPrefix(previousLine, "//") {
+			startIndex--
+			continue
+		}
+		if !strings.HasSuffix(previousLine, "*/") {
+			break
+		}
+		commentStartIndex := startIndex - 1
+		for commentStartIndex >= minIndex && !strings.HasPrefix(strings.TrimSpace(lines[commentStartIndex]), "/*") {
+			if commentStartIndex < startIndex-1 && strings.Contains(lines[commentStartIndex], "*/") {
+				return nil, false
+			}
+			commentStartIndex--
+		}
+		if commentStartIndex < minIndex {
+			return nil, false
+		}
+		startIndex = commentStartIndex
+	}
+	return &lineEdit{
+		startIndex: startIndex,
+		endIndex:   endIndex,
+		lines:      lines[startIndex:endIndex],
+	}, true
+}
+
+// columnToOffset returns the byte offset within the line of the zero-based
+// column, as computed by the Protobuf compiler.
+//
+// Tabs advance the column to the next multiple of 8.
+func columnToOffset(line string, column int) (int, bool) {
+	currentColumn := 0
+	for offset, r := range line {
+		if currentColumn == column {
+			return offset, true
+		}
+		if currentColumn > column {
+			return 0, false
+		}
+		switch r {
+		case '\t':
+			currentColumn += 8 - currentColumn%8
+		case '\r':
+		default:
+			currentColumn++
+		}
+	}
+	if currentColumn == column {
+		return len(line), true
+	}
+	return 0, false
+}
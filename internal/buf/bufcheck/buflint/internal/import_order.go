@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/pkg/protodesc"
+)
+
+const (
+	importGroupWellKnown = iota
+	importGroupExternal
+	importGroupLocal
+)
+
+// FixImportOrdered reorders the import declarations within the source of the
+// file so that the imports are ordered and grouped as IMPORT_ORDERED expects.
+//
+// Imports are moved together with their leading comments and trailing comments
+// on the same line, and the groups are separated by a single blank line.
+//
+// Returns false if the imports cannot be moved safely, for example because
+// they are not contiguous, in which case the source is not changed.
+func FixImportOrdered(
+	file protodesc.File,
+	data []byte,
+	localFilePaths map[string]struct{},
+) ([]byte, bool, error) {
+	fileImports := file.FileImports()
+	if len(fileImports) == 0 {
+		return data, true, nil
+	}
+	lines := strings.Split(string(data), "\n")
+	importToChunk := make(map[protodesc.FileImport]*lineEdit, len(fileImports))
+	isChunkLine := make(map[int]struct{})
+	previousEndIndex := 0
+	for _, fileImport := range fileImports {
+		chunk, ok := getDeclarationChunk(lines, fileImport.Location(), previousEndIndex)
+		if !ok {
+			return nil, false, nil
+		}
+		importToChunk[fileImport] = chunk
+		for i := chunk.startIndex; i < chunk.endIndex; i++ {
+			isChunkLine[i] = struct{}{}
+		}
+		previousEndIndex = chunk.endIndex
+	}
+	startIndex := importToChunk[fileImports[0]].startIndex
+	endIndex := importToChunk[fileImports[len(fileImports)-1]].endIndex
+	// only blank lines can be between the imports, as anything else would be moved
+	for i := startIndex; i < endIndex; i++ {
+		if _, ok := isChunkLine[i]; !ok && strings.TrimSpace(lines[i]) != "" {
+			return nil, false, nil
+		}
+	}
+	sortedFileImports := make([]protodesc.FileImport, len(fileImports))
+	copy(sortedFileImports, fileImports)
+	sort.SliceStable(
+		sortedFileImports,
+		func(i int, j int) bool {
+			return importOrderLess(sortedFileImports[i], sortedFileImports[j], localFilePaths)
+		},
+	)
+	newLines := make([]string, 0, endIndex-startIndex)
+	for i, fileImport := range sortedFileImports {
+		if i > 0 && getImportGroup(fileImport, localFilePaths) != getImportGroup(sortedFileImports[i-1], localFilePaths) {
+			newLines = append(newLines, "")
+		}
+		newLines = append(newLines, importToChunk[fileImport].lines...)
+	}
+	fixedLines := make([]string, 0, len(lines)-(endIndex-startIndex)+len(newLines))
+	fixedLines = append(fixedLines, lines[:startIndex]...)
+	fixedLines = append(fixedLines, newLines...)
+	fixedLines = append(fixedLines, lines[endIndex:]...)
+	return []byte(strings.Join(fixedLines, "\n")), true, nil
+}
+
+// getImportGroup returns the group of the import.
+//
+// Imports of the Protobuf well-known types are first, followed by imports of
+// files outside of the files being linted, and then imports of files being linted.
+func getImportGroup(fileImport protodesc.FileImport, localFilePaths map[string]struct{}) int {
+	if strings.HasPrefix(fileImport.Import(), "google/protobuf/") {
+		return importGroupWellKnown
+	}
+	if _, ok := localFilePaths[fileImport.Import()]; ok {
+		return importGroupLocal
+	}
+	return importGroupExternal
+}
+
+// importOrderLess returns true if one should be declared before two.
+func importOrderLess(one protodesc.FileImport, two protodesc.FileImport, localFilePaths map[string]struct{}) bool {
+	oneGroup := getImportGroup(one, localFilePaths)
+	twoGroup := getImportGroup(two, localFilePaths)
+	if oneGroup != twoGroup {
+		return oneGroup < twoGroup
+	}
+	return one.Import() < two.Import()
+}
+
+// getImportBlankLineCount returns the number of blank lines between the
+// consecutive imports.
+//
+// Returns false if this cannot be determined from the locations, that is if
+// there are comments that are not attached directly to the imports.
+func getImportBlankLineCount(previous protodesc.FileImport, fileImport protodesc.FileImport) (int, bool) {
+	previousLocation := previous.Location()
+	location := fileImport.Location()
+	if previousLocation == nil || location == nil || len(location.LeadingDetachedComments()) > 0 {
+		return 0, false
+	}
+	blankLineCount := location.StartLine() - previousLocation.EndLine() - 1 - getCommentLineCount(location.LeadingComments())
+	// a trailing comment is either on the same line as the import, or on the
+	// lines after the import followed by a blank line, which we cannot tell apart
+	// from the locations unless there are no other lines
+	if previousLocation.TrailingComments() != "" && blankLineCount > 1 {
+		return 0, false
+	}
+	return blankLineCount, blankLineCount >= 0
+}
+
+// getCommentLineCount returns the number of lines of the comment.
+//
+// Line comments end with a newline, while block comments do not.
+func getCommentLineCount(comment string) int {
+	if comment == "" {
+		return 0
+	}
+	if strings.HasSuffix(comment, "\n") {
+		return strings.Count(comment, "\n")
+	}
+	return strings.Count(comment, "\n") + 1
+}
+
+// getLocalFilePaths returns the paths of the files.
+func getLocalFilePaths(files []protodesc.File) map[string]struct{} {
+	localFilePaths := make(map[string]struct{}, len(files))
+	for _, file := range files {
+		localFilePaths[file.FilePath()] = struct{}{}
+	}
+	return localFilePaths
+}
@@ -2,6 +2,8 @@ package internal
 
 import (
 	"errors"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -72,6 +74,60 @@ func checkCommentNamedDescriptor(
 	return nil
 }
 
+// commentTODORegexp matches the markers of unresolved notes in comments.
+var commentTODORegexp = regexp.MustCompile(`\b(TODO|FIXME)\b`)
+
+// CheckCommentNoTODO is a check function.
+//
+// Lines of comments that match any of the allowed regular expressions are not checked.
+var CheckCommentNoTODO = func(id string, files []protodesc.File, allowedRegexps []*regexp.Regexp) ([]*filev1beta1.FileAnnotation, error) {
+	return newFileCheckFunc(
+		func(add addFunc, file protodesc.File) error {
+			return checkCommentNoTODO(add, file, allowedRegexps)
+		},
+	)(id, files)
+}
+
+func checkCommentNoTODO(add addFunc, file protodesc.File, allowedRegexps []*regexp.Regexp) error {
+	if !packageIsStable(file.Package()) {
+		return nil
+	}
+	for _, location := range file.Locations() {
+		comments := append([]string{location.LeadingComments(), location.TrailingComments()}, location.LeadingDetachedComments()...)
+		for _, comment := range comments {
+			if marker := getCommentTODOMarker(comment, allowedRegexps); marker != "" {
+				add(file, location, `Comment contains %s in stable package %q.`, marker, file.Package())
+				// only one annotation per location
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// getCommentTODOMarker returns the first TODO or FIXME marker within the lines
+// of the comment that do not match any of the allowed regular expressions,
+// or empty if there is no such marker.
+func getCommentTODOMarker(comment string, allowedRegexps []*regexp.Regexp) string {
+	for _, line := range strings.Split(comment, "\n") {
+		marker := commentTODORegexp.FindString(line)
+		if marker == "" {
+			continue
+		}
+		allowed := false
+		for _, allowedRegexp := range allowedRegexps {
+			if allowedRegexp.MatchString(line) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return marker
+		}
+	}
+	return ""
+}
+
 // CheckDirectorySamePackage is a check function.
 var CheckDirectorySamePackage = newDirToFilesCheckFunc(checkDirectorySamePackage)
 
@@ -101,7 +157,16 @@ func checkEnumNoAllowAlias(add addFunc, enum protodesc.Enum) error {
 }
 
 // CheckEnumPascalCase is a check function.
-var CheckEnumPascalCase = newEnumCheckFunc(checkEnumPascalCase)
+var CheckEnumPascalCase = func(id string, files []protodesc.File, namingExceptions map[string]struct{}) ([]*filev1beta1.FileAnnotation, error) {
+	return newEnumCheckFunc(
+		func(add addFunc, enum protodesc.Enum) error {
+			if isNamingException(enum, namingExceptions) {
+				return nil
+			}
+			return checkEnumPascalCase(add, enum)
+		},
+	)(id, files)
+}
 
 func checkEnumPascalCase(add addFunc, enum protodesc.Enum) error {
 	name := enum.Name()
@@ -125,7 +190,16 @@ func checkEnumValuePrefix(add addFunc, enumValue protodesc.EnumValue) error {
 }
 
 // CheckEnumValueUpperSnakeCase is a check function.
-var CheckEnumValueUpperSnakeCase = newEnumValueCheckFunc(checkEnumValueUpperSnakeCase)
+var CheckEnumValueUpperSnakeCase = func(id string, files []protodesc.File, namingExceptions map[string]struct{}) ([]*filev1beta1.FileAnnotation, error) {
+	return newEnumValueCheckFunc(
+		func(add addFunc, enumValue protodesc.EnumValue) error {
+			if isNamingException(enumValue, namingExceptions) {
+				return nil
+			}
+			return checkEnumValueUpperSnakeCase(add, enumValue)
+		},
+	)(id, files)
+}
 
 func checkEnumValueUpperSnakeCase(add addFunc, enumValue protodesc.EnumValue) error {
 	name := enumValue.Name()
@@ -157,7 +231,16 @@ func checkEnumZeroValueSuffix(add addFunc, enumValue protodesc.EnumValue, suffix
 }
 
 // CheckFieldLowerSnakeCase is a check function.
-var CheckFieldLowerSnakeCase = newFieldCheckFunc(checkFieldLowerSnakeCase)
+var CheckFieldLowerSnakeCase = func(id string, files []protodesc.File, namingExceptions map[string]struct{}) ([]*filev1beta1.FileAnnotation, error) {
+	return newFieldCheckFunc(
+		func(add addFunc, field protodesc.Field) error {
+			if isNamingException(field, namingExceptions) {
+				return nil
+			}
+			return checkFieldLowerSnakeCase(add, field)
+		},
+	)(id, files)
+}
 
 func checkFieldLowerSnakeCase(add addFunc, field protodesc.Field) error {
 	message := field.Message()
@@ -188,6 +271,75 @@ func checkFieldNoDescriptor(add addFunc, field protodesc.Field) error {
 	return nil
 }
 
+// CheckFieldNoJSONNameCollision is a check function.
+var CheckFieldNoJSONNameCollision = newMessageCheckFunc(checkFieldNoJSONNameCollision)
+
+func checkFieldNoJSONNameCollision(add addFunc, message protodesc.Message) error {
+	lowerJSONNameToField := make(map[string]protodesc.Field)
+	for _, field := range message.Fields() {
+		jsonName := getFieldJSONName(field)
+		lowerJSONName := strings.ToLower(jsonName)
+		if previousField, ok := lowerJSONNameToField[lowerJSONName]; ok {
+			add(
+				field,
+				withBackupLocation(field.JSONNameLocation(), field.NameLocation()),
+				`Field %q on message %q has JSON name %q which collides with the JSON name %q of field %q.`,
+				field.Name(),
+				message.Name(),
+				jsonName,
+				getFieldJSONName(previousField),
+				previousField.Name(),
+			)
+			continue
+		}
+		lowerJSONNameToField[lowerJSONName] = field
+	}
+	return nil
+}
+
+// CheckFieldOrdered is a check function.
+var CheckFieldOrdered = func(id string, files []protodesc.File, requiredFirst bool) ([]*filev1beta1.FileAnnotation, error) {
+	return newMessageCheckFunc(
+		func(add addFunc, message protodesc.Message) error {
+			return checkFieldOrdered(add, message, requiredFirst)
+		},
+	)(id, files)
+}
+
+func checkFieldOrdered(add addFunc, message protodesc.Message, requiredFirst bool) error {
+	for _, fields := range getFieldOrderGroups(message) {
+		var maxField protodesc.Field
+		for _, field := range fields {
+			if maxField != nil && fieldOrderLess(field, maxField, requiredFirst) {
+				add(field, field.Location(), "Field %q should be declared before field %q.", field.Name(), maxField.Name())
+				continue
+			}
+			maxField = field
+		}
+	}
+	return nil
+}
+
+// CheckFileLicenseHeader is a check function.
+var CheckFileLicenseHeader = func(id string, files []protodesc.File, licenseHeader string) ([]*filev1beta1.FileAnnotation, error) {
+	if licenseHeader == "" {
+		return nil, nil
+	}
+	licenseHeaderRegexp := newLicenseHeaderRegexp(licenseHeader)
+	return newFileCheckFunc(
+		func(add addFunc, file protodesc.File) error {
+			return checkFileLicenseHeader(add, file, licenseHeaderRegexp)
+		},
+	)(id, files)
+}
+
+func checkFileLicenseHeader(add addFunc, file protodesc.File, licenseHeaderRegexp *regexp.Regexp) error {
+	if firstComment, ok := getFirstComment(file); !ok || !licenseHeaderRegexp.MatchString(firstComment) {
+		add(file, nil, `File does not start with the license header.`)
+	}
+	return nil
+}
+
 // CheckFileLowerSnakeCase is a check function.
 var CheckFileLowerSnakeCase = newFileCheckFunc(checkFileLowerSnakeCase)
 
@@ -203,6 +355,77 @@ func checkFileLowerSnakeCase(add addFunc, file protodesc.File) error {
 	return nil
 }
 
+// CheckFileMaxLines is a check function.
+var CheckFileMaxLines = func(id string, files []protodesc.File, maxLines int) ([]*filev1beta1.FileAnnotation, error) {
+	return newFileCheckFunc(
+		func(add addFunc, file protodesc.File) error {
+			return checkFileMaxLines(add, file, maxLines)
+		},
+	)(id, files)
+}
+
+func checkFileMaxLines(add addFunc, file protodesc.File, maxLines int) error {
+	// the source code info does not include comments or whitespace after
+	// the last element, so the file length is the end line of the last element
+	numLines := 0
+	for _, location := range file.Locations() {
+		if endLine := location.EndLine(); endLine > numLines {
+			numLines = endLine
+		}
+	}
+	if numLines > maxLines {
+		add(file, nil, `File is %d lines long, which is longer than the maximum of %d lines.`, numLines, maxLines)
+	}
+	return nil
+}
+
+// CheckFileMaxLineLength is a check function.
+var CheckFileMaxLineLength = func(id string, files []protodesc.File, maxLineLength int) ([]*filev1beta1.FileAnnotation, error) {
+	return newFileCheckFunc(
+		func(add addFunc, file protodesc.File) error {
+			return checkFileMaxLineLength(add, file, maxLineLength)
+		},
+	)(id, files)
+}
+
+func checkFileMaxLineLength(add addFunc, file protodesc.File, maxLineLength int) error {
+	optionLines := make(map[int]struct{})
+	for _, location := range file.OptionLocations() {
+		for line := location.StartLine(); line <= location.EndLine(); line++ {
+			optionLines[line] = struct{}{}
+		}
+	}
+	// the length of a line is the end of the last element on the line, as the
+	// source code info does not include comments, so we keep the narrowest
+	// location that ends furthest on each line
+	lineToLocation := make(map[int]protodesc.Location)
+	for _, location := range file.Locations() {
+		line := location.EndLine()
+		if _, ok := optionLines[line]; ok {
+			continue
+		}
+		existingLocation, ok := lineToLocation[line]
+		if !ok ||
+			location.EndColumn() > existingLocation.EndColumn() ||
+			(location.EndColumn() == existingLocation.EndColumn() && isNarrowerLocation(location, existingLocation)) {
+			lineToLocation[line] = location
+		}
+	}
+	lines := make([]int, 0, len(lineToLocation))
+	for line := range lineToLocation {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	for _, line := range lines {
+		location := lineToLocation[line]
+		// the end column is one past the last character
+		if lineLength := location.EndColumn() - 1; lineLength > maxLineLength {
+			add(file, location, `Line is %d characters long, which is longer than the maximum of %d characters.`, lineLength, maxLineLength)
+		}
+	}
+	return nil
+}
+
 var (
 	// CheckImportNoPublic is a check function.
 	CheckImportNoPublic = newFileImportCheckFunc(checkImportNoPublic)
@@ -225,8 +448,181 @@ func checkImportNoPublicWeak(add addFunc, fileImport protodesc.FileImport, value
 	return nil
 }
 
+// CheckImportNoRestricted is a check function.
+//
+// restrictedImports is a map from import path pattern to the path patterns of
+// the files that cannot import matching paths. If the slice of path patterns is
+// empty, no file can import matching paths.
+var CheckImportNoRestricted = func(id string, files []protodesc.File, restrictedImports map[string][]string) ([]*filev1beta1.FileAnnotation, error) {
+	return newFileImportCheckFunc(
+		func(add addFunc, fileImport protodesc.FileImport) error {
+			return checkImportNoRestricted(add, fileImport, restrictedImports)
+		},
+	)(id, files)
+}
+
+func checkImportNoRestricted(add addFunc, fileImport protodesc.FileImport, restrictedImports map[string][]string) error {
+	importPatterns := make([]string, 0, len(restrictedImports))
+	for importPattern := range restrictedImports {
+		importPatterns = append(importPatterns, importPattern)
+	}
+	sort.Strings(importPatterns)
+	for _, importPattern := range importPatterns {
+		if !pathMatchesPattern(importPattern, fileImport.Import()) {
+			continue
+		}
+		fromPatterns := restrictedImports[importPattern]
+		if len(fromPatterns) == 0 {
+			add(fileImport, fileImport.Location(), `Import %q is restricted by the pattern %q.`, fileImport.Import(), importPattern)
+			return nil
+		}
+		for _, fromPattern := range fromPatterns {
+			if pathMatchesPattern(fromPattern, fileImport.FilePath()) {
+				add(fileImport, fileImport.Location(), `Import %q is restricted by the pattern %q for files matching %q.`, fileImport.Import(), importPattern, fromPattern)
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// CheckImportOrdered is a check function.
+var CheckImportOrdered = func(id string, files []protodesc.File) ([]*filev1beta1.FileAnnotation, error) {
+	localFilePaths := getLocalFilePaths(files)
+	return newFileCheckFunc(
+		func(add addFunc, file protodesc.File) error {
+			return checkImportOrdered(add, file, localFilePaths)
+		},
+	)(id, files)
+}
+
+func checkImportOrdered(add addFunc, file protodesc.File, localFilePaths map[string]struct{}) error {
+	fileImports := file.FileImports()
+	var maxFileImport protodesc.FileImport
+	for i, fileImport := range fileImports {
+		if maxFileImport != nil && importOrderLess(fileImport, maxFileImport, localFilePaths) {
+			add(fileImport, fileImport.Location(), "Import %q should be declared before import %q.", fileImport.Import(), maxFileImport.Import())
+			continue
+		}
+		// the blank lines are only checked between imports that are in order
+		if i > 0 && fileImports[i-1] == maxFileImport {
+			if blankLineCount, ok := getImportBlankLineCount(maxFileImport, fileImport); ok {
+				sameGroup := getImportGroup(fileImport, localFilePaths) == getImportGroup(maxFileImport, localFilePaths)
+				if sameGroup && blankLineCount > 0 {
+					add(fileImport, fileImport.Location(), "Import %q should not be separated from import %q by a blank line as they are in the same group.", fileImport.Import(), maxFileImport.Import())
+				}
+				if !sameGroup && blankLineCount != 1 {
+					add(fileImport, fileImport.Location(), "Import %q should be separated from import %q by a single blank line as they are in different groups.", fileImport.Import(), maxFileImport.Import())
+				}
+			}
+		}
+		maxFileImport = fileImport
+	}
+	return nil
+}
+
+// CheckMessageNoCrossPackageDuplicate is a check function.
+var CheckMessageNoCrossPackageDuplicate = func(id string, files []protodesc.File, similarityThreshold float64) ([]*filev1beta1.FileAnnotation, error) {
+	return newFilesCheckFunc(
+		func(add addFunc, files []protodesc.File) error {
+			return checkMessageNoCrossPackageDuplicate(add, files, similarityThreshold)
+		},
+	)(id, files)
+}
+
+func checkMessageNoCrossPackageDuplicate(add addFunc, files []protodesc.File, similarityThreshold float64) error {
+	fullNameToMessage, err := protodesc.FullNameToMessage(files...)
+	if err != nil {
+		return err
+	}
+	nameToMessages := make(map[string][]protodesc.Message)
+	for _, message := range fullNameToMessage {
+		if message.IsMapEntry() || len(message.Fields()) == 0 {
+			continue
+		}
+		nameToMessages[message.Name()] = append(nameToMessages[message.Name()], message)
+	}
+	for _, messages := range nameToMessages {
+		if len(messages) < 2 {
+			continue
+		}
+		sort.Slice(messages, func(i int, j int) bool { return messages[i].FullName() < messages[j].FullName() })
+		for _, message := range messages {
+			var similarFullNames []string
+			for _, otherMessage := range messages {
+				if message.Package() == otherMessage.Package() {
+					continue
+				}
+				if messageFieldSimilarity(message, otherMessage) >= similarityThreshold {
+					similarFullNames = append(similarFullNames, otherMessage.FullName())
+				}
+			}
+			if len(similarFullNames) > 0 {
+				add(
+					message,
+					message.NameLocation(),
+					"Message %q has the same name and similar fields as %s in other packages, consider consolidating these into a single message.",
+					message.FullName(),
+					utilstring.JoinSliceQuoted(similarFullNames, ", "),
+				)
+			}
+		}
+	}
+	return nil
+}
+
+// messageFieldSimilarity returns the Jaccard similarity of the fields of the two messages.
+//
+// Fields are compared by name, number, label, and type. Message and enum types are
+// compared by their simple names, as the types will usually be in different packages.
+func messageFieldSimilarity(one protodesc.Message, two protodesc.Message) float64 {
+	oneFieldKeys := messageFieldKeys(one)
+	twoFieldKeys := messageFieldKeys(two)
+	intersection := 0
+	for fieldKey := range oneFieldKeys {
+		if _, ok := twoFieldKeys[fieldKey]; ok {
+			intersection++
+		}
+	}
+	union := len(oneFieldKeys) + len(twoFieldKeys) - intersection
+	if union == 0 {
+		return 0
+	}
+	return float64(intersection) / float64(union)
+}
+
+func messageFieldKeys(message protodesc.Message) map[string]struct{} {
+	fieldKeys := make(map[string]struct{}, len(message.Fields()))
+	for _, field := range message.Fields() {
+		typeName := field.TypeName()
+		if index := strings.LastIndex(typeName, "."); index >= 0 {
+			typeName = typeName[index+1:]
+		}
+		fieldKeys[strings.Join(
+			[]string{
+				field.Name(),
+				strconv.Itoa(field.Number()),
+				field.Label().String(),
+				field.Type().String(),
+				typeName,
+			},
+			":",
+		)] = struct{}{}
+	}
+	return fieldKeys
+}
+
 // CheckMessagePascalCase is a check function.
-var CheckMessagePascalCase = newMessageCheckFunc(checkMessagePascalCase)
+var CheckMessagePascalCase = func(id string, files []protodesc.File, namingExceptions map[string]struct{}) ([]*filev1beta1.FileAnnotation, error) {
+	return newMessageCheckFunc(
+		func(add addFunc, message protodesc.Message) error {
+			if isNamingException(message, namingExceptions) {
+				return nil
+			}
+			return checkMessagePascalCase(add, message)
+		},
+	)(id, files)
+}
 
 func checkMessagePascalCase(add addFunc, message protodesc.Message) error {
 	if message.IsMapEntry() {
@@ -241,8 +637,102 @@ func checkMessagePascalCase(add addFunc, message protodesc.Message) error {
 	return nil
 }
 
+// CheckNameNoGeneratedCollision is a check function.
+var CheckNameNoGeneratedCollision = newFileCheckFunc(checkNameNoGeneratedCollision)
+
+func checkNameNoGeneratedCollision(add addFunc, file protodesc.File) error {
+	var topLevelDescriptors []protodesc.NamedDescriptor
+	for _, message := range file.Messages() {
+		topLevelDescriptors = append(topLevelDescriptors, message)
+	}
+	for _, enum := range file.Enums() {
+		topLevelDescriptors = append(topLevelDescriptors, enum)
+	}
+	for _, service := range file.Services() {
+		topLevelDescriptors = append(topLevelDescriptors, service)
+	}
+	// types are generated with their names as-is in java
+	checkNamedDescriptorsNoGeneratedCollision(add, topLevelDescriptors, goGeneratedNamer)
+	for _, service := range file.Services() {
+		methodDescriptors := make([]protodesc.NamedDescriptor, 0, len(service.Methods()))
+		for _, method := range service.Methods() {
+			methodDescriptors = append(methodDescriptors, method)
+		}
+		checkNamedDescriptorsNoGeneratedCollision(add, methodDescriptors, goGeneratedNamer, javaGeneratedNamer)
+	}
+	return protodesc.ForEachMessage(
+		func(message protodesc.Message) error {
+			var memberDescriptors []protodesc.NamedDescriptor
+			for _, field := range message.Fields() {
+				memberDescriptors = append(memberDescriptors, field)
+			}
+			for _, oneof := range message.Oneofs() {
+				memberDescriptors = append(memberDescriptors, oneof)
+			}
+			checkNamedDescriptorsNoGeneratedCollision(add, memberDescriptors, goGeneratedNamer, javaGeneratedNamer)
+			var nestedDescriptors []protodesc.NamedDescriptor
+			for _, nestedMessage := range message.Messages() {
+				if nestedMessage.IsMapEntry() {
+					continue
+				}
+				nestedDescriptors = append(nestedDescriptors, nestedMessage)
+			}
+			for _, nestedEnum := range message.Enums() {
+				nestedDescriptors = append(nestedDescriptors, nestedEnum)
+			}
+			checkNamedDescriptorsNoGeneratedCollision(add, nestedDescriptors, goGeneratedNamer)
+			return nil
+		},
+		file,
+	)
+}
+
+// checkNamedDescriptorsNoGeneratedCollision adds a FileAnnotation for each of the
+// sibling descriptors whose generated name for any of the namers is the same as
+// that of a sibling declared before it.
+func checkNamedDescriptorsNoGeneratedCollision(
+	add addFunc,
+	namedDescriptors []protodesc.NamedDescriptor,
+	namers ...*generatedNamer,
+) {
+	generatedNameToNamedDescriptors := make([]map[string]protodesc.NamedDescriptor, len(namers))
+	for i := range namers {
+		generatedNameToNamedDescriptors[i] = make(map[string]protodesc.NamedDescriptor)
+	}
+	for _, namedDescriptor := range namedDescriptors {
+		for i, namer := range namers {
+			generatedName := namer.getName(namedDescriptor.Name())
+			previousNamedDescriptor, ok := generatedNameToNamedDescriptors[i][generatedName]
+			if ok && previousNamedDescriptor.Name() != namedDescriptor.Name() {
+				add(
+					namedDescriptor,
+					namedDescriptor.NameLocation(),
+					"%q collides with %q as both are generated as %q in %s.",
+					namedDescriptor.Name(),
+					previousNamedDescriptor.Name(),
+					generatedName,
+					namer.language,
+				)
+				break
+			}
+			if !ok {
+				generatedNameToNamedDescriptors[i][generatedName] = namedDescriptor
+			}
+		}
+	}
+}
+
 // CheckOneofLowerSnakeCase is a check function.
-var CheckOneofLowerSnakeCase = newOneofCheckFunc(checkOneofLowerSnakeCase)
+var CheckOneofLowerSnakeCase = func(id string, files []protodesc.File, namingExceptions map[string]struct{}) ([]*filev1beta1.FileAnnotation, error) {
+	return newOneofCheckFunc(
+		func(add addFunc, oneof protodesc.Oneof) error {
+			if isNamingException(oneof, namingExceptions) {
+				return nil
+			}
+			return checkOneofLowerSnakeCase(add, oneof)
+		},
+	)(id, files)
+}
 
 func checkOneofLowerSnakeCase(add addFunc, oneof protodesc.Oneof) error {
 	name := oneof.Name()
@@ -412,6 +902,28 @@ func checkPackageVersionSuffix(add addFunc, file protodesc.File) error {
 	return nil
 }
 
+// CheckPackageVersionSuffixPattern is a check function.
+//
+// The pattern must match the entire last component of the package.
+var CheckPackageVersionSuffixPattern = func(id string, files []protodesc.File, pattern *regexp.Regexp) ([]*filev1beta1.FileAnnotation, error) {
+	return newFileCheckFunc(
+		func(add addFunc, file protodesc.File) error {
+			return checkPackageVersionSuffixPattern(add, file, pattern)
+		},
+	)(id, files)
+}
+
+func checkPackageVersionSuffixPattern(add addFunc, file protodesc.File, pattern *regexp.Regexp) error {
+	pkg := file.Package()
+	if pkg == "" {
+		return nil
+	}
+	if !packageHasVersionSuffixPattern(pkg, pattern) {
+		add(file, file.PackageLocation(), `Package name %q should be suffixed with a version matching the pattern %q.`, pkg, pattern.String())
+	}
+	return nil
+}
+
 // CheckRPCNoClientStreaming is a check function.
 var CheckRPCNoClientStreaming = newMethodCheckFunc(checkRPCNoClientStreaming)
 
@@ -422,6 +934,41 @@ func checkRPCNoClientStreaming(add addFunc, method protodesc.Method) error {
 	return nil
 }
 
+// CheckRPCNoRestrictedClientStreaming is a check function.
+//
+// patternToRegexp is a map from the configured pattern to the compiled regular
+// expression, which must match the entire name of the RPC.
+var CheckRPCNoRestrictedClientStreaming = func(id string, files []protodesc.File, patternToRegexp map[string]*regexp.Regexp) ([]*filev1beta1.FileAnnotation, error) {
+	patterns := make([]string, 0, len(patternToRegexp))
+	for pattern := range patternToRegexp {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	return newMethodCheckFunc(
+		func(add addFunc, method protodesc.Method) error {
+			return checkRPCNoRestrictedClientStreaming(add, method, patterns, patternToRegexp)
+		},
+	)(id, files)
+}
+
+func checkRPCNoRestrictedClientStreaming(
+	add addFunc,
+	method protodesc.Method,
+	patterns []string,
+	patternToRegexp map[string]*regexp.Regexp,
+) error {
+	if !method.ClientStreaming() {
+		return nil
+	}
+	for _, pattern := range patterns {
+		if patternToRegexp[pattern].MatchString(method.Name()) {
+			add(method, method.Location(), "RPC %q is client streaming, which is restricted by the pattern %q.", method.Name(), pattern)
+			return nil
+		}
+	}
+	return nil
+}
+
 // CheckRPCNoServerStreaming is a check function.
 var CheckRPCNoServerStreaming = newMethodCheckFunc(checkRPCNoServerStreaming)
 
@@ -433,7 +980,16 @@ func checkRPCNoServerStreaming(add addFunc, method protodesc.Method) error {
 }
 
 // CheckRPCPascalCase is a check function.
-var CheckRPCPascalCase = newMethodCheckFunc(checkRPCPascalCase)
+var CheckRPCPascalCase = func(id string, files []protodesc.File, namingExceptions map[string]struct{}) ([]*filev1beta1.FileAnnotation, error) {
+	return newMethodCheckFunc(
+		func(add addFunc, method protodesc.Method) error {
+			if isNamingException(method, namingExceptions) {
+				return nil
+			}
+			return checkRPCPascalCase(add, method)
+		},
+	)(id, files)
+}
 
 func checkRPCPascalCase(add addFunc, method protodesc.Method) error {
 	name := method.Name()
@@ -605,8 +1161,55 @@ func checkRPCResponseStandardName(add addFunc, method protodesc.Method, allowGoo
 	return nil
 }
 
+// CheckRPCStreamingLifecycleDocumented is a check function.
+var CheckRPCStreamingLifecycleDocumented = newMethodCheckFunc(checkRPCStreamingLifecycleDocumented)
+
+func checkRPCStreamingLifecycleDocumented(add addFunc, method protodesc.Method) error {
+	if !method.ClientStreaming() && !method.ServerStreaming() {
+		return nil
+	}
+	location := method.Location()
+	if location == nil {
+		return nil
+	}
+	comment := strings.ToLower(location.LeadingComments())
+	if !commentContainsAny(comment, "keepalive", "keep-alive", "keep alive") {
+		add(method, location, "Streaming RPC %q should document its keepalive behavior in its comment.", method.Name())
+	}
+	if !strings.Contains(comment, "cancel") {
+		add(method, location, "Streaming RPC %q should document its cancellation behavior in its comment.", method.Name())
+	}
+	return nil
+}
+
+// CheckRPCStreamingPaginationDocumented is a check function.
+var CheckRPCStreamingPaginationDocumented = newMethodCheckFunc(checkRPCStreamingPaginationDocumented)
+
+func checkRPCStreamingPaginationDocumented(add addFunc, method protodesc.Method) error {
+	if !method.ServerStreaming() {
+		return nil
+	}
+	location := method.Location()
+	if location == nil {
+		return nil
+	}
+	if !strings.Contains(strings.ToLower(location.LeadingComments()), "paginat") {
+		add(method, location, "Server streaming RPC %q should document a paginated alternative in its comment.", method.Name())
+	}
+	return nil
+}
+
 // CheckServicePascalCase is a check function.
-var CheckServicePascalCase = newServiceCheckFunc(checkServicePascalCase)
+var CheckServicePascalCase = func(id string, files []protodesc.File, namingExceptions map[string]struct{}) ([]*filev1beta1.FileAnnotation, error) {
+	return newServiceCheckFunc(
+		func(add addFunc, service protodesc.Service) error {
+			if isNamingException(service, namingExceptions) {
+				return nil
+			}
+			return checkServicePascalCase(add, service)
+		},
+	)(id, files)
+}
 
 func checkServicePascalCase(add addFunc, service protodesc.Service) error {
 	name := service.Name()
@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/pkg/protodesc"
+)
+
+// licenseHeaderYearPlaceholder matches a year or a range of years such as
+// 2019-2020 within a license header.
+const licenseHeaderYearPlaceholder = "{year}"
+
+// FixFileLicenseHeader inserts the license header at the top of the source,
+// with the year placeholder replaced by the year.
+//
+// Returns false if the source already starts with a comment, as this may be
+// an outdated license header that needs to be updated by hand.
+func FixFileLicenseHeader(data []byte, licenseHeader string, year int) ([]byte, bool) {
+	source := strings.TrimLeft(string(data), "\r\n")
+	if trimmedSource := strings.TrimSpace(source); strings.HasPrefix(trimmedSource, "//") || strings.HasPrefix(trimmedSource, "/*") {
+		return nil, false
+	}
+	var builder strings.Builder
+	for _, line := range getLicenseHeaderLines(licenseHeader) {
+		line = strings.Replace(line, licenseHeaderYearPlaceholder, strconv.Itoa(year), -1)
+		if line == "" {
+			_, _ = builder.WriteString("//\n")
+			continue
+		}
+		_, _ = builder.WriteString("// ")
+		_, _ = builder.WriteString(line)
+		_, _ = builder.WriteString("\n")
+	}
+	_, _ = builder.WriteString("\n")
+	_, _ = builder.WriteString(source)
+	return []byte(builder.String()), true
+}
+
+// newLicenseHeaderRegexp returns a regexp that matches comment text that
+// starts with the license header.
+func newLicenseHeaderRegexp(licenseHeader string) *regexp.Regexp {
+	var builder strings.Builder
+	_, _ = builder.WriteString("^")
+	for _, line := range getLicenseHeaderLines(licenseHeader) {
+		if line != "" {
+			_, _ = builder.WriteString(" ?")
+			_, _ = builder.WriteString(
+				strings.Replace(
+					regexp.QuoteMeta(line),
+					regexp.QuoteMeta(licenseHeaderYearPlaceholder),
+					`\d{4}(-\d{4})?`,
+					-1,
+				),
+			)
+		}
+		_, _ = builder.WriteString(`[ \t\r]*\n`)
+	}
+	return regexp.MustCompile(builder.String())
+}
+
+func getLicenseHeaderLines(licenseHeader string) []string {
+	lines := strings.Split(strings.TrimRight(licenseHeader, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	return lines
+}
+
+// getFirstComment returns the text of the first comment within the file,
+// or false if the file does not start with a comment.
+func getFirstComment(file protodesc.File) (string, bool) {
+	var firstLocations []protodesc.Location
+	for _, location := range file.Locations() {
+		if len(firstLocations) == 0 ||
+			location.StartLine() < firstLocations[0].StartLine() ||
+			(location.StartLine() == firstLocations[0].StartLine() && location.StartColumn() < firstLocations[0].StartColumn()) {
+			firstLocations = []protodesc.Location{location}
+			continue
+		}
+		if location.StartLine() == firstLocations[0].StartLine() && location.StartColumn() == firstLocations[0].StartColumn() {
+			firstLocations = append(firstLocations, location)
+		}
+	}
+	// the location of the file itself starts at the same position as the
+	// first element, but never has comments
+	for _, location := range firstLocations {
+		if leadingDetachedComments := location.LeadingDetachedComments(); len(leadingDetachedComments) > 0 {
+			return leadingDetachedComments[0], true
+		}
+		if leadingComments := location.LeadingComments(); leadingComments != "" {
+			return leadingComments, true
+		}
+	}
+	return "", false
+}
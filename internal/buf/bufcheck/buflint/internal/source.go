@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"bytes"
+
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+	"github.com/bufbuild/buf/internal/pkg/protodesc"
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// CheckFileNoBOMCRLF is a check function.
+//
+// readFile returns the source of the file with the given file path.
+var CheckFileNoBOMCRLF = func(id string, files []protodesc.File, readFile func(string) ([]byte, error)) ([]*filev1beta1.FileAnnotation, error) {
+	return newFileCheckFunc(
+		func(add addFunc, file protodesc.File) error {
+			data, err := readFile(file.FilePath())
+			if err != nil {
+				return err
+			}
+			return checkFileNoBOMCRLF(add, file, data)
+		},
+	)(id, files)
+}
+
+func checkFileNoBOMCRLF(add addFunc, file protodesc.File, data []byte) error {
+	if bytes.HasPrefix(data, utf8BOM) {
+		add(file, newSourceLocation(1, 1), `File starts with a UTF-8 byte order mark.`)
+	}
+	if index := bytes.Index(data, []byte("\r\n")); index >= 0 {
+		line := bytes.Count(data[:index], []byte("\n")) + 1
+		add(file, newSourceLocation(line, 1), `File uses CRLF line endings.`)
+	}
+	return nil
+}
+
+// FixFileNoBOMCRLF removes the UTF-8 byte order mark from the start of the
+// source, and replaces CRLF line endings with LF line endings.
+func FixFileNoBOMCRLF(data []byte) []byte {
+	return bytes.Replace(bytes.TrimPrefix(data, utf8BOM), []byte("\r\n"), []byte("\n"), -1)
+}
+
+// sourceLocation is a Location within the source of a file that is not
+// the location of a descriptor.
+type sourceLocation struct {
+	line   int
+	column int
+}
+
+func newSourceLocation(line int, column int) *sourceLocation {
+	return &sourceLocation{
+		line:   line,
+		column: column,
+	}
+}
+
+func (s *sourceLocation) StartLine() int {
+	return s.line
+}
+
+func (s *sourceLocation) StartColumn() int {
+	return s.column
+}
+
+func (s *sourceLocation) EndLine() int {
+	return s.line
+}
+
+func (s *sourceLocation) EndColumn() int {
+	return s.column
+}
+
+func (s *sourceLocation) LeadingComments() string {
+	return ""
+}
+
+func (s *sourceLocation) TrailingComments() string {
+	return ""
+}
+
+func (s *sourceLocation) LeadingDetachedComments() []string {
+	return nil
+}
@@ -1,12 +1,15 @@
 package internal
 
 import (
+	pathpkg "path"
+	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/bufbuild/buf/internal/buf/bufcheck/internal"
 	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
 	"github.com/bufbuild/buf/internal/pkg/protodesc"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagepath"
 	"github.com/bufbuild/buf/internal/pkg/util/utilstring"
 )
 
@@ -29,6 +32,34 @@ func fieldToUpperSnakeCase(s string) string {
 	return utilstring.ToUpperSnakeCase(s)
 }
 
+// isNamingException returns true if the name or fully-qualified name of the
+// descriptor is within the naming exceptions.
+func isNamingException(namedDescriptor protodesc.NamedDescriptor, namingExceptions map[string]struct{}) bool {
+	if len(namingExceptions) == 0 {
+		return false
+	}
+	if _, ok := namingExceptions[namedDescriptor.Name()]; ok {
+		return true
+	}
+	_, ok := namingExceptions[namedDescriptor.FullName()]
+	return ok
+}
+
+// pathMatchesPattern returns true if the path matches the pattern.
+//
+// If the pattern contains any of the glob characters *?[, the pattern is
+// matched using path.Match. Otherwise, the path matches if it is equal to the
+// pattern or within the directory of the pattern.
+//
+// The pattern and path are expected to be normalized.
+func pathMatchesPattern(pattern string, path string) bool {
+	if strings.ContainsAny(pattern, "*?[") {
+		matched, err := pathpkg.Match(pattern, path)
+		return err == nil && matched
+	}
+	return storagepath.MapContainsMatch(map[string]struct{}{pattern: {}}, path)
+}
+
 // https://cloud.google.com/apis/design/versioning
 //
 // All Proto Package values pass.
@@ -67,6 +98,45 @@ func packageHasVersionSuffix(pkg string) bool {
 	return stringIsPositiveNumber(version)
 }
 
+// packageHasVersionSuffixPattern returns true if the last component of
+// the package entirely matches the pattern.
+//
+// Packages with a single component never pass, as with packageHasVersionSuffix.
+func packageHasVersionSuffixPattern(pkg string, pattern *regexp.Regexp) bool {
+	if pkg == "" {
+		return false
+	}
+	parts := strings.Split(pkg, ".")
+	if len(parts) < 2 {
+		return false
+	}
+	lastPart := parts[len(parts)-1]
+	loc := pattern.FindStringIndex(lastPart)
+	return loc != nil && loc[0] == 0 && loc[1] == len(lastPart)
+}
+
+// packageIsStable returns true if the last component of the package is a
+// stable version such as v1, that is a version that is not an alpha, beta,
+// or test version.
+func packageIsStable(pkg string) bool {
+	parts := strings.Split(pkg, ".")
+	if len(parts) < 2 {
+		return false
+	}
+	lastPart := parts[len(parts)-1]
+	return strings.HasPrefix(lastPart, "v") && stringIsPositiveNumber(lastPart[1:])
+}
+
+// commentContainsAny returns true if the lowercase comment contains any of the lowercase values.
+func commentContainsAny(comment string, values ...string) bool {
+	for _, value := range values {
+		if strings.Contains(comment, value) {
+			return true
+		}
+	}
+	return false
+}
+
 func packageVersionIsValidAlphaOrBeta(version string, name string) bool {
 	split := strings.SplitN(version, name, 2)
 	if len(split) != 2 {
@@ -99,6 +169,14 @@ func stringIsPositiveNumber(s string) bool {
 	return value > 0
 }
 
+// isNarrowerLocation returns true if one starts after two.
+func isNarrowerLocation(one protodesc.Location, two protodesc.Location) bool {
+	if one.StartLine() != two.StartLine() {
+		return one.StartLine() > two.StartLine()
+	}
+	return one.StartColumn() > two.StartColumn()
+}
+
 func newFilesCheckFunc(
 	f func(addFunc, []protodesc.File) error,
 ) func(string, []protodesc.File) ([]*filev1beta1.FileAnnotation, error) {
@@ -209,6 +287,121 @@ func newEnumValueCheckFunc(
 	)
 }
 
+func withBackupLocation(primary protodesc.Location, secondary protodesc.Location) protodesc.Location {
+	if primary != nil {
+		return primary
+	}
+	return secondary
+}
+
+// getFieldJSONName returns the json_name of the field, deriving it from the
+// field name as protoc does if it is not set.
+func getFieldJSONName(field protodesc.Field) string {
+	if jsonName := field.JSONName(); jsonName != "" {
+		return jsonName
+	}
+	var builder strings.Builder
+	capitalizeNext := false
+	for _, c := range field.Name() {
+		switch {
+		case c == '_':
+			capitalizeNext = true
+		case capitalizeNext && 'a' <= c && c <= 'z':
+			builder.WriteRune(c - 'a' + 'A')
+			capitalizeNext = false
+		default:
+			builder.WriteRune(c)
+			capitalizeNext = false
+		}
+	}
+	return builder.String()
+}
+
+// generatedNamer derives the names used in the generated code of a language.
+type generatedNamer struct {
+	language string
+	getName  func(string) string
+}
+
+var (
+	// goGeneratedNamer mirrors CamelCase in protoc-gen-go.
+	goGeneratedNamer = &generatedNamer{
+		language: "Go",
+		getName:  getGoGeneratedName,
+	}
+	// javaGeneratedNamer mirrors UnderscoresToCamelCase in protoc's java generator,
+	// as used for accessors.
+	javaGeneratedNamer = &generatedNamer{
+		language: "Java",
+		getName:  getJavaGeneratedName,
+	}
+)
+
+func getGoGeneratedName(name string) string {
+	if name == "" {
+		return ""
+	}
+	generatedName := make([]byte, 0, len(name)+1)
+	i := 0
+	if name[0] == '_' {
+		// a leading underscore is replaced with an X to export the name
+		generatedName = append(generatedName, 'X')
+		i++
+	}
+	for ; i < len(name); i++ {
+		c := name[i]
+		if c == '_' && i+1 < len(name) && isLowerASCII(name[i+1]) {
+			continue
+		}
+		if isDigitASCII(c) {
+			generatedName = append(generatedName, c)
+			continue
+		}
+		if isLowerASCII(c) {
+			c ^= ' '
+		}
+		generatedName = append(generatedName, c)
+		for i+1 < len(name) && isLowerASCII(name[i+1]) {
+			i++
+			generatedName = append(generatedName, name[i])
+		}
+	}
+	return string(generatedName)
+}
+
+func getJavaGeneratedName(name string) string {
+	generatedName := make([]byte, 0, len(name))
+	capitalizeNext := true
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case isLowerASCII(c):
+			if capitalizeNext {
+				c ^= ' '
+			}
+			generatedName = append(generatedName, c)
+			capitalizeNext = false
+		case 'A' <= c && c <= 'Z':
+			generatedName = append(generatedName, c)
+			capitalizeNext = false
+		case isDigitASCII(c):
+			generatedName = append(generatedName, c)
+			capitalizeNext = true
+		default:
+			capitalizeNext = true
+		}
+	}
+	return string(generatedName)
+}
+
+func isLowerASCII(c byte) bool {
+	return 'a' <= c && c <= 'z'
+}
+
+func isDigitASCII(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
 func newMessageCheckFunc(
 	f func(addFunc, protodesc.Message) error,
 ) func(string, []protodesc.File) ([]*filev1beta1.FileAnnotation, error) {
@@ -60,6 +60,26 @@ func TestPackageHasVersionSuffix(t *testing.T) {
 	testPackageHasVersionSuffix(t, false, "foo.bar.v1aalpha1")
 }
 
+func TestPackageIsStable(t *testing.T) {
+	testPackageIsStable(t, false, "")
+	testPackageIsStable(t, false, "foo")
+	testPackageIsStable(t, false, "v1")
+	testPackageIsStable(t, false, "foo.v0")
+	testPackageIsStable(t, false, "foo.v1.bar")
+	testPackageIsStable(t, false, "foo.bar.v1alpha1")
+	testPackageIsStable(t, false, "foo.bar.v1beta1")
+	testPackageIsStable(t, false, "foo.bar.v1p1beta1")
+	testPackageIsStable(t, false, "foo.bar.v1test")
+	testPackageIsStable(t, false, "foo.bar.vv1")
+	testPackageIsStable(t, true, "foo.v1")
+	testPackageIsStable(t, true, "foo.bar.v2")
+	testPackageIsStable(t, true, "foo.bar.v10")
+}
+
 func testPackageHasVersionSuffix(t *testing.T, expected bool, pkg string) {
 	assert.Equal(t, expected, packageHasVersionSuffix(pkg), pkg)
 }
+
+func testPackageIsStable(t *testing.T, expected bool, pkg string) {
+	assert.Equal(t, expected, packageIsStable(pkg), pkg)
+}
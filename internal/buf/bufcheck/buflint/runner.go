@@ -2,23 +2,67 @@ package buflint
 
 import (
 	"context"
+	"time"
 
+	buflintinternal "github.com/bufbuild/buf/internal/buf/bufcheck/buflint/internal"
 	"github.com/bufbuild/buf/internal/buf/bufcheck/internal"
 	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
 	"github.com/bufbuild/buf/internal/pkg/protodesc"
 	"go.uber.org/zap"
 )
 
+// sourceCheckerIDToCheckFunc are the checkers that check the source of files,
+// which only produce FileAnnotations if the runner can read files.
+var sourceCheckerIDToCheckFunc = map[string]func(string, []protodesc.File, func(string) ([]byte, error)) ([]*filev1beta1.FileAnnotation, error){
+	"FILE_NO_BOM_CRLF": buflintinternal.CheckFileNoBOMCRLF,
+}
+
 type runner struct {
-	delegate *internal.Runner
+	delegate   *internal.Runner
+	readFile   func(string) ([]byte, error)
+	timingFunc func(string, time.Duration)
 }
 
-func newRunner(logger *zap.Logger) *runner {
-	return &runner{
-		delegate: internal.NewRunner(logger.Named("lint")),
+func newRunner(logger *zap.Logger, options ...RunnerOption) *runner {
+	runner := &runner{}
+	for _, option := range options {
+		option(runner)
 	}
+	runner.delegate = internal.NewRunner(
+		logger.Named("lint"),
+		internal.RunnerWithFilePartitioning(),
+		internal.RunnerWithTimingFunc(runner.timingFunc),
+	)
+	return runner
 }
 
 func (r *runner) Check(ctx context.Context, config *Config, files []protodesc.File) ([]*filev1beta1.FileAnnotation, error) {
-	return r.delegate.Check(ctx, configToInternalConfig(config), nil, files)
+	return r.delegate.Check(ctx, r.configToInternalConfig(config), nil, files)
+}
+
+func (r *runner) CheckStream(
+	ctx context.Context,
+	config *Config,
+	files []protodesc.File,
+	fileAnnotationFunc func(*filev1beta1.FileAnnotation) error,
+) error {
+	return r.delegate.CheckStream(ctx, r.configToInternalConfig(config), nil, files, fileAnnotationFunc)
+}
+
+func (r *runner) configToInternalConfig(config *Config) *internal.Config {
+	internalConfig := configToInternalConfig(config)
+	if r.readFile != nil {
+		for i, checker := range internalConfig.Checkers {
+			sourceCheckFunc, ok := sourceCheckerIDToCheckFunc[checker.ID()]
+			if !ok {
+				continue
+			}
+			internalConfig.Checkers[i] = checker.WithCheckFunc(
+				func(id string, _ []protodesc.File, files []protodesc.File) ([]*filev1beta1.FileAnnotation, error) {
+					return sourceCheckFunc(id, files, r.readFile)
+				},
+			)
+		}
+	}
+	return internalConfig
 }
@@ -2,11 +2,15 @@ package buflint
 
 import (
 	"errors"
+	"fmt"
+	"regexp"
 
 	"github.com/bufbuild/buf/internal/buf/bufcheck/buflint/internal"
 	bufcheckinternal "github.com/bufbuild/buf/internal/buf/bufcheck/internal"
 	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
 	"github.com/bufbuild/buf/internal/pkg/protodesc"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagepath"
+	"github.com/bufbuild/buf/internal/pkg/util/utilstring"
 )
 
 var (
@@ -16,6 +20,7 @@ var (
 		v1CommentEnumValueCheckerBuilder,
 		v1CommentFieldCheckerBuilder,
 		v1CommentMessageCheckerBuilder,
+		v1CommentNoTODOCheckerBuilder,
 		v1CommentOneofCheckerBuilder,
 		v1CommentRPCCheckerBuilder,
 		v1CommentServiceCheckerBuilder,
@@ -27,10 +32,20 @@ var (
 		v1EnumZeroValueSuffixCheckerBuilder,
 		v1FieldLowerSnakeCaseCheckerBuilder,
 		v1FieldNoDescriptorCheckerBuilder,
+		v1FieldNoJSONNameCollisionCheckerBuilder,
+		v1FieldOrderedCheckerBuilder,
+		v1FileLicenseHeaderCheckerBuilder,
 		v1FileLowerSnakeCaseCheckerBuilder,
+		v1FileMaxLineLengthCheckerBuilder,
+		v1FileMaxLinesCheckerBuilder,
+		v1FileNoBOMCRLFCheckerBuilder,
 		v1ImportNoPublicCheckerBuilder,
+		v1ImportNoRestrictedCheckerBuilder,
 		v1ImportNoWeakCheckerBuilder,
+		v1ImportOrderedCheckerBuilder,
+		v1MessageNoCrossPackageDuplicateCheckerBuilder,
 		v1MessagePascalCaseCheckerBuilder,
+		v1NameNoGeneratedCollisionCheckerBuilder,
 		v1OneofLowerSnakeCaseCheckerBuilder,
 		v1PackageDefinedCheckerBuilder,
 		v1PackageDirectoryMatchCheckerBuilder,
@@ -45,11 +60,14 @@ var (
 		v1PackageSameSwiftPrefixCheckerBuilder,
 		v1PackageVersionSuffixCheckerBuilder,
 		v1RPCNoClientStreamingCheckerBuilder,
+		v1RPCNoRestrictedClientStreamingCheckerBuilder,
 		v1RPCNoServerStreamingCheckerBuilder,
 		v1RPCPascalCaseCheckerBuilder,
 		v1RPCRequestResponseUniqueCheckerBuilder,
 		v1RPCRequestStandardNameCheckerBuilder,
 		v1RPCResponseStandardNameCheckerBuilder,
+		v1RPCStreamingLifecycleDocumentedCheckerBuilder,
+		v1RPCStreamingPaginationDocumentedCheckerBuilder,
 		v1ServicePascalCaseCheckerBuilder,
 		v1ServiceSuffixCheckerBuilder,
 	}
@@ -65,6 +83,9 @@ var (
 		"DEFAULT",
 		"COMMENTS",
 		"UNARY_RPC",
+		"CONSISTENCY",
+		"POLICY",
+		"STREAMING",
 		"FILE_LAYOUT",
 		"PACKAGE_AFFINITY",
 		"SENSIBLE",
@@ -85,6 +106,9 @@ var (
 		"COMMENT_MESSAGE": {
 			"COMMENTS",
 		},
+		"COMMENT_NO_TODO": {
+			"POLICY",
+		},
 		"COMMENT_ONEOF": {
 			"COMMENTS",
 		},
@@ -138,28 +162,61 @@ var (
 			"DEFAULT",
 			"SENSIBLE",
 		},
+		"FIELD_NO_JSON_NAME_COLLISION": {
+			"MINIMAL",
+			"BASIC",
+			"DEFAULT",
+			"SENSIBLE",
+		},
+		"FIELD_ORDERED": {
+			"POLICY",
+		},
+		"FILE_LICENSE_HEADER": {
+			"POLICY",
+		},
 		"FILE_LOWER_SNAKE_CASE": {
 			"DEFAULT",
 			"STYLE_DEFAULT",
 		},
+		"FILE_MAX_LINE_LENGTH": {
+			"POLICY",
+		},
+		"FILE_MAX_LINES": {
+			"POLICY",
+		},
+		"FILE_NO_BOM_CRLF": {
+			"POLICY",
+		},
 		"IMPORT_NO_PUBLIC": {
 			"MINIMAL",
 			"BASIC",
 			"DEFAULT",
 			"SENSIBLE",
 		},
+		"IMPORT_NO_RESTRICTED": {
+			"POLICY",
+		},
 		"IMPORT_NO_WEAK": {
 			"MINIMAL",
 			"BASIC",
 			"DEFAULT",
 			"SENSIBLE",
 		},
+		"IMPORT_ORDERED": {
+			"POLICY",
+		},
+		"MESSAGE_NO_CROSS_PACKAGE_DUPLICATE": {
+			"CONSISTENCY",
+		},
 		"MESSAGE_PASCAL_CASE": {
 			"BASIC",
 			"DEFAULT",
 			"STYLE_BASIC",
 			"STYLE_DEFAULT",
 		},
+		"NAME_NO_GENERATED_COLLISION": {
+			"POLICY",
+		},
 		"ONEOF_LOWER_SNAKE_CASE": {
 			"BASIC",
 			"DEFAULT",
@@ -239,6 +296,9 @@ var (
 		"RPC_NO_CLIENT_STREAMING": {
 			"UNARY_RPC",
 		},
+		"RPC_NO_RESTRICTED_CLIENT_STREAMING": {
+			"STREAMING",
+		},
 		"RPC_NO_SERVER_STREAMING": {
 			"UNARY_RPC",
 		},
@@ -260,6 +320,12 @@ var (
 			"DEFAULT",
 			"STYLE_DEFAULT",
 		},
+		"RPC_STREAMING_LIFECYCLE_DOCUMENTED": {
+			"STREAMING",
+		},
+		"RPC_STREAMING_PAGINATION_DOCUMENTED": {
+			"STREAMING",
+		},
 		"SERVICE_PASCAL_CASE": {
 			"BASIC",
 			"DEFAULT",
@@ -292,6 +358,25 @@ var (
 		"messages have non-empty comments",
 		newAdapter(internal.CheckCommentMessage),
 	)
+	v1CommentNoTODOCheckerBuilder = bufcheckinternal.NewCheckerBuilder(
+		"COMMENT_NO_TODO",
+		func(configBuilder bufcheckinternal.ConfigBuilder) (string, error) {
+			return "comments in stable packages do not contain TODO or FIXME unless matching the comment_todo_allowed_patterns option (patterns are configurable)", nil
+		},
+		func(configBuilder bufcheckinternal.ConfigBuilder) (bufcheckinternal.CheckFunc, error) {
+			allowedRegexps := make([]*regexp.Regexp, 0, len(configBuilder.CommentTODOAllowedPatterns))
+			for _, pattern := range configBuilder.CommentTODOAllowedPatterns {
+				compiledRegexp, err := regexp.Compile(pattern)
+				if err != nil {
+					return nil, fmt.Errorf("comment_todo_allowed_patterns %q is not a valid regular expression: %v", pattern, err)
+				}
+				allowedRegexps = append(allowedRegexps, compiledRegexp)
+			}
+			return bufcheckinternal.CheckFunc(func(id string, _ []protodesc.File, files []protodesc.File) ([]*filev1beta1.FileAnnotation, error) {
+				return internal.CheckCommentNoTODO(id, files, allowedRegexps)
+			}), nil
+		},
+	)
 	v1CommentOneofCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"COMMENT_ONEOF",
 		"oneof have non-empty comments",
@@ -317,20 +402,20 @@ var (
 		"enums do not have the allow_alias option set",
 		newAdapter(internal.CheckEnumNoAllowAlias),
 	)
-	v1EnumPascalCaseCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+	v1EnumPascalCaseCheckerBuilder = newNamingExceptionsCheckerBuilder(
 		"ENUM_PASCAL_CASE",
 		"enums are PascalCase",
-		newAdapter(internal.CheckEnumPascalCase),
+		internal.CheckEnumPascalCase,
 	)
 	v1EnumValuePrefixCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"ENUM_VALUE_PREFIX",
 		"enum values are prefixed with ENUM_NAME_UPPER_SNAKE_CASE",
 		newAdapter(internal.CheckEnumValuePrefix),
 	)
-	v1EnumValueUpperSnakeCaseCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+	v1EnumValueUpperSnakeCaseCheckerBuilder = newNamingExceptionsCheckerBuilder(
 		"ENUM_VALUE_UPPER_SNAKE_CASE",
 		"enum values are UPPER_SNAKE_CASE",
-		newAdapter(internal.CheckEnumValueUpperSnakeCase),
+		internal.CheckEnumValueUpperSnakeCase,
 	)
 	v1EnumZeroValueSuffixCheckerBuilder = bufcheckinternal.NewCheckerBuilder(
 		"ENUM_ZERO_VALUE_SUFFIX",
@@ -349,16 +434,48 @@ var (
 			}), nil
 		},
 	)
-	v1FieldLowerSnakeCaseCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+	v1FieldLowerSnakeCaseCheckerBuilder = newNamingExceptionsCheckerBuilder(
 		"FIELD_LOWER_SNAKE_CASE",
 		"field names are lower_snake_case",
-		newAdapter(internal.CheckFieldLowerSnakeCase),
+		internal.CheckFieldLowerSnakeCase,
 	)
 	v1FieldNoDescriptorCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"FIELD_NO_DESCRIPTOR",
 		`field names are are not name capitalization of "descriptor" with any number of prefix or suffix underscores`,
 		newAdapter(internal.CheckFieldNoDescriptor),
 	)
+	v1FieldNoJSONNameCollisionCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+		"FIELD_NO_JSON_NAME_COLLISION",
+		"field JSON names do not collide case-insensitively within a message",
+		newAdapter(internal.CheckFieldNoJSONNameCollision),
+	)
+	v1FieldOrderedCheckerBuilder = bufcheckinternal.NewCheckerBuilder(
+		"FIELD_ORDERED",
+		func(configBuilder bufcheckinternal.ConfigBuilder) (string, error) {
+			if configBuilder.FieldOrderedRequiredFirst {
+				return "fields are declared with required fields first, and then in order of field number (ordering is configurable)", nil
+			}
+			return "fields are declared in order of field number (ordering is configurable)", nil
+		},
+		func(configBuilder bufcheckinternal.ConfigBuilder) (bufcheckinternal.CheckFunc, error) {
+			requiredFirst := configBuilder.FieldOrderedRequiredFirst
+			return bufcheckinternal.CheckFunc(func(id string, _ []protodesc.File, files []protodesc.File) ([]*filev1beta1.FileAnnotation, error) {
+				return internal.CheckFieldOrdered(id, files, requiredFirst)
+			}), nil
+		},
+	)
+	v1FileLicenseHeaderCheckerBuilder = bufcheckinternal.NewCheckerBuilder(
+		"FILE_LICENSE_HEADER",
+		func(configBuilder bufcheckinternal.ConfigBuilder) (string, error) {
+			return "files start with the license header set by the license_header option (header is configurable)", nil
+		},
+		func(configBuilder bufcheckinternal.ConfigBuilder) (bufcheckinternal.CheckFunc, error) {
+			licenseHeader := configBuilder.LicenseHeader
+			return bufcheckinternal.CheckFunc(func(id string, _ []protodesc.File, files []protodesc.File) ([]*filev1beta1.FileAnnotation, error) {
+				return internal.CheckFileLicenseHeader(id, files, licenseHeader)
+			}), nil
+		},
+	)
 	v1FileLowerSnakeCaseCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"FILE_LOWER_SNAKE_CASE",
 		"filenames are lower_snake_case",
@@ -369,20 +486,112 @@ var (
 		"imports are not public",
 		newAdapter(internal.CheckImportNoPublic),
 	)
+	v1FileMaxLineLengthCheckerBuilder = bufcheckinternal.NewCheckerBuilder(
+		"FILE_MAX_LINE_LENGTH",
+		func(configBuilder bufcheckinternal.ConfigBuilder) (string, error) {
+			return fmt.Sprintf("lines are at most %d characters long, excluding option lines (maximum is configurable)", configBuilder.MaxLineLength), nil
+		},
+		func(configBuilder bufcheckinternal.ConfigBuilder) (bufcheckinternal.CheckFunc, error) {
+			maxLineLength := configBuilder.MaxLineLength
+			if maxLineLength < 0 {
+				return nil, fmt.Errorf("max_line_length must be positive but was %d", maxLineLength)
+			}
+			return bufcheckinternal.CheckFunc(func(id string, _ []protodesc.File, files []protodesc.File) ([]*filev1beta1.FileAnnotation, error) {
+				return internal.CheckFileMaxLineLength(id, files, maxLineLength)
+			}), nil
+		},
+	)
+	v1FileMaxLinesCheckerBuilder = bufcheckinternal.NewCheckerBuilder(
+		"FILE_MAX_LINES",
+		func(configBuilder bufcheckinternal.ConfigBuilder) (string, error) {
+			return fmt.Sprintf("files are at most %d lines long (maximum is configurable)", configBuilder.MaxFileLines), nil
+		},
+		func(configBuilder bufcheckinternal.ConfigBuilder) (bufcheckinternal.CheckFunc, error) {
+			maxFileLines := configBuilder.MaxFileLines
+			if maxFileLines < 0 {
+				return nil, fmt.Errorf("max_file_lines must be positive but was %d", maxFileLines)
+			}
+			return bufcheckinternal.CheckFunc(func(id string, _ []protodesc.File, files []protodesc.File) ([]*filev1beta1.FileAnnotation, error) {
+				return internal.CheckFileMaxLines(id, files, maxFileLines)
+			}), nil
+		},
+	)
+	v1FileNoBOMCRLFCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+		"FILE_NO_BOM_CRLF",
+		"files do not start with a UTF-8 byte order mark and do not use CRLF line endings",
+		// this reads the source of files, which the runner provides if it can
+		func(string, []protodesc.File, []protodesc.File) ([]*filev1beta1.FileAnnotation, error) {
+			return nil, nil
+		},
+	)
+	v1ImportNoRestrictedCheckerBuilder = bufcheckinternal.NewCheckerBuilder(
+		"IMPORT_NO_RESTRICTED",
+		func(configBuilder bufcheckinternal.ConfigBuilder) (string, error) {
+			return "imports are not restricted by the restricted_imports option (restrictions are configurable)", nil
+		},
+		func(configBuilder bufcheckinternal.ConfigBuilder) (bufcheckinternal.CheckFunc, error) {
+			restrictedImports := make(map[string][]string, len(configBuilder.RestrictedImports))
+			for importPattern, fromPatterns := range configBuilder.RestrictedImports {
+				normalizedImportPattern, err := storagepath.NormalizeAndValidate(importPattern)
+				if err != nil {
+					return nil, fmt.Errorf("restricted_imports: %v", err)
+				}
+				normalizedFromPatterns := make([]string, 0, len(fromPatterns))
+				for _, fromPattern := range fromPatterns {
+					normalizedFromPattern, err := storagepath.NormalizeAndValidate(fromPattern)
+					if err != nil {
+						return nil, fmt.Errorf("restricted_imports: %v", err)
+					}
+					normalizedFromPatterns = append(normalizedFromPatterns, normalizedFromPattern)
+				}
+				restrictedImports[normalizedImportPattern] = normalizedFromPatterns
+			}
+			return bufcheckinternal.CheckFunc(func(id string, _ []protodesc.File, files []protodesc.File) ([]*filev1beta1.FileAnnotation, error) {
+				return internal.CheckImportNoRestricted(id, files, restrictedImports)
+			}), nil
+		},
+	)
 	v1ImportNoWeakCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"IMPORT_NO_WEAK",
 		"imports are not weak",
 		newAdapter(internal.CheckImportNoWeak),
 	)
-	v1MessagePascalCaseCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+	v1ImportOrderedCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+		"IMPORT_ORDERED",
+		"imports are sorted and grouped into well-known types, external imports, and local imports",
+		newAdapter(internal.CheckImportOrdered),
+	).WithAllFiles()
+	v1MessageNoCrossPackageDuplicateCheckerBuilder = bufcheckinternal.NewCheckerBuilder(
+		"MESSAGE_NO_CROSS_PACKAGE_DUPLICATE",
+		func(configBuilder bufcheckinternal.ConfigBuilder) (string, error) {
+			return fmt.Sprintf(
+				"messages with the same name in different packages do not have similar fields, with a similarity threshold of %v (threshold is configurable)",
+				configBuilder.MessageDuplicateSimilarityThreshold,
+			), nil
+		},
+		func(configBuilder bufcheckinternal.ConfigBuilder) (bufcheckinternal.CheckFunc, error) {
+			if configBuilder.MessageDuplicateSimilarityThreshold <= 0 || configBuilder.MessageDuplicateSimilarityThreshold > 1 {
+				return nil, fmt.Errorf("message_duplicate_similarity_threshold must be greater than 0 and at most 1 but was %v", configBuilder.MessageDuplicateSimilarityThreshold)
+			}
+			return bufcheckinternal.CheckFunc(func(id string, _ []protodesc.File, files []protodesc.File) ([]*filev1beta1.FileAnnotation, error) {
+				return internal.CheckMessageNoCrossPackageDuplicate(id, files, configBuilder.MessageDuplicateSimilarityThreshold)
+			}), nil
+		},
+	).WithAllFiles()
+	v1MessagePascalCaseCheckerBuilder = newNamingExceptionsCheckerBuilder(
 		"MESSAGE_PASCAL_CASE",
 		"messages are PascalCase",
-		newAdapter(internal.CheckMessagePascalCase),
+		internal.CheckMessagePascalCase,
+	)
+	v1NameNoGeneratedCollisionCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+		"NAME_NO_GENERATED_COLLISION",
+		"sibling names do not collide in generated code",
+		newAdapter(internal.CheckNameNoGeneratedCollision),
 	)
-	v1OneofLowerSnakeCaseCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+	v1OneofLowerSnakeCaseCheckerBuilder = newNamingExceptionsCheckerBuilder(
 		"ONEOF_LOWER_SNAKE_CASE",
 		"oneof names are lower_snake_case",
-		newAdapter(internal.CheckOneofLowerSnakeCase),
+		internal.CheckOneofLowerSnakeCase,
 	)
 	v1PackageDefinedCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"PACKAGE_DEFINED",
@@ -439,25 +648,61 @@ var (
 		"all files with a given package have the same value for the swift_prefix option",
 		newAdapter(internal.CheckPackageSameSwiftPrefix),
 	)
-	v1PackageVersionSuffixCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+	v1PackageVersionSuffixCheckerBuilder = bufcheckinternal.NewCheckerBuilder(
 		"PACKAGE_VERSION_SUFFIX",
-		`the last component of all packages is a version of the form v\d+, v\d+test.*, v\d+(alpha|beta)\d+, or v\d+p\d+(alpha|beta)\d+, where numbers are >=1`,
-		newAdapter(internal.CheckPackageVersionSuffix),
+		func(configBuilder bufcheckinternal.ConfigBuilder) (string, error) {
+			if configBuilder.PackageVersionSuffixPattern != "" {
+				return "the last component of all packages is a version matching the pattern " + configBuilder.PackageVersionSuffixPattern + " (pattern is configurable)", nil
+			}
+			return `the last component of all packages is a version of the form v\d+, v\d+test.*, v\d+(alpha|beta)\d+, or v\d+p\d+(alpha|beta)\d+, where numbers are >=1 (pattern is configurable)`, nil
+		},
+		func(configBuilder bufcheckinternal.ConfigBuilder) (bufcheckinternal.CheckFunc, error) {
+			if configBuilder.PackageVersionSuffixPattern == "" {
+				return newAdapter(internal.CheckPackageVersionSuffix), nil
+			}
+			pattern, err := regexp.Compile(configBuilder.PackageVersionSuffixPattern)
+			if err != nil {
+				return nil, fmt.Errorf("package_version_suffix_pattern %q is not a valid regular expression: %v", configBuilder.PackageVersionSuffixPattern, err)
+			}
+			return bufcheckinternal.CheckFunc(func(id string, _ []protodesc.File, files []protodesc.File) ([]*filev1beta1.FileAnnotation, error) {
+				return internal.CheckPackageVersionSuffixPattern(id, files, pattern)
+			}), nil
+		},
 	)
 	v1RPCNoClientStreamingCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"RPC_NO_CLIENT_STREAMING",
 		"RPCs are not client streaming",
 		newAdapter(internal.CheckRPCNoClientStreaming),
 	)
+	v1RPCNoRestrictedClientStreamingCheckerBuilder = bufcheckinternal.NewCheckerBuilder(
+		"RPC_NO_RESTRICTED_CLIENT_STREAMING",
+		func(configBuilder bufcheckinternal.ConfigBuilder) (string, error) {
+			return "RPCs matching the rpc_restricted_client_streaming_patterns option are not client streaming (patterns are configurable)", nil
+		},
+		func(configBuilder bufcheckinternal.ConfigBuilder) (bufcheckinternal.CheckFunc, error) {
+			patternToRegexp := make(map[string]*regexp.Regexp, len(configBuilder.RPCRestrictedClientStreamingPatterns))
+			for _, pattern := range configBuilder.RPCRestrictedClientStreamingPatterns {
+				// the pattern must match the entire name of the RPC
+				compiledRegexp, err := regexp.Compile("^(?:" + pattern + ")$")
+				if err != nil {
+					return nil, fmt.Errorf("rpc_restricted_client_streaming_patterns %q is not a valid regular expression: %v", pattern, err)
+				}
+				patternToRegexp[pattern] = compiledRegexp
+			}
+			return bufcheckinternal.CheckFunc(func(id string, _ []protodesc.File, files []protodesc.File) ([]*filev1beta1.FileAnnotation, error) {
+				return internal.CheckRPCNoRestrictedClientStreaming(id, files, patternToRegexp)
+			}), nil
+		},
+	)
 	v1RPCNoServerStreamingCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"RPC_NO_SERVER_STREAMING",
 		"RPCs are not server streaming",
 		newAdapter(internal.CheckRPCNoServerStreaming),
 	)
-	v1RPCPascalCaseCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+	v1RPCPascalCaseCheckerBuilder = newNamingExceptionsCheckerBuilder(
 		"RPC_PASCAL_CASE",
 		"RPCs are PascalCase",
-		newAdapter(internal.CheckRPCPascalCase),
+		internal.CheckRPCPascalCase,
 	)
 	v1RPCRequestResponseUniqueCheckerBuilder = bufcheckinternal.NewCheckerBuilder(
 		"RPC_REQUEST_RESPONSE_UNIQUE",
@@ -475,7 +720,7 @@ var (
 				)
 			}), nil
 		},
-	)
+	).WithAllFiles()
 	v1RPCRequestStandardNameCheckerBuilder = bufcheckinternal.NewCheckerBuilder(
 		"RPC_REQUEST_STANDARD_NAME",
 		func(configBuilder bufcheckinternal.ConfigBuilder) (string, error) {
@@ -506,10 +751,20 @@ var (
 			}), nil
 		},
 	)
-	v1ServicePascalCaseCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+	v1RPCStreamingLifecycleDocumentedCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+		"RPC_STREAMING_LIFECYCLE_DOCUMENTED",
+		"streaming RPCs have comments documenting their keepalive and cancellation behavior",
+		newAdapter(internal.CheckRPCStreamingLifecycleDocumented),
+	)
+	v1RPCStreamingPaginationDocumentedCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+		"RPC_STREAMING_PAGINATION_DOCUMENTED",
+		"server streaming RPCs have comments documenting a paginated alternative",
+		newAdapter(internal.CheckRPCStreamingPaginationDocumented),
+	)
+	v1ServicePascalCaseCheckerBuilder = newNamingExceptionsCheckerBuilder(
 		"SERVICE_PASCAL_CASE",
 		"services are PascalCase",
-		newAdapter(internal.CheckServicePascalCase),
+		internal.CheckServicePascalCase,
 	)
 	v1ServiceSuffixCheckerBuilder = bufcheckinternal.NewCheckerBuilder(
 		"SERVICE_SUFFIX",
@@ -530,6 +785,25 @@ var (
 	)
 )
 
+func newNamingExceptionsCheckerBuilder(
+	id string,
+	purpose string,
+	f func(string, []protodesc.File, map[string]struct{}) ([]*filev1beta1.FileAnnotation, error),
+) *bufcheckinternal.CheckerBuilder {
+	return bufcheckinternal.NewCheckerBuilder(
+		id,
+		func(configBuilder bufcheckinternal.ConfigBuilder) (string, error) {
+			return purpose, nil
+		},
+		func(configBuilder bufcheckinternal.ConfigBuilder) (bufcheckinternal.CheckFunc, error) {
+			namingExceptions := utilstring.SliceToMap(configBuilder.NamingExceptions)
+			return bufcheckinternal.CheckFunc(func(id string, _ []protodesc.File, files []protodesc.File) ([]*filev1beta1.FileAnnotation, error) {
+				return f(id, files, namingExceptions)
+			}), nil
+		},
+	)
+}
+
 func newAdapter(
 	f func(string, []protodesc.File) ([]*filev1beta1.FileAnnotation, error),
 ) func(string, []protodesc.File, []protodesc.File) ([]*filev1beta1.FileAnnotation, error) {
@@ -0,0 +1,247 @@
+package bufcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CheckerDoc is the documentation for a Checker.
+type CheckerDoc struct {
+	// Description describes what the Checker checks in more detail than the purpose.
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Rationale describes why the Checker exists.
+	Rationale string `json:"rationale,omitempty" yaml:"rationale,omitempty"`
+	// Options are the configuration options that affect the Checker.
+	Options []*CheckerOptionDoc `json:"options,omitempty" yaml:"options,omitempty"`
+	// Examples are examples of the Checker.
+	Examples []*CheckerExampleDoc `json:"examples,omitempty" yaml:"examples,omitempty"`
+}
+
+// CheckerOptionDoc is the documentation for a configuration option of a Checker.
+type CheckerOptionDoc struct {
+	// Name is the name of the option within the configuration.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Description describes the effect of the option on the Checker.
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// CheckerExampleDoc is an example for a Checker.
+type CheckerExampleDoc struct {
+	// Description describes the example.
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Content is the Protobuf content of the example.
+	Content string `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+// CheckerDocFormat is a format to print CheckerDocs as.
+type CheckerDocFormat int
+
+const (
+	// CheckerDocFormatText is the text format.
+	CheckerDocFormatText CheckerDocFormat = iota + 1
+	// CheckerDocFormatJSON is the JSON format.
+	//
+	// One JSON object is printed per line.
+	CheckerDocFormatJSON
+	// CheckerDocFormatMarkdown is the markdown format.
+	CheckerDocFormatMarkdown
+)
+
+// ParseCheckerDocFormat parses the CheckerDocFormat.
+//
+// The empty string is parsed as CheckerDocFormatText.
+func ParseCheckerDocFormat(s string) (CheckerDocFormat, error) {
+	switch t := strings.TrimSpace(strings.ToLower(s)); t {
+	case "text", "":
+		return CheckerDocFormatText, nil
+	case "json":
+		return CheckerDocFormatJSON, nil
+	case "markdown":
+		return CheckerDocFormatMarkdown, nil
+	default:
+		return 0, fmt.Errorf("unknown checker doc format: %q", t)
+	}
+}
+
+// PrintCheckerDocs prints the checkers with their documentation to the writer.
+//
+// getCheckerDoc returns the documentation for the given checker ID, or nil if there is none.
+func PrintCheckerDocs(
+	writer io.Writer,
+	checkers []Checker,
+	getCheckerDoc func(string) *CheckerDoc,
+	format CheckerDocFormat,
+) error {
+	for i, checker := range checkers {
+		checkerDoc := getCheckerDoc(checker.ID())
+		if checkerDoc == nil {
+			checkerDoc = &CheckerDoc{}
+		}
+		var err error
+		switch format {
+		case CheckerDocFormatText:
+			if i > 0 {
+				if _, err := fmt.Fprintln(writer); err != nil {
+					return err
+				}
+			}
+			err = printCheckerDocText(writer, checker, checkerDoc)
+		case CheckerDocFormatJSON:
+			err = printCheckerDocJSON(writer, checker, checkerDoc)
+		case CheckerDocFormatMarkdown:
+			if i > 0 {
+				if _, err := fmt.Fprintln(writer); err != nil {
+					return err
+				}
+			}
+			err = printCheckerDocMarkdown(writer, checker, checkerDoc)
+		default:
+			return fmt.Errorf("unknown CheckerDocFormat: %v", format)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printCheckerDocText(writer io.Writer, checker Checker, checkerDoc *CheckerDoc) error {
+	p := newPrinter(writer)
+	p.P(checker.ID())
+	p.P("  Categories: ", strings.Join(checker.Categories(), ", "))
+	p.P("  Purpose: ", checker.Purpose())
+	if checkerDoc.Description != "" {
+		p.P()
+		p.P(indent(checkerDoc.Description, "  "))
+	}
+	if checkerDoc.Rationale != "" {
+		p.P()
+		p.P("  Rationale:")
+		p.P(indent(checkerDoc.Rationale, "    "))
+	}
+	if len(checkerDoc.Options) > 0 {
+		p.P()
+		p.P("  Options:")
+		for _, option := range checkerDoc.Options {
+			p.P("    ", option.Name, ": ", option.Description)
+		}
+	}
+	if len(checkerDoc.Examples) > 0 {
+		p.P()
+		p.P("  Examples:")
+		for i, example := range checkerDoc.Examples {
+			if i > 0 {
+				p.P()
+			}
+			p.P(indent(example.Description, "    "))
+			p.P()
+			p.P(indent(strings.TrimSpace(example.Content), "      "))
+		}
+	}
+	return p.err
+}
+
+func printCheckerDocJSON(writer io.Writer, checker Checker, checkerDoc *CheckerDoc) error {
+	data, err := json.Marshal(
+		checkerDocJSON{
+			ID:          checker.ID(),
+			Categories:  checker.Categories(),
+			Purpose:     checker.Purpose(),
+			Description: checkerDoc.Description,
+			Rationale:   checkerDoc.Rationale,
+			Options:     checkerDoc.Options,
+			Examples:    checkerDoc.Examples,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(writer, string(data))
+	return err
+}
+
+func printCheckerDocMarkdown(writer io.Writer, checker Checker, checkerDoc *CheckerDoc) error {
+	p := newPrinter(writer)
+	p.P("## ", checker.ID())
+	p.P()
+	categories := make([]string, len(checker.Categories()))
+	for i, category := range checker.Categories() {
+		categories[i] = "`" + category + "`"
+	}
+	p.P("Categories: ", strings.Join(categories, ", "))
+	p.P()
+	p.P(checker.Purpose())
+	if checkerDoc.Description != "" {
+		p.P()
+		p.P(checkerDoc.Description)
+	}
+	if checkerDoc.Rationale != "" {
+		p.P()
+		p.P("### Rationale")
+		p.P()
+		p.P(checkerDoc.Rationale)
+	}
+	if len(checkerDoc.Options) > 0 {
+		p.P()
+		p.P("### Options")
+		p.P()
+		for _, option := range checkerDoc.Options {
+			p.P("- `", option.Name, "`: ", option.Description)
+		}
+	}
+	if len(checkerDoc.Examples) > 0 {
+		p.P()
+		p.P("### Examples")
+		for _, example := range checkerDoc.Examples {
+			p.P()
+			p.P(example.Description)
+			p.P()
+			p.P("```proto")
+			p.P(strings.TrimSpace(example.Content))
+			p.P("```")
+		}
+	}
+	return p.err
+}
+
+type checkerDocJSON struct {
+	ID          string               `json:"id" yaml:"id"`
+	Categories  []string             `json:"categories" yaml:"categories"`
+	Purpose     string               `json:"purpose" yaml:"purpose"`
+	Description string               `json:"description,omitempty" yaml:"description,omitempty"`
+	Rationale   string               `json:"rationale,omitempty" yaml:"rationale,omitempty"`
+	Options     []*CheckerOptionDoc  `json:"options,omitempty" yaml:"options,omitempty"`
+	Examples    []*CheckerExampleDoc `json:"examples,omitempty" yaml:"examples,omitempty"`
+}
+
+// printer prints lines and records the first error.
+type printer struct {
+	writer io.Writer
+	err    error
+}
+
+func newPrinter(writer io.Writer) *printer {
+	return &printer{
+		writer: writer,
+	}
+}
+
+func (p *printer) P(args ...string) {
+	if p.err != nil {
+		return
+	}
+	_, p.err = fmt.Fprintln(p.writer, strings.Join(args, ""))
+}
+
+// indent indents all non-empty lines of s.
+func indent(s string, prefix string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
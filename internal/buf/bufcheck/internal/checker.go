@@ -17,6 +17,8 @@ type Checker struct {
 	categories []string
 	purpose    string
 	checkFunc  CheckFunc
+	// allFiles is true if the checker must be run on all files, see CheckerBuilder.WithAllFiles.
+	allFiles bool
 }
 
 // newChecker returns a new Checker.
@@ -65,6 +67,17 @@ func (c *Checker) MarshalJSON() ([]byte, error) {
 	return json.Marshal(checkerJSON{ID: c.id, Categories: c.categories, Purpose: c.purpose})
 }
 
+// WithCheckFunc returns a copy of the Checker with the CheckFunc replaced.
+func (c *Checker) WithCheckFunc(checkFunc CheckFunc) *Checker {
+	return &Checker{
+		id:         c.id,
+		categories: c.categories,
+		purpose:    c.purpose,
+		checkFunc:  checkFunc,
+		allFiles:   c.allFiles,
+	}
+}
+
 func (c *Checker) check(previousFiles []protodesc.File, files []protodesc.File) ([]*filev1beta1.FileAnnotation, error) {
 	return c.checkFunc(c.ID(), previousFiles, files)
 }
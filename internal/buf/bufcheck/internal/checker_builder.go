@@ -10,6 +10,7 @@ type CheckerBuilder struct {
 	id         string
 	newPurpose func(ConfigBuilder) (string, error)
 	newCheck   func(ConfigBuilder) (CheckFunc, error)
+	allFiles   bool
 }
 
 // NewCheckerBuilder returns a new CheckerBuilder.
@@ -54,12 +55,24 @@ func (c *CheckerBuilder) NewChecker(configBuilder ConfigBuilder, categories []st
 	if err != nil {
 		return nil, err
 	}
-	return newChecker(
+	checker := newChecker(
 		c.id,
 		categories,
 		purpose,
 		check,
-	), nil
+	)
+	checker.allFiles = c.allFiles
+	return checker, nil
+}
+
+// WithAllFiles returns a copy of the CheckerBuilder for checkers that look
+// across all files, such as checkers that compare files with each other.
+//
+// These checkers are always run on all files, and never on partitions of the files.
+func (c *CheckerBuilder) WithAllFiles() *CheckerBuilder {
+	checkerBuilder := *c
+	checkerBuilder.allFiles = true
+	return &checkerBuilder
 }
 
 // ID returns the id.
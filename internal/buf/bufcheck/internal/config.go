@@ -12,6 +12,11 @@ import (
 const (
 	defaultEnumZeroValueSuffix = "_UNSPECIFIED"
 	defaultServiceSuffix       = "Service"
+
+	defaultMessageDuplicateSimilarityThreshold = 0.8
+
+	defaultMaxFileLines  = 1000
+	defaultMaxLineLength = 120
 )
 
 // Config is the check config.
@@ -39,6 +44,17 @@ type ConfigBuilder struct {
 	RPCAllowGoogleProtobufEmptyRequests  bool
 	RPCAllowGoogleProtobufEmptyResponses bool
 	ServiceSuffix                        string
+	NamingExceptions                     []string
+	MessageDuplicateSimilarityThreshold  float64
+	PackageVersionSuffixPattern          string
+	RestrictedImports                    map[string][]string
+	RPCRestrictedClientStreamingPatterns []string
+	MaxFileLines                         int
+	MaxLineLength                        int
+	FieldOrderedRequiredFirst            bool
+	LicenseHeader                        string
+	CommentTODOAllowedPatterns           []string
+	CustomOptions                        []string
 }
 
 // NewConfig returns a new Config.
@@ -73,6 +89,16 @@ func newConfig(
 	if configBuilder.ServiceSuffix == "" {
 		configBuilder.ServiceSuffix = defaultServiceSuffix
 	}
+	if configBuilder.MessageDuplicateSimilarityThreshold == 0 {
+		configBuilder.MessageDuplicateSimilarityThreshold = defaultMessageDuplicateSimilarityThreshold
+	}
+	if configBuilder.MaxFileLines == 0 {
+		configBuilder.MaxFileLines = defaultMaxFileLines
+	}
+	if configBuilder.MaxLineLength == 0 {
+		configBuilder.MaxLineLength = defaultMaxLineLength
+	}
+	configBuilder.CustomOptions = utilstring.SliceToUniqueSortedSliceFilterEmptyStrings(configBuilder.CustomOptions)
 	return newConfigForCheckerBuilders(
 		configBuilder,
 		checkerBuilders,
@@ -3,6 +3,7 @@ package internaltesting
 import (
 	"testing"
 
+	"github.com/bufbuild/buf/internal/buf/bufcheck"
 	"github.com/bufbuild/buf/internal/buf/bufcheck/internal"
 	"github.com/bufbuild/buf/internal/pkg/util/utilstring"
 	"github.com/stretchr/testify/assert"
@@ -51,3 +52,25 @@ func RunTestCheckerBuilders(
 		assert.True(t, ok, "id %q configured in categories is not added to checkerBuilders", id)
 	}
 }
+
+// RunTestCheckerDocs runs the test.
+func RunTestCheckerDocs(
+	t *testing.T,
+	checkerBuilders []*internal.CheckerBuilder,
+	idToCheckerDoc map[string]*bufcheck.CheckerDoc,
+) {
+	idsMap := make(map[string]struct{}, len(checkerBuilders))
+	for _, checkerBuilder := range checkerBuilders {
+		idsMap[checkerBuilder.ID()] = struct{}{}
+		checkerDoc, ok := idToCheckerDoc[checkerBuilder.ID()]
+		if !assert.True(t, ok, "id %q is not documented", checkerBuilder.ID()) {
+			continue
+		}
+		assert.NotEmpty(t, checkerDoc.Description, "id %q must have a description", checkerBuilder.ID())
+		assert.NotEmpty(t, checkerDoc.Rationale, "id %q must have a rationale", checkerBuilder.ID())
+	}
+	for id := range idToCheckerDoc {
+		_, ok := idsMap[id]
+		assert.True(t, ok, "id %q documented is not added to checkerBuilders", id)
+	}
+}
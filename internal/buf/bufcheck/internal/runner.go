@@ -2,6 +2,9 @@ package internal
 
 import (
 	"context"
+	"runtime"
+	"sort"
+	"time"
 
 	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
 	"github.com/bufbuild/buf/internal/pkg/ext/extfile"
@@ -14,60 +17,233 @@ import (
 
 // Runner is a runner.
 type Runner struct {
-	logger *zap.Logger
+	logger         *zap.Logger
+	parallelism    int
+	partitionFiles bool
+	timingFunc     func(string, time.Duration)
 }
 
 // NewRunner returns a new Runner.
-func NewRunner(logger *zap.Logger) *Runner {
-	return &Runner{
-		logger: logger,
+func NewRunner(logger *zap.Logger, options ...RunnerOption) *Runner {
+	runner := &Runner{
+		logger:      logger,
+		parallelism: runtime.NumCPU(),
+	}
+	for _, option := range options {
+		option(runner)
+	}
+	return runner
+}
+
+// RunnerOption is an option for a new Runner.
+type RunnerOption func(*Runner)
+
+// RunnerWithParallelism returns a new RunnerOption that sets the maximum
+// number of checks that run at the same time.
+//
+// The default is runtime.NumCPU().
+func RunnerWithParallelism(parallelism int) RunnerOption {
+	return func(runner *Runner) {
+		if parallelism > 0 {
+			runner.parallelism = parallelism
+		}
+	}
+}
+
+// RunnerWithFilePartitioning returns a new RunnerOption that splits the files
+// into partitions and runs each checker on each partition concurrently.
+//
+// Files in the same package or directory are always in the same partition,
+// so checkers that look at a single file, package, or directory return the
+// same results. Checkers built with CheckerBuilder.WithAllFiles look across
+// all files, and are always run on all files.
+//
+// This must not be used if the checkers use the previous files.
+func RunnerWithFilePartitioning() RunnerOption {
+	return func(runner *Runner) {
+		runner.partitionFiles = true
+	}
+}
+
+// RunnerWithTimingFunc returns a new RunnerOption that calls the function with
+// the checker ID and the execution time of the checker for every run of a checker.
+//
+// If the files are partitioned, the function is called once per partition.
+// The function is only called from the goroutine that called Check.
+func RunnerWithTimingFunc(timingFunc func(string, time.Duration)) RunnerOption {
+	return func(runner *Runner) {
+		runner.timingFunc = timingFunc
 	}
 }
 
 // Check runs the Checkers.
 func (r *Runner) Check(ctx context.Context, config *Config, previousFiles []protodesc.File, files []protodesc.File) ([]*filev1beta1.FileAnnotation, error) {
+	var fileAnnotations []*filev1beta1.FileAnnotation
+	if err := r.CheckStream(
+		ctx,
+		config,
+		previousFiles,
+		files,
+		func(fileAnnotation *filev1beta1.FileAnnotation) error {
+			fileAnnotations = append(fileAnnotations, fileAnnotation)
+			return nil
+		},
+	); err != nil {
+		return nil, err
+	}
+	extfile.SortFileAnnotations(fileAnnotations)
+	return fileAnnotations, nil
+}
+
+// CheckStream runs the Checkers, calling the function with each FileAnnotation
+// as soon as the checker run that produced it completes.
+//
+// The FileAnnotations are not sorted. The function is only called from the
+// goroutine that called CheckStream. If the function returns an error, the
+// remaining checker runs are abandoned and the error is returned.
+func (r *Runner) CheckStream(
+	ctx context.Context,
+	config *Config,
+	previousFiles []protodesc.File,
+	files []protodesc.File,
+	fileAnnotationFunc func(*filev1beta1.FileAnnotation) error,
+) error {
 	checkers := config.Checkers
 	if len(checkers) == 0 {
-		return nil, nil
+		return nil
 	}
 	defer utillog.Defer(r.logger, "check", zap.Int("num_files", len(files)), zap.Int("num_checkers", len(checkers)))()
 
-	var fileAnnotations []*filev1beta1.FileAnnotation
-	resultC := make(chan *result, len(checkers))
+	partitions := [][]protodesc.File{files}
+	if r.partitionFiles {
+		partitions = partitionFiles(files, r.parallelism)
+	}
+	var jobs []*job
 	for _, checker := range checkers {
 		checker := checker
+		checkerPartitions := partitions
+		if checker.allFiles {
+			checkerPartitions = [][]protodesc.File{files}
+		}
+		for _, partition := range checkerPartitions {
+			partition := partition
+			jobs = append(
+				jobs,
+				newJob(
+					checker.ID(),
+					func() ([]*filev1beta1.FileAnnotation, error) {
+						return checker.check(previousFiles, partition)
+					},
+				),
+			)
+		}
+	}
+	r.logger.Debug("check_jobs", zap.Int("num_partitions", len(partitions)), zap.Int("num_jobs", len(jobs)))
+
+	resultC := make(chan *result, len(jobs))
+	semaphoreC := make(chan struct{}, r.parallelism)
+	for _, job := range jobs {
+		job := job
 		go func() {
-			iFileAnnotations, iErr := checker.check(previousFiles, files)
-			resultC <- newResult(iFileAnnotations, iErr)
+			semaphoreC <- struct{}{}
+			start := time.Now()
+			iFileAnnotations, iErr := job.run()
+			duration := time.Since(start)
+			<-semaphoreC
+			resultC <- newResult(job.checkerID, duration, iFileAnnotations, iErr)
 		}()
 	}
 	var err error
-	for i := 0; i < len(checkers); i++ {
+	for i := 0; i < len(jobs); i++ {
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return ctx.Err()
 		case result := <-resultC:
-			fileAnnotations = append(fileAnnotations, result.FileAnnotations...)
+			if r.timingFunc != nil {
+				r.timingFunc(result.CheckerID, result.Duration)
+			}
 			err = multierr.Append(err, result.Err)
+			if err != nil {
+				// the FileAnnotations are discarded on error, so they are not passed on
+				continue
+			}
+			for _, fileAnnotation := range result.FileAnnotations {
+				if shouldIgnoreFileAnnotation(fileAnnotation, config.IgnoreRootPaths, config.IgnoreIDToRootPaths) {
+					continue
+				}
+				if err := fileAnnotationFunc(fileAnnotation); err != nil {
+					return err
+				}
+			}
 		}
 	}
-	if err != nil {
-		return nil, err
-	}
+	return err
+}
 
-	if len(config.IgnoreRootPaths) == 0 && len(config.IgnoreIDToRootPaths) == 0 {
-		extfile.SortFileAnnotations(fileAnnotations)
-		return fileAnnotations, nil
+// partitionFiles splits the files into at most numPartitions partitions of
+// roughly equal size, keeping files in the same package or directory together.
+func partitionFiles(files []protodesc.File, numPartitions int) [][]protodesc.File {
+	if numPartitions < 2 || len(files) < 2 {
+		return [][]protodesc.File{files}
 	}
-
-	filteredFileAnnotations := make([]*filev1beta1.FileAnnotation, 0, len(fileAnnotations))
-	for _, fileAnnotation := range fileAnnotations {
-		if !shouldIgnoreFileAnnotation(fileAnnotation, config.IgnoreRootPaths, config.IgnoreIDToRootPaths) {
-			filteredFileAnnotations = append(filteredFileAnnotations, fileAnnotation)
+	// union-find over the file indexes, joining files that share a package or directory
+	parents := make([]int, len(files))
+	for i := range parents {
+		parents[i] = i
+	}
+	find := func(i int) int {
+		for parents[i] != i {
+			parents[i] = parents[parents[i]]
+			i = parents[i]
+		}
+		return i
+	}
+	keyToIndex := make(map[string]int)
+	for i, file := range files {
+		for _, key := range []string{
+			"package:" + file.Package(),
+			"dir:" + storagepath.Dir(file.FilePath()),
+		} {
+			if j, ok := keyToIndex[key]; ok {
+				parents[find(i)] = find(j)
+			} else {
+				keyToIndex[key] = i
+			}
 		}
 	}
-	extfile.SortFileAnnotations(filteredFileAnnotations)
-	return filteredFileAnnotations, nil
+	var groups [][]protodesc.File
+	rootToGroupIndex := make(map[int]int)
+	for i, file := range files {
+		root := find(i)
+		groupIndex, ok := rootToGroupIndex[root]
+		if !ok {
+			groupIndex = len(groups)
+			rootToGroupIndex[root] = groupIndex
+			groups = append(groups, nil)
+		}
+		groups[groupIndex] = append(groups[groupIndex], file)
+	}
+	if len(groups) < numPartitions {
+		numPartitions = len(groups)
+	}
+	// assign the largest groups first, each to the partition with the fewest files
+	sort.SliceStable(
+		groups,
+		func(i int, j int) bool {
+			return len(groups[i]) > len(groups[j])
+		},
+	)
+	partitions := make([][]protodesc.File, numPartitions)
+	for _, group := range groups {
+		smallest := 0
+		for i := 1; i < len(partitions); i++ {
+			if len(partitions[i]) < len(partitions[smallest]) {
+				smallest = i
+			}
+		}
+		partitions[smallest] = append(partitions[smallest], group...)
+	}
+	return partitions
 }
 
 func shouldIgnoreFileAnnotation(fileAnnotation *filev1beta1.FileAnnotation, ignoreAllRootPaths map[string]struct{}, ignoreIDToRootPaths map[string]map[string]struct{}) bool {
@@ -87,13 +263,29 @@ func shouldIgnoreFileAnnotation(fileAnnotation *filev1beta1.FileAnnotation, igno
 	return storagepath.MapContainsMatch(ignoreRootPaths, fileAnnotation.Path)
 }
 
+type job struct {
+	checkerID string
+	run       func() ([]*filev1beta1.FileAnnotation, error)
+}
+
+func newJob(checkerID string, run func() ([]*filev1beta1.FileAnnotation, error)) *job {
+	return &job{
+		checkerID: checkerID,
+		run:       run,
+	}
+}
+
 type result struct {
+	CheckerID       string
+	Duration        time.Duration
 	FileAnnotations []*filev1beta1.FileAnnotation
 	Err             error
 }
 
-func newResult(fileAnnotations []*filev1beta1.FileAnnotation, err error) *result {
+func newResult(checkerID string, duration time.Duration, fileAnnotations []*filev1beta1.FileAnnotation, err error) *result {
 	return &result{
+		CheckerID:       checkerID,
+		Duration:        duration,
 		FileAnnotations: fileAnnotations,
 		Err:             err,
 	}
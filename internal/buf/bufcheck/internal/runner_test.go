@@ -0,0 +1,167 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+	"github.com/bufbuild/buf/internal/pkg/protodesc"
+	"github.com/golang/protobuf/proto"
+	protobufdescriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPartitionFiles(t *testing.T) {
+	t.Parallel()
+	files := testNewFiles(
+		t,
+		"a/a1.proto", "a",
+		"a/a2.proto", "a",
+		"b/b.proto", "b",
+		"c/c.proto", "a",
+		"d/d.proto", "d",
+		"e/e1.proto", "e",
+		"e/e2.proto", "f",
+		"f/f.proto", "f",
+	)
+	assert.Equal(
+		t,
+		[][]string{{"a/a1.proto", "a/a2.proto", "c/c.proto"}, {"e/e1.proto", "e/e2.proto", "f/f.proto"}, {"b/b.proto", "d/d.proto"}},
+		testFilePaths(partitionFiles(files, 3)),
+	)
+	assert.Equal(
+		t,
+		[][]string{{"a/a1.proto", "a/a2.proto", "c/c.proto", "b/b.proto"}, {"e/e1.proto", "e/e2.proto", "f/f.proto", "d/d.proto"}},
+		testFilePaths(partitionFiles(files, 2)),
+	)
+	assert.Equal(
+		t,
+		[][]string{{"a/a1.proto", "a/a2.proto", "b/b.proto", "c/c.proto", "d/d.proto", "e/e1.proto", "e/e2.proto", "f/f.proto"}},
+		testFilePaths(partitionFiles(files, 1)),
+	)
+	assert.Len(t, partitionFiles(files, 100), 4)
+	assert.Equal(t, [][]string{{}}, testFilePaths(partitionFiles(nil, 3)))
+}
+
+func TestRunnerCheckStream(t *testing.T) {
+	t.Parallel()
+	// the slow checker only completes once the FileAnnotation of the fast checker
+	// was passed on, so this only completes if FileAnnotations are not held back
+	// until all checkers complete
+	fastPassedC := make(chan struct{})
+	config := &Config{
+		Checkers: []*Checker{
+			newChecker(
+				"SLOW",
+				nil,
+				"slow",
+				func(id string, _ []protodesc.File, _ []protodesc.File) ([]*filev1beta1.FileAnnotation, error) {
+					select {
+					case <-fastPassedC:
+					case <-time.After(10 * time.Second):
+						return nil, errors.New("timed out waiting for the FileAnnotation of FAST")
+					}
+					return []*filev1beta1.FileAnnotation{{Path: "a.proto", Type: id}}, nil
+				},
+			),
+			newChecker(
+				"FAST",
+				nil,
+				"fast",
+				func(id string, _ []protodesc.File, _ []protodesc.File) ([]*filev1beta1.FileAnnotation, error) {
+					return []*filev1beta1.FileAnnotation{
+						{Path: "b.proto", Type: id},
+						{Path: "ignore/c.proto", Type: id},
+					}, nil
+				},
+			),
+		},
+		IgnoreRootPaths: map[string]struct{}{"ignore": {}},
+	}
+	var types []string
+	err := NewRunner(zap.NewNop(), RunnerWithParallelism(2)).CheckStream(
+		context.Background(),
+		config,
+		nil,
+		nil,
+		func(fileAnnotation *filev1beta1.FileAnnotation) error {
+			types = append(types, fileAnnotation.Type)
+			if fileAnnotation.Type == "FAST" {
+				close(fastPassedC)
+			}
+			return nil
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"FAST", "SLOW"}, types)
+}
+
+func TestRunnerFilePartitioningAllFiles(t *testing.T) {
+	t.Parallel()
+	files := testNewFiles(
+		t,
+		"a/a.proto", "a",
+		"b/b.proto", "b",
+		"c/c.proto", "c",
+		"d/d.proto", "d",
+	)
+	var lock sync.Mutex
+	checkerIDToNumFiles := make(map[string][]int)
+	checkFunc := func(id string, _ []protodesc.File, files []protodesc.File) ([]*filev1beta1.FileAnnotation, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		checkerIDToNumFiles[id] = append(checkerIDToNumFiles[id], len(files))
+		return nil, nil
+	}
+	partitionedChecker, err := NewNopCheckerBuilder("PARTITIONED", "partitioned", checkFunc).NewChecker(ConfigBuilder{}, nil)
+	require.NoError(t, err)
+	allFilesChecker, err := NewNopCheckerBuilder("ALL_FILES", "all files", checkFunc).WithAllFiles().NewChecker(ConfigBuilder{}, nil)
+	require.NoError(t, err)
+	// replacing the CheckFunc keeps the property
+	allFilesChecker = allFilesChecker.WithCheckFunc(checkFunc)
+	_, err = NewRunner(
+		zap.NewNop(),
+		RunnerWithParallelism(4),
+		RunnerWithFilePartitioning(),
+	).Check(
+		context.Background(),
+		&Config{Checkers: []*Checker{partitionedChecker, allFilesChecker}},
+		nil,
+		files,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 1, 1, 1}, checkerIDToNumFiles["PARTITIONED"])
+	assert.Equal(t, []int{4}, checkerIDToNumFiles["ALL_FILES"])
+}
+
+// testNewFiles takes pairs of file paths and packages.
+func testNewFiles(t *testing.T, filePathsAndPackages ...string) []protodesc.File {
+	var files []protodesc.File
+	for i := 0; i < len(filePathsAndPackages); i += 2 {
+		file, err := protodesc.NewFile(
+			&protobufdescriptor.FileDescriptorProto{
+				Name:    proto.String(filePathsAndPackages[i]),
+				Package: proto.String(filePathsAndPackages[i+1]),
+			},
+		)
+		require.NoError(t, err)
+		files = append(files, file)
+	}
+	return files
+}
+
+func testFilePaths(partitions [][]protodesc.File) [][]string {
+	filePaths := make([][]string, len(partitions))
+	for i, partition := range partitions {
+		filePaths[i] = []string{}
+		for _, file := range partition {
+			filePaths[i] = append(filePaths[i], file.FilePath())
+		}
+	}
+	return filePaths
+}
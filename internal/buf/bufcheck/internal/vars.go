@@ -2,15 +2,19 @@ package internal
 
 // priority 1 is higher than priority two
 var topLevelCategoryToPriority = map[string]int{
-	"MINIMAL":   1,
-	"BASIC":     2,
-	"DEFAULT":   3,
-	"COMMENTS":  4,
-	"UNARY_RPC": 5,
-	"FILE":      1,
-	"PACKAGE":   2,
-	"WIRE_JSON": 3,
-	"WIRE":      4,
+	"MINIMAL":     1,
+	"BASIC":       2,
+	"DEFAULT":     3,
+	"COMMENTS":    4,
+	"UNARY_RPC":   5,
+	"CONSISTENCY": 6,
+	"POLICY":      7,
+	"STREAMING":   8,
+	"FILE":        1,
+	"PACKAGE":     2,
+	"WIRE_JSON":   3,
+	"WIRE":        4,
+	"RESERVED":    5,
 }
 
 func categoryCompare(one string, two string) int {
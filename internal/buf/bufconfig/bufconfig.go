@@ -4,13 +4,22 @@ package bufconfig
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"io"
+	"net/url"
 	"sort"
+	"strings"
 
+	"github.com/bufbuild/buf/internal/buf/bufassert"
 	"github.com/bufbuild/buf/internal/buf/bufcheck/bufbreaking"
 	"github.com/bufbuild/buf/internal/buf/bufcheck/buflint"
 	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
 	"github.com/bufbuild/buf/internal/pkg/storage"
+	"github.com/bufbuild/buf/internal/pkg/util/utilencoding"
+	"github.com/bufbuild/buf/internal/pkg/util/utilnet"
 	"go.uber.org/zap"
 )
 
@@ -19,6 +28,10 @@ import (
 // TODO: make sure copied for git
 const ConfigFilePath = "buf.yaml"
 
+// InputAliasPrefix is the prefix of input values that refer to an input alias
+// defined in the inputs section of the config.
+const InputAliasPrefix = "alias:"
+
 // Config is the user config.
 //
 // Configs must not be linked to a specific Bucket object, that is if a Config
@@ -30,6 +43,121 @@ type Config struct {
 	Build    ExternalBuildConfig
 	Breaking *bufbreaking.Config
 	Lint     *buflint.Config
+	// Inputs is a map from input alias to input value.
+	Inputs map[string]string
+	// Tests are the assertions run by buf test.
+	Tests []*bufassert.Assertion
+	// LintExtends is the location of the shared config the lint config extends, if any.
+	//
+	// Lint does not include the extended config until WithLintExtends is called.
+	LintExtends string
+
+	// externalLintConfig is the lint section of the config before it was built,
+	// so that it can be merged with the lint section of the extended config.
+	externalLintConfig ExternalLintConfig
+	// extendedExternalLintConfig is the lint section of the extended config,
+	// or nil if WithLintExtends was not called.
+	extendedExternalLintConfig *ExternalLintConfig
+	// externalBreakingConfig is the breaking section of the config before it was built.
+	externalBreakingConfig ExternalBreakingConfig
+	// externalTests is the tests section of the config before it was built.
+	externalTests []bufassert.ExternalAssertion
+}
+
+// ResolveInputAlias returns the input value for the alias if the value has the
+// InputAliasPrefix, otherwise returns the value.
+//
+// Returns error if the alias is not defined in the config.
+func ResolveInputAlias(config *Config, value string) (string, error) {
+	if !strings.HasPrefix(value, InputAliasPrefix) {
+		return value, nil
+	}
+	alias := strings.TrimPrefix(value, InputAliasPrefix)
+	input, ok := config.Inputs[alias]
+	if !ok {
+		return "", fmt.Errorf("unknown input alias %q, input aliases must be defined in the inputs section of the config", alias)
+	}
+	return input, nil
+}
+
+// ConfigDigest returns the hex-encoded SHA256 digest of the values of the config.
+//
+// The lint values include the values of the extended config, if any, so that
+// configs with the same effective values have the same digest.
+func ConfigDigest(config *Config) (string, error) {
+	externalConfig := ExternalConfig{
+		Build:    config.Build,
+		Breaking: config.externalBreakingConfig,
+		Lint:     config.externalLintConfig,
+		Inputs:   config.Inputs,
+		Tests:    config.externalTests,
+	}
+	if config.extendedExternalLintConfig != nil {
+		externalConfig.Lint = mergeExtendedExternalLintConfig(*config.extendedExternalLintConfig, config.externalLintConfig)
+	}
+	// encoding/json sorts map keys so this is deterministic
+	data, err := json.Marshal(externalConfig)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(data)
+	return hex.EncodeToString(digest[:]), nil
+}
+
+// ParseLintExtends parses the lint.extends value into the URL and the expected
+// hex-encoded SHA256 hash of the data at the URL.
+//
+// The value must be of the form https://host/path#sha256=hash. http URLs are only
+// allowed for localhost and loopback IP addresses, such as for local testing.
+func ParseLintExtends(value string) (string, string, error) {
+	split := strings.SplitN(value, "#", 2)
+	rawURL := split[0]
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil || parsedURL.Host == "" || (parsedURL.Scheme != "https" && parsedURL.Scheme != "http") {
+		return "", "", fmt.Errorf("lint.extends: %q must be an https URL", value)
+	}
+	if parsedURL.Scheme == "http" && !utilnet.IsLocalhost(parsedURL.Hostname()) {
+		return "", "", fmt.Errorf("lint.extends: %q must be an https URL, http is only allowed for localhost", value)
+	}
+	if len(split) != 2 || !strings.HasPrefix(split[1], "sha256=") {
+		return "", "", fmt.Errorf("lint.extends: %q must end with #sha256=hash so that the extended config is verified", value)
+	}
+	sha256Hex := strings.ToLower(strings.TrimPrefix(split[1], "sha256="))
+	if sha256Data, err := hex.DecodeString(sha256Hex); err != nil || len(sha256Data) != sha256.Size {
+		return "", "", fmt.Errorf("lint.extends: %q does not have a valid sha256 hash", value)
+	}
+	return rawURL, sha256Hex, nil
+}
+
+// WithLintExtends returns a copy of the config with the lint config merged beneath
+// the lint section of the extended config data, which must already be verified.
+//
+// The extended config data is YAML or JSON containing only a lint section. Values set in
+// the lint section of the config replace the values of the extended config, and lint
+// overrides apply to the merged config. The extended config cannot itself extend
+// another config, or contain overrides or a baseline, as these are specific to a repository.
+func WithLintExtends(config *Config, data []byte) (*Config, error) {
+	externalExtendsConfig := &externalExtendsConfig{}
+	if err := utilencoding.UnmarshalJSONOrYAMLStrict(data, externalExtendsConfig); err != nil {
+		return nil, fmt.Errorf("lint.extends: %s: %v", config.LintExtends, err)
+	}
+	extendedLintConfig := externalExtendsConfig.Lint
+	switch {
+	case extendedLintConfig.Extends != "":
+		return nil, fmt.Errorf("lint.extends: %s: extended config cannot contain extends", config.LintExtends)
+	case len(extendedLintConfig.Overrides) > 0:
+		return nil, fmt.Errorf("lint.extends: %s: extended config cannot contain overrides", config.LintExtends)
+	case extendedLintConfig.Baseline != "":
+		return nil, fmt.Errorf("lint.extends: %s: extended config cannot contain a baseline", config.LintExtends)
+	}
+	lintConfig, err := newLintConfig(mergeExtendedExternalLintConfig(extendedLintConfig, config.externalLintConfig))
+	if err != nil {
+		return nil, fmt.Errorf("lint.extends: %s: %v", config.LintExtends, err)
+	}
+	newConfig := *config
+	newConfig.Lint = lintConfig
+	newConfig.extendedExternalLintConfig = &extendedLintConfig
+	return &newConfig, nil
 }
 
 // Provider is a provider.
@@ -66,6 +194,9 @@ func NewProvider(logger *zap.Logger, options ...ProviderOption) Provider {
 type ExternalBuildConfig struct {
 	Roots    []string `json:"roots,omitempty" yaml:"roots,omitempty"`
 	Excludes []string `json:"excludes,omitempty" yaml:"excludes,omitempty"`
+	// Deps are the names of builtin dependencies, such as googleapis, that are
+	// fetched and cached for import resolution only.
+	Deps []string `json:"deps,omitempty" yaml:"deps,omitempty"`
 }
 
 // ExternalConfig is an external config.
@@ -75,6 +206,13 @@ type ExternalConfig struct {
 	Build    ExternalBuildConfig    `json:"build,omitempty" yaml:"build,omitempty"`
 	Breaking ExternalBreakingConfig `json:"breaking,omitempty" yaml:"breaking,omitempty"`
 	Lint     ExternalLintConfig     `json:"lint,omitempty" yaml:"lint,omitempty"`
+	// Inputs is a map from input alias to input value, so that inputs can be
+	// referred to as alias:name, such as --against-input alias:prod.
+	Inputs map[string]string `json:"inputs,omitempty" yaml:"inputs,omitempty"`
+	// Tests are assertions against the image that are run by buf test, such as
+	// that a package contains a service, or that no message has more than a
+	// maximum number of fields.
+	Tests []bufassert.ExternalAssertion `json:"tests,omitempty" yaml:"tests,omitempty"`
 }
 
 // ExternalBreakingConfig is an external config.
@@ -85,6 +223,14 @@ type ExternalBreakingConfig struct {
 	Except     []string            `json:"except,omitempty" yaml:"except,omitempty"`
 	Ignore     []string            `json:"ignore,omitempty" yaml:"ignore,omitempty"`
 	IgnoreOnly map[string][]string `json:"ignore_only,omitempty" yaml:"ignore_only,omitempty"`
+	// CustomOptions are the names of the options checked by the *_SAME_CUSTOM_OPTIONS
+	// checkers, either standard options such as deprecated or extensions such as
+	// google.api.http.
+	CustomOptions []string `json:"custom_options,omitempty" yaml:"custom_options,omitempty"`
+	// EnumValueDeleteGraceReleases is a map from enum value deletion checker IDs or
+	// categories to the number of releases an enum value must have been deprecated
+	// for before it can be deleted.
+	EnumValueDeleteGraceReleases map[string]int `json:"enum_value_delete_grace_releases,omitempty" yaml:"enum_value_delete_grace_releases,omitempty"`
 }
 
 // ExternalLintConfig is an external config.
@@ -100,6 +246,65 @@ type ExternalLintConfig struct {
 	RPCAllowGoogleProtobufEmptyRequests  bool                `json:"rpc_allow_google_protobuf_empty_requests,omitempty" yaml:"rpc_allow_google_protobuf_empty_requests,omitempty"`
 	RPCAllowGoogleProtobufEmptyResponses bool                `json:"rpc_allow_google_protobuf_empty_responses,omitempty" yaml:"rpc_allow_google_protobuf_empty_responses,omitempty"`
 	ServiceSuffix                        string              `json:"service_suffix,omitempty" yaml:"service_suffix,omitempty"`
+	NamingExceptions                     []string            `json:"naming_exceptions,omitempty" yaml:"naming_exceptions,omitempty"`
+	MessageDuplicateSimilarityThreshold  float64             `json:"message_duplicate_similarity_threshold,omitempty" yaml:"message_duplicate_similarity_threshold,omitempty"`
+	PackageVersionSuffixPattern          string              `json:"package_version_suffix_pattern,omitempty" yaml:"package_version_suffix_pattern,omitempty"`
+	RestrictedImports                    map[string][]string `json:"restricted_imports,omitempty" yaml:"restricted_imports,omitempty"`
+	RPCRestrictedClientStreamingPatterns []string            `json:"rpc_restricted_client_streaming_patterns,omitempty" yaml:"rpc_restricted_client_streaming_patterns,omitempty"`
+	MaxFileLines                         int                 `json:"max_file_lines,omitempty" yaml:"max_file_lines,omitempty"`
+	MaxLineLength                        int                 `json:"max_line_length,omitempty" yaml:"max_line_length,omitempty"`
+	FieldOrderedRequiredFirst            bool                `json:"field_ordered_required_first,omitempty" yaml:"field_ordered_required_first,omitempty"`
+	LicenseHeader                        string              `json:"license_header,omitempty" yaml:"license_header,omitempty"`
+	CommentTODOAllowedPatterns           []string            `json:"comment_todo_allowed_patterns,omitempty" yaml:"comment_todo_allowed_patterns,omitempty"`
+	AllowCommentIgnores                  bool                `json:"allow_comment_ignores,omitempty" yaml:"allow_comment_ignores,omitempty"`
+	// Overrides are applied to the files within their paths.
+	//
+	// An override inherits all values from this config, and any value set on the
+	// override replaces the inherited value. If multiple override paths contain a file,
+	// the most specific path wins. Overrides cannot be nested.
+	Overrides []ExternalLintOverrideConfig `json:"overrides,omitempty" yaml:"overrides,omitempty"`
+	// FailurePolicy cannot be set on overrides.
+	FailurePolicy ExternalLintFailurePolicyConfig `json:"failure_policy,omitempty" yaml:"failure_policy,omitempty"`
+	// Extends is the location of a shared config whose lint section this config
+	// extends, of the form https://host/path#sha256=hash.
+	//
+	// Extends cannot be set on overrides.
+	Extends string `json:"extends,omitempty" yaml:"extends,omitempty"`
+	// Baseline is the path of a baseline file relative to the input directory.
+	//
+	// Baseline cannot be set on overrides.
+	Baseline string `json:"baseline,omitempty" yaml:"baseline,omitempty"`
+}
+
+// externalExtendsConfig is an extended config.
+type externalExtendsConfig struct {
+	Lint ExternalLintConfig `json:"lint,omitempty" yaml:"lint,omitempty"`
+}
+
+// ExternalLintFailurePolicyConfig is an external config.
+//
+// Should only be used outside this package for testing.
+type ExternalLintFailurePolicyConfig struct {
+	// FailOn is one of error, warning, or never.
+	FailOn string `json:"fail_on,omitempty" yaml:"fail_on,omitempty"`
+	// Warn are the checker IDs or categories whose violations are warnings.
+	Warn []string `json:"warn,omitempty" yaml:"warn,omitempty"`
+	// Thresholds are the number of violations of checker IDs or categories that are
+	// warnings before all of their violations are errors.
+	Thresholds map[string]int `json:"thresholds,omitempty" yaml:"thresholds,omitempty"`
+}
+
+func (e ExternalLintFailurePolicyConfig) isEmpty() bool {
+	return e.FailOn == "" && len(e.Warn) == 0 && len(e.Thresholds) == 0
+}
+
+// ExternalLintOverrideConfig is an external config.
+//
+// Should only be used outside this package for testing.
+type ExternalLintOverrideConfig struct {
+	// Path is the root-relative directory path the override applies to.
+	Path               string `json:"path,omitempty" yaml:"path,omitempty"`
+	ExternalLintConfig `yaml:",inline"`
 }
 
 // PrintFileAnnotationsLintConfigIgnoreYAML prints the FileAnnotations to the Writer as config-ignore-yaml.
@@ -0,0 +1,221 @@
+package bufconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/bufbuild/buf/internal/buf/bufcheck"
+	"github.com/bufbuild/buf/internal/buf/bufcheck/buflint"
+)
+
+// ConfigDiffEntry is a difference in a section of the effective config.
+type ConfigDiffEntry struct {
+	// Section is the section of the config, such as lint.checkers.
+	Section string `json:"section,omitempty" yaml:"section,omitempty"`
+	// Added are the values present in the new config but not the old config.
+	Added []string `json:"added,omitempty" yaml:"added,omitempty"`
+	// Removed are the values present in the old config but not the new config.
+	Removed []string `json:"removed,omitempty" yaml:"removed,omitempty"`
+}
+
+// GetConfigDiff returns the differences in the effective configs.
+//
+// Checkers are compared after resolving categories, so moving a checker between
+// use and except or changing categories only shows the checkers actually enabled or disabled.
+// Sections without differences are not returned.
+func GetConfigDiff(oldConfig *Config, newConfig *Config) []*ConfigDiffEntry {
+	var entries []*ConfigDiffEntry
+	entries = appendConfigDiffEntry(entries, "build.roots", oldConfig.Build.Roots, newConfig.Build.Roots)
+	entries = appendConfigDiffEntry(entries, "build.excludes", oldConfig.Build.Excludes, newConfig.Build.Excludes)
+	entries = appendConfigDiffEntry(entries, "build.deps", oldConfig.Build.Deps, newConfig.Build.Deps)
+	entries = appendConfigDiffEntry(
+		entries,
+		"breaking.checkers",
+		getCheckerIDs(oldConfig.Breaking.GetCheckers()),
+		getCheckerIDs(newConfig.Breaking.GetCheckers()),
+	)
+	entries = appendConfigDiffEntry(entries, "breaking.ignore", mapToSlice(oldConfig.Breaking.IgnoreRootPaths), mapToSlice(newConfig.Breaking.IgnoreRootPaths))
+	entries = appendConfigDiffEntry(entries, "breaking.ignore_only", ignoreOnlyToSlice(oldConfig.Breaking.IgnoreIDToRootPaths), ignoreOnlyToSlice(newConfig.Breaking.IgnoreIDToRootPaths))
+	return appendLintConfigDiffEntries(entries, "lint", oldConfig.Lint, newConfig.Lint)
+}
+
+// PrintConfigDiff prints the ConfigDiffEntries to the writer.
+//
+// The text format prints one line per added or removed value, prefixed by the section
+// and + or -. The JSON format prints one ConfigDiffEntry per line.
+func PrintConfigDiff(writer io.Writer, entries []*ConfigDiffEntry, asJSON bool) error {
+	for _, entry := range entries {
+		if asJSON {
+			data, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(writer, string(data)); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, added := range entry.Added {
+			if _, err := fmt.Fprintf(writer, "%s: +%s\n", entry.Section, added); err != nil {
+				return err
+			}
+		}
+		for _, removed := range entry.Removed {
+			if _, err := fmt.Fprintf(writer, "%s: -%s\n", entry.Section, removed); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func appendLintConfigDiffEntries(
+	entries []*ConfigDiffEntry,
+	section string,
+	oldLintConfig *buflint.Config,
+	newLintConfig *buflint.Config,
+) []*ConfigDiffEntry {
+	entries = appendConfigDiffEntry(
+		entries,
+		section+".checkers",
+		getCheckerIDs(oldLintConfig.GetCheckers()),
+		getCheckerIDs(newLintConfig.GetCheckers()),
+	)
+	entries = appendConfigDiffEntry(entries, section+".ignore", mapToSlice(oldLintConfig.IgnoreRootPaths), mapToSlice(newLintConfig.IgnoreRootPaths))
+	entries = appendConfigDiffEntry(entries, section+".ignore_only", ignoreOnlyToSlice(oldLintConfig.IgnoreIDToRootPaths), ignoreOnlyToSlice(newLintConfig.IgnoreIDToRootPaths))
+	entries = appendConfigDiffEntry(
+		entries,
+		section+".allow_comment_ignores",
+		[]string{strconv.FormatBool(oldLintConfig.AllowCommentIgnores)},
+		[]string{strconv.FormatBool(newLintConfig.AllowCommentIgnores)},
+	)
+	oldFailurePolicy := getFailurePolicy(oldLintConfig)
+	newFailurePolicy := getFailurePolicy(newLintConfig)
+	entries = appendConfigDiffEntry(
+		entries,
+		section+".failure_policy.fail_on",
+		[]string{oldFailurePolicy.FailOn.String()},
+		[]string{newFailurePolicy.FailOn.String()},
+	)
+	entries = appendConfigDiffEntry(entries, section+".failure_policy.warn", mapToSlice(oldFailurePolicy.WarningIDs), mapToSlice(newFailurePolicy.WarningIDs))
+	entries = appendConfigDiffEntry(entries, section+".failure_policy.thresholds", thresholdsToSlice(oldFailurePolicy.Thresholds), thresholdsToSlice(newFailurePolicy.Thresholds))
+	entries = appendConfigDiffEntry(entries, section+".baseline", stringToSlice(oldLintConfig.Baseline), stringToSlice(newLintConfig.Baseline))
+	oldRootPathToOverride := make(map[string]*buflint.ConfigOverride, len(oldLintConfig.Overrides))
+	for _, configOverride := range oldLintConfig.Overrides {
+		oldRootPathToOverride[configOverride.RootPath] = configOverride
+	}
+	newRootPathToOverride := make(map[string]*buflint.ConfigOverride, len(newLintConfig.Overrides))
+	for _, configOverride := range newLintConfig.Overrides {
+		newRootPathToOverride[configOverride.RootPath] = configOverride
+	}
+	oldRootPaths := make([]string, 0, len(oldRootPathToOverride))
+	for rootPath := range oldRootPathToOverride {
+		oldRootPaths = append(oldRootPaths, rootPath)
+	}
+	newRootPaths := make([]string, 0, len(newRootPathToOverride))
+	for rootPath := range newRootPathToOverride {
+		newRootPaths = append(newRootPaths, rootPath)
+	}
+	entries = appendConfigDiffEntry(entries, section+".overrides", oldRootPaths, newRootPaths)
+	sort.Strings(newRootPaths)
+	for _, rootPath := range newRootPaths {
+		if oldConfigOverride, ok := oldRootPathToOverride[rootPath]; ok {
+			entries = appendLintConfigDiffEntries(
+				entries,
+				fmt.Sprintf("%s.overrides[%s]", section, rootPath),
+				oldConfigOverride.Config,
+				newRootPathToOverride[rootPath].Config,
+			)
+		}
+	}
+	return entries
+}
+
+// appendConfigDiffEntry appends an entry if there is a difference between the values.
+func appendConfigDiffEntry(entries []*ConfigDiffEntry, section string, oldValues []string, newValues []string) []*ConfigDiffEntry {
+	oldValueMap := make(map[string]struct{}, len(oldValues))
+	for _, oldValue := range oldValues {
+		oldValueMap[oldValue] = struct{}{}
+	}
+	newValueMap := make(map[string]struct{}, len(newValues))
+	for _, newValue := range newValues {
+		newValueMap[newValue] = struct{}{}
+	}
+	entry := &ConfigDiffEntry{
+		Section: section,
+	}
+	for newValue := range newValueMap {
+		if _, ok := oldValueMap[newValue]; !ok {
+			entry.Added = append(entry.Added, newValue)
+		}
+	}
+	for oldValue := range oldValueMap {
+		if _, ok := newValueMap[oldValue]; !ok {
+			entry.Removed = append(entry.Removed, oldValue)
+		}
+	}
+	if len(entry.Added) == 0 && len(entry.Removed) == 0 {
+		return entries
+	}
+	sort.Strings(entry.Added)
+	sort.Strings(entry.Removed)
+	return append(entries, entry)
+}
+
+func getCheckerIDs(checkers []bufcheck.Checker, err error) []string {
+	// GetCheckers only returns an error if categories are given
+	if err != nil {
+		return nil
+	}
+	checkerIDs := make([]string, len(checkers))
+	for i, checker := range checkers {
+		checkerIDs[i] = checker.ID()
+	}
+	return checkerIDs
+}
+
+func mapToSlice(m map[string]struct{}) []string {
+	s := make([]string, 0, len(m))
+	for key := range m {
+		s = append(s, key)
+	}
+	return s
+}
+
+// stringToSlice returns the string as a slice, or nil if the string is empty.
+func stringToSlice(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return []string{s}
+}
+
+func ignoreOnlyToSlice(ignoreIDToRootPaths map[string]map[string]struct{}) []string {
+	var s []string
+	for id, rootPaths := range ignoreIDToRootPaths {
+		for rootPath := range rootPaths {
+			s = append(s, id+"="+rootPath)
+		}
+	}
+	return s
+}
+
+// getFailurePolicy returns the FailurePolicy of the Config, or the default
+// FailurePolicy if it is not set.
+func getFailurePolicy(lintConfig *buflint.Config) *buflint.FailurePolicy {
+	if lintConfig.FailurePolicy == nil {
+		return &buflint.FailurePolicy{FailOn: buflint.FailOnError}
+	}
+	return lintConfig.FailurePolicy
+}
+
+func thresholdsToSlice(thresholds []*buflint.FailureThreshold) []string {
+	s := make([]string, 0, len(thresholds))
+	for _, threshold := range thresholds {
+		s = append(s, threshold.IDOrCategory+"="+strconv.Itoa(threshold.MaxViolations))
+	}
+	return s
+}
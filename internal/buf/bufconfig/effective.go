@@ -0,0 +1,169 @@
+package bufconfig
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/bufbuild/buf/internal/buf/bufcheck/bufbreaking"
+	"github.com/bufbuild/buf/internal/buf/bufcheck/buflint"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigSource is the source of values that are not set by any config.
+const DefaultConfigSource = "default"
+
+// PrintEffectiveConfig prints the effective config as YAML.
+//
+// The lint section includes the values of the extended config, if any. Each value has
+// a comment with its source, which is the given source for values set by the config, the
+// URL for values set by the extended config, or DefaultConfigSource for the checkers used
+// if use is not set.
+func PrintEffectiveConfig(writer io.Writer, config *Config, source string) error {
+	externalLintConfig := config.externalLintConfig
+	extendsSource := ""
+	var extendedExternalLintConfig ExternalLintConfig
+	if config.extendedExternalLintConfig != nil {
+		extendedExternalLintConfig = *config.extendedExternalLintConfig
+		externalLintConfig = mergeExtendedExternalLintConfig(extendedExternalLintConfig, config.externalLintConfig)
+		rawURL, _, err := ParseLintExtends(config.LintExtends)
+		if err != nil {
+			return err
+		}
+		extendsSource = rawURL
+	}
+	buildNode, err := newProvenanceNode(config.Build, nil, source, "")
+	if err != nil {
+		return err
+	}
+	breakingNode, err := newProvenanceNode(config.externalBreakingConfig, nil, source, "")
+	if err != nil {
+		return err
+	}
+	if len(config.externalBreakingConfig.Use) == 0 {
+		prependDefaultUse(breakingNode, bufbreaking.GetDefaultCategories())
+	}
+	lintNode, err := newProvenanceNode(externalLintConfig, config.externalLintConfig, source, extendsSource)
+	if err != nil {
+		return err
+	}
+	if len(externalLintConfig.Use) == 0 {
+		prependDefaultUse(lintNode, buflint.GetDefaultCategories())
+	}
+	inputsNode, err := newProvenanceNode(config.Inputs, nil, source, "")
+	if err != nil {
+		return err
+	}
+	documentNode := &yaml.Node{
+		Kind: yaml.MappingNode,
+	}
+	for _, section := range []struct {
+		name string
+		node *yaml.Node
+	}{
+		{name: "build", node: buildNode},
+		{name: "breaking", node: breakingNode},
+		{name: "lint", node: lintNode},
+		{name: "inputs", node: inputsNode},
+	} {
+		if len(section.node.Content) == 0 {
+			continue
+		}
+		documentNode.Content = append(
+			documentNode.Content,
+			&yaml.Node{
+				Kind:  yaml.ScalarNode,
+				Value: section.name,
+			},
+			section.node,
+		)
+	}
+	if len(documentNode.Content) == 0 {
+		return nil
+	}
+	encoder := yaml.NewEncoder(writer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(documentNode); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// newProvenanceNode returns the mapping node for the value, with a comment on each key
+// with the source of the value.
+//
+// If local is nil, all keys are from the source. Otherwise, keys set in local are from
+// the source, and all other keys are from the extends source.
+func newProvenanceNode(value interface{}, local interface{}, source string, extendsSource string) (*yaml.Node, error) {
+	node, err := newMappingNode(value)
+	if err != nil {
+		return nil, err
+	}
+	localKeys := make(map[string]struct{})
+	if local != nil {
+		localNode, err := newMappingNode(local)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < len(localNode.Content); i += 2 {
+			localKeys[localNode.Content[i].Value] = struct{}{}
+		}
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		keySource := source
+		if _, ok := localKeys[keyNode.Value]; local != nil && !ok {
+			keySource = extendsSource
+		}
+		keyNode.LineComment = keySource
+	}
+	return node, nil
+}
+
+// newMappingNode returns the mapping node for the value, with only the set values.
+func newMappingNode(value interface{}) (*yaml.Node, error) {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	documentNode := &yaml.Node{}
+	if err := yaml.Unmarshal(data, documentNode); err != nil {
+		return nil, err
+	}
+	if len(documentNode.Content) != 1 {
+		return nil, fmt.Errorf("expected one node for %T but got %d", value, len(documentNode.Content))
+	}
+	node := documentNode.Content[0]
+	if node.Kind != yaml.MappingNode {
+		// empty values are marshaled as {} or null
+		return &yaml.Node{Kind: yaml.MappingNode}, nil
+	}
+	// flow style is used for empty mappings, but the keys are printed in block style
+	node.Style = 0
+	return node, nil
+}
+
+func prependDefaultUse(node *yaml.Node, defaultCategories []string) {
+	useNode := &yaml.Node{
+		Kind: yaml.SequenceNode,
+	}
+	for _, category := range defaultCategories {
+		useNode.Content = append(
+			useNode.Content,
+			&yaml.Node{
+				Kind:  yaml.ScalarNode,
+				Value: category,
+			},
+		)
+	}
+	node.Content = append(
+		[]*yaml.Node{
+			{
+				Kind:        yaml.ScalarNode,
+				Value:       "use",
+				LineComment: DefaultConfigSource,
+			},
+			useNode,
+		},
+		node.Content...,
+	)
+}
@@ -0,0 +1,143 @@
+package bufconfig
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/buf/bufcheck/bufbreaking"
+	"github.com/bufbuild/buf/internal/buf/bufcheck/buflint"
+	"github.com/bufbuild/buf/internal/pkg/storage"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagepath"
+	"github.com/bufbuild/buf/internal/pkg/storage/storageutil"
+	"gopkg.in/yaml.v3"
+)
+
+// packageRegexp matches the package statement of a .proto file, which is at the
+// start of a line or after another statement such as the syntax statement.
+var packageRegexp = regexp.MustCompile(`(?m)(?:^|;)\s*package\s+([\w.]+)\s*;`)
+
+// InferRoots infers the roots of the .proto files in the bucket.
+//
+// The root of a file is the directory that its package directory is in, such
+// as proto for proto/foo/v1/foo.proto with package foo.v1, or the top-level
+// directory if the package does not match the directory of the file. Roots within
+// other roots are removed, as roots cannot overlap.
+//
+// Returns nil if the only root is the top-level directory, as this is the default.
+// Hidden directories are skipped.
+func InferRoots(ctx context.Context, bucket storage.ReadBucket) ([]string, error) {
+	rootMap := make(map[string]struct{})
+	if err := bucket.Walk(ctx, "", func(path string) error {
+		if storagepath.Ext(path) != ".proto" || isHiddenPath(path) {
+			return nil
+		}
+		data, err := storageutil.ReadPath(ctx, bucket, path)
+		if err != nil {
+			return err
+		}
+		rootMap[inferRoot(path, data)] = struct{}{}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if _, ok := rootMap["."]; ok {
+		return nil, nil
+	}
+	roots := make([]string, 0, len(rootMap))
+	for root := range rootMap {
+		roots = append(roots, root)
+	}
+	// sorting puts roots before the roots within them
+	sort.Strings(roots)
+	var outerRoots []string
+	for _, root := range roots {
+		if !isWithinRoots(root, outerRoots) {
+			outerRoots = append(outerRoots, root)
+		}
+	}
+	return outerRoots, nil
+}
+
+// NewInitConfigData returns the YAML data of a new config with the roots and
+// the lint and breaking checkers or categories to use.
+//
+// If lintUse or breakingUse are empty, the default categories are used, so that
+// the defaults are visible in the config.
+func NewInitConfigData(roots []string, lintUse []string, breakingUse []string) ([]byte, error) {
+	if len(lintUse) == 0 {
+		lintUse = buflint.GetDefaultCategories()
+	}
+	if len(breakingUse) == 0 {
+		breakingUse = bufbreaking.GetDefaultCategories()
+	}
+	// build, lint, and breaking are in the order they are usually read
+	initConfig := struct {
+		Build    ExternalBuildConfig    `yaml:"build,omitempty"`
+		Lint     ExternalLintConfig     `yaml:"lint,omitempty"`
+		Breaking ExternalBreakingConfig `yaml:"breaking,omitempty"`
+	}{
+		Build: ExternalBuildConfig{
+			Roots: roots,
+		},
+		Lint: ExternalLintConfig{
+			Use: lintUse,
+		},
+		Breaking: ExternalBreakingConfig{
+			Use: breakingUse,
+		},
+	}
+	return marshalConfigYAML(initConfig)
+}
+
+// inferRoot returns the root of the file with the path and data.
+func inferRoot(path string, data []byte) string {
+	dirPath := storagepath.Dir(path)
+	match := packageRegexp.FindSubmatch(data)
+	if match == nil {
+		return "."
+	}
+	packageDirPath := strings.Replace(string(match[1]), ".", "/", -1)
+	if dirPath == packageDirPath {
+		return "."
+	}
+	if !strings.HasSuffix(dirPath, "/"+packageDirPath) {
+		return "."
+	}
+	return strings.TrimSuffix(dirPath, "/"+packageDirPath)
+}
+
+// marshalConfigYAML marshals the config as YAML with the two-space indentation
+// used by buf.yaml files.
+func marshalConfigYAML(config interface{}) ([]byte, error) {
+	buffer := bytes.NewBuffer(nil)
+	encoder := yaml.NewEncoder(buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(config); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func isWithinRoots(path string, roots []string) bool {
+	for _, root := range roots {
+		if strings.HasPrefix(path, root+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func isHiddenPath(path string) bool {
+	for _, component := range strings.Split(path, "/") {
+		if strings.HasPrefix(component, ".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,397 @@
+package bufconfig
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/buf/bufcheck/buflint"
+	"github.com/bufbuild/buf/internal/buf/bufgen"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// MigrateFormatPrototool is the format of prototool configs.
+	MigrateFormatPrototool = "prototool"
+	// MigrateFormatProtolint is the format of protolint configs.
+	MigrateFormatProtolint = "protolint"
+)
+
+var (
+	// MigrateConfigFilePaths are the config file paths that can be migrated,
+	// in the order they are looked for.
+	MigrateConfigFilePaths = []string{
+		"prototool.yaml",
+		"prototool.json",
+		".protolint.yaml",
+		".protolint.yml",
+		"protolint.yaml",
+		"protolint.yml",
+	}
+
+	// ruleToLintIDs maps the names of prototool and protolint rules to the
+	// equivalent lint checker IDs.
+	//
+	// The names of the two do not conflict, and where they are the same they
+	// have the same meaning.
+	ruleToLintIDs = map[string][]string{
+		// prototool
+		"ENUM_FIELD_NAMES_UPPER_SNAKE_CASE":            {"ENUM_VALUE_UPPER_SNAKE_CASE"},
+		"ENUM_FIELD_PREFIXES":                          {"ENUM_VALUE_PREFIX"},
+		"ENUM_FIELDS_HAVE_COMMENTS":                    {"COMMENT_ENUM_VALUE"},
+		"ENUM_NAMES_CAMEL_CASE":                        {"ENUM_PASCAL_CASE"},
+		"ENUM_NAMES_CAPITALIZED":                       {"ENUM_PASCAL_CASE"},
+		"ENUM_ZERO_VALUES_INVALID":                     {"ENUM_ZERO_VALUE_SUFFIX"},
+		"ENUMS_HAVE_COMMENTS":                          {"COMMENT_ENUM"},
+		"ENUMS_NO_ALLOW_ALIAS":                         {"ENUM_NO_ALLOW_ALIAS"},
+		"FILE_NAMES_LOWER_SNAKE_CASE":                  {"FILE_LOWER_SNAKE_CASE"},
+		"FILE_OPTIONS_CSHARP_NAMESPACE_SAME_IN_DIR":    {"PACKAGE_SAME_CSHARP_NAMESPACE"},
+		"FILE_OPTIONS_GO_PACKAGE_SAME_IN_DIR":          {"PACKAGE_SAME_GO_PACKAGE"},
+		"FILE_OPTIONS_JAVA_MULTIPLE_FILES_SAME_IN_DIR": {"PACKAGE_SAME_JAVA_MULTIPLE_FILES"},
+		"FILE_OPTIONS_JAVA_PACKAGE_SAME_IN_DIR":        {"PACKAGE_SAME_JAVA_PACKAGE"},
+		"FILE_OPTIONS_PHP_NAMESPACE_SAME_IN_DIR":       {"PACKAGE_SAME_PHP_NAMESPACE"},
+		"IMPORTS_NOT_PUBLIC":                           {"IMPORT_NO_PUBLIC"},
+		"IMPORTS_NOT_WEAK":                             {"IMPORT_NO_WEAK"},
+		"MESSAGE_FIELD_NAMES_LOWER_SNAKE_CASE":         {"FIELD_LOWER_SNAKE_CASE"},
+		"MESSAGE_FIELDS_HAVE_COMMENTS":                 {"COMMENT_FIELD"},
+		"MESSAGE_NAMES_CAMEL_CASE":                     {"MESSAGE_PASCAL_CASE"},
+		"MESSAGE_NAMES_CAPITALIZED":                    {"MESSAGE_PASCAL_CASE"},
+		"MESSAGES_HAVE_COMMENTS":                       {"COMMENT_MESSAGE"},
+		"ONEOF_NAMES_LOWER_SNAKE_CASE":                 {"ONEOF_LOWER_SNAKE_CASE"},
+		"PACKAGE_IS_DECLARED":                          {"PACKAGE_DEFINED"},
+		"PACKAGE_LOWER_SNAKE_CASE":                     {"PACKAGE_LOWER_SNAKE_CASE"},
+		"PACKAGE_MAJOR_BETA_VERSIONED":                 {"PACKAGE_VERSION_SUFFIX"},
+		"PACKAGES_SAME_IN_DIR":                         {"DIRECTORY_SAME_PACKAGE"},
+		"REQUEST_RESPONSE_NAMES_MATCH_RPC":             {"RPC_REQUEST_STANDARD_NAME", "RPC_RESPONSE_STANDARD_NAME"},
+		"REQUEST_RESPONSE_TYPES_UNIQUE":                {"RPC_REQUEST_RESPONSE_UNIQUE"},
+		"RPC_NAMES_CAMEL_CASE":                         {"RPC_PASCAL_CASE"},
+		"RPC_NAMES_CAPITALIZED":                        {"RPC_PASCAL_CASE"},
+		"RPCS_HAVE_COMMENTS":                           {"COMMENT_RPC"},
+		"RPCS_NO_STREAMING":                            {"RPC_NO_CLIENT_STREAMING", "RPC_NO_SERVER_STREAMING"},
+		"SERVICE_NAMES_API_SUFFIX":                     {"SERVICE_SUFFIX"},
+		"SERVICE_NAMES_CAMEL_CASE":                     {"SERVICE_PASCAL_CASE"},
+		"SERVICE_NAMES_CAPITALIZED":                    {"SERVICE_PASCAL_CASE"},
+		"SERVICES_HAVE_COMMENTS":                       {"COMMENT_SERVICE"},
+		// protolint
+		"ENUM_FIELD_NAMES_PREFIX":              {"ENUM_VALUE_PREFIX"},
+		"ENUM_FIELD_NAMES_ZERO_VALUE_END_WITH": {"ENUM_ZERO_VALUE_SUFFIX"},
+		"ENUM_FIELDS_HAVE_COMMENT":             {"COMMENT_ENUM_VALUE"},
+		"ENUM_NAMES_UPPER_CAMEL_CASE":          {"ENUM_PASCAL_CASE"},
+		"ENUMS_HAVE_COMMENT":                   {"COMMENT_ENUM"},
+		"FIELD_NAMES_LOWER_SNAKE_CASE":         {"FIELD_LOWER_SNAKE_CASE"},
+		"FIELDS_HAVE_COMMENT":                  {"COMMENT_FIELD"},
+		"IMPORTS_SORTED":                       {"IMPORT_ORDERED"},
+		"MAX_LINE_LENGTH":                      {"FILE_MAX_LINE_LENGTH"},
+		"MESSAGE_NAMES_UPPER_CAMEL_CASE":       {"MESSAGE_PASCAL_CASE"},
+		"MESSAGES_HAVE_COMMENT":                {"COMMENT_MESSAGE"},
+		"PACKAGE_NAME_LOWER_CASE":              {"PACKAGE_LOWER_SNAKE_CASE"},
+		"RPC_NAMES_UPPER_CAMEL_CASE":           {"RPC_PASCAL_CASE"},
+		"RPCS_HAVE_COMMENT":                    {"COMMENT_RPC"},
+		"SERVICE_NAMES_END_WITH":               {"SERVICE_SUFFIX"},
+		"SERVICE_NAMES_UPPER_CAMEL_CASE":       {"SERVICE_PASCAL_CASE"},
+		"SERVICES_HAVE_COMMENT":                {"COMMENT_SERVICE"},
+	}
+
+	// prototoolGroupToLintCategory maps the prototool lint groups to the
+	// closest lint category.
+	prototoolGroupToLintCategory = map[string]string{
+		"google": "BASIC",
+		"uber1":  "DEFAULT",
+		"uber2":  "DEFAULT",
+	}
+)
+
+// Migration is a migrated config.
+type Migration struct {
+	// Data is the YAML data of the migrated config.
+	Data []byte
+	// Notes describe the settings that could not be migrated, or that were
+	// migrated to settings that are similar but not identical.
+	//
+	// Each note starts with the name of the setting, such as lint.group.
+	Notes []string
+}
+
+// MigrateFormatForFilePath returns the format of the config file path, or
+// empty if the config file path cannot be migrated.
+func MigrateFormatForFilePath(filePath string) string {
+	base := filepath.Base(filePath)
+	switch {
+	case strings.HasPrefix(base, "prototool."):
+		return MigrateFormatPrototool
+	case strings.HasPrefix(base, ".protolint."), strings.HasPrefix(base, "protolint."):
+		return MigrateFormatProtolint
+	default:
+		return ""
+	}
+}
+
+// MigrateConfig migrates the YAML or JSON data of a config of the format to a config.
+//
+// The migrated config is not validated.
+func MigrateConfig(data []byte, format string) (*Migration, error) {
+	migrator := &migrator{}
+	var err error
+	switch format {
+	case MigrateFormatPrototool:
+		err = migrator.migratePrototool(data)
+	case MigrateFormatProtolint:
+		err = migrator.migrateProtolint(data)
+	default:
+		return nil, fmt.Errorf("unknown config format %q, must be one of [%s,%s]", format, MigrateFormatPrototool, MigrateFormatProtolint)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s config: %v", format, err)
+	}
+	data, err = marshalConfigYAML(migrator.externalConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &Migration{
+		Data:  data,
+		Notes: migrator.notes,
+	}, nil
+}
+
+type externalPrototoolConfig struct {
+	Excludes []string `yaml:"excludes"`
+	Protoc   struct {
+		Version            string   `yaml:"version"`
+		Includes           []string `yaml:"includes"`
+		AllowUnusedImports bool     `yaml:"allow_unused_imports"`
+	} `yaml:"protoc"`
+	Lint struct {
+		Group             string                   `yaml:"group"`
+		Ignores           []externalMigrateIgnore  `yaml:"ignores"`
+		Rules             externalMigrateLintRules `yaml:"rules"`
+		FileHeader        interface{}              `yaml:"file_header"`
+		JavaPackagePrefix string                   `yaml:"java_package_prefix"`
+	} `yaml:"lint"`
+	Break struct {
+		IncludeBeta   bool `yaml:"include_beta"`
+		AllowBetaDeps bool `yaml:"allow_beta_deps"`
+	} `yaml:"break"`
+	Generate interface{} `yaml:"generate"`
+	Create   interface{} `yaml:"create"`
+}
+
+type externalProtolintConfig struct {
+	Lint struct {
+		Ignores []externalMigrateIgnore `yaml:"ignores"`
+		Files   struct {
+			Exclude []string `yaml:"exclude"`
+		} `yaml:"files"`
+		Directories struct {
+			Exclude []string `yaml:"exclude"`
+		} `yaml:"directories"`
+		Rules       externalMigrateLintRules          `yaml:"rules"`
+		RulesOption map[string]map[string]interface{} `yaml:"rules_option"`
+	} `yaml:"lint"`
+}
+
+type externalMigrateIgnore struct {
+	ID    string   `yaml:"id"`
+	Files []string `yaml:"files"`
+}
+
+type externalMigrateLintRules struct {
+	NoDefault  bool     `yaml:"no_default"`
+	AllDefault bool     `yaml:"all_default"`
+	Add        []string `yaml:"add"`
+	Remove     []string `yaml:"remove"`
+}
+
+type migrator struct {
+	externalConfig ExternalConfig
+	notes          []string
+}
+
+func (m *migrator) migratePrototool(data []byte) error {
+	externalPrototoolConfig := &externalPrototoolConfig{}
+	// JSON is valid YAML
+	if err := yaml.Unmarshal(data, externalPrototoolConfig); err != nil {
+		return err
+	}
+	m.externalConfig.Build.Excludes = externalPrototoolConfig.Excludes
+	if externalPrototoolConfig.Protoc.Version != "" {
+		m.addNote("protoc.version", "is not needed, as buf has its own compiler")
+	}
+	if len(externalPrototoolConfig.Protoc.Includes) > 0 {
+		m.addNote("protoc.includes", "cannot be migrated, add the include directories to build.roots if they are within the input, or use build.deps or --dep-image")
+	}
+	if externalPrototoolConfig.Protoc.AllowUnusedImports {
+		m.addNote("protoc.allow_unused_imports", "cannot be migrated, as buf does not fail on unused imports")
+	}
+
+	lint := externalPrototoolConfig.Lint
+	category := ""
+	if !lint.Rules.NoDefault {
+		group := lint.Group
+		if group == "" {
+			group = "uber1"
+		}
+		var ok bool
+		category, ok = prototoolGroupToLintCategory[group]
+		if !ok {
+			return fmt.Errorf("unknown lint group %q", group)
+		}
+		m.addNote("lint.group", fmt.Sprintf("the %s rules are migrated to the %s category, which is similar but not identical", group, category))
+	}
+	if err := m.migrateLintRules("lint.rules", category, lint.Rules); err != nil {
+		return err
+	}
+	m.migrateLintIgnores("lint.ignores", lint.Ignores)
+	if lint.FileHeader != nil {
+		m.addNote("lint.file_header", "cannot be migrated, set lint.license_header to the header and use FILE_LICENSE_HEADER")
+	}
+	if lint.JavaPackagePrefix != "" {
+		m.addNote("lint.java_package_prefix", "cannot be migrated, as there is no equivalent checker")
+	}
+	if externalPrototoolConfig.Break.IncludeBeta {
+		m.addNote("break.include_beta", "cannot be migrated, as breaking checks always include beta packages")
+	}
+	if externalPrototoolConfig.Break.AllowBetaDeps {
+		m.addNote("break.allow_beta_deps", "cannot be migrated, as there is no equivalent setting")
+	}
+	if externalPrototoolConfig.Generate != nil {
+		m.addNote("generate", fmt.Sprintf("cannot be migrated, use buf generate with %s", bufgen.TemplateFilePath))
+	}
+	if externalPrototoolConfig.Create != nil {
+		m.addNote("create", "cannot be migrated, as there is no equivalent setting")
+	}
+	return nil
+}
+
+func (m *migrator) migrateProtolint(data []byte) error {
+	externalProtolintConfig := &externalProtolintConfig{}
+	if err := yaml.Unmarshal(data, externalProtolintConfig); err != nil {
+		return err
+	}
+	lint := externalProtolintConfig.Lint
+	m.externalConfig.Build.Excludes = lint.Directories.Exclude
+	m.externalConfig.Lint.Ignore = lint.Files.Exclude
+	category := ""
+	switch {
+	case lint.Rules.AllDefault:
+		category = "DEFAULT"
+		m.addNote("lint.rules.all_default", "all rules are migrated to the DEFAULT category, add categories to lint.use for more checkers")
+	case !lint.Rules.NoDefault:
+		category = "DEFAULT"
+		m.addNote("lint.rules", "the default rules are migrated to the DEFAULT category, which is similar but not identical")
+	}
+	if err := m.migrateLintRules("lint.rules", category, lint.Rules); err != nil {
+		return err
+	}
+	m.migrateLintIgnores("lint.ignores", lint.Ignores)
+	rules := make([]string, 0, len(lint.RulesOption))
+	for rule := range lint.RulesOption {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+	for _, rule := range rules {
+		options := lint.RulesOption[rule]
+		setting := "lint.rules_option." + rule
+		switch rule {
+		case "max_line_length":
+			if maxChars, ok := options["max_chars"].(int); ok {
+				m.externalConfig.Lint.MaxLineLength = maxChars
+			}
+			if _, ok := options["tab_chars"]; ok {
+				m.addNote(setting+".tab_chars", "cannot be migrated, as there is no equivalent setting")
+			}
+		case "service_names_end_with":
+			if text, ok := options["text"].(string); ok {
+				m.externalConfig.Lint.ServiceSuffix = text
+			}
+		case "enum_field_names_zero_value_end_with":
+			if suffix, ok := options["suffix"].(string); ok {
+				if !strings.HasPrefix(suffix, "_") {
+					suffix = "_" + suffix
+				}
+				m.externalConfig.Lint.EnumZeroValueSuffix = suffix
+			}
+		default:
+			m.addNote(setting, "cannot be migrated, as there is no equivalent setting")
+		}
+	}
+	return nil
+}
+
+// migrateLintRules sets lint.use to the category, if any, and the checkers
+// of the added rules that are not in the category, and sets lint.except to the
+// checkers of the removed rules.
+func (m *migrator) migrateLintRules(setting string, category string, rules externalMigrateLintRules) error {
+	categoryIDs := make(map[string]struct{})
+	if category != "" {
+		checkers, err := buflint.GetAllCheckers(category)
+		if err != nil {
+			return err
+		}
+		for _, checker := range checkers {
+			categoryIDs[checker.ID()] = struct{}{}
+		}
+		m.externalConfig.Lint.Use = append(m.externalConfig.Lint.Use, category)
+	}
+	useIDs := make(map[string]struct{})
+	for _, id := range m.getLintIDs(setting+".add", rules.Add) {
+		if _, ok := categoryIDs[id]; ok {
+			continue
+		}
+		if _, ok := useIDs[id]; ok {
+			continue
+		}
+		useIDs[id] = struct{}{}
+		m.externalConfig.Lint.Use = append(m.externalConfig.Lint.Use, id)
+	}
+	exceptIDs := make(map[string]struct{})
+	for _, id := range m.getLintIDs(setting+".remove", rules.Remove) {
+		_, inCategory := categoryIDs[id]
+		_, inUse := useIDs[id]
+		if !inCategory && !inUse {
+			continue
+		}
+		if _, ok := exceptIDs[id]; ok {
+			continue
+		}
+		exceptIDs[id] = struct{}{}
+		m.externalConfig.Lint.Except = append(m.externalConfig.Lint.Except, id)
+	}
+	if len(m.externalConfig.Lint.Use) == 0 {
+		// an empty use is the default categories, which is not what was configured
+		m.addNote(setting, "no rules are enabled, so lint.use is empty and the default categories are used")
+	}
+	return nil
+}
+
+// migrateLintIgnores sets lint.ignore_only to the files ignored for the
+// checkers of each rule.
+func (m *migrator) migrateLintIgnores(setting string, ignores []externalMigrateIgnore) {
+	for _, ignore := range ignores {
+		for _, id := range m.getLintIDs(setting, []string{ignore.ID}) {
+			if m.externalConfig.Lint.IgnoreOnly == nil {
+				m.externalConfig.Lint.IgnoreOnly = make(map[string][]string)
+			}
+			m.externalConfig.Lint.IgnoreOnly[id] = append(m.externalConfig.Lint.IgnoreOnly[id], ignore.Files...)
+		}
+	}
+}
+
+// getLintIDs returns the checker IDs for the rules, and adds a note for each
+// rule without checkers.
+func (m *migrator) getLintIDs(setting string, rules []string) []string {
+	var ids []string
+	for _, rule := range rules {
+		ruleIDs, ok := ruleToLintIDs[strings.ToUpper(rule)]
+		if !ok {
+			m.addNote(setting, fmt.Sprintf("%s cannot be migrated, as there is no equivalent checker", rule))
+			continue
+		}
+		ids = append(ids, ruleIDs...)
+	}
+	return ids
+}
+
+func (m *migrator) addNote(setting string, message string) {
+	m.notes = append(m.notes, setting+": "+message)
+}
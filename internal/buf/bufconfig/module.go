@@ -0,0 +1,24 @@
+package bufconfig
+
+// NewModuleConfigData returns the YAML data of the config to push with a module.
+//
+// Only the lint and breaking sections are included, as the build, inputs, and
+// tests sections only apply to the repository the module was built from. The
+// lint section includes the values of the extended config, if any, and does not
+// include the baseline, as the baseline file is not part of the module.
+func NewModuleConfigData(config *Config) ([]byte, error) {
+	externalLintConfig := config.externalLintConfig
+	if config.extendedExternalLintConfig != nil {
+		externalLintConfig = mergeExtendedExternalLintConfig(*config.extendedExternalLintConfig, config.externalLintConfig)
+	}
+	externalLintConfig.Extends = ""
+	externalLintConfig.Baseline = ""
+	moduleConfig := struct {
+		Lint     ExternalLintConfig     `yaml:"lint,omitempty"`
+		Breaking ExternalBreakingConfig `yaml:"breaking,omitempty"`
+	}{
+		Lint:     externalLintConfig,
+		Breaking: config.externalBreakingConfig,
+	}
+	return marshalConfigYAML(moduleConfig)
+}
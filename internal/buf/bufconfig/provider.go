@@ -2,8 +2,12 @@ package bufconfig
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"strings"
 
+	"github.com/bufbuild/buf/internal/buf/bufassert"
 	"github.com/bufbuild/buf/internal/buf/bufcheck/bufbreaking"
 	"github.com/bufbuild/buf/internal/buf/bufcheck/buflint"
 	"github.com/bufbuild/buf/internal/pkg/storage"
@@ -73,21 +77,34 @@ func (p *provider) newConfig(externalConfig *ExternalConfig) (*Config, error) {
 		Except:                        externalConfig.Breaking.Except,
 		IgnoreRootPaths:               externalConfig.Breaking.Ignore,
 		IgnoreIDOrCategoryToRootPaths: externalConfig.Breaking.IgnoreOnly,
+		CustomOptions:                 externalConfig.Breaking.CustomOptions,
+		EnumValueDeleteGraceReleases:  externalConfig.Breaking.EnumValueDeleteGraceReleases,
 	}.NewConfig()
 	if err != nil {
 		return nil, err
 	}
-	lintConfig, err := buflint.ConfigBuilder{
-		Use:                                  externalConfig.Lint.Use,
-		Except:                               externalConfig.Lint.Except,
-		IgnoreRootPaths:                      externalConfig.Lint.Ignore,
-		IgnoreIDOrCategoryToRootPaths:        externalConfig.Lint.IgnoreOnly,
-		EnumZeroValueSuffix:                  externalConfig.Lint.EnumZeroValueSuffix,
-		RPCAllowSameRequestResponse:          externalConfig.Lint.RPCAllowSameRequestResponse,
-		RPCAllowGoogleProtobufEmptyRequests:  externalConfig.Lint.RPCAllowGoogleProtobufEmptyRequests,
-		RPCAllowGoogleProtobufEmptyResponses: externalConfig.Lint.RPCAllowGoogleProtobufEmptyResponses,
-		ServiceSuffix:                        externalConfig.Lint.ServiceSuffix,
-	}.NewConfig()
+	lintConfig, err := newLintConfig(externalConfig.Lint)
+	if err != nil {
+		return nil, err
+	}
+	if externalConfig.Lint.Extends != "" {
+		// the extended config is fetched by the caller
+		if _, _, err := ParseLintExtends(externalConfig.Lint.Extends); err != nil {
+			return nil, err
+		}
+	}
+	for alias, input := range externalConfig.Inputs {
+		if alias == "" {
+			return nil, errors.New("inputs: alias must not be empty")
+		}
+		if input == "" {
+			return nil, fmt.Errorf("inputs: input for alias %q must not be empty", alias)
+		}
+		if strings.HasPrefix(input, InputAliasPrefix) {
+			return nil, fmt.Errorf("inputs: input for alias %q must not be another alias", alias)
+		}
+	}
+	tests, err := bufassert.NewAssertions(externalConfig.Tests)
 	if err != nil {
 		return nil, err
 	}
@@ -95,5 +112,159 @@ func (p *provider) newConfig(externalConfig *ExternalConfig) (*Config, error) {
 		Build:    externalConfig.Build,
 		Breaking: breakingConfig,
 		Lint:     lintConfig,
+		Inputs:   externalConfig.Inputs,
+		Tests:    tests,
+
+		LintExtends:            externalConfig.Lint.Extends,
+		externalLintConfig:     externalConfig.Lint,
+		externalBreakingConfig: externalConfig.Breaking,
+		externalTests:          externalConfig.Tests,
 	}, nil
 }
+
+func newLintConfig(externalLintConfig ExternalLintConfig) (*buflint.Config, error) {
+	lintConfig, err := buflint.ConfigBuilder{
+		Use:                                  externalLintConfig.Use,
+		Except:                               externalLintConfig.Except,
+		IgnoreRootPaths:                      externalLintConfig.Ignore,
+		IgnoreIDOrCategoryToRootPaths:        externalLintConfig.IgnoreOnly,
+		EnumZeroValueSuffix:                  externalLintConfig.EnumZeroValueSuffix,
+		RPCAllowSameRequestResponse:          externalLintConfig.RPCAllowSameRequestResponse,
+		RPCAllowGoogleProtobufEmptyRequests:  externalLintConfig.RPCAllowGoogleProtobufEmptyRequests,
+		RPCAllowGoogleProtobufEmptyResponses: externalLintConfig.RPCAllowGoogleProtobufEmptyResponses,
+		ServiceSuffix:                        externalLintConfig.ServiceSuffix,
+		NamingExceptions:                     externalLintConfig.NamingExceptions,
+		MessageDuplicateSimilarityThreshold:  externalLintConfig.MessageDuplicateSimilarityThreshold,
+		PackageVersionSuffixPattern:          externalLintConfig.PackageVersionSuffixPattern,
+		RestrictedImports:                    externalLintConfig.RestrictedImports,
+		RPCRestrictedClientStreamingPatterns: externalLintConfig.RPCRestrictedClientStreamingPatterns,
+		MaxFileLines:                         externalLintConfig.MaxFileLines,
+		MaxLineLength:                        externalLintConfig.MaxLineLength,
+		FieldOrderedRequiredFirst:            externalLintConfig.FieldOrderedRequiredFirst,
+		LicenseHeader:                        externalLintConfig.LicenseHeader,
+		CommentTODOAllowedPatterns:           externalLintConfig.CommentTODOAllowedPatterns,
+		AllowCommentIgnores:                  externalLintConfig.AllowCommentIgnores,
+		FailOn:                               externalLintConfig.FailurePolicy.FailOn,
+		Warn:                                 externalLintConfig.FailurePolicy.Warn,
+		FailureThresholds:                    externalLintConfig.FailurePolicy.Thresholds,
+		Baseline:                             externalLintConfig.Baseline,
+	}.NewConfig()
+	if err != nil {
+		return nil, err
+	}
+	seenRootPaths := make(map[string]struct{}, len(externalLintConfig.Overrides))
+	for _, externalLintOverrideConfig := range externalLintConfig.Overrides {
+		if len(externalLintOverrideConfig.Overrides) > 0 {
+			return nil, fmt.Errorf("lint override for %q cannot contain overrides", externalLintOverrideConfig.Path)
+		}
+		if !externalLintOverrideConfig.FailurePolicy.isEmpty() {
+			return nil, fmt.Errorf("lint override for %q cannot contain a failure_policy", externalLintOverrideConfig.Path)
+		}
+		if externalLintOverrideConfig.Baseline != "" {
+			return nil, fmt.Errorf("lint override for %q cannot contain a baseline", externalLintOverrideConfig.Path)
+		}
+		if externalLintOverrideConfig.Extends != "" {
+			return nil, fmt.Errorf("lint override for %q cannot contain extends", externalLintOverrideConfig.Path)
+		}
+		overrideConfig, err := newLintConfig(
+			mergeExternalLintConfigs(externalLintConfig, externalLintOverrideConfig.ExternalLintConfig),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("lint override for %q: %v", externalLintOverrideConfig.Path, err)
+		}
+		configOverride, err := buflint.NewConfigOverride(externalLintOverrideConfig.Path, overrideConfig)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := seenRootPaths[configOverride.RootPath]; ok {
+			return nil, fmt.Errorf("duplicate lint override for %q", configOverride.RootPath)
+		}
+		seenRootPaths[configOverride.RootPath] = struct{}{}
+		lintConfig.Overrides = append(lintConfig.Overrides, configOverride)
+	}
+	return lintConfig, nil
+}
+
+// mergeExtendedExternalLintConfig merges the extended lint config beneath the lint config.
+//
+// Overrides and the baseline are only taken from the lint config.
+func mergeExtendedExternalLintConfig(extended ExternalLintConfig, lint ExternalLintConfig) ExternalLintConfig {
+	merged := mergeExternalLintConfigs(extended, lint)
+	merged.Overrides = lint.Overrides
+	merged.Baseline = lint.Baseline
+	merged.Extends = lint.Extends
+	if !lint.FailurePolicy.isEmpty() {
+		merged.FailurePolicy = lint.FailurePolicy
+	}
+	return merged
+}
+
+// mergeExternalLintConfigs merges the override into the base.
+//
+// Any value set on the override replaces the value on the base.
+// Overrides on the base are not inherited.
+func mergeExternalLintConfigs(base ExternalLintConfig, override ExternalLintConfig) ExternalLintConfig {
+	merged := base
+	merged.Overrides = nil
+	if len(override.Use) > 0 {
+		merged.Use = override.Use
+	}
+	if len(override.Except) > 0 {
+		merged.Except = override.Except
+	}
+	if len(override.Ignore) > 0 {
+		merged.Ignore = override.Ignore
+	}
+	if len(override.IgnoreOnly) > 0 {
+		merged.IgnoreOnly = override.IgnoreOnly
+	}
+	if override.EnumZeroValueSuffix != "" {
+		merged.EnumZeroValueSuffix = override.EnumZeroValueSuffix
+	}
+	if override.RPCAllowSameRequestResponse {
+		merged.RPCAllowSameRequestResponse = true
+	}
+	if override.RPCAllowGoogleProtobufEmptyRequests {
+		merged.RPCAllowGoogleProtobufEmptyRequests = true
+	}
+	if override.RPCAllowGoogleProtobufEmptyResponses {
+		merged.RPCAllowGoogleProtobufEmptyResponses = true
+	}
+	if override.ServiceSuffix != "" {
+		merged.ServiceSuffix = override.ServiceSuffix
+	}
+	if len(override.NamingExceptions) > 0 {
+		merged.NamingExceptions = override.NamingExceptions
+	}
+	if override.MessageDuplicateSimilarityThreshold != 0 {
+		merged.MessageDuplicateSimilarityThreshold = override.MessageDuplicateSimilarityThreshold
+	}
+	if override.PackageVersionSuffixPattern != "" {
+		merged.PackageVersionSuffixPattern = override.PackageVersionSuffixPattern
+	}
+	if len(override.RestrictedImports) > 0 {
+		merged.RestrictedImports = override.RestrictedImports
+	}
+	if len(override.RPCRestrictedClientStreamingPatterns) > 0 {
+		merged.RPCRestrictedClientStreamingPatterns = override.RPCRestrictedClientStreamingPatterns
+	}
+	if override.MaxFileLines != 0 {
+		merged.MaxFileLines = override.MaxFileLines
+	}
+	if override.MaxLineLength != 0 {
+		merged.MaxLineLength = override.MaxLineLength
+	}
+	if override.FieldOrderedRequiredFirst {
+		merged.FieldOrderedRequiredFirst = true
+	}
+	if override.LicenseHeader != "" {
+		merged.LicenseHeader = override.LicenseHeader
+	}
+	if len(override.CommentTODOAllowedPatterns) > 0 {
+		merged.CommentTODOAllowedPatterns = override.CommentTODOAllowedPatterns
+	}
+	if override.AllowCommentIgnores {
+		merged.AllowCommentIgnores = true
+	}
+	return merged
+}
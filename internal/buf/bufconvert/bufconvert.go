@@ -0,0 +1,242 @@
+// Package bufconvert converts messages between the binary, JSON, and text
+// formats using the descriptors of images.
+package bufconvert
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/buf/ext/extimage"
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+const (
+	// MessageEncodingBin is the binary encoding.
+	MessageEncodingBin MessageEncoding = 1
+	// MessageEncodingJSON is the JSON encoding.
+	MessageEncodingJSON MessageEncoding = 2
+	// MessageEncodingText is the text encoding.
+	MessageEncodingText MessageEncoding = 3
+)
+
+var (
+	messageEncodingToString = map[MessageEncoding]string{
+		MessageEncodingBin:  "bin",
+		MessageEncodingJSON: "json",
+		MessageEncodingText: "txt",
+	}
+	stringToMessageEncoding = map[string]MessageEncoding{
+		"bin":  MessageEncodingBin,
+		"json": MessageEncodingJSON,
+		"txt":  MessageEncodingText,
+	}
+)
+
+// MessageEncoding is the encoding of a message.
+type MessageEncoding int
+
+// String implements fmt.Stringer.
+func (m MessageEncoding) String() string {
+	s, ok := messageEncodingToString[m]
+	if !ok {
+		return fmt.Sprintf("%d", m)
+	}
+	return s
+}
+
+// ParseMessageEncoding parses the MessageEncoding.
+func ParseMessageEncoding(s string) (MessageEncoding, error) {
+	messageEncoding, ok := stringToMessageEncoding[strings.ToLower(strings.TrimSpace(s))]
+	if !ok {
+		return 0, fmt.Errorf("unknown message encoding %q, must be one of %s", s, MessageEncodingsToString())
+	}
+	return messageEncoding, nil
+}
+
+// MessageEncodingsToString returns the message encodings as a string.
+func MessageEncodingsToString() string {
+	var strs []string
+	for s := range stringToMessageEncoding {
+		strs = append(strs, s)
+	}
+	sort.Strings(strs)
+	return "[" + strings.Join(strs, ",") + "]"
+}
+
+// MessageRef is a reference to a file containing a single message.
+type MessageRef struct {
+	// Path is the path of the file, or "-" for stdin or stdout.
+	Path string
+	// Encoding is the encoding of the message.
+	Encoding MessageEncoding
+}
+
+// ParseMessageRef parses a MessageRef of the form path#format=encoding.
+//
+// If the format is not specified, the encoding is derived from the file
+// extension, where .json is JSON and .txt is text. Otherwise, the encoding
+// is binary. The flag name is used for errors.
+func ParseMessageRef(valueFlagName string, value string) (*MessageRef, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, fmt.Errorf("%s is required", valueFlagName)
+	}
+	path := value
+	var options string
+	switch splitValue := strings.Split(value, "#"); len(splitValue) {
+	case 1:
+	case 2:
+		path = strings.TrimSpace(splitValue[0])
+		options = strings.TrimSpace(splitValue[1])
+		if path == "" {
+			return nil, fmt.Errorf("%s: %q starts with # which is invalid", valueFlagName, value)
+		}
+		if options == "" {
+			return nil, fmt.Errorf("%s: %q ends with # which is invalid", valueFlagName, value)
+		}
+	default:
+		return nil, fmt.Errorf("%s: %q has multiple #s which is invalid", valueFlagName, value)
+	}
+	messageRef := &MessageRef{
+		Path: path,
+	}
+	if options != "" {
+		for _, pair := range strings.Split(options, ",") {
+			split := strings.Split(pair, "=")
+			if len(split) != 2 {
+				return nil, fmt.Errorf("%s: invalid options: %q", valueFlagName, options)
+			}
+			key := strings.TrimSpace(split[0])
+			value := strings.TrimSpace(split[1])
+			if key == "" || value == "" {
+				return nil, fmt.Errorf("%s: invalid options: %q", valueFlagName, options)
+			}
+			if key != "format" {
+				return nil, fmt.Errorf("%s: invalid options key: %q", valueFlagName, key)
+			}
+			encoding, err := ParseMessageEncoding(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", valueFlagName, err)
+			}
+			messageRef.Encoding = encoding
+		}
+	}
+	if messageRef.Encoding == 0 {
+		switch filepath.Ext(path) {
+		case ".json":
+			messageRef.Encoding = MessageEncodingJSON
+		case ".txt":
+			messageRef.Encoding = MessageEncodingText
+		default:
+			messageRef.Encoding = MessageEncodingBin
+		}
+	}
+	return messageRef, nil
+}
+
+// Converter converts messages between encodings.
+type Converter interface {
+	// Convert converts the message of the type from one encoding to another.
+	//
+	// The type name is the fully-qualified name of the message, with or
+	// without a leading period.
+	Convert(typeName string, data []byte, from MessageEncoding, to MessageEncoding) ([]byte, error)
+}
+
+// NewConverter returns a new Converter for the messages of the Image.
+//
+// The Image must include imports.
+func NewConverter(image *imagev1beta1.Image) (Converter, error) {
+	return newConverter(image)
+}
+
+type converter struct {
+	files       []*desc.FileDescriptor
+	anyResolver jsonpb.AnyResolver
+}
+
+func newConverter(image *imagev1beta1.Image) (*converter, error) {
+	fileDescriptorSet, err := extimage.ImageToFileDescriptorSet(image)
+	if err != nil {
+		return nil, err
+	}
+	nameToFile, err := desc.CreateFileDescriptorsFromSet(fileDescriptorSet)
+	if err != nil {
+		return nil, fmt.Errorf("could not link image: %v", err)
+	}
+	files := make([]*desc.FileDescriptor, 0, len(nameToFile))
+	for _, file := range nameToFile {
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i int, j int) bool {
+		return files[i].GetName() < files[j].GetName()
+	})
+	return &converter{
+		files:       files,
+		anyResolver: dynamic.AnyResolver(nil, files...),
+	}, nil
+}
+
+func (c *converter) Convert(typeName string, data []byte, from MessageEncoding, to MessageEncoding) ([]byte, error) {
+	messageDescriptor, err := c.getMessageDescriptor(typeName)
+	if err != nil {
+		return nil, err
+	}
+	message := dynamic.NewMessage(messageDescriptor)
+	switch from {
+	case MessageEncodingBin:
+		err = message.Unmarshal(data)
+	case MessageEncodingJSON:
+		err = message.UnmarshalJSONPB(&jsonpb.Unmarshaler{AnyResolver: c.anyResolver}, data)
+	case MessageEncodingText:
+		err = message.UnmarshalText(data)
+	default:
+		return nil, fmt.Errorf("unknown message encoding: %v", from)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal %s as %s: %v", messageDescriptor.GetFullyQualifiedName(), from.String(), err)
+	}
+	var output []byte
+	switch to {
+	case MessageEncodingBin:
+		// deterministic so that the same input always converts to the same bytes
+		output, err = message.MarshalDeterministic()
+	case MessageEncodingJSON:
+		output, err = message.MarshalJSONPB(&jsonpb.Marshaler{AnyResolver: c.anyResolver, Indent: "  "})
+	case MessageEncodingText:
+		output, err = message.MarshalTextIndent()
+	default:
+		return nil, fmt.Errorf("unknown message encoding: %v", to)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal %s as %s: %v", messageDescriptor.GetFullyQualifiedName(), to.String(), err)
+	}
+	if to != MessageEncodingBin && len(output) > 0 && !bytes.HasSuffix(output, []byte("\n")) {
+		output = append(output, '\n')
+	}
+	return output, nil
+}
+
+func (c *converter) getMessageDescriptor(typeName string) (*desc.MessageDescriptor, error) {
+	typeName = strings.TrimPrefix(strings.TrimSpace(typeName), ".")
+	if typeName == "" {
+		return nil, errors.New("type name is empty")
+	}
+	for _, file := range c.files {
+		switch descriptor := file.FindSymbol(typeName).(type) {
+		case nil:
+		case *desc.MessageDescriptor:
+			return descriptor, nil
+		default:
+			return nil, fmt.Errorf("%s is not a message", typeName)
+		}
+	}
+	return nil, fmt.Errorf("message %s not found", typeName)
+}
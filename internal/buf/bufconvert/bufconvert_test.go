@@ -0,0 +1,126 @@
+package bufconvert
+
+import (
+	"testing"
+
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMessageRef(t *testing.T) {
+	t.Parallel()
+	for value, expected := range map[string]*MessageRef{
+		"-":                   {Path: "-", Encoding: MessageEncodingBin},
+		"-#format=json":       {Path: "-", Encoding: MessageEncodingJSON},
+		"foo.bin":             {Path: "foo.bin", Encoding: MessageEncodingBin},
+		"foo.json":            {Path: "foo.json", Encoding: MessageEncodingJSON},
+		"foo.txt":             {Path: "foo.txt", Encoding: MessageEncodingText},
+		"foo.json#format=txt": {Path: "foo.json", Encoding: MessageEncodingText},
+	} {
+		messageRef, err := ParseMessageRef("to", value)
+		require.NoError(t, err, value)
+		assert.Equal(t, expected, messageRef, value)
+	}
+	for _, value := range []string{
+		"",
+		"#format=json",
+		"-#",
+		"-#format=json#",
+		"-#format",
+		"-#format=yaml",
+		"-#foo=bar",
+	} {
+		_, err := ParseMessageRef("to", value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	t.Parallel()
+	converter, err := NewConverter(
+		&imagev1beta1.Image{
+			File: []*descriptor.FileDescriptorProto{
+				{
+					Name:    proto.String("foo/v1/foo.proto"),
+					Package: proto.String("foo.v1"),
+					Syntax:  proto.String("proto3"),
+					MessageType: []*descriptor.DescriptorProto{
+						{
+							Name: proto.String("Foo"),
+							Field: []*descriptor.FieldDescriptorProto{
+								{
+									Name:     proto.String("name"),
+									Number:   proto.Int32(1),
+									Label:    descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+									Type:     descriptor.FieldDescriptorProto_TYPE_STRING.Enum(),
+									JsonName: proto.String("name"),
+								},
+								{
+									Name:     proto.String("values"),
+									Number:   proto.Int32(2),
+									Label:    descriptor.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+									Type:     descriptor.FieldDescriptorProto_TYPE_INT64.Enum(),
+									JsonName: proto.String("values"),
+								},
+							},
+						},
+					},
+					EnumType: []*descriptor.EnumDescriptorProto{
+						{
+							Name: proto.String("Kind"),
+							Value: []*descriptor.EnumValueDescriptorProto{
+								{
+									Name:   proto.String("KIND_UNSPECIFIED"),
+									Number: proto.Int32(0),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	binData := []byte{0x0a, 0x03, 'f', 'o', 'o', 0x12, 0x02, 0x01, 0x02}
+	jsonData := []byte(`{
+  "name": "foo",
+  "values": [
+    "1",
+    "2"
+  ]
+}
+`)
+	textData := []byte(`name: "foo"
+values: 1
+values: 2
+`)
+	for _, from := range []MessageEncoding{MessageEncodingBin, MessageEncodingJSON, MessageEncodingText} {
+		for to, expected := range map[MessageEncoding][]byte{
+			MessageEncodingBin:  binData,
+			MessageEncodingJSON: jsonData,
+			MessageEncodingText: textData,
+		} {
+			var data []byte
+			switch from {
+			case MessageEncodingBin:
+				data = binData
+			case MessageEncodingJSON:
+				data = jsonData
+			case MessageEncodingText:
+				data = textData
+			}
+			output, err := converter.Convert(".foo.v1.Foo", data, from, to)
+			require.NoError(t, err, "%v to %v", from, to)
+			assert.Equal(t, string(expected), string(output), "%v to %v", from, to)
+		}
+	}
+	_, err = converter.Convert("foo.v1.Bar", binData, MessageEncodingBin, MessageEncodingJSON)
+	assert.Error(t, err)
+	_, err = converter.Convert("foo.v1.Kind", binData, MessageEncodingBin, MessageEncodingJSON)
+	assert.Error(t, err)
+	_, err = converter.Convert("foo.v1.Foo", []byte(`{"foo": 1}`), MessageEncodingJSON, MessageEncodingBin)
+	assert.Error(t, err)
+}
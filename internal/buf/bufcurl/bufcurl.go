@@ -0,0 +1,86 @@
+// Package bufcurl invokes gRPC methods using the descriptors of images, so
+// that servers do not need to support server reflection.
+package bufcurl
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"go.uber.org/zap"
+)
+
+// Invoker invokes gRPC methods.
+type Invoker interface {
+	// Invoke invokes the method at the address with the JSON request data,
+	// and writes each response message as JSON to the writer.
+	//
+	// The address is host:port. The method is of the form
+	// foo.v1.FooService/GetFoo or foo.v1.FooService.GetFoo.
+	//
+	// The request data is a JSON message, or a stream of JSON messages for
+	// client streaming methods. Empty request data is an empty message.
+	//
+	// Returns error if the call fails or does not have an OK status.
+	Invoke(
+		ctx context.Context,
+		address string,
+		method string,
+		requestData []byte,
+		writer io.Writer,
+	) error
+}
+
+// NewInvoker returns a new Invoker for the methods of the Image.
+//
+// The Image must include imports.
+func NewInvoker(
+	logger *zap.Logger,
+	image *imagev1beta1.Image,
+	options ...InvokerOption,
+) (Invoker, error) {
+	return newInvoker(
+		logger,
+		image,
+		options...,
+	)
+}
+
+// InvokerOption is an option for a new Invoker.
+type InvokerOption func(*invoker)
+
+// InvokerWithPlaintext returns a new InvokerOption that uses HTTP/2 without
+// TLS, also known as h2c.
+//
+// The default is to use TLS.
+func InvokerWithPlaintext() InvokerOption {
+	return func(invoker *invoker) {
+		invoker.plaintext = true
+	}
+}
+
+// InvokerWithTLSConfig returns a new InvokerOption that uses the TLS config.
+//
+// The default is the default TLS config, which verifies the server with the
+// system roots. This is ignored if InvokerWithPlaintext is set.
+func InvokerWithTLSConfig(tlsConfig *tls.Config) InvokerOption {
+	return func(invoker *invoker) {
+		invoker.tlsConfig = tlsConfig
+	}
+}
+
+// InvokerWithHeader returns a new InvokerOption that adds the header to
+// every call.
+//
+// Headers that gRPC uses, such as content-type, cannot be overridden.
+func InvokerWithHeader(header http.Header) InvokerOption {
+	return func(invoker *invoker) {
+		for key, values := range header {
+			for _, value := range values {
+				invoker.header.Add(key, value)
+			}
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package bufcurl
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func TestInvoke(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(testHandler), &http2.Server{}))
+	defer server.Close()
+	address := strings.TrimPrefix(server.URL, "http://")
+	invoker, err := NewInvoker(
+		zap.NewNop(),
+		testImage,
+		InvokerWithPlaintext(),
+		InvokerWithHeader(http.Header{"X-Name": []string{"header"}}),
+	)
+	require.NoError(t, err)
+
+	testInvoke(t, invoker, address, "foo.v1.FooService/GetFoo", `{"name": "bar"}`, `{
+  "name": "bar"
+}
+`)
+	testInvoke(t, invoker, address, ".foo.v1.FooService.GetFoo", ``, `{
+  "name": "header"
+}
+`)
+	testInvoke(t, invoker, address, "foo.v1.FooService/ListFoos", `{"name": "bar"}`, `{
+  "name": "bar"
+}
+{
+  "name": "bar"
+}
+`)
+
+	buffer := bytes.NewBuffer(nil)
+	err = invoker.Invoke(context.Background(), address, "foo.v1.FooService/GetFoo", []byte(`{"name": "error"}`), buffer)
+	require.Error(t, err)
+	assert.Equal(t, "gRPC status NotFound: foo error not found", err.Error())
+	for _, method := range []string{
+		"foo.v1.FooService",
+		"foo.v1.FooService/Other",
+		"foo.v1.BarService/GetFoo",
+		"foo.v1.Foo/GetFoo",
+	} {
+		assert.Error(t, invoker.Invoke(context.Background(), address, method, nil, buffer), method)
+	}
+	// GetFoo is not client streaming
+	assert.Error(t, invoker.Invoke(context.Background(), address, "foo.v1.FooService/GetFoo", []byte(`{} {}`), buffer))
+	assert.Error(t, invoker.Invoke(context.Background(), address, "foo.v1.FooService/GetFoo", []byte(`{"other": 1}`), buffer))
+}
+
+func testInvoke(t *testing.T, invoker Invoker, address string, method string, request string, expected string) {
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, invoker.Invoke(context.Background(), address, method, []byte(request), buffer))
+	assert.Equal(t, expected, buffer.String())
+}
+
+// testHandler echos the request, or the x-name header if the request name is
+// empty, once for GetFoo and twice for ListFoos.
+func testHandler(responseWriter http.ResponseWriter, request *http.Request) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(request.Body, prefix[:]); err != nil {
+		http.Error(responseWriter, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data := make([]byte, binary.BigEndian.Uint32(prefix[1:]))
+	if _, err := io.ReadFull(request.Body, data); err != nil {
+		http.Error(responseWriter, err.Error(), http.StatusBadRequest)
+		return
+	}
+	foo := &descriptor.FileDescriptorProto{}
+	// FileDescriptorProto has a string name with field number 1, like foo.v1.Foo
+	if err := proto.Unmarshal(data, foo); err != nil {
+		http.Error(responseWriter, err.Error(), http.StatusBadRequest)
+		return
+	}
+	responseWriter.Header().Set("Content-Type", "application/grpc")
+	responseWriter.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	if foo.GetName() == "error" {
+		responseWriter.Header().Set("Grpc-Status", "5")
+		responseWriter.Header().Set("Grpc-Message", "foo%20error%20not%20found")
+		return
+	}
+	if foo.GetName() == "" {
+		foo.Name = proto.String(request.Header.Get("X-Name"))
+	}
+	data, err := proto.Marshal(foo)
+	if err != nil {
+		http.Error(responseWriter, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	binary.BigEndian.PutUint32(prefix[1:], uint32(len(data)))
+	numResponses := 1
+	if strings.HasSuffix(request.URL.Path, "/ListFoos") {
+		numResponses = 2
+	}
+	for i := 0; i < numResponses; i++ {
+		_, _ = responseWriter.Write(prefix[:])
+		_, _ = responseWriter.Write(data)
+	}
+	responseWriter.Header().Set("Grpc-Status", "0")
+}
+
+var testImage = &imagev1beta1.Image{
+	File: []*descriptor.FileDescriptorProto{
+		{
+			Name:    proto.String("foo/v1/foo.proto"),
+			Package: proto.String("foo.v1"),
+			Syntax:  proto.String("proto3"),
+			MessageType: []*descriptor.DescriptorProto{
+				{
+					Name: proto.String("Foo"),
+					Field: []*descriptor.FieldDescriptorProto{
+						{
+							Name:     proto.String("name"),
+							Number:   proto.Int32(1),
+							Label:    descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+							Type:     descriptor.FieldDescriptorProto_TYPE_STRING.Enum(),
+							JsonName: proto.String("name"),
+						},
+					},
+				},
+			},
+			Service: []*descriptor.ServiceDescriptorProto{
+				{
+					Name: proto.String("FooService"),
+					Method: []*descriptor.MethodDescriptorProto{
+						{
+							Name:       proto.String("GetFoo"),
+							InputType:  proto.String(".foo.v1.Foo"),
+							OutputType: proto.String(".foo.v1.Foo"),
+						},
+						{
+							Name:            proto.String("ListFoos"),
+							InputType:       proto.String(".foo.v1.Foo"),
+							OutputType:      proto.String(".foo.v1.Foo"),
+							ServerStreaming: proto.Bool(true),
+						},
+					},
+				},
+			},
+		},
+	},
+}
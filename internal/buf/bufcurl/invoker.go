@@ -0,0 +1,334 @@
+package bufcurl
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/buf/ext/extimage"
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+)
+
+// maxMessageSize is the maximum size of a response message, which matches
+// the default of gRPC clients.
+const maxMessageSize = 4 * 1024 * 1024
+
+var statusCodeToString = map[int]string{
+	0:  "OK",
+	1:  "Canceled",
+	2:  "Unknown",
+	3:  "InvalidArgument",
+	4:  "DeadlineExceeded",
+	5:  "NotFound",
+	6:  "AlreadyExists",
+	7:  "PermissionDenied",
+	8:  "ResourceExhausted",
+	9:  "FailedPrecondition",
+	10: "Aborted",
+	11: "OutOfRange",
+	12: "Unimplemented",
+	13: "Internal",
+	14: "Unavailable",
+	15: "DataLoss",
+	16: "Unauthenticated",
+}
+
+type invoker struct {
+	logger      *zap.Logger
+	files       []*desc.FileDescriptor
+	anyResolver jsonpb.AnyResolver
+	plaintext   bool
+	tlsConfig   *tls.Config
+	header      http.Header
+}
+
+func newInvoker(
+	logger *zap.Logger,
+	image *imagev1beta1.Image,
+	options ...InvokerOption,
+) (*invoker, error) {
+	fileDescriptorSet, err := extimage.ImageToFileDescriptorSet(image)
+	if err != nil {
+		return nil, err
+	}
+	nameToFile, err := desc.CreateFileDescriptorsFromSet(fileDescriptorSet)
+	if err != nil {
+		return nil, fmt.Errorf("could not link image: %v", err)
+	}
+	files := make([]*desc.FileDescriptor, 0, len(nameToFile))
+	for _, file := range nameToFile {
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i int, j int) bool {
+		return files[i].GetName() < files[j].GetName()
+	})
+	invoker := &invoker{
+		logger:      logger.Named("bufcurl"),
+		files:       files,
+		anyResolver: dynamic.AnyResolver(nil, files...),
+		header:      make(http.Header),
+	}
+	for _, option := range options {
+		option(invoker)
+	}
+	return invoker, nil
+}
+
+func (i *invoker) Invoke(
+	ctx context.Context,
+	address string,
+	method string,
+	requestData []byte,
+	writer io.Writer,
+) (retErr error) {
+	if address == "" {
+		return errors.New("address is empty")
+	}
+	methodDescriptor, err := i.getMethodDescriptor(method)
+	if err != nil {
+		return err
+	}
+	requestBody, err := i.getRequestBody(methodDescriptor, requestData)
+	if err != nil {
+		return err
+	}
+	scheme := "https"
+	if i.plaintext {
+		scheme = "http"
+	}
+	requestURL := fmt.Sprintf(
+		"%s://%s/%s/%s",
+		scheme,
+		address,
+		methodDescriptor.GetService().GetFullyQualifiedName(),
+		methodDescriptor.GetName(),
+	)
+	request, err := http.NewRequestWithContext(ctx, "POST", requestURL, bytes.NewReader(requestBody))
+	if err != nil {
+		return err
+	}
+	for key, values := range i.header {
+		for _, value := range values {
+			request.Header.Add(key, value)
+		}
+	}
+	request.Header.Set("Content-Type", "application/grpc+proto")
+	request.Header.Set("Te", "trailers")
+	request.Header.Set("Grpc-Accept-Encoding", "gzip")
+	i.logger.Debug("request", zap.String("url", requestURL), zap.Int("size", len(requestBody)))
+
+	transport := i.newTransport()
+	defer transport.CloseIdleConnections()
+	response, err := transport.RoundTrip(request)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+	i.logger.Debug("response_headers", zap.Int("status_code", response.StatusCode), zap.Any("headers", response.Header))
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("got HTTP status code %d from %s", response.StatusCode, address)
+	}
+	if contentType := response.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "application/grpc") {
+		return fmt.Errorf("got content type %q from %s which is not gRPC", contentType, address)
+	}
+	// a response without messages can have the status in the headers
+	if err := getStatusError(response.Header); err != nil {
+		return err
+	}
+	compressed := response.Header.Get("Grpc-Encoding")
+	for {
+		data, err := readMessage(response.Body, compressed)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := i.writeResponse(methodDescriptor, data, writer); err != nil {
+			return err
+		}
+	}
+	i.logger.Debug("response_trailers", zap.Any("trailers", response.Trailer))
+	if response.Trailer.Get("Grpc-Status") == "" && response.Header.Get("Grpc-Status") == "" {
+		return fmt.Errorf("no gRPC status from %s", address)
+	}
+	return getStatusError(response.Trailer)
+}
+
+func (i *invoker) newTransport() *http2.Transport {
+	if i.plaintext {
+		return &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network string, address string, _ *tls.Config) (net.Conn, error) {
+				return net.Dial(network, address)
+			},
+		}
+	}
+	return &http2.Transport{
+		TLSClientConfig: i.tlsConfig,
+	}
+}
+
+func (i *invoker) getMethodDescriptor(method string) (*desc.MethodDescriptor, error) {
+	method = strings.TrimPrefix(strings.TrimSpace(method), ".")
+	var serviceName string
+	var methodName string
+	if index := strings.LastIndexAny(method, "/."); index > 0 {
+		serviceName = method[:index]
+		methodName = method[index+1:]
+	}
+	if serviceName == "" || methodName == "" {
+		return nil, fmt.Errorf("method %q must be of the form foo.v1.FooService/GetFoo", method)
+	}
+	for _, file := range i.files {
+		switch descriptor := file.FindSymbol(serviceName).(type) {
+		case nil:
+		case *desc.ServiceDescriptor:
+			methodDescriptor := descriptor.FindMethodByName(methodName)
+			if methodDescriptor == nil {
+				return nil, fmt.Errorf("method %s not found on service %s", methodName, serviceName)
+			}
+			return methodDescriptor, nil
+		default:
+			return nil, fmt.Errorf("%s is not a service", serviceName)
+		}
+	}
+	return nil, fmt.Errorf("service %s not found", serviceName)
+}
+
+// getRequestBody returns the framed request messages for the JSON request data.
+func (i *invoker) getRequestBody(methodDescriptor *desc.MethodDescriptor, requestData []byte) ([]byte, error) {
+	var jsonMessages []json.RawMessage
+	if len(bytes.TrimSpace(requestData)) == 0 {
+		jsonMessages = append(jsonMessages, json.RawMessage(`{}`))
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(requestData))
+		for {
+			var jsonMessage json.RawMessage
+			if err := decoder.Decode(&jsonMessage); err != nil {
+				if err == io.EOF {
+					break
+				}
+				return nil, fmt.Errorf("could not parse request: %v", err)
+			}
+			jsonMessages = append(jsonMessages, jsonMessage)
+		}
+	}
+	if len(jsonMessages) > 1 && !methodDescriptor.IsClientStreaming() {
+		return nil, fmt.Errorf("got %d request messages but %s is not client streaming", len(jsonMessages), methodDescriptor.GetFullyQualifiedName())
+	}
+	unmarshaler := &jsonpb.Unmarshaler{AnyResolver: i.anyResolver}
+	var buffer bytes.Buffer
+	for _, jsonMessage := range jsonMessages {
+		message := dynamic.NewMessage(methodDescriptor.GetInputType())
+		if err := message.UnmarshalJSONPB(unmarshaler, jsonMessage); err != nil {
+			return nil, fmt.Errorf("could not unmarshal request as %s: %v", methodDescriptor.GetInputType().GetFullyQualifiedName(), err)
+		}
+		data, err := message.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		var prefix [5]byte
+		binary.BigEndian.PutUint32(prefix[1:], uint32(len(data)))
+		buffer.Write(prefix[:])
+		buffer.Write(data)
+	}
+	return buffer.Bytes(), nil
+}
+
+func (i *invoker) writeResponse(methodDescriptor *desc.MethodDescriptor, data []byte, writer io.Writer) error {
+	message := dynamic.NewMessage(methodDescriptor.GetOutputType())
+	if err := message.Unmarshal(data); err != nil {
+		return fmt.Errorf("could not unmarshal response as %s: %v", methodDescriptor.GetOutputType().GetFullyQualifiedName(), err)
+	}
+	output, err := message.MarshalJSONPB(&jsonpb.Marshaler{AnyResolver: i.anyResolver, Indent: "  "})
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(append(output, '\n'))
+	return err
+}
+
+// readMessage reads the next length-prefixed message.
+//
+// Returns io.EOF if there are no more messages.
+func readMessage(reader io.Reader, encoding string) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(reader, prefix[:]); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("could not read response: %v", err)
+	}
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if size > maxMessageSize {
+		return nil, fmt.Errorf("response message of size %d is larger than the maximum size %d", size, maxMessageSize)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, fmt.Errorf("could not read response: %v", err)
+	}
+	if prefix[0] == 0 {
+		return data, nil
+	}
+	if encoding != "gzip" {
+		return nil, fmt.Errorf("response message is compressed with unsupported encoding %q", encoding)
+	}
+	gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("could not decompress response: %v", err)
+	}
+	data, err = ioutil.ReadAll(io.LimitReader(gzipReader, maxMessageSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("could not decompress response: %v", err)
+	}
+	if len(data) > maxMessageSize {
+		return nil, fmt.Errorf("response message is larger than the maximum size %d", maxMessageSize)
+	}
+	return data, nil
+}
+
+// getStatusError returns an error if the header has a gRPC status that is not OK.
+func getStatusError(header http.Header) error {
+	value := header.Get("Grpc-Status")
+	if value == "" || value == "0" {
+		return nil
+	}
+	code, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid gRPC status %q", value)
+	}
+	codeString, ok := statusCodeToString[code]
+	if !ok {
+		codeString = value
+	}
+	message := header.Get("Grpc-Message")
+	if unescaped, err := url.PathUnescape(message); err == nil {
+		message = unescaped
+	}
+	if message == "" {
+		return fmt.Errorf("gRPC status %s", codeString)
+	}
+	return fmt.Errorf("gRPC status %s: %s", codeString, message)
+}
@@ -0,0 +1,97 @@
+// Package bufdaemon provides a long-running process that re-runs checks when
+// files change and pushes the results to a client as JSON-RPC 2.0 notifications.
+//
+// This is meant for lightweight editor integrations that do not want to
+// implement a full language server client. Messages are newline-delimited JSON.
+//
+// The following notifications are sent to the client:
+//
+//	textDocument/publishDiagnostics: the diagnostics for a file, in the same
+//	  shape as the Language Server Protocol. This is sent after every check for
+//	  each file with diagnostics, and with no diagnostics for each file whose
+//	  diagnostics were cleared since the previous check.
+//	buf/checkCompleted: sent after every check with the total number of diagnostics.
+//	window/showMessage: sent if a check fails, or for diagnostics without a file.
+//
+// The following requests are accepted from the client:
+//
+//	buf/check: run the checks now.
+//	shutdown: stop the daemon.
+//
+// The exit notification also stops the daemon.
+//
+// The daemon is meant to run for days. Nothing is retained between checks
+// except the diagnostics published to the client, and memory used by a check
+// is returned to the operating system once the check completes. If a debug
+// address is set, memory and check statistics are served as JSON at
+// /debug/vars, in the same format as the expvar package.
+package bufdaemon
+
+import (
+	"context"
+	"io"
+	"time"
+
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+	"go.uber.org/zap"
+)
+
+// DefaultPollInterval is the default interval between checking for file
+// changes when filesystem notifications are not available.
+const DefaultPollInterval = time.Second
+
+// Checker runs the checks, returning the FileAnnotations.
+//
+// The paths of the FileAnnotations must be real file paths, either absolute
+// or relative to the current working directory.
+type Checker func(ctx context.Context) ([]*filev1beta1.FileAnnotation, error)
+
+// Daemon runs the checks for a directory whenever a file within the directory changes.
+type Daemon interface {
+	// Run runs the checks and then runs them again on every file change until
+	// the reader is closed, the client asks the daemon to stop, or the context
+	// is done.
+	//
+	// Messages from the client are read from the reader, and messages to the
+	// client are written to the writer.
+	Run(ctx context.Context, reader io.Reader, writer io.Writer) error
+}
+
+// NewDaemon returns a new Daemon for the directory.
+func NewDaemon(
+	logger *zap.Logger,
+	dirPath string,
+	checker Checker,
+	options ...DaemonOption,
+) Daemon {
+	return newDaemon(
+		logger,
+		dirPath,
+		checker,
+		options...,
+	)
+}
+
+// DaemonOption is an option for a new Daemon.
+type DaemonOption func(*daemon)
+
+// DaemonWithPollInterval returns a new DaemonOption that sets the interval
+// between checking for file changes when filesystem notifications are not
+// available.
+//
+// The default is DefaultPollInterval.
+func DaemonWithPollInterval(pollInterval time.Duration) DaemonOption {
+	return func(daemon *daemon) {
+		daemon.pollInterval = pollInterval
+	}
+}
+
+// DaemonWithDebugAddress returns a new DaemonOption that serves memory and check
+// statistics as JSON at /debug/vars on the address while the daemon is running.
+//
+// The default is to not serve statistics.
+func DaemonWithDebugAddress(debugAddress string) DaemonOption {
+	return func(daemon *daemon) {
+		daemon.debugAddress = debugAddress
+	}
+}
@@ -0,0 +1,172 @@
+package bufdaemon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+	"github.com/bufbuild/buf/internal/pkg/util/utillsp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	filePath := filepath.Join(dirPath, "a.proto")
+	require.NoError(t, ioutil.WriteFile(filePath, []byte("oneTwo"), 0644))
+	uri, err := utillsp.PathToURI(filePath)
+	require.NoError(t, err)
+
+	checker := func(ctx context.Context) ([]*filev1beta1.FileAnnotation, error) {
+		data, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		switch string(data) {
+		case "oneTwo":
+			return []*filev1beta1.FileAnnotation{
+				{
+					Path:        filePath,
+					StartLine:   6,
+					StartColumn: 9,
+					EndLine:     6,
+					EndColumn:   15,
+					Type:        "FIELD_LOWER_SNAKE_CASE",
+					Message:     "Field name mismatch.",
+				},
+			}, nil
+		case "error":
+			return nil, errors.New("system error")
+		default:
+			return nil, nil
+		}
+	}
+	clientReader, daemonWriter := io.Pipe()
+	daemonReader, clientWriter := io.Pipe()
+	defer func() { assert.NoError(t, clientWriter.Close()) }()
+	errC := make(chan error, 1)
+	go func() {
+		errC <- NewDaemon(
+			zap.NewNop(),
+			dirPath,
+			checker,
+			DaemonWithPollInterval(10*time.Millisecond),
+		).Run(context.Background(), daemonReader, daemonWriter)
+		_ = daemonWriter.Close()
+	}()
+	reader := bufio.NewReader(clientReader)
+
+	testReadMessage(
+		t,
+		reader,
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"`+uri+`","diagnostics":[{"range":{"start":{"line":5,"character":8},"end":{"line":5,"character":14}},"severity":1,"code":"FIELD_LOWER_SNAKE_CASE","source":"buf","message":"Field name mismatch."}]}}`,
+	)
+	testReadMessage(t, reader, `{"jsonrpc":"2.0","method":"buf/checkCompleted","params":{"diagnostics":1}}`)
+
+	// fixing the file clears the diagnostics
+	require.NoError(t, ioutil.WriteFile(filePath, []byte("one_two"), 0644))
+	testReadMessage(t, reader, `{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"`+uri+`","diagnostics":[]}}`)
+	testReadMessage(t, reader, `{"jsonrpc":"2.0","method":"buf/checkCompleted","params":{"diagnostics":0}}`)
+
+	require.NoError(t, ioutil.WriteFile(filePath, []byte("error"), 0644))
+	testReadMessage(t, reader, `{"jsonrpc":"2.0","method":"window/showMessage","params":{"type":1,"message":"system error"}}`)
+
+	testWriteMessage(t, clientWriter, `{"jsonrpc":"2.0","id":1,"method":"foo"}`)
+	testReadMessage(t, reader, `{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"method not found: \"foo\""}}`)
+	testWriteMessage(t, clientWriter, `{"jsonrpc":"2.0","id":2,"method":"buf/check"}`)
+	testReadMessage(t, reader, `{"jsonrpc":"2.0","method":"window/showMessage","params":{"type":1,"message":"system error"}}`)
+	testReadMessage(t, reader, `{"jsonrpc":"2.0","id":2,"result":null}`)
+	testWriteMessage(t, clientWriter, `{"jsonrpc":"2.0","id":3,"method":"shutdown"}`)
+	testReadMessage(t, reader, `{"jsonrpc":"2.0","id":3,"result":null}`)
+	assert.NoError(t, <-errC)
+}
+
+func TestRunEOF(t *testing.T) {
+	t.Parallel()
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	writer := bytes.NewBuffer(nil)
+	require.NoError(
+		t,
+		NewDaemon(
+			zap.NewNop(),
+			dirPath,
+			func(ctx context.Context) ([]*filev1beta1.FileAnnotation, error) {
+				return []*filev1beta1.FileAnnotation{{Type: "COMPILE", Message: "no path"}}, nil
+			},
+		).Run(context.Background(), bytes.NewReader([]byte("\n{\n")), writer),
+	)
+	assert.Equal(
+		t,
+		`{"jsonrpc":"2.0","method":"window/showMessage","params":{"type":1,"message":"<input>:1:1:no path"}}
+{"jsonrpc":"2.0","method":"buf/checkCompleted","params":{"diagnostics":1}}
+{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"unexpected end of JSON input"}}
+`,
+		writer.String(),
+	)
+}
+
+func TestDebugHandler(t *testing.T) {
+	t.Parallel()
+	stats := &stats{}
+	var checkErr error
+	session := newSession(
+		zap.NewNop(),
+		func(ctx context.Context) ([]*filev1beta1.FileAnnotation, error) {
+			if checkErr != nil {
+				return nil, checkErr
+			}
+			return []*filev1beta1.FileAnnotation{
+				{Path: "a.proto", Type: "FIELD_LOWER_SNAKE_CASE"},
+				{Path: "a.proto", Type: "ENUM_PASCAL_CASE"},
+				{Path: "b.proto", Type: "FIELD_LOWER_SNAKE_CASE"},
+			}, nil
+		},
+		stats,
+		ioutil.Discard,
+	)
+	require.NoError(t, session.check(context.Background()))
+	checkErr = errors.New("system error")
+	require.NoError(t, session.check(context.Background()))
+
+	server := httptest.NewServer(newDebugHandler(stats))
+	defer server.Close()
+	response, err := server.Client().Get(server.URL + debugVarsPath)
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, response.Body.Close()) }()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	vars := &debugVars{}
+	require.NoError(t, json.NewDecoder(response.Body).Decode(vars))
+	assert.NotZero(t, vars.Memstats.HeapAlloc)
+	assert.Equal(t, int64(2), vars.Bufdaemon.Checks)
+	assert.Equal(t, int64(1), vars.Bufdaemon.FailedChecks)
+	assert.Equal(t, int64(3), vars.Bufdaemon.LastCheckDiagnostics)
+	assert.Equal(t, int64(2), vars.Bufdaemon.PublishedURIs)
+}
+
+func testReadMessage(t *testing.T, reader *bufio.Reader, expected string) {
+	line, err := reader.ReadBytes('\n')
+	require.NoError(t, err)
+	assert.JSONEq(t, expected, string(line))
+}
+
+func testWriteMessage(t *testing.T, writer io.Writer, message string) {
+	_, err := io.WriteString(writer, message+"\n")
+	require.NoError(t, err)
+}
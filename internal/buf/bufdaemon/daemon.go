@@ -0,0 +1,222 @@
+package bufdaemon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/bufbuild/buf/internal/buf/bufwatch"
+	"github.com/bufbuild/buf/internal/pkg/util/utillsp"
+	"go.uber.org/zap"
+)
+
+const (
+	methodCheckCompleted = "buf/checkCompleted"
+	methodCheck          = "buf/check"
+	methodShutdown       = "shutdown"
+	methodExit           = "exit"
+
+	diagnosticSource = "buf"
+
+	// maxMessageSize is the maximum size of a message from the client.
+	maxMessageSize = 1 << 20
+)
+
+type daemon struct {
+	logger       *zap.Logger
+	dirPath      string
+	checker      Checker
+	pollInterval time.Duration
+	debugAddress string
+	stats        *stats
+}
+
+func newDaemon(
+	logger *zap.Logger,
+	dirPath string,
+	checker Checker,
+	options ...DaemonOption,
+) *daemon {
+	daemon := &daemon{
+		logger:       logger.Named("bufdaemon"),
+		dirPath:      dirPath,
+		checker:      checker,
+		pollInterval: DefaultPollInterval,
+		stats:        &stats{},
+	}
+	for _, option := range options {
+		option(daemon)
+	}
+	return daemon
+}
+
+func (d *daemon) Run(ctx context.Context, reader io.Reader, writer io.Writer) error {
+	if d.debugAddress != "" {
+		listener, err := net.Listen("tcp", d.debugAddress)
+		if err != nil {
+			return err
+		}
+		server := &http.Server{Handler: newDebugHandler(d.stats)}
+		go func() {
+			if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+				d.logger.Error("debug_server", zap.Error(err))
+			}
+		}()
+		defer func() {
+			_ = server.Close()
+		}()
+		d.logger.Debug("debug_server", zap.String("address", listener.Addr().String()+debugVarsPath))
+	}
+	session := newSession(d.logger, d.checker, d.stats, writer)
+	// the watcher calls the function for the first check and then for every
+	// change, and the check is run here so that checks and requests are handled
+	// one at a time
+	watchCtx, watchCancel := context.WithCancel(ctx)
+	defer watchCancel()
+	changeC := make(chan struct{})
+	watchErrC := make(chan error, 1)
+	go func() {
+		watchErrC <- bufwatch.NewWatcher(
+			d.dirPath,
+			bufwatch.WatcherWithAllFiles(),
+			bufwatch.WatcherWithPollInterval(d.pollInterval),
+		).Watch(
+			watchCtx,
+			func(ctx context.Context) error {
+				select {
+				case changeC <- struct{}{}:
+				case <-ctx.Done():
+				}
+				return nil
+			},
+		)
+	}()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-watchErrC:
+		return err
+	case <-changeC:
+		if err := session.check(ctx); err != nil {
+			return err
+		}
+	}
+	lineC := make(chan []byte)
+	readErrC := make(chan error, 1)
+	doneC := make(chan struct{})
+	defer close(doneC)
+	go func() {
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(nil, maxMessageSize)
+		for scanner.Scan() {
+			// the scanner re-uses its buffer, so copy the line
+			line := append([]byte(nil), scanner.Bytes()...)
+			select {
+			case lineC <- line:
+			case <-doneC:
+				return
+			}
+		}
+		readErrC <- scanner.Err()
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-readErrC:
+			return err
+		case err := <-watchErrC:
+			return err
+		case line := <-lineC:
+			stop, err := session.handle(ctx, line)
+			if err != nil {
+				return err
+			}
+			if stop {
+				return nil
+			}
+		case <-changeC:
+			d.logger.Debug("files_changed")
+			if err := session.check(ctx); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+type session struct {
+	logger               *zap.Logger
+	checker              Checker
+	stats                *stats
+	writer               utillsp.Writer
+	diagnosticsPublisher utillsp.DiagnosticsPublisher
+}
+
+func newSession(logger *zap.Logger, checker Checker, stats *stats, writer io.Writer) *session {
+	lspWriter := utillsp.NewLineWriter(writer)
+	return &session{
+		logger:               logger,
+		checker:              checker,
+		stats:                stats,
+		writer:               lspWriter,
+		diagnosticsPublisher: utillsp.NewDiagnosticsPublisher(lspWriter, diagnosticSource),
+	}
+}
+
+// handle handles a single message from the client.
+//
+// Returns true if the daemon should stop.
+func (s *session) handle(ctx context.Context, line []byte) (bool, error) {
+	if len(bytes.TrimSpace(line)) == 0 {
+		return false, nil
+	}
+	request := &utillsp.Request{}
+	if err := json.Unmarshal(line, request); err != nil {
+		return false, s.writer.RespondError(json.RawMessage("null"), utillsp.ParseErrorCode, err.Error())
+	}
+	s.logger.Debug("request", zap.String("method", request.Method))
+	switch request.Method {
+	case methodShutdown:
+		return true, s.writer.Respond(request.ID, nil)
+	case methodExit:
+		return true, nil
+	case methodCheck:
+		if err := s.check(ctx); err != nil {
+			return false, err
+		}
+		return false, s.writer.Respond(request.ID, nil)
+	default:
+		return false, s.writer.RespondError(request.ID, utillsp.MethodNotFoundCode, fmt.Sprintf("method not found: %q", request.Method))
+	}
+}
+
+// check runs the checker and publishes the diagnostics.
+//
+// A failed check is reported to the client, and only returns an error if the
+// context is done or the client cannot be written to.
+func (s *session) check(ctx context.Context) error {
+	start := time.Now()
+	fileAnnotations, err := s.checker(ctx)
+	// the image built by the check is no longer referenced, so return its memory
+	// to the operating system instead of holding on to the peak while idle
+	debug.FreeOSMemory()
+	s.stats.recordCheck(time.Since(start), err != nil, len(fileAnnotations))
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return s.writer.Notify(utillsp.MethodShowMessage, &utillsp.ShowMessageParams{Type: utillsp.MessageTypeError, Message: err.Error()})
+	}
+	if err := s.diagnosticsPublisher.Publish(fileAnnotations); err != nil {
+		return err
+	}
+	s.stats.recordPublishedURIs(s.diagnosticsPublisher.NumPublishedURIs())
+	return s.writer.Notify(methodCheckCompleted, &checkCompletedParams{Diagnostics: len(fileAnnotations)})
+}
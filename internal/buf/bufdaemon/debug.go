@@ -0,0 +1,89 @@
+package bufdaemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+const debugVarsPath = "/debug/vars"
+
+// stats are the statistics for the checks of a daemon.
+//
+// All fields are accessed atomically.
+type stats struct {
+	checks                int64
+	failedChecks          int64
+	lastCheckDurationNano int64
+	lastCheckDiagnostics  int64
+	publishedURIs         int64
+}
+
+// recordCheck records a check that took the duration.
+//
+// If the check failed, diagnostics is ignored.
+func (s *stats) recordCheck(duration time.Duration, failed bool, diagnostics int) {
+	atomic.AddInt64(&s.checks, 1)
+	atomic.StoreInt64(&s.lastCheckDurationNano, int64(duration))
+	if failed {
+		atomic.AddInt64(&s.failedChecks, 1)
+		return
+	}
+	atomic.StoreInt64(&s.lastCheckDiagnostics, int64(diagnostics))
+}
+
+// recordPublishedURIs records the number of URIs with published diagnostics.
+func (s *stats) recordPublishedURIs(publishedURIs int) {
+	atomic.StoreInt64(&s.publishedURIs, int64(publishedURIs))
+}
+
+type debugVars struct {
+	Cmdline   []string          `json:"cmdline"`
+	Memstats  *runtime.MemStats `json:"memstats"`
+	Bufdaemon *debugStats       `json:"bufdaemon"`
+}
+
+type debugStats struct {
+	Checks                int64 `json:"checks"`
+	FailedChecks          int64 `json:"failed_checks"`
+	LastCheckDurationNano int64 `json:"last_check_duration_nano"`
+	LastCheckDiagnostics  int64 `json:"last_check_diagnostics"`
+	PublishedURIs         int64 `json:"published_uris"`
+}
+
+// newDebugHandler returns a new http.Handler that serves the statistics at
+// /debug/vars.
+//
+// This does not use the global expvar handler, as the variables published
+// with expvar cannot be removed, and there may be more than one daemon in a
+// process.
+func newDebugHandler(stats *stats) http.Handler {
+	serveMux := http.NewServeMux()
+	serveMux.HandleFunc(
+		debugVarsPath,
+		func(responseWriter http.ResponseWriter, request *http.Request) {
+			memStats := &runtime.MemStats{}
+			runtime.ReadMemStats(memStats)
+			responseWriter.Header().Set("Content-Type", "application/json; charset=utf-8")
+			encoder := json.NewEncoder(responseWriter)
+			encoder.SetIndent("", "  ")
+			_ = encoder.Encode(
+				&debugVars{
+					Cmdline:  os.Args,
+					Memstats: memStats,
+					Bufdaemon: &debugStats{
+						Checks:                atomic.LoadInt64(&stats.checks),
+						FailedChecks:          atomic.LoadInt64(&stats.failedChecks),
+						LastCheckDurationNano: atomic.LoadInt64(&stats.lastCheckDurationNano),
+						LastCheckDiagnostics:  atomic.LoadInt64(&stats.lastCheckDiagnostics),
+						PublishedURIs:         atomic.LoadInt64(&stats.publishedURIs),
+					},
+				},
+			)
+		},
+	)
+	return serveMux
+}
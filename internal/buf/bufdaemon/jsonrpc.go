@@ -0,0 +1,5 @@
+package bufdaemon
+
+type checkCompletedParams struct {
+	Diagnostics int `json:"diagnostics"`
+}
@@ -0,0 +1,370 @@
+// Package bufdoc renders documentation for the descriptors and comments of images.
+//
+// Documentation is rendered by executing a template with a Doc. The html and
+// markdown formats have default templates that can be overridden, and the json
+// format is the Doc as JSON.
+package bufdoc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/bufbuild/buf/internal/buf/ext/extimage"
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/bufbuild/buf/internal/pkg/protodesc"
+)
+
+const (
+	// FormatHTML is the html format.
+	FormatHTML = "html"
+	// FormatMarkdown is the markdown format.
+	FormatMarkdown = "markdown"
+	// FormatJSON is the json format.
+	FormatJSON = "json"
+)
+
+var (
+	formatToDefaultTemplate = map[string]string{
+		FormatHTML:     defaultHTMLTemplate,
+		FormatMarkdown: defaultMarkdownTemplate,
+	}
+	templateFuncs = map[string]interface{}{
+		"anchor":  getAnchor,
+		"oneline": getOneline,
+	}
+)
+
+// Doc is the documentation of an image.
+//
+// This is the data that templates are executed with.
+type Doc struct {
+	Files []*File `json:"files,omitempty"`
+}
+
+// File is the documentation of a file.
+type File struct {
+	Name    string `json:"name,omitempty"`
+	Package string `json:"package,omitempty"`
+	// Description is the comment of the package statement.
+	Description string     `json:"description,omitempty"`
+	Services    []*Service `json:"services,omitempty"`
+	// Messages are the messages of the file, with nested messages after their
+	// parents. Map entries are not included.
+	Messages []*Message `json:"messages,omitempty"`
+	// Enums are the enums of the file, including nested enums.
+	Enums []*Enum `json:"enums,omitempty"`
+}
+
+// Service is the documentation of a service.
+type Service struct {
+	Name        string    `json:"name,omitempty"`
+	FullName    string    `json:"full_name,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Methods     []*Method `json:"methods,omitempty"`
+}
+
+// Method is the documentation of a method.
+type Method struct {
+	Name            string `json:"name,omitempty"`
+	Description     string `json:"description,omitempty"`
+	RequestType     *Type  `json:"request_type,omitempty"`
+	ResponseType    *Type  `json:"response_type,omitempty"`
+	ClientStreaming bool   `json:"client_streaming,omitempty"`
+	ServerStreaming bool   `json:"server_streaming,omitempty"`
+}
+
+// Message is the documentation of a message.
+type Message struct {
+	// Name is the nested name of the message without the package, such as Foo.Bar.
+	Name        string   `json:"name,omitempty"`
+	FullName    string   `json:"full_name,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Fields      []*Field `json:"fields,omitempty"`
+}
+
+// Field is the documentation of a field.
+type Field struct {
+	Name   string `json:"name,omitempty"`
+	Number int    `json:"number,omitempty"`
+	// Label is optional, required, or repeated, and is empty for maps.
+	Label       string `json:"label,omitempty"`
+	Type        *Type  `json:"type,omitempty"`
+	Oneof       string `json:"oneof,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Enum is the documentation of an enum.
+type Enum struct {
+	// Name is the nested name of the enum without the package, such as Foo.Kind.
+	Name        string       `json:"name,omitempty"`
+	FullName    string       `json:"full_name,omitempty"`
+	Description string       `json:"description,omitempty"`
+	Values      []*EnumValue `json:"values,omitempty"`
+}
+
+// EnumValue is the documentation of an enum value.
+type EnumValue struct {
+	Name        string `json:"name,omitempty"`
+	Number      int    `json:"number"`
+	Description string `json:"description,omitempty"`
+}
+
+// Type is a type of a field or method.
+type Type struct {
+	// Name is the name of the type as written, such as string, foo.v1.Foo, or
+	// map<string, foo.v1.Foo>.
+	Name string `json:"name,omitempty"`
+	// Link is the full name of the message or enum that documents the type, if
+	// the type or the value type of a map is documented.
+	Link string `json:"link,omitempty"`
+}
+
+// NewDoc returns a new Doc for the files of the image that are not imports.
+//
+// Files are sorted by name. Comments are only included if the image has source info.
+func NewDoc(ctx context.Context, image *imagev1beta1.Image) (*Doc, error) {
+	image, err := extimage.ImageWithoutImports(image)
+	if err != nil {
+		return nil, err
+	}
+	files, err := protodesc.NewFilesUnstable(ctx, image.GetFile()...)
+	if err != nil {
+		return nil, err
+	}
+	protodesc.SortFiles(files)
+	// the full names of the messages and enums that are documented, for links
+	documented := make(map[string]struct{})
+	mapEntries := make(map[string]protodesc.Message)
+	for _, file := range files {
+		if err := protodesc.ForEachMessage(func(message protodesc.Message) error {
+			if message.IsMapEntry() {
+				mapEntries[message.FullName()] = message
+			} else {
+				documented[message.FullName()] = struct{}{}
+			}
+			return nil
+		}, file); err != nil {
+			return nil, err
+		}
+		if err := protodesc.ForEachEnum(func(enum protodesc.Enum) error {
+			documented[enum.FullName()] = struct{}{}
+			return nil
+		}, file); err != nil {
+			return nil, err
+		}
+	}
+	builder := &docBuilder{
+		documented: documented,
+		mapEntries: mapEntries,
+	}
+	doc := &Doc{}
+	for _, file := range files {
+		docFile, err := builder.newFile(file)
+		if err != nil {
+			return nil, err
+		}
+		doc.Files = append(doc.Files, docFile)
+	}
+	return doc, nil
+}
+
+// Render renders the documentation in the format.
+//
+// If templateData is not empty, it is used instead of the default template of
+// the format. Templates for the html format are html/template templates, and
+// templates for the markdown format are text/template templates. Templates
+// have the functions anchor, which returns the anchor for a full name or file
+// name, and oneline, which joins the lines of a comment for markdown tables.
+//
+// The json format cannot have a template.
+func Render(writer io.Writer, doc *Doc, format string, templateData string) error {
+	switch format {
+	case FormatJSON:
+		if templateData != "" {
+			return fmt.Errorf("the %s format cannot have a template", FormatJSON)
+		}
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = writer.Write(append(data, '\n'))
+		return err
+	case FormatHTML, FormatMarkdown:
+		if templateData == "" {
+			templateData = formatToDefaultTemplate[format]
+		}
+		if format == FormatHTML {
+			template, err := htmltemplate.New(format).Funcs(templateFuncs).Parse(templateData)
+			if err != nil {
+				return fmt.Errorf("could not parse template: %v", err)
+			}
+			return template.Execute(writer, doc)
+		}
+		template, err := texttemplate.New(format).Funcs(templateFuncs).Parse(templateData)
+		if err != nil {
+			return fmt.Errorf("could not parse template: %v", err)
+		}
+		return template.Execute(writer, doc)
+	default:
+		return fmt.Errorf("unknown format %q, must be one of %s", format, FormatsToString())
+	}
+}
+
+// FormatsToString returns the formats as a string.
+func FormatsToString() string {
+	formats := []string{FormatHTML, FormatJSON, FormatMarkdown}
+	sort.Strings(formats)
+	return "[" + strings.Join(formats, ",") + "]"
+}
+
+type docBuilder struct {
+	documented map[string]struct{}
+	mapEntries map[string]protodesc.Message
+}
+
+func (d *docBuilder) newFile(file protodesc.File) (*File, error) {
+	docFile := &File{
+		Name:        file.FilePath(),
+		Package:     file.Package(),
+		Description: getDescription(file.PackageLocation()),
+	}
+	for _, service := range file.Services() {
+		docService := &Service{
+			Name:        service.Name(),
+			FullName:    service.FullName(),
+			Description: getDescription(service.Location()),
+		}
+		for _, method := range service.Methods() {
+			docService.Methods = append(docService.Methods, &Method{
+				Name:            method.Name(),
+				Description:     getDescription(method.Location()),
+				RequestType:     d.newType(method.InputTypeName()),
+				ResponseType:    d.newType(method.OutputTypeName()),
+				ClientStreaming: method.ClientStreaming(),
+				ServerStreaming: method.ServerStreaming(),
+			})
+		}
+		docFile.Services = append(docFile.Services, docService)
+	}
+	if err := protodesc.ForEachMessage(func(message protodesc.Message) error {
+		if message.IsMapEntry() {
+			return nil
+		}
+		docMessage, err := d.newMessage(message)
+		if err != nil {
+			return err
+		}
+		docFile.Messages = append(docFile.Messages, docMessage)
+		return nil
+	}, file); err != nil {
+		return nil, err
+	}
+	// ForEachEnum only returns errors returned by the function
+	_ = protodesc.ForEachEnum(func(enum protodesc.Enum) error {
+		docEnum := &Enum{
+			Name:        enum.NestedName(),
+			FullName:    enum.FullName(),
+			Description: getDescription(enum.Location()),
+		}
+		for _, enumValue := range enum.Values() {
+			docEnum.Values = append(docEnum.Values, &EnumValue{
+				Name:        enumValue.Name(),
+				Number:      enumValue.Number(),
+				Description: getDescription(enumValue.Location()),
+			})
+		}
+		docFile.Enums = append(docFile.Enums, docEnum)
+		return nil
+	}, file)
+	return docFile, nil
+}
+
+func (d *docBuilder) newMessage(message protodesc.Message) (*Message, error) {
+	docMessage := &Message{
+		Name:        message.NestedName(),
+		FullName:    message.FullName(),
+		Description: getDescription(message.Location()),
+	}
+	for _, field := range message.Fields() {
+		docField := &Field{
+			Name:        field.Name(),
+			Number:      field.Number(),
+			Label:       field.Label().String(),
+			Type:        d.newFieldType(field),
+			Description: getDescription(field.Location()),
+		}
+		oneof, err := protodesc.FieldOneof(field)
+		if err != nil {
+			return nil, err
+		}
+		if oneof != nil {
+			docField.Oneof = oneof.Name()
+		}
+		if _, ok := d.mapEntries[strings.TrimPrefix(field.TypeName(), ".")]; ok {
+			docField.Label = ""
+		}
+		docMessage.Fields = append(docMessage.Fields, docField)
+	}
+	return docMessage, nil
+}
+
+func (d *docBuilder) newFieldType(field protodesc.Field) *Type {
+	if field.TypeName() == "" {
+		return &Type{
+			Name: field.Type().String(),
+		}
+	}
+	mapEntry, ok := d.mapEntries[strings.TrimPrefix(field.TypeName(), ".")]
+	if !ok || len(mapEntry.Fields()) != 2 {
+		return d.newType(field.TypeName())
+	}
+	keyType := d.newFieldType(mapEntry.Fields()[0])
+	valueType := d.newFieldType(mapEntry.Fields()[1])
+	return &Type{
+		Name: fmt.Sprintf("map<%s, %s>", keyType.Name, valueType.Name),
+		Link: valueType.Link,
+	}
+}
+
+func (d *docBuilder) newType(typeName string) *Type {
+	name := strings.TrimPrefix(typeName, ".")
+	docType := &Type{
+		Name: name,
+	}
+	if _, ok := d.documented[name]; ok {
+		docType.Link = name
+	}
+	return docType
+}
+
+// getDescription returns the leading and trailing comments of the location,
+// with leading spaces of each line removed.
+func getDescription(location protodesc.Location) string {
+	if location == nil {
+		return ""
+	}
+	var lines []string
+	for _, comment := range []string{location.LeadingComments(), location.TrailingComments()} {
+		for _, line := range strings.Split(strings.TrimSpace(comment), "\n") {
+			lines = append(lines, strings.TrimSpace(line))
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// getAnchor returns the anchor for the full name or file name.
+func getAnchor(name string) string {
+	return strings.NewReplacer("/", "_", ".", "_").Replace(name)
+}
+
+// getOneline joins the lines of the description with spaces and escapes
+// pipes, for markdown tables.
+func getOneline(description string) string {
+	return strings.Replace(strings.Join(strings.Fields(description), " "), "|", "\\|", -1)
+}
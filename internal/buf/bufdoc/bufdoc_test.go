@@ -0,0 +1,244 @@
+package bufdoc
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDoc(t *testing.T) {
+	t.Parallel()
+	doc, err := NewDoc(context.Background(), testImage)
+	require.NoError(t, err)
+	require.Len(t, doc.Files, 1)
+	file := doc.Files[0]
+	assert.Equal(t, "foo/v1/foo.proto", file.Name)
+	assert.Equal(t, "foo.v1", file.Package)
+	assert.Equal(t, "Foo things.", file.Description)
+
+	require.Len(t, file.Services, 1)
+	assert.Equal(t, &Service{
+		Name:        "FooService",
+		FullName:    "foo.v1.FooService",
+		Description: "FooService does things.",
+		Methods: []*Method{
+			{
+				Name:            "ListFoos",
+				Description:     "ListFoos lists Foos.\n\nThe Foos are streamed.",
+				RequestType:     &Type{Name: "foo.v1.Foo", Link: "foo.v1.Foo"},
+				ResponseType:    &Type{Name: "google.protobuf.Empty"},
+				ServerStreaming: true,
+			},
+		},
+	}, file.Services[0])
+
+	require.Len(t, file.Messages, 2)
+	assert.Equal(t, &Message{
+		Name:        "Foo",
+		FullName:    "foo.v1.Foo",
+		Description: "Foo is a foo.",
+		Fields: []*Field{
+			{
+				Name:        "name",
+				Number:      1,
+				Label:       "optional",
+				Type:        &Type{Name: "string"},
+				Description: "The name.",
+			},
+			{
+				Name:   "bars",
+				Number: 2,
+				Type:   &Type{Name: "map<string, foo.v1.Foo.Bar>", Link: "foo.v1.Foo.Bar"},
+			},
+			{
+				Name:   "kind",
+				Number: 3,
+				Label:  "optional",
+				Type:   &Type{Name: "foo.v1.Foo.Kind", Link: "foo.v1.Foo.Kind"},
+				Oneof:  "value",
+			},
+		},
+	}, file.Messages[0])
+	assert.Equal(t, "Foo.Bar", file.Messages[1].Name)
+
+	require.Len(t, file.Enums, 1)
+	assert.Equal(t, &Enum{
+		Name:     "Foo.Kind",
+		FullName: "foo.v1.Foo.Kind",
+		Values: []*EnumValue{
+			{
+				Name:   "KIND_UNSPECIFIED",
+				Number: 0,
+			},
+		},
+	}, file.Enums[0])
+}
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+	doc, err := NewDoc(context.Background(), testImage)
+	require.NoError(t, err)
+
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, Render(buffer, doc, FormatMarkdown, ""))
+	assert.Contains(t, buffer.String(), "## foo/v1/foo.proto")
+	assert.Contains(t, buffer.String(), "| ListFoos | [foo.v1.Foo](#foo_v1_Foo) | stream google.protobuf.Empty | ListFoos lists Foos. The Foos are streamed. |")
+	assert.Contains(t, buffer.String(), "| bars | [map<string, foo.v1.Foo.Bar>](#foo_v1_Foo_Bar) |  |  |")
+	assert.Contains(t, buffer.String(), "| kind | [foo.v1.Foo.Kind](#foo_v1_Foo_Kind) | optional | Oneof value. |")
+
+	buffer.Reset()
+	require.NoError(t, Render(buffer, doc, FormatHTML, ""))
+	assert.Contains(t, buffer.String(), `<h3 id="foo_v1_Foo">Foo</h3>`)
+	assert.Contains(t, buffer.String(), `<a href="#foo_v1_Foo_Bar"><code>map&lt;string, foo.v1.Foo.Bar&gt;</code></a>`)
+
+	buffer.Reset()
+	require.NoError(t, Render(buffer, doc, FormatJSON, ""))
+	assert.Contains(t, buffer.String(), `"full_name": "foo.v1.FooService"`)
+
+	buffer.Reset()
+	require.NoError(t, Render(buffer, doc, FormatMarkdown, `{{range .Files}}{{.Name}}{{range .Messages}} {{anchor .FullName}}{{end}}{{end}}`))
+	assert.Equal(t, "foo/v1/foo.proto foo_v1_Foo foo_v1_Foo_Bar", buffer.String())
+
+	assert.Error(t, Render(buffer, doc, FormatJSON, `{{.Files}}`))
+	assert.Error(t, Render(buffer, doc, FormatHTML, `{{.Files`))
+	assert.Error(t, Render(buffer, doc, "pdf", ""))
+}
+
+var testImage = &imagev1beta1.Image{
+	File: []*descriptor.FileDescriptorProto{
+		{
+			Name:       proto.String("foo/v1/foo.proto"),
+			Package:    proto.String("foo.v1"),
+			Dependency: []string{"google/protobuf/empty.proto"},
+			Syntax:     proto.String("proto3"),
+			MessageType: []*descriptor.DescriptorProto{
+				{
+					Name: proto.String("Foo"),
+					Field: []*descriptor.FieldDescriptorProto{
+						{
+							Name:     proto.String("name"),
+							Number:   proto.Int32(1),
+							Label:    descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+							Type:     descriptor.FieldDescriptorProto_TYPE_STRING.Enum(),
+							JsonName: proto.String("name"),
+						},
+						{
+							Name:     proto.String("bars"),
+							Number:   proto.Int32(2),
+							Label:    descriptor.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+							Type:     descriptor.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+							TypeName: proto.String(".foo.v1.Foo.BarsEntry"),
+							JsonName: proto.String("bars"),
+						},
+						{
+							Name:       proto.String("kind"),
+							Number:     proto.Int32(3),
+							Label:      descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+							Type:       descriptor.FieldDescriptorProto_TYPE_ENUM.Enum(),
+							TypeName:   proto.String(".foo.v1.Foo.Kind"),
+							JsonName:   proto.String("kind"),
+							OneofIndex: proto.Int32(0),
+						},
+					},
+					NestedType: []*descriptor.DescriptorProto{
+						{
+							Name: proto.String("Bar"),
+						},
+						{
+							Name: proto.String("BarsEntry"),
+							Field: []*descriptor.FieldDescriptorProto{
+								{
+									Name:     proto.String("key"),
+									Number:   proto.Int32(1),
+									Label:    descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+									Type:     descriptor.FieldDescriptorProto_TYPE_STRING.Enum(),
+									JsonName: proto.String("key"),
+								},
+								{
+									Name:     proto.String("value"),
+									Number:   proto.Int32(2),
+									Label:    descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+									Type:     descriptor.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+									TypeName: proto.String(".foo.v1.Foo.Bar"),
+									JsonName: proto.String("value"),
+								},
+							},
+							Options: &descriptor.MessageOptions{
+								MapEntry: proto.Bool(true),
+							},
+						},
+					},
+					EnumType: []*descriptor.EnumDescriptorProto{
+						{
+							Name: proto.String("Kind"),
+							Value: []*descriptor.EnumValueDescriptorProto{
+								{
+									Name:   proto.String("KIND_UNSPECIFIED"),
+									Number: proto.Int32(0),
+								},
+							},
+						},
+					},
+					OneofDecl: []*descriptor.OneofDescriptorProto{
+						{
+							Name: proto.String("value"),
+						},
+					},
+				},
+			},
+			Service: []*descriptor.ServiceDescriptorProto{
+				{
+					Name: proto.String("FooService"),
+					Method: []*descriptor.MethodDescriptorProto{
+						{
+							Name:            proto.String("ListFoos"),
+							InputType:       proto.String(".foo.v1.Foo"),
+							OutputType:      proto.String(".google.protobuf.Empty"),
+							ServerStreaming: proto.Bool(true),
+						},
+					},
+				},
+			},
+			SourceCodeInfo: &descriptor.SourceCodeInfo{
+				Location: []*descriptor.SourceCodeInfo_Location{
+					{
+						// package
+						Path:            []int32{2},
+						Span:            []int32{2, 0, 15},
+						LeadingComments: proto.String(" Foo things.\n"),
+					},
+					{
+						// message Foo
+						Path:            []int32{4, 0},
+						Span:            []int32{5, 0, 20, 1},
+						LeadingComments: proto.String(" Foo is a foo.\n"),
+					},
+					{
+						// field name
+						Path:             []int32{4, 0, 2, 0},
+						Span:             []int32{6, 2, 18},
+						TrailingComments: proto.String(" The name.\n"),
+					},
+					{
+						// service FooService
+						Path:            []int32{6, 0},
+						Span:            []int32{22, 0, 26, 1},
+						LeadingComments: proto.String(" FooService does things.\n"),
+					},
+					{
+						// method ListFoos
+						Path:            []int32{6, 0, 2, 0},
+						Span:            []int32{24, 2, 50},
+						LeadingComments: proto.String(" ListFoos lists Foos.\n\n The Foos are streamed.\n"),
+					},
+				},
+			},
+		},
+	},
+}
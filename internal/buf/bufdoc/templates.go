@@ -0,0 +1,150 @@
+package bufdoc
+
+const defaultMarkdownTemplate = `# API Documentation
+
+## Table of Contents
+{{range .Files}}
+- [{{.Name}}](#{{anchor .Name}})
+{{- range .Services}}
+  - [{{.Name}}](#{{anchor .FullName}})
+{{- end}}
+{{- range .Messages}}
+  - [{{.Name}}](#{{anchor .FullName}})
+{{- end}}
+{{- range .Enums}}
+  - [{{.Name}}](#{{anchor .FullName}})
+{{- end}}
+{{- end}}
+{{range .Files}}
+<a name="{{anchor .Name}}"></a>
+## {{.Name}}
+{{if .Package}}
+Package: ` + "`{{.Package}}`" + `
+{{end}}
+{{- if .Description}}
+{{.Description}}
+{{end}}
+{{- range .Services}}
+<a name="{{anchor .FullName}}"></a>
+### {{.Name}}
+{{if .Description}}
+{{.Description}}
+{{end}}
+| Method | Request | Response | Description |
+| ------ | ------- | -------- | ----------- |
+{{range .Methods -}}
+| {{.Name}} | {{if .ClientStreaming}}stream {{end}}{{template "type" .RequestType}} | {{if .ServerStreaming}}stream {{end}}{{template "type" .ResponseType}} | {{oneline .Description}} |
+{{end}}
+{{- end}}
+{{- range .Messages}}
+<a name="{{anchor .FullName}}"></a>
+### {{.Name}}
+{{if .Description}}
+{{.Description}}
+{{end}}
+{{- if .Fields}}
+| Field | Type | Label | Description |
+| ----- | ---- | ----- | ----------- |
+{{range .Fields -}}
+| {{.Name}} | {{template "type" .Type}} | {{.Label}} | {{if .Oneof}}Oneof {{.Oneof}}.{{if .Description}} {{end}}{{end}}{{oneline .Description}} |
+{{end}}
+{{- end}}
+{{- end}}
+{{- range .Enums}}
+<a name="{{anchor .FullName}}"></a>
+### {{.Name}}
+{{if .Description}}
+{{.Description}}
+{{end}}
+| Name | Number | Description |
+| ---- | ------ | ----------- |
+{{range .Values -}}
+| {{.Name}} | {{.Number}} | {{oneline .Description}} |
+{{end}}
+{{- end}}
+{{- end}}
+{{- define "type"}}{{if .Link}}[{{.Name}}](#{{anchor .Link}}){{else}}{{.Name}}{{end}}{{end}}`
+
+const defaultHTMLTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>API Documentation</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+code { font-family: monospace; }
+.description { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>API Documentation</h1>
+<h2>Table of Contents</h2>
+<ul>
+{{- range .Files}}
+<li><a href="#{{anchor .Name}}">{{.Name}}</a>
+<ul>
+{{- range .Services}}
+<li><a href="#{{anchor .FullName}}">{{.Name}}</a></li>
+{{- end}}
+{{- range .Messages}}
+<li><a href="#{{anchor .FullName}}">{{.Name}}</a></li>
+{{- end}}
+{{- range .Enums}}
+<li><a href="#{{anchor .FullName}}">{{.Name}}</a></li>
+{{- end}}
+</ul>
+</li>
+{{- end}}
+</ul>
+{{- range .Files}}
+<h2 id="{{anchor .Name}}">{{.Name}}</h2>
+{{- if .Package}}
+<p>Package: <code>{{.Package}}</code></p>
+{{- end}}
+{{- if .Description}}
+<p class="description">{{.Description}}</p>
+{{- end}}
+{{- range .Services}}
+<h3 id="{{anchor .FullName}}">{{.Name}}</h3>
+{{- if .Description}}
+<p class="description">{{.Description}}</p>
+{{- end}}
+<table>
+<tr><th>Method</th><th>Request</th><th>Response</th><th>Description</th></tr>
+{{- range .Methods}}
+<tr><td>{{.Name}}</td><td>{{if .ClientStreaming}}stream {{end}}{{template "type" .RequestType}}</td><td>{{if .ServerStreaming}}stream {{end}}{{template "type" .ResponseType}}</td><td class="description">{{.Description}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- range .Messages}}
+<h3 id="{{anchor .FullName}}">{{.Name}}</h3>
+{{- if .Description}}
+<p class="description">{{.Description}}</p>
+{{- end}}
+{{- if .Fields}}
+<table>
+<tr><th>Field</th><th>Type</th><th>Label</th><th>Description</th></tr>
+{{- range .Fields}}
+<tr><td>{{.Name}}</td><td>{{template "type" .Type}}</td><td>{{.Label}}</td><td class="description">{{if .Oneof}}Oneof {{.Oneof}}.{{if .Description}} {{end}}{{end}}{{.Description}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+{{- range .Enums}}
+<h3 id="{{anchor .FullName}}">{{.Name}}</h3>
+{{- if .Description}}
+<p class="description">{{.Description}}</p>
+{{- end}}
+<table>
+<tr><th>Name</th><th>Number</th><th>Description</th></tr>
+{{- range .Values}}
+<tr><td>{{.Name}}</td><td>{{.Number}}</td><td class="description">{{.Description}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+</body>
+</html>
+{{define "type"}}{{if .Link}}<a href="#{{anchor .Link}}"><code>{{.Name}}</code></a>{{else}}<code>{{.Name}}</code>{{end}}{{end}}`
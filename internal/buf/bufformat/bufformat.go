@@ -0,0 +1,34 @@
+// Package bufformat formats Protobuf files in a canonical style.
+//
+// The canonical style is:
+//
+//	two spaces of indentation per level of nesting.
+//	syntax, package, imports sorted by path, and options sorted by name at
+//	  the top of the file, separated by blank lines.
+//	options sorted by name at the top of messages, enums, services, and the like.
+//	one space around = and after commas, and no spaces within brackets and
+//	  parentheses, such as map<string, Foo> and [deprecated = true].
+//	at most one blank line between declarations, and no blank lines at the start
+//	  of bodies.
+//
+// All comments are kept with the declarations they belong to, and line breaks
+// within declarations, such as between field options, are kept. Standard options
+// are sorted before custom options.
+package bufformat
+
+// Format formats the Protobuf source data.
+//
+// The file path is only used for errors. Returns error if the data cannot be
+// parsed, but otherwise does not validate that the data is valid Protobuf.
+func Format(filePath string, data []byte) ([]byte, error) {
+	tokens, err := newLexer(filePath, string(data)).tokenize()
+	if err != nil {
+		return nil, err
+	}
+	file, err := parse(filePath, tokens)
+	if err != nil {
+		return nil, err
+	}
+	p := &printer{}
+	return []byte(p.printFile(file)), nil
+}
@@ -0,0 +1,157 @@
+package bufformat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+	testFormat(
+		t,
+		`// Copyright.
+
+syntax="proto3";
+import "z/z.proto";
+option java_package="com.foo";
+package foo.v1;
+// Comment for b.
+import public "b/b.proto";
+option (custom.opt).x = { a: 1 b: "x" };
+option go_package = "foo/v1;foov1";
+/* Detached. */
+
+// Foo is a foo.
+message Foo {  // open
+
+
+    string name=1; // trailing
+  map<string,Bar>   bars = 2 [ deprecated=true, (foo.bar) = -1 ];
+  option (my_opt) = true;
+    repeated .foo.v1.Foo children = 3 [
+      (a) = 1, // first
+      (b) = {
+        c: "d"
+      }
+    ];
+  message Bar{}
+  enum Kind { KIND_UNSPECIFIED=0; option allow_alias = true; KIND_A = 1; }
+  reserved 4 to 10, 20;
+  reserved "x","y";;
+  // closing
+}
+service FooService{
+  rpc GetFoo ( GetFooRequest ) returns ( stream GetFooResponse );
+  rpc Other(A) returns (B) {
+    option (x) = "y";
+  };
+}
+// end`,
+		`// Copyright.
+
+syntax = "proto3";
+
+package foo.v1;
+
+// Comment for b.
+import public "b/b.proto";
+import "z/z.proto";
+
+option go_package = "foo/v1;foov1";
+option java_package = "com.foo";
+option (custom.opt).x = { a: 1 b: "x" };
+
+/* Detached. */
+
+// Foo is a foo.
+message Foo { // open
+  option (my_opt) = true;
+
+  string name = 1; // trailing
+  map<string, Bar> bars = 2 [deprecated = true, (foo.bar) = -1];
+  repeated .foo.v1.Foo children = 3 [
+    (a) = 1, // first
+    (b) = {
+      c: "d"
+    }
+  ];
+  message Bar {}
+  enum Kind {
+    option allow_alias = true;
+
+    KIND_UNSPECIFIED = 0;
+    KIND_A = 1;
+  }
+  reserved 4 to 10, 20;
+  reserved "x", "y";
+  // closing
+}
+service FooService {
+  rpc GetFoo(GetFooRequest) returns (stream GetFooResponse);
+  rpc Other(A) returns (B) {
+    option (x) = "y";
+  }
+}
+// end
+`,
+	)
+	testFormat(t, ``, ``)
+	testFormat(t, "// only a comment\n", "// only a comment\n")
+	testFormat(
+		t,
+		`syntax = "proto2";
+message Foo {
+  optional group Bar = 1 {
+    optional int64 baz = 2 [default = -inf];
+  }
+  extensions 100 to max;
+}
+extend Foo {
+  optional string qux = 100;
+}`,
+		`syntax = "proto2";
+
+message Foo {
+  optional group Bar = 1 {
+    optional int64 baz = 2 [default = -inf];
+  }
+  extensions 100 to max;
+}
+extend Foo {
+  optional string qux = 100;
+}
+`,
+	)
+}
+
+func TestFormatErrors(t *testing.T) {
+	t.Parallel()
+	for _, data := range []string{
+		`message Foo {`,
+		`message Foo {}}`,
+		`syntax = "proto3"`,
+		`syntax = "proto3;`,
+		`/* unterminated`,
+		`message Foo { string foo = 1 [deprecated = true; }`,
+		`message Foo { string foo = 1 (deprecated = true]; }`,
+		`{}`,
+	} {
+		_, err := Format("a.proto", []byte(data))
+		assert.Error(t, err, data)
+	}
+	_, err := Format("a.proto", []byte("syntax = \"proto3\";\n\nmessage Foo {\n  string foo = 1;\n"))
+	require.Error(t, err)
+	assert.Equal(t, "a.proto:3:13: unclosed {", err.Error())
+}
+
+func testFormat(t *testing.T, input string, expected string) {
+	formatted, err := Format("a.proto", []byte(input))
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(formatted))
+	// formatting must be idempotent
+	formattedAgain, err := Format("a.proto", formatted)
+	require.NoError(t, err)
+	assert.Equal(t, string(formatted), string(formattedAgain))
+}
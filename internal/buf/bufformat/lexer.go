@@ -0,0 +1,186 @@
+package bufformat
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	tokenTypeIdent tokenType = iota + 1
+	tokenTypeNumber
+	tokenTypeString
+	tokenTypePunct
+	tokenTypeComment
+)
+
+type tokenType int
+
+type token struct {
+	tokenType tokenType
+	// value is the text of the token as written.
+	value string
+	// line and column are 1-based, for errors.
+	line   int
+	column int
+	// newlinesBefore is the number of newlines between the previous token and
+	// this token, which is used to preserve line breaks and blank lines.
+	newlinesBefore int
+}
+
+func (t *token) isPunct(values ...string) bool {
+	if t.tokenType != tokenTypePunct {
+		return false
+	}
+	for _, value := range values {
+		if t.value == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *token) isLineComment() bool {
+	return t.tokenType == tokenTypeComment && strings.HasPrefix(t.value, "//")
+}
+
+// lexer splits Protobuf source into tokens, including comments.
+//
+// Identifiers include dots, so that foo.bar.Baz and .foo.Bar are single tokens.
+type lexer struct {
+	filePath string
+	data     string
+	offset   int
+	line     int
+	column   int
+}
+
+func newLexer(filePath string, data string) *lexer {
+	return &lexer{
+		filePath: filePath,
+		data:     data,
+		line:     1,
+		column:   1,
+	}
+}
+
+// tokenize returns all tokens.
+func (l *lexer) tokenize() ([]*token, error) {
+	var tokens []*token
+	for {
+		newlinesBefore := l.skipWhitespace()
+		if l.offset >= len(l.data) {
+			return tokens, nil
+		}
+		t := &token{
+			line:           l.line,
+			column:         l.column,
+			newlinesBefore: newlinesBefore,
+		}
+		start := l.offset
+		c := l.data[l.offset]
+		switch {
+		case strings.HasPrefix(l.data[l.offset:], "//"):
+			t.tokenType = tokenTypeComment
+			for l.offset < len(l.data) && l.data[l.offset] != '\n' {
+				l.advance()
+			}
+			t.value = strings.TrimRight(l.data[start:l.offset], " \t\r")
+			tokens = append(tokens, t)
+			continue
+		case strings.HasPrefix(l.data[l.offset:], "/*"):
+			t.tokenType = tokenTypeComment
+			end := strings.Index(l.data[l.offset+2:], "*/")
+			if end < 0 {
+				return nil, l.newError(t, "unterminated comment")
+			}
+			for l.offset < start+2+end+2 {
+				l.advance()
+			}
+		case c == '"' || c == '\'':
+			t.tokenType = tokenTypeString
+			l.advance()
+			for {
+				if l.offset >= len(l.data) || l.data[l.offset] == '\n' {
+					return nil, l.newError(t, "unterminated string")
+				}
+				if l.data[l.offset] == '\\' {
+					l.advance()
+					if l.offset >= len(l.data) {
+						return nil, l.newError(t, "unterminated string")
+					}
+				} else if l.data[l.offset] == c {
+					l.advance()
+					break
+				}
+				l.advance()
+			}
+		case isDigit(c) || (c == '.' && l.offset+1 < len(l.data) && isDigit(l.data[l.offset+1])):
+			t.tokenType = tokenTypeNumber
+			for l.offset < len(l.data) {
+				n := l.data[l.offset]
+				// exponents can have signs, such as 1e-5
+				if isIdentChar(n) || n == '.' || ((n == '-' || n == '+') && (l.data[l.offset-1] == 'e' || l.data[l.offset-1] == 'E')) {
+					l.advance()
+					continue
+				}
+				break
+			}
+		case isIdentStart(c) || (c == '.' && l.offset+1 < len(l.data) && isIdentStart(l.data[l.offset+1])):
+			t.tokenType = tokenTypeIdent
+			for l.offset < len(l.data) && (isIdentChar(l.data[l.offset]) || l.data[l.offset] == '.') {
+				l.advance()
+			}
+		default:
+			t.tokenType = tokenTypePunct
+			l.advance()
+		}
+		t.value = l.data[start:l.offset]
+		tokens = append(tokens, t)
+	}
+}
+
+// skipWhitespace skips whitespace and returns the number of newlines skipped.
+func (l *lexer) skipWhitespace() int {
+	newlines := 0
+	for l.offset < len(l.data) {
+		switch l.data[l.offset] {
+		case '\n':
+			newlines++
+		case ' ', '\t', '\r', '\v', '\f':
+		default:
+			return newlines
+		}
+		l.advance()
+	}
+	return newlines
+}
+
+func (l *lexer) advance() {
+	if l.data[l.offset] == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
+	l.offset++
+}
+
+func (l *lexer) newError(t *token, message string) error {
+	return newError(l.filePath, t, message)
+}
+
+func newError(filePath string, t *token, message string) error {
+	return fmt.Errorf("%s:%d:%d: %s", filePath, t.line, t.column, message)
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
@@ -0,0 +1,188 @@
+package bufformat
+
+// statement is a declaration, ending with a semicolon or a body.
+type statement struct {
+	// comments are the comments on the lines before the statement.
+	comments []*token
+	// tokens are the tokens of the statement, without the semicolon or the
+	// braces of the body.
+	//
+	// Comments within the statement are included.
+	tokens []*token
+	// body is the body of messages, enums, services, and the like, or nil if
+	// the statement ends with a semicolon.
+	body *block
+	// trailingComment is the comment on the same line after the statement, if any.
+	trailingComment *token
+	// end is the semicolon or the closing brace of the body.
+	end *token
+}
+
+// newlinesBefore returns the number of newlines before the statement,
+// including its comments.
+func (s *statement) newlinesBefore() int {
+	if len(s.comments) > 0 {
+		return s.comments[0].newlinesBefore
+	}
+	return s.tokens[0].newlinesBefore
+}
+
+func (s *statement) keyword() string {
+	return s.tokens[0].value
+}
+
+type block struct {
+	// open is the opening brace, or nil for the file.
+	open *token
+	// openComment is the comment on the same line after the opening brace, if any.
+	openComment *token
+	statements  []*statement
+	// closingComments are the comments after the last statement.
+	closingComments []*token
+	// close is the closing brace, or nil for the file.
+	close *token
+}
+
+type parser struct {
+	filePath string
+	tokens   []*token
+	index    int
+}
+
+// parse parses the tokens into the statements of the file.
+//
+// The parser only knows enough of the grammar to find statements, bodies, and
+// where comments belong, and does not validate the statements.
+func parse(filePath string, tokens []*token) (*block, error) {
+	p := &parser{
+		filePath: filePath,
+		tokens:   tokens,
+	}
+	return p.parseBlock(nil)
+}
+
+func (p *parser) parseBlock(open *token) (*block, error) {
+	b := &block{
+		open: open,
+	}
+	var comments []*token
+	for {
+		t := p.peek()
+		if t == nil {
+			if open != nil {
+				return nil, newError(p.filePath, open, "unclosed {")
+			}
+			b.closingComments = comments
+			return b, nil
+		}
+		p.index++
+		switch {
+		case t.tokenType == tokenTypeComment:
+			previous := p.previous()
+			switch {
+			case t.newlinesBefore > 0 || len(comments) > 0:
+				comments = append(comments, t)
+			case len(b.statements) > 0 && b.statements[len(b.statements)-1].end == previous && b.statements[len(b.statements)-1].trailingComment == nil:
+				b.statements[len(b.statements)-1].trailingComment = t
+			case len(b.statements) == 0 && open != nil && open == previous && b.openComment == nil:
+				b.openComment = t
+			default:
+				comments = append(comments, t)
+			}
+		case t.isPunct("}"):
+			if open == nil {
+				return nil, newError(p.filePath, t, "unexpected }")
+			}
+			b.closingComments = comments
+			b.close = t
+			return b, nil
+		case t.isPunct(";"):
+			// empty statements are dropped
+		default:
+			p.index--
+			s, err := p.parseStatement()
+			if err != nil {
+				return nil, err
+			}
+			s.comments = comments
+			comments = nil
+			b.statements = append(b.statements, s)
+		}
+	}
+}
+
+func (p *parser) parseStatement() (*statement, error) {
+	s := &statement{}
+	// nesting is the nesting of brackets, parentheses, and the braces of
+	// literals within the statement.
+	var nesting []*token
+	var previousNonComment *token
+	for {
+		t := p.peek()
+		if t == nil {
+			if len(nesting) > 0 {
+				return nil, newError(p.filePath, nesting[len(nesting)-1], "unclosed "+nesting[len(nesting)-1].value)
+			}
+			return nil, newError(p.filePath, s.tokens[0], "expected ; or { at the end of the statement")
+		}
+		p.index++
+		switch {
+		case t.isPunct("(", "["):
+			nesting = append(nesting, t)
+		case t.isPunct("{"):
+			// braces after = or : or within brackets are message literals,
+			// otherwise they open the body of the statement
+			if len(nesting) == 0 && (previousNonComment == nil || !previousNonComment.isPunct("=", ":")) {
+				if previousNonComment == nil {
+					return nil, newError(p.filePath, t, "unexpected {")
+				}
+				body, err := p.parseBlock(t)
+				if err != nil {
+					return nil, err
+				}
+				s.body = body
+				s.end = body.close
+				return s, nil
+			}
+			nesting = append(nesting, t)
+		case t.isPunct(")", "]", "}"):
+			if len(nesting) == 0 || !isMatchingClose(nesting[len(nesting)-1], t) {
+				return nil, newError(p.filePath, t, "unexpected "+t.value)
+			}
+			nesting = nesting[:len(nesting)-1]
+		case t.isPunct(";") && len(nesting) == 0:
+			s.end = t
+			return s, nil
+		}
+		s.tokens = append(s.tokens, t)
+		if t.tokenType != tokenTypeComment {
+			previousNonComment = t
+		}
+	}
+}
+
+func (p *parser) peek() *token {
+	if p.index >= len(p.tokens) {
+		return nil
+	}
+	return p.tokens[p.index]
+}
+
+// previous returns the token before the last consumed token.
+func (p *parser) previous() *token {
+	if p.index < 2 {
+		return nil
+	}
+	return p.tokens[p.index-2]
+}
+
+func isMatchingClose(open *token, close *token) bool {
+	switch open.value {
+	case "(":
+		return close.value == ")"
+	case "[":
+		return close.value == "]"
+	default:
+		return close.value == "}"
+	}
+}
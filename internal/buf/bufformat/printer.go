@@ -0,0 +1,256 @@
+package bufformat
+
+import (
+	"sort"
+	"strings"
+)
+
+const indentString = "  "
+
+type printer struct {
+	builder strings.Builder
+}
+
+func (p *printer) printFile(file *block) string {
+	statements, blankLinesBefore := orderFileStatements(file.statements)
+	p.printStatements(statements, blankLinesBefore, 0)
+	p.printClosingComments(file, 0)
+	return p.builder.String()
+}
+
+func (p *printer) printStatements(statements []*statement, blankLinesBefore []bool, indent int) {
+	for i, s := range statements {
+		p.printStatement(s, blankLinesBefore[i], indent)
+	}
+}
+
+func (p *printer) printStatement(s *statement, blankLineBefore bool, indent int) {
+	for i, comment := range s.comments {
+		if (i == 0 && blankLineBefore) || (i > 0 && comment.newlinesBefore > 1) {
+			p.printBlankLine()
+		}
+		p.printIndent(indent)
+		p.builder.WriteString(comment.value)
+		p.builder.WriteString("\n")
+	}
+	if len(s.comments) == 0 && blankLineBefore {
+		p.printBlankLine()
+	} else if len(s.comments) > 0 && s.tokens[0].newlinesBefore > 1 {
+		// comments detached from the statement stay detached
+		p.printBlankLine()
+	}
+	p.printIndent(indent)
+	p.printTokens(s, indent)
+	if s.body == nil {
+		p.builder.WriteString(";")
+	} else {
+		p.printBody(s.body, indent)
+	}
+	if s.trailingComment != nil {
+		p.builder.WriteString(" ")
+		p.builder.WriteString(s.trailingComment.value)
+	}
+	p.builder.WriteString("\n")
+}
+
+func (p *printer) printBody(body *block, indent int) {
+	if body.openComment == nil && len(body.statements) == 0 && len(body.closingComments) == 0 {
+		p.builder.WriteString(" {}")
+		return
+	}
+	p.builder.WriteString(" {")
+	if body.openComment != nil {
+		p.builder.WriteString(" ")
+		p.builder.WriteString(body.openComment.value)
+	}
+	p.builder.WriteString("\n")
+	statements, blankLinesBefore := orderBlockStatements(body.statements)
+	p.printStatements(statements, blankLinesBefore, indent+1)
+	p.printClosingComments(body, indent+1)
+	p.printIndent(indent)
+	p.builder.WriteString("}")
+}
+
+func (p *printer) printClosingComments(b *block, indent int) {
+	for i, comment := range b.closingComments {
+		if comment.newlinesBefore > 1 && (i > 0 || len(b.statements) > 0) {
+			p.printBlankLine()
+		}
+		p.printIndent(indent)
+		p.builder.WriteString(comment.value)
+		p.builder.WriteString("\n")
+	}
+}
+
+// printTokens prints the tokens of the statement separated by canonical
+// spacing, keeping line breaks within the statement.
+func (p *printer) printTokens(s *statement, indent int) {
+	nesting := 0
+	for i, t := range s.tokens {
+		closing := t.isPunct(")", "]", "}")
+		if closing {
+			nesting--
+		}
+		if i > 0 {
+			previous := s.tokens[i-1]
+			if t.newlinesBefore > 0 || previous.isLineComment() {
+				p.builder.WriteString("\n")
+				switch {
+				case nesting > 0:
+					p.printIndent(indent + nesting)
+				case closing:
+					p.printIndent(indent)
+				default:
+					// continuation lines outside of brackets are indented twice
+					p.printIndent(indent + 2)
+				}
+			} else if needsSpace(s, i) {
+				p.builder.WriteString(" ")
+			}
+		}
+		p.builder.WriteString(t.value)
+		if t.isPunct("(", "[", "{") {
+			nesting++
+		}
+	}
+}
+
+func (p *printer) printIndent(indent int) {
+	p.builder.WriteString(strings.Repeat(indentString, indent))
+}
+
+// printBlankLine prints a blank line unless there already is one or nothing
+// was printed yet.
+func (p *printer) printBlankLine() {
+	output := p.builder.String()
+	if output == "" || strings.HasSuffix(output, "\n\n") || strings.HasSuffix(output, "{\n") {
+		return
+	}
+	p.builder.WriteString("\n")
+}
+
+// needsSpace returns true if there should be a space between the token at the
+// index and the token before it.
+func needsSpace(s *statement, index int) bool {
+	previous := s.tokens[index-1]
+	t := s.tokens[index]
+	switch {
+	case previous.isPunct("(", "[", "<", "-", "/"):
+		return false
+	case t.isPunct(")", "]", ",", ";", ":", ">", "/"):
+		return false
+	case previous.isPunct("{") && t.isPunct("}"):
+		return false
+	case previous.isPunct(")") && strings.HasPrefix(t.value, "."):
+		// custom option names such as (foo).bar
+		return false
+	case t.isPunct("<"):
+		// map<string, Foo>
+		return previous.value != "map"
+	case t.isPunct("("):
+		// rpc Foo(Request), but option (foo) and returns (Response)
+		return !(index == 2 && s.keyword() == "rpc")
+	}
+	return true
+}
+
+// orderFileStatements orders the statements of the file as syntax, package,
+// imports sorted by path, options sorted by name, and then everything else in
+// the order written.
+//
+// The returned bools are whether there is a blank line before each statement:
+// groups are separated by blank lines, and blank lines are kept within the
+// group of everything else.
+func orderFileStatements(statements []*statement) ([]*statement, []bool) {
+	var syntaxes, packages, imports, options, others []*statement
+	for _, s := range statements {
+		switch s.keyword() {
+		case "syntax":
+			syntaxes = append(syntaxes, s)
+		case "package":
+			packages = append(packages, s)
+		case "import":
+			imports = append(imports, s)
+		case "option":
+			options = append(options, s)
+		default:
+			others = append(others, s)
+		}
+	}
+	sort.SliceStable(imports, func(i int, j int) bool {
+		return getImportPath(imports[i]) < getImportPath(imports[j])
+	})
+	sortOptions(options)
+	var ordered []*statement
+	var blankLinesBefore []bool
+	groups := [][]*statement{syntaxes, packages, imports, options, others}
+	for groupIndex, group := range groups {
+		keepBlankLines := groupIndex == len(groups)-1
+		for i, s := range group {
+			blankLinesBefore = append(blankLinesBefore, len(ordered) > 0 && (i == 0 || (keepBlankLines && s.newlinesBefore() > 1)))
+			ordered = append(ordered, s)
+		}
+	}
+	return ordered, blankLinesBefore
+}
+
+// orderBlockStatements orders the statements of a body as options sorted by
+// name and then everything else in the order written.
+func orderBlockStatements(statements []*statement) ([]*statement, []bool) {
+	var options, others []*statement
+	for _, s := range statements {
+		if s.keyword() == "option" {
+			options = append(options, s)
+		} else {
+			others = append(others, s)
+		}
+	}
+	sortOptions(options)
+	ordered := append(options, others...)
+	blankLinesBefore := make([]bool, len(ordered))
+	for i, s := range others {
+		if i == 0 {
+			blankLinesBefore[len(options)] = len(options) > 0
+		} else {
+			blankLinesBefore[len(options)+i] = s.newlinesBefore() > 1
+		}
+	}
+	return ordered, blankLinesBefore
+}
+
+// sortOptions sorts option statements by name, with standard options before
+// custom options.
+func sortOptions(options []*statement) {
+	sort.SliceStable(options, func(i int, j int) bool {
+		one := getOptionName(options[i])
+		two := getOptionName(options[j])
+		if oneCustom, twoCustom := strings.HasPrefix(one, "("), strings.HasPrefix(two, "("); oneCustom != twoCustom {
+			return twoCustom
+		}
+		return one < two
+	})
+}
+
+// getOptionName returns the name of the option, such as java_package or (foo).bar.
+func getOptionName(s *statement) string {
+	var builder strings.Builder
+	for _, t := range s.tokens[1:] {
+		if t.isPunct("=") {
+			break
+		}
+		if t.tokenType != tokenTypeComment {
+			builder.WriteString(t.value)
+		}
+	}
+	return builder.String()
+}
+
+// getImportPath returns the path of the import, without quotes.
+func getImportPath(s *statement) string {
+	for _, t := range s.tokens {
+		if t.tokenType == tokenTypeString {
+			return strings.Trim(t.value, `"'`)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,141 @@
+// Package bufgen generates code by running protoc plugins against images.
+//
+// The plugins are configured in a template, by default buf.gen.yaml:
+//
+//	plugins:
+//	  - name: go
+//	    out: gen/go
+//	    opt: paths=source_relative
+//	  - path: bin/protoc-gen-foo
+//	    out: gen/foo
+package bufgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/buf/bufplugin"
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/bufbuild/buf/internal/pkg/util/utilencoding"
+	"go.uber.org/zap"
+)
+
+// TemplateFilePath is the default template file path.
+const TemplateFilePath = "buf.gen.yaml"
+
+// pluginNamePrefix is the prefix of the executables of plugins referred to by name.
+const pluginNamePrefix = "protoc-gen-"
+
+// ExternalConfig is an external template.
+//
+// Should only be used outside this package for testing.
+type ExternalConfig struct {
+	Plugins []ExternalPluginConfig `json:"plugins,omitempty" yaml:"plugins,omitempty"`
+}
+
+// ExternalPluginConfig is an external plugin config.
+//
+// Exactly one of Name and Path must be set.
+//
+// Should only be used outside this package for testing.
+type ExternalPluginConfig struct {
+	// Name is the name of the plugin, which runs protoc-gen-NAME from the PATH.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Path is the path to the plugin executable.
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	// Out is the directory to write the generated files to.
+	//
+	// Required.
+	Out string `json:"out,omitempty" yaml:"out,omitempty"`
+	// Opt is the parameter passed to the plugin, as with --NAME_opt for protoc.
+	Opt string `json:"opt,omitempty" yaml:"opt,omitempty"`
+}
+
+// Config is a validated template.
+type Config struct {
+	// PluginConfigs are run in order.
+	PluginConfigs []*PluginConfig
+}
+
+// PluginConfig is a validated plugin config.
+type PluginConfig struct {
+	// Name is the name of the plugin for messages, either the name or the path
+	// of the external plugin config.
+	Name string
+	// Path is the name of the executable to look up on the PATH, or the path to
+	// the executable if it contains a path separator.
+	Path string
+	Out  string
+	Opt  string
+}
+
+// GetConfigForData validates the YAML or JSON template data and returns the Config.
+func GetConfigForData(data []byte) (*Config, error) {
+	externalConfig := &ExternalConfig{}
+	if err := utilencoding.UnmarshalJSONOrYAMLStrict(data, externalConfig); err != nil {
+		return nil, err
+	}
+	return newConfig(externalConfig)
+}
+
+func newConfig(externalConfig *ExternalConfig) (*Config, error) {
+	if len(externalConfig.Plugins) == 0 {
+		return nil, errors.New("plugins: at least one plugin must be set")
+	}
+	config := &Config{
+		PluginConfigs: make([]*PluginConfig, 0, len(externalConfig.Plugins)),
+	}
+	for i, externalPluginConfig := range externalConfig.Plugins {
+		name := strings.TrimSpace(externalPluginConfig.Name)
+		path := strings.TrimSpace(externalPluginConfig.Path)
+		out := strings.TrimSpace(externalPluginConfig.Out)
+		switch {
+		case name == "" && path == "":
+			return nil, fmt.Errorf("plugins: plugin %d: one of name or path must be set", i+1)
+		case name != "" && path != "":
+			return nil, fmt.Errorf("plugins: plugin %d: only one of name or path can be set", i+1)
+		case strings.ContainsAny(name, `/\`):
+			return nil, fmt.Errorf("plugins: plugin %d: name %q must not contain a path separator, use path instead", i+1, name)
+		}
+		pluginConfig := &PluginConfig{
+			Name: name,
+			Path: path,
+			Out:  out,
+			Opt:  externalPluginConfig.Opt,
+		}
+		if name != "" {
+			pluginConfig.Path = pluginNamePrefix + name
+		} else {
+			pluginConfig.Name = path
+		}
+		if out == "" {
+			return nil, fmt.Errorf("plugins: %s: out must be set", pluginConfig.Name)
+		}
+		config.PluginConfigs = append(config.PluginConfigs, pluginConfig)
+	}
+	return config, nil
+}
+
+// Generator generates code.
+type Generator interface {
+	// Generate runs the plugins of the config against the image and writes the
+	// generated files to the out directories of the plugins.
+	//
+	// Code is generated for the files of the image that are not imports, but
+	// the image should include imports so that plugins can resolve all types.
+	// Files are only written once all plugins succeed. Relative out directories
+	// and plugin paths are relative to the current working directory.
+	Generate(
+		ctx context.Context,
+		getenv func(string) string,
+		config *Config,
+		image *imagev1beta1.Image,
+	) error
+}
+
+// NewGenerator returns a new Generator that runs plugins with the executor.
+func NewGenerator(logger *zap.Logger, executor bufplugin.Executor) Generator {
+	return newGenerator(logger, executor)
+}
@@ -0,0 +1,269 @@
+package bufgen
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/buf/internal/buf/bufplugin"
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	plugin_go "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// testPluginEnvKey is the environment variable that makes the test binary act as a plugin.
+const testPluginEnvKey = "BUF_GEN_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(testPluginEnvKey) != "" {
+		os.Exit(runTestPlugin())
+	}
+	os.Exit(m.Run())
+}
+
+func TestGetConfigForData(t *testing.T) {
+	t.Parallel()
+	config, err := GetConfigForData([]byte(`
+plugins:
+  - name: go
+    out: gen/go
+    opt: paths=source_relative
+  - path: bin/protoc-gen-foo
+    out: gen/foo
+`))
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		&Config{
+			PluginConfigs: []*PluginConfig{
+				{
+					Name: "go",
+					Path: "protoc-gen-go",
+					Out:  "gen/go",
+					Opt:  "paths=source_relative",
+				},
+				{
+					Name: "bin/protoc-gen-foo",
+					Path: "bin/protoc-gen-foo",
+					Out:  "gen/foo",
+				},
+			},
+		},
+		config,
+	)
+	for _, data := range []string{
+		``,
+		`plugins: []`,
+		`plugins: [{out: gen}]`,
+		`plugins: [{name: go, path: protoc-gen-go, out: gen}]`,
+		`plugins: [{name: bin/go, out: gen}]`,
+		`plugins: [{name: go}]`,
+		`plugins: [{name: go, out: gen, opts: foo}]`,
+	} {
+		_, err := GetConfigForData([]byte(data))
+		assert.Error(t, err, data)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+	outDirPath, err := ioutil.TempDir("", "bufgen")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(outDirPath))
+	}()
+	config := &Config{
+		PluginConfigs: []*PluginConfig{
+			{
+				Name: "generate",
+				Path: testPluginPath(t),
+				Out:  outDirPath,
+				Opt:  "generate",
+			},
+			{
+				Name: "insert",
+				Path: testPluginPath(t),
+				Out:  outDirPath,
+				Opt:  "insert",
+			},
+		},
+	}
+	err = newTestGenerator().Generate(context.Background(), newTestGetenv(), config, newTestImage())
+	require.NoError(t, err)
+	data, err := ioutil.ReadFile(filepath.Join(outDirPath, "a", "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`parameter: generate
+files: a/a.proto
+  // inserted
+  // second
+  // @@protoc_insertion_point(imports)
+appended
+`,
+		string(data),
+	)
+	_, err = os.Stat(filepath.Join(outDirPath, "b", "b.txt"))
+	assert.True(t, os.IsNotExist(err), "imports must not be generated")
+}
+
+func TestGenerateNothingWrittenOnFailure(t *testing.T) {
+	t.Parallel()
+	outDirPath, err := ioutil.TempDir("", "bufgen")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(outDirPath))
+	}()
+	for _, failingPluginName := range []string{"error", "generate", "insert_missing"} {
+		config := &Config{
+			PluginConfigs: []*PluginConfig{
+				{
+					Name: "generate",
+					Path: testPluginPath(t),
+					Out:  outDirPath,
+					Opt:  "generate",
+				},
+				{
+					Name: failingPluginName,
+					Path: testPluginPath(t),
+					Out:  outDirPath,
+					Opt:  failingPluginName,
+				},
+			},
+		}
+		err := newTestGenerator().Generate(context.Background(), newTestGetenv(), config, newTestImage())
+		assert.Error(t, err, failingPluginName)
+		fileInfos, err := ioutil.ReadDir(outDirPath)
+		require.NoError(t, err)
+		assert.Empty(t, fileInfos, failingPluginName)
+	}
+}
+
+func TestInsert(t *testing.T) {
+	t.Parallel()
+	content, err := insert("a\n\t// @@protoc_insertion_point(x)\nb\n", "x", "c\n\nd")
+	require.NoError(t, err)
+	assert.Equal(t, "a\n\tc\n\n\td\n\t// @@protoc_insertion_point(x)\nb\n", content)
+	_, err = insert("a\n", "x", "c\n")
+	assert.Error(t, err)
+}
+
+// runTestPlugin runs the test binary as a plugin, which determines what
+// to generate by the parameter.
+func runTestPlugin() int {
+	requestData, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	request := &plugin_go.CodeGeneratorRequest{}
+	if err := proto.Unmarshal(requestData, request); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	response := &plugin_go.CodeGeneratorResponse{}
+	switch mode := request.GetParameter(); mode {
+	case "generate":
+		for _, fileToGenerate := range request.GetFileToGenerate() {
+			response.File = append(
+				response.File,
+				&plugin_go.CodeGeneratorResponse_File{
+					Name: proto.String(strings.TrimSuffix(fileToGenerate, ".proto") + ".txt"),
+					Content: proto.String(
+						fmt.Sprintf(
+							"parameter: %s\nfiles: %s\n  // @@protoc_insertion_point(imports)\n",
+							request.GetParameter(),
+							strings.Join(request.GetFileToGenerate(), ","),
+						),
+					),
+				},
+				&plugin_go.CodeGeneratorResponse_File{
+					Content: proto.String("appended\n"),
+				},
+			)
+		}
+	case "insert", "insert_missing":
+		name := "a/a.txt"
+		if mode == "insert_missing" {
+			name = "a/missing.txt"
+		}
+		response.File = []*plugin_go.CodeGeneratorResponse_File{
+			{
+				Name:           proto.String(name),
+				InsertionPoint: proto.String("imports"),
+				Content:        proto.String("// inserted\n"),
+			},
+			{
+				Content: proto.String("// second\n"),
+			},
+		}
+	case "error":
+		response.Error = proto.String("bad request")
+	default:
+		fmt.Fprintf(os.Stderr, "unknown mode %q\n", mode)
+		return 1
+	}
+	responseData, err := proto.Marshal(response)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	if _, err := os.Stdout.Write(responseData); err != nil {
+		return 1
+	}
+	return 0
+}
+
+func newTestGenerator() Generator {
+	return NewGenerator(zap.NewNop(), bufplugin.NewExecutor(zap.NewNop(), bufplugin.ExecutorWithEnvKeys(testPluginEnvKey)))
+}
+
+func newTestGetenv() func(string) string {
+	return func(key string) string {
+		switch key {
+		case testPluginEnvKey:
+			return "1"
+		case "PATH":
+			return os.Getenv("PATH")
+		default:
+			return ""
+		}
+	}
+}
+
+func newTestImage() *imagev1beta1.Image {
+	return &imagev1beta1.Image{
+		File: []*descriptor.FileDescriptorProto{
+			{
+				Name:    proto.String("b/b.proto"),
+				Package: proto.String("b"),
+			},
+			{
+				Name:       proto.String("a/a.proto"),
+				Package:    proto.String("a"),
+				Dependency: []string{"b/b.proto"},
+			},
+		},
+		BufbuildImageExtension: &imagev1beta1.ImageExtension{
+			ImageImportRefs: []*imagev1beta1.ImageImportRef{
+				{
+					FileIndex: proto.Uint32(0),
+				},
+			},
+		},
+	}
+}
+
+func testPluginPath(t *testing.T) string {
+	pluginPath, err := filepath.Abs(os.Args[0])
+	require.NoError(t, err)
+	return pluginPath
+}
@@ -0,0 +1,231 @@
+package bufgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/buf/bufplugin"
+	"github.com/bufbuild/buf/internal/buf/ext/extimage"
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagepath"
+	"github.com/bufbuild/buf/internal/pkg/util/utillog"
+	"github.com/golang/protobuf/proto"
+	plugin_go "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"go.uber.org/zap"
+)
+
+type generator struct {
+	logger   *zap.Logger
+	executor bufplugin.Executor
+}
+
+func newGenerator(logger *zap.Logger, executor bufplugin.Executor) *generator {
+	return &generator{
+		logger:   logger.Named("bufgen"),
+		executor: executor,
+	}
+}
+
+func (g *generator) Generate(
+	ctx context.Context,
+	getenv func(string) string,
+	config *Config,
+	image *imagev1beta1.Image,
+) error {
+	defer utillog.Defer(g.logger, "generate")()
+
+	fileToGenerate, err := getFileToGenerate(image)
+	if err != nil {
+		return err
+	}
+	// the generated files of all plugins are kept in memory so that nothing is
+	// written if any plugin fails, and so that later plugins can insert into
+	// the files of earlier plugins with the same out directory
+	outToOutput := make(map[string]*output)
+	var outputs []*output
+	for _, pluginConfig := range config.PluginConfigs {
+		request, err := extimage.ImageToCodeGeneratorRequest(image, pluginConfig.Opt, fileToGenerate...)
+		if err != nil {
+			return err
+		}
+		pluginPath, err := getPluginPath(pluginConfig.Path)
+		if err != nil {
+			return err
+		}
+		response, err := g.executor.Execute(ctx, getenv, pluginPath, request)
+		if err != nil {
+			return err
+		}
+		out := filepath.Clean(pluginConfig.Out)
+		output, ok := outToOutput[out]
+		if !ok {
+			output = newOutput(out)
+			outToOutput[out] = output
+			outputs = append(outputs, output)
+		}
+		if err := output.add(pluginConfig.Name, response.GetFile()); err != nil {
+			return err
+		}
+	}
+	for _, output := range outputs {
+		if err := output.write(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// output is the generated files of one out directory.
+type output struct {
+	out           string
+	pathToFile    map[string]*generatedFile
+	orderedFiles  []*generatedFile
+	numPluginRuns int
+}
+
+type generatedFile struct {
+	path    string
+	content string
+	// pluginRun is the index of the plugin run that generated the file, so that
+	// a file cannot be generated by multiple plugins.
+	pluginRun int
+}
+
+func newOutput(out string) *output {
+	return &output{
+		out:        out,
+		pathToFile: make(map[string]*generatedFile),
+	}
+}
+
+// add adds the files of the response of the named plugin.
+//
+// As with protoc, a file with an insertion point is inserted into a file that
+// was already generated, and the content of a file without a name continues
+// the previous file of the response.
+func (o *output) add(pluginName string, files []*plugin_go.CodeGeneratorResponse_File) error {
+	o.numPluginRuns++
+	var mergedFiles []*plugin_go.CodeGeneratorResponse_File
+	for _, file := range files {
+		if file.GetName() == "" {
+			if len(mergedFiles) == 0 {
+				return fmt.Errorf("plugin %s: first file must have a name", pluginName)
+			}
+			previous := mergedFiles[len(mergedFiles)-1]
+			previous.Content = proto.String(previous.GetContent() + file.GetContent())
+			continue
+		}
+		mergedFiles = append(mergedFiles, proto.Clone(file).(*plugin_go.CodeGeneratorResponse_File))
+	}
+	for _, file := range mergedFiles {
+		path, err := storagepath.NormalizeAndValidate(file.GetName())
+		if err != nil {
+			return fmt.Errorf("plugin %s: %v", pluginName, err)
+		}
+		if file.GetInsertionPoint() != "" {
+			existing, ok := o.pathToFile[path]
+			if !ok {
+				return fmt.Errorf("plugin %s: insertion point %q is in file %q which was not generated", pluginName, file.GetInsertionPoint(), path)
+			}
+			content, err := insert(existing.content, file.GetInsertionPoint(), file.GetContent())
+			if err != nil {
+				return fmt.Errorf("plugin %s: %s: %v", pluginName, path, err)
+			}
+			existing.content = content
+			continue
+		}
+		if existing, ok := o.pathToFile[path]; ok {
+			if existing.pluginRun == o.numPluginRuns {
+				return fmt.Errorf("plugin %s: generated file %q multiple times", pluginName, path)
+			}
+			return fmt.Errorf("plugin %s: generated file %q which was already generated by another plugin in %s", pluginName, path, o.out)
+		}
+		generatedFile := &generatedFile{
+			path:      path,
+			content:   file.GetContent(),
+			pluginRun: o.numPluginRuns,
+		}
+		o.pathToFile[path] = generatedFile
+		o.orderedFiles = append(o.orderedFiles, generatedFile)
+	}
+	return nil
+}
+
+func (o *output) write() error {
+	for _, generatedFile := range o.orderedFiles {
+		filePath := filepath.Join(o.out, storagepath.Unnormalize(generatedFile.path))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filePath, []byte(generatedFile.content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insert inserts the insertion immediately above the line that contains the
+// insertion point, with each line of the insertion indented by the indentation
+// of that line.
+func insert(content string, insertionPoint string, insertion string) (string, error) {
+	index := strings.Index(content, "@@protoc_insertion_point("+insertionPoint+")")
+	if index < 0 {
+		return "", fmt.Errorf("insertion point %q not found", insertionPoint)
+	}
+	lineStart := strings.LastIndexByte(content[:index], '\n') + 1
+	line := content[lineStart:index]
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	if insertion != "" && !strings.HasSuffix(insertion, "\n") {
+		insertion += "\n"
+	}
+	var builder strings.Builder
+	builder.WriteString(content[:lineStart])
+	for _, insertionLine := range strings.SplitAfter(insertion, "\n") {
+		if insertionLine == "" {
+			continue
+		}
+		if insertionLine != "\n" {
+			builder.WriteString(indent)
+		}
+		builder.WriteString(insertionLine)
+	}
+	builder.WriteString(content[lineStart:])
+	return builder.String(), nil
+}
+
+// getFileToGenerate returns the names of the files of the image that are not imports.
+func getFileToGenerate(image *imagev1beta1.Image) ([]string, error) {
+	importNames, err := extimage.ImageImportNames(image)
+	if err != nil {
+		return nil, err
+	}
+	importNameMap := make(map[string]struct{}, len(importNames))
+	for _, importName := range importNames {
+		importNameMap[importName] = struct{}{}
+	}
+	var fileToGenerate []string
+	for _, file := range image.GetFile() {
+		if _, ok := importNameMap[file.GetName()]; !ok {
+			fileToGenerate = append(fileToGenerate, file.GetName())
+		}
+	}
+	if len(fileToGenerate) == 0 {
+		return nil, errors.New("no files to generate")
+	}
+	return fileToGenerate, nil
+}
+
+// getPluginPath returns the absolute path of plugin paths with a path separator,
+// as plugins are run in a temporary working directory.
+func getPluginPath(path string) (string, error) {
+	path = filepath.FromSlash(path)
+	if !strings.ContainsRune(path, os.PathSeparator) {
+		return path, nil
+	}
+	return filepath.Abs(path)
+}
@@ -0,0 +1,66 @@
+// Package buflsp provides a Language Server Protocol server for .proto files.
+//
+// The server publishes build errors and lint violations as diagnostics, and
+// provides go to definition and hover for the messages and enums referenced by
+// fields and methods, including those defined in imports.
+//
+// Diagnostics are updated when the client is initialized and whenever a file
+// is opened, changed, saved, or closed. Changes are synced in full, and the
+// contents of the files open in the client are checked instead of the files on
+// disk, so that unsaved changes are checked as they are made. Definitions and
+// hovers use the Image of the last successful build, so positions are those of
+// the files as of the last change that built.
+//
+// Messages are read and written with the Content-Length headers of the Language
+// Server Protocol base protocol, usually over stdin and stdout.
+package buflsp
+
+import (
+	"context"
+	"io"
+
+	"github.com/bufbuild/buf/internal/buf/bufbuild"
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"go.uber.org/zap"
+)
+
+// Checker builds and lints the input.
+//
+// The pathToData are the contents of the files open in the client by absolute
+// path, which must be used instead of the files on disk as they may have unsaved
+// changes. The map must not be modified.
+type Checker func(ctx context.Context, pathToData map[string][]byte) (*CheckResult, error)
+
+// CheckResult is the result of a check.
+type CheckResult struct {
+	// Image is the Image built from the input, with imports and source code info.
+	//
+	// Nil if the input did not build.
+	Image *imagev1beta1.Image
+	// Resolver resolves the real file paths of the files in the Image.
+	//
+	// Files without a real file path, such as imports from outside the input,
+	// cannot be navigated to.
+	Resolver bufbuild.ProtoRealFilePathResolver
+	// FileAnnotations are the build errors or lint violations.
+	//
+	// The paths must be real file paths, either absolute or relative to the
+	// current working directory.
+	FileAnnotations []*filev1beta1.FileAnnotation
+}
+
+// Server is a Language Server Protocol server.
+type Server interface {
+	// Serve serves a client until the client sends the exit notification, the
+	// reader is closed, or the context is done.
+	//
+	// Messages from the client are read from the reader, and messages to the
+	// client are written to the writer.
+	Serve(ctx context.Context, reader io.Reader, writer io.Writer) error
+}
+
+// NewServer returns a new Server.
+func NewServer(logger *zap.Logger, checker Checker) Server {
+	return newServer(logger, checker)
+}
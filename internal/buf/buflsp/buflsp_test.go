@@ -0,0 +1,234 @@
+package buflsp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/bufbuild/buf/internal/pkg/util/utillsp"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const (
+	testAFileContent = `syntax = "proto3";
+
+package a.v1;
+
+import "b/v1/b.proto";
+
+// A is an a.
+message A {
+  // The b.
+  b.v1.B b = 1;
+  map<string, b.v1.B> bs = 2;
+  repeated string names = 3;
+}
+
+service AService {
+  // GetA gets an A.
+  rpc GetA(b.v1.B) returns (stream A);
+}
+`
+	testBFileContent = `syntax = "proto3";
+
+package b.v1;
+
+// B is a b.
+//
+// Bs are great.
+message B {}
+`
+)
+
+func TestServe(t *testing.T) {
+	t.Parallel()
+	image := testNewImage(t)
+	aURI, err := utillsp.PathToURI("testdata/a/v1/a.proto")
+	require.NoError(t, err)
+	bURI, err := utillsp.PathToURI("testdata/b/v1/b.proto")
+	require.NoError(t, err)
+
+	checkCount := 0
+	checker := func(ctx context.Context, pathToData map[string][]byte) (*CheckResult, error) {
+		checkCount++
+		if checkCount > 1 {
+			// the index of the previous successful build is kept
+			return &CheckResult{}, nil
+		}
+		return &CheckResult{
+			Image: image,
+			Resolver: testResolver{
+				"a/v1/a.proto": "testdata/a/v1/a.proto",
+				"b/v1/b.proto": "testdata/b/v1/b.proto",
+			},
+			FileAnnotations: []*filev1beta1.FileAnnotation{
+				{
+					Path:        "testdata/a/v1/a.proto",
+					StartLine:   12,
+					StartColumn: 23,
+					EndLine:     12,
+					EndColumn:   25,
+					Type:        "FIELD_LOWER_SNAKE_CASE",
+					Message:     "Field name is bad.",
+				},
+			},
+		}, nil
+	}
+
+	input := bytes.NewBuffer(nil)
+	for _, message := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"initialized","params":{}}`,
+		// b.v1.B in the type of field b
+		`{"jsonrpc":"2.0","id":2,"method":"textDocument/definition","params":{"textDocument":{"uri":"` + aURI + `"},"position":{"line":9,"character":6}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"textDocument/hover","params":{"textDocument":{"uri":"` + aURI + `"},"position":{"line":9,"character":6}}}`,
+		// the name of field bs
+		`{"jsonrpc":"2.0","id":4,"method":"textDocument/hover","params":{"textDocument":{"uri":"` + aURI + `"},"position":{"line":10,"character":23}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"textDocument/definition","params":{"textDocument":{"uri":"` + aURI + `"},"position":{"line":10,"character":23}}}`,
+		// the name of field names
+		`{"jsonrpc":"2.0","id":6,"method":"textDocument/definition","params":{"textDocument":{"uri":"` + aURI + `"},"position":{"line":11,"character":20}}}`,
+		// the name of method GetA
+		`{"jsonrpc":"2.0","id":7,"method":"textDocument/hover","params":{"textDocument":{"uri":"` + aURI + `"},"position":{"line":16,"character":7}}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didSave","params":{}}`,
+		// A in the output type of method GetA
+		`{"jsonrpc":"2.0","id":8,"method":"textDocument/definition","params":{"textDocument":{"uri":"` + aURI + `"},"position":{"line":16,"character":35}}}`,
+		`{"jsonrpc":"2.0","id":9,"method":"textDocument/hover","params":{"textDocument":{"uri":"` + aURI + `"},"position":{"line":0,"character":0}}}`,
+		`{"jsonrpc":"2.0","id":10,"method":"textDocument/hover","params":{}}`,
+		`{"jsonrpc":"2.0","id":11,"method":"foo"}`,
+		`{"jsonrpc":"2.0","id":12,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+		`{"jsonrpc":"2.0","id":13,"method":"shutdown"}`,
+	} {
+		_, err := fmt.Fprintf(input, "Content-Length: %d\r\nContent-Type: application/vscode-jsonrpc; charset=utf-8\r\n\r\n%s", len(message), message)
+		require.NoError(t, err)
+	}
+	output := bytes.NewBuffer(nil)
+	require.NoError(t, NewServer(zap.NewNop(), checker).Serve(context.Background(), input, output))
+
+	reader := bufio.NewReader(output)
+	for _, expected := range []string{
+		`{"jsonrpc":"2.0","id":1,"result":{"capabilities":{"textDocumentSync":{"openClose":true,"change":1,"save":true},"definitionProvider":true,"hoverProvider":true},"serverInfo":{"name":"buf"}}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"` + aURI + `","diagnostics":[{"range":{"start":{"line":11,"character":22},"end":{"line":11,"character":24}},"severity":1,"code":"FIELD_LOWER_SNAKE_CASE","source":"buf","message":"Field name is bad."}]}}`,
+		`{"jsonrpc":"2.0","id":2,"result":{"uri":"` + bURI + `","range":{"start":{"line":7,"character":8},"end":{"line":7,"character":9}}}}`,
+		`{"jsonrpc":"2.0","id":3,"result":{"contents":{"kind":"markdown","value":"` + "```proto\\nmessage b.v1.B\\n```\\n\\nB is a b.\\n\\nBs are great." + `"},"range":{"start":{"line":9,"character":2},"end":{"line":9,"character":8}}}}`,
+		`{"jsonrpc":"2.0","id":4,"result":{"contents":{"kind":"markdown","value":"` + "```proto\\nmap<string, b.v1.B> bs = 2;\\n```" + `"},"range":{"start":{"line":10,"character":2},"end":{"line":10,"character":29}}}}`,
+		`{"jsonrpc":"2.0","id":5,"result":{"uri":"` + bURI + `","range":{"start":{"line":7,"character":8},"end":{"line":7,"character":9}}}}`,
+		`{"jsonrpc":"2.0","id":6,"result":null}`,
+		`{"jsonrpc":"2.0","id":7,"result":{"contents":{"kind":"markdown","value":"` + "```proto\\nrpc GetA(b.v1.B) returns (stream a.v1.A);\\n```\\n\\nGetA gets an A." + `"},"range":{"start":{"line":16,"character":2},"end":{"line":16,"character":38}}}}`,
+		// the diagnostics are cleared by the second check
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"` + aURI + `","diagnostics":[]}}`,
+		`{"jsonrpc":"2.0","id":8,"result":{"uri":"` + aURI + `","range":{"start":{"line":7,"character":8},"end":{"line":7,"character":9}}}}`,
+		`{"jsonrpc":"2.0","id":9,"result":null}`,
+		`{"jsonrpc":"2.0","id":10,"error":{"code":-32602,"message":"invalid params for textDocument/hover"}}`,
+		`{"jsonrpc":"2.0","id":11,"error":{"code":-32601,"message":"method not found: \"foo\""}}`,
+		`{"jsonrpc":"2.0","id":12,"result":null}`,
+	} {
+		data, err := utillsp.ReadMessage(reader)
+		require.NoError(t, err)
+		assert.JSONEq(t, expected, string(data))
+	}
+	_, err = utillsp.ReadMessage(reader)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestServeUnsavedChanges(t *testing.T) {
+	t.Parallel()
+	aURI, err := utillsp.PathToURI("testdata/a/v1/a.proto")
+	require.NoError(t, err)
+	aPath, err := filepath.Abs("testdata/a/v1/a.proto")
+	require.NoError(t, err)
+
+	// the file on disk is fine, and only unsaved contents of "bad" are a violation
+	checker := func(ctx context.Context, pathToData map[string][]byte) (*CheckResult, error) {
+		data, ok := pathToData[aPath]
+		if !ok || string(data) != "bad" {
+			return &CheckResult{}, nil
+		}
+		return &CheckResult{
+			FileAnnotations: []*filev1beta1.FileAnnotation{
+				{
+					Path:      "testdata/a/v1/a.proto",
+					StartLine: 1,
+					Type:      "BAD",
+				},
+			},
+		}, nil
+	}
+
+	input := bytes.NewBuffer(nil)
+	for _, message := range []string{
+		`{"jsonrpc":"2.0","method":"initialized","params":{}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"` + aURI + `","languageId":"proto","version":1,"text":"good"}}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":"` + aURI + `","version":2},"contentChanges":[{"text":"bad"}]}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":"` + aURI + `","version":3},"contentChanges":[{"text":"good"}]}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":"` + aURI + `","version":4},"contentChanges":[{"text":"good"},{"text":"bad"}]}}`,
+		// closing the file discards the unsaved changes
+		`{"jsonrpc":"2.0","method":"textDocument/didClose","params":{"textDocument":{"uri":"` + aURI + `"}}}`,
+		`{"jsonrpc":"2.0","id":1,"method":"textDocument/didChange","params":{}}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	} {
+		_, err := fmt.Fprintf(input, "Content-Length: %d\r\n\r\n%s", len(message), message)
+		require.NoError(t, err)
+	}
+	output := bytes.NewBuffer(nil)
+	require.NoError(t, NewServer(zap.NewNop(), checker).Serve(context.Background(), input, output))
+
+	reader := bufio.NewReader(output)
+	for _, expected := range []string{
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"` + aURI + `","diagnostics":[{"range":{"start":{"line":0,"character":0},"end":{"line":0,"character":0}},"severity":1,"code":"BAD","source":"buf","message":"BAD"}]}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"` + aURI + `","diagnostics":[]}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"` + aURI + `","diagnostics":[{"range":{"start":{"line":0,"character":0},"end":{"line":0,"character":0}},"severity":1,"code":"BAD","source":"buf","message":"BAD"}]}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"` + aURI + `","diagnostics":[]}}`,
+		`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"invalid params for textDocument/didChange"}}`,
+	} {
+		data, err := utillsp.ReadMessage(reader)
+		require.NoError(t, err)
+		assert.JSONEq(t, expected, string(data))
+	}
+	_, err = utillsp.ReadMessage(reader)
+	assert.Equal(t, io.EOF, err)
+}
+
+type testResolver map[string]string
+
+func (r testResolver) GetRealFilePath(rootFilePath string) (string, error) {
+	return r[rootFilePath], nil
+}
+
+func testNewImage(t *testing.T) *imagev1beta1.Image {
+	fileDescriptors, err := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(
+			map[string]string{
+				"a/v1/a.proto": testAFileContent,
+				"b/v1/b.proto": testBFileContent,
+			},
+		),
+		IncludeSourceCodeInfo: true,
+	}.ParseFiles("a/v1/a.proto")
+	require.NoError(t, err)
+	require.Len(t, fileDescriptors, 1)
+	return &imagev1beta1.Image{
+		File: []*descriptor.FileDescriptorProto{
+			fileDescriptors[0].GetDependencies()[0].AsFileDescriptorProto(),
+			fileDescriptors[0].AsFileDescriptorProto(),
+		},
+		BufbuildImageExtension: &imagev1beta1.ImageExtension{
+			ImageImportRefs: []*imagev1beta1.ImageImportRef{
+				{
+					FileIndex: proto.Uint32(0),
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,398 @@
+package buflsp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/buf/bufbuild"
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/bufbuild/buf/internal/pkg/protodesc"
+	"github.com/bufbuild/buf/internal/pkg/util/utillsp"
+)
+
+// index is an index of the messages and enums of an Image, and of the elements
+// of the files with a real file path that refer to them.
+type index struct {
+	// uriKeyToElements are the elements of the files by URI key.
+	uriKeyToElements map[string][]*element
+	// fullNameToDefinition are the messages and enums of all files, including
+	// imports, by fully-qualified name.
+	fullNameToDefinition map[string]*definition
+	// fullNameToMapEntry are the map entry messages by fully-qualified name.
+	fullNameToMapEntry map[string]protodesc.Message
+}
+
+// definition is the definition of a message or enum.
+type definition struct {
+	// uri is empty if the file does not have a real file path.
+	uri       string
+	location  protodesc.Location
+	signature string
+	comments  string
+}
+
+// element is a part of a file that can be hovered over or navigated from.
+type element struct {
+	location protodesc.Location
+	// definitionFullName is the fully-qualified name of the message or enum
+	// that the element refers to, if any.
+	definitionFullName string
+	// signature and comments are shown on hover if the signature is set,
+	// otherwise the definition is shown.
+	signature string
+	comments  string
+}
+
+func newIndex(
+	ctx context.Context,
+	image *imagev1beta1.Image,
+	resolver bufbuild.ProtoRealFilePathResolver,
+) (*index, error) {
+	files, err := protodesc.NewFilesUnstable(ctx, image.File...)
+	if err != nil {
+		return nil, err
+	}
+	index := &index{
+		uriKeyToElements:     make(map[string][]*element),
+		fullNameToDefinition: make(map[string]*definition),
+		fullNameToMapEntry:   make(map[string]protodesc.Message),
+	}
+	fileToURI := make(map[protodesc.File]string, len(files))
+	for _, file := range files {
+		uri, err := getFileURI(file, resolver)
+		if err != nil {
+			return nil, err
+		}
+		fileToURI[file] = uri
+		if err := protodesc.ForEachMessage(
+			func(message protodesc.Message) error {
+				if message.IsMapEntry() {
+					index.fullNameToMapEntry[message.FullName()] = message
+					return nil
+				}
+				index.addDefinition(uri, message, "message")
+				return nil
+			},
+			file,
+		); err != nil {
+			return nil, err
+		}
+		if err := protodesc.ForEachEnum(
+			func(enum protodesc.Enum) error {
+				index.addDefinition(uri, enum, "enum")
+				return nil
+			},
+			file,
+		); err != nil {
+			return nil, err
+		}
+	}
+	for _, file := range files {
+		if uri := fileToURI[file]; uri != "" {
+			if err := index.addElements(uri, file); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return index, nil
+}
+
+// getDefinition gets the location of the definition of the message or enum
+// referred to at the position, or nil if there is none.
+func (i *index) getDefinition(uri string, position *utillsp.Position) *location {
+	element := i.getElement(uri, position)
+	if element == nil {
+		return nil
+	}
+	definition, ok := i.fullNameToDefinition[element.definitionFullName]
+	if !ok || definition.uri == "" || definition.location == nil {
+		return nil
+	}
+	return &location{
+		URI:   definition.uri,
+		Range: locationToRange(definition.location),
+	}
+}
+
+// getHover gets the hover for the position, or nil if there is none.
+func (i *index) getHover(uri string, position *utillsp.Position) *hover {
+	element := i.getElement(uri, position)
+	if element == nil {
+		return nil
+	}
+	signature := element.signature
+	comments := element.comments
+	if signature == "" {
+		definition, ok := i.fullNameToDefinition[element.definitionFullName]
+		if !ok {
+			return nil
+		}
+		signature = definition.signature
+		comments = definition.comments
+	}
+	value := "```proto\n" + signature + "\n```"
+	if comments != "" {
+		value += "\n\n" + comments
+	}
+	return &hover{
+		Contents: &markupContent{
+			Kind:  markupKindMarkdown,
+			Value: value,
+		},
+		Range: locationToRange(element.location),
+	}
+}
+
+// getElement gets the innermost element at the position.
+func (i *index) getElement(uri string, position *utillsp.Position) *element {
+	// locations are one-based
+	line := position.Line + 1
+	column := position.Character + 1
+	var innermostElement *element
+	for _, element := range i.uriKeyToElements[utillsp.URIKey(uri)] {
+		if !locationContains(element.location, line, column) {
+			continue
+		}
+		if innermostElement == nil || locationSize(element.location) < locationSize(innermostElement.location) {
+			innermostElement = element
+		}
+	}
+	return innermostElement
+}
+
+func (i *index) addDefinition(uri string, namedDescriptor protodesc.NamedDescriptor, kind string) {
+	i.fullNameToDefinition[namedDescriptor.FullName()] = &definition{
+		uri:       uri,
+		location:  namedDescriptor.NameLocation(),
+		signature: kind + " " + namedDescriptor.FullName(),
+		comments:  getComments(namedDescriptor.Location()),
+	}
+}
+
+func (i *index) addElements(uri string, file protodesc.File) error {
+	uriKey := utillsp.URIKey(uri)
+	addElement := func(element *element) {
+		if element.location != nil {
+			i.uriKeyToElements[uriKey] = append(i.uriKeyToElements[uriKey], element)
+		}
+	}
+	addFieldElements := func(field protodesc.Field) {
+		definitionFullName := i.getFieldDefinitionFullName(field)
+		addElement(
+			&element{
+				location:           field.Location(),
+				definitionFullName: definitionFullName,
+				signature:          i.getFieldSignature(file, field),
+				comments:           getComments(field.Location()),
+			},
+		)
+		if field.TypeName() != "" {
+			addElement(
+				&element{
+					location:           field.TypeNameLocation(),
+					definitionFullName: definitionFullName,
+				},
+			)
+		}
+	}
+	if err := protodesc.ForEachMessage(
+		func(message protodesc.Message) error {
+			if message.IsMapEntry() {
+				return nil
+			}
+			addElement(
+				&element{
+					location:           message.NameLocation(),
+					definitionFullName: message.FullName(),
+				},
+			)
+			for _, field := range message.Fields() {
+				addFieldElements(field)
+			}
+			for _, extension := range message.Extensions() {
+				addFieldElements(extension)
+			}
+			return nil
+		},
+		file,
+	); err != nil {
+		return err
+	}
+	if err := protodesc.ForEachEnum(
+		func(enum protodesc.Enum) error {
+			addElement(
+				&element{
+					location:           enum.NameLocation(),
+					definitionFullName: enum.FullName(),
+				},
+			)
+			return nil
+		},
+		file,
+	); err != nil {
+		return err
+	}
+	for _, extension := range file.Extensions() {
+		addFieldElements(extension)
+	}
+	for _, service := range file.Services() {
+		for _, method := range service.Methods() {
+			addElement(
+				&element{
+					location:  method.Location(),
+					signature: getMethodSignature(method),
+					comments:  getComments(method.Location()),
+				},
+			)
+			addElement(
+				&element{
+					location:           method.InputTypeLocation(),
+					definitionFullName: strings.TrimPrefix(method.InputTypeName(), "."),
+				},
+			)
+			addElement(
+				&element{
+					location:           method.OutputTypeLocation(),
+					definitionFullName: strings.TrimPrefix(method.OutputTypeName(), "."),
+				},
+			)
+		}
+	}
+	return nil
+}
+
+// getFieldDefinitionFullName gets the fully-qualified name of the message or
+// enum of the field, which is the value of the map for map fields.
+func (i *index) getFieldDefinitionFullName(field protodesc.Field) string {
+	fullName := strings.TrimPrefix(field.TypeName(), ".")
+	if mapEntry, ok := i.fullNameToMapEntry[fullName]; ok && len(mapEntry.Fields()) == 2 {
+		return strings.TrimPrefix(mapEntry.Fields()[1].TypeName(), ".")
+	}
+	return fullName
+}
+
+func (i *index) getFieldSignature(file protodesc.File, field protodesc.Field) string {
+	var label string
+	switch field.Label() {
+	case protodesc.FieldDescriptorProtoLabelRepeated:
+		label = "repeated "
+	case protodesc.FieldDescriptorProtoLabelRequired:
+		label = "required "
+	case protodesc.FieldDescriptorProtoLabelOptional:
+		if file.Syntax() == protodesc.SyntaxProto2 {
+			label = "optional "
+		}
+	}
+	typeName := i.getFieldTypeName(field)
+	if strings.HasPrefix(typeName, "map<") {
+		label = ""
+	}
+	return fmt.Sprintf("%s%s %s = %d;", label, typeName, field.Name(), field.Number())
+}
+
+func (i *index) getFieldTypeName(field protodesc.Field) string {
+	if field.TypeName() == "" {
+		return field.Type().String()
+	}
+	fullName := strings.TrimPrefix(field.TypeName(), ".")
+	mapEntry, ok := i.fullNameToMapEntry[fullName]
+	if !ok || len(mapEntry.Fields()) != 2 {
+		return fullName
+	}
+	return fmt.Sprintf(
+		"map<%s, %s>",
+		i.getFieldTypeName(mapEntry.Fields()[0]),
+		i.getFieldTypeName(mapEntry.Fields()[1]),
+	)
+}
+
+func getMethodSignature(method protodesc.Method) string {
+	var clientStreaming string
+	if method.ClientStreaming() {
+		clientStreaming = "stream "
+	}
+	var serverStreaming string
+	if method.ServerStreaming() {
+		serverStreaming = "stream "
+	}
+	return fmt.Sprintf(
+		"rpc %s(%s%s) returns (%s%s);",
+		method.Name(),
+		clientStreaming,
+		strings.TrimPrefix(method.InputTypeName(), "."),
+		serverStreaming,
+		strings.TrimPrefix(method.OutputTypeName(), "."),
+	)
+}
+
+// getFileURI gets the URI of the file, or empty if the file does not have a
+// real file path.
+func getFileURI(file protodesc.File, resolver bufbuild.ProtoRealFilePathResolver) (string, error) {
+	if resolver == nil {
+		return "", nil
+	}
+	realFilePath, err := resolver.GetRealFilePath(file.FilePath())
+	if err != nil || realFilePath == "" {
+		return "", err
+	}
+	return utillsp.PathToURI(realFilePath)
+}
+
+// getComments returns the leading and trailing comments of the location,
+// with leading spaces of each line removed.
+func getComments(location protodesc.Location) string {
+	if location == nil {
+		return ""
+	}
+	var lines []string
+	for _, comment := range []string{location.LeadingComments(), location.TrailingComments()} {
+		for _, line := range strings.Split(strings.TrimSpace(comment), "\n") {
+			lines = append(lines, strings.TrimSpace(line))
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// locationContains returns true if the one-based line and column are within
+// the location, including the end of the location.
+func locationContains(location protodesc.Location, line int, column int) bool {
+	if line < location.StartLine() || line > location.EndLine() {
+		return false
+	}
+	if line == location.StartLine() && column < location.StartColumn() {
+		return false
+	}
+	if line == location.EndLine() && column > location.EndColumn() {
+		return false
+	}
+	return true
+}
+
+// locationSize returns a size of the location that orders locations within
+// other locations before the other locations.
+func locationSize(location protodesc.Location) int {
+	return (location.EndLine()-location.StartLine())<<16 + location.EndColumn() - location.StartColumn()
+}
+
+func locationToRange(location protodesc.Location) *utillsp.Range {
+	return &utillsp.Range{
+		Start: &utillsp.Position{
+			Line:      toZeroBased(location.StartLine()),
+			Character: toZeroBased(location.StartColumn()),
+		},
+		End: &utillsp.Position{
+			Line:      toZeroBased(location.EndLine()),
+			Character: toZeroBased(location.EndColumn()),
+		},
+	}
+}
+
+// toZeroBased converts the one-based line or column of a location, which is
+// zero if unknown.
+func toZeroBased(value int) int {
+	if value == 0 {
+		return 0
+	}
+	return value - 1
+}
@@ -0,0 +1,84 @@
+package buflsp
+
+import (
+	"github.com/bufbuild/buf/internal/pkg/util/utillsp"
+)
+
+const (
+	// textDocumentSyncKindFull is the LSP TextDocumentSyncKind for sending the
+	// full contents of a file on every change.
+	textDocumentSyncKindFull = 1
+	// markupKindMarkdown is the LSP MarkupKind for markdown.
+	markupKindMarkdown = "markdown"
+)
+
+type initializeResult struct {
+	Capabilities *serverCapabilities `json:"capabilities"`
+	ServerInfo   *serverInfo         `json:"serverInfo"`
+}
+
+type serverCapabilities struct {
+	TextDocumentSync   *textDocumentSyncOptions `json:"textDocumentSync"`
+	DefinitionProvider bool                     `json:"definitionProvider"`
+	HoverProvider      bool                     `json:"hoverProvider"`
+}
+
+type textDocumentSyncOptions struct {
+	OpenClose bool `json:"openClose"`
+	Change    int  `json:"change"`
+	Save      bool `json:"save"`
+}
+
+type serverInfo struct {
+	Name string `json:"name"`
+}
+
+type textDocumentPositionParams struct {
+	TextDocument *textDocumentIdentifier `json:"textDocument"`
+	Position     *utillsp.Position       `json:"position"`
+}
+
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type didOpenTextDocumentParams struct {
+	TextDocument *textDocumentItem `json:"textDocument"`
+}
+
+type textDocumentItem struct {
+	URI  string `json:"uri"`
+	Text string `json:"text"`
+}
+
+type didChangeTextDocumentParams struct {
+	TextDocument   *textDocumentIdentifier           `json:"textDocument"`
+	ContentChanges []*textDocumentContentChangeEvent `json:"contentChanges"`
+}
+
+// textDocumentContentChangeEvent is a change to a file.
+//
+// As changes are synced in full, this does not have a range, and the text is
+// the full contents of the file.
+type textDocumentContentChangeEvent struct {
+	Text string `json:"text"`
+}
+
+type didCloseTextDocumentParams struct {
+	TextDocument *textDocumentIdentifier `json:"textDocument"`
+}
+
+type location struct {
+	URI   string         `json:"uri"`
+	Range *utillsp.Range `json:"range"`
+}
+
+type hover struct {
+	Contents *markupContent `json:"contents"`
+	Range    *utillsp.Range `json:"range"`
+}
+
+type markupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
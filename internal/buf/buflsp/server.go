@@ -0,0 +1,226 @@
+package buflsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/bufbuild/buf/internal/pkg/util/utillsp"
+	"go.uber.org/zap"
+)
+
+const (
+	methodInitialize  = "initialize"
+	methodInitialized = "initialized"
+	methodDidOpen     = "textDocument/didOpen"
+	methodDidChange   = "textDocument/didChange"
+	methodDidSave     = "textDocument/didSave"
+	methodDidClose    = "textDocument/didClose"
+	methodDefinition  = "textDocument/definition"
+	methodHover       = "textDocument/hover"
+	methodShutdown    = "shutdown"
+	methodExit        = "exit"
+
+	diagnosticSource = "buf"
+)
+
+type server struct {
+	logger  *zap.Logger
+	checker Checker
+}
+
+func newServer(logger *zap.Logger, checker Checker) *server {
+	return &server{
+		logger:  logger.Named("buflsp"),
+		checker: checker,
+	}
+}
+
+func (s *server) Serve(ctx context.Context, reader io.Reader, writer io.Writer) error {
+	session := newSession(s.logger, s.checker, writer)
+	dataC := make(chan []byte)
+	readErrC := make(chan error, 1)
+	doneC := make(chan struct{})
+	defer close(doneC)
+	go func() {
+		bufReader := bufio.NewReader(reader)
+		for {
+			data, err := utillsp.ReadMessage(bufReader)
+			if err != nil {
+				readErrC <- err
+				return
+			}
+			select {
+			case dataC <- data:
+			case <-doneC:
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-readErrC:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case data := <-dataC:
+			stop, err := session.handle(ctx, data)
+			if err != nil {
+				return err
+			}
+			if stop {
+				return nil
+			}
+		}
+	}
+}
+
+type session struct {
+	logger               *zap.Logger
+	checker              Checker
+	writer               utillsp.Writer
+	diagnosticsPublisher utillsp.DiagnosticsPublisher
+	// index is the index of the Image of the last successful build.
+	index *index
+	// pathToData are the contents of the files open in the client by absolute path.
+	pathToData map[string][]byte
+}
+
+func newSession(logger *zap.Logger, checker Checker, writer io.Writer) *session {
+	lspWriter := utillsp.NewWriter(writer)
+	return &session{
+		logger:               logger,
+		checker:              checker,
+		writer:               lspWriter,
+		diagnosticsPublisher: utillsp.NewDiagnosticsPublisher(lspWriter, diagnosticSource),
+		pathToData:           make(map[string][]byte),
+	}
+}
+
+// handle handles a single message from the client.
+//
+// Returns true if the server should stop.
+func (s *session) handle(ctx context.Context, data []byte) (bool, error) {
+	request := &utillsp.Request{}
+	if err := json.Unmarshal(data, request); err != nil {
+		return false, s.writer.RespondError(json.RawMessage("null"), utillsp.ParseErrorCode, err.Error())
+	}
+	s.logger.Debug("request", zap.String("method", request.Method))
+	switch request.Method {
+	case methodInitialize:
+		return false, s.writer.Respond(
+			request.ID,
+			&initializeResult{
+				Capabilities: &serverCapabilities{
+					TextDocumentSync: &textDocumentSyncOptions{
+						OpenClose: true,
+						Change:    textDocumentSyncKindFull,
+						Save:      true,
+					},
+					DefinitionProvider: true,
+					HoverProvider:      true,
+				},
+				ServerInfo: &serverInfo{
+					Name: diagnosticSource,
+				},
+			},
+		)
+	case methodInitialized, methodDidSave:
+		return false, s.check(ctx)
+	case methodDidOpen:
+		params := &didOpenTextDocumentParams{}
+		if err := json.Unmarshal(request.Params, params); err != nil || params.TextDocument == nil {
+			return false, s.respondInvalidParams(request)
+		}
+		path, err := utillsp.URIToPath(params.TextDocument.URI)
+		if err != nil {
+			return false, s.respondInvalidParams(request)
+		}
+		s.pathToData[path] = []byte(params.TextDocument.Text)
+		return false, s.check(ctx)
+	case methodDidChange:
+		params := &didChangeTextDocumentParams{}
+		if err := json.Unmarshal(request.Params, params); err != nil || params.TextDocument == nil || len(params.ContentChanges) == 0 {
+			return false, s.respondInvalidParams(request)
+		}
+		path, err := utillsp.URIToPath(params.TextDocument.URI)
+		if err != nil {
+			return false, s.respondInvalidParams(request)
+		}
+		// changes are synced in full, so the last change has the current contents
+		s.pathToData[path] = []byte(params.ContentChanges[len(params.ContentChanges)-1].Text)
+		return false, s.check(ctx)
+	case methodDidClose:
+		params := &didCloseTextDocumentParams{}
+		if err := json.Unmarshal(request.Params, params); err != nil || params.TextDocument == nil {
+			return false, s.respondInvalidParams(request)
+		}
+		path, err := utillsp.URIToPath(params.TextDocument.URI)
+		if err != nil {
+			return false, s.respondInvalidParams(request)
+		}
+		// unsaved changes are discarded when a file is closed, so check the file on disk
+		delete(s.pathToData, path)
+		return false, s.check(ctx)
+	case methodDefinition, methodHover:
+		params := &textDocumentPositionParams{}
+		if err := json.Unmarshal(request.Params, params); err != nil || params.TextDocument == nil || params.Position == nil {
+			return false, s.respondInvalidParams(request)
+		}
+		if s.index == nil {
+			return false, s.writer.Respond(request.ID, nil)
+		}
+		if request.Method == methodDefinition {
+			return false, s.writer.Respond(request.ID, s.index.getDefinition(params.TextDocument.URI, params.Position))
+		}
+		return false, s.writer.Respond(request.ID, s.index.getHover(params.TextDocument.URI, params.Position))
+	case methodShutdown:
+		return false, s.writer.Respond(request.ID, nil)
+	case methodExit:
+		return true, nil
+	default:
+		// notifications that are not handled are ignored
+		return false, s.writer.RespondError(request.ID, utillsp.MethodNotFoundCode, fmt.Sprintf("method not found: %q", request.Method))
+	}
+}
+
+// check runs the checker, updates the index, and publishes the diagnostics.
+//
+// A failed check is reported to the client, and only returns an error if the
+// context is done or the client cannot be written to.
+func (s *session) check(ctx context.Context) error {
+	checkResult, err := s.checker(ctx, s.pathToData)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return s.showError(err)
+	}
+	if checkResult.Image != nil {
+		index, err := newIndex(ctx, checkResult.Image, checkResult.Resolver)
+		if err != nil {
+			return s.showError(err)
+		}
+		s.index = index
+	}
+	return s.diagnosticsPublisher.Publish(checkResult.FileAnnotations)
+}
+
+func (s *session) showError(err error) error {
+	return s.writer.Notify(
+		utillsp.MethodShowMessage,
+		&utillsp.ShowMessageParams{
+			Type:    utillsp.MessageTypeError,
+			Message: err.Error(),
+		},
+	)
+}
+
+func (s *session) respondInvalidParams(request *utillsp.Request) error {
+	return s.writer.RespondError(request.ID, utillsp.InvalidParamsCode, fmt.Sprintf("invalid params for %s", request.Method))
+}
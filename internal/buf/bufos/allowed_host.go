@@ -0,0 +1,153 @@
+package bufos
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// maxHTTPRedirects is the maximum number of redirects followed, matching net/http.
+	maxHTTPRedirects = 10
+	// s3Host is the host of S3 that bucket hosts are subdomains of.
+	s3Host = "s3.amazonaws.com"
+)
+
+// getAllowedHosts gets the allowed hosts for remote inputs from the environment.
+//
+// The value is a comma-separated list of hosts. Returns nil if no allowed hosts
+// are set, in which case all hosts are allowed.
+func (e *envReader) getAllowedHosts(getenv func(string) string) []string {
+	if getenv == nil || e.allowedHostsEnvKey == "" {
+		return nil
+	}
+	var allowedHosts []string
+	for _, allowedHost := range strings.Split(getenv(e.allowedHostsEnvKey), ",") {
+		if allowedHost = strings.ToLower(strings.TrimSpace(allowedHost)); allowedHost != "" {
+			allowedHosts = append(allowedHosts, allowedHost)
+		}
+	}
+	return allowedHosts
+}
+
+// checkAllowedHost returns a policy error if the host is not within the allowed hosts.
+//
+// An allowed host of the form *.example.com allows all subdomains of example.com.
+// If allowedHosts is empty, all hosts are allowed.
+func (e *envReader) checkAllowedHost(allowedHosts []string, host string) error {
+	if len(allowedHosts) == 0 {
+		return nil
+	}
+	host = strings.ToLower(host)
+	for _, allowedHost := range allowedHosts {
+		if host == allowedHost {
+			return nil
+		}
+		if strings.HasPrefix(allowedHost, "*.") && strings.HasSuffix(host, allowedHost[1:]) {
+			return nil
+		}
+	}
+	return fmt.Errorf(
+		"policy error: remote inputs from host %q are not allowed, $%s is set to %q",
+		host,
+		e.allowedHostsEnvKey,
+		strings.Join(allowedHosts, ","),
+	)
+}
+
+// checkAllowedURL returns a policy error if the host of the URL is not within the allowed hosts.
+func (e *envReader) checkAllowedURL(allowedHosts []string, rawURL string) error {
+	if len(allowedHosts) == 0 {
+		return nil
+	}
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	return e.checkAllowedHost(allowedHosts, parsedURL.Hostname())
+}
+
+// checkAllowedS3Bucket returns a policy error if the host of the S3 bucket is not
+// within the allowed hosts.
+//
+// The host is the virtual-hosted-style host of the bucket, <bucket>.s3.amazonaws.com.
+func (e *envReader) checkAllowedS3Bucket(allowedHosts []string, bucketName string) error {
+	return e.checkAllowedHost(allowedHosts, bucketName+"."+s3Host)
+}
+
+// checkAllowedGitURL returns a policy error if the git URL is remote and its host
+// is not within the allowed hosts.
+//
+// Local repositories are always allowed.
+func (e *envReader) checkAllowedGitURL(allowedHosts []string, gitURL string) error {
+	if len(allowedHosts) == 0 {
+		return nil
+	}
+	if strings.Contains(gitURL, "://") {
+		if strings.HasPrefix(gitURL, "file://") {
+			return nil
+		}
+		return e.checkAllowedURL(allowedHosts, gitURL)
+	}
+	// scp-like ssh syntax, such as git@github.com:bufbuild/buf.git or github.com:bufbuild/buf.git
+	if host, ok := getSCPGitHost(gitURL); ok {
+		// local directories may contain a colon
+		if fileInfo, err := os.Stat(gitURL); err == nil && fileInfo.IsDir() {
+			return nil
+		}
+		return e.checkAllowedHost(allowedHosts, host)
+	}
+	return nil
+}
+
+// getSCPGitHost returns the host of the git URL if it has the scp-like syntax
+// [user@]host:path, as git does.
+//
+// This is the case if there is a colon before the first slash, unless the colon
+// is part of a Windows volume name.
+func getSCPGitHost(gitURL string) (string, bool) {
+	if filepath.VolumeName(gitURL) != "" {
+		return "", false
+	}
+	host := gitURL
+	if slashIndex := strings.IndexAny(host, "/"+string(filepath.Separator)); slashIndex >= 0 {
+		host = host[:slashIndex]
+	}
+	if atIndex := strings.LastIndex(host, "@"); atIndex >= 0 {
+		host = host[atIndex+1:]
+	}
+	// ipv6 addresses are within brackets, such as [::1]:repo.git
+	if strings.HasPrefix(host, "[") {
+		closeIndex := strings.Index(host, "]")
+		if closeIndex < 0 || !strings.HasPrefix(host[closeIndex+1:], ":") {
+			return "", false
+		}
+		return host[1:closeIndex], true
+	}
+	colonIndex := strings.Index(host, ":")
+	if colonIndex < 0 {
+		return "", false
+	}
+	return host[:colonIndex], true
+}
+
+// newAllowedHostsHTTPClient returns a copy of the HTTP client that only follows
+// redirects to the allowed hosts.
+//
+// If allowedHosts is empty, the HTTP client is returned.
+func (e *envReader) newAllowedHostsHTTPClient(allowedHosts []string) *http.Client {
+	if len(allowedHosts) == 0 {
+		return e.httpClient
+	}
+	httpClient := *e.httpClient
+	httpClient.CheckRedirect = func(request *http.Request, via []*http.Request) error {
+		if len(via) >= maxHTTPRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxHTTPRedirects)
+		}
+		return e.checkAllowedHost(allowedHosts, request.URL.Hostname())
+	}
+	return &httpClient
+}
@@ -0,0 +1,63 @@
+package bufos
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"go.uber.org/multierr"
+)
+
+// writeFileAtomic writes the data to a temporary file that is renamed into place.
+//
+// The temporary file is created in the same directory so that the rename does
+// not cross filesystems, and the file and the directory are synced so that the
+// file is either fully written or not present at all after a crash.
+func writeFileAtomic(filePath string, data []byte, perm os.FileMode) (retErr error) {
+	dirPath := filepath.Dir(filePath)
+	file, err := ioutil.TempFile(dirPath, "."+filepath.Base(filePath)+".tmp")
+	if err != nil {
+		return err
+	}
+	renamed := false
+	defer func() {
+		if retErr != nil && !renamed {
+			retErr = multierr.Append(retErr, os.Remove(file.Name()))
+		}
+	}()
+	if _, err := file.Write(data); err != nil {
+		return multierr.Append(err, file.Close())
+	}
+	if err := file.Chmod(perm); err != nil {
+		return multierr.Append(err, file.Close())
+	}
+	if err := file.Sync(); err != nil {
+		return multierr.Append(err, file.Close())
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(file.Name(), filePath); err != nil {
+		return err
+	}
+	renamed = true
+	return syncDir(dirPath)
+}
+
+// syncDir syncs the directory so that a rename within it is durable.
+//
+// Directories cannot be synced on Windows, where this is a no-op.
+func syncDir(dirPath string) (retErr error) {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	dir, err := os.Open(dirPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, dir.Close())
+	}()
+	return dir.Sync()
+}
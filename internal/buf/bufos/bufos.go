@@ -7,12 +7,14 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/bufbuild/buf/internal/buf/bufbuild"
 	"github.com/bufbuild/buf/internal/buf/bufconfig"
 	"github.com/bufbuild/buf/internal/buf/bufos/internal"
 	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
 	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/bufbuild/buf/internal/pkg/storage"
 	"go.uber.org/zap"
 )
 
@@ -42,6 +44,12 @@ type EnvReader interface {
 	// Note that includeSourceInfo will only be respected for Sources. We make
 	// no modifications for Images.
 	//
+	// Images are verified against the checksum of the sha256 option if set, or
+	// otherwise against the checksum sidecar file of a local file if it exists,
+	// such as image.bin.sha256 for image.bin.
+	//
+	// Modules use the config they were pushed with if there is no config override.
+	//
 	// FileAnnotations will be fixed per the resolver before returning.
 	// If stdin is nil and this tries to read from stdin, returns user error.
 	ReadEnv(
@@ -83,6 +91,27 @@ type EnvReader interface {
 		includeImports bool,
 	) (*Env, error)
 
+	// ExportSource writes the .proto files of a source value, and all files
+	// they transitively import, to the bucket with paths relative to the roots.
+	//
+	// If specificFilePaths is empty, this exports all the files under Buf control.
+	//
+	// Files are copied exactly from the source or from the dependencies of the
+	// config. Imports without a source, such as the well-known types or the
+	// files of dependency images, are printed from their descriptors.
+	//
+	// FileAnnotations will be fixed per the resolver before returning.
+	ExportSource(
+		ctx context.Context,
+		stdin io.Reader,
+		getenv func(string) string,
+		value string,
+		configOverride string,
+		specificFilePaths []string,
+		specificFilePathsAllowNotExist bool,
+		bucket storage.Bucket,
+	) ([]*filev1beta1.FileAnnotation, error)
+
 	// ListFiles lists the files.
 	ListFiles(
 		ctx context.Context,
@@ -91,10 +120,25 @@ type EnvReader interface {
 		value string,
 		configOverride string,
 	) ([]string, error)
+	// GetProtoFileSet gets the ProtoFileSet for a source value.
+	//
+	// The real file paths are relative to the source, and are not resolved
+	// relative to the current directory for directory values.
+	// Returns user error if the value is an image.
+	GetProtoFileSet(
+		ctx context.Context,
+		stdin io.Reader,
+		getenv func(string) string,
+		value string,
+		configOverride string,
+	) (bufbuild.ProtoFileSet, error)
 
 	// GetConfig gets the config.
+	//
+	// If the lint config extends a shared config, the shared config is fetched and merged.
 	GetConfig(
 		ctx context.Context,
+		getenv func(string) string,
 		configOverride string,
 	) (*bufconfig.Config, error)
 }
@@ -112,6 +156,9 @@ func NewEnvReader(
 	sshKeyFileEnvKey string,
 	sshKeyPassphraseEnvKey string,
 	sshKnownHostsFilesEnvKey string,
+	gitPureGoEnvKey string,
+	allowedHostsEnvKey string,
+	options ...EnvReaderOption,
 ) EnvReader {
 	return newEnvReader(
 		logger,
@@ -125,20 +172,154 @@ func NewEnvReader(
 		sshKeyFileEnvKey,
 		sshKeyPassphraseEnvKey,
 		sshKnownHostsFilesEnvKey,
+		gitPureGoEnvKey,
+		allowedHostsEnvKey,
+		options...,
 	)
 }
 
+// EnvReaderOption is an option for a new EnvReader.
+type EnvReaderOption func(*envReader)
+
+// EnvReaderWithDependencyImages returns a new EnvReaderOption that reads the
+// given image values and uses them as dependencies when building sources.
+//
+// The files within these images are only used to resolve imports, they are
+// never compiled or checked. When reading an image, the files these images
+// provide for the imports of the image are added to it as imports, like the
+// descriptor_set_in flag of protoc. The values must be image formats.
+// The flag name is used for error messages.
+func EnvReaderWithDependencyImages(flagName string, values ...string) EnvReaderOption {
+	return func(envReader *envReader) {
+		envReader.dependencyImageRefParser = internal.NewInputRefParser(flagName)
+		envReader.dependencyImageValues = values
+	}
+}
+
+// EnvReaderWithProtoFileSet returns a new EnvReaderOption that reads the
+// ProtoFileSet for source values from the given file instead of walking the bucket.
+//
+// The file must contain JSON produced by bufbuild.MarshalProtoFileSetJSON, such
+// as the output of buf ls-files --format json, for the same value and config.
+// The file set is not used when specific file paths are given.
+// The flag name is used for error messages.
+func EnvReaderWithProtoFileSet(flagName string, path string) EnvReaderOption {
+	return func(envReader *envReader) {
+		envReader.protoFileSetFlagName = flagName
+		envReader.protoFileSetPath = path
+	}
+}
+
+// EnvReaderWithRequirePinned returns a new EnvReaderOption that requires git
+// values to reference a full commit hash with ref=<sha> if requirePinned is true.
+//
+// Branches and tags can move, so they are refused for reproducible builds.
+// The flag name is used for error messages.
+func EnvReaderWithRequirePinned(flagName string, requirePinned bool) EnvReaderOption {
+	return func(envReader *envReader) {
+		if requirePinned {
+			envReader.requirePinnedFlagName = flagName
+		}
+	}
+}
+
+// EnvReaderWithAllowSymlinks returns a new EnvReaderOption that skips symlinks
+// and hard links within archive values that point outside of the extraction root
+// if allowSymlinks is true.
+//
+// By default, such entries result in a policy error. Entries with absolute paths
+// or ".." components always result in a policy error.
+// The flag name is used for error messages.
+func EnvReaderWithAllowSymlinks(flagName string, allowSymlinks bool) EnvReaderOption {
+	return func(envReader *envReader) {
+		envReader.allowSymlinksFlagName = flagName
+		envReader.allowSymlinks = allowSymlinks
+	}
+}
+
+// EnvReaderWithFollowSymlinks returns a new EnvReaderOption that follows symlinks
+// to files and directories within directory values if followSymlinks is true.
+//
+// By default, symlinks within directory values are skipped.
+func EnvReaderWithFollowSymlinks(followSymlinks bool) EnvReaderOption {
+	return func(envReader *envReader) {
+		envReader.followSymlinks = followSymlinks
+	}
+}
+
+// EnvReaderWithGitPureGo returns a new EnvReaderOption that reads file:// git
+// values with the pure-Go git implementation instead of the system git if gitPureGo is true.
+//
+// This is the same as setting the environment variable gitPureGoEnvKey to true,
+// and takes precedence over it. Sparse values are then also not partially cloned
+// with the system git. By default, the environment variable is used.
+func EnvReaderWithGitPureGo(gitPureGo bool) EnvReaderOption {
+	return func(envReader *envReader) {
+		envReader.gitPureGo = gitPureGo
+	}
+}
+
+// EnvReaderWithHTTPCache returns a new EnvReaderOption that caches values
+// downloaded over HTTP or HTTPS in the given directory.
+//
+// Cached values are revalidated with conditional requests using the ETag and
+// Last-Modified headers of the response, so unchanged values are not downloaded
+// again. Within the TTL, cached values are used without sending any request.
+// If dirPath is empty, $XDG_CACHE_HOME/buf/http is used.
+func EnvReaderWithHTTPCache(dirPath string, ttl time.Duration) EnvReaderOption {
+	return func(envReader *envReader) {
+		envReader.httpCacheEnabled = true
+		envReader.httpCacheDirPath = dirPath
+		envReader.httpCacheTTL = ttl
+	}
+}
+
+// EnvReaderWithHTTPClient returns a new EnvReaderOption that uses the HTTP client
+// for values fetched over HTTP or HTTPS, for OCI repositories, and for module
+// registries instead of the HTTP client given to NewEnvReader.
+//
+// If httpClient is nil, this has no effect.
+func EnvReaderWithHTTPClient(httpClient *http.Client) EnvReaderOption {
+	return func(envReader *envReader) {
+		if httpClient != nil {
+			envReader.httpClient = httpClient
+		}
+	}
+}
+
+// EnvReaderWithOverlay returns a new EnvReaderOption that reads the files at
+// the given paths from the data instead of from disk within directory values.
+//
+// This is used to check files with unsaved changes. The paths are absolute or
+// relative to the current working directory, and paths outside of the directory
+// value are ignored. Only the contents of files on disk are replaced, files that
+// are not on disk are not added.
+func EnvReaderWithOverlay(pathToData map[string][]byte) EnvReaderOption {
+	return func(envReader *envReader) {
+		envReader.overlayPathToData = pathToData
+	}
+}
+
 // ImageWriter is an image writer.
 type ImageWriter interface {
 	// WriteImage writes the image to the value.
 	//
 	// The file must be an image format.
 	// This is a no-np if value is the equivalent of /dev/null.
+	// Images written to OCI repositories are always written in the binary format.
+	// Images cannot be written to modules, as modules also include the config.
+	// Images can be written to S3 with s3://bucket/key and to GCS with gs://bucket/path,
+	// using the same credentials as for reading.
+	// Local files are written to a temporary file in the same directory that is
+	// renamed into place, so that a partially-written image is never left behind.
+	// If the value has the shards option, the image is split into shards along package
+	// boundaries that are written to separate local files, with a manifest of the shards.
 	//
 	// Validates the image before writing.
 	WriteImage(
 		ctx context.Context,
 		stdout io.Writer,
+		getenv func(string) string,
 		value string,
 		asFileDescriptorSet bool,
 		image *imagev1beta1.Image,
@@ -148,14 +329,59 @@ type ImageWriter interface {
 // NewImageWriter returns a new ImageWriter.
 func NewImageWriter(
 	logger *zap.Logger,
+	httpClient *http.Client,
 	valueFlagName string,
+	options ...ImageWriterOption,
 ) ImageWriter {
 	return newImageWriter(
 		logger,
+		httpClient,
 		valueFlagName,
+		options...,
 	)
 }
 
+// ImageWriterOption is an option for a new ImageWriter.
+type ImageWriterOption func(*imageWriter)
+
+// ImageWriterWithFormatOverride returns a new ImageWriterOption that writes
+// images in the given format instead of the format derived from the value.
+//
+// This is useful when writing to stdout, where there is no extension to derive the
+// format from. The value must not also specify the format with options.
+// If formatOverride is empty, this has no effect.
+func ImageWriterWithFormatOverride(flagName string, formatOverride string) ImageWriterOption {
+	return func(imageWriter *imageWriter) {
+		imageWriter.formatOverrideFlagName = flagName
+		imageWriter.formatOverride = formatOverride
+	}
+}
+
+// ImageWriterWithChecksum returns a new ImageWriterOption that writes a checksum
+// sidecar file next to every local file written, such as image.bin.sha256 for image.bin.
+//
+// The only supported algorithm is ChecksumAlgorithmSHA256, and the sidecar is in the
+// format of sha256sum. Writing to locations other than local files is an error.
+// If algorithm is empty, this has no effect.
+func ImageWriterWithChecksum(flagName string, algorithm string) ImageWriterOption {
+	return func(imageWriter *imageWriter) {
+		imageWriter.checksumFlagName = flagName
+		imageWriter.checksumAlgorithm = algorithm
+	}
+}
+
+// ImageWriterWithHTTPClient returns a new ImageWriterOption that uses the HTTP
+// client for OCI repositories instead of the HTTP client given to NewImageWriter.
+//
+// If httpClient is nil, this has no effect.
+func ImageWriterWithHTTPClient(httpClient *http.Client) ImageWriterOption {
+	return func(imageWriter *imageWriter) {
+		if httpClient != nil {
+			imageWriter.httpClient = httpClient
+		}
+	}
+}
+
 // AllFormatsToString returns all format strings.
 func AllFormatsToString() string {
 	return internal.AllFormatsToString()
@@ -0,0 +1,65 @@
+package bufos
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// ChecksumAlgorithmSHA256 is the SHA256 checksum algorithm.
+	ChecksumAlgorithmSHA256 = "sha256"
+
+	// checksumSidecarExt is the extension of checksum sidecar files, which are
+	// written next to the file they are the checksum of.
+	checksumSidecarExt = ".sha256"
+)
+
+// writeChecksumSidecar writes the SHA256 checksum of the data to the sidecar
+// file of the file path.
+//
+// The sidecar is in the format of sha256sum, so that it can be verified with
+// sha256sum -c from the directory of the file.
+func writeChecksumSidecar(filePath string, data []byte) error {
+	sidecarFilePath := filePath + checksumSidecarExt
+	sidecarData := []byte(fmt.Sprintf("%s  %s\n", sha256Hex(data), filepath.Base(filePath)))
+	return writeFileAtomic(sidecarFilePath, sidecarData, getImageFileMode(sidecarFilePath))
+}
+
+// verifyChecksum verifies the data of the file path against the expected
+// hex-encoded SHA256 checksum.
+//
+// If the expected checksum is empty, the data is verified against the checksum
+// in the sidecar file of the file path, if the sidecar file exists.
+func verifyChecksum(filePath string, data []byte, expectedSHA256Hex string) error {
+	source := "sha256 option"
+	if expectedSHA256Hex == "" {
+		sidecarFilePath := filePath + checksumSidecarExt
+		sidecarData, err := ioutil.ReadFile(sidecarFilePath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		fields := strings.Fields(string(sidecarData))
+		if len(fields) == 0 {
+			return fmt.Errorf("%s: checksum sidecar file is empty", sidecarFilePath)
+		}
+		expectedSHA256Hex = strings.ToLower(fields[0])
+		source = sidecarFilePath
+	}
+	if actualSHA256Hex := sha256Hex(data); actualSHA256Hex != expectedSHA256Hex {
+		return fmt.Errorf("%s: sha256 checksum %s does not match the expected checksum %s from %s", filePath, actualSHA256Hex, expectedSHA256Hex, source)
+	}
+	return nil
+}
+
+func sha256Hex(data []byte) string {
+	digest := sha256.Sum256(data)
+	return hex.EncodeToString(digest[:])
+}
@@ -0,0 +1,186 @@
+package bufos
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/pkg/storage"
+	"github.com/bufbuild/buf/internal/pkg/storage/storageos"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagepath"
+	"github.com/bufbuild/buf/internal/pkg/storage/storageutil"
+	"github.com/bufbuild/buf/internal/pkg/util/utillog"
+	"github.com/bufbuild/cli/clios"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+// builtinDeps are the dependencies that can be specified by name in the build.deps
+// section of the configuration.
+//
+// These are pinned to a specific commit so that builds are reproducible.
+var builtinDeps = map[string]*builtinDep{
+	"googleapis": {
+		owner:      "googleapis",
+		repository: "googleapis",
+		commit:     "37c923effe8b002884466074f84bc4e78e6ade62",
+	},
+}
+
+type builtinDep struct {
+	owner      string
+	repository string
+	commit     string
+}
+
+func (d *builtinDep) archiveURL() string {
+	return fmt.Sprintf("https://github.com/%s/%s/archive/%s.tar.gz", d.owner, d.repository, d.commit)
+}
+
+// getDepBuckets gets the buckets for the named builtin dependencies.
+//
+// The dependencies are downloaded once and cached under $XDG_CACHE_HOME/buf/deps.
+// The caller is responsible for closing the returned buckets.
+func (e *envReader) getDepBuckets(
+	ctx context.Context,
+	getenv func(string) string,
+	depNames []string,
+) (_ []storage.ReadBucket, retErr error) {
+	if len(depNames) == 0 {
+		return nil, nil
+	}
+	cacheDirPath, err := clios.XdgCacheHome(getenv)
+	if err != nil {
+		return nil, err
+	}
+	var depBuckets []storage.ReadBucket
+	defer func() {
+		if retErr != nil {
+			for _, depBucket := range depBuckets {
+				retErr = multierr.Append(retErr, depBucket.Close())
+			}
+		}
+	}()
+	for _, depName := range depNames {
+		dep, ok := builtinDeps[depName]
+		if !ok {
+			return nil, fmt.Errorf("unknown dep %q, must be one of %s", depName, builtinDepNamesString())
+		}
+		depDirPath := filepath.Join(cacheDirPath, "buf", "deps", depName, dep.commit)
+		if err := e.downloadBuiltinDep(ctx, getenv, dep, depDirPath); err != nil {
+			return nil, fmt.Errorf("could not get dep %s: %v", depName, err)
+		}
+		depBucket, err := storageos.NewReadBucket(depDirPath)
+		if err != nil {
+			return nil, err
+		}
+		depBuckets = append(depBuckets, depBucket)
+	}
+	return depBuckets, nil
+}
+
+// downloadBuiltinDep downloads the dependency to the directory path.
+//
+// If the directory already exists, this is a no-op. The archive is extracted to a
+// temporary directory that is renamed into place so that partial downloads are
+// never used.
+func (e *envReader) downloadBuiltinDep(
+	ctx context.Context,
+	getenv func(string) string,
+	dep *builtinDep,
+	depDirPath string,
+) (retErr error) {
+	if fileInfo, err := os.Stat(depDirPath); err == nil {
+		if !fileInfo.IsDir() {
+			return fmt.Errorf("expected %s to be a directory", depDirPath)
+		}
+		return nil
+	}
+	defer utillog.Defer(e.logger, "download_dep", zap.String("url", dep.archiveURL()))()
+
+	data, err := e.getDepArchiveData(ctx, getenv, dep)
+	if err != nil {
+		return err
+	}
+	parentDirPath := filepath.Dir(depDirPath)
+	if err := os.MkdirAll(parentDirPath, 0755); err != nil {
+		return err
+	}
+	tmpDirPath, err := ioutil.TempDir(parentDirPath, ".tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, os.RemoveAll(tmpDirPath))
+	}()
+	bucket, err := storageos.NewBucket(tmpDirPath)
+	if err != nil {
+		return err
+	}
+	if err := storageutil.Untargz(
+		ctx,
+		bytes.NewReader(data),
+		bucket,
+		false,
+		storagepath.WithExt(".proto"),
+		storagepath.WithStripComponents(1),
+	); err != nil {
+		return multierr.Append(fmt.Errorf("untar error: %v", err), bucket.Close())
+	}
+	if err := bucket.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpDirPath, depDirPath); err != nil {
+		// another process may have won the race
+		if fileInfo, statErr := os.Stat(depDirPath); statErr == nil && fileInfo.IsDir() {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func builtinDepNamesString() string {
+	depNames := make([]string, 0, len(builtinDeps))
+	for depName := range builtinDeps {
+		depNames = append(depNames, depName)
+	}
+	sort.Strings(depNames)
+	return "[" + strings.Join(depNames, ",") + "]"
+}
+
+// getDepArchiveData downloads the archive of the dependency.
+//
+// The allowed hosts for remote inputs apply, but the credentials for inputs are never sent.
+func (e *envReader) getDepArchiveData(
+	ctx context.Context,
+	getenv func(string) string,
+	dep *builtinDep,
+) (_ []byte, retErr error) {
+	allowedHosts := e.getAllowedHosts(getenv)
+	archiveURL := dep.archiveURL()
+	if err := e.checkAllowedURL(allowedHosts, archiveURL); err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequestWithContext(ctx, "GET", archiveURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := e.newAllowedHostsHTTPClient(allowedHosts).Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got HTTP status code %d for %s", response.StatusCode, archiveURL)
+	}
+	return ioutil.ReadAll(response.Body)
+}
@@ -9,14 +9,20 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/bufbuild/buf/internal/buf/bufbuild"
 	"github.com/bufbuild/buf/internal/buf/bufconfig"
 	"github.com/bufbuild/buf/internal/buf/bufos/internal"
+	"github.com/bufbuild/buf/internal/buf/bufregistry"
 	"github.com/bufbuild/buf/internal/buf/ext/extimage"
 	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
 	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
@@ -26,18 +32,35 @@ import (
 	"github.com/bufbuild/buf/internal/pkg/storage/storagemem"
 	"github.com/bufbuild/buf/internal/pkg/storage/storageos"
 	"github.com/bufbuild/buf/internal/pkg/storage/storagepath"
+	"github.com/bufbuild/buf/internal/pkg/storage/storages3"
 	"github.com/bufbuild/buf/internal/pkg/storage/storageutil"
+	"github.com/bufbuild/buf/internal/pkg/util/utilencoding"
 	"github.com/bufbuild/buf/internal/pkg/util/utillog"
+	"github.com/bufbuild/buf/internal/pkg/util/utiloci"
 	"github.com/bufbuild/cli/clios"
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
 	"go.uber.org/multierr"
 	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
 )
 
-var jsonUnmarshaler = &jsonpb.Unmarshaler{
-	AllowUnknownFields: true,
-}
+const (
+	gcsURLPrefix      = "gs://"
+	gcsAPIHost        = "storage.googleapis.com"
+	gcsReadOnlyScope  = "https://www.googleapis.com/auth/devstorage.read_only"
+	gcsReadWriteScope = "https://www.googleapis.com/auth/devstorage.read_write"
+)
+
+var (
+	jsonUnmarshaler = &jsonpb.Unmarshaler{
+		AllowUnknownFields: true,
+	}
+
+	// errStopWalk is returned from a walk function to stop the walk early.
+	errStopWalk = errors.New("stop walk")
+)
 
 type envReader struct {
 	logger                   *zap.Logger
@@ -51,6 +74,21 @@ type envReader struct {
 	sshKeyFileEnvKey         string
 	sshKeyPassphraseEnvKey   string
 	sshKnownHostsFilesEnvKey string
+	gitPureGoEnvKey          string
+	gitPureGo                bool
+	allowedHostsEnvKey       string
+	dependencyImageRefParser internal.InputRefParser
+	dependencyImageValues    []string
+	protoFileSetFlagName     string
+	protoFileSetPath         string
+	requirePinnedFlagName    string
+	allowSymlinksFlagName    string
+	allowSymlinks            bool
+	followSymlinks           bool
+	httpCacheEnabled         bool
+	httpCacheDirPath         string
+	httpCacheTTL             time.Duration
+	overlayPathToData        map[string][]byte
 }
 
 func newEnvReader(
@@ -65,8 +103,11 @@ func newEnvReader(
 	sshKeyFileEnvKey string,
 	sshKeyPassphraseEnvKey string,
 	sshKnownHostsFilesEnvKey string,
+	gitPureGoEnvKey string,
+	allowedHostsEnvKey string,
+	options ...EnvReaderOption,
 ) *envReader {
-	return &envReader{
+	envReader := &envReader{
 		logger:         logger.Named("bufos"),
 		httpClient:     httpClient,
 		configProvider: configProvider,
@@ -83,7 +124,13 @@ func newEnvReader(
 		sshKeyFileEnvKey:         sshKeyFileEnvKey,
 		sshKeyPassphraseEnvKey:   sshKeyPassphraseEnvKey,
 		sshKnownHostsFilesEnvKey: sshKnownHostsFilesEnvKey,
+		gitPureGoEnvKey:          gitPureGoEnvKey,
+		allowedHostsEnvKey:       allowedHostsEnvKey,
 	}
+	for _, option := range options {
+		option(envReader)
+	}
+	return envReader
 }
 
 func (e *envReader) ReadEnv(
@@ -171,13 +218,43 @@ func (e *envReader) ReadImageEnv(
 	return env, nil
 }
 
+func (e *envReader) ExportSource(
+	ctx context.Context,
+	stdin io.Reader,
+	getenv func(string) string,
+	value string,
+	configOverride string,
+	specificFilePaths []string,
+	specificFilePathsAllowNotExist bool,
+	bucket storage.Bucket,
+) ([]*filev1beta1.FileAnnotation, error) {
+	inputRef, err := e.inputRefParser.ParseInputRef(value, true, false)
+	if err != nil {
+		return nil, err
+	}
+	e.logger.Debug("parse", zap.Any("input_ref", inputRef), zap.Stringer("format", inputRef.Format))
+	_, fileAnnotations, err := e.readEnvFromBucket(
+		ctx,
+		stdin,
+		getenv,
+		configOverride,
+		specificFilePaths,
+		specificFilePathsAllowNotExist,
+		true, // imports are exported
+		true, // comments of files printed from descriptors are kept
+		inputRef,
+		bucket,
+	)
+	return fileAnnotations, err
+}
+
 func (e *envReader) ListFiles(
 	ctx context.Context,
 	stdin io.Reader,
 	getenv func(string) string,
 	value string,
 	configOverride string,
-) (_ []string, retErr error) {
+) ([]string, error) {
 	inputRef, err := e.inputRefParser.ParseInputRef(value, false, false)
 	if err != nil {
 		return nil, err
@@ -200,44 +277,15 @@ func (e *envReader) ListFiles(
 	}
 
 	// we have a source, we need to get everything
-	bucket, err := e.getBucket(ctx, stdin, getenv, inputRef)
-	if err != nil {
-		return nil, err
-	}
-	defer func() {
-		retErr = multierr.Append(retErr, bucket.Close())
-	}()
-	var config *bufconfig.Config
-	if configOverride != "" {
-		config, err = e.configOverrideParser.ParseConfigOverride(configOverride)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		// if there is no config override, we read the config from the bucket
-		// if there was no file, this just returns default config
-		config, err = e.configProvider.GetConfigForBucket(ctx, bucket)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	protoFileSet, err := e.buildHandler.Files(
-		ctx,
-		bucket,
-		bufbuild.FilesOptions{
-			Roots:    config.Build.Roots,
-			Excludes: config.Build.Excludes,
-		},
-	)
+	protoFileSet, err := e.getProtoFileSetForInputRef(ctx, stdin, getenv, configOverride, inputRef)
 	if err != nil {
 		return nil, err
 	}
 	filePaths := protoFileSet.RealFilePaths()
 	//// The files are in the order of the root file paths, we want to sort them for output.
 	sort.Strings(filePaths)
-	if inputRef.Format != internal.FormatDir {
-		// if format is not a directory, just output the file paths
+	if inputRef.Format != internal.FormatDir || storages3.IsURL(inputRef.Path) {
+		// if format is not a local directory, just output the file paths
 		return filePaths, nil
 	}
 
@@ -257,12 +305,32 @@ func (e *envReader) ListFiles(
 	return filePaths, nil
 }
 
+func (e *envReader) GetProtoFileSet(
+	ctx context.Context,
+	stdin io.Reader,
+	getenv func(string) string,
+	value string,
+	configOverride string,
+) (bufbuild.ProtoFileSet, error) {
+	inputRef, err := e.inputRefParser.ParseInputRef(value, true, false)
+	if err != nil {
+		return nil, err
+	}
+	e.logger.Debug("parse", zap.Any("input_ref", inputRef), zap.Stringer("format", inputRef.Format))
+	return e.getProtoFileSetForInputRef(ctx, stdin, getenv, configOverride, inputRef)
+}
+
 func (e *envReader) GetConfig(
 	ctx context.Context,
+	getenv func(string) string,
 	configOverride string,
 ) (*bufconfig.Config, error) {
 	if configOverride != "" {
-		return e.configOverrideParser.ParseConfigOverride(configOverride)
+		config, err := e.configOverrideParser.ParseConfigOverride(configOverride)
+		if err != nil {
+			return nil, err
+		}
+		return e.getConfigWithLintExtends(ctx, getenv, config)
 	}
 	// if there is no config override, we read the config from the current directory
 	pwd, err := os.Getwd()
@@ -278,7 +346,11 @@ func (e *envReader) GetConfig(
 		data = nil
 	}
 	// if there was no file, this just returns default config
-	return e.configProvider.GetConfigForData(data)
+	config, err := e.configProvider.GetConfigForData(data)
+	if err != nil {
+		return nil, err
+	}
+	return e.getConfigWithLintExtends(ctx, getenv, config)
 }
 
 func (e *envReader) readEnv(
@@ -323,6 +395,7 @@ func (e *envReader) readEnv(
 		includeImports,
 		includeSourceInfo,
 		inputRef,
+		nil,
 	)
 }
 
@@ -336,22 +409,27 @@ func (e *envReader) readEnvFromBucket(
 	includeImports bool,
 	includeSourceInfo bool,
 	inputRef *internal.InputRef,
+	// exportBucket is the bucket to export the files of the image to, if any
+	exportBucket storage.Bucket,
 ) (_ *Env, _ []*filev1beta1.FileAnnotation, retErr error) {
-	bucket, err := e.getBucket(ctx, stdin, getenv, inputRef)
-	if err != nil {
-		return nil, nil, err
-	}
-	defer func() {
-		retErr = multierr.Append(retErr, bucket.Close())
-	}()
-
 	var config *bufconfig.Config
+	var knownRoots []string
 	if configOverride != "" {
+		var err error
 		config, err = e.configOverrideParser.ParseConfigOverride(configOverride)
 		if err != nil {
 			return nil, nil, err
 		}
-	} else {
+		knownRoots = config.Build.Roots
+	}
+	bucket, err := e.getBucket(ctx, stdin, getenv, inputRef, knownRoots)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, bucket.Close())
+	}()
+	if config == nil {
 		// if there is no config override, we read the config from the bucket
 		// if there was no file, this just returns default config
 		config, err = e.configProvider.GetConfigForBucket(ctx, bucket)
@@ -359,14 +437,18 @@ func (e *envReader) readEnvFromBucket(
 			return nil, nil, err
 		}
 	}
+	config, err = e.getConfigWithLintExtends(ctx, getenv, config)
+	if err != nil {
+		return nil, nil, err
+	}
 	var specificRealFilePaths []string
 	if len(specificFilePaths) > 0 {
 		// since we are doing a build, we filter before doing the build
 		// via bufbuild.Provider
 		// this will include imports if necessary
 		specificRealFilePaths = make([]string, len(specificFilePaths))
-		if inputRef.Format == internal.FormatDir {
-			// if we had a directory input, then we need to make everything relative to that directory
+		if inputRef.Format == internal.FormatDir && !storages3.IsURL(inputRef.Path) {
+			// if we had a local directory input, then we need to make everything relative to that directory
 			absDirPath, err := filepath.Abs(inputRef.Path)
 			if err != nil {
 				return nil, nil, err
@@ -387,7 +469,7 @@ func (e *envReader) readEnvFromBucket(
 				specificRealFilePaths[i] = specificRealFilePath
 			}
 		} else {
-			// if we did not have a directory input, then we need to make sure all paths are normalized
+			// if we did not have a local directory input, then we need to make sure all paths are normalized
 			// and relative
 			for i, specificFilePath := range specificFilePaths {
 				specificRealFilePath, err := storagepath.NormalizeAndValidate(specificFilePath)
@@ -399,8 +481,9 @@ func (e *envReader) readEnvFromBucket(
 		}
 	}
 	// we now have everything we need, actually build the image
-	protoFileSet, err := e.buildHandler.Files(
+	protoFileSet, err := e.getProtoFileSet(
 		ctx,
+		stdin,
 		bucket,
 		bufbuild.FilesOptions{
 			Roots:                              config.Build.Roots,
@@ -413,12 +496,25 @@ func (e *envReader) readEnvFromBucket(
 		return nil, nil, err
 	}
 	var resolver bufbuild.ProtoRealFilePathResolver = protoFileSet
-	if inputRef.Format == internal.FormatDir {
+	if inputRef.Format == internal.FormatDir && !storages3.IsURL(inputRef.Path) {
 		resolver, err = internal.NewRelProtoFilePathResolver(inputRef.Path, resolver)
 		if err != nil {
 			return nil, nil, err
 		}
 	}
+	dependencyImages, err := e.getDependencyImages(ctx, stdin, getenv)
+	if err != nil {
+		return nil, nil, err
+	}
+	depBuckets, err := e.getDepBuckets(ctx, getenv, config.Build.Deps)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		for _, depBucket := range depBuckets {
+			retErr = multierr.Append(retErr, depBucket.Close())
+		}
+	}()
 	image, fileAnnotations, err := e.buildHandler.Build(
 		ctx,
 		bucket,
@@ -427,7 +523,9 @@ func (e *envReader) readEnvFromBucket(
 			IncludeImports:    includeImports,
 			IncludeSourceInfo: includeSourceInfo,
 			// If we specified specific file paths, do not copy to memory
-			CopyToMemory: len(specificRealFilePaths) == 0,
+			CopyToMemory:      len(specificRealFilePaths) == 0,
+			DependencyImages:  dependencyImages,
+			DependencyBuckets: depBuckets,
 		},
 	)
 	if err != nil {
@@ -440,6 +538,12 @@ func (e *envReader) readEnvFromBucket(
 		}
 		return nil, fileAnnotations, nil
 	}
+	if exportBucket != nil {
+		// the buckets are closed when this returns, so the files are exported here
+		if err := exportImage(ctx, image, protoFileSet.Roots(), bucket, depBuckets, exportBucket); err != nil {
+			return nil, nil, err
+		}
+	}
 	return &Env{Image: image, Resolver: resolver, Config: config}, nil, nil
 }
 
@@ -453,14 +557,39 @@ func (e *envReader) readEnvFromImage(
 	includeImports bool,
 	inputRef *internal.InputRef,
 ) (_ *Env, retErr error) {
-	image, err := e.getImage(ctx, stdin, getenv, inputRef)
+	var image *imagev1beta1.Image
+	var moduleConfigData []byte
+	var err error
+	if inputRef.Format == internal.FormatModule {
+		image, moduleConfigData, err = e.getImageFromModule(ctx, getenv, inputRef.Path)
+	} else {
+		image, err = e.getImage(ctx, stdin, getenv, inputRef)
+	}
+	if err != nil {
+		return nil, err
+	}
+	dependencyImages, err := e.getDependencyImages(ctx, stdin, getenv)
 	if err != nil {
 		return nil, err
 	}
-	config, err := e.GetConfig(ctx, configOverride)
+	// this is a no-op if there are no dependency images
+	image, err = extimage.ImageWithDependencyImages(image, dependencyImages...)
 	if err != nil {
 		return nil, err
 	}
+	var config *bufconfig.Config
+	if inputRef.Format == internal.FormatModule && configOverride == "" {
+		// if there is no config override, we use the config the module was pushed with
+		config, err = e.configProvider.GetConfigForData(moduleConfigData)
+		if err != nil {
+			return nil, fmt.Errorf("config of %s: %v", inputRef.Path, err)
+		}
+	} else {
+		config, err = e.GetConfig(ctx, getenv, configOverride)
+		if err != nil {
+			return nil, err
+		}
+	}
 	if len(specificFilePaths) > 0 {
 		// note this must include imports if these are required for whatever operation
 		// you are doing
@@ -481,30 +610,54 @@ func (e *envReader) readEnvFromImage(
 	}, nil
 }
 
+// getBucket gets the bucket for the source input.
+//
+// knownRoots are the roots from the config override, if any. For git inputs,
+// only the files under these roots are fetched.
 func (e *envReader) getBucket(
 	ctx context.Context,
 	stdin io.Reader,
 	getenv func(string) string,
 	inputRef *internal.InputRef,
+	knownRoots []string,
 ) (storage.ReadBucket, error) {
 	switch inputRef.Format {
 	case internal.FormatDir:
+		if storages3.IsURL(inputRef.Path) {
+			return e.getBucketFromS3(ctx, getenv, inputRef.Path)
+		}
+		if strings.HasPrefix(inputRef.Path, gcsURLPrefix) {
+			return nil, fmt.Errorf("%s: %s inputs must be archives or images", inputRef.Path, gcsURLPrefix)
+		}
 		return e.getBucketFromLocalDir(inputRef.Path)
-	case internal.FormatTar, internal.FormatTarGz:
-		return e.getBucketFromLocalTarball(
+	case internal.FormatTar, internal.FormatTarGz, internal.FormatZip:
+		return e.getBucketFromLocalArchive(
 			ctx,
 			stdin,
 			getenv,
 			inputRef.Format,
+			inputRef.Compression,
 			inputRef.Path,
 			inputRef.StripComponents,
 		)
 	case internal.FormatGit:
+		if e.requirePinnedFlagName != "" && !storagegitplumbing.IsCommitHash(inputRef.GitRefName) {
+			return nil, fmt.Errorf(
+				"%s: git input %s must be pinned to a full commit hash with ref=<sha> but has reference %q",
+				e.requirePinnedFlagName,
+				inputRef.Path,
+				inputRef.GitRefName.String(),
+			)
+		}
 		return e.getBucketFromGitRepo(
 			ctx,
 			getenv,
 			inputRef.Path,
 			inputRef.GitRefName,
+			inputRef.GitDepth,
+			inputRef.GitRecurseSubmodules,
+			inputRef.GitSubDir,
+			knownRoots,
 		)
 	default:
 		return nil, fmt.Errorf("unknown format outside of parse: %v", inputRef.Format)
@@ -518,33 +671,161 @@ func (e *envReader) getImage(
 	inputRef *internal.InputRef,
 ) (*imagev1beta1.Image, error) {
 	switch inputRef.Format {
-	case internal.FormatBin, internal.FormatBinGz, internal.FormatJSON, internal.FormatJSONGz:
-		return e.getImageFromLocalFile(ctx, stdin, getenv, inputRef.Format, inputRef.Path)
+	case internal.FormatBin, internal.FormatBinGz, internal.FormatJSON, internal.FormatJSONGz, internal.FormatYAML, internal.FormatYAMLGz:
+		return e.getImageFromLocalFile(ctx, stdin, getenv, inputRef.Format, inputRef.Path, inputRef.SHA256)
+	case internal.FormatOCIRepo:
+		return e.getImageFromOCIRepo(ctx, getenv, inputRef.Path)
+	case internal.FormatModule:
+		image, _, err := e.getImageFromModule(ctx, getenv, inputRef.Path)
+		return image, err
 	default:
 		return nil, fmt.Errorf("unknown format outside of parse: %v", inputRef.Format)
 	}
 }
 
+// Can handle source formats.
+func (e *envReader) getProtoFileSetForInputRef(
+	ctx context.Context,
+	stdin io.Reader,
+	getenv func(string) string,
+	configOverride string,
+	inputRef *internal.InputRef,
+) (_ bufbuild.ProtoFileSet, retErr error) {
+	var config *bufconfig.Config
+	var knownRoots []string
+	if configOverride != "" {
+		var err error
+		config, err = e.configOverrideParser.ParseConfigOverride(configOverride)
+		if err != nil {
+			return nil, err
+		}
+		knownRoots = config.Build.Roots
+	}
+	bucket, err := e.getBucket(ctx, stdin, getenv, inputRef, knownRoots)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, bucket.Close())
+	}()
+	if config == nil {
+		// if there is no config override, we read the config from the bucket
+		// if there was no file, this just returns default config
+		config, err = e.configProvider.GetConfigForBucket(ctx, bucket)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return e.getProtoFileSet(
+		ctx,
+		stdin,
+		bucket,
+		bufbuild.FilesOptions{
+			Roots:    config.Build.Roots,
+			Excludes: config.Build.Excludes,
+		},
+	)
+}
+
+// getProtoFileSet gets the ProtoFileSet for the bucket.
+//
+// If a file set was given and there are no specific file paths, the file set
+// is read instead of walking the bucket.
+func (e *envReader) getProtoFileSet(
+	ctx context.Context,
+	stdin io.Reader,
+	bucket storage.ReadBucket,
+	options bufbuild.FilesOptions,
+) (bufbuild.ProtoFileSet, error) {
+	if e.protoFileSetPath == "" || len(options.SpecificRealFilePaths) > 0 {
+		return e.buildHandler.Files(ctx, bucket, options)
+	}
+	data, err := e.getFileDataFromOS(stdin, e.protoFileSetPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: could not read file: %v", e.protoFileSetFlagName, err)
+	}
+	protoFileSet, err := bufbuild.UnmarshalProtoFileSetJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", e.protoFileSetFlagName, err)
+	}
+	e.logger.Debug("proto_file_set_from_file", zap.Int("num_files", len(protoFileSet.RootFilePaths())))
+	return protoFileSet, nil
+}
+
+func (e *envReader) getDependencyImages(
+	ctx context.Context,
+	stdin io.Reader,
+	getenv func(string) string,
+) ([]*imagev1beta1.Image, error) {
+	if len(e.dependencyImageValues) == 0 {
+		return nil, nil
+	}
+	dependencyImages := make([]*imagev1beta1.Image, 0, len(e.dependencyImageValues))
+	for _, value := range e.dependencyImageValues {
+		inputRef, err := e.dependencyImageRefParser.ParseInputRef(value, false, true)
+		if err != nil {
+			return nil, err
+		}
+		dependencyImage, err := e.getImage(ctx, stdin, getenv, inputRef)
+		if err != nil {
+			return nil, err
+		}
+		dependencyImages = append(dependencyImages, dependencyImage)
+	}
+	return dependencyImages, nil
+}
+
 // Can handle formats FormatDir
 func (e *envReader) getBucketFromLocalDir(
 	path string,
 ) (storage.ReadBucket, error) {
-	bucket, err := storageos.NewBucket(path)
+	var options []storageos.BucketOption
+	if e.followSymlinks {
+		options = append(options, storageos.BucketWithFollowSymlinks())
+	}
+	bucket, err := storageos.NewBucket(path, options...)
 	if err != nil {
 		if storage.IsNotExist(err) || storageos.IsNotDir(err) {
 			return nil, err
 		}
 		return nil, err
 	}
+	if len(e.overlayPathToData) > 0 {
+		return newOverlayReadBucket(bucket, path, e.overlayPathToData)
+	}
 	return bucket, nil
 }
 
-// Can handle formats FormatTar, FormatTarGz
-func (e *envReader) getBucketFromLocalTarball(
+// Can handle formats FormatDir
+//
+// Credentials and the region are resolved using the standard AWS resolution,
+// that is the environment, the shared configuration files, and instance roles.
+func (e *envReader) getBucketFromS3(
+	ctx context.Context,
+	getenv func(string) string,
+	s3URL string,
+) (storage.ReadBucket, error) {
+	bucketName, prefix, err := storages3.ParseURL(s3URL)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.checkAllowedS3Bucket(e.getAllowedHosts(getenv), bucketName); err != nil {
+		return nil, err
+	}
+	client, err := newS3Client()
+	if err != nil {
+		return nil, err
+	}
+	return storages3.NewReadBucket(client, bucketName, prefix), nil
+}
+
+// Can handle formats FormatTar, FormatTarGz, FormatZip
+func (e *envReader) getBucketFromLocalArchive(
 	ctx context.Context,
 	stdin io.Reader,
 	getenv func(string) string,
 	format internal.Format,
+	compression internal.Compression,
 	path string,
 	stripComponents uint32,
 ) (_ storage.ReadBucket, retErr error) {
@@ -554,28 +835,116 @@ func (e *envReader) getBucketFromLocalTarball(
 	}
 	transformerOptions := []storagepath.TransformerOption{
 		storagepath.WithExt(".proto"),
-		storagepath.WithExactPath(bufconfig.ConfigFilePath),
 	}
 	if stripComponents > 0 {
 		transformerOptions = append(
 			transformerOptions,
+			storagepath.WithExactPath(bufconfig.ConfigFilePath),
 			storagepath.WithStripComponents(stripComponents),
 		)
+	} else {
+		// we also keep the config file of a top-level directory, so that an archive
+		// of a directory can be used the same as the directory, see getArchiveDirBucket
+		transformerOptions = append(
+			transformerOptions,
+			storagepath.WithMatcher(isArchiveConfigFilePath),
+		)
 	}
 	bucket := storagemem.NewBucket()
+	errorPrefix := "untar"
 	switch format {
 	case internal.FormatTar:
-		err = storageutil.Untar(ctx, bytes.NewReader(data), bucket, transformerOptions...)
+		switch compression {
+		case 0:
+			err = storageutil.Untar(ctx, bytes.NewReader(data), bucket, e.allowSymlinks, transformerOptions...)
+		case internal.CompressionZstd:
+			err = storageutil.Untarzst(ctx, bytes.NewReader(data), bucket, e.allowSymlinks, transformerOptions...)
+		default:
+			return nil, fmt.Errorf("got compression %v outside of parse", compression)
+		}
 	case internal.FormatTarGz:
-		err = storageutil.Untargz(ctx, bytes.NewReader(data), bucket, transformerOptions...)
+		err = storageutil.Untargz(ctx, bytes.NewReader(data), bucket, e.allowSymlinks, transformerOptions...)
+	case internal.FormatZip:
+		errorPrefix = "unzip"
+		err = storageutil.Unzip(ctx, bytes.NewReader(data), int64(len(data)), bucket, e.allowSymlinks, transformerOptions...)
 	default:
 		return nil, fmt.Errorf("got image format %v outside of parse", format)
 	}
 	if err != nil {
+		if storageutil.IsLinkPolicyError(err) && e.allowSymlinksFlagName != "" {
+			err = fmt.Errorf("%v (use --%s to skip symlinks outside of the extraction root)", err, e.allowSymlinksFlagName)
+		}
 		// TODO: this isn't really an invalid argument
-		return nil, multierr.Append(fmt.Errorf("untar error: %v", err), bucket.Close())
+		return nil, multierr.Append(fmt.Errorf("%s error: %v", errorPrefix, err), bucket.Close())
 	}
-	return bucket, nil
+	if stripComponents > 0 {
+		return bucket, nil
+	}
+	return e.getArchiveDirBucket(ctx, bucket)
+}
+
+// getArchiveDirBucket returns a bucket for the single top-level directory of
+// the archive bucket if the archive is of a directory with a config file, such
+// as the output of tar czf - dir, so that the archive is treated the same as
+// the directory. Otherwise, the archive bucket is returned.
+//
+// The archive bucket is closed if a new bucket is returned.
+func (e *envReader) getArchiveDirBucket(
+	ctx context.Context,
+	bucket storage.Bucket,
+) (_ storage.ReadBucket, retErr error) {
+	if _, err := bucket.Stat(ctx, bufconfig.ConfigFilePath); err == nil || !storage.IsNotExist(err) {
+		return bucket, err
+	}
+	var topLevelDirPath string
+	if err := bucket.Walk(
+		ctx,
+		"",
+		func(path string) error {
+			components := storagepath.Components(path)
+			if len(components) < 2 {
+				// a file at the root, so this is not an archive of a single directory
+				topLevelDirPath = ""
+				return errStopWalk
+			}
+			if topLevelDirPath != "" && topLevelDirPath != components[0] {
+				topLevelDirPath = ""
+				return errStopWalk
+			}
+			topLevelDirPath = components[0]
+			return nil
+		},
+	); err != nil && err != errStopWalk {
+		return nil, multierr.Append(err, bucket.Close())
+	}
+	if topLevelDirPath == "" {
+		return bucket, nil
+	}
+	if _, err := bucket.Stat(ctx, storagepath.Join(topLevelDirPath, bufconfig.ConfigFilePath)); err != nil {
+		if storage.IsNotExist(err) {
+			return bucket, nil
+		}
+		return nil, multierr.Append(err, bucket.Close())
+	}
+	e.logger.Debug("archive_dir", zap.String("path", topLevelDirPath))
+	defer func() {
+		retErr = multierr.Append(retErr, bucket.Close())
+	}()
+	dirBucket := storagemem.NewBucket()
+	if _, err := storageutil.Copy(ctx, bucket, dirBucket, "", storagepath.WithStripComponents(1)); err != nil {
+		return nil, multierr.Append(err, dirBucket.Close())
+	}
+	return dirBucket, nil
+}
+
+// isArchiveConfigFilePath returns true if the path is the config file at the
+// root of an archive, or within a top-level directory of the archive.
+func isArchiveConfigFilePath(path string) bool {
+	if path == bufconfig.ConfigFilePath {
+		return true
+	}
+	components := storagepath.Components(path)
+	return len(components) == 2 && components[1] == bufconfig.ConfigFilePath
 }
 
 // For FormatGit
@@ -584,29 +953,52 @@ func (e *envReader) getBucketFromGitRepo(
 	getenv func(string) string,
 	gitRepo string,
 	gitRefName storagegitplumbing.RefName,
+	gitDepth uint32,
+	gitRecurseSubmodules bool,
+	gitSubDir string,
+	knownRoots []string,
 ) (_ storage.ReadBucket, retErr error) {
 	defer utillog.Defer(e.logger, "get_git_bucket_memory")()
 
+	if err := e.checkAllowedGitURL(e.getAllowedHosts(getenv), gitRepo); err != nil {
+		return nil, err
+	}
+
 	homeDirPath, err := clios.Home(getenv)
 	if err != nil {
 		return nil, err
 	}
+	sparsePaths := getGitSparsePaths(gitSubDir, knownRoots)
+	transformerOptions := []storagepath.TransformerOption{
+		storagepath.WithExt(".proto"),
+		storagepath.WithExactPath(bufconfig.ConfigFilePath),
+	}
+	if gitSubDir != "" {
+		transformerOptions = append(
+			transformerOptions,
+			storagepath.WithStripComponents(uint32(len(storagepath.Components(gitSubDir)))),
+		)
+	}
+	e.logger.Debug("git_sparse_paths", zap.Strings("sparse_paths", sparsePaths))
 	bucket := storagemem.NewBucket()
 	if err := storagegit.Clone(
 		ctx,
 		e.logger,
-		getenv,
+		e.getGitGetenv(getenv),
 		homeDirPath,
 		gitRepo,
 		gitRefName,
+		gitDepth,
+		gitRecurseSubmodules,
+		sparsePaths,
 		e.httpsUsernameEnvKey,
 		e.httpsPasswordEnvKey,
 		e.sshKeyFileEnvKey,
 		e.sshKeyPassphraseEnvKey,
 		e.sshKnownHostsFilesEnvKey,
+		e.gitPureGoEnvKey,
 		bucket,
-		storagepath.WithExt(".proto"),
-		storagepath.WithExactPath(bufconfig.ConfigFilePath),
+		transformerOptions...,
 	); err != nil {
 		return nil, multierr.Append(
 			fmt.Errorf("could not clone %s: %v", gitRepo, err),
@@ -616,21 +1008,120 @@ func (e *envReader) getBucketFromGitRepo(
 	return bucket, nil
 }
 
-// Can handle formats FormatBin, FormatBinGz, FormatJSON, FormatJSONGz
+// getGitGetenv returns the getenv to clone git repositories with.
+//
+// If the pure-Go git implementation was selected with EnvReaderWithGitPureGo,
+// gitPureGoEnvKey is set to true regardless of the environment.
+func (e *envReader) getGitGetenv(getenv func(string) string) func(string) string {
+	if !e.gitPureGo || e.gitPureGoEnvKey == "" {
+		return getenv
+	}
+	return func(key string) string {
+		if key == e.gitPureGoEnvKey {
+			return "true"
+		}
+		if getenv == nil {
+			return ""
+		}
+		return getenv(key)
+	}
+}
+
+// getGitSparsePaths returns the paths of the git repository that need to be fetched.
+//
+// If there are known roots, only the roots under the subdir are needed, otherwise
+// the whole subdir is needed. Returns nil if the whole repository is needed.
+func getGitSparsePaths(gitSubDir string, knownRoots []string) []string {
+	if len(knownRoots) == 0 {
+		if gitSubDir == "" {
+			return nil
+		}
+		return []string{gitSubDir}
+	}
+	sparsePaths := make([]string, len(knownRoots))
+	for i, knownRoot := range knownRoots {
+		if gitSubDir == "" {
+			sparsePaths[i] = knownRoot
+		} else {
+			sparsePaths[i] = storagepath.Join(gitSubDir, knownRoot)
+		}
+	}
+	return sparsePaths
+}
+
+// Can handle formats FormatBin, FormatBinGz, FormatJSON, FormatJSONGz, FormatYAML, FormatYAMLGz
 func (e *envReader) getImageFromLocalFile(
 	ctx context.Context,
 	stdin io.Reader,
 	getenv func(string) string,
 	format internal.Format,
 	path string,
+	sha256Hex string,
 ) (_ *imagev1beta1.Image, retErr error) {
 	data, err := e.getFileData(ctx, stdin, getenv, path)
 	if err != nil {
 		return nil, err
 	}
+	// sidecar files are only read for files on the local filesystem
+	isOSFile := path != "-" &&
+		!strings.HasPrefix(path, "http://") &&
+		!strings.HasPrefix(path, "https://") &&
+		!storages3.IsURL(path) &&
+		!strings.HasPrefix(path, gcsURLPrefix)
+	if sha256Hex != "" || isOSFile {
+		if err := verifyChecksum(strings.TrimPrefix(path, "file://"), data, sha256Hex); err != nil {
+			return nil, err
+		}
+	}
 	return e.getImageFromData(format, data)
 }
 
+// For FormatOCIRepo
+func (e *envReader) getImageFromOCIRepo(
+	ctx context.Context,
+	getenv func(string) string,
+	path string,
+) (*imagev1beta1.Image, error) {
+	reference, err := utiloci.ParseReference(strings.TrimPrefix(path, internal.OCIRepoPathPrefix))
+	if err != nil {
+		return nil, err
+	}
+	if err := e.checkAllowedHost(e.getAllowedHosts(getenv), reference.Registry); err != nil {
+		return nil, err
+	}
+	data, err := utiloci.NewClient(e.httpClient, getenv).Pull(ctx, reference, ociImageLayerMediaType)
+	if err != nil {
+		return nil, fmt.Errorf("could not pull %s: %v", path, err)
+	}
+	return e.getImageFromData(internal.FormatBin, data)
+}
+
+// For FormatModule
+//
+// Returns the image and the config data of the module.
+func (e *envReader) getImageFromModule(
+	ctx context.Context,
+	getenv func(string) string,
+	path string,
+) (*imagev1beta1.Image, []byte, error) {
+	moduleReference, err := bufregistry.ParseModuleReference(strings.TrimPrefix(path, internal.ModulePathPrefix))
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := e.checkAllowedHost(e.getAllowedHosts(getenv), moduleReference.Remote); err != nil {
+		return nil, nil, err
+	}
+	module, err := bufregistry.NewClient(e.httpClient, getenv).Pull(ctx, moduleReference)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not pull %s: %v", path, err)
+	}
+	image, err := e.getImageFromData(internal.FormatBin, module.Image)
+	if err != nil {
+		return nil, nil, err
+	}
+	return image, module.Config, nil
+}
+
 func (e *envReader) getFileData(
 	ctx context.Context,
 	stdin io.Reader,
@@ -640,14 +1131,63 @@ func (e *envReader) getFileData(
 	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
 		return e.getFileDataFromHTTP(ctx, getenv, path)
 	}
+	if storages3.IsURL(path) {
+		return e.getFileDataFromS3(ctx, getenv, path)
+	}
+	if strings.HasPrefix(path, gcsURLPrefix) {
+		return e.getFileDataFromGCS(ctx, getenv, path)
+	}
 	return e.getFileDataFromOS(stdin, path)
 }
 
+func (e *envReader) getFileDataFromS3(
+	ctx context.Context,
+	getenv func(string) string,
+	s3URL string,
+) (_ []byte, retErr error) {
+	bucketName, key, err := storages3.ParseURL(s3URL)
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return nil, fmt.Errorf("%s does not have a key", s3URL)
+	}
+	if err := e.checkAllowedS3Bucket(e.getAllowedHosts(getenv), bucketName); err != nil {
+		return nil, err
+	}
+	client, err := newS3Client()
+	if err != nil {
+		return nil, err
+	}
+	output, err := client.GetObjectWithContext(
+		ctx,
+		&s3.GetObjectInput{
+			Bucket: aws.String(bucketName),
+			Key:    aws.String(key),
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not get %s: %v", s3URL, err)
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, output.Body.Close())
+	}()
+	data, err := ioutil.ReadAll(output.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", s3URL, err)
+	}
+	return data, nil
+}
+
 func (e *envReader) getFileDataFromHTTP(
 	ctx context.Context,
 	getenv func(string) string,
 	path string,
 ) (_ []byte, retErr error) {
+	allowedHosts := e.getAllowedHosts(getenv)
+	if err := e.checkAllowedURL(allowedHosts, path); err != nil {
+		return nil, err
+	}
 	request, err := http.NewRequestWithContext(ctx, "GET", path, nil)
 	if err != nil {
 		return nil, err
@@ -659,7 +1199,64 @@ func (e *envReader) getFileDataFromHTTP(
 			request.SetBasicAuth(httpsUsername, httpsPassword)
 		}
 	}
-	response, err := e.httpClient.Do(request)
+	httpClient := e.newAllowedHostsHTTPClient(allowedHosts)
+	if cacheDirPath := e.getHTTPCacheDirPath(getenv); cacheDirPath != "" {
+		return e.getFileDataFromHTTPCache(httpClient, request, cacheDirPath)
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got HTTP status code %d for %s", response.StatusCode, path)
+	}
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", path, err)
+	}
+	return data, nil
+}
+
+// getFileDataFromGCS gets the object at the gs://bucket/path URL using the
+// GCS JSON API.
+//
+// Credentials are resolved using the application default credentials.
+func (e *envReader) getFileDataFromGCS(
+	ctx context.Context,
+	getenv func(string) string,
+	path string,
+) (_ []byte, retErr error) {
+	bucketName, objectName, err := parseGCSURL(path)
+	if err != nil {
+		return nil, err
+	}
+	objectURL := fmt.Sprintf(
+		"https://%s/storage/v1/b/%s/o/%s?alt=media",
+		gcsAPIHost,
+		url.PathEscape(bucketName),
+		url.PathEscape(objectName),
+	)
+	allowedHosts := e.getAllowedHosts(getenv)
+	if err := e.checkAllowedURL(allowedHosts, objectURL); err != nil {
+		return nil, err
+	}
+	credentials, err := google.FindDefaultCredentials(ctx, gcsReadOnlyScope)
+	if err != nil {
+		return nil, fmt.Errorf("could not find application default credentials: %v", err)
+	}
+	request, err := http.NewRequestWithContext(ctx, "GET", objectURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpClient := *e.newAllowedHostsHTTPClient(allowedHosts)
+	httpClient.Transport = &oauth2.Transport{
+		Source: credentials.TokenSource,
+		Base:   httpClient.Transport,
+	}
+	response, err := httpClient.Do(request)
 	if err != nil {
 		return nil, err
 	}
@@ -700,12 +1297,12 @@ func (e *envReader) getFileDataFromOS(
 	return data, nil
 }
 
-// Can handle formats FormatBin, FormatBinGz, FormatJSON, FormatJSONGz
+// Can handle formats FormatBin, FormatBinGz, FormatJSON, FormatJSONGz, FormatYAML, FormatYAMLGz
 func (e *envReader) getImageFromData(
 	format internal.Format,
 	data []byte,
 ) (_ *imagev1beta1.Image, retErr error) {
-	if format == internal.FormatBinGz || format == internal.FormatJSONGz {
+	if format == internal.FormatBinGz || format == internal.FormatJSONGz || format == internal.FormatYAMLGz {
 		// TODO: this has to be woefully inefficient
 		// we can prob do a non-copy
 		gzipReader, err := gzip.NewReader(bytes.NewReader(data))
@@ -729,6 +1326,12 @@ func (e *envReader) getImageFromData(
 		err = proto.Unmarshal(data, image)
 	case internal.FormatJSON, internal.FormatJSONGz:
 		err = unmarshalJSON(data, image)
+	case internal.FormatYAML, internal.FormatYAMLGz:
+		// YAML images are the YAML rendering of the JSON mapping
+		data, err = utilencoding.YAMLToJSON(data)
+		if err == nil {
+			err = unmarshalJSON(data, image)
+		}
 	default:
 		return nil, fmt.Errorf("got image format %v outside of parse", format)
 	}
@@ -744,3 +1347,26 @@ func (e *envReader) getImageFromData(
 func unmarshalJSON(data []byte, message proto.Message) error {
 	return jsonUnmarshaler.Unmarshal(bytes.NewReader(data), message)
 }
+
+// parseGCSURL parses the gs://bucket/path URL into the bucket and object names.
+func parseGCSURL(path string) (string, string, error) {
+	split := strings.SplitN(strings.TrimPrefix(path, gcsURLPrefix), "/", 2)
+	if len(split) != 2 || split[0] == "" || strings.Trim(split[1], "/") == "" {
+		return "", "", fmt.Errorf("%s must be of the form %sbucket/path", path, gcsURLPrefix)
+	}
+	return split[0], strings.Trim(split[1], "/"), nil
+}
+
+// newS3Client returns a new S3 client using the standard AWS credential
+// and region resolution.
+func newS3Client() (*s3.S3, error) {
+	awsSession, err := session.NewSessionWithOptions(
+		session.Options{
+			SharedConfigState: session.SharedConfigEnable,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not create AWS session: %v", err)
+	}
+	return s3.New(awsSession), nil
+}
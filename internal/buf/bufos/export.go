@@ -0,0 +1,85 @@
+package bufos
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/bufbuild/buf/internal/buf/ext/extimage"
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/bufbuild/buf/internal/pkg/storage"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagepath"
+	"github.com/bufbuild/buf/internal/pkg/storage/storageutil"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoprint"
+)
+
+// exportImage writes the files of the image to the export bucket with paths
+// relative to the roots.
+//
+// Files are read from the bucket or the dependency buckets the same way the
+// build reads them, that is from the first root of the bucket that has the
+// file, and then the dependency buckets. Files that are in neither are
+// printed from their descriptors.
+func exportImage(
+	ctx context.Context,
+	image *imagev1beta1.Image,
+	roots []string,
+	bucket storage.ReadBucket,
+	depBuckets []storage.ReadBucket,
+	exportBucket storage.Bucket,
+) error {
+	// only linked if there are files without a source
+	var nameToDescFileDescriptor map[string]*desc.FileDescriptor
+	for _, file := range image.GetFile() {
+		name := file.GetName()
+		data, err := readRootFile(ctx, name, roots, append([]storage.ReadBucket{bucket}, depBuckets...))
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			if nameToDescFileDescriptor == nil {
+				fileDescriptorSet, err := extimage.ImageToFileDescriptorSet(image)
+				if err != nil {
+					return err
+				}
+				nameToDescFileDescriptor, err = desc.CreateFileDescriptorsFromSet(fileDescriptorSet)
+				if err != nil {
+					return err
+				}
+			}
+			descFileDescriptor, ok := nameToDescFileDescriptor[name]
+			if !ok {
+				return fmt.Errorf("no descriptor for %s", name)
+			}
+			buffer := bytes.NewBuffer(nil)
+			if err := (&protoprint.Printer{}).PrintProtoFile(descFileDescriptor, buffer); err != nil {
+				return fmt.Errorf("could not print %s: %v", name, err)
+			}
+			data = buffer.Bytes()
+		}
+		if err := storageutil.WritePath(ctx, exportBucket, name, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readRootFile reads the file relative to the roots from the first bucket
+// and root that has the file.
+//
+// Returns nil if no bucket has the file.
+func readRootFile(ctx context.Context, name string, roots []string, buckets []storage.ReadBucket) ([]byte, error) {
+	for _, bucket := range buckets {
+		for _, root := range roots {
+			data, err := storageutil.ReadPath(ctx, bucket, storagepath.Join(root, name))
+			if err == nil {
+				return data, nil
+			}
+			if !storage.IsNotExist(err) {
+				return nil, err
+			}
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,102 @@
+package bufos
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/bufbuild/buf/internal/buf/bufconfig"
+	"github.com/bufbuild/buf/internal/pkg/util/utillog"
+	"github.com/bufbuild/cli/clios"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+// getConfigWithLintExtends gets the config with the extended lint config merged,
+// if the lint config extends a shared config.
+//
+// Extended configs are verified against their sha256 hash, and are cached under
+// $XDG_CACHE_HOME/buf/extends by hash, so they are only downloaded once.
+func (e *envReader) getConfigWithLintExtends(
+	ctx context.Context,
+	getenv func(string) string,
+	config *bufconfig.Config,
+) (*bufconfig.Config, error) {
+	if config.LintExtends == "" {
+		return config, nil
+	}
+	rawURL, sha256Hex, err := bufconfig.ParseLintExtends(config.LintExtends)
+	if err != nil {
+		return nil, err
+	}
+	var cacheFilePath string
+	if getenv != nil {
+		if cacheDirPath, err := clios.XdgCacheHome(getenv); err == nil {
+			cacheFilePath = filepath.Join(cacheDirPath, "buf", "extends", sha256Hex)
+		} else {
+			e.logger.Debug("extends_cache_disabled", zap.Error(err))
+		}
+	}
+	if cacheFilePath != "" {
+		if data, err := ioutil.ReadFile(cacheFilePath); err == nil && getSHA256Hex(data) == sha256Hex {
+			e.logger.Debug("extends_cache_hit", zap.String("url", rawURL))
+			return bufconfig.WithLintExtends(config, data)
+		}
+	}
+	data, err := e.getLintExtendsData(ctx, getenv, rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("lint.extends: could not get %s: %v", rawURL, err)
+	}
+	if actualSHA256Hex := getSHA256Hex(data); actualSHA256Hex != sha256Hex {
+		return nil, fmt.Errorf("lint.extends: sha256 of %s was %s but expected %s", rawURL, actualSHA256Hex, sha256Hex)
+	}
+	if cacheFilePath != "" {
+		if err := os.MkdirAll(filepath.Dir(cacheFilePath), 0755); err != nil {
+			e.logger.Debug("extends_cache_write_failed", zap.String("url", rawURL), zap.Error(err))
+		} else if err := writeFileAtomic(cacheFilePath, data, 0600); err != nil {
+			e.logger.Debug("extends_cache_write_failed", zap.String("url", rawURL), zap.Error(err))
+		}
+	}
+	return bufconfig.WithLintExtends(config, data)
+}
+
+// getLintExtendsData downloads the extended config.
+//
+// The allowed hosts for remote inputs apply, but the credentials for inputs are never
+// sent, as the extended config is usually hosted by a third party.
+func (e *envReader) getLintExtendsData(
+	ctx context.Context,
+	getenv func(string) string,
+	rawURL string,
+) (_ []byte, retErr error) {
+	defer utillog.Defer(e.logger, "download_extends", zap.String("url", rawURL))()
+	allowedHosts := e.getAllowedHosts(getenv)
+	if err := e.checkAllowedURL(allowedHosts, rawURL); err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := e.newAllowedHostsHTTPClient(allowedHosts).Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got HTTP status code %d for %s", response.StatusCode, rawURL)
+	}
+	return ioutil.ReadAll(response.Body)
+}
+
+func getSHA256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,85 @@
+package bufos
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bufbuild/buf/internal/buf/bufbuild"
+	"github.com/bufbuild/buf/internal/buf/bufconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestGetConfigWithLintExtendsNoCredentials(t *testing.T) {
+	t.Parallel()
+	cacheDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(cacheDirPath)) }()
+	extendsData := []byte("lint:\n  use:\n    - BASIC\n")
+	sum := sha256.Sum256(extendsData)
+	var requestCount int64
+	var authorizationCount int64
+	server := httptest.NewTLSServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				atomic.AddInt64(&requestCount, 1)
+				if request.Header.Get("Authorization") != "" {
+					atomic.AddInt64(&authorizationCount, 1)
+				}
+				_, _ = responseWriter.Write(extendsData)
+			},
+		),
+	)
+	defer server.Close()
+
+	logger := zap.NewNop()
+	configProvider := bufconfig.NewProvider(logger)
+	envReader := newEnvReader(
+		logger,
+		server.Client(),
+		configProvider,
+		bufbuild.NewHandler(logger),
+		"input",
+		"input-config",
+		"HTTPS_USERNAME",
+		"HTTPS_PASSWORD",
+		"",
+		"",
+		"",
+		"",
+		"",
+	)
+	config, err := configProvider.GetConfigForData(
+		[]byte(fmt.Sprintf(`{"lint":{"extends":"%s/policy.yaml#sha256=%s"}}`, server.URL, hex.EncodeToString(sum[:]))),
+	)
+	require.NoError(t, err)
+	// the credentials for inputs are never sent to the host of the extended config
+	_, err = envReader.getConfigWithLintExtends(
+		context.Background(),
+		func(key string) string {
+			switch key {
+			case "HTTPS_USERNAME":
+				return "username"
+			case "HTTPS_PASSWORD":
+				return "password"
+			case "XDG_CACHE_HOME":
+				return cacheDirPath
+			default:
+				return ""
+			}
+		},
+		config,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&requestCount))
+	assert.Equal(t, int64(0), atomic.LoadInt64(&authorizationCount))
+}
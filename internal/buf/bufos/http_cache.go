@@ -0,0 +1,177 @@
+package bufos
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bufbuild/buf/internal/pkg/util/utilencoding"
+	"github.com/bufbuild/cli/clios"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+// httpCacheEntry is the metadata for a cached HTTP response.
+//
+// The data is stored in a sibling file so that the metadata can be read
+// without reading the potentially large data.
+type httpCacheEntry struct {
+	URL          string    `json:"url,omitempty" yaml:"url,omitempty"`
+	ETag         string    `json:"etag,omitempty" yaml:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty" yaml:"last_modified,omitempty"`
+	SHA256       string    `json:"sha256,omitempty" yaml:"sha256,omitempty"`
+	ValidatedAt  time.Time `json:"validated_at,omitempty" yaml:"validated_at,omitempty"`
+}
+
+// getHTTPCacheDirPath gets the directory path of the HTTP cache.
+//
+// Returns empty if the cache is disabled or the directory cannot be determined.
+func (e *envReader) getHTTPCacheDirPath(getenv func(string) string) string {
+	if !e.httpCacheEnabled {
+		return ""
+	}
+	if e.httpCacheDirPath != "" {
+		return e.httpCacheDirPath
+	}
+	cacheDirPath, err := clios.XdgCacheHome(getenv)
+	if err != nil {
+		e.logger.Debug("http_cache_disabled", zap.Error(err))
+		return ""
+	}
+	return filepath.Join(cacheDirPath, "buf", "http")
+}
+
+// getFileDataFromHTTPCache gets the data for the URL using the HTTP cache.
+//
+// If the cached entry was validated within the TTL, it is used without any request.
+// Otherwise, a conditional request is sent with If-None-Match and If-Modified-Since,
+// and the cached data is used if the server responds with 304 Not Modified.
+// Responses without an ETag or Last-Modified header are never cached.
+//
+// The request must not have been sent yet. Failures to read or write the cache
+// are logged and otherwise ignored.
+func (e *envReader) getFileDataFromHTTPCache(
+	httpClient *http.Client,
+	request *http.Request,
+	cacheDirPath string,
+) (_ []byte, retErr error) {
+	rawURL := request.URL.String()
+	sum := sha256.Sum256([]byte(rawURL))
+	entryFilePath := filepath.Join(cacheDirPath, hex.EncodeToString(sum[:])+".json")
+	dataFilePath := filepath.Join(cacheDirPath, hex.EncodeToString(sum[:])+".data")
+
+	entry, cachedData := e.readHTTPCacheEntry(rawURL, entryFilePath, dataFilePath)
+	if entry != nil {
+		if e.httpCacheTTL > 0 && time.Since(entry.ValidatedAt) < e.httpCacheTTL {
+			e.logger.Debug("http_cache_hit", zap.String("url", rawURL))
+			return cachedData, nil
+		}
+		if entry.ETag != "" {
+			request.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			request.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+	if entry != nil && response.StatusCode == http.StatusNotModified {
+		e.logger.Debug("http_cache_not_modified", zap.String("url", rawURL))
+		entry.ValidatedAt = time.Now().UTC()
+		if err := e.writeHTTPCacheEntry(cacheDirPath, entryFilePath, "", entry, nil); err != nil {
+			e.logger.Debug("http_cache_write_error", zap.Error(err))
+		}
+		return cachedData, nil
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got HTTP status code %d for %s", response.StatusCode, rawURL)
+	}
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", rawURL, err)
+	}
+	newEntry := &httpCacheEntry{
+		URL:          rawURL,
+		ETag:         response.Header.Get("ETag"),
+		LastModified: response.Header.Get("Last-Modified"),
+		ValidatedAt:  time.Now().UTC(),
+	}
+	if newEntry.ETag == "" && newEntry.LastModified == "" {
+		return data, nil
+	}
+	dataSum := sha256.Sum256(data)
+	newEntry.SHA256 = hex.EncodeToString(dataSum[:])
+	if err := e.writeHTTPCacheEntry(cacheDirPath, entryFilePath, dataFilePath, newEntry, data); err != nil {
+		e.logger.Debug("http_cache_write_error", zap.Error(err))
+	}
+	return data, nil
+}
+
+// readHTTPCacheEntry reads the cached entry and data for the URL.
+//
+// Returns nil if there is no valid cached entry.
+func (e *envReader) readHTTPCacheEntry(
+	rawURL string,
+	entryFilePath string,
+	dataFilePath string,
+) (*httpCacheEntry, []byte) {
+	entryData, err := ioutil.ReadFile(entryFilePath)
+	if err != nil {
+		return nil, nil
+	}
+	entry := &httpCacheEntry{}
+	if err := utilencoding.UnmarshalJSONStrict(entryData, entry); err != nil {
+		e.logger.Debug("http_cache_invalid_entry", zap.String("url", rawURL), zap.Error(err))
+		return nil, nil
+	}
+	if entry.URL != rawURL {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(dataFilePath)
+	if err != nil {
+		return nil, nil
+	}
+	dataSum := sha256.Sum256(data)
+	if hex.EncodeToString(dataSum[:]) != entry.SHA256 {
+		e.logger.Debug("http_cache_invalid_data", zap.String("url", rawURL))
+		return nil, nil
+	}
+	return entry, data
+}
+
+// writeHTTPCacheEntry writes the data before the entry so that an entry never
+// refers to partially-written data.
+//
+// If dataFilePath is empty, only the entry is written.
+func (e *envReader) writeHTTPCacheEntry(
+	cacheDirPath string,
+	entryFilePath string,
+	dataFilePath string,
+	entry *httpCacheEntry,
+	data []byte,
+) error {
+	if err := os.MkdirAll(cacheDirPath, 0755); err != nil {
+		return err
+	}
+	if dataFilePath != "" {
+		if err := writeFileAtomic(dataFilePath, data, 0600); err != nil {
+			return err
+		}
+	}
+	entryData, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(entryFilePath, entryData, 0600)
+}
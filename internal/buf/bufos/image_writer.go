@@ -4,40 +4,78 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"encoding/json"
+	"fmt"
 	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/bufbuild/buf/internal/buf/bufos/internal"
 	"github.com/bufbuild/buf/internal/buf/ext/extimage"
 	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/bufbuild/buf/internal/pkg/storage/storages3"
+	"github.com/bufbuild/buf/internal/pkg/util/utilencoding"
+	"github.com/bufbuild/buf/internal/pkg/util/utiloci"
 	"github.com/bufbuild/cli/clios"
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
 	"go.uber.org/multierr"
 	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	// ociImageConfigMediaType is the media type of the config of images pushed to OCI registries.
+	ociImageConfigMediaType = "application/vnd.buf.image.config.v1+json"
+	// ociImageLayerMediaType is the media type of the layer of images pushed to OCI registries.
+	//
+	// The layer is the binary serialized image.
+	ociImageLayerMediaType = "application/vnd.buf.image.layer.v1+bin"
 )
 
 var jsonMarshaler = &jsonpb.Marshaler{}
 
 type imageWriter struct {
-	logger         *zap.Logger
-	inputRefParser internal.InputRefParser
+	logger                 *zap.Logger
+	httpClient             *http.Client
+	valueFlagName          string
+	inputRefParser         internal.InputRefParser
+	formatOverrideFlagName string
+	formatOverride         string
+	checksumFlagName       string
+	checksumAlgorithm      string
 }
 
 func newImageWriter(
 	logger *zap.Logger,
+	httpClient *http.Client,
 	valueFlagName string,
+	options ...ImageWriterOption,
 ) *imageWriter {
-	return &imageWriter{
-		logger: logger.Named("bufos"),
+	imageWriter := &imageWriter{
+		logger:        logger.Named("bufos"),
+		httpClient:    httpClient,
+		valueFlagName: valueFlagName,
 		inputRefParser: internal.NewInputRefParser(
 			valueFlagName,
 		),
 	}
+	for _, option := range options {
+		option(imageWriter)
+	}
+	return imageWriter
 }
 
 func (i *imageWriter) WriteImage(
 	ctx context.Context,
 	stdout io.Writer,
+	getenv func(string) string,
 	value string,
 	asFileDescriptorSet bool,
 	image *imagev1beta1.Image,
@@ -45,16 +83,32 @@ func (i *imageWriter) WriteImage(
 	if err := extimage.ValidateImage(image); err != nil {
 		return err
 	}
+	if i.checksumAlgorithm != "" && i.checksumAlgorithm != ChecksumAlgorithmSHA256 {
+		return fmt.Errorf("--%s: unknown checksum algorithm %q, must be %s", i.checksumFlagName, i.checksumAlgorithm, ChecksumAlgorithmSHA256)
+	}
 	// stop short if we have /dev/null equivalent for performance
 	if value == clios.DevNull {
 		return nil
 	}
-	inputRef, err := i.inputRefParser.ParseInputRef(value, false, true)
+	inputRef, err := i.parseInputRef(value)
 	if err != nil {
 		return err
 	}
 	i.logger.Debug("parse", zap.Any("input_ref", inputRef), zap.Stringer("format", inputRef.Format))
-	// we now know the format this is only one of FormatBin, FormatBinGz, FormatJSON, FormatJSONGz
+	// we now know the format this is only one of FormatBin, FormatBinGz, FormatJSON, FormatJSONGz, FormatYAML, FormatYAMLGz, FormatOCIRepo, FormatModule
+	if inputRef.Format == internal.FormatModule {
+		// modules include the config, so they are only written by buf push
+		return fmt.Errorf("%s: %q is a module, modules can only be written with buf push", i.valueFlagName, inputRef.Path)
+	}
+	if i.checksumAlgorithm != "" && (inputRef.Format == internal.FormatOCIRepo ||
+		storages3.IsURL(inputRef.Path) ||
+		strings.HasPrefix(inputRef.Path, gcsURLPrefix) ||
+		(inputRef.Shards == 0 && !shouldWriteImageFileAtomic(inputRef.Path))) {
+		return fmt.Errorf("--%s: checksums can only be written for local files but %s is %q", i.checksumFlagName, i.valueFlagName, inputRef.Path)
+	}
+	if inputRef.Shards > 0 {
+		return i.writeImageShards(inputRef, asFileDescriptorSet, image)
+	}
 
 	var message proto.Message = image
 	if asFileDescriptorSet {
@@ -64,18 +118,39 @@ func (i *imageWriter) WriteImage(
 		}
 	}
 
-	var data []byte
-	switch inputRef.Format {
-	case internal.FormatJSON, internal.FormatJSONGz:
-		data, err = marshalJSON(message)
-		if err != nil {
-			return err
+	data, err := marshalImage(message, inputRef.Format)
+	if err != nil {
+		return err
+	}
+
+	if inputRef.Format == internal.FormatOCIRepo {
+		return i.pushImageToOCIRepo(ctx, getenv, inputRef.Path, data)
+	}
+	if storages3.IsURL(inputRef.Path) || strings.HasPrefix(inputRef.Path, gcsURLPrefix) {
+		// object storage requires the full data up front
+		switch inputRef.Format {
+		case internal.FormatBinGz, internal.FormatJSONGz, internal.FormatYAMLGz:
+			data, err = gzipData(data, inputRef.CompressionLevel)
+			if err != nil {
+				return err
+			}
 		}
-	default:
-		data, err = proto.Marshal(message)
-		if err != nil {
-			return err
+		if storages3.IsURL(inputRef.Path) {
+			return i.putImageToS3(ctx, inputRef.Path, data)
 		}
+		return i.putImageToGCS(ctx, inputRef.Path, data)
+	}
+
+	if shouldWriteImageFileAtomic(inputRef.Path) {
+		// a cancelled or crashed build never leaves a truncated image behind
+		switch inputRef.Format {
+		case internal.FormatBinGz, internal.FormatJSONGz, internal.FormatYAMLGz:
+			data, err = gzipData(data, inputRef.CompressionLevel)
+			if err != nil {
+				return err
+			}
+		}
+		return i.writeImageFile(inputRef.Path, data)
 	}
 
 	writeCloser, err := clios.WriteCloserForFilePath(stdout, inputRef.Path)
@@ -87,8 +162,11 @@ func (i *imageWriter) WriteImage(
 	}()
 
 	switch inputRef.Format {
-	case internal.FormatBinGz, internal.FormatJSONGz:
-		gzipWriteCloser := gzip.NewWriter(writeCloser)
+	case internal.FormatBinGz, internal.FormatJSONGz, internal.FormatYAMLGz:
+		gzipWriteCloser, err := newGzipWriter(writeCloser, inputRef.CompressionLevel)
+		if err != nil {
+			return err
+		}
 		defer func() {
 			retErr = multierr.Append(retErr, gzipWriteCloser.Close())
 		}()
@@ -100,6 +178,304 @@ func (i *imageWriter) WriteImage(
 	}
 }
 
+// writeImageShards splits the image along package boundaries and writes each
+// shard to its own file, along with a manifest of the shards.
+//
+// For a path of dir/image.bin.gz split into two shards, the shards are written to
+// dir/image-00000-of-00002.bin.gz and dir/image-00001-of-00002.bin.gz, and the
+// manifest is written to dir/image.manifest.json.
+func (i *imageWriter) writeImageShards(
+	inputRef *internal.InputRef,
+	asFileDescriptorSet bool,
+	image *imagev1beta1.Image,
+) error {
+	path := inputRef.Path
+	if path == "-" || path == clios.DevNull || storages3.IsURL(path) || strings.HasPrefix(path, gcsURLPrefix) {
+		return fmt.Errorf("%s: shards can only be written to local files", i.valueFlagName)
+	}
+	imageShards, err := extimage.ImageShards(image, int(inputRef.Shards))
+	if err != nil {
+		return fmt.Errorf("%s: %v", i.valueFlagName, err)
+	}
+	pathPrefix, pathExt := splitImageFilePath(path)
+	shardPaths := make([]string, len(imageShards))
+	for shardIndex := range imageShards {
+		shardPaths[shardIndex] = fmt.Sprintf("%s-%05d-of-%05d%s", pathPrefix, shardIndex, len(imageShards), pathExt)
+	}
+	manifest := &externalImageShardManifest{
+		Shards: make([]*externalImageShard, 0, len(imageShards)),
+	}
+	for shardIndex, imageShard := range imageShards {
+		var message proto.Message = imageShard.Image
+		if asFileDescriptorSet {
+			message, err = extimage.ImageToFileDescriptorSet(imageShard.Image)
+			if err != nil {
+				return err
+			}
+		}
+		data, err := marshalImage(message, inputRef.Format)
+		if err != nil {
+			return err
+		}
+		switch inputRef.Format {
+		case internal.FormatBinGz, internal.FormatJSONGz, internal.FormatYAMLGz:
+			data, err = gzipData(data, inputRef.CompressionLevel)
+			if err != nil {
+				return err
+			}
+		}
+		shardPath := shardPaths[shardIndex]
+		if err := i.writeImageFile(shardPath, data); err != nil {
+			return err
+		}
+		externalShard := &externalImageShard{
+			Path:     filepath.Base(shardPath),
+			Packages: imageShard.Packages,
+		}
+		for _, file := range imageShard.Image.File {
+			externalShard.Files = append(externalShard.Files, file.GetName())
+		}
+		for _, dependency := range imageShard.Dependencies {
+			externalShard.Dependencies = append(externalShard.Dependencies, filepath.Base(shardPaths[dependency]))
+		}
+		manifest.Shards = append(manifest.Shards, externalShard)
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return i.writeImageFile(pathPrefix+".manifest.json", append(data, '\n'))
+}
+
+// writeImageFile writes the data to the local file, along with the checksum
+// sidecar file if a checksum algorithm is set.
+func (i *imageWriter) writeImageFile(path string, data []byte) error {
+	if err := writeFileAtomic(path, data, getImageFileMode(path)); err != nil {
+		return err
+	}
+	if i.checksumAlgorithm == "" {
+		return nil
+	}
+	return writeChecksumSidecar(path, data)
+}
+
+// externalImageShardManifest is the manifest of an image split into shards.
+type externalImageShardManifest struct {
+	Shards []*externalImageShard `json:"shards,omitempty" yaml:"shards,omitempty"`
+}
+
+// externalImageShard is a shard of an image.
+//
+// The paths are relative to the directory of the manifest.
+type externalImageShard struct {
+	Path     string   `json:"path,omitempty" yaml:"path,omitempty"`
+	Packages []string `json:"packages,omitempty" yaml:"packages,omitempty"`
+	Files    []string `json:"files,omitempty" yaml:"files,omitempty"`
+	// Dependencies are the paths of the other shards that contain files imported
+	// by the files of the shard.
+	Dependencies []string `json:"dependencies,omitempty" yaml:"dependencies,omitempty"`
+}
+
+// splitImageFilePath splits the path into the path without the image file
+// extension, and the extension, such as .bin or .json.gz.
+//
+// If the path does not have an image file extension, the extension is empty.
+func splitImageFilePath(path string) (string, string) {
+	ext := filepath.Ext(path)
+	if ext == ".gz" {
+		ext = filepath.Ext(strings.TrimSuffix(path, ext)) + ext
+	}
+	switch ext {
+	case ".bin", ".json", ".yaml", ".yml", ".bin.gz", ".json.gz", ".yaml.gz", ".yml.gz":
+		return strings.TrimSuffix(path, ext), ext
+	default:
+		return path, ""
+	}
+}
+
+// shouldWriteImageFileAtomic returns true if the path is a regular file or
+// does not exist yet.
+//
+// Stdout, /dev/null, and other files such as named pipes and devices are
+// written to directly, as they cannot be replaced by a rename.
+func shouldWriteImageFileAtomic(path string) bool {
+	if path == "-" || path == clios.DevNull {
+		return false
+	}
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+	return fileInfo.Mode().IsRegular()
+}
+
+// getImageFileMode returns the mode of the existing file at the path, so that
+// it is kept when the file is replaced, or 0644 if there is no such file.
+func getImageFileMode(path string) os.FileMode {
+	if fileInfo, err := os.Stat(path); err == nil {
+		return fileInfo.Mode().Perm()
+	}
+	return 0644
+}
+
+func (i *imageWriter) pushImageToOCIRepo(
+	ctx context.Context,
+	getenv func(string) string,
+	path string,
+	data []byte,
+) error {
+	reference, err := utiloci.ParseReference(strings.TrimPrefix(path, internal.OCIRepoPathPrefix))
+	if err != nil {
+		return err
+	}
+	if err := utiloci.NewClient(i.httpClient, getenv).Push(
+		ctx,
+		reference,
+		ociImageConfigMediaType,
+		ociImageLayerMediaType,
+		data,
+	); err != nil {
+		return fmt.Errorf("could not push %s: %v", path, err)
+	}
+	return nil
+}
+
+// putImageToS3 puts the data to the s3://bucket/key URL.
+//
+// Credentials and the region are resolved using the standard AWS resolution.
+func (i *imageWriter) putImageToS3(
+	ctx context.Context,
+	s3URL string,
+	data []byte,
+) error {
+	bucketName, key, err := storages3.ParseURL(s3URL)
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		return fmt.Errorf("%s does not have a key", s3URL)
+	}
+	client, err := newS3Client()
+	if err != nil {
+		return err
+	}
+	if _, err := client.PutObjectWithContext(
+		ctx,
+		&s3.PutObjectInput{
+			Bucket: aws.String(bucketName),
+			Key:    aws.String(key),
+			Body:   bytes.NewReader(data),
+		},
+	); err != nil {
+		return fmt.Errorf("could not put %s: %v", s3URL, err)
+	}
+	return nil
+}
+
+// putImageToGCS uploads the data to the gs://bucket/path URL using the
+// GCS JSON API.
+//
+// Credentials are resolved using the application default credentials.
+func (i *imageWriter) putImageToGCS(
+	ctx context.Context,
+	path string,
+	data []byte,
+) (retErr error) {
+	bucketName, objectName, err := parseGCSURL(path)
+	if err != nil {
+		return err
+	}
+	uploadURL := fmt.Sprintf(
+		"https://%s/upload/storage/v1/b/%s/o?uploadType=media&name=%s",
+		gcsAPIHost,
+		url.PathEscape(bucketName),
+		url.QueryEscape(objectName),
+	)
+	credentials, err := google.FindDefaultCredentials(ctx, gcsReadWriteScope)
+	if err != nil {
+		return fmt.Errorf("could not find application default credentials: %v", err)
+	}
+	request, err := http.NewRequestWithContext(ctx, "POST", uploadURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/octet-stream")
+	httpClient := *i.httpClient
+	httpClient.Transport = &oauth2.Transport{
+		Source: credentials.TokenSource,
+		Base:   httpClient.Transport,
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("got HTTP status code %d for %s", response.StatusCode, path)
+	}
+	return nil
+}
+
+func (i *imageWriter) parseInputRef(value string) (*internal.InputRef, error) {
+	if i.formatOverride == "" {
+		return i.inputRefParser.ParseInputRef(value, false, true)
+	}
+	if strings.Contains(value, "#") {
+		return nil, fmt.Errorf("%s: cannot be used if %s has options", i.formatOverrideFlagName, i.valueFlagName)
+	}
+	format, err := internal.ParseFormatOverride(i.formatOverrideFlagName, i.formatOverride)
+	if err != nil {
+		return nil, err
+	}
+	if !format.IsImage() {
+		return nil, fmt.Errorf("%s: format was %q but must be an image format (allowed formats are %s)", i.formatOverrideFlagName, format.String(), internal.ImageFormatsToString())
+	}
+	// the format is validated above, so the value is only parsed for the path
+	return i.inputRefParser.ParseInputRef(value+"#format="+format.String(), false, true)
+}
+
+func gzipData(data []byte, level int) ([]byte, error) {
+	buffer := bytes.NewBuffer(nil)
+	gzipWriter, err := newGzipWriter(buffer, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := gzipWriter.Write(data); err != nil {
+		return nil, err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// newGzipWriter returns a new gzip writer with the level,
+// or the default level if the level is 0.
+func newGzipWriter(writer io.Writer, level int) (*gzip.Writer, error) {
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	return gzip.NewWriterLevel(writer, level)
+}
+
+// marshalImage marshals the Image or FileDescriptorSet for the image format.
+func marshalImage(message proto.Message, format internal.Format) ([]byte, error) {
+	switch format {
+	case internal.FormatJSON, internal.FormatJSONGz:
+		return marshalJSON(message)
+	case internal.FormatYAML, internal.FormatYAMLGz:
+		data, err := marshalJSON(message)
+		if err != nil {
+			return nil, err
+		}
+		return utilencoding.JSONToYAML(data)
+	default:
+		return proto.Marshal(message)
+	}
+}
+
 func marshalJSON(message proto.Message) ([]byte, error) {
 	buffer := bytes.NewBuffer(nil)
 	if err := jsonMarshaler.Marshal(buffer, message); err != nil {
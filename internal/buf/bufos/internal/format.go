@@ -24,28 +24,60 @@ const (
 	FormatJSON Format = 7
 	// FormatJSONGz is a format.
 	FormatJSONGz Format = 8
+	// FormatZip is a format.
+	FormatZip Format = 9
+	// FormatOCIRepo is a format.
+	FormatOCIRepo Format = 10
+	// FormatYAML is a format.
+	FormatYAML Format = 11
+	// FormatYAMLGz is a format.
+	FormatYAMLGz Format = 12
+	// FormatModule is a format.
+	FormatModule Format = 13
+)
+
+const (
+	// CompressionZstd is a compression.
+	CompressionZstd Compression = 1
 )
 
 var (
 	formatToString = map[Format]string{
-		FormatDir:    "dir",
-		FormatTar:    "tar",
-		FormatTarGz:  "targz",
-		FormatGit:    "git",
-		FormatBin:    "bin",
-		FormatBinGz:  "bingz",
-		FormatJSON:   "json",
-		FormatJSONGz: "jsongz",
+		FormatDir:     "dir",
+		FormatTar:     "tar",
+		FormatTarGz:   "targz",
+		FormatGit:     "git",
+		FormatBin:     "bin",
+		FormatBinGz:   "bingz",
+		FormatJSON:    "json",
+		FormatJSONGz:  "jsongz",
+		FormatZip:     "zip",
+		FormatOCIRepo: "ocirepo",
+		FormatYAML:    "yaml",
+		FormatYAMLGz:  "yamlgz",
+		FormatModule:  "module",
 	}
 	stringToFormat = map[string]Format{
-		"dir":    FormatDir,
-		"tar":    FormatTar,
-		"targz":  FormatTarGz,
-		"git":    FormatGit,
-		"bin":    FormatBin,
-		"bingz":  FormatBinGz,
-		"json":   FormatJSON,
-		"jsongz": FormatJSONGz,
+		"dir":     FormatDir,
+		"tar":     FormatTar,
+		"targz":   FormatTarGz,
+		"git":     FormatGit,
+		"bin":     FormatBin,
+		"bingz":   FormatBinGz,
+		"json":    FormatJSON,
+		"jsongz":  FormatJSONGz,
+		"zip":     FormatZip,
+		"ocirepo": FormatOCIRepo,
+		"yaml":    FormatYAML,
+		"yamlgz":  FormatYAMLGz,
+		"module":  FormatModule,
+	}
+
+	compressionToString = map[Compression]string{
+		CompressionZstd: "zstd",
+	}
+	stringToCompression = map[string]Compression{
+		"zstd": CompressionZstd,
 	}
 
 	formatToIsSource = map[Format]struct{}{
@@ -53,12 +85,17 @@ var (
 		FormatTar:   {},
 		FormatTarGz: {},
 		FormatGit:   {},
+		FormatZip:   {},
 	}
 	formatToIsImage = map[Format]struct{}{
-		FormatBin:    {},
-		FormatBinGz:  {},
-		FormatJSON:   {},
-		FormatJSONGz: {},
+		FormatBin:     {},
+		FormatBinGz:   {},
+		FormatJSON:    {},
+		FormatJSONGz:  {},
+		FormatOCIRepo: {},
+		FormatYAML:    {},
+		FormatYAMLGz:  {},
+		FormatModule:  {},
 	}
 	formatToIsFile = map[Format]struct{}{
 		FormatTar:    {},
@@ -67,6 +104,9 @@ var (
 		FormatBinGz:  {},
 		FormatJSON:   {},
 		FormatJSONGz: {},
+		FormatZip:    {},
+		FormatYAML:   {},
+		FormatYAMLGz: {},
 	}
 )
 
@@ -102,6 +142,23 @@ func (f Format) isFile() bool {
 	return ok
 }
 
+// Compression is a compression of a file.
+//
+// The zero value is no compression.
+type Compression int
+
+// String returns the string value of c.
+func (c Compression) String() string {
+	if c == 0 {
+		return "none"
+	}
+	s, ok := compressionToString[c]
+	if !ok {
+		return strconv.Itoa(int(c))
+	}
+	return s
+}
+
 // AllFormatsToString returns all format strings.
 func AllFormatsToString() string {
 	return formatsToString(allFormats())
@@ -178,6 +235,15 @@ func parseFormatOverride(valueFlagName string, formatOverride string) (Format, e
 	return value, nil
 }
 
+// parseCompression parses the compression.
+func parseCompression(valueFlagName string, compression string) (Compression, error) {
+	value, ok := stringToCompression[strings.ToLower(strings.TrimSpace(compression))]
+	if !ok {
+		return 0, newCompressionUnknownError(valueFlagName, compression)
+	}
+	return value, nil
+}
+
 func sortFormats(formats []Format) {
 	sort.Slice(formats, func(i int, j int) bool { return formats[i].String() < formats[j].String() })
 }
@@ -185,3 +251,7 @@ func sortFormats(formats []Format) {
 func newFormatOverrideUnknownError(formatOverrideFlagName string, formatOverride string) error {
 	return fmt.Errorf("%s: unknown format: %q", formatOverrideFlagName, formatOverride)
 }
+
+func newCompressionUnknownError(valueFlagName string, compression string) error {
+	return fmt.Errorf("%s: unknown compression: %q (allowed compressions are [zstd])", valueFlagName, compression)
+}
@@ -1,6 +1,9 @@
 package internal
 
 import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"path/filepath"
@@ -8,6 +11,7 @@ import (
 	"strings"
 
 	"github.com/bufbuild/buf/internal/pkg/storage/storagegit/storagegitplumbing"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagepath"
 	"github.com/bufbuild/cli/clios"
 )
 
@@ -54,20 +58,38 @@ func (i *inputRefParser) ParseInputRef(value string, onlySources bool, onlyImage
 		return nil, err
 	}
 	if inputRef.Format == 0 {
-		format, err := i.parseFormatFromPath(path)
+		format, compression, err := i.parseFormatFromPath(path)
 		if err != nil {
 			return nil, err
 		}
 		inputRef.Format = format
+		if inputRef.Compression == 0 {
+			inputRef.Compression = compression
+		}
 	}
 
+	if (inputRef.Format == FormatOCIRepo) != strings.HasPrefix(path, OCIRepoPathPrefix) {
+		return nil, newOCIRepoPathError(i.valueFlagName, path)
+	}
+	if (inputRef.Format == FormatModule) != strings.HasPrefix(path, ModulePathPrefix) {
+		return nil, newModulePathError(i.valueFlagName, path)
+	}
 	if inputRef.Format == FormatGit && inputRef.GitRefName == nil {
 		return nil, newMustSpecifyGitRefNameError(i.valueFlagName, value)
 	}
-	if inputRef.Format != FormatGit && inputRef.GitRefName != nil {
+	if inputRef.Format != FormatGit && (inputRef.GitRefName != nil || inputRef.GitDepth > 0 || inputRef.GitRecurseSubmodules || inputRef.GitSubDir != "") {
+		return nil, newOptionsInvalidForFormatError(i.valueFlagName, inputRef.Format, options)
+	}
+	if inputRef.Format != FormatTar && inputRef.Format != FormatTarGz && inputRef.Format != FormatZip && inputRef.StripComponents > 0 {
+		return nil, newOptionsInvalidForFormatError(i.valueFlagName, inputRef.Format, options)
+	}
+	if inputRef.Format != FormatTar && inputRef.Compression != 0 {
+		return nil, newOptionsInvalidForFormatError(i.valueFlagName, inputRef.Format, options)
+	}
+	if inputRef.Format != FormatBinGz && inputRef.Format != FormatJSONGz && inputRef.Format != FormatYAMLGz && inputRef.CompressionLevel != 0 {
 		return nil, newOptionsInvalidForFormatError(i.valueFlagName, inputRef.Format, options)
 	}
-	if inputRef.Format != FormatTar && inputRef.Format != FormatTarGz && inputRef.StripComponents > 0 {
+	if (!inputRef.Format.IsImage() || inputRef.Format == FormatOCIRepo || inputRef.Format == FormatModule) && (inputRef.Shards != 0 || inputRef.SHA256 != "") {
 		return nil, newOptionsInvalidForFormatError(i.valueFlagName, inputRef.Format, options)
 	}
 
@@ -86,35 +108,54 @@ func (i *inputRefParser) ParseInputRef(value string, onlySources bool, onlyImage
 
 // we know that path is non-empty at this point
 // we know that format override is not set at this point
-func (i *inputRefParser) parseFormatFromPath(path string) (Format, error) {
+func (i *inputRefParser) parseFormatFromPath(path string) (Format, Compression, error) {
 	// if formatOverride is not set and path is "-", default to FormatBin
 	if path == "-" || path == clios.DevNull {
-		return FormatBin, nil
+		return FormatBin, 0, nil
+	}
+	if strings.HasPrefix(path, OCIRepoPathPrefix) {
+		return FormatOCIRepo, 0, nil
+	}
+	if strings.HasPrefix(path, ModulePathPrefix) {
+		return FormatModule, 0, nil
 	}
 	switch filepath.Ext(path) {
 	case ".bin":
-		return FormatBin, nil
+		return FormatBin, 0, nil
 	case ".json":
-		return FormatJSON, nil
+		return FormatJSON, 0, nil
+	case ".yaml", ".yml":
+		return FormatYAML, 0, nil
 	case ".tar":
-		return FormatTar, nil
+		return FormatTar, 0, nil
 	case ".gz":
 		switch filepath.Ext(strings.TrimSuffix(path, filepath.Ext(path))) {
 		case ".bin":
-			return FormatBinGz, nil
+			return FormatBinGz, 0, nil
 		case ".json":
-			return FormatJSONGz, nil
+			return FormatJSONGz, 0, nil
+		case ".yaml", ".yml":
+			return FormatYAMLGz, 0, nil
+		case ".tar":
+			return FormatTarGz, 0, nil
+		default:
+			return 0, 0, newPathUnknownGzError(i.valueFlagName, path)
+		}
+	case ".zst":
+		switch filepath.Ext(strings.TrimSuffix(path, filepath.Ext(path))) {
 		case ".tar":
-			return FormatTarGz, nil
+			return FormatTar, CompressionZstd, nil
 		default:
-			return 0, newPathUnknownGzError(i.valueFlagName, path)
+			return 0, 0, newPathUnknownZstError(i.valueFlagName, path)
 		}
 	case ".tgz":
-		return FormatTarGz, nil
+		return FormatTarGz, 0, nil
+	case ".zip":
+		return FormatZip, 0, nil
 	case ".git":
-		return FormatGit, nil
+		return FormatGit, 0, nil
 	default:
-		return FormatDir, nil
+		return FormatDir, 0, nil
 	}
 }
 
@@ -153,12 +194,61 @@ func (i *inputRefParser) applyInputRefOptions(inputRef *InputRef, options string
 				return newCannotSpecifyMultipleGitRefNamesError(i.valueFlagName)
 			}
 			inputRef.GitRefName = storagegitplumbing.NewTagRefName(value)
+		case "ref":
+			if inputRef.GitRefName != nil {
+				return newCannotSpecifyMultipleGitRefNamesError(i.valueFlagName)
+			}
+			inputRef.GitRefName = storagegitplumbing.NewRefName(value)
+		case "compression":
+			compression, err := parseCompression(i.valueFlagName, value)
+			if err != nil {
+				return err
+			}
+			inputRef.Compression = compression
+		case "level":
+			level, err := strconv.Atoi(value)
+			if err != nil || level < gzip.BestSpeed || level > gzip.BestCompression {
+				return newOptionsCouldNotParseLevelError(i.valueFlagName, value)
+			}
+			inputRef.CompressionLevel = level
+		case "shards":
+			shards, err := strconv.ParseUint(value, 10, 32)
+			if err != nil || shards == 0 {
+				return newOptionsCouldNotParseShardsError(i.valueFlagName, value)
+			}
+			inputRef.Shards = uint32(shards)
+		case "sha256":
+			sha256Data, err := hex.DecodeString(value)
+			if err != nil || len(sha256Data) != sha256.Size {
+				return newOptionsCouldNotParseSHA256Error(i.valueFlagName, value)
+			}
+			inputRef.SHA256 = hex.EncodeToString(sha256Data)
 		case "strip_components":
 			stripComponents, err := strconv.ParseUint(value, 10, 32)
 			if err != nil {
 				return newOptionsCouldNotParseStripComponentsError(i.valueFlagName, value)
 			}
 			inputRef.StripComponents = uint32(stripComponents)
+		case "depth":
+			depth, err := strconv.ParseUint(value, 10, 32)
+			if err != nil || depth == 0 {
+				return newOptionsCouldNotParseDepthError(i.valueFlagName, value)
+			}
+			inputRef.GitDepth = uint32(depth)
+		case "recurse_submodules":
+			recurseSubmodules, err := strconv.ParseBool(value)
+			if err != nil {
+				return newOptionsCouldNotParseRecurseSubmodulesError(i.valueFlagName, value)
+			}
+			inputRef.GitRecurseSubmodules = recurseSubmodules
+		case "subdir":
+			subDir, err := storagepath.NormalizeAndValidate(value)
+			if err != nil {
+				return newOptionsInvalidSubDirError(i.valueFlagName, value, err)
+			}
+			if subDir != "." {
+				inputRef.GitSubDir = subDir
+			}
 		default:
 			return newOptionsInvalidKeyError(i.valueFlagName, key)
 		}
@@ -199,13 +289,25 @@ func newMustSpecifyGitRefNameError(valueFlagName string, path string) error {
 }
 
 func newCannotSpecifyMultipleGitRefNamesError(valueFlagName string) error {
-	return fmt.Errorf(`%s: must specify only one of "branch", "tag"`, valueFlagName)
+	return fmt.Errorf(`%s: must specify only one of "branch", "tag", "ref"`, valueFlagName)
 }
 
 func newPathUnknownGzError(valueFlagName string, path string) error {
 	return fmt.Errorf("%s: path %q had .gz extension with unknown format", valueFlagName, path)
 }
 
+func newPathUnknownZstError(valueFlagName string, path string) error {
+	return fmt.Errorf("%s: path %q had .zst extension with unknown format", valueFlagName, path)
+}
+
+func newOCIRepoPathError(valueFlagName string, path string) error {
+	return fmt.Errorf(`%s: path %q must have the prefix %q if and only if the format is "ocirepo"`, valueFlagName, path, OCIRepoPathPrefix)
+}
+
+func newModulePathError(valueFlagName string, path string) error {
+	return fmt.Errorf(`%s: path %q must have the prefix %q if and only if the format is "module"`, valueFlagName, path, ModulePathPrefix)
+}
+
 func newOptionsInvalidError(valueFlagName string, s string) error {
 	return fmt.Errorf("%s: invalid options: %q", valueFlagName, s)
 }
@@ -222,6 +324,30 @@ func newOptionsCouldNotParseStripComponentsError(valueFlagName string, s string)
 	return fmt.Errorf("%s: could not parse strip_components value %q", valueFlagName, s)
 }
 
+func newOptionsCouldNotParseLevelError(valueFlagName string, s string) error {
+	return fmt.Errorf("%s: could not parse level value %q, must be an integer from %d to %d", valueFlagName, s, gzip.BestSpeed, gzip.BestCompression)
+}
+
+func newOptionsCouldNotParseShardsError(valueFlagName string, s string) error {
+	return fmt.Errorf("%s: could not parse shards value %q, must be a positive integer", valueFlagName, s)
+}
+
+func newOptionsCouldNotParseSHA256Error(valueFlagName string, s string) error {
+	return fmt.Errorf("%s: could not parse sha256 value %q, must be a hex-encoded SHA256 checksum", valueFlagName, s)
+}
+
+func newOptionsCouldNotParseDepthError(valueFlagName string, s string) error {
+	return fmt.Errorf("%s: could not parse depth value %q, must be a positive integer", valueFlagName, s)
+}
+
+func newOptionsCouldNotParseRecurseSubmodulesError(valueFlagName string, s string) error {
+	return fmt.Errorf("%s: could not parse recurse_submodules value %q, must be true or false", valueFlagName, s)
+}
+
+func newOptionsInvalidSubDirError(valueFlagName string, s string, err error) error {
+	return fmt.Errorf("%s: invalid subdir value %q: %v", valueFlagName, s, err)
+}
+
 func newFormatOverrideNotAllowedForDevNullError(valueFlagName string, devNull string) error {
 	return fmt.Errorf("%s: not allowed if path is %s", valueFlagName, devNull)
 }
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/bufbuild/buf/internal/pkg/storage/storagegit/storagegitplumbing"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagepath"
 	"github.com/bufbuild/cli/clios"
 	"github.com/stretchr/testify/assert"
 )
@@ -89,6 +90,64 @@ func TestParseInputRefSuccess(t *testing.T) {
 		},
 		"path/to/dir.git#tag=master",
 	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format:     FormatGit,
+			Path:       ".git",
+			GitRefName: storagegitplumbing.NewRefName("HEAD~1"),
+		},
+		".git#ref=HEAD~1",
+	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format:     FormatGit,
+			Path:       "path/to/dir.git",
+			GitRefName: storagegitplumbing.NewBranchRefName("master"),
+			GitDepth:   10,
+		},
+		"path/to/dir.git#branch=master,depth=10",
+	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format:     FormatGit,
+			Path:       "path/to/dir.git",
+			GitRefName: storagegitplumbing.NewRefName("0123456789abcdef0123456789abcdef01234567"),
+			GitDepth:   50,
+		},
+		"path/to/dir.git#depth=50,ref=0123456789abcdef0123456789abcdef01234567",
+	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format:               FormatGit,
+			Path:                 "path/to/dir.git",
+			GitRefName:           storagegitplumbing.NewBranchRefName("master"),
+			GitRecurseSubmodules: true,
+		},
+		"path/to/dir.git#branch=master,recurse_submodules=true",
+	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format:     FormatGit,
+			Path:       "path/to/dir.git",
+			GitRefName: storagegitplumbing.NewBranchRefName("master"),
+			GitSubDir:  "proto/foo",
+		},
+		"path/to/dir.git#branch=master,subdir=./proto/foo/",
+	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format:     FormatGit,
+			Path:       "path/to/dir.git",
+			GitRefName: storagegitplumbing.NewBranchRefName("master"),
+		},
+		"path/to/dir.git#branch=master,subdir=.",
+	)
 	testParseInputRefSuccess(
 		t,
 		&InputRef{
@@ -121,6 +180,76 @@ func TestParseInputRefSuccess(t *testing.T) {
 		},
 		"path/to/file.json.gz",
 	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format: FormatYAML,
+			Path:   "path/to/file.yaml",
+		},
+		"path/to/file.yaml",
+	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format: FormatYAML,
+			Path:   "path/to/file.yml",
+		},
+		"path/to/file.yml",
+	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format: FormatYAMLGz,
+			Path:   "path/to/file.yaml.gz",
+		},
+		"path/to/file.yaml.gz",
+	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format:           FormatBinGz,
+			Path:             "path/to/file.bin.gz",
+			CompressionLevel: 9,
+		},
+		"path/to/file.bin.gz#level=9",
+	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format:           FormatJSONGz,
+			Path:             "-",
+			CompressionLevel: 1,
+		},
+		"-#format=jsongz,level=1",
+	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format: FormatBin,
+			Path:   "path/to/file.bin",
+			Shards: 4,
+		},
+		"path/to/file.bin#shards=4",
+	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format:           FormatJSONGz,
+			Path:             "path/to/file.json.gz",
+			CompressionLevel: 9,
+			Shards:           2,
+		},
+		"path/to/file.json.gz#level=9,shards=2",
+	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format: FormatBinGz,
+			Path:   "path/to/file.bin.gz",
+			SHA256: "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9",
+		},
+		"path/to/file.bin.gz#sha256=B94D27B9934D3E08A52E52D7DA7DABFAC484EFE37A5380EE9088F7ACE2EFCDE9",
+	)
 	testParseInputRefSuccess(
 		t,
 		&InputRef{
@@ -198,6 +327,132 @@ func TestParseInputRefSuccess(t *testing.T) {
 		},
 		"path/to/file#format=targz,strip_components=1",
 	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format: FormatZip,
+			Path:   "path/to/file.zip",
+		},
+		"path/to/file.zip",
+	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format:          FormatZip,
+			Path:            "path/to/file.zip",
+			StripComponents: 1,
+		},
+		"path/to/file.zip#strip_components=1",
+	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format: FormatZip,
+			Path:   "-",
+		},
+		"-#format=zip",
+	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format:      FormatTar,
+			Path:        "path/to/file.tar.zst",
+			Compression: CompressionZstd,
+		},
+		"path/to/file.tar.zst",
+	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format:          FormatTar,
+			Path:            "path/to/file.tar.zst",
+			Compression:     CompressionZstd,
+			StripComponents: 1,
+		},
+		"path/to/file.tar.zst#strip_components=1",
+	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format:      FormatTar,
+			Path:        "-",
+			Compression: CompressionZstd,
+		},
+		"-#format=tar,compression=zstd",
+	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format: FormatDir,
+			Path:   "s3://bucket/path/to/dir",
+		},
+		"s3://bucket/path/to/dir",
+	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format: FormatBin,
+			Path:   "s3://bucket/path/to/file.bin",
+		},
+		"s3://bucket/path/to/file.bin",
+	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format: FormatTarGz,
+			Path:   "s3://bucket/path/to/file.tar.gz",
+		},
+		"s3://bucket/path/to/file.tar.gz",
+	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format: FormatJSONGz,
+			Path:   "gs://bucket/path/to/file.json.gz",
+		},
+		"gs://bucket/path/to/file.json.gz",
+	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format:          FormatTarGz,
+			Path:            "gs://bucket/path/to/file.tgz",
+			StripComponents: 1,
+		},
+		"gs://bucket/path/to/file.tgz#strip_components=1",
+	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format: FormatOCIRepo,
+			Path:   "ocirepo://ghcr.io/foo/bar:v1.0",
+		},
+		"ocirepo://ghcr.io/foo/bar:v1.0",
+	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format: FormatOCIRepo,
+			Path:   "ocirepo://localhost:5000/foo/bar",
+		},
+		"ocirepo://localhost:5000/foo/bar#format=ocirepo",
+	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format: FormatModule,
+			Path:   "module://buf.example.com/foo/bar:v1.0.0",
+		},
+		"module://buf.example.com/foo/bar:v1.0.0",
+	)
+	testParseInputRefSuccess(
+		t,
+		&InputRef{
+			Format: FormatModule,
+			Path:   "module://localhost:8080/foo/bar:v1",
+		},
+		"module://localhost:8080/foo/bar:v1#format=module",
+	)
 }
 
 func TestParseInputRefError(t *testing.T) {
@@ -265,6 +520,11 @@ func TestParseInputRefError(t *testing.T) {
 		newCannotSpecifyMultipleGitRefNamesError(testValueFlagName),
 		"path/to/foo#format=git,branch=foo,branch=bar",
 	)
+	testParseInputRefErrorBasic(
+		t,
+		newCannotSpecifyMultipleGitRefNamesError(testValueFlagName),
+		"path/to/foo#format=git,tag=foo,ref=bar",
+	)
 	testParseInputRefErrorBasic(
 		t,
 		newPathUnknownGzError(testValueFlagName, "path/to/foo.gz"),
@@ -310,6 +570,31 @@ func TestParseInputRefError(t *testing.T) {
 		newOptionsCouldNotParseStripComponentsError(testValueFlagName, "foo"),
 		"path/to/foo.tar.gz#strip_components=foo",
 	)
+	testParseInputRefErrorBasic(
+		t,
+		newOptionsCouldNotParseDepthError(testValueFlagName, "foo"),
+		"path/to/foo.git#branch=master,depth=foo",
+	)
+	testParseInputRefErrorBasic(
+		t,
+		newOptionsCouldNotParseDepthError(testValueFlagName, "0"),
+		"path/to/foo.git#branch=master,depth=0",
+	)
+	testParseInputRefErrorBasic(
+		t,
+		newOptionsInvalidForFormatError(testValueFlagName, FormatDir, "depth=1"),
+		"path/to/foo#depth=1",
+	)
+	testParseInputRefErrorBasic(
+		t,
+		newOptionsCouldNotParseRecurseSubmodulesError(testValueFlagName, "foo"),
+		"path/to/foo.git#branch=master,recurse_submodules=foo",
+	)
+	testParseInputRefErrorBasic(
+		t,
+		newOptionsInvalidForFormatError(testValueFlagName, FormatTar, "recurse_submodules=true"),
+		"path/to/foo.tar#recurse_submodules=true",
+	)
 	testParseInputRefErrorBasic(
 		t,
 		newOptionsInvalidKeyError(testValueFlagName, "foo"),
@@ -320,11 +605,119 @@ func TestParseInputRefError(t *testing.T) {
 		newOptionsInvalidForFormatError(testValueFlagName, FormatTarGz, "branch=master"),
 		"path/to/foo.tar.gz#branch=master",
 	)
+	testParseInputRefErrorBasic(
+		t,
+		newOptionsInvalidForFormatError(testValueFlagName, FormatZip, "branch=master"),
+		"path/to/foo.zip#branch=master",
+	)
+	testParseInputRefErrorBasic(
+		t,
+		newCompressionUnknownError(testValueFlagName, "gzip"),
+		"-#format=tar,compression=gzip",
+	)
+	testParseInputRefErrorBasic(
+		t,
+		newOptionsInvalidForFormatError(testValueFlagName, FormatZip, "compression=zstd"),
+		"path/to/foo.zip#compression=zstd",
+	)
+	testParseInputRefErrorBasic(
+		t,
+		newPathUnknownZstError(testValueFlagName, "path/to/foo.bin.zst"),
+		"path/to/foo.bin.zst",
+	)
 	testParseInputRefErrorBasic(
 		t,
 		newOptionsInvalidForFormatError(testValueFlagName, FormatDir, "strip_components=1"),
 		"path/to/foo#strip_components=1",
 	)
+	testParseInputRefErrorBasic(
+		t,
+		newOptionsInvalidForFormatError(testValueFlagName, FormatBin, "level=9"),
+		"path/to/foo.bin#level=9",
+	)
+	testParseInputRefErrorBasic(
+		t,
+		newOptionsCouldNotParseLevelError(testValueFlagName, "10"),
+		"path/to/foo.bin.gz#level=10",
+	)
+	testParseInputRefErrorBasic(
+		t,
+		newOptionsCouldNotParseLevelError(testValueFlagName, "best"),
+		"path/to/foo.bin.gz#level=best",
+	)
+	testParseInputRefErrorBasic(
+		t,
+		newOptionsInvalidForFormatError(testValueFlagName, FormatDir, "shards=2"),
+		"path/to/foo#shards=2",
+	)
+	testParseInputRefErrorBasic(
+		t,
+		newOptionsCouldNotParseShardsError(testValueFlagName, "0"),
+		"path/to/foo.bin#shards=0",
+	)
+	testParseInputRefErrorBasic(
+		t,
+		newOptionsCouldNotParseSHA256Error(testValueFlagName, "abc"),
+		"path/to/foo.bin#sha256=abc",
+	)
+	testParseInputRefErrorBasic(
+		t,
+		newOptionsInvalidForFormatError(testValueFlagName, FormatTar, "sha256=b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"),
+		"path/to/foo.tar#sha256=b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9",
+	)
+	testParseInputRefErrorBasic(
+		t,
+		newOptionsInvalidForFormatError(testValueFlagName, FormatTarGz, "subdir=proto"),
+		"path/to/foo.tar.gz#subdir=proto",
+	)
+	testParseInputRefErrorBasic(
+		t,
+		newOptionsInvalidSubDirError(
+			testValueFlagName,
+			"../proto",
+			storagepath.NewError("../proto", storagepath.ErrOutsideContextDir),
+		),
+		"path/to/dir.git#branch=master,subdir=../proto",
+	)
+	testParseInputRefErrorBasic(
+		t,
+		newOCIRepoPathError(testValueFlagName, "ocirepo://ghcr.io/foo/bar:v1"),
+		"ocirepo://ghcr.io/foo/bar:v1#format=bin",
+	)
+	testParseInputRefErrorBasic(
+		t,
+		newOCIRepoPathError(testValueFlagName, "path/to/foo"),
+		"path/to/foo#format=ocirepo",
+	)
+	testParseInputRefError(
+		t,
+		newFormatMustBeSourceError(FormatOCIRepo),
+		"ocirepo://ghcr.io/foo/bar:v1",
+		true,
+		false,
+	)
+	testParseInputRefErrorBasic(
+		t,
+		newModulePathError(testValueFlagName, "module://buf.example.com/foo/bar:v1"),
+		"module://buf.example.com/foo/bar:v1#format=bin",
+	)
+	testParseInputRefErrorBasic(
+		t,
+		newModulePathError(testValueFlagName, "path/to/foo"),
+		"path/to/foo#format=module",
+	)
+	testParseInputRefErrorBasic(
+		t,
+		newOptionsInvalidForFormatError(testValueFlagName, FormatModule, "shards=2"),
+		"module://buf.example.com/foo/bar:v1#shards=2",
+	)
+	testParseInputRefError(
+		t,
+		newFormatMustBeSourceError(FormatModule),
+		"module://buf.example.com/foo/bar:v1",
+		true,
+		false,
+	)
 }
 
 func testParseInputRefSuccess(
@@ -3,9 +3,16 @@ package internal
 import (
 	"github.com/bufbuild/buf/internal/buf/bufbuild"
 	"github.com/bufbuild/buf/internal/buf/bufconfig"
+	"github.com/bufbuild/buf/internal/buf/bufregistry"
 	"github.com/bufbuild/buf/internal/pkg/storage/storagegit/storagegitplumbing"
 )
 
+// OCIRepoPathPrefix is the prefix of paths for FormatOCIRepo.
+const OCIRepoPathPrefix = "ocirepo://"
+
+// ModulePathPrefix is the prefix of paths for FormatModule.
+const ModulePathPrefix = bufregistry.ModuleReferencePrefix
+
 // InputRef is a parsed input reference.
 type InputRef struct {
 	// Format is the format of the input.
@@ -13,16 +20,52 @@ type InputRef struct {
 	Format Format
 	// Path is the path of the input.
 	// The special value "-" indicates stdin or stdout.
-	// If this is "-", Format == FormatTar, FormatTarGz, FormatBin, FormatBinGz, FormatJSON, FormatJSONGz.
+	// If this is "-", Format == FormatTar, FormatTarGz, FormatZip, FormatBin, FormatBinGz, FormatJSON, FormatJSONGz, FormatYAML, FormatYAMLGz.
+	// If Format == FormatOCIRepo, this always has the prefix OCIRepoPathPrefix.
+	// If Format == FormatModule, this always has the prefix ModulePathPrefix.
 	// Required.
 	Path string
 
-	// StripComponents is the number of components to strip from a tarball.
-	// This will only be set if Format == FormatTar, FormatTarGz
+	// Compression is the compression of the file.
+	// This will only be set if Format == FormatTar.
+	// If not set, the file is not compressed.
+	Compression Compression
+	// CompressionLevel is the gzip compression level to write the file with.
+	// This will only be set if Format == FormatBinGz, FormatJSONGz, FormatYAMLGz.
+	// If not set, the default compression level is used.
+	// This is ignored when reading.
+	CompressionLevel int
+	// Shards is the number of files to split the image into along package boundaries.
+	// This will only be set if Format == FormatBin, FormatBinGz, FormatJSON, FormatJSONGz, FormatYAML, FormatYAMLGz.
+	// If not set, the image is written to a single file.
+	// This is ignored when reading.
+	Shards uint32
+	// SHA256 is the expected lowercase hex-encoded SHA256 checksum of the file.
+	// This will only be set if Format == FormatBin, FormatBinGz, FormatJSON, FormatJSONGz, FormatYAML, FormatYAMLGz.
+	// If not set, the checksum in the sidecar file of a local file is verified if present.
+	// This is ignored when writing.
+	SHA256 string
+	// StripComponents is the number of components to strip from a tarball or zip archive.
+	// This will only be set if Format == FormatTar, FormatTarGz, FormatZip
+	//
+	// If not set and the archive is of a single directory with a config file,
+	// the directory is stripped so that the archive is read the same as the directory.
 	StripComponents uint32
 	// GitRefName is the git reference name.
 	// This will only and always be set if Format == FormatGit.
 	GitRefName storagegitplumbing.RefName
+	// GitDepth is the number of commits to clone.
+	// This will only be set if Format == FormatGit.
+	// If not set, a depth of 1 is used for branches and tags, and the full history
+	// is used for commit hashes that cannot be fetched directly.
+	GitDepth uint32
+	// GitRecurseSubmodules says to clone the submodules of the git repository.
+	// This will only be set if Format == FormatGit.
+	GitRecurseSubmodules bool
+	// GitSubDir is the normalized subdirectory of the git repository to use as the input.
+	// This will only be set if Format == FormatGit.
+	// If set, only the files under this directory are fetched, and the paths are relative to it.
+	GitSubDir string
 }
 
 // InputRefParser parses InputRefs.
@@ -30,10 +73,10 @@ type InputRefParser interface {
 	// ParseInputRef parses the InputRef from the value.
 	//
 	// Value should always be non-empty - if you want this to be ".", specify it.
-	// If onlySources is true, the Format will only be FormatDir, FormatTar, FormatTarGz, FormatGit.
-	// If onlyImages is true, the Format will only be FormatBin, FormatBinGz, FormatJSON, FormatJSONGz.
+	// If onlySources is true, the Format will only be FormatDir, FormatTar, FormatTarGz, FormatZip, FormatGit.
+	// If onlyImages is true, the Format will only be FormatBin, FormatBinGz, FormatJSON, FormatJSONGz, FormatYAML, FormatYAMLGz, FormatOCIRepo, FormatModule.
 	// If onlySources and onlyImages is true, this returns system error.
-	// Format will be valid and only one of these eight types.
+	// Format will be valid and only one of these thirteen types.
 	ParseInputRef(value string, onlySources bool, onlyImages bool) (*InputRef, error)
 }
 
@@ -42,6 +85,13 @@ func NewInputRefParser(valueFlagName string) InputRefParser {
 	return newInputRefParser(valueFlagName)
 }
 
+// ParseFormatOverride parses the format override.
+//
+// The flag name is used for errors.
+func ParseFormatOverride(formatOverrideFlagName string, formatOverride string) (Format, error) {
+	return parseFormatOverride(formatOverrideFlagName, formatOverride)
+}
+
 // ConfigOverrideParser parses config overrides.
 type ConfigOverrideParser interface {
 	// ParseConfigOverride parses the config override.
@@ -0,0 +1,114 @@
+package bufos
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+
+	"github.com/bufbuild/buf/internal/pkg/storage"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagepath"
+)
+
+// overlayReadBucket is a bucket of a local directory that reads some files from
+// memory instead of from disk, such as files with unsaved changes in an editor.
+//
+// Only the contents of files are replaced, files that are not on disk are not
+// added to the bucket.
+type overlayReadBucket struct {
+	storage.ReadBucket
+
+	pathToData map[string][]byte
+}
+
+// newOverlayReadBucket returns a new overlayReadBucket.
+//
+// The paths of overlayPathToData are absolute or relative to the current working
+// directory. Paths outside of the directory are ignored.
+func newOverlayReadBucket(
+	delegate storage.ReadBucket,
+	dirPath string,
+	overlayPathToData map[string][]byte,
+) (*overlayReadBucket, error) {
+	absDirPath, err := filepath.Abs(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	pathToData := make(map[string][]byte)
+	for overlayPath, data := range overlayPathToData {
+		absOverlayPath, err := filepath.Abs(overlayPath)
+		if err != nil {
+			return nil, err
+		}
+		relPath, err := filepath.Rel(absDirPath, absOverlayPath)
+		if err != nil {
+			continue
+		}
+		path, err := storagepath.NormalizeAndValidate(relPath)
+		if err != nil {
+			// outside of the directory
+			continue
+		}
+		pathToData[path] = data
+	}
+	return &overlayReadBucket{
+		ReadBucket: delegate,
+		pathToData: pathToData,
+	}, nil
+}
+
+func (b *overlayReadBucket) Get(ctx context.Context, path string) (storage.ReadObject, error) {
+	data, ok, err := b.getData(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return b.ReadBucket.Get(ctx, path)
+	}
+	return &overlayReadObject{
+		Reader: bytes.NewReader(data),
+		size:   uint32(len(data)),
+	}, nil
+}
+
+func (b *overlayReadBucket) Stat(ctx context.Context, path string) (storage.ObjectInfo, error) {
+	data, ok, err := b.getData(ctx, path)
+	if err != nil {
+		return storage.ObjectInfo{}, err
+	}
+	if !ok {
+		return b.ReadBucket.Stat(ctx, path)
+	}
+	return storage.ObjectInfo{
+		Size: uint32(len(data)),
+	}, nil
+}
+
+// getData gets the data of the path if it is in the overlay and exists on disk.
+func (b *overlayReadBucket) getData(ctx context.Context, path string) ([]byte, bool, error) {
+	normalizedPath, err := storagepath.NormalizeAndValidate(path)
+	if err != nil {
+		return nil, false, err
+	}
+	data, ok := b.pathToData[normalizedPath]
+	if !ok {
+		return nil, false, nil
+	}
+	if _, err := b.ReadBucket.Stat(ctx, normalizedPath); err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+type overlayReadObject struct {
+	*bytes.Reader
+
+	size uint32
+}
+
+func (o *overlayReadObject) Close() error {
+	return nil
+}
+
+func (o *overlayReadObject) Size() uint32 {
+	return o.size
+}
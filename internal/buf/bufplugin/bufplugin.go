@@ -0,0 +1,135 @@
+// Package bufplugin runs external protoc plugins in a restricted environment.
+//
+// Plugins, such as code generators and custom checkers, are arbitrary executables
+// that may not be trusted. Each plugin is run with:
+//
+//	a restricted environment: only PATH and explicitly allowed variables are
+//	  passed, and HOME and TMPDIR are set to the working directory.
+//	an isolated working directory: a new temporary directory that is removed
+//	  after the plugin exits.
+//	an optional timeout and an optional memory limit.
+//
+// This is not a security boundary. The plugin still runs as the current user and
+// can read and write anything the current user can, but well-behaved plugins do
+// not depend on the environment of the caller and misbehaving plugins are contained.
+package bufplugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	plugin_go "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"go.uber.org/zap"
+)
+
+const (
+	// LimitTimeout says the plugin did not exit within the timeout.
+	LimitTimeout Limit = iota + 1
+	// LimitMemory says the plugin ran out of memory within the memory limit.
+	LimitMemory
+)
+
+var (
+	limitToString = map[Limit]string{
+		LimitTimeout: "timeout",
+		LimitMemory:  "memory",
+	}
+)
+
+// Limit is a limit on a plugin.
+type Limit int
+
+// String implements fmt.Stringer.
+func (l Limit) String() string {
+	s, ok := limitToString[l]
+	if !ok {
+		return strconv.Itoa(int(l))
+	}
+	return s
+}
+
+// LimitError is the error returned when a plugin exceeds a limit.
+type LimitError struct {
+	// PluginPath is the path of the plugin.
+	PluginPath string
+	// Limit is the limit that was exceeded.
+	Limit Limit
+	// Value is the value of the limit, such as 10s or 1048576 bytes.
+	Value string
+	// Stderr is the stderr of the plugin, if any.
+	Stderr string
+}
+
+// Error implements error.
+func (e *LimitError) Error() string {
+	s := fmt.Sprintf("plugin %s exceeded the %s limit of %s", e.PluginPath, e.Limit.String(), e.Value)
+	if e.Stderr != "" {
+		s += ": " + e.Stderr
+	}
+	return s
+}
+
+// IsLimitError returns true if the error is a *LimitError.
+func IsLimitError(err error) bool {
+	var limitError *LimitError
+	return errors.As(err, &limitError)
+}
+
+// Executor executes plugins.
+type Executor interface {
+	// Execute executes the plugin at the path with the request.
+	//
+	// The path is resolved using the PATH returned by getenv if it does not contain a
+	// separator, and other relative paths are relative to the current working directory.
+	// If the plugin exceeds a limit, a *LimitError is returned. If the plugin exits
+	// successfully but sets the error on the response, an error is returned.
+	Execute(
+		ctx context.Context,
+		getenv func(string) string,
+		pluginPath string,
+		request *plugin_go.CodeGeneratorRequest,
+	) (*plugin_go.CodeGeneratorResponse, error)
+}
+
+// NewExecutor returns a new Executor.
+func NewExecutor(logger *zap.Logger, options ...ExecutorOption) Executor {
+	return newExecutor(logger, options...)
+}
+
+// ExecutorOption is an option for a new Executor.
+type ExecutorOption func(*executor)
+
+// ExecutorWithTimeout returns a new ExecutorOption that kills plugins that do
+// not exit within the timeout.
+//
+// The default is to use no timeout other than the context.
+func ExecutorWithTimeout(timeout time.Duration) ExecutorOption {
+	return func(executor *executor) {
+		executor.timeout = timeout
+	}
+}
+
+// ExecutorWithMemoryLimit returns a new ExecutorOption that limits the virtual
+// memory of plugins to the given number of bytes.
+//
+// Plugins that run out of memory are detected by their stderr, so plugins that
+// handle allocation failures silently only fail with a non-zero exit code.
+// Memory limits are not supported on Windows. The default is no memory limit.
+func ExecutorWithMemoryLimit(memoryLimitBytes uint64) ExecutorOption {
+	return func(executor *executor) {
+		executor.memoryLimitBytes = memoryLimitBytes
+	}
+}
+
+// ExecutorWithEnvKeys returns a new ExecutorOption that passes the given
+// environment variables of the caller to plugins.
+//
+// By default, only PATH is passed.
+func ExecutorWithEnvKeys(envKeys ...string) ExecutorOption {
+	return func(executor *executor) {
+		executor.envKeys = append(executor.envKeys, envKeys...)
+	}
+}
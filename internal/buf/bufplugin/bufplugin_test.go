@@ -0,0 +1,252 @@
+package bufplugin
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	plugin_go "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// testModeEnvKey is the environment variable that makes the test binary act as a plugin.
+const testModeEnvKey = "BUF_PLUGIN_TEST_MODE"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(testModeEnvKey); mode != "" {
+		os.Exit(runTestPlugin(mode))
+	}
+	os.Exit(m.Run())
+}
+
+func TestExecute(t *testing.T) {
+	t.Parallel()
+	executor := NewExecutor(zap.NewNop(), ExecutorWithEnvKeys(testModeEnvKey))
+	response, err := executor.Execute(
+		context.Background(),
+		newTestGetenv("env"),
+		testPluginPath(t),
+		&plugin_go.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto"},
+		},
+	)
+	require.NoError(t, err)
+	nameToContent := make(map[string]string)
+	for _, file := range response.GetFile() {
+		nameToContent[file.GetName()] = file.GetContent()
+	}
+	assert.Equal(t, "a.proto", nameToContent["request"])
+	workDirPath := nameToContent["pwd"]
+	require.NotEmpty(t, workDirPath)
+	assert.NotEqual(t, testWorkDirPath(t), workDirPath)
+	// the working directory is removed after the plugin exits
+	_, err = os.Stat(workDirPath)
+	assert.True(t, os.IsNotExist(err))
+	env := strings.Split(nameToContent["env"], "\n")
+	assert.Contains(t, env, "HOME="+workDirPath)
+	assert.Contains(t, env, "TMPDIR="+workDirPath)
+	assert.Contains(t, env, testModeEnvKey+"=env")
+	assert.Contains(t, env, "PATH="+os.Getenv("PATH"))
+	for _, envValue := range env {
+		assert.False(t, strings.HasPrefix(envValue, "SECRET="), envValue)
+	}
+}
+
+func TestExecuteRelativePath(t *testing.T) {
+	t.Parallel()
+	// relative paths are relative to the current working directory, not the working directory of the plugin
+	pluginPath, err := filepath.Rel(testWorkDirPath(t), testPluginPath(t))
+	require.NoError(t, err)
+	require.False(t, filepath.IsAbs(pluginPath))
+	executor := NewExecutor(zap.NewNop(), ExecutorWithEnvKeys(testModeEnvKey))
+	response, err := executor.Execute(
+		context.Background(),
+		newTestGetenv("env"),
+		pluginPath,
+		&plugin_go.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto"},
+		},
+	)
+	require.NoError(t, err)
+	require.NotEmpty(t, response.GetFile())
+	assert.Equal(t, "a.proto", response.GetFile()[0].GetContent())
+}
+
+func TestExecuteGetenvPath(t *testing.T) {
+	t.Parallel()
+	// plugins without a separator are looked up in the PATH of getenv, not of the current process
+	pluginDirPath := filepath.Dir(testPluginPath(t))
+	getenv := func(key string) string {
+		if key == "PATH" {
+			return pluginDirPath
+		}
+		return newTestGetenv("env")(key)
+	}
+	executor := NewExecutor(zap.NewNop(), ExecutorWithEnvKeys(testModeEnvKey))
+	response, err := executor.Execute(
+		context.Background(),
+		getenv,
+		filepath.Base(testPluginPath(t)),
+		&plugin_go.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto"},
+		},
+	)
+	require.NoError(t, err)
+	require.NotEmpty(t, response.GetFile())
+	assert.Equal(t, "a.proto", response.GetFile()[0].GetContent())
+
+	_, err = executor.Execute(
+		context.Background(),
+		newTestGetenv("env"),
+		filepath.Base(testPluginPath(t)),
+		&plugin_go.CodeGeneratorRequest{},
+	)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not found in PATH")
+}
+
+func TestExecuteResponseError(t *testing.T) {
+	t.Parallel()
+	executor := NewExecutor(zap.NewNop(), ExecutorWithEnvKeys(testModeEnvKey))
+	_, err := executor.Execute(context.Background(), newTestGetenv("error"), testPluginPath(t), &plugin_go.CodeGeneratorRequest{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bad request")
+	assert.False(t, IsLimitError(err))
+}
+
+func TestExecuteTimeout(t *testing.T) {
+	t.Parallel()
+	executor := NewExecutor(
+		zap.NewNop(),
+		ExecutorWithEnvKeys(testModeEnvKey),
+		ExecutorWithTimeout(100*time.Millisecond),
+	)
+	_, err := executor.Execute(context.Background(), newTestGetenv("sleep"), testPluginPath(t), &plugin_go.CodeGeneratorRequest{})
+	require.Error(t, err)
+	require.True(t, IsLimitError(err), err.Error())
+	limitError := err.(*LimitError)
+	assert.Equal(t, LimitTimeout, limitError.Limit)
+	assert.Equal(t, "100ms", limitError.Value)
+}
+
+func TestExecuteOutOfMemory(t *testing.T) {
+	t.Parallel()
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("memory limits require /bin/sh")
+	}
+	executor := NewExecutor(
+		zap.NewNop(),
+		ExecutorWithEnvKeys(testModeEnvKey),
+		ExecutorWithMemoryLimit(1<<40),
+	)
+	_, err := executor.Execute(context.Background(), newTestGetenv("oom"), testPluginPath(t), &plugin_go.CodeGeneratorRequest{})
+	require.Error(t, err)
+	require.True(t, IsLimitError(err), err.Error())
+	limitError := err.(*LimitError)
+	assert.Equal(t, LimitMemory, limitError.Limit)
+	assert.Equal(t, fmt.Sprintf("%d bytes", uint64(1<<40)), limitError.Value)
+	assert.Contains(t, limitError.Stderr, "out of memory")
+}
+
+func TestExecuteNonZeroExit(t *testing.T) {
+	t.Parallel()
+	// without a memory limit, running out of memory is a regular failure
+	executor := NewExecutor(zap.NewNop(), ExecutorWithEnvKeys(testModeEnvKey))
+	_, err := executor.Execute(context.Background(), newTestGetenv("oom"), testPluginPath(t), &plugin_go.CodeGeneratorRequest{})
+	require.Error(t, err)
+	assert.False(t, IsLimitError(err))
+	assert.Contains(t, err.Error(), "out of memory")
+}
+
+func runTestPlugin(mode string) int {
+	requestData, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	request := &plugin_go.CodeGeneratorRequest{}
+	if err := proto.Unmarshal(requestData, request); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	response := &plugin_go.CodeGeneratorResponse{}
+	switch mode {
+	case "env":
+		workDirPath, err := os.Getwd()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 1
+		}
+		env := os.Environ()
+		sort.Strings(env)
+		response.File = []*plugin_go.CodeGeneratorResponse_File{
+			{
+				Name:    proto.String("request"),
+				Content: proto.String(strings.Join(request.GetFileToGenerate(), ",")),
+			},
+			{
+				Name:    proto.String("pwd"),
+				Content: proto.String(workDirPath),
+			},
+			{
+				Name:    proto.String("env"),
+				Content: proto.String(strings.Join(env, "\n")),
+			},
+		}
+	case "error":
+		response.Error = proto.String("bad request")
+	case "sleep":
+		time.Sleep(10 * time.Second)
+	case "oom":
+		fmt.Fprintln(os.Stderr, "fatal error: runtime: out of memory")
+		return 2
+	default:
+		fmt.Fprintf(os.Stderr, "unknown mode %q\n", mode)
+		return 1
+	}
+	responseData, err := proto.Marshal(response)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	if _, err := os.Stdout.Write(responseData); err != nil {
+		return 1
+	}
+	return 0
+}
+
+func newTestGetenv(mode string) func(string) string {
+	return func(key string) string {
+		switch key {
+		case testModeEnvKey:
+			return mode
+		case "PATH":
+			return os.Getenv("PATH")
+		case "SECRET":
+			return "secret"
+		default:
+			return ""
+		}
+	}
+}
+
+func testPluginPath(t *testing.T) string {
+	pluginPath, err := filepath.Abs(os.Args[0])
+	require.NoError(t, err)
+	return pluginPath
+}
+
+func testWorkDirPath(t *testing.T) string {
+	workDirPath, err := os.Getwd()
+	require.NoError(t, err)
+	return workDirPath
+}
@@ -0,0 +1,40 @@
+//go:build !windows
+// +build !windows
+
+package bufplugin
+
+import (
+	"fmt"
+	"os"
+)
+
+// getCommand returns the command to run the plugin.
+//
+// The memory limit is applied with ulimit -v in a shell that then replaces
+// itself with the plugin, so that the limit applies to the plugin only.
+func (e *executor) getCommand(pluginPath string) (string, []string, error) {
+	if e.memoryLimitBytes == 0 {
+		return pluginPath, nil, nil
+	}
+	// ulimit -v is in kilobytes
+	memoryLimitKilobytes := (e.memoryLimitBytes + 1023) / 1024
+	return "/bin/sh", []string{
+		"-c",
+		fmt.Sprintf(`ulimit -v %d && exec "$0"`, memoryLimitKilobytes),
+		pluginPath,
+	}, nil
+}
+
+// getCandidatePaths returns the paths to check for a plugin found in a PATH directory.
+func getCandidatePaths(getenv func(string) string, path string) []string {
+	return []string{path}
+}
+
+// isExecutable returns true if the path is a regular file with an executable bit set.
+func isExecutable(path string) bool {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return fileInfo.Mode().IsRegular() && fileInfo.Mode()&0111 != 0
+}
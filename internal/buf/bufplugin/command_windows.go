@@ -0,0 +1,57 @@
+package bufplugin
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultPathExt is the default value of PATHEXT.
+const defaultPathExt = ".com;.exe;.bat;.cmd"
+
+// getCommand returns the command to run the plugin.
+//
+// Memory limits are not supported on Windows.
+func (e *executor) getCommand(pluginPath string) (string, []string, error) {
+	if e.memoryLimitBytes > 0 {
+		return "", nil, errors.New("plugin memory limits are not supported on windows")
+	}
+	return pluginPath, nil, nil
+}
+
+// getCandidatePaths returns the paths to check for a plugin found in a PATH directory.
+//
+// The path itself is only checked if it already has an extension in PATHEXT.
+func getCandidatePaths(getenv func(string) string, path string) []string {
+	var pathExt string
+	if getenv != nil {
+		pathExt = getenv("PATHEXT")
+	}
+	if pathExt == "" {
+		pathExt = defaultPathExt
+	}
+	var candidatePaths []string
+	for _, ext := range strings.Split(strings.ToLower(pathExt), ";") {
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if strings.ToLower(filepath.Ext(path)) == ext {
+			return []string{path}
+		}
+		candidatePaths = append(candidatePaths, path+ext)
+	}
+	return candidatePaths
+}
+
+// isExecutable returns true if the path is a regular file.
+func isExecutable(path string) bool {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return fileInfo.Mode().IsRegular()
+}
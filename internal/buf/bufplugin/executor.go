@@ -0,0 +1,173 @@
+package bufplugin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bufbuild/buf/internal/pkg/util/utillog"
+	"github.com/golang/protobuf/proto"
+	plugin_go "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+// outOfMemoryMessages are the substrings of stderr that say a plugin ran out of memory.
+var outOfMemoryMessages = []string{
+	"out of memory",
+	"cannot allocate memory",
+	"bad_alloc",
+	"MemoryError",
+}
+
+type executor struct {
+	logger           *zap.Logger
+	timeout          time.Duration
+	memoryLimitBytes uint64
+	envKeys          []string
+}
+
+func newExecutor(logger *zap.Logger, options ...ExecutorOption) *executor {
+	executor := &executor{
+		logger: logger.Named("bufplugin"),
+	}
+	for _, option := range options {
+		option(executor)
+	}
+	return executor
+}
+
+func (e *executor) Execute(
+	ctx context.Context,
+	getenv func(string) string,
+	pluginPath string,
+	request *plugin_go.CodeGeneratorRequest,
+) (_ *plugin_go.CodeGeneratorResponse, retErr error) {
+	defer utillog.Defer(e.logger, "execute", zap.String("plugin", pluginPath))()
+
+	requestData, err := proto.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	// resolve the path before the environment is restricted and the working directory is changed
+	pluginPath, err = resolvePluginPath(getenv, pluginPath)
+	if err != nil {
+		return nil, err
+	}
+	workDirPath, err := ioutil.TempDir("", "buf-plugin")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, os.RemoveAll(workDirPath))
+	}()
+
+	executeCtx := ctx
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		executeCtx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+	name, args, err := e.getCommand(pluginPath)
+	if err != nil {
+		return nil, err
+	}
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	cmd := exec.CommandContext(executeCtx, name, args...)
+	cmd.Dir = workDirPath
+	cmd.Env = e.getEnv(getenv, workDirPath)
+	cmd.Stdin = bytes.NewReader(requestData)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		stderrString := strings.TrimSpace(stderr.String())
+		if ctx.Err() == nil && errors.Is(executeCtx.Err(), context.DeadlineExceeded) {
+			return nil, &LimitError{
+				PluginPath: pluginPath,
+				Limit:      LimitTimeout,
+				Value:      e.timeout.String(),
+				Stderr:     stderrString,
+			}
+		}
+		if e.memoryLimitBytes > 0 && isOutOfMemory(stderrString) {
+			return nil, &LimitError{
+				PluginPath: pluginPath,
+				Limit:      LimitMemory,
+				Value:      fmt.Sprintf("%d bytes", e.memoryLimitBytes),
+				Stderr:     stderrString,
+			}
+		}
+		if stderrString != "" {
+			return nil, fmt.Errorf("plugin %s: %v: %s", pluginPath, err, stderrString)
+		}
+		return nil, fmt.Errorf("plugin %s: %v", pluginPath, err)
+	}
+	response := &plugin_go.CodeGeneratorResponse{}
+	if err := proto.Unmarshal(stdout.Bytes(), response); err != nil {
+		return nil, fmt.Errorf("plugin %s: could not unmarshal CodeGeneratorResponse: %v", pluginPath, err)
+	}
+	if response.Error != nil {
+		return nil, fmt.Errorf("plugin %s: %s", pluginPath, response.GetError())
+	}
+	return response, nil
+}
+
+// getEnv returns the restricted environment for a plugin.
+func (e *executor) getEnv(getenv func(string) string, workDirPath string) []string {
+	env := []string{
+		"HOME=" + workDirPath,
+		"TMPDIR=" + workDirPath,
+	}
+	if getenv == nil {
+		return env
+	}
+	for _, envKey := range append([]string{"PATH"}, e.envKeys...) {
+		if value := getenv(envKey); value != "" {
+			env = append(env, envKey+"="+value)
+		}
+	}
+	return env
+}
+
+// resolvePluginPath returns the absolute path of the plugin.
+//
+// Paths without a separator are looked up in the PATH returned by getenv, and
+// other relative paths are relative to the current working directory.
+func resolvePluginPath(getenv func(string) string, pluginPath string) (string, error) {
+	if strings.ContainsRune(pluginPath, os.PathSeparator) || strings.ContainsRune(pluginPath, '/') {
+		return filepath.Abs(pluginPath)
+	}
+	var pathEnv string
+	if getenv != nil {
+		pathEnv = getenv("PATH")
+	}
+	for _, dirPath := range filepath.SplitList(pathEnv) {
+		// an empty element is the current working directory
+		if dirPath == "" {
+			dirPath = "."
+		}
+		for _, candidatePath := range getCandidatePaths(getenv, filepath.Join(dirPath, pluginPath)) {
+			if isExecutable(candidatePath) {
+				return filepath.Abs(candidatePath)
+			}
+		}
+	}
+	return "", fmt.Errorf("plugin %s: executable file not found in PATH", pluginPath)
+}
+
+func isOutOfMemory(stderr string) bool {
+	for _, outOfMemoryMessage := range outOfMemoryMessages {
+		if strings.Contains(stderr, outOfMemoryMessage) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,304 @@
+// Package bufquery evaluates queries over the descriptors of images.
+//
+// A query selects a kind of element, optionally filtered by an expression over
+// the attributes of the elements:
+//
+//	fields where type == "bytes" && !(comment contains "size")
+//
+// Expressions support the operators ==, !=, <, <=, >, >=, contains, startsWith,
+// endsWith, matches, !, &&, ||, and parentheses. Values are strings in double quotes,
+// integers, true, and false. The right operand of matches is a regular expression.
+package bufquery
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/bufbuild/buf/internal/pkg/protodesc"
+)
+
+const (
+	// KindFiles selects files.
+	KindFiles = "files"
+	// KindMessages selects messages, including nested messages but not map entries.
+	KindMessages = "messages"
+	// KindFields selects the fields of messages.
+	KindFields = "fields"
+	// KindEnums selects enums, including nested enums.
+	KindEnums = "enums"
+	// KindEnumValues selects the values of enums.
+	KindEnumValues = "enum_values"
+	// KindServices selects services.
+	KindServices = "services"
+	// KindRPCs selects the RPCs of services.
+	KindRPCs = "rpcs"
+)
+
+var (
+	// the attributes of all kinds other than files
+	namedAttributeTypes = map[string]valueType{
+		"file":      valueTypeString,
+		"package":   valueTypeString,
+		"name":      valueTypeString,
+		"full_name": valueTypeString,
+		"comment":   valueTypeString,
+	}
+	kindToAttributeTypes = map[string]map[string]valueType{
+		KindFiles: {
+			"file":         valueTypeString,
+			"package":      valueTypeString,
+			"syntax":       valueTypeString,
+			"go_package":   valueTypeString,
+			"java_package": valueTypeString,
+		},
+		KindMessages: withNamedAttributeTypes(map[string]valueType{
+			"nested":      valueTypeBool,
+			"field_count": valueTypeInt,
+		}),
+		KindFields: withNamedAttributeTypes(map[string]valueType{
+			"message":   valueTypeString,
+			"number":    valueTypeInt,
+			"type":      valueTypeString,
+			"type_name": valueTypeString,
+			"label":     valueTypeString,
+			"json_name": valueTypeString,
+			"oneof":     valueTypeString,
+		}),
+		KindEnums: withNamedAttributeTypes(map[string]valueType{
+			"value_count": valueTypeInt,
+		}),
+		KindEnumValues: withNamedAttributeTypes(map[string]valueType{
+			"enum":   valueTypeString,
+			"number": valueTypeInt,
+		}),
+		KindServices: withNamedAttributeTypes(map[string]valueType{
+			"rpc_count": valueTypeInt,
+		}),
+		KindRPCs: withNamedAttributeTypes(map[string]valueType{
+			"service":          valueTypeString,
+			"input_type":       valueTypeString,
+			"output_type":      valueTypeString,
+			"client_streaming": valueTypeBool,
+			"server_streaming": valueTypeBool,
+		}),
+	}
+)
+
+// Query is a parsed query.
+type Query struct {
+	kind string
+	// expr is nil if the query selects all elements of the kind.
+	expr node
+}
+
+// Parse parses the query.
+//
+// The query is of the form "kind" or "kind where expression".
+func Parse(query string) (*Query, error) {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return nil, err
+	}
+	kindToken := tokens[0]
+	if kindToken.tokenType != tokenTypeIdent {
+		return nil, fmt.Errorf("query must start with one of %s", kindsToString())
+	}
+	attributeTypes, ok := kindToAttributeTypes[kindToken.value]
+	if !ok {
+		return nil, fmt.Errorf("unknown kind %q, must be one of %s", kindToken.value, kindsToString())
+	}
+	q := &Query{
+		kind: kindToken.value,
+	}
+	switch whereToken := tokens[1]; {
+	case whereToken.tokenType == tokenTypeEOF:
+		return q, nil
+	case whereToken.tokenType != tokenTypeIdent || whereToken.value != "where":
+		return nil, fmt.Errorf(`offset %d: expected "where" after %s`, whereToken.offset, q.kind)
+	}
+	q.expr, err = parseExpr(tokens, 2, attributeTypes)
+	if err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// Result is an element that matched a query.
+type Result struct {
+	// Kind is the kind of the element.
+	Kind string `json:"kind,omitempty" yaml:"kind,omitempty"`
+	// Path is the path of the file of the element.
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	// StartLine is the line of the element, or 0 if the image does not have source code info.
+	StartLine int `json:"start_line,omitempty" yaml:"start_line,omitempty"`
+	// StartColumn is the column of the element, or 0 if the image does not have source code info.
+	StartColumn int `json:"start_column,omitempty" yaml:"start_column,omitempty"`
+	// FullName is the fully-qualified name of the element, or empty for files.
+	FullName string `json:"full_name,omitempty" yaml:"full_name,omitempty"`
+	// Attributes are the attributes of the element that can be used in queries.
+	Attributes map[string]interface{} `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+}
+
+// Run runs the query against the image.
+//
+// The results are sorted by path, and then are in the order of the elements within
+// the files, with nested elements after their parents.
+func (q *Query) Run(ctx context.Context, image *imagev1beta1.Image) ([]*Result, error) {
+	files, err := protodesc.NewFilesUnstable(ctx, image.GetFile()...)
+	if err != nil {
+		return nil, err
+	}
+	protodesc.SortFiles(files)
+	var results []*Result
+	for _, file := range files {
+		for _, result := range getFileResults(q.kind, file) {
+			if q.expr == nil || q.expr.eval(result.Attributes).(bool) {
+				results = append(results, result)
+			}
+		}
+	}
+	return results, nil
+}
+
+func getFileResults(kind string, file protodesc.File) []*Result {
+	switch kind {
+	case KindFiles:
+		return []*Result{
+			{
+				Kind: kind,
+				Path: file.FilePath(),
+				Attributes: map[string]interface{}{
+					"file":         file.FilePath(),
+					"package":      file.Package(),
+					"syntax":       file.Syntax().String(),
+					"go_package":   file.GoPackage(),
+					"java_package": file.JavaPackage(),
+				},
+			},
+		}
+	case KindServices, KindRPCs:
+		var results []*Result
+		for _, service := range file.Services() {
+			if kind == KindServices {
+				results = append(results, newNamedResult(kind, service, map[string]interface{}{
+					"rpc_count": len(service.Methods()),
+				}))
+				continue
+			}
+			for _, method := range service.Methods() {
+				results = append(results, newNamedResult(kind, method, map[string]interface{}{
+					"service":          service.FullName(),
+					"input_type":       strings.TrimPrefix(method.InputTypeName(), "."),
+					"output_type":      strings.TrimPrefix(method.OutputTypeName(), "."),
+					"client_streaming": method.ClientStreaming(),
+					"server_streaming": method.ServerStreaming(),
+				}))
+			}
+		}
+		return results
+	default:
+		return getContainerResults(kind, file)
+	}
+}
+
+// getContainerResults returns the results of the messages, fields, enums, or enum
+// values within the container, recursively.
+func getContainerResults(kind string, container protodesc.ContainerDescriptor) []*Result {
+	var results []*Result
+	for _, enum := range container.Enums() {
+		switch kind {
+		case KindEnums:
+			results = append(results, newNamedResult(kind, enum, map[string]interface{}{
+				"value_count": len(enum.Values()),
+			}))
+		case KindEnumValues:
+			for _, enumValue := range enum.Values() {
+				results = append(results, newNamedResult(kind, enumValue, map[string]interface{}{
+					"enum":   enum.FullName(),
+					"number": enumValue.Number(),
+				}))
+			}
+		}
+	}
+	for _, message := range container.Messages() {
+		if message.IsMapEntry() {
+			// map entries are generated and not part of the schema as written
+			continue
+		}
+		switch kind {
+		case KindMessages:
+			results = append(results, newNamedResult(kind, message, map[string]interface{}{
+				"nested":      message.Parent() != nil,
+				"field_count": len(message.Fields()),
+			}))
+		case KindFields:
+			oneofs := message.Oneofs()
+			for _, field := range message.Fields() {
+				oneof := ""
+				if oneofIndex, ok := field.OneofIndex(); ok && oneofIndex < len(oneofs) {
+					oneof = oneofs[oneofIndex].Name()
+				}
+				results = append(results, newNamedResult(kind, field, map[string]interface{}{
+					"message":   message.FullName(),
+					"number":    field.Number(),
+					"type":      field.Type().String(),
+					"type_name": strings.TrimPrefix(field.TypeName(), "."),
+					"label":     field.Label().String(),
+					"json_name": field.JSONName(),
+					"oneof":     oneof,
+				}))
+			}
+		}
+		results = append(results, getContainerResults(kind, message)...)
+	}
+	return results
+}
+
+func newNamedResult(kind string, namedDescriptor protodesc.NamedDescriptor, attributes map[string]interface{}) *Result {
+	result := &Result{
+		Kind:       kind,
+		Path:       namedDescriptor.FilePath(),
+		FullName:   namedDescriptor.FullName(),
+		Attributes: attributes,
+	}
+	comment := ""
+	if location := namedDescriptor.Location(); location != nil {
+		result.StartLine = location.StartLine()
+		result.StartColumn = location.StartColumn()
+		comment = strings.TrimSpace(strings.TrimSpace(location.LeadingComments()) + "\n" + strings.TrimSpace(location.TrailingComments()))
+	}
+	attributes["file"] = namedDescriptor.FilePath()
+	attributes["package"] = namedDescriptor.Package()
+	attributes["name"] = namedDescriptor.Name()
+	attributes["full_name"] = namedDescriptor.FullName()
+	attributes["comment"] = comment
+	return result
+}
+
+func withNamedAttributeTypes(attributeTypes map[string]valueType) map[string]valueType {
+	for name, t := range namedAttributeTypes {
+		attributeTypes[name] = t
+	}
+	return attributeTypes
+}
+
+func kindsToString() string {
+	kinds := make([]string, 0, len(kindToAttributeTypes))
+	for kind := range kindToAttributeTypes {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return "[" + strings.Join(kinds, ",") + "]"
+}
+
+func attributeTypesToString(attributeTypes map[string]valueType) string {
+	names := make([]string, 0, len(attributeTypes))
+	for name := range attributeTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return "[" + strings.Join(names, ",") + "]"
+}
@@ -0,0 +1,181 @@
+package bufquery
+
+import (
+	"context"
+	"testing"
+
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseErrors(t *testing.T) {
+	t.Parallel()
+	for _, query := range []string{
+		``,
+		`"fields"`,
+		`things`,
+		`fields type == "bytes"`,
+		`fields where`,
+		`fields where type`,
+		`fields where size == 1`,
+		`fields where type == 1`,
+		`fields where number < "1"`,
+		`fields where number contains "1"`,
+		`fields where name matches type`,
+		`fields where name matches "("`,
+		`fields where !name`,
+		`fields where number == 1 && name`,
+		`fields where (number == 1`,
+		`fields where number == 1)`,
+		`fields where name == "foo`,
+		`fields where name == 'foo'`,
+		`files where name == "foo"`,
+	} {
+		_, err := Parse(query)
+		assert.Error(t, err, query)
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+	image := &imagev1beta1.Image{
+		File: []*descriptor.FileDescriptorProto{
+			{
+				Name:    proto.String("foo/v1/foo.proto"),
+				Package: proto.String("foo.v1"),
+				Syntax:  proto.String("proto3"),
+				MessageType: []*descriptor.DescriptorProto{
+					{
+						Name: proto.String("Foo"),
+						Field: []*descriptor.FieldDescriptorProto{
+							{
+								Name:     proto.String("data"),
+								Number:   proto.Int32(1),
+								Label:    descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+								Type:     descriptor.FieldDescriptorProto_TYPE_BYTES.Enum(),
+								JsonName: proto.String("data"),
+							},
+							{
+								Name:     proto.String("limited_data"),
+								Number:   proto.Int32(2),
+								Label:    descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+								Type:     descriptor.FieldDescriptorProto_TYPE_BYTES.Enum(),
+								JsonName: proto.String("limitedData"),
+							},
+							{
+								Name:     proto.String("bar"),
+								Number:   proto.Int32(3),
+								Label:    descriptor.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+								Type:     descriptor.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+								TypeName: proto.String(".foo.v1.Foo.Bar"),
+								JsonName: proto.String("bar"),
+							},
+						},
+						NestedType: []*descriptor.DescriptorProto{
+							{
+								Name: proto.String("Bar"),
+								Field: []*descriptor.FieldDescriptorProto{
+									{
+										Name:     proto.String("raw"),
+										Number:   proto.Int32(1),
+										Label:    descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+										Type:     descriptor.FieldDescriptorProto_TYPE_BYTES.Enum(),
+										JsonName: proto.String("raw"),
+									},
+								},
+							},
+						},
+					},
+				},
+				SourceCodeInfo: &descriptor.SourceCodeInfo{
+					Location: []*descriptor.SourceCodeInfo_Location{
+						{
+							Path: []int32{4, 0},
+							Span: []int32{2, 0, 12, 1},
+						},
+						{
+							Path: []int32{4, 0, 2, 0},
+							Span: []int32{3, 2, 19},
+						},
+						{
+							Path:            []int32{4, 0, 2, 1},
+							Span:            []int32{5, 2, 27},
+							LeadingComments: proto.String(" Limited to a size of 1MB.\n"),
+						},
+						{
+							Path: []int32{4, 0, 2, 2},
+							Span: []int32{6, 2, 23},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	query, err := Parse(`fields where type == "bytes" && !(comment contains "size")`)
+	require.NoError(t, err)
+	results, err := query.Run(context.Background(), image)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(
+		t,
+		&Result{
+			Kind:        KindFields,
+			Path:        "foo/v1/foo.proto",
+			StartLine:   4,
+			StartColumn: 3,
+			FullName:    "foo.v1.Foo.data",
+			Attributes: map[string]interface{}{
+				"file":      "foo/v1/foo.proto",
+				"package":   "foo.v1",
+				"name":      "data",
+				"full_name": "foo.v1.Foo.data",
+				"comment":   "",
+				"message":   "foo.v1.Foo",
+				"number":    1,
+				"type":      "bytes",
+				"type_name": "",
+				"label":     "optional",
+				"json_name": "data",
+				"oneof":     "",
+			},
+		},
+		results[0],
+	)
+	assert.Equal(t, "foo.v1.Foo.Bar.raw", results[1].FullName)
+	assert.Equal(t, 0, results[1].StartLine)
+
+	for queryString, expectedFullNames := range map[string][]string{
+		`messages`:              {"foo.v1.Foo", "foo.v1.Foo.Bar"},
+		`messages where nested`: {"foo.v1.Foo.Bar"},
+		`messages where field_count >= 3 || name == "X"`:     {"foo.v1.Foo"},
+		`fields where type_name == "foo.v1.Foo.Bar"`:         {"foo.v1.Foo.bar"},
+		`fields where label != "optional"`:                   {"foo.v1.Foo.bar"},
+		`fields where name matches "^(raw|bar)$"`:            {"foo.v1.Foo.bar", "foo.v1.Foo.Bar.raw"},
+		`fields where comment startsWith "Limited"`:          {"foo.v1.Foo.limited_data"},
+		`fields where number > 2 && json_name endsWith "ar"`: {"foo.v1.Foo.bar"},
+		`enums`:    nil,
+		`services`: nil,
+	} {
+		query, err := Parse(queryString)
+		require.NoError(t, err, queryString)
+		results, err := query.Run(context.Background(), image)
+		require.NoError(t, err, queryString)
+		var fullNames []string
+		for _, result := range results {
+			fullNames = append(fullNames, result.FullName)
+		}
+		assert.Equal(t, expectedFullNames, fullNames, queryString)
+	}
+
+	query, err = Parse(`files where syntax == "proto3"`)
+	require.NoError(t, err)
+	results, err = query.Run(context.Background(), image)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "foo/v1/foo.proto", results[0].Path)
+	assert.Equal(t, "foo.v1", results[0].Attributes["package"])
+}
@@ -0,0 +1,426 @@
+package bufquery
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	valueTypeString valueType = iota + 1
+	valueTypeInt
+	valueTypeBool
+)
+
+type valueType int
+
+func (t valueType) String() string {
+	switch t {
+	case valueTypeString:
+		return "string"
+	case valueTypeInt:
+		return "int"
+	case valueTypeBool:
+		return "bool"
+	default:
+		return strconv.Itoa(int(t))
+	}
+}
+
+const (
+	tokenTypeIdent tokenType = iota + 1
+	tokenTypeString
+	tokenTypeInt
+	tokenTypeOperator
+	tokenTypeEOF
+)
+
+type tokenType int
+
+type token struct {
+	tokenType tokenType
+	value     string
+	// offset is the byte offset of the token within the query, for errors.
+	offset int
+}
+
+// node is a node of an expression.
+//
+// The type of every node is checked when the expression is parsed, so eval
+// always returns a value of the type of the node.
+type node interface {
+	valueType() valueType
+	eval(attributes map[string]interface{}) interface{}
+}
+
+type literalNode struct {
+	value interface{}
+	t     valueType
+}
+
+func (n *literalNode) valueType() valueType {
+	return n.t
+}
+
+func (n *literalNode) eval(map[string]interface{}) interface{} {
+	return n.value
+}
+
+type attributeNode struct {
+	name string
+	t    valueType
+}
+
+func (n *attributeNode) valueType() valueType {
+	return n.t
+}
+
+func (n *attributeNode) eval(attributes map[string]interface{}) interface{} {
+	return attributes[n.name]
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) valueType() valueType {
+	return valueTypeBool
+}
+
+func (n *notNode) eval(attributes map[string]interface{}) interface{} {
+	return !n.operand.eval(attributes).(bool)
+}
+
+type logicalNode struct {
+	// and is true for &&, false for ||.
+	and   bool
+	left  node
+	right node
+}
+
+func (n *logicalNode) valueType() valueType {
+	return valueTypeBool
+}
+
+func (n *logicalNode) eval(attributes map[string]interface{}) interface{} {
+	left := n.left.eval(attributes).(bool)
+	if n.and != left {
+		// short-circuit false && x and true || x
+		return left
+	}
+	return n.right.eval(attributes).(bool)
+}
+
+type comparisonNode struct {
+	operator string
+	left     node
+	right    node
+	// regexp is set for matches, whose right operand is always a string literal.
+	regexp *regexp.Regexp
+}
+
+func (n *comparisonNode) valueType() valueType {
+	return valueTypeBool
+}
+
+func (n *comparisonNode) eval(attributes map[string]interface{}) interface{} {
+	left := n.left.eval(attributes)
+	right := n.right.eval(attributes)
+	switch n.operator {
+	case "==":
+		return left == right
+	case "!=":
+		return left != right
+	case "<":
+		return left.(int) < right.(int)
+	case "<=":
+		return left.(int) <= right.(int)
+	case ">":
+		return left.(int) > right.(int)
+	case ">=":
+		return left.(int) >= right.(int)
+	case "contains":
+		return strings.Contains(left.(string), right.(string))
+	case "startsWith":
+		return strings.HasPrefix(left.(string), right.(string))
+	case "endsWith":
+		return strings.HasSuffix(left.(string), right.(string))
+	case "matches":
+		return n.regexp.MatchString(left.(string))
+	default:
+		// checked when parsing
+		return false
+	}
+}
+
+type parser struct {
+	tokens         []*token
+	index          int
+	attributeTypes map[string]valueType
+}
+
+// parseExpr parses the expression starting at the index of the tokens, whose
+// attributes must be within the attribute types.
+//
+// The expression must be of type bool and end with the tokens.
+func parseExpr(tokens []*token, index int, attributeTypes map[string]valueType) (node, error) {
+	p := &parser{
+		tokens:         tokens,
+		index:          index,
+		attributeTypes: attributeTypes,
+	}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.tokenType != tokenTypeEOF {
+		return nil, newUnexpectedTokenError(t)
+	}
+	if n.valueType() != valueTypeBool {
+		return nil, fmt.Errorf("expression must be a bool but was a %v", n.valueType())
+	}
+	return n, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekOperator("||") {
+		t := p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if err := checkBoolOperands(t, left, right); err != nil {
+			return nil, err
+		}
+		left = &logicalNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekOperator("&&") {
+		t := p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if err := checkBoolOperands(t, left, right); err != nil {
+			return nil, err
+		}
+		left = &logicalNode{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.peekOperator("!") {
+		t := p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if err := checkBoolOperands(t, operand); err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if !isComparisonOperator(t) {
+		return left, nil
+	}
+	p.next()
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	comparison := &comparisonNode{
+		operator: t.value,
+		left:     left,
+		right:    right,
+	}
+	var operandType valueType
+	switch t.value {
+	case "==", "!=":
+		operandType = left.valueType()
+	case "<", "<=", ">", ">=":
+		operandType = valueTypeInt
+	default:
+		operandType = valueTypeString
+	}
+	if left.valueType() != operandType || right.valueType() != operandType {
+		return nil, fmt.Errorf("offset %d: %s cannot compare %v and %v", t.offset, t.value, left.valueType(), right.valueType())
+	}
+	if t.value == "matches" {
+		literal, ok := right.(*literalNode)
+		if !ok {
+			return nil, fmt.Errorf("offset %d: matches must be followed by a string", t.offset)
+		}
+		comparison.regexp, err = regexp.Compile(literal.value.(string))
+		if err != nil {
+			return nil, fmt.Errorf("offset %d: invalid regular expression: %v", t.offset, err)
+		}
+	}
+	return comparison, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.tokenType {
+	case tokenTypeString:
+		return &literalNode{value: t.value, t: valueTypeString}, nil
+	case tokenTypeInt:
+		value, err := strconv.Atoi(t.value)
+		if err != nil {
+			return nil, fmt.Errorf("offset %d: invalid integer %q", t.offset, t.value)
+		}
+		return &literalNode{value: value, t: valueTypeInt}, nil
+	case tokenTypeIdent:
+		switch t.value {
+		case "true":
+			return &literalNode{value: true, t: valueTypeBool}, nil
+		case "false":
+			return &literalNode{value: false, t: valueTypeBool}, nil
+		}
+		attributeType, ok := p.attributeTypes[t.value]
+		if !ok {
+			return nil, fmt.Errorf("offset %d: unknown attribute %q, must be one of %s", t.offset, t.value, attributeTypesToString(p.attributeTypes))
+		}
+		return &attributeNode{name: t.value, t: attributeType}, nil
+	case tokenTypeOperator:
+		if t.value == "(" {
+			n, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if closing := p.next(); closing.tokenType != tokenTypeOperator || closing.value != ")" {
+				return nil, newUnexpectedTokenError(closing)
+			}
+			return n, nil
+		}
+	}
+	return nil, newUnexpectedTokenError(t)
+}
+
+func (p *parser) peek() *token {
+	return p.tokens[p.index]
+}
+
+func (p *parser) peekOperator(value string) bool {
+	t := p.peek()
+	return t.tokenType == tokenTypeOperator && t.value == value
+}
+
+func (p *parser) next() *token {
+	t := p.tokens[p.index]
+	// the last token is always EOF
+	if t.tokenType != tokenTypeEOF {
+		p.index++
+	}
+	return t
+}
+
+func checkBoolOperands(t *token, operands ...node) error {
+	for _, operand := range operands {
+		if operand.valueType() != valueTypeBool {
+			return fmt.Errorf("offset %d: %s requires bool operands but got a %v", t.offset, t.value, operand.valueType())
+		}
+	}
+	return nil
+}
+
+func isComparisonOperator(t *token) bool {
+	switch t.tokenType {
+	case tokenTypeOperator:
+		switch t.value {
+		case "==", "!=", "<", "<=", ">", ">=":
+			return true
+		}
+	case tokenTypeIdent:
+		switch t.value {
+		case "contains", "startsWith", "endsWith", "matches":
+			return true
+		}
+	}
+	return false
+}
+
+// tokenize splits the query into tokens, ending with an EOF token.
+func tokenize(expr string) ([]*token, error) {
+	var tokens []*token
+	for offset := 0; offset < len(expr); {
+		c := rune(expr[offset])
+		switch {
+		case unicode.IsSpace(c):
+			offset++
+		case c == '"':
+			end := offset + 1
+			for ; end < len(expr) && expr[end] != '"'; end++ {
+				if expr[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(expr) {
+				return nil, fmt.Errorf("offset %d: unterminated string", offset)
+			}
+			value, err := strconv.Unquote(expr[offset : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("offset %d: invalid string %s", offset, expr[offset:end+1])
+			}
+			tokens = append(tokens, &token{tokenType: tokenTypeString, value: value, offset: offset})
+			offset = end + 1
+		case unicode.IsDigit(c):
+			end := offset
+			for end < len(expr) && unicode.IsDigit(rune(expr[end])) {
+				end++
+			}
+			tokens = append(tokens, &token{tokenType: tokenTypeInt, value: expr[offset:end], offset: offset})
+			offset = end
+		case c == '_' || unicode.IsLetter(c):
+			end := offset
+			for end < len(expr) && (expr[end] == '_' || unicode.IsLetter(rune(expr[end])) || unicode.IsDigit(rune(expr[end]))) {
+				end++
+			}
+			tokens = append(tokens, &token{tokenType: tokenTypeIdent, value: expr[offset:end], offset: offset})
+			offset = end
+		default:
+			operator := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")"} {
+				if strings.HasPrefix(expr[offset:], candidate) {
+					operator = candidate
+					break
+				}
+			}
+			if operator == "" {
+				return nil, fmt.Errorf("offset %d: unexpected character %q", offset, c)
+			}
+			tokens = append(tokens, &token{tokenType: tokenTypeOperator, value: operator, offset: offset})
+			offset += len(operator)
+		}
+	}
+	return append(tokens, &token{tokenType: tokenTypeEOF, offset: len(expr)}), nil
+}
+
+func newUnexpectedTokenError(t *token) error {
+	if t.tokenType == tokenTypeEOF {
+		return fmt.Errorf("offset %d: unexpected end of query", t.offset)
+	}
+	return fmt.Errorf("offset %d: unexpected %q", t.offset, t.value)
+}
@@ -0,0 +1,112 @@
+// Package bufregistry implements a client for module registries.
+//
+// A module is an image and the config it was built with, published by name
+// and version so that other repositories can use it as an input.
+//
+// Registries serve a JSON API over HTTP:
+//
+//	PUT /v1/modules/{owner}/{name}/versions/{version}
+//	GET /v1/modules/{owner}/{name}/versions/{version}
+//
+// Both use a JSON body with the fields image, config, and image_digest, where
+// image is the base64-encoded binary image, config is the base64-encoded YAML
+// config, and image_digest is the sha256 digest of the image. Versions are
+// immutable, so registries respond to a PUT of an existing version with 409.
+package bufregistry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const (
+	// ModuleReferencePrefix is the prefix of module references when used as inputs,
+	// such as module://buf.example.com/foo/bar:v1.0.0.
+	ModuleReferencePrefix = "module://"
+	// TokenEnvKey is the environment variable key of the tokens used to authenticate to registries.
+	//
+	// The value is a comma-separated list of token@remote, such as token@buf.example.com.
+	TokenEnvKey = "BUF_REGISTRY_TOKEN"
+)
+
+var (
+	nameRegexp    = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+	versionRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+)
+
+// ModuleReference is a reference to a version of a module within a registry.
+type ModuleReference struct {
+	// Remote is the host of the registry, optionally with a port.
+	Remote string
+	// Owner is the owner of the module, such as an organization.
+	Owner string
+	// Name is the name of the module.
+	Name string
+	// Version is the version of the module.
+	Version string
+}
+
+// ParseModuleReference parses a reference of the form remote/owner/name:version.
+func ParseModuleReference(value string) (*ModuleReference, error) {
+	colonIndex := strings.LastIndex(value, ":")
+	if colonIndex < 0 || strings.Contains(value[colonIndex:], "/") {
+		return nil, fmt.Errorf("%q must be of the form remote/owner/name:version", value)
+	}
+	split := strings.Split(value[:colonIndex], "/")
+	if len(split) != 3 || split[0] == "" {
+		return nil, fmt.Errorf("%q must be of the form remote/owner/name:version", value)
+	}
+	moduleReference := &ModuleReference{
+		Remote:  split[0],
+		Owner:   split[1],
+		Name:    split[2],
+		Version: value[colonIndex+1:],
+	}
+	if !nameRegexp.MatchString(moduleReference.Owner) {
+		return nil, fmt.Errorf("%q has invalid owner %q, owners must be lowercase letters, numbers, underscores, and dashes", value, moduleReference.Owner)
+	}
+	if !nameRegexp.MatchString(moduleReference.Name) {
+		return nil, fmt.Errorf("%q has invalid name %q, names must be lowercase letters, numbers, underscores, and dashes", value, moduleReference.Name)
+	}
+	if !versionRegexp.MatchString(moduleReference.Version) {
+		return nil, fmt.Errorf("%q has invalid version %q, versions must be letters, numbers, dots, underscores, and dashes", value, moduleReference.Version)
+	}
+	return moduleReference, nil
+}
+
+// String returns the string value of m.
+func (m *ModuleReference) String() string {
+	return m.Remote + "/" + m.Owner + "/" + m.Name + ":" + m.Version
+}
+
+// Module is a version of a module.
+type Module struct {
+	// Image is the binary image of the module.
+	Image []byte
+	// Config is the YAML config of the module.
+	Config []byte
+}
+
+// Client is a client for module registries.
+type Client interface {
+	// Push publishes the module as the version of the reference.
+	//
+	// Returns error if the version already exists.
+	Push(ctx context.Context, moduleReference *ModuleReference, module *Module) error
+	// Pull fetches the version of the module of the reference.
+	//
+	// The digest of the image is verified.
+	Pull(ctx context.Context, moduleReference *ModuleReference) (*Module, error)
+}
+
+// NewClient returns a new Client.
+//
+// If TokenEnvKey has a token for the remote of a reference, the token is sent as a
+// bearer token. Tokens are never sent to other remotes.
+// Registries on localhost are accessed over plain HTTP.
+func NewClient(httpClient *http.Client, getenv func(string) string) Client {
+	return newClient(httpClient, getenv)
+}
@@ -0,0 +1,210 @@
+package bufregistry
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseModuleReference(t *testing.T) {
+	testParseModuleReference(
+		t,
+		"buf.example.com/foo/bar:v1.0.0",
+		&ModuleReference{Remote: "buf.example.com", Owner: "foo", Name: "bar", Version: "v1.0.0"},
+	)
+	testParseModuleReference(
+		t,
+		"localhost:8080/foo-bar/baz_1:main",
+		&ModuleReference{Remote: "localhost:8080", Owner: "foo-bar", Name: "baz_1", Version: "main"},
+	)
+	testParseModuleReferenceError(t, "buf.example.com/foo/bar")
+	testParseModuleReferenceError(t, "localhost:8080/foo/bar")
+	testParseModuleReferenceError(t, "buf.example.com/foo:v1")
+	testParseModuleReferenceError(t, "buf.example.com/foo/bar/baz:v1")
+	testParseModuleReferenceError(t, "/foo/bar:v1")
+	testParseModuleReferenceError(t, "buf.example.com/Foo/bar:v1")
+	testParseModuleReferenceError(t, "buf.example.com/foo/bar:")
+	testParseModuleReferenceError(t, "buf.example.com/foo/bar:.v1")
+}
+
+func TestPushPull(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(newTestRegistry(t, ""))
+	defer server.Close()
+	client := NewClient(server.Client(), func(string) string { return "" })
+	moduleReference := newTestModuleReference(t, server, "v1")
+
+	_, err := client.Pull(context.Background(), moduleReference)
+	assert.EqualError(t, err, moduleReference.String()+" does not exist")
+
+	module := &Module{
+		Image:  []byte("image"),
+		Config: []byte("lint:\n  use:\n    - DEFAULT\n"),
+	}
+	require.NoError(t, client.Push(context.Background(), moduleReference, module))
+	pulledModule, err := client.Pull(context.Background(), moduleReference)
+	require.NoError(t, err)
+	assert.Equal(t, module, pulledModule)
+
+	err = client.Push(context.Background(), moduleReference, &Module{Image: []byte("other")})
+	assert.EqualError(t, err, moduleReference.String()+" already exists, versions cannot be changed once pushed")
+}
+
+func TestPushPullToken(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(newTestRegistry(t, "foo"))
+	defer server.Close()
+	moduleReference := newTestModuleReference(t, server, "v1")
+
+	err := NewClient(server.Client(), func(string) string { return "" }).Push(
+		context.Background(),
+		moduleReference,
+		&Module{Image: []byte("image")},
+	)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), TokenEnvKey)
+
+	client := NewClient(server.Client(), newTestGetenv("bar@other.example.com, foo@"+moduleReference.Remote))
+	require.NoError(t, client.Push(context.Background(), moduleReference, &Module{Image: []byte("image")}))
+	module, err := client.Pull(context.Background(), moduleReference)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("image"), module.Image)
+
+	// tokens must be scoped to a remote
+	err = NewClient(server.Client(), newTestGetenv("foo")).Push(
+		context.Background(),
+		moduleReference,
+		&Module{Image: []byte("image")},
+	)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), TokenEnvKey)
+	assert.NotContains(t, err.Error(), "foo")
+}
+
+func TestPullTokenOtherRemote(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(newTestRegistry(t, "foo"))
+	defer server.Close()
+	var authorizationCount int64
+	otherServer := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				if request.Header.Get("Authorization") != "" {
+					atomic.AddInt64(&authorizationCount, 1)
+				}
+				responseWriter.WriteHeader(http.StatusNotFound)
+			},
+		),
+	)
+	defer otherServer.Close()
+	moduleReference := newTestModuleReference(t, server, "v1")
+	otherModuleReference := newTestModuleReference(t, otherServer, "v1")
+
+	// the token for one registry is never sent to another registry
+	client := NewClient(server.Client(), newTestGetenv("foo@"+moduleReference.Remote))
+	require.NoError(t, client.Push(context.Background(), moduleReference, &Module{Image: []byte("image")}))
+	_, err := client.Pull(context.Background(), otherModuleReference)
+	assert.EqualError(t, err, otherModuleReference.String()+" does not exist")
+	assert.Equal(t, int64(0), atomic.LoadInt64(&authorizationCount))
+}
+
+func TestPullDigestMismatch(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				assert.NoError(
+					t,
+					json.NewEncoder(responseWriter).Encode(
+						&externalModule{
+							Image:       []byte("image"),
+							ImageDigest: digest([]byte("other")),
+						},
+					),
+				)
+			},
+		),
+	)
+	defer server.Close()
+	_, err := NewClient(server.Client(), func(string) string { return "" }).Pull(
+		context.Background(),
+		newTestModuleReference(t, server, "v1"),
+	)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "expected digest")
+}
+
+func testParseModuleReference(t *testing.T, value string, expectedModuleReference *ModuleReference) {
+	moduleReference, err := ParseModuleReference(value)
+	require.NoError(t, err)
+	assert.Equal(t, expectedModuleReference, moduleReference)
+	assert.Equal(t, value, moduleReference.String())
+}
+
+func testParseModuleReferenceError(t *testing.T, value string) {
+	_, err := ParseModuleReference(value)
+	assert.Error(t, err, value)
+}
+
+func newTestModuleReference(t *testing.T, server *httptest.Server, version string) *ModuleReference {
+	moduleReference, err := ParseModuleReference(strings.TrimPrefix(server.URL, "http://") + "/foo/bar:" + version)
+	require.NoError(t, err)
+	return moduleReference
+}
+
+func newTestGetenv(token string) func(string) string {
+	return func(key string) string {
+		if key == TokenEnvKey {
+			return token
+		}
+		return ""
+	}
+}
+
+// newTestRegistry returns a new in-memory registry.
+//
+// If token is set, requests must have the token as a bearer token.
+func newTestRegistry(t *testing.T, token string) http.Handler {
+	var lock sync.Mutex
+	pathToData := make(map[string][]byte)
+	return http.HandlerFunc(
+		func(responseWriter http.ResponseWriter, request *http.Request) {
+			if token != "" && request.Header.Get("Authorization") != "Bearer "+token {
+				responseWriter.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			switch request.Method {
+			case http.MethodGet:
+				data, ok := pathToData[request.URL.Path]
+				if !ok {
+					responseWriter.WriteHeader(http.StatusNotFound)
+					return
+				}
+				_, err := responseWriter.Write(data)
+				assert.NoError(t, err)
+			case http.MethodPut:
+				if _, ok := pathToData[request.URL.Path]; ok {
+					responseWriter.WriteHeader(http.StatusConflict)
+					return
+				}
+				data, err := ioutil.ReadAll(request.Body)
+				assert.NoError(t, err)
+				pathToData[request.URL.Path] = data
+				responseWriter.WriteHeader(http.StatusCreated)
+			default:
+				responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		},
+	)
+}
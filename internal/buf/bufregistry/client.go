@@ -0,0 +1,176 @@
+package bufregistry
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/pkg/util/utilnet"
+	"go.uber.org/multierr"
+)
+
+type externalModule struct {
+	Image       []byte `json:"image,omitempty"`
+	Config      []byte `json:"config,omitempty"`
+	ImageDigest string `json:"image_digest,omitempty"`
+}
+
+type client struct {
+	httpClient *http.Client
+	getenv     func(string) string
+}
+
+func newClient(httpClient *http.Client, getenv func(string) string) *client {
+	return &client{
+		httpClient: httpClient,
+		getenv:     getenv,
+	}
+}
+
+func (c *client) Push(ctx context.Context, moduleReference *ModuleReference, module *Module) error {
+	data, err := json.Marshal(
+		&externalModule{
+			Image:       module.Image,
+			Config:      module.Config,
+			ImageDigest: digest(module.Image),
+		},
+	)
+	if err != nil {
+		return err
+	}
+	response, err := c.do(ctx, http.MethodPut, moduleReference, data)
+	if err != nil {
+		return err
+	}
+	switch response.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return response.Body.Close()
+	case http.StatusConflict:
+		return multierr.Append(
+			fmt.Errorf("%s already exists, versions cannot be changed once pushed", moduleReference.String()),
+			response.Body.Close(),
+		)
+	default:
+		return multierr.Append(newStatusError(response, moduleReference), response.Body.Close())
+	}
+}
+
+func (c *client) Pull(ctx context.Context, moduleReference *ModuleReference) (_ *Module, retErr error) {
+	response, err := c.do(ctx, http.MethodGet, moduleReference, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%s does not exist", moduleReference.String())
+	default:
+		return nil, newStatusError(response, moduleReference)
+	}
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", moduleReference.String(), err)
+	}
+	externalModule := &externalModule{}
+	if err := json.Unmarshal(data, externalModule); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", moduleReference.String(), err)
+	}
+	if len(externalModule.Image) == 0 {
+		return nil, fmt.Errorf("%s does not have an image", moduleReference.String())
+	}
+	if actualDigest := digest(externalModule.Image); actualDigest != externalModule.ImageDigest {
+		return nil, fmt.Errorf("image of %s: expected digest %s but got %s", moduleReference.String(), externalModule.ImageDigest, actualDigest)
+	}
+	return &Module{
+		Image:  externalModule.Image,
+		Config: externalModule.Config,
+	}, nil
+}
+
+func (c *client) do(
+	ctx context.Context,
+	method string,
+	moduleReference *ModuleReference,
+	data []byte,
+) (*http.Response, error) {
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, newURL(moduleReference), body)
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		request.Header.Set("Content-Type", "application/json")
+	} else {
+		request.Header.Set("Accept", "application/json")
+	}
+	token, err := getToken(c.getenv, moduleReference.Remote)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	return c.httpClient.Do(request)
+}
+
+func newURL(moduleReference *ModuleReference) string {
+	scheme := "https"
+	if utilnet.IsLocalhost(moduleReference.Remote) {
+		scheme = "http"
+	}
+	return scheme + "://" + moduleReference.Remote +
+		"/v1/modules/" + moduleReference.Owner + "/" + moduleReference.Name +
+		"/versions/" + moduleReference.Version
+}
+
+func newStatusError(response *http.Response, moduleReference *ModuleReference) error {
+	switch response.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("got HTTP status code %d for %s %s, check that %s has a valid token for %s", response.StatusCode, response.Request.Method, moduleReference.String(), TokenEnvKey, moduleReference.Remote)
+	default:
+		return fmt.Errorf("got HTTP status code %d for %s %s", response.StatusCode, response.Request.Method, moduleReference.String())
+	}
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// getToken returns the token for the remote from TokenEnvKey, or empty if there is none.
+//
+// The value is a comma-separated list of token@remote, so that tokens are only
+// sent to the registry they are for, and never to registries named by others.
+func getToken(getenv func(string) string, remote string) (string, error) {
+	if getenv == nil {
+		return "", nil
+	}
+	for _, entry := range strings.Split(getenv(TokenEnvKey), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		// the token is not included in errors
+		atIndex := strings.LastIndex(entry, "@")
+		if atIndex <= 0 || atIndex == len(entry)-1 {
+			return "", fmt.Errorf("%s must be a comma-separated list of token@remote, such as token@buf.example.com", TokenEnvKey)
+		}
+		if strings.EqualFold(entry[atIndex+1:], remote) {
+			return entry[:atIndex], nil
+		}
+	}
+	return "", nil
+}
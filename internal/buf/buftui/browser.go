@@ -0,0 +1,458 @@
+package buftui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+)
+
+const (
+	enterAlternateScreen = "\x1b[?1049h"
+	exitAlternateScreen  = "\x1b[?1049l"
+	hideCursor           = "\x1b[?25l"
+	showCursor           = "\x1b[?25h"
+	clearScreen          = "\x1b[H\x1b[2J"
+	reverseVideo         = "\x1b[7m"
+	boldText             = "\x1b[1m"
+	resetText            = "\x1b[0m"
+
+	// maxPreviewHeight is the maximum number of lines of the source preview.
+	maxPreviewHeight = 11
+)
+
+type groupBy int
+
+const (
+	groupByFile groupBy = iota
+	groupByType
+)
+
+type key int
+
+const (
+	keyNone key = iota
+	keyQuit
+	keyUp
+	keyDown
+	keyPageUp
+	keyPageDown
+	keyHome
+	keyEnd
+	keyNextGroup
+	keyPreviousGroup
+	keyToggleGroupBy
+)
+
+// row is a row of the list.
+//
+// Rows are either a group header or a FileAnnotation.
+type row struct {
+	header         string
+	fileAnnotation *filev1beta1.FileAnnotation
+}
+
+type browser struct {
+	fileAnnotations []*filev1beta1.FileAnnotation
+	getFileData     func(string) ([]byte, error)
+	// a nil value means the source is not available
+	pathToLines map[string][]string
+
+	groupBy groupBy
+	rows    []*row
+	// selected is the index of the selected row, which is always a FileAnnotation
+	selected int
+	// offset is the index of the first visible row
+	offset int
+}
+
+func newBrowser(
+	fileAnnotations []*filev1beta1.FileAnnotation,
+	getFileData func(string) ([]byte, error),
+) *browser {
+	b := &browser{
+		fileAnnotations: fileAnnotations,
+		getFileData:     getFileData,
+		pathToLines:     make(map[string][]string),
+	}
+	b.setGroupBy(groupByFile)
+	return b
+}
+
+// run reads keys from in and renders to out until the user quits.
+func (b *browser) run(in io.Reader, out io.Writer, getSize func() (int, int, error)) error {
+	reader := bufio.NewReader(in)
+	for {
+		width, height, err := getSize()
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(out, clearScreen+b.render(width, height)); err != nil {
+			return err
+		}
+		key, err := readKey(reader)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if key == keyQuit {
+			return nil
+		}
+		b.handleKey(key, b.listHeight(height))
+	}
+}
+
+func (b *browser) handleKey(key key, listHeight int) {
+	switch key {
+	case keyUp:
+		b.moveSelected(-1)
+	case keyDown:
+		b.moveSelected(1)
+	case keyPageUp:
+		b.moveSelected(-listHeight)
+	case keyPageDown:
+		b.moveSelected(listHeight)
+	case keyHome:
+		b.moveSelected(-len(b.rows))
+	case keyEnd:
+		b.moveSelected(len(b.rows))
+	case keyNextGroup:
+		for i := b.selected + 1; i < len(b.rows); i++ {
+			if b.rows[i].header != "" {
+				b.selected = i + 1
+				break
+			}
+		}
+	case keyPreviousGroup:
+		// the header of the current group is the closest header above the selected row
+		currentHeader := b.selected - 1
+		for currentHeader > 0 && b.rows[currentHeader].header == "" {
+			currentHeader--
+		}
+		for i := currentHeader - 1; i >= 0; i-- {
+			if b.rows[i].header != "" {
+				b.selected = i + 1
+				break
+			}
+		}
+	case keyToggleGroupBy:
+		if b.groupBy == groupByFile {
+			b.setGroupBy(groupByType)
+		} else {
+			b.setGroupBy(groupByFile)
+		}
+	}
+	b.scrollToSelected(listHeight)
+}
+
+// moveSelected moves the selected row by the delta, skipping over headers.
+func (b *browser) moveSelected(delta int) {
+	if len(b.rows) == 0 {
+		return
+	}
+	selected := b.selected + delta
+	if selected < 0 {
+		selected = 0
+	}
+	if selected >= len(b.rows) {
+		selected = len(b.rows) - 1
+	}
+	step := -1
+	if delta > 0 {
+		step = 1
+	}
+	// headers are never the last row, and the first row is always a header
+	for b.rows[selected].header != "" {
+		if selected == 0 {
+			step = 1
+		}
+		selected += step
+	}
+	b.selected = selected
+}
+
+func (b *browser) scrollToSelected(listHeight int) {
+	if listHeight < 1 {
+		listHeight = 1
+	}
+	if b.selected < b.offset {
+		b.offset = b.selected
+		// show the group header if the first row of the group is selected
+		if b.offset > 0 && b.rows[b.offset-1].header != "" {
+			b.offset--
+		}
+	}
+	if b.selected >= b.offset+listHeight {
+		b.offset = b.selected - listHeight + 1
+	}
+}
+
+// setGroupBy sets the grouping and rebuilds the rows, keeping the selected FileAnnotation.
+func (b *browser) setGroupBy(groupBy groupBy) {
+	var selectedFileAnnotation *filev1beta1.FileAnnotation
+	if b.selected < len(b.rows) {
+		selectedFileAnnotation = b.rows[b.selected].fileAnnotation
+	}
+	b.groupBy = groupBy
+	var groupKeys []string
+	groupKeyToFileAnnotations := make(map[string][]*filev1beta1.FileAnnotation)
+	for _, fileAnnotation := range b.fileAnnotations {
+		groupKey := b.groupKey(fileAnnotation)
+		if _, ok := groupKeyToFileAnnotations[groupKey]; !ok {
+			groupKeys = append(groupKeys, groupKey)
+		}
+		groupKeyToFileAnnotations[groupKey] = append(groupKeyToFileAnnotations[groupKey], fileAnnotation)
+	}
+	b.rows = nil
+	b.selected = 0
+	b.offset = 0
+	for _, groupKey := range groupKeys {
+		fileAnnotations := groupKeyToFileAnnotations[groupKey]
+		b.rows = append(b.rows, &row{header: fmt.Sprintf("%s (%d)", groupKey, len(fileAnnotations))})
+		for _, fileAnnotation := range fileAnnotations {
+			if fileAnnotation == selectedFileAnnotation {
+				b.selected = len(b.rows)
+			}
+			b.rows = append(b.rows, &row{fileAnnotation: fileAnnotation})
+		}
+	}
+	if b.selected == 0 && len(b.rows) > 1 {
+		b.selected = 1
+	}
+}
+
+func (b *browser) groupKey(fileAnnotation *filev1beta1.FileAnnotation) string {
+	if b.groupBy == groupByType {
+		if fileAnnotation.Type == "" {
+			return "<none>"
+		}
+		return fileAnnotation.Type
+	}
+	if fileAnnotation.Path == "" {
+		return "<input>"
+	}
+	return fileAnnotation.Path
+}
+
+// listHeight is the number of rows of the list that are visible.
+func (b *browser) listHeight(height int) int {
+	// one line for the title and one line for the preview separator
+	return height - 2 - b.previewHeight(height)
+}
+
+func (b *browser) previewHeight(height int) int {
+	previewHeight := (height - 2) / 2
+	if previewHeight > maxPreviewHeight {
+		previewHeight = maxPreviewHeight
+	}
+	if previewHeight < 0 {
+		previewHeight = 0
+	}
+	return previewHeight
+}
+
+// render renders the browser for the given terminal size.
+//
+// Lines are separated by \r\n as the terminal is in raw mode.
+func (b *browser) render(width int, height int) string {
+	var lines []string
+	groupByName := "file"
+	otherGroupByName := "rule"
+	if b.groupBy == groupByType {
+		groupByName, otherGroupByName = otherGroupByName, groupByName
+	}
+	lines = append(
+		lines,
+		boldText+truncate(
+			fmt.Sprintf(
+				"%d violations grouped by %s | j/k: move  n/p: next/previous group  g: group by %s  q: quit",
+				len(b.fileAnnotations),
+				groupByName,
+				otherGroupByName,
+			),
+			width,
+		)+resetText,
+	)
+	listHeight := b.listHeight(height)
+	for i := b.offset; i < b.offset+listHeight && i < len(b.rows); i++ {
+		lines = append(lines, b.renderRow(i, width))
+	}
+	for len(lines) < listHeight+1 {
+		lines = append(lines, "")
+	}
+	var selectedFileAnnotation *filev1beta1.FileAnnotation
+	if b.selected < len(b.rows) {
+		selectedFileAnnotation = b.rows[b.selected].fileAnnotation
+	}
+	if selectedFileAnnotation == nil {
+		return strings.Join(lines, "\r\n")
+	}
+	lines = append(lines, truncate("--- "+fileAnnotationLocation(selectedFileAnnotation)+" "+strings.Repeat("-", width), width))
+	lines = append(lines, b.renderPreview(selectedFileAnnotation, width, b.previewHeight(height))...)
+	return strings.Join(lines, "\r\n")
+}
+
+func (b *browser) renderRow(i int, width int) string {
+	row := b.rows[i]
+	if row.header != "" {
+		return boldText + truncate(row.header, width) + resetText
+	}
+	fileAnnotation := row.fileAnnotation
+	var s string
+	if b.groupBy == groupByType {
+		s = fmt.Sprintf("  %s %s", fileAnnotationLocation(fileAnnotation), fileAnnotation.Message)
+	} else {
+		s = fmt.Sprintf("  %d:%d %s %s", fileAnnotation.StartLine, fileAnnotation.StartColumn, fileAnnotation.Type, fileAnnotation.Message)
+	}
+	s = truncate(s, width)
+	if i == b.selected {
+		return reverseVideo + s + strings.Repeat(" ", width-len([]rune(s))) + resetText
+	}
+	return s
+}
+
+// renderPreview renders the source around the start line of the FileAnnotation,
+// with a caret under the start column.
+func (b *browser) renderPreview(fileAnnotation *filev1beta1.FileAnnotation, width int, previewHeight int) []string {
+	if previewHeight < 1 {
+		return nil
+	}
+	if fileAnnotation.Path == "" || fileAnnotation.StartLine == 0 {
+		return []string{"no source location"}
+	}
+	sourceLines := b.getLines(fileAnnotation.Path)
+	if sourceLines == nil {
+		return []string{"source not available"}
+	}
+	startLine := int(fileAnnotation.StartLine)
+	// one line is used for the caret
+	numLines := previewHeight - 1
+	first := startLine - numLines/2
+	if first < 1 {
+		first = 1
+	}
+	var lines []string
+	for line := first; line < first+numLines && line <= len(sourceLines); line++ {
+		marker := " "
+		if line == startLine {
+			marker = ">"
+		}
+		prefix := fmt.Sprintf("%s%5d | ", marker, line)
+		lines = append(lines, truncate(prefix+sourceLines[line-1], width))
+		if line == startLine && fileAnnotation.StartColumn > 0 {
+			lines = append(lines, truncate(strings.Repeat(" ", len(prefix)+int(fileAnnotation.StartColumn)-1)+"^", width))
+		}
+	}
+	return lines
+}
+
+func (b *browser) getLines(path string) []string {
+	lines, ok := b.pathToLines[path]
+	if ok {
+		return lines
+	}
+	data, err := b.getFileData(path)
+	if err == nil {
+		// tabs are replaced so that the caret lines up with the start column
+		lines = strings.Split(strings.Replace(string(data), "\t", " ", -1), "\n")
+	}
+	b.pathToLines[path] = lines
+	return lines
+}
+
+func readKey(reader *bufio.Reader) (key, error) {
+	c, err := reader.ReadByte()
+	if err != nil {
+		return keyNone, err
+	}
+	switch c {
+	case 'q', 3, 4:
+		// 3 is ctrl-c and 4 is ctrl-d, which are not signals in raw mode
+		return keyQuit, nil
+	case 'k':
+		return keyUp, nil
+	case 'j':
+		return keyDown, nil
+	case 'n':
+		return keyNextGroup, nil
+	case 'p':
+		return keyPreviousGroup, nil
+	case 'g':
+		return keyToggleGroupBy, nil
+	case ' ':
+		return keyPageDown, nil
+	case 0x1b:
+		return readEscapeKey(reader)
+	default:
+		return keyNone, nil
+	}
+}
+
+// readEscapeKey reads the remainder of an escape sequence.
+func readEscapeKey(reader *bufio.Reader) (key, error) {
+	c, err := reader.ReadByte()
+	if err != nil {
+		return keyNone, err
+	}
+	if c != '[' && c != 'O' {
+		return keyNone, nil
+	}
+	var param string
+	for {
+		c, err = reader.ReadByte()
+		if err != nil {
+			return keyNone, err
+		}
+		if c < '0' || c > '9' {
+			break
+		}
+		param += string(c)
+	}
+	switch c {
+	case 'A':
+		return keyUp, nil
+	case 'B':
+		return keyDown, nil
+	case 'H':
+		return keyHome, nil
+	case 'F':
+		return keyEnd, nil
+	case '~':
+		switch n, _ := strconv.Atoi(param); n {
+		case 1, 7:
+			return keyHome, nil
+		case 4, 8:
+			return keyEnd, nil
+		case 5:
+			return keyPageUp, nil
+		case 6:
+			return keyPageDown, nil
+		}
+	}
+	return keyNone, nil
+}
+
+func fileAnnotationLocation(fileAnnotation *filev1beta1.FileAnnotation) string {
+	path := fileAnnotation.Path
+	if path == "" {
+		path = "<input>"
+	}
+	return fmt.Sprintf("%s:%d:%d", path, fileAnnotation.StartLine, fileAnnotation.StartColumn)
+}
+
+// truncate truncates s to at most width runes.
+func truncate(s string, width int) string {
+	if width < 0 {
+		width = 0
+	}
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width])
+}
@@ -0,0 +1,200 @@
+package buftui
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testFileAnnotations = []*filev1beta1.FileAnnotation{
+	{
+		Path:        "a.proto",
+		StartLine:   3,
+		StartColumn: 1,
+		Type:        "PACKAGE_DIRECTORY_MATCH",
+		Message:     "Package mismatch.",
+	},
+	{
+		Path:        "a.proto",
+		StartLine:   6,
+		StartColumn: 9,
+		Type:        "FIELD_LOWER_SNAKE_CASE",
+		Message:     "Field name mismatch.",
+	},
+	{
+		Path:        "b.proto",
+		StartLine:   2,
+		StartColumn: 3,
+		Type:        "FIELD_LOWER_SNAKE_CASE",
+		Message:     "Field name mismatch.",
+	},
+}
+
+func TestBrowserGroupBy(t *testing.T) {
+	t.Parallel()
+	b := newBrowser(testFileAnnotations, testGetFileData)
+	assert.Equal(
+		t,
+		[]string{
+			"a.proto (2)",
+			"PACKAGE_DIRECTORY_MATCH",
+			"FIELD_LOWER_SNAKE_CASE",
+			"b.proto (1)",
+			"FIELD_LOWER_SNAKE_CASE",
+		},
+		testRowStrings(b),
+	)
+	assert.Equal(t, 1, b.selected)
+	// the selected FileAnnotation is kept when changing the grouping
+	b.handleKey(keyDown, 10)
+	b.handleKey(keyToggleGroupBy, 10)
+	assert.Equal(
+		t,
+		[]string{
+			"PACKAGE_DIRECTORY_MATCH (1)",
+			"PACKAGE_DIRECTORY_MATCH",
+			"FIELD_LOWER_SNAKE_CASE (2)",
+			"FIELD_LOWER_SNAKE_CASE",
+			"FIELD_LOWER_SNAKE_CASE",
+		},
+		testRowStrings(b),
+	)
+	assert.Equal(t, testFileAnnotations[1], b.rows[b.selected].fileAnnotation)
+}
+
+func TestBrowserNavigation(t *testing.T) {
+	t.Parallel()
+	b := newBrowser(testFileAnnotations, testGetFileData)
+	// headers are skipped
+	b.handleKey(keyDown, 10)
+	b.handleKey(keyDown, 10)
+	assert.Equal(t, testFileAnnotations[2], b.rows[b.selected].fileAnnotation)
+	b.handleKey(keyDown, 10)
+	assert.Equal(t, testFileAnnotations[2], b.rows[b.selected].fileAnnotation)
+	b.handleKey(keyUp, 10)
+	assert.Equal(t, testFileAnnotations[1], b.rows[b.selected].fileAnnotation)
+	b.handleKey(keyHome, 10)
+	assert.Equal(t, testFileAnnotations[0], b.rows[b.selected].fileAnnotation)
+	b.handleKey(keyUp, 10)
+	assert.Equal(t, testFileAnnotations[0], b.rows[b.selected].fileAnnotation)
+	b.handleKey(keyNextGroup, 10)
+	assert.Equal(t, testFileAnnotations[2], b.rows[b.selected].fileAnnotation)
+	b.handleKey(keyNextGroup, 10)
+	assert.Equal(t, testFileAnnotations[2], b.rows[b.selected].fileAnnotation)
+	b.handleKey(keyPreviousGroup, 10)
+	assert.Equal(t, testFileAnnotations[0], b.rows[b.selected].fileAnnotation)
+	b.handleKey(keyEnd, 10)
+	assert.Equal(t, testFileAnnotations[2], b.rows[b.selected].fileAnnotation)
+	// only two rows are visible, so the list scrolls
+	b.handleKey(keyHome, 2)
+	assert.Equal(t, 0, b.offset)
+	b.handleKey(keyEnd, 2)
+	assert.Equal(t, 3, b.offset)
+	b.handleKey(keyUp, 2)
+	assert.Equal(t, 2, b.offset)
+	b.handleKey(keyUp, 2)
+	// the group header is shown with the first row of the group
+	assert.Equal(t, 0, b.offset)
+}
+
+func TestBrowserRender(t *testing.T) {
+	t.Parallel()
+	b := newBrowser(testFileAnnotations, testGetFileData)
+	b.handleKey(keyDown, 10)
+	lines := strings.Split(testStripEscapes(b.render(40, 12)), "\r\n")
+	assert.Equal(
+		t,
+		[]string{
+			"3 violations grouped by file | j/k: move",
+			"a.proto (2)",
+			"  3:1 PACKAGE_DIRECTORY_MATCH Package mi",
+			"  6:9 FIELD_LOWER_SNAKE_CASE Field name ",
+			"b.proto (1)",
+			"  2:3 FIELD_LOWER_SNAKE_CASE Field name ",
+			"--- a.proto:6:9 ------------------------",
+			"     4 | ",
+			"     5 | message Foo {",
+			">    6 |   int64 oneTwo = 1;",
+			"                 ^",
+			"     7 | }",
+		},
+		lines,
+	)
+	b.handleKey(keyDown, 10)
+	lines = strings.Split(testStripEscapes(b.render(40, 12)), "\r\n")
+	assert.Equal(t, "source not available", lines[len(lines)-1])
+}
+
+func TestBrowserRun(t *testing.T) {
+	t.Parallel()
+	b := newBrowser(testFileAnnotations, testGetFileData)
+	out := bytes.NewBuffer(nil)
+	// down arrow, g, then q
+	require.NoError(
+		t,
+		b.run(
+			strings.NewReader("\x1b[Bgq"),
+			out,
+			func() (int, int, error) {
+				return 80, 12, nil
+			},
+		),
+	)
+	assert.Equal(t, groupByType, b.groupBy)
+	assert.Equal(t, testFileAnnotations[1], b.rows[b.selected].fileAnnotation)
+	assert.Equal(t, 3, strings.Count(out.String(), clearScreen))
+}
+
+func TestReadKey(t *testing.T) {
+	t.Parallel()
+	for input, expectedKey := range map[string]key{
+		"q":       keyQuit,
+		"\x03":    keyQuit,
+		"j":       keyDown,
+		"k":       keyUp,
+		"\x1b[A":  keyUp,
+		"\x1b[B":  keyDown,
+		"\x1bOA":  keyUp,
+		"\x1b[5~": keyPageUp,
+		"\x1b[6~": keyPageDown,
+		"\x1b[H":  keyHome,
+		"\x1b[4~": keyEnd,
+		"x":       keyNone,
+	} {
+		key, err := readKey(bufio.NewReader(strings.NewReader(input)))
+		assert.NoError(t, err)
+		assert.Equal(t, expectedKey, key, input)
+	}
+}
+
+func testGetFileData(path string) ([]byte, error) {
+	if path == "a.proto" {
+		return []byte("syntax = \"proto3\";\n\npackage a;\n\nmessage Foo {\n  int64 oneTwo = 1;\n}\n"), nil
+	}
+	return nil, errors.New("not found")
+}
+
+func testRowStrings(b *browser) []string {
+	var rowStrings []string
+	for _, row := range b.rows {
+		if row.header != "" {
+			rowStrings = append(rowStrings, row.header)
+		} else {
+			rowStrings = append(rowStrings, row.fileAnnotation.Type)
+		}
+	}
+	return rowStrings
+}
+
+func testStripEscapes(s string) string {
+	for _, escape := range []string{reverseVideo, boldText, resetText} {
+		s = strings.Replace(s, escape, "", -1)
+	}
+	return s
+}
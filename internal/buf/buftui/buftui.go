@@ -0,0 +1,61 @@
+// Package buftui provides an interactive terminal browser for check results.
+//
+// This is primarily meant for the CLI tool, and isn't used in a service context.
+package buftui
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+	"go.uber.org/multierr"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Run runs the browser for the FileAnnotations until the user quits.
+//
+// The FileAnnotations are expected to be sorted and have real file paths.
+// getFileData is used to get the source for the preview. If it returns an error,
+// the preview shows that the source is not available.
+//
+// in and out must be a terminal.
+func Run(
+	in *os.File,
+	out *os.File,
+	fileAnnotations []*filev1beta1.FileAnnotation,
+	getFileData func(string) ([]byte, error),
+) (retErr error) {
+	inFd := int(in.Fd())
+	outFd := int(out.Fd())
+	if !IsTerminal(in, out) {
+		return errors.New("input and output must be a terminal")
+	}
+	oldState, err := terminal.MakeRaw(inFd)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, terminal.Restore(inFd, oldState))
+	}()
+	// use the alternate screen so that the previous terminal contents are restored on exit
+	if _, err := io.WriteString(out, enterAlternateScreen+hideCursor); err != nil {
+		return err
+	}
+	defer func() {
+		_, err := io.WriteString(out, showCursor+exitAlternateScreen)
+		retErr = multierr.Append(retErr, err)
+	}()
+	return newBrowser(fileAnnotations, getFileData).run(
+		in,
+		out,
+		func() (int, int, error) {
+			return terminal.GetSize(outFd)
+		},
+	)
+}
+
+// IsTerminal returns true if in and out are both a terminal.
+func IsTerminal(in *os.File, out *os.File) bool {
+	return terminal.IsTerminal(int(in.Fd())) && terminal.IsTerminal(int(out.Fd()))
+}
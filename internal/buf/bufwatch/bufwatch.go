@@ -0,0 +1,69 @@
+// Package bufwatch runs a function again whenever a .proto file or buf.yaml
+// within a directory changes.
+//
+// Changes are found with OS filesystem notifications where available, which
+// is currently linux, and by polling the directory otherwise. Changes are
+// debounced, so that many files changing at once, such as with a formatter or a
+// git checkout, result in a single run.
+package bufwatch
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// DefaultPollInterval is the default interval between checking for file
+	// changes when filesystem notifications are not available.
+	DefaultPollInterval = 250 * time.Millisecond
+	// DefaultDebounceInterval is the default duration that files must stay
+	// unchanged after a change before running again.
+	DefaultDebounceInterval = 100 * time.Millisecond
+)
+
+// Watcher runs a function whenever a .proto file or buf.yaml within a directory changes.
+type Watcher interface {
+	// Watch calls run, and then calls run again on every change until the
+	// context is done or run returns an error.
+	//
+	// Returns the error of the context if the context is done.
+	Watch(ctx context.Context, run func(context.Context) error) error
+}
+
+// NewWatcher returns a new Watcher for the directory.
+//
+// Hidden directories such as .git are not watched.
+func NewWatcher(dirPath string, options ...WatcherOption) Watcher {
+	return newWatcher(dirPath, options...)
+}
+
+// WatcherOption is an option for a new Watcher.
+type WatcherOption func(*watcher)
+
+// WatcherWithPollInterval returns a new WatcherOption that sets the interval
+// between checking for file changes when filesystem notifications are not available.
+//
+// The default is DefaultPollInterval.
+func WatcherWithPollInterval(pollInterval time.Duration) WatcherOption {
+	return func(watcher *watcher) {
+		watcher.pollInterval = pollInterval
+	}
+}
+
+// WatcherWithAllFiles returns a new WatcherOption that watches all files
+// instead of only .proto files and buf.yaml files.
+func WatcherWithAllFiles() WatcherOption {
+	return func(watcher *watcher) {
+		watcher.allFiles = true
+	}
+}
+
+// WatcherWithDebounceInterval returns a new WatcherOption that sets the duration
+// that files must stay unchanged after a change before running again.
+//
+// The default is DefaultDebounceInterval.
+func WatcherWithDebounceInterval(debounceInterval time.Duration) WatcherOption {
+	return func(watcher *watcher) {
+		watcher.debounceInterval = debounceInterval
+	}
+}
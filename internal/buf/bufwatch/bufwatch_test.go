@@ -0,0 +1,136 @@
+package bufwatch
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	testWatch(
+		t,
+		dirPath,
+		newWatcher(
+			dirPath,
+			WatcherWithPollInterval(10*time.Millisecond),
+			WatcherWithDebounceInterval(10*time.Millisecond),
+		),
+	)
+}
+
+func TestWatchPoll(t *testing.T) {
+	t.Parallel()
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	watcher := newWatcher(
+		dirPath,
+		WatcherWithPollInterval(10*time.Millisecond),
+		WatcherWithDebounceInterval(10*time.Millisecond),
+	)
+	watcher.poll = true
+	testWatch(t, dirPath, watcher)
+}
+
+func TestWatchNotifications(t *testing.T) {
+	t.Parallel()
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	notifier, err := newNotifier(dirPath)
+	if err == errNotificationsUnsupported {
+		t.Skip(err)
+	}
+	require.NoError(t, err)
+	require.NoError(t, notifier.Close())
+	// changes are found without polling
+	testWatch(
+		t,
+		dirPath,
+		newWatcher(
+			dirPath,
+			WatcherWithPollInterval(time.Hour),
+			WatcherWithDebounceInterval(10*time.Millisecond),
+		),
+	)
+}
+
+func TestWatchRunError(t *testing.T) {
+	t.Parallel()
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	runErr := errors.New("system error")
+	assert.Equal(
+		t,
+		runErr,
+		NewWatcher(dirPath).Watch(
+			context.Background(),
+			func(context.Context) error {
+				return runErr
+			},
+		),
+	)
+}
+
+func testWatch(t *testing.T, dirPath string, watcher *watcher) {
+	filePath := filepath.Join(dirPath, "a.proto")
+	require.NoError(t, ioutil.WriteFile(filePath, []byte("one"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(dirPath, ".git"), 0755))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runC := make(chan string)
+	errC := make(chan error, 1)
+	go func() {
+		errC <- watcher.Watch(
+			ctx,
+			func(ctx context.Context) error {
+				data, err := ioutil.ReadFile(filePath)
+				if err != nil {
+					return err
+				}
+				select {
+				case runC <- string(data):
+				case <-ctx.Done():
+				}
+				return nil
+			},
+		)
+	}()
+	assert.Equal(t, "one", <-runC)
+
+	// files that are not watched do not result in a run
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "a.txt"), []byte("two"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, ".git", "b.proto"), []byte("two"), 0644))
+	select {
+	case data := <-runC:
+		t.Fatalf("unexpected run after changing unwatched files: %q", data)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NoError(t, ioutil.WriteFile(filePath, []byte("three"), 0644))
+	assert.Equal(t, "three", <-runC)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "buf.yaml"), []byte("four"), 0644))
+	assert.Equal(t, "three", <-runC)
+	// files within new directories are watched
+	require.NoError(t, os.MkdirAll(filepath.Join(dirPath, "b", "c"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "b", "c", "c.proto"), []byte("five"), 0644))
+	assert.Equal(t, "three", <-runC)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "b", "c", "c.proto"), []byte("six"), 0644))
+	assert.Equal(t, "three", <-runC)
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-errC)
+}
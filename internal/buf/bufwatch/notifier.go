@@ -0,0 +1,34 @@
+package bufwatch
+
+import (
+	"errors"
+	"io"
+)
+
+// errNotificationsUnsupported is returned by newNotifier if OS filesystem
+// notifications are not supported on the platform.
+var errNotificationsUnsupported = errors.New("filesystem notifications are not supported")
+
+// notifier receives OS filesystem notifications for a directory and all of
+// its subdirectories, except hidden directories.
+type notifier struct {
+	// changeC receives a value after one or more files may have changed.
+	//
+	// Notifications are coalesced, so this never holds more than one value.
+	changeC chan struct{}
+	// errC receives an error if notifications can no longer be received.
+	errC   chan error
+	closer io.Closer
+}
+
+func (n *notifier) Close() error {
+	return n.closer.Close()
+}
+
+// notify signals that files may have changed without blocking.
+func (n *notifier) notify() {
+	select {
+	case n.changeC <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,131 @@
+package bufwatch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CREATE |
+	syscall.IN_DELETE |
+	syscall.IN_MODIFY |
+	syscall.IN_ATTRIB |
+	syscall.IN_MOVED_FROM |
+	syscall.IN_MOVED_TO |
+	syscall.IN_ONLYDIR
+
+// newNotifier returns a new notifier using inotify.
+//
+// inotify does not watch subdirectories, so a watch is added for each
+// directory, including directories created after the notifier is created.
+func newNotifier(dirPath string) (*notifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	// the file descriptor is non-blocking, so reads use the runtime poller and
+	// are interrupted by closing the file
+	file := os.NewFile(uintptr(fd), "inotify")
+	inotify := &inotify{
+		dirPath:               dirPath,
+		fd:                    fd,
+		file:                  file,
+		watchDescriptorToPath: make(map[int]string),
+	}
+	if err := inotify.addDirs(dirPath); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	notifier := &notifier{
+		changeC: make(chan struct{}, 1),
+		errC:    make(chan error, 1),
+		closer:  file,
+	}
+	go func() {
+		if err := inotify.read(notifier); err != nil {
+			notifier.errC <- err
+		}
+	}()
+	return notifier, nil
+}
+
+type inotify struct {
+	dirPath string
+	fd      int
+	file    *os.File
+	// watchDescriptorToPath are the paths of the directories by watch descriptor.
+	//
+	// This is only accessed by addDirs, which is called before reading starts
+	// and then only by read.
+	watchDescriptorToPath map[int]string
+}
+
+// addDirs adds a watch for the directory and all of its subdirectories.
+func (i *inotify) addDirs(dirPath string) error {
+	return filepath.Walk(
+		dirPath,
+		func(path string, fileInfo os.FileInfo, err error) error {
+			if err != nil {
+				// the directory may have been removed since its parent was read
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if !fileInfo.IsDir() {
+				return nil
+			}
+			if isSkippedDir(i.dirPath, path, fileInfo.Name()) {
+				return filepath.SkipDir
+			}
+			watchDescriptor, err := syscall.InotifyAddWatch(i.fd, path, inotifyMask)
+			if err != nil {
+				if err == syscall.ENOENT {
+					return nil
+				}
+				return os.NewSyscallError("inotify_add_watch", err)
+			}
+			i.watchDescriptorToPath[watchDescriptor] = path
+			return nil
+		},
+	)
+}
+
+// read reads events until the file is closed.
+func (i *inotify) read(notifier *notifier) error {
+	buffer := make([]byte, 64*1024)
+	for {
+		n, err := i.file.Read(buffer)
+		if err != nil {
+			if errors.Is(err, os.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
+			nameBytes := buffer[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(event.Len)]
+			offset += syscall.SizeofInotifyEvent + int(event.Len)
+			if event.Mask&syscall.IN_ISDIR != 0 && event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+				if parentPath, ok := i.watchDescriptorToPath[int(event.Wd)]; ok {
+					// the name is padded with null bytes
+					name := string(nameBytes)
+					for len(name) > 0 && name[len(name)-1] == 0 {
+						name = name[:len(name)-1]
+					}
+					if err := i.addDirs(filepath.Join(parentPath, name)); err != nil {
+						return err
+					}
+				}
+			}
+			if event.Mask&syscall.IN_IGNORED != 0 {
+				delete(i.watchDescriptorToPath, int(event.Wd))
+			}
+		}
+		// the queue may have overflowed, in which case events were dropped, but
+		// the snapshot is compared on every notification regardless
+		notifier.notify()
+	}
+}
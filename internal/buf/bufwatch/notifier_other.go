@@ -0,0 +1,8 @@
+//go:build !linux
+// +build !linux
+
+package bufwatch
+
+func newNotifier(dirPath string) (*notifier, error) {
+	return nil, errNotificationsUnsupported
+}
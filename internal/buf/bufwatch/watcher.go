@@ -0,0 +1,173 @@
+package bufwatch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bufbuild/buf/internal/buf/bufconfig"
+)
+
+type watcher struct {
+	dirPath          string
+	pollInterval     time.Duration
+	debounceInterval time.Duration
+	allFiles         bool
+	// poll is true if OS filesystem notifications should not be used, which
+	// is only set by tests.
+	poll bool
+}
+
+func newWatcher(dirPath string, options ...WatcherOption) *watcher {
+	watcher := &watcher{
+		dirPath:          dirPath,
+		pollInterval:     DefaultPollInterval,
+		debounceInterval: DefaultDebounceInterval,
+	}
+	for _, option := range options {
+		option(watcher)
+	}
+	return watcher
+}
+
+func (w *watcher) Watch(ctx context.Context, run func(context.Context) error) error {
+	// start watching before the first run, so that changes during the run are not missed
+	var notifier *notifier
+	if !w.poll {
+		// fall back to polling if notifications are not available, such as on
+		// platforms other than linux or if the limit of watches is reached
+		if n, err := newNotifier(w.dirPath); err == nil {
+			notifier = n
+			defer func() {
+				_ = notifier.Close()
+			}()
+		}
+	}
+	snapshot, err := w.getSnapshot()
+	if err != nil {
+		return err
+	}
+	if err := run(ctx); err != nil {
+		return err
+	}
+	for {
+		if err := w.wait(ctx, notifier); err != nil {
+			return err
+		}
+		newSnapshot, err := w.getSnapshot()
+		if err != nil {
+			return err
+		}
+		if snapshotsEqual(snapshot, newSnapshot) {
+			continue
+		}
+		// wait until the files stop changing
+		for !snapshotsEqual(snapshot, newSnapshot) {
+			snapshot = newSnapshot
+			if err := sleep(ctx, w.debounceInterval); err != nil {
+				return err
+			}
+			newSnapshot, err = w.getSnapshot()
+			if err != nil {
+				return err
+			}
+		}
+		if err := run(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// wait waits until files may have changed.
+//
+// If notifier is nil, this waits for the poll interval.
+func (w *watcher) wait(ctx context.Context, notifier *notifier) error {
+	if notifier == nil {
+		return sleep(ctx, w.pollInterval)
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-notifier.changeC:
+		return nil
+	case err := <-notifier.errC:
+		return err
+	}
+}
+
+// getSnapshot returns the state of all watched files within the directory.
+//
+// Hidden directories such as .git are skipped.
+func (w *watcher) getSnapshot() (map[string]fileState, error) {
+	snapshot := make(map[string]fileState)
+	if err := filepath.Walk(
+		w.dirPath,
+		func(path string, fileInfo os.FileInfo, err error) error {
+			if err != nil {
+				// the file may have been removed since the directory was read
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if fileInfo.IsDir() {
+				if isSkippedDir(w.dirPath, path, fileInfo.Name()) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if fileInfo.Mode().IsRegular() && (w.allFiles || isWatchedFile(fileInfo.Name())) {
+				snapshot[path] = fileState{
+					modTime: fileInfo.ModTime().UnixNano(),
+					size:    fileInfo.Size(),
+				}
+			}
+			return nil
+		},
+	); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+type fileState struct {
+	modTime int64
+	size    int64
+}
+
+func isWatchedFile(name string) bool {
+	return filepath.Ext(name) == ".proto" || name == bufconfig.ConfigFilePath
+}
+
+// isSkippedDir returns true for hidden directories within the directory.
+func isSkippedDir(dirPath string, path string, name string) bool {
+	return path != dirPath && strings.HasPrefix(name, ".")
+}
+
+func snapshotsEqual(one map[string]fileState, two map[string]fileState) bool {
+	if len(one) != len(two) {
+		return false
+	}
+	for path, oneFileState := range one {
+		twoFileState, ok := two[path]
+		if !ok || oneFileState != twoFileState {
+			return false
+		}
+	}
+	return true
+}
+
+// sleep sleeps for the duration, returning the error of the context if the
+// context is done first.
+func sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -1,19 +1,41 @@
 package buf
 
 import (
+	"archive/tar"
+	"bufio"
 	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/bufbuild/buf/internal/pkg/util/utilencoding"
+	"github.com/bufbuild/buf/internal/pkg/util/utillsp"
 	"github.com/bufbuild/buf/internal/pkg/util/utilstring"
 	"github.com/bufbuild/cli/clicobra"
 	"github.com/bufbuild/cli/clienv"
 	"github.com/bufbuild/cli/clios"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 func TestSuccess1(t *testing.T) {
@@ -40,6 +62,402 @@ func TestSuccess6(t *testing.T) {
 	testRun(t, 0, ``, "check", "lint", "--input", filepath.Join("testdata", "success"))
 }
 
+func TestSuccess7(t *testing.T) {
+	testRun(t, 0, ``, "image", "build", "-o", clios.DevNull, "--normalize", "--source", filepath.Join("testdata", "success"))
+}
+
+func TestImageBuildOutputFormat(t *testing.T) {
+	t.Parallel()
+	for _, outputFormat := range []string{"bin", "bingz", "json", "jsongz"} {
+		outputFormat := outputFormat
+		t.Run(outputFormat, func(t *testing.T) {
+			t.Parallel()
+			stdout := bytes.NewBuffer(nil)
+			stderr := bytes.NewBuffer(nil)
+			exitCode := clicobra.Run(
+				newRootCommand("test"),
+				"test",
+				clienv.NewEnv(
+					[]string{
+						"image",
+						"build",
+						"--source",
+						filepath.Join("testdata", "success"),
+						"-o",
+						"-",
+						"--output-format",
+						outputFormat,
+					},
+					nil,
+					stdout,
+					stderr,
+					nil,
+				),
+			)
+			require.Equal(t, 0, exitCode, utilstring.TrimLines(stderr.String()))
+			data := stdout.Bytes()
+			if strings.HasSuffix(outputFormat, "gz") {
+				gzipReader, err := gzip.NewReader(stdout)
+				require.NoError(t, err)
+				data, err = ioutil.ReadAll(gzipReader)
+				require.NoError(t, err)
+			}
+			image := &imagev1beta1.Image{}
+			if strings.HasPrefix(outputFormat, "json") {
+				require.NoError(t, jsonpb.Unmarshal(bytes.NewReader(data), image))
+			} else {
+				require.NoError(t, proto.Unmarshal(data, image))
+			}
+			assert.NotEmpty(t, image.File)
+		})
+	}
+}
+
+func TestFailImageBuildOutputFormat1(t *testing.T) {
+	testRun(t, 1, ``, "image", "build", "-o", "-", "--output-format", "tar", "--source", filepath.Join("testdata", "success"))
+}
+
+func TestFailImageBuildOutputFormat2(t *testing.T) {
+	testRun(t, 1, ``, "image", "build", "-o", "-#format=json", "--output-format", "json", "--source", filepath.Join("testdata", "success"))
+}
+
+func TestImageBuildMultipleOutputs(t *testing.T) {
+	t.Parallel()
+	tmpDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDirPath)) }()
+	binFilePath := filepath.Join(tmpDirPath, "image.bin")
+	binGzFilePath := filepath.Join(tmpDirPath, "image.bin.gz")
+	jsonFilePath := filepath.Join(tmpDirPath, "image.json")
+	jsonGzFilePath := filepath.Join(tmpDirPath, "image.json.gz")
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		0,
+		``,
+		"image",
+		"build",
+		"--source",
+		filepath.Join("testdata", "success"),
+		"-o",
+		binFilePath,
+		"-o",
+		binGzFilePath,
+		"-o",
+		jsonFilePath,
+		"-o",
+		jsonGzFilePath,
+	)
+	binData, err := ioutil.ReadFile(binFilePath)
+	require.NoError(t, err)
+	image := &imagev1beta1.Image{}
+	require.NoError(t, proto.Unmarshal(binData, image))
+	assert.NotEmpty(t, image.File)
+
+	binGzFile, err := os.Open(binGzFilePath)
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, binGzFile.Close()) }()
+	gzipReader, err := gzip.NewReader(binGzFile)
+	require.NoError(t, err)
+	binGzData, err := ioutil.ReadAll(gzipReader)
+	require.NoError(t, err)
+	assert.Equal(t, binData, binGzData)
+
+	jsonFile, err := os.Open(jsonFilePath)
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, jsonFile.Close()) }()
+	jsonImage := &imagev1beta1.Image{}
+	require.NoError(t, jsonpb.Unmarshal(jsonFile, jsonImage))
+	assert.True(t, proto.Equal(image, jsonImage))
+
+	jsonGzFile, err := os.Open(jsonGzFilePath)
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, jsonGzFile.Close()) }()
+	gzipReader, err = gzip.NewReader(jsonGzFile)
+	require.NoError(t, err)
+	jsonGzImage := &imagev1beta1.Image{}
+	require.NoError(t, jsonpb.Unmarshal(gzipReader, jsonGzImage))
+	assert.True(t, proto.Equal(image, jsonGzImage))
+}
+
+func TestImageBuildYAMLOutput(t *testing.T) {
+	t.Parallel()
+	tmpDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDirPath)) }()
+	binFilePath := filepath.Join(tmpDirPath, "image.bin")
+	yamlFilePath := filepath.Join(tmpDirPath, "image.yaml")
+	yamlGzFilePath := filepath.Join(tmpDirPath, "image.yaml.gz")
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		0,
+		``,
+		"image",
+		"build",
+		"--source",
+		filepath.Join("testdata", "success"),
+		"-o",
+		binFilePath,
+		"-o",
+		yamlFilePath,
+		"-o",
+		yamlGzFilePath,
+	)
+	binData, err := ioutil.ReadFile(binFilePath)
+	require.NoError(t, err)
+	image := &imagev1beta1.Image{}
+	require.NoError(t, proto.Unmarshal(binData, image))
+
+	yamlData, err := ioutil.ReadFile(yamlFilePath)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(yamlData), "file:\n"), string(yamlData))
+	jsonData, err := utilencoding.YAMLToJSON(yamlData)
+	require.NoError(t, err)
+	yamlImage := &imagev1beta1.Image{}
+	require.NoError(t, jsonpb.Unmarshal(bytes.NewReader(jsonData), yamlImage))
+	assert.True(t, proto.Equal(image, yamlImage))
+
+	testRunCmdNoParallel(t, newRootCommand("test"), 0, `buf/buf.proto`, "ls-files", "--input", yamlFilePath)
+	testRunCmdNoParallel(t, newRootCommand("test"), 0, `buf/buf.proto`, "ls-files", "--input", yamlGzFilePath)
+}
+
+func TestImageBuildCompressionLevel(t *testing.T) {
+	t.Parallel()
+	tmpDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDirPath)) }()
+	binFilePath := filepath.Join(tmpDirPath, "image.bin")
+	binGzFilePath := filepath.Join(tmpDirPath, "image.bin.gz")
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		0,
+		``,
+		"image",
+		"build",
+		"--source",
+		filepath.Join("testdata", "success"),
+		"-o",
+		binFilePath,
+		"-o",
+		binGzFilePath+"#level=9",
+	)
+	binData, err := ioutil.ReadFile(binFilePath)
+	require.NoError(t, err)
+	binGzFile, err := os.Open(binGzFilePath)
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, binGzFile.Close()) }()
+	gzipReader, err := gzip.NewReader(binGzFile)
+	require.NoError(t, err)
+	binGzData, err := ioutil.ReadAll(gzipReader)
+	require.NoError(t, err)
+	assert.Equal(t, binData, binGzData)
+}
+
+func TestFailImageBuildCompressionLevel(t *testing.T) {
+	testRun(t, 1, ``, "image", "build", "-o", "-#format=bin,level=9", "--source", filepath.Join("testdata", "success"))
+}
+
+func TestImageBuildShards(t *testing.T) {
+	t.Parallel()
+	tmpDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDirPath)) }()
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		0,
+		``,
+		"image",
+		"build",
+		"--source",
+		filepath.Join("testdata", "shards"),
+		"-o",
+		filepath.Join(tmpDirPath, "image.bin")+"#shards=3",
+	)
+	manifestData, err := ioutil.ReadFile(filepath.Join(tmpDirPath, "image.manifest.json"))
+	require.NoError(t, err)
+	assert.JSONEq(
+		t,
+		`{
+			"shards": [
+				{"path": "image-00000-of-00003.bin", "packages": ["a.v1"], "files": ["a/v1/a.proto"]},
+				{"path": "image-00001-of-00003.bin", "packages": ["b.v1"], "files": ["b/v1/b.proto"], "dependencies": ["image-00000-of-00003.bin"]},
+				{"path": "image-00002-of-00003.bin", "packages": ["c.v1"], "files": ["c/v1/c.proto"], "dependencies": ["image-00001-of-00003.bin"]}
+			]
+		}`,
+		string(manifestData),
+	)
+	for i, fileName := range []string{"a/v1/a.proto", "b/v1/b.proto", "c/v1/c.proto"} {
+		data, err := ioutil.ReadFile(filepath.Join(tmpDirPath, fmt.Sprintf("image-%05d-of-00003.bin", i)))
+		require.NoError(t, err)
+		image := &imagev1beta1.Image{}
+		require.NoError(t, proto.Unmarshal(data, image))
+		require.Len(t, image.GetFile(), 1)
+		assert.Equal(t, fileName, image.GetFile()[0].GetName())
+	}
+	_, err = os.Stat(filepath.Join(tmpDirPath, "image.bin"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFailImageBuildShards(t *testing.T) {
+	testRun(t, 1, ``, "image", "build", "-o", "-#format=bin,shards=2", "--source", filepath.Join("testdata", "shards"))
+}
+
+func TestImageBuildWriteChecksum(t *testing.T) {
+	t.Parallel()
+	tmpDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDirPath)) }()
+	imageFilePath := filepath.Join(tmpDirPath, "image.bin")
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		0,
+		``,
+		"image",
+		"build",
+		"--source",
+		filepath.Join("testdata", "success"),
+		"-o",
+		imageFilePath,
+		"--write-checksum",
+		"sha256",
+	)
+	data, err := ioutil.ReadFile(imageFilePath)
+	require.NoError(t, err)
+	digest := sha256.Sum256(data)
+	sha256Hex := hex.EncodeToString(digest[:])
+	sidecarData, err := ioutil.ReadFile(imageFilePath + ".sha256")
+	require.NoError(t, err)
+	assert.Equal(t, sha256Hex+"  image.bin\n", string(sidecarData))
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		0,
+		`buf/buf.proto`,
+		"ls-files",
+		"--input",
+		imageFilePath,
+	)
+
+	require.NoError(t, ioutil.WriteFile(imageFilePath+".sha256", []byte(strings.Repeat("0", 64)+"  image.bin\n"), 0644))
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		1,
+		``,
+		"ls-files",
+		"--input",
+		imageFilePath,
+	)
+	// the sha256 option takes precedence over the sidecar file
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		0,
+		`buf/buf.proto`,
+		"ls-files",
+		"--input",
+		imageFilePath+"#sha256="+sha256Hex,
+	)
+}
+
+func TestFailImageBuildWriteChecksumStdout(t *testing.T) {
+	testRun(t, 1, ``, "image", "build", "-o", "-", "--write-checksum", "sha256", "--source", filepath.Join("testdata", "success"))
+}
+
+func TestFailImageBuildWriteChecksumAlgorithm(t *testing.T) {
+	testRun(t, 1, ``, "image", "build", "-o", clios.DevNull, "--write-checksum", "md5", "--source", filepath.Join("testdata", "success"))
+}
+
+func TestImageBuildOutputTemplate(t *testing.T) {
+	t.Parallel()
+	tmpDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDirPath)) }()
+	sourceDirPath := filepath.Join("testdata", "success")
+	gitSHAOutput, err := exec.Command("git", "-C", sourceDirPath, "rev-parse", "HEAD").Output()
+	require.NoError(t, err)
+	gitSHA := strings.TrimSpace(string(gitSHAOutput))
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		0,
+		``,
+		"image",
+		"build",
+		"--source",
+		sourceDirPath,
+		"-o",
+		filepath.Join(tmpDirPath, "image-{{.GitSHA}}.bin"),
+		"-o",
+		filepath.Join(tmpDirPath, "image-{{.Timestamp}}-{{.ConfigHash}}.json"),
+	)
+	_, err = os.Stat(filepath.Join(tmpDirPath, "image-"+gitSHA+".bin"))
+	assert.NoError(t, err)
+	jsonFilePaths, err := filepath.Glob(filepath.Join(tmpDirPath, "*.json"))
+	require.NoError(t, err)
+	require.Len(t, jsonFilePaths, 1)
+	assert.Regexp(t, `^image-\d{8}T\d{6}Z-[0-9a-f]{64}\.json$`, filepath.Base(jsonFilePaths[0]))
+}
+
+func TestFailImageBuildOutputTemplate(t *testing.T) {
+	testRun(t, 1, ``, "image", "build", "-o", "image-{{.Foo}}.bin", "--source", filepath.Join("testdata", "success"))
+}
+
+func TestImageBuildOutputReplacesFile(t *testing.T) {
+	t.Parallel()
+	tmpDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDirPath)) }()
+	binFilePath := filepath.Join(tmpDirPath, "image.bin")
+	require.NoError(t, ioutil.WriteFile(binFilePath, []byte("previous"), 0600))
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		0,
+		``,
+		"image",
+		"build",
+		"--source",
+		filepath.Join("testdata", "success"),
+		"-o",
+		binFilePath,
+	)
+	binData, err := ioutil.ReadFile(binFilePath)
+	require.NoError(t, err)
+	image := &imagev1beta1.Image{}
+	require.NoError(t, proto.Unmarshal(binData, image))
+	assert.NotEmpty(t, image.File)
+	// the image is written to a temporary file in the same directory that is renamed into place
+	fileInfos, err := ioutil.ReadDir(tmpDirPath)
+	require.NoError(t, err)
+	require.Len(t, fileInfos, 1)
+	assert.Equal(t, "image.bin", fileInfos[0].Name())
+	if runtime.GOOS != "windows" {
+		assert.Equal(t, os.FileMode(0600), fileInfos[0].Mode().Perm())
+	}
+}
+
+func TestFailImageBuildOutputMissingDirectory(t *testing.T) {
+	testRun(t, 1, ``, "image", "build", "-o", filepath.Join("testdata", "missing", "image.bin"), "--source", filepath.Join("testdata", "success"))
+}
+
+func TestFailImageBuildObjectStorageOutput1(t *testing.T) {
+	// keys are required before any request is made
+	testRun(t, 1, ``, "image", "build", "-o", "s3://bucket#format=bin", "--source", filepath.Join("testdata", "success"))
+}
+
+func TestFailImageBuildObjectStorageOutput2(t *testing.T) {
+	testRun(t, 1, ``, "image", "build", "-o", "gs://bucket#format=bin", "--source", filepath.Join("testdata", "success"))
+}
+
+func TestFailImageBuildMultipleOutputs(t *testing.T) {
+	testRun(t, 1, ``, "image", "build", "-o", "-", "-o", "-#format=json", "--source", filepath.Join("testdata", "success"))
+}
+
 func TestSuccessProfile1(t *testing.T) {
 	testRunProfile(t, 0, ``, "image", "build", "-o", clios.DevNull, "--source", filepath.Join("testdata", "success"))
 }
@@ -137,242 +555,3686 @@ func TestFail7(t *testing.T) {
 	)
 }
 
-func TestFail8(t *testing.T) {
+func TestCheckLintFailurePolicy1(t *testing.T) {
 	testRun(
 		t,
-		1,
-		`testdata/fail2/buf/buf.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".
-		testdata/fail2/buf/buf2.proto:9:9:Field name "oneThree" should be lower_snake_case, such as "one_three".`,
+		0,
+		`testdata/fail/buf/buf.proto:3:1:Files with package "other" must be within a directory "other" relative to root but were in directory "fail/buf".
+        testdata/fail/buf/buf.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".`,
 		"check",
 		"lint",
+		"--file",
+		filepath.Join("testdata", "fail", "buf", "buf.proto"),
 		"--input",
-		filepath.Join("testdata", "fail2"),
+		filepath.Join("testdata"),
+		"--input-config",
+		`{"lint":{"use":["BASIC"],"failure_policy":{"warn":["FILE_LAYOUT"],"thresholds":{"STYLE_BASIC":1}}}}`,
 	)
 }
 
-func TestFail9(t *testing.T) {
+func TestCheckLintFailurePolicy2(t *testing.T) {
 	testRun(
 		t,
 		1,
-		`testdata/fail2/buf/buf.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".`,
+		`testdata/fail/buf/buf.proto:3:1:Files with package "other" must be within a directory "other" relative to root but were in directory "fail/buf".
+        testdata/fail/buf/buf.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".`,
 		"check",
 		"lint",
-		"--input",
-		filepath.Join("testdata", "fail2"),
 		"--file",
-		filepath.Join("testdata", "fail2", "buf", "buf.proto"),
+		filepath.Join("testdata", "fail", "buf", "buf.proto"),
+		"--input",
+		filepath.Join("testdata"),
+		"--input-config",
+		`{"lint":{"use":["BASIC"],"failure_policy":{"fail_on":"warning","warn":["BASIC"]}}}`,
 	)
 }
 
-func TestFail10(t *testing.T) {
+func TestCheckLintFailurePolicy3(t *testing.T) {
 	testRun(
 		t,
-		0,
+		1,
 		``,
 		"check",
 		"lint",
 		"--input",
-		filepath.Join("testdata", "fail2"),
-		"--file",
-		filepath.Join("testdata", "fail2", "buf", "buf3.proto"),
+		filepath.Join("testdata"),
+		"--input-config",
+		`{"lint":{"overrides":[{"path":"fail","failure_policy":{"fail_on":"never"}}]}}`,
 	)
 }
 
-func TestFail11(t *testing.T) {
-	testRun(
+func TestCheckLintExtends(t *testing.T) {
+	t.Parallel()
+	cacheDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(cacheDirPath)) }()
+	extendsData := []byte(`lint:
+  use:
+    - BASIC
+  except:
+    - FIELD_LOWER_SNAKE_CASE
+`)
+	sum := sha256.Sum256(extendsData)
+	extendsSHA256Hex := hex.EncodeToString(sum[:])
+	var requestCount int64
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				atomic.AddInt64(&requestCount, 1)
+				_, _ = responseWriter.Write(extendsData)
+			},
+		),
+	)
+	defer server.Close()
+
+	for _, testCase := range []struct {
+		sha256Hex        string
+		except           string
+		expectedExitCode int
+		expectedStdout   string
+	}{
+		{
+			sha256Hex:        extendsSHA256Hex,
+			expectedExitCode: 1,
+			expectedStdout:   `testdata/fail/buf/buf.proto:3:1:Files with package "other" must be within a directory "other" relative to root but were in directory "fail/buf".`,
+		},
+		{
+			// the local config replaces the values of the extended config
+			sha256Hex:        extendsSHA256Hex,
+			except:           "FILE_LAYOUT",
+			expectedExitCode: 1,
+			expectedStdout:   `testdata/fail/buf/buf.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".`,
+		},
+		{
+			sha256Hex:        strings.Repeat("0", 64),
+			expectedExitCode: 1,
+		},
+	} {
+		config := fmt.Sprintf(`{"lint":{"extends":"%s/policy.yaml#sha256=%s"}}`, server.URL, testCase.sha256Hex)
+		if testCase.except != "" {
+			config = fmt.Sprintf(`{"lint":{"extends":"%s/policy.yaml#sha256=%s","except":["%s"]}}`, server.URL, testCase.sha256Hex, testCase.except)
+		}
+		stdout := bytes.NewBuffer(nil)
+		stderr := bytes.NewBuffer(nil)
+		exitCode := clicobra.Run(
+			newRootCommand("test"),
+			"test",
+			clienv.NewEnv(
+				[]string{
+					"check",
+					"lint",
+					"--file",
+					filepath.Join("testdata", "fail", "buf", "buf.proto"),
+					"--input",
+					filepath.Join("testdata"),
+					"--input-config",
+					config,
+				},
+				nil,
+				stdout,
+				stderr,
+				map[string]string{
+					"XDG_CACHE_HOME": cacheDirPath,
+				},
+			),
+		)
+		assert.Equal(t, testCase.expectedExitCode, exitCode, utilstring.TrimLines(stderr.String()))
+		assert.Equal(t, utilstring.TrimLines(testCase.expectedStdout), utilstring.TrimLines(stdout.String()))
+		if testCase.expectedStdout == "" {
+			assert.Contains(t, stderr.String(), "sha256")
+		}
+	}
+	// the extended config is cached by hash after the first request
+	assert.Equal(t, int64(2), atomic.LoadInt64(&requestCount))
+}
+
+func TestCheckLintExtendsInsecure(t *testing.T) {
+	t.Parallel()
+	sha256Hex := strings.Repeat("0", 64)
+	for _, extends := range []string{
+		"http://buf.example.com/policy.yaml#sha256=" + sha256Hex,
+		"http://10.0.0.1:8080/policy.yaml#sha256=" + sha256Hex,
+		"ftp://buf.example.com/policy.yaml#sha256=" + sha256Hex,
+		"https:///policy.yaml#sha256=" + sha256Hex,
+	} {
+		stdout := bytes.NewBuffer(nil)
+		stderr := bytes.NewBuffer(nil)
+		exitCode := clicobra.Run(
+			newRootCommand("test"),
+			"test",
+			clienv.NewEnv(
+				[]string{
+					"check",
+					"lint",
+					"--input",
+					filepath.Join("testdata", "success"),
+					"--input-config",
+					fmt.Sprintf(`{"lint":{"extends":"%s"}}`, extends),
+				},
+				nil,
+				stdout,
+				stderr,
+				nil,
+			),
+		)
+		assert.Equal(t, 1, exitCode, extends)
+		assert.Empty(t, stdout.String(), extends)
+		assert.Contains(t, stderr.String(), "must be an https URL", extends)
+	}
+}
+
+func TestCheckLintFix(t *testing.T) {
+	t.Parallel()
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	filePath := filepath.Join(dirPath, "a.proto")
+	require.NoError(
 		t,
-		1,
-		`testdata/fail2/buf/buf2.proto:5:8:testdata/buf/buf.proto: does not exist`,
+		ioutil.WriteFile(
+			filePath,
+			[]byte(`syntax = "proto3";
+
+package a;
+
+message Foo {
+  // three is three.
+  int64 three = 3; // trailing
+  /* one
+     is one */
+  int64 one = 1;
+  message Bar {}
+
+  // detached
+
+  int64 two = 2;
+  oneof baz {
+    int64 five = 5;
+    int64 four = 4;
+  }
+}
+`),
+			0644,
+		),
+	)
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		0,
+		``,
 		"check",
 		"lint",
-		"--file",
-		filepath.Join("testdata", "fail2", "buf", "buf2.proto"),
+		"--fix",
 		"--input",
-		filepath.Join("testdata"),
+		dirPath,
+		"--input-config",
+		`{"lint":{"use":["FIELD_ORDERED"]}}`,
 	)
+	data, err := ioutil.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`syntax = "proto3";
+
+package a;
+
+message Foo {
+  /* one
+     is one */
+  int64 one = 1;
+  int64 two = 2;
+  message Bar {}
+
+  // detached
+
+  // three is three.
+  int64 three = 3; // trailing
+  oneof baz {
+    int64 four = 4;
+    int64 five = 5;
+  }
 }
+`,
+		string(data),
+	)
+	// fields that share a line cannot be moved
+	unfixableData := []byte(`syntax = "proto3";
 
-func TestFail12(t *testing.T) {
-	testRun(
+package a;
+
+message Foo {
+  int64 two = 2; int64 one = 1;
+}
+`)
+	require.NoError(t, ioutil.WriteFile(filePath, unfixableData, 0644))
+	testRunCmdNoParallel(
 		t,
+		newRootCommand("test"),
 		1,
-		`lint:
-  ignore_only:
-    FIELD_LOWER_SNAKE_CASE:
-	  - buf/buf.proto
-	PACKAGE_DIRECTORY_MATCH:
-	  - buf/buf.proto`,
+		filePath+`:6:18:Field "one" should be declared before field "two".`,
 		"check",
 		"lint",
+		"--fix",
 		"--input",
-		filepath.Join("testdata", "fail"),
-		"--error-format",
-		"config-ignore-yaml",
+		dirPath,
+		"--input-config",
+		`{"lint":{"use":["FIELD_ORDERED"]}}`,
 	)
+	data, err = ioutil.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, string(unfixableData), string(data))
 }
 
-func TestFailCheckBreaking1(t *testing.T) {
-	testRun(
+func TestCheckLintFixImportOrdered(t *testing.T) {
+	t.Parallel()
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	// pre-populate the cache so that we do not hit the network
+	cacheDirPath := filepath.Join(dirPath, "cache")
+	depDirPath := filepath.Join(cacheDirPath, "buf", "deps", "googleapis", "37c923effe8b002884466074f84bc4e78e6ade62", "google", "api")
+	require.NoError(t, os.MkdirAll(depDirPath, 0755))
+	require.NoError(
 		t,
-		1,
-		`
-		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:5:1:Previously present field "3" with name "three" on message "Two" was deleted.
-		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:10:1:Previously present field "3" with name "three" on message "Three" was deleted.
-		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:12:5:Previously present field "3" with name "three" on message "Five" was deleted.
-		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:22:3:Previously present field "3" with name "three" on message "Seven" was deleted.
-		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/2.proto:57:1:Previously present field "3" with name "three" on message "Nine" was deleted.
-		`,
-		"check",
-		"breaking",
-		"--input",
-		// can't bother right now to filepath.Join this
-		"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete",
+		ioutil.WriteFile(
+			filepath.Join(depDirPath, "http.proto"),
+			[]byte(`syntax = "proto3"; package google.api; message HttpRule { string get = 2; }`),
+			0644,
+		),
+	)
+	inputDirPath := filepath.Join(dirPath, "input")
+	require.NoError(t, os.MkdirAll(inputDirPath, 0755))
+	require.NoError(
+		t,
+		ioutil.WriteFile(
+			filepath.Join(inputDirPath, "buf.yaml"),
+			[]byte("build:\n  deps:\n    - googleapis\nlint:\n  use:\n    - IMPORT_ORDERED\n"),
+			0644,
+		),
+	)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(inputDirPath, "b.proto"), []byte(`syntax = "proto3";`), 0644))
+	filePath := filepath.Join(inputDirPath, "a.proto")
+	require.NoError(
+		t,
+		ioutil.WriteFile(
+			filePath,
+			[]byte(`syntax = "proto3";
+
+package a;
+
+// b is local.
+import "b.proto";
+
+import "google/protobuf/timestamp.proto"; // trailing
+import "google/api/http.proto";
+import "google/protobuf/duration.proto";
+`),
+			0644,
+		),
+	)
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	exitCode := clicobra.Run(
+		newRootCommand("test"),
+		"test",
+		clienv.NewEnv(
+			[]string{
+				"check",
+				"lint",
+				"--fix",
+				"--input",
+				inputDirPath,
+			},
+			nil,
+			stdout,
+			stderr,
+			map[string]string{
+				"XDG_CACHE_HOME": cacheDirPath,
+			},
+		),
+	)
+	assert.Equal(t, 0, exitCode, utilstring.TrimLines(stderr.String()))
+	assert.Equal(t, "", utilstring.TrimLines(stdout.String()), utilstring.TrimLines(stderr.String()))
+	data, err := ioutil.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`syntax = "proto3";
+
+package a;
+
+import "google/protobuf/duration.proto";
+import "google/protobuf/timestamp.proto"; // trailing
+
+import "google/api/http.proto";
+
+// b is local.
+import "b.proto";
+`,
+		string(data),
+	)
+}
+
+func TestCheckLintFixLicenseHeader(t *testing.T) {
+	t.Parallel()
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	filePath := filepath.Join(dirPath, "a.proto")
+	require.NoError(t, ioutil.WriteFile(filePath, []byte("\nsyntax = \"proto3\";\n\npackage a;\n"), 0644))
+	// files that start with another comment are not fixed
+	unfixableFilePath := filepath.Join(dirPath, "b.proto")
+	unfixableData := []byte("// Copyright Acme, Inc.\n\nsyntax = \"proto3\";\n\npackage a;\n")
+	require.NoError(t, ioutil.WriteFile(unfixableFilePath, unfixableData, 0644))
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		1,
+		unfixableFilePath+`:1:1:File does not start with the license header.`,
+		"check",
+		"lint",
+		"--fix",
+		"--input",
+		dirPath,
+		"--input-config",
+		`{"lint":{"use":["FILE_LICENSE_HEADER"],"license_header":"Copyright {year} Acme, Inc.\n\nLicensed under the Apache License."}}`,
+	)
+	data, err := ioutil.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		fmt.Sprintf(
+			`// Copyright %d Acme, Inc.
+//
+// Licensed under the Apache License.
+
+syntax = "proto3";
+
+package a;
+`,
+			time.Now().Year(),
+		),
+		string(data),
+	)
+	data, err = ioutil.ReadFile(unfixableFilePath)
+	require.NoError(t, err)
+	assert.Equal(t, string(unfixableData), string(data))
+}
+
+func TestCheckLintFixBOMCRLF(t *testing.T) {
+	t.Parallel()
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	filePath := filepath.Join(dirPath, "a.proto")
+	require.NoError(t, ioutil.WriteFile(filePath, []byte("\xEF\xBB\xBFsyntax = \"proto3\";\r\n\r\npackage a;\r\n"), 0644))
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		1,
+		filePath+`:1:1:File starts with a UTF-8 byte order mark.
+		`+filePath+`:1:1:File uses CRLF line endings.`,
+		"check",
+		"lint",
+		"--input",
+		dirPath,
+		"--input-config",
+		`{"lint":{"use":["FILE_NO_BOM_CRLF"]}}`,
+	)
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		0,
+		``,
+		"check",
+		"lint",
+		"--fix",
+		"--input",
+		dirPath,
+		"--input-config",
+		`{"lint":{"use":["FILE_NO_BOM_CRLF"]}}`,
+	)
+	data, err := ioutil.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, "syntax = \"proto3\";\n\npackage a;\n", string(data))
+}
+
+func TestCheckLintFixNotDirectory(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"check",
+		"lint",
+		"--fix",
+		"--input",
+		filepath.Join("testdata", "fail", "buf", "buf.proto"),
+	)
+}
+
+func TestCheckLintBaseline(t *testing.T) {
+	t.Parallel()
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	require.NoError(
+		t,
+		ioutil.WriteFile(
+			filepath.Join(dirPath, "buf.yaml"),
+			[]byte(`lint:
+  use:
+    - FIELD_LOWER_SNAKE_CASE
+  baseline: baseline.json
+`),
+			0644,
+		),
+	)
+	filePath := filepath.Join(dirPath, "a.proto")
+	require.NoError(
+		t,
+		ioutil.WriteFile(
+			filePath,
+			[]byte(`syntax = "proto3";
+
+package a;
+
+message Foo {
+  int64 oneTwo = 1;
+}
+`),
+			0644,
+		),
+	)
+	// the baseline does not exist yet
+	testRunCmdNoParallel(t, newRootCommand("test"), 1, ``, "check", "lint", "--input", dirPath)
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		0,
+		``,
+		"check",
+		"lint",
+		"--input",
+		dirPath,
+		"--write-baseline",
+		filepath.Join(dirPath, "baseline.json"),
+	)
+	data, err := ioutil.ReadFile(filepath.Join(dirPath, "baseline.json"))
+	require.NoError(t, err)
+	assert.JSONEq(
+		t,
+		`{"violations":[{"path":"a.proto","type":"FIELD_LOWER_SNAKE_CASE","message":"Field name \"oneTwo\" should be lower_snake_case, such as \"one_two\".","count":1}]}`,
+		string(data),
+	)
+	testRunCmdNoParallel(t, newRootCommand("test"), 0, ``, "check", "lint", "--input", dirPath)
+	// moving the suppressed violation does not matter, but new violations fail
+	require.NoError(
+		t,
+		ioutil.WriteFile(
+			filePath,
+			[]byte(`syntax = "proto3";
+
+package a;
+
+message Foo {
+  int64 threeFour = 3;
+  int64 oneTwo = 1;
+}
+`),
+			0644,
+		),
+	)
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		1,
+		filePath+`:6:9:Field name "threeFour" should be lower_snake_case, such as "three_four".`,
+		"check",
+		"lint",
+		"--input",
+		dirPath,
+	)
+}
+
+func TestFail8(t *testing.T) {
+	testRun(
+		t,
+		1,
+		`testdata/fail2/buf/buf.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".
+		testdata/fail2/buf/buf2.proto:9:9:Field name "oneThree" should be lower_snake_case, such as "one_three".`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail2"),
+	)
+}
+
+func TestFail9(t *testing.T) {
+	testRun(
+		t,
+		1,
+		`testdata/fail2/buf/buf.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail2"),
+		"--file",
+		filepath.Join("testdata", "fail2", "buf", "buf.proto"),
+	)
+}
+
+func TestFail10(t *testing.T) {
+	testRun(
+		t,
+		0,
+		``,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail2"),
+		"--file",
+		filepath.Join("testdata", "fail2", "buf", "buf3.proto"),
+	)
+}
+
+func TestFail11(t *testing.T) {
+	testRun(
+		t,
+		1,
+		`testdata/fail2/buf/buf2.proto:5:8:testdata/buf/buf.proto: does not exist`,
+		"check",
+		"lint",
+		"--file",
+		filepath.Join("testdata", "fail2", "buf", "buf2.proto"),
+		"--input",
+		filepath.Join("testdata"),
+	)
+}
+
+func TestFail12(t *testing.T) {
+	testRun(
+		t,
+		1,
+		`lint:
+  ignore_only:
+    FIELD_LOWER_SNAKE_CASE:
+	  - buf/buf.proto
+	PACKAGE_DIRECTORY_MATCH:
+	  - buf/buf.proto`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--error-format",
+		"config-ignore-yaml",
+	)
+}
+
+func TestFail13(t *testing.T) {
+	testRun(
+		t,
+		1,
+		`<?xml version="1.0" encoding="UTF-8"?>
+		<testsuites tests="2" failures="2">
+		<testsuite name="testdata/fail/buf/buf.proto" tests="2" failures="2">
+		<testcase name="PACKAGE_DIRECTORY_MATCH:3:1" classname="testdata/fail/buf/buf.proto">
+		<failure message="testdata/fail/buf/buf.proto:3:1:Files with package &#34;other&#34; must be within a directory &#34;other&#34; relative to root but were in directory &#34;buf&#34;." type="PACKAGE_DIRECTORY_MATCH"></failure>
+		</testcase>
+		<testcase name="FIELD_LOWER_SNAKE_CASE:6:9" classname="testdata/fail/buf/buf.proto">
+		<failure message="testdata/fail/buf/buf.proto:6:9:Field name &#34;oneTwo&#34; should be lower_snake_case, such as &#34;one_two&#34;." type="FIELD_LOWER_SNAKE_CASE"></failure>
+		</testcase>
+		</testsuite>
+		</testsuites>`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--error-format",
+		"junit",
+	)
+}
+
+func TestSuccessJUnit(t *testing.T) {
+	testRun(
+		t,
+		0,
+		`<?xml version="1.0" encoding="UTF-8"?>
+		<testsuites tests="1" failures="0">
+		<testsuite name="&lt;input&gt;" tests="1" failures="0">
+		<testcase name="PASS" classname="&lt;input&gt;"></testcase>
+		</testsuite>
+		</testsuites>`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "success"),
+		"--error-format",
+		"junit",
+	)
+}
+
+func TestSuccessCheckBreakingJUnit(t *testing.T) {
+	testRun(
+		t,
+		0,
+		`<?xml version="1.0" encoding="UTF-8"?>
+		<testsuites tests="1" failures="0">
+		<testsuite name="&lt;input&gt;" tests="1" failures="0">
+		<testcase name="PASS" classname="&lt;input&gt;"></testcase>
+		</testsuite>
+		</testsuites>`,
+		"check",
+		"breaking",
+		"--input",
+		filepath.Join("testdata", "success"),
 		"--against-input",
-		"../../bufcheck/bufbreaking/testdata_previous/breaking_field_no_delete",
+		filepath.Join("testdata", "success"),
+		"--error-format",
+		"junit",
+	)
+}
+
+func TestFailUnknownErrorFormat(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"check",
+		"breaking",
+		"--input",
+		filepath.Join("testdata", "success"),
+		"--against-input",
+		filepath.Join("testdata", "success"),
+		"--error-format",
+		"config-ignore-yaml",
+	)
+}
+
+func TestFail14(t *testing.T) {
+	testRun(
+		t,
+		1,
+		`
+		testdata/fail/buf/buf.proto:3 [PACKAGE_DIRECTORY_MATCH] Files with package "other" must be within a directory "other" relative to root but were in directory "buf".
+		testdata/fail/buf/buf.proto:6 [FIELD_LOWER_SNAKE_CASE] Field name "oneTwo" should be lower_snake_case, such as "one_two".
+		`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--error-format",
+		"template",
+		"--error-format-template",
+		"{{.Path}}:{{.StartLine}} [{{.Type}}] {{.Message}}",
+	)
+}
+
+func TestFail15(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--error-format",
+		"template",
+	)
+}
+
+func TestFail16(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--error-format-template",
+		"{{.Path}}",
+	)
+}
+
+func TestFail17(t *testing.T) {
+	// jsonl is printed as the checkers complete, so the order is not deterministic
+	testRunUnordered(
+		t,
+		1,
+		`
+		{"path":"testdata/fail/buf/buf.proto","start_line":3,"start_column":1,"end_line":3,"end_column":15,"type":"PACKAGE_DIRECTORY_MATCH","message":"Files with package \"other\" must be within a directory \"other\" relative to root but were in directory \"buf\"."}
+		{"path":"testdata/fail/buf/buf.proto","start_line":6,"start_column":9,"end_line":6,"end_column":15,"type":"FIELD_LOWER_SNAKE_CASE","message":"Field name \"oneTwo\" should be lower_snake_case, such as \"one_two\"."}
+		`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--error-format",
+		"jsonl",
+	)
+}
+
+func TestFailJSONLCache(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--error-format",
+		"jsonl",
+		"--cache",
+	)
+}
+
+func TestFailCheckBreakingJSONL(t *testing.T) {
+	testRunUnordered(
+		t,
+		1,
+		`
+		{"path":"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto","start_line":5,"start_column":1,"end_line":8,"end_column":2,"type":"FIELD_NO_DELETE","message":"Previously present field \"3\" with name \"three\" on message \"Two\" was deleted.","categories":["FILE","PACKAGE"],"impact":"source","full_name":"a.Two"}
+		{"path":"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto","start_line":10,"start_column":1,"end_line":33,"end_column":2,"type":"FIELD_NO_DELETE","message":"Previously present field \"3\" with name \"three\" on message \"Three\" was deleted.","categories":["FILE","PACKAGE"],"impact":"source","full_name":"a.Three"}
+		{"path":"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto","start_line":12,"start_column":5,"end_line":15,"end_column":6,"type":"FIELD_NO_DELETE","message":"Previously present field \"3\" with name \"three\" on message \"Five\" was deleted.","categories":["FILE","PACKAGE"],"impact":"source","full_name":"a.Three.Four.Five"}
+		{"path":"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto","start_line":22,"start_column":3,"end_line":25,"end_column":4,"type":"FIELD_NO_DELETE","message":"Previously present field \"3\" with name \"three\" on message \"Seven\" was deleted.","categories":["FILE","PACKAGE"],"impact":"source","full_name":"a.Three.Seven"}
+		{"path":"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/2.proto","start_line":57,"start_column":1,"end_line":60,"end_column":2,"type":"FIELD_NO_DELETE","message":"Previously present field \"3\" with name \"three\" on message \"Nine\" was deleted.","categories":["FILE","PACKAGE"],"impact":"source","full_name":"a.Nine"}
+		`,
+		"check",
+		"breaking",
+		"--input",
+		"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete",
+		"--against-input",
+		"../../bufcheck/bufbreaking/testdata_previous/breaking_field_no_delete",
+		"--error-format",
+		"jsonl",
+	)
+}
+
+func TestFailCheckBreaking1(t *testing.T) {
+	testRun(
+		t,
+		1,
+		`
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:5:1:Previously present field "3" with name "three" on message "Two" was deleted.
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:10:1:Previously present field "3" with name "three" on message "Three" was deleted.
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:12:5:Previously present field "3" with name "three" on message "Five" was deleted.
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:22:3:Previously present field "3" with name "three" on message "Seven" was deleted.
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/2.proto:57:1:Previously present field "3" with name "three" on message "Nine" was deleted.
+		`,
+		"check",
+		"breaking",
+		"--input",
+		// can't bother right now to filepath.Join this
+		"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete",
+		"--against-input",
+		"../../bufcheck/bufbreaking/testdata_previous/breaking_field_no_delete",
+	)
+}
+
+func TestFailCheckBreaking2(t *testing.T) {
+	testRun(
+		t,
+		1,
+		`
+		FIELD_NO_DELETE 5:1
+		FIELD_NO_DELETE 10:1
+		FIELD_NO_DELETE 12:5
+		FIELD_NO_DELETE 22:3
+		FIELD_NO_DELETE 57:1
+		`,
+		"check",
+		"breaking",
+		"--input",
+		"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete",
+		"--against-input",
+		"../../bufcheck/bufbreaking/testdata_previous/breaking_field_no_delete",
+		"--error-format",
+		"template",
+		"--error-format-template",
+		"{{.Type}} {{.StartLine}}:{{.StartColumn}}",
+	)
+}
+
+func TestFailCheckBreakingInputAlias(t *testing.T) {
+	t.Parallel()
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	inputDirPath := filepath.Join(dirPath, "input")
+	previousDirPath := filepath.Join(dirPath, "previous")
+	for _, subDirPath := range []string{inputDirPath, previousDirPath} {
+		require.NoError(t, os.MkdirAll(subDirPath, 0755))
+	}
+	require.NoError(
+		t,
+		ioutil.WriteFile(
+			filepath.Join(inputDirPath, "buf.yaml"),
+			[]byte("inputs:\n  previous: "+previousDirPath+"\n  missing: "+filepath.Join(dirPath, "missing")+"\n"),
+			0644,
+		),
+	)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(inputDirPath, "a.proto"), []byte("syntax = \"proto3\";\n\nmessage Foo {}\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(previousDirPath, "a.proto"), []byte("syntax = \"proto3\";\n\nmessage Foo {\n  int64 one = 1;\n}\n"), 0644))
+
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		1,
+		filepath.Join(inputDirPath, "a.proto")+`:3:1:Previously present field "1" with name "one" on message "Foo" was deleted.`,
+		"check",
+		"breaking",
+		"--input",
+		inputDirPath,
+		"--against-input",
+		"alias:previous",
+	)
+	for _, againstInput := range []string{"alias:missing", "alias:unknown"} {
+		stderr := bytes.NewBuffer(nil)
+		exitCode := clicobra.Run(
+			newRootCommand("test"),
+			"test",
+			clienv.NewEnv(
+				[]string{"check", "breaking", "--input", inputDirPath, "--against-input", againstInput},
+				nil,
+				nil,
+				stderr,
+				nil,
+			),
+		)
+		assert.Equal(t, 1, exitCode, againstInput)
+		if againstInput == "alias:unknown" {
+			assert.Contains(t, stderr.String(), `--against-input: unknown input alias "unknown"`)
+		}
+	}
+}
+
+func TestFailCheckBreakingMultipleAgainstInputs(t *testing.T) {
+	testRun(
+		t,
+		1,
+		`
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:5:1:Against ../../bufcheck/bufbreaking/testdata_previous/breaking_field_no_delete: Previously present field "3" with name "three" on message "Two" was deleted.
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:10:1:Against ../../bufcheck/bufbreaking/testdata_previous/breaking_field_no_delete: Previously present field "3" with name "three" on message "Three" was deleted.
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:12:5:Against ../../bufcheck/bufbreaking/testdata_previous/breaking_field_no_delete: Previously present field "3" with name "three" on message "Five" was deleted.
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:22:3:Against ../../bufcheck/bufbreaking/testdata_previous/breaking_field_no_delete: Previously present field "3" with name "three" on message "Seven" was deleted.
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/2.proto:57:1:Against ../../bufcheck/bufbreaking/testdata_previous/breaking_field_no_delete: Previously present field "3" with name "three" on message "Nine" was deleted.
+		`,
+		"check",
+		"breaking",
+		"--input",
+		"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete",
+		"--against-input",
+		"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete",
+		"--against-input",
+		"../../bufcheck/bufbreaking/testdata_previous/breaking_field_no_delete",
+	)
+}
+
+func TestFailCheckBreakingLimitToInputFilesRename(t *testing.T) {
+	testRun(
+		t,
+		1,
+		filepath.FromSlash(`testdata/rename/current/a/v1/foo_renamed.proto:5:1:Previously present field "2" with name "two" on message "Foo" was deleted.`),
+		"check",
+		"breaking",
+		"--input",
+		filepath.Join("testdata", "rename", "current"),
+		"--against-input",
+		filepath.Join("testdata", "rename", "previous"),
+		"--limit-to-input-files",
+	)
+}
+
+func TestFailCheckBreakingJSON(t *testing.T) {
+	testRun(
+		t,
+		1,
+		`
+		{"path":"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto","start_line":5,"start_column":1,"end_line":8,"end_column":2,"type":"FIELD_NO_DELETE","message":"Previously present field \"3\" with name \"three\" on message \"Two\" was deleted.","categories":["FILE","PACKAGE"],"impact":"source","full_name":"a.Two"}
+		{"path":"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto","start_line":10,"start_column":1,"end_line":33,"end_column":2,"type":"FIELD_NO_DELETE","message":"Previously present field \"3\" with name \"three\" on message \"Three\" was deleted.","categories":["FILE","PACKAGE"],"impact":"source","full_name":"a.Three"}
+		{"path":"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto","start_line":12,"start_column":5,"end_line":15,"end_column":6,"type":"FIELD_NO_DELETE","message":"Previously present field \"3\" with name \"three\" on message \"Five\" was deleted.","categories":["FILE","PACKAGE"],"impact":"source","full_name":"a.Three.Four.Five"}
+		{"path":"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto","start_line":22,"start_column":3,"end_line":25,"end_column":4,"type":"FIELD_NO_DELETE","message":"Previously present field \"3\" with name \"three\" on message \"Seven\" was deleted.","categories":["FILE","PACKAGE"],"impact":"source","full_name":"a.Three.Seven"}
+		{"path":"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/2.proto","start_line":57,"start_column":1,"end_line":60,"end_column":2,"type":"FIELD_NO_DELETE","message":"Previously present field \"3\" with name \"three\" on message \"Nine\" was deleted.","categories":["FILE","PACKAGE"],"impact":"source","full_name":"a.Nine"}
+		`,
+		"check",
+		"breaking",
+		"--input",
+		"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete",
+		"--against-input",
+		"../../bufcheck/bufbreaking/testdata_previous/breaking_field_no_delete",
+		"--error-format",
+		"json",
+	)
+}
+
+func TestFailCheckBreakingMultipleAgainstInputsReport(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"check",
+		"breaking",
+		"--input",
+		"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete",
+		"--against-input",
+		"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete",
+		"--against-input",
+		"../../bufcheck/bufbreaking/testdata_previous/breaking_field_no_delete",
+		"--report-output",
+		"report.json",
+	)
+}
+
+func TestFailCheckBreakingSummary1(t *testing.T) {
+	testRun(
+		t,
+		1,
+		`
+		ID                         COUNT
+		PACKAGE_ENUM_NO_DELETE     5
+		PACKAGE_MESSAGE_NO_DELETE  4
+		PACKAGE_SERVICE_NO_DELETE  2
+		PACKAGE_NO_DELETE          1
+		TOTAL                      12
+		`,
+		"check",
+		"breaking",
+		"--input",
+		"../../bufcheck/bufbreaking/testdata/breaking_package_no_delete",
+		"--against-input",
+		"../../bufcheck/bufbreaking/testdata_previous/breaking_package_no_delete",
+		"--summary",
+	)
+}
+
+func TestFailCheckBreakingSummary2(t *testing.T) {
+	testRun(
+		t,
+		1,
+		`
+		{"id":"PACKAGE_ENUM_NO_DELETE","count":5}
+		{"id":"PACKAGE_MESSAGE_NO_DELETE","count":4}
+		{"id":"PACKAGE_SERVICE_NO_DELETE","count":2}
+		{"id":"PACKAGE_NO_DELETE","count":1}
+		`,
+		"check",
+		"breaking",
+		"--input",
+		"../../bufcheck/bufbreaking/testdata/breaking_package_no_delete",
+		"--against-input",
+		"../../bufcheck/bufbreaking/testdata_previous/breaking_package_no_delete",
+		"--summary",
+		"--error-format",
+		"json",
+	)
+}
+
+func TestFailCheckBreakingExitCodeOnly(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"check",
+		"breaking",
+		"--input",
+		"../../bufcheck/bufbreaking/testdata/breaking_package_no_delete",
+		"--against-input",
+		"../../bufcheck/bufbreaking/testdata_previous/breaking_package_no_delete",
+		"--exit-code-only",
+	)
+}
+
+func TestCheckBreakingExitCodeOnly(t *testing.T) {
+	testRun(
+		t,
+		0,
+		``,
+		"check",
+		"breaking",
+		"--input",
+		"../../bufcheck/bufbreaking/testdata/breaking_package_no_delete",
+		"--against-input",
+		"../../bufcheck/bufbreaking/testdata/breaking_package_no_delete",
+		"--exit-code-only",
+	)
+}
+
+func TestFailCheckBreakingSummaryExitCodeOnly(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"check",
+		"breaking",
+		"--input",
+		"../../bufcheck/bufbreaking/testdata/breaking_package_no_delete",
+		"--against-input",
+		"../../bufcheck/bufbreaking/testdata_previous/breaking_package_no_delete",
+		"--summary",
+		"--exit-code-only",
+	)
+}
+
+func TestCheckLintRuleTiming(t *testing.T) {
+	t.Parallel()
+	ids := testRunRuleTiming(
+		t,
+		0,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "success"),
+		"--rule-timing",
+	)
+	assert.Contains(t, ids, "FIELD_LOWER_SNAKE_CASE")
+	assert.Contains(t, ids, "PACKAGE_DIRECTORY_MATCH")
+}
+
+func TestCheckBreakingAllowIfEnv(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		envValue         string
+		args             []string
+		expectedExitCode int
+		expectedStdout   string
+	}{
+		{
+			envValue:         "",
+			args:             []string{"--summary", "--error-format", "json"},
+			expectedExitCode: 1,
+			expectedStdout: `
+			{"id":"PACKAGE_ENUM_NO_DELETE","count":5}
+			{"id":"PACKAGE_MESSAGE_NO_DELETE","count":4}
+			{"id":"PACKAGE_SERVICE_NO_DELETE","count":2}
+			{"id":"PACKAGE_NO_DELETE","count":1}
+			`,
+		},
+		{
+			envValue:         "1",
+			args:             []string{"--summary", "--error-format", "json"},
+			expectedExitCode: 0,
+			expectedStdout: `
+			{"id":"PACKAGE_ENUM_NO_DELETE","count":5,"allowed_by_env":"ALLOW_BREAKING"}
+			{"id":"PACKAGE_MESSAGE_NO_DELETE","count":4,"allowed_by_env":"ALLOW_BREAKING"}
+			{"id":"PACKAGE_SERVICE_NO_DELETE","count":2,"allowed_by_env":"ALLOW_BREAKING"}
+			{"id":"PACKAGE_NO_DELETE","count":1,"allowed_by_env":"ALLOW_BREAKING"}
+			`,
+		},
+		{
+			envValue:         "1",
+			args:             []string{"--exit-code-only"},
+			expectedExitCode: 0,
+			expectedStdout:   ``,
+		},
+	} {
+		stdout := bytes.NewBuffer(nil)
+		stderr := bytes.NewBuffer(nil)
+		exitCode := clicobra.Run(
+			newRootCommand("test"),
+			"test",
+			clienv.NewEnv(
+				append(
+					[]string{
+						"check",
+						"breaking",
+						"--input",
+						"../../bufcheck/bufbreaking/testdata/breaking_package_no_delete",
+						"--against-input",
+						"../../bufcheck/bufbreaking/testdata_previous/breaking_package_no_delete",
+						"--allow-breaking-if-env",
+						"ALLOW_BREAKING",
+					},
+					testCase.args...,
+				),
+				nil,
+				stdout,
+				stderr,
+				map[string]string{
+					"ALLOW_BREAKING": testCase.envValue,
+				},
+			),
+		)
+		assert.Equal(t, testCase.expectedExitCode, exitCode, utilstring.TrimLines(stderr.String()))
+		assert.Equal(t, utilstring.TrimLines(testCase.expectedStdout), utilstring.TrimLines(stdout.String()))
+		if testCase.expectedExitCode == 0 {
+			assert.Contains(t, stderr.String(), "ALLOW_BREAKING")
+		}
+	}
+}
+
+func TestFailCheckBreakingRuleTiming(t *testing.T) {
+	t.Parallel()
+	ids := testRunRuleTiming(
+		t,
+		1,
+		"check",
+		"breaking",
+		"--input",
+		"../../bufcheck/bufbreaking/testdata/breaking_package_no_delete",
+		"--against-input",
+		"../../bufcheck/bufbreaking/testdata_previous/breaking_package_no_delete",
+		"--exit-code-only",
+		"--rule-timing",
+	)
+	assert.Contains(t, ids, "PACKAGE_NO_DELETE")
+	assert.Contains(t, ids, "FIELD_SAME_TYPE")
+}
+
+func TestFailCheckBreakingReport1(t *testing.T) {
+	t.Parallel()
+	reportDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(reportDirPath)) }()
+	reportFilePath := filepath.Join(reportDirPath, "report.json")
+	expectedStdout := `
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:5:1:Previously present field "3" with name "three" on message "Two" was deleted.
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:10:1:Previously present field "3" with name "three" on message "Three" was deleted.
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:12:5:Previously present field "3" with name "three" on message "Five" was deleted.
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:22:3:Previously present field "3" with name "three" on message "Seven" was deleted.
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/2.proto:57:1:Previously present field "3" with name "three" on message "Nine" was deleted.
+		`
+	// the first run writes the report, the second run uses the report
+	for i := 0; i < 2; i++ {
+		stdout := bytes.NewBuffer(nil)
+		stderr := bytes.NewBuffer(nil)
+		exitCode := clicobra.Run(
+			newRootCommand("test"),
+			"test",
+			clienv.NewEnv(
+				[]string{
+					"check",
+					"breaking",
+					"--input",
+					"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete",
+					"--against-input",
+					"../../bufcheck/bufbreaking/testdata_previous/breaking_field_no_delete",
+					"--against-report",
+					reportFilePath,
+					"--report-output",
+					reportFilePath,
+				},
+				nil,
+				stdout,
+				stderr,
+				nil,
+			),
+		)
+		assert.Equal(t, 1, exitCode, utilstring.TrimLines(stderr.String()))
+		assert.Equal(t, utilstring.TrimLines(expectedStdout), utilstring.TrimLines(stdout.String()), utilstring.TrimLines(stderr.String()))
+	}
+}
+
+func TestFailCheckBreakingExemptions1(t *testing.T) {
+	t.Parallel()
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	exemptionsFilePath := filepath.Join(dirPath, "exemptions.yaml")
+	expectedStdout := `
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:5:1:Previously present field "3" with name "three" on message "Two" was deleted.
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:10:1:Previously present field "3" with name "three" on message "Three" was deleted.
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:12:5:Previously present field "3" with name "three" on message "Five" was deleted.
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:22:3:Previously present field "3" with name "three" on message "Seven" was deleted.
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/2.proto:57:1:Previously present field "3" with name "three" on message "Nine" was deleted.
+		`
+	unexpired := time.Now().UTC().Format("2006-01-02")
+	expired := time.Now().UTC().AddDate(0, 0, -1).Format("2006-01-02")
+	exemptionsPrefix := fmt.Sprintf(`exemptions:
+  - id: FIELD_NO_DELETE
+    name: a.Two
+    expires: %s
+  - id: FIELD_NO_DELETE
+    name: a.Three
+    expires: %s
+  - id: FIELD_NO_DELETE
+    name: a.Three.Four.Five
+    expires: %s
+  - id: FIELD_NO_DELETE
+    name: a.Three.Seven
+    expires: %s
+    reason: migrating
+`, unexpired, unexpired, unexpired, unexpired)
+	for _, testCase := range []struct {
+		exemptions       string
+		expectedExitCode int
+	}{
+		{
+			exemptions:       exemptionsPrefix,
+			expectedExitCode: 1,
+		},
+		{
+			exemptions:       exemptionsPrefix + fmt.Sprintf("  - id: FIELD_NO_DELETE\n    name: a.Nine\n    expires: %s\n", unexpired),
+			expectedExitCode: 0,
+		},
+		{
+			exemptions:       exemptionsPrefix + fmt.Sprintf("  - id: FIELD_NO_DELETE\n    name: a.Nine\n    expires: %s\n", expired),
+			expectedExitCode: 1,
+		},
+		{
+			exemptions:       exemptionsPrefix + fmt.Sprintf("  - id: FIELD_SAME_TYPE\n    name: a.Nine\n    expires: %s\n", unexpired),
+			expectedExitCode: 1,
+		},
+	} {
+		require.NoError(t, ioutil.WriteFile(exemptionsFilePath, []byte(testCase.exemptions), 0644))
+		testRunCmdNoParallel(
+			t,
+			newRootCommand("test"),
+			testCase.expectedExitCode,
+			expectedStdout,
+			"check",
+			"breaking",
+			"--input",
+			"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete",
+			"--against-input",
+			"../../bufcheck/bufbreaking/testdata_previous/breaking_field_no_delete",
+			"--exemptions",
+			exemptionsFilePath,
+		)
+	}
+}
+
+func TestFailCheckBreakingExemptions2(t *testing.T) {
+	t.Parallel()
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	for _, exemptions := range []string{
+		`exemptions: [{"id": "FIELD_NO_DELETE", "name": "a.Two"}]`,
+		`exemptions: [{"id": "FIELD_NO_DELETE", "name": "a.Two", "expires": "tomorrow"}]`,
+		`exemptions: [{"id": "NOT_A_CHECKER", "name": "a.Two", "expires": "2020-01-01"}]`,
+		`exemptions: [{"id": "FIELD_NO_DELETE", "expires": "2020-01-01"}]`,
+		`exemptions: [{"id": "FIELD_NO_DELETE", "name": "a.Two", "expires": "2020-01-01", "unknown": true}]`,
+	} {
+		exemptionsFilePath := filepath.Join(dirPath, "exemptions.yaml")
+		require.NoError(t, ioutil.WriteFile(exemptionsFilePath, []byte(exemptions), 0644))
+		testRunCmdNoParallel(
+			t,
+			newRootCommand("test"),
+			1,
+			``,
+			"check",
+			"breaking",
+			"--input",
+			"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete",
+			"--against-input",
+			"../../bufcheck/bufbreaking/testdata_previous/breaking_field_no_delete",
+			"--exemptions",
+			exemptionsFilePath,
+		)
+	}
+}
+
+func TestLsFilesAllowedHosts(t *testing.T) {
+	t.Parallel()
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	imageFilePath := filepath.Join(dirPath, "image.bin")
+	testRunCmdNoParallel(t, newRootCommand("test"), 0, ``, "image", "build", "-o", imageFilePath, "--source", filepath.Join("testdata", "success"))
+	imageData, err := ioutil.ReadFile(imageFilePath)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				_, _ = responseWriter.Write(imageData)
+			},
+		),
+	)
+	defer server.Close()
+	// redirects to the same server with a different host
+	redirectServer := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				http.Redirect(responseWriter, request, strings.Replace(server.URL, "127.0.0.1", "localhost", 1)+"/image.bin", http.StatusFound)
+			},
+		),
+	)
+	defer redirectServer.Close()
+
+	for _, testCase := range []struct {
+		source           string
+		allowedHosts     string
+		expectedExitCode int
+	}{
+		{source: server.URL + "/image.bin", allowedHosts: "", expectedExitCode: 0},
+		{source: server.URL + "/image.bin", allowedHosts: "example.com, 127.0.0.1", expectedExitCode: 0},
+		{source: server.URL + "/image.bin", allowedHosts: "example.com,*.example.com", expectedExitCode: 1},
+		{source: redirectServer.URL + "/image.bin", allowedHosts: "127.0.0.1", expectedExitCode: 1},
+		{source: redirectServer.URL + "/image.bin", allowedHosts: "127.0.0.1,localhost", expectedExitCode: 0},
+		{source: "https://github.com/bufbuild/buf.git#branch=master", allowedHosts: "example.com", expectedExitCode: 1},
+		{source: "git@github.com:bufbuild/buf.git#branch=master", allowedHosts: "example.com", expectedExitCode: 1},
+		{source: "github.com:bufbuild/buf.git#branch=master", allowedHosts: "example.com", expectedExitCode: 1},
+		{source: "evil.example:repo.git#branch=master", allowedHosts: "example.com,*.example.com", expectedExitCode: 1},
+		{source: "[::1]:repo.git#branch=master", allowedHosts: "example.com", expectedExitCode: 1},
+		{source: "gs://bucket/image.bin", allowedHosts: "example.com", expectedExitCode: 1},
+		{source: "s3://bucket/image.bin", allowedHosts: "example.com", expectedExitCode: 1},
+		{source: "s3://bucket/dir#format=dir", allowedHosts: "example.com,*.example.com", expectedExitCode: 1},
+	} {
+		stdout := bytes.NewBuffer(nil)
+		stderr := bytes.NewBuffer(nil)
+		exitCode := clicobra.Run(
+			newRootCommand("test"),
+			"test",
+			clienv.NewEnv(
+				[]string{"ls-files", "--input", testCase.source},
+				nil,
+				stdout,
+				stderr,
+				map[string]string{
+					"BUF_INPUT_ALLOWED_HOSTS": testCase.allowedHosts,
+				},
+			),
+		)
+		assert.Equal(t, testCase.expectedExitCode, exitCode, testCase.source+": "+utilstring.TrimLines(stderr.String()))
+		if testCase.expectedExitCode != 0 {
+			assert.Contains(t, stderr.String(), "policy error", testCase.source)
+		}
+	}
+}
+
+func TestImageBuildArchivePolicyError(t *testing.T) {
+	t.Parallel()
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	for _, testCase := range []struct {
+		tarHeader    *tar.Header
+		expectedHint bool
+	}{
+		{
+			// --allow-symlinks never allows bad entry names, so it is not suggested
+			tarHeader: &tar.Header{Typeflag: tar.TypeReg, Name: "../x.proto", Mode: 0644},
+		},
+		{
+			tarHeader: &tar.Header{Typeflag: tar.TypeReg, Name: "/x.proto", Mode: 0644},
+		},
+		{
+			tarHeader:    &tar.Header{Typeflag: tar.TypeSymlink, Name: "x.proto", Linkname: "../../etc/passwd", Mode: 0644},
+			expectedHint: true,
+		},
+	} {
+		buffer := bytes.NewBuffer(nil)
+		tarWriter := tar.NewWriter(buffer)
+		require.NoError(t, tarWriter.WriteHeader(testCase.tarHeader))
+		require.NoError(t, tarWriter.Close())
+		tarFilePath := filepath.Join(dirPath, "input.tar")
+		require.NoError(t, ioutil.WriteFile(tarFilePath, buffer.Bytes(), 0644))
+		stdout := bytes.NewBuffer(nil)
+		stderr := bytes.NewBuffer(nil)
+		exitCode := clicobra.Run(
+			newRootCommand("test"),
+			"test",
+			clienv.NewEnv(
+				[]string{"image", "build", "-o", clios.DevNull, "--source", tarFilePath},
+				nil,
+				stdout,
+				stderr,
+				nil,
+			),
+		)
+		assert.Equal(t, 1, exitCode, testCase.tarHeader.Name)
+		assert.Contains(t, stderr.String(), "policy error", testCase.tarHeader.Name)
+		if testCase.expectedHint {
+			assert.Contains(t, stderr.String(), "--allow-symlinks", testCase.tarHeader.Name)
+		} else {
+			assert.NotContains(t, stderr.String(), "--allow-symlinks", testCase.tarHeader.Name)
+		}
+	}
+}
+
+func TestImageBuildFollowSymlinks(t *testing.T) {
+	t.Parallel()
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	inputDirPath := filepath.Join(dirPath, "input")
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDirPath, "a"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dirPath, "b"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(inputDirPath, "a", "a.proto"), []byte(`syntax = "proto3";
+
+package a;
+`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "b", "b.proto"), []byte(`syntax = "proto3";
+
+package b;
+`), 0644))
+	if err := os.Symlink(filepath.Join("..", "b"), filepath.Join(inputDirPath, "b")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	require.NoError(t, os.Symlink("..", filepath.Join(inputDirPath, "a", "parent")))
+	imageFilePath := filepath.Join(dirPath, "image.bin")
+
+	testRunCmdNoParallel(t, newRootCommand("test"), 0, ``, "image", "build", "-o", imageFilePath, "--source", inputDirPath)
+	testRunCmdNoParallel(t, newRootCommand("test"), 0, `a/a.proto`, "ls-files", "--input", imageFilePath)
+	testRunCmdNoParallel(t, newRootCommand("test"), 0, ``, "image", "build", "-o", imageFilePath, "--source", inputDirPath, "--follow-symlinks")
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		0,
+		`
+		a/a.proto
+		b/b.proto
+		`,
+		"ls-files",
+		"--input",
+		imageFilePath,
+	)
+}
+
+func TestLsFilesStdinTarGzDir(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		pathToContent  map[string]string
+		options        string
+		expectedStdout string
+	}{
+		{
+			// an archive of a directory with a config file is the same as the directory
+			pathToContent: map[string]string{
+				"foo/buf.yaml":        "build:\n  roots:\n    - proto\n",
+				"foo/proto/a/a.proto": `syntax = "proto3"; package a;`,
+				"foo/other/b/b.proto": `syntax = "proto3"; package b;`,
+			},
+			expectedStdout: "proto/a/a.proto",
+		},
+		{
+			pathToContent: map[string]string{
+				"buf.yaml":        "build:\n  roots:\n    - proto\n",
+				"proto/a/a.proto": `syntax = "proto3"; package a;`,
+				"other/b/b.proto": `syntax = "proto3"; package b;`,
+			},
+			expectedStdout: "proto/a/a.proto",
+		},
+		{
+			// without a config file, the top-level directory is kept
+			pathToContent: map[string]string{
+				"foo/proto/a/a.proto": `syntax = "proto3"; package a;`,
+			},
+			expectedStdout: "foo/proto/a/a.proto",
+		},
+		{
+			// more than one top-level directory
+			pathToContent: map[string]string{
+				"foo/buf.yaml":        "build:\n  roots:\n    - proto\n",
+				"foo/proto/a/a.proto": `syntax = "proto3"; package a;`,
+				"bar/b/b.proto":       `syntax = "proto3"; package b;`,
+			},
+			expectedStdout: "bar/b/b.proto\nfoo/proto/a/a.proto",
+		},
+		{
+			// strip_components is used as given
+			pathToContent: map[string]string{
+				"foo/buf.yaml":        "build:\n  roots:\n    - proto\n",
+				"foo/proto/a/a.proto": `syntax = "proto3"; package a;`,
+			},
+			options:        ",strip_components=1",
+			expectedStdout: "proto/a/a.proto",
+		},
+	} {
+		stdout := bytes.NewBuffer(nil)
+		stderr := bytes.NewBuffer(nil)
+		exitCode := clicobra.Run(
+			newRootCommand("test"),
+			"test",
+			clienv.NewEnv(
+				[]string{"ls-files", "--input", "-#format=targz" + testCase.options},
+				bytes.NewReader(testTarGz(t, testCase.pathToContent)),
+				stdout,
+				stderr,
+				nil,
+			),
+		)
+		require.Equal(t, 0, exitCode, utilstring.TrimLines(stderr.String()))
+		assert.Equal(t, testCase.expectedStdout, utilstring.TrimLines(stdout.String()))
+	}
+}
+
+func TestCheckLintStdinTarGzDir(t *testing.T) {
+	t.Parallel()
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	exitCode := clicobra.Run(
+		newRootCommand("test"),
+		"test",
+		clienv.NewEnv(
+			[]string{"check", "lint", "--input", "-#format=targz"},
+			bytes.NewReader(
+				testTarGz(
+					t,
+					map[string]string{
+						"foo/buf.yaml":  "lint:\n  use:\n    - FIELD_LOWER_SNAKE_CASE\n",
+						"foo/a/a.proto": "syntax = \"proto3\";\n\npackage a;\n\nmessage foo {\n  int64 oneTwo = 1;\n}\n",
+					},
+				),
+			),
+			stdout,
+			stderr,
+			nil,
+		),
+	)
+	assert.Equal(t, 1, exitCode, utilstring.TrimLines(stderr.String()))
+	assert.Equal(
+		t,
+		`a/a.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".`,
+		utilstring.TrimLines(stdout.String()),
+	)
+}
+
+func TestCheckBreakingAgainstCache(t *testing.T) {
+	t.Parallel()
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	imageFilePath := filepath.Join(dirPath, "image.bin")
+	testRunCmdNoParallel(t, newRootCommand("test"), 0, ``, "image", "build", "-o", imageFilePath, "--source", filepath.Join("testdata", "success"))
+	imageData, err := ioutil.ReadFile(imageFilePath)
+	require.NoError(t, err)
+
+	var numOK int32
+	var numNotModified int32
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				if request.Header.Get("If-None-Match") == `"v1"` {
+					atomic.AddInt32(&numNotModified, 1)
+					responseWriter.WriteHeader(http.StatusNotModified)
+					return
+				}
+				atomic.AddInt32(&numOK, 1)
+				responseWriter.Header().Set("ETag", `"v1"`)
+				_, _ = responseWriter.Write(imageData)
+			},
+		),
+	)
+	defer server.Close()
+
+	cacheDirPath := filepath.Join(dirPath, "cache")
+	args := []string{
+		"check",
+		"breaking",
+		"--input",
+		filepath.Join("testdata", "success"),
+		"--against-input",
+		server.URL + "/image.bin",
+		"--against-cache-dir",
+		cacheDirPath,
+	}
+	// the first run downloads the image
+	testRunCmdNoParallel(t, newRootCommand("test"), 0, ``, args...)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&numOK))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&numNotModified))
+	// the second run revalidates the cached image
+	testRunCmdNoParallel(t, newRootCommand("test"), 0, ``, args...)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&numOK))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&numNotModified))
+	// within the ttl, no request is sent
+	testRunCmdNoParallel(t, newRootCommand("test"), 0, ``, append(args, "--against-cache-ttl", "1h")...)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&numOK))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&numNotModified))
+	testRunCmdNoParallel(t, newRootCommand("test"), 1, ``, append(args, "--against-cache-ttl", "-1h")...)
+}
+
+func TestDependencyImage1(t *testing.T) {
+	t.Parallel()
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	imageFilePath := filepath.Join(dirPath, "image.bin")
+	testRunCmdNoParallel(t, newRootCommand("test"), 0, ``, "image", "build", "-o", imageFilePath, "--source", filepath.Join("testdata", "success"))
+	// buf/buf.proto cannot be resolved without the dependency image
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		1,
+		`testdata/dep_image/dep/dep.proto:5:8:buf/buf.proto: does not exist`,
+		"check", "lint",
+		"--input",
+		filepath.Join("testdata", "dep_image"),
+	)
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		0,
+		``,
+		"check", "lint",
+		"--input",
+		filepath.Join("testdata", "dep_image"),
+		"--dep-image",
+		imageFilePath,
+	)
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		0,
+		``,
+		"image", "build", "-o", clios.DevNull,
+		"--source",
+		filepath.Join("testdata", "dep_image"),
+		"--dep-image",
+		imageFilePath,
+	)
+}
+
+func TestBuiltinDeps1(t *testing.T) {
+	t.Parallel()
+	cacheDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(cacheDirPath)) }()
+	// pre-populate the cache so that we do not hit the network
+	depDirPath := filepath.Join(cacheDirPath, "buf", "deps", "googleapis", "37c923effe8b002884466074f84bc4e78e6ade62", "google", "api")
+	require.NoError(t, os.MkdirAll(depDirPath, 0755))
+	require.NoError(
+		t,
+		ioutil.WriteFile(
+			filepath.Join(depDirPath, "http.proto"),
+			[]byte(`syntax = "proto3"; package google.api; message HttpRule { string get = 2; }`),
+			0644,
+		),
+	)
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	exitCode := clicobra.Run(
+		newRootCommand("test"),
+		"test",
+		clienv.NewEnv(
+			[]string{
+				"check",
+				"lint",
+				"--input",
+				filepath.Join("testdata", "deps"),
+			},
+			nil,
+			stdout,
+			stderr,
+			map[string]string{
+				"XDG_CACHE_HOME": cacheDirPath,
+			},
+		),
+	)
+	assert.Equal(t, 0, exitCode, utilstring.TrimLines(stderr.String()))
+	assert.Equal(t, "", utilstring.TrimLines(stdout.String()), utilstring.TrimLines(stderr.String()))
+}
+
+func TestCheckLintChangedSince1(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "buf.yaml"), []byte("lint:\n  use:\n  - BASIC\n"), 0644))
+	for _, name := range []string{"a", "b"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dirPath, name), 0755))
+		require.NoError(
+			t,
+			ioutil.WriteFile(
+				filepath.Join(dirPath, name, name+".proto"),
+				[]byte(fmt.Sprintf("syntax = \"proto3\";\n\npackage %s;\n\nmessage Foo {\n  int64 oneTwo = 1;\n}\n", name)),
+				0644,
+			),
+		)
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dirPath
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	// only b/b.proto changes after the commit
+	require.NoError(
+		t,
+		ioutil.WriteFile(
+			filepath.Join(dirPath, "b", "b.proto"),
+			[]byte("syntax = \"proto3\";\n\npackage b;\n\nmessage Foo {\n  int64 oneTwo = 1;\n  int64 three = 2;\n}\n"),
+			0644,
+		),
+	)
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		1,
+		filepath.Join(dirPath, "b", "b.proto")+`:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".`,
+		"check",
+		"lint",
+		"--input",
+		dirPath,
+		"--changed-since",
+		"HEAD",
+	)
+}
+
+func TestImageBuildRequirePinned(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "a.proto"), []byte("syntax = \"proto3\";\n\npackage a;\n"), 0644))
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dirPath
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = dirPath
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	commit := strings.TrimSpace(string(output))
+	gitPath := filepath.Join(dirPath, ".git")
+	for _, testCase := range []struct {
+		ref              string
+		requirePinned    bool
+		expectedExitCode int
+	}{
+		{ref: "ref=" + commit, requirePinned: true, expectedExitCode: 0},
+		{ref: "ref=HEAD", requirePinned: false, expectedExitCode: 0},
+		{ref: "ref=HEAD", requirePinned: true, expectedExitCode: 1},
+		{ref: "tag=v1.0.0", requirePinned: true, expectedExitCode: 1},
+		// the commit must exist
+		{ref: "ref=0123456789abcdef0123456789abcdef01234567", requirePinned: true, expectedExitCode: 1},
+	} {
+		args := []string{"image", "build", "-o", clios.DevNull, "--source", gitPath + "#" + testCase.ref}
+		if testCase.requirePinned {
+			args = append(args, "--require-pinned")
+		}
+		stdout := bytes.NewBuffer(nil)
+		stderr := bytes.NewBuffer(nil)
+		exitCode := clicobra.Run(
+			newRootCommand("test"),
+			"test",
+			clienv.NewEnv(
+				args,
+				nil,
+				stdout,
+				stderr,
+				map[string]string{
+					"HOME": dirPath,
+				},
+			),
+		)
+		assert.Equal(t, testCase.expectedExitCode, exitCode, testCase.ref, utilstring.TrimLines(stderr.String()))
+	}
+}
+
+func TestCheckLintGitPureGo(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "buf.yaml"), []byte("lint:\n  use:\n    - FIELD_LOWER_SNAKE_CASE\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "a.proto"), []byte("syntax = \"proto3\";\n\npackage a;\n"), 0644))
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial"},
+		{"tag", "v1.0.0"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dirPath
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	// the second commit is not referenced by any branch or tag, so it can only
+	// be read directly from the repository and not fetched with the system git
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "b.proto"), []byte("syntax = \"proto3\";\n\npackage a;\n\nmessage Foo {\n  int64 oneTwo = 1;\n}\n"), 0644))
+	testGitOutput := func(args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dirPath
+		output, err := cmd.Output()
+		require.NoError(t, err)
+		return strings.TrimSpace(string(output))
+	}
+	testGitOutput("add", ".")
+	tree := testGitOutput("write-tree")
+	commit := testGitOutput("-c", "user.name=test", "-c", "user.email=test@example.com", "commit-tree", "-p", "HEAD", "-m", "dangling", tree)
+	gitURL := "file://" + filepath.ToSlash(filepath.Join(dirPath, ".git"))
+	for _, testCase := range []struct {
+		ref              string
+		gitPureGo        bool
+		expectedExitCode int
+		expectedStdout   string
+	}{
+		{ref: "tag=v1.0.0", gitPureGo: true, expectedExitCode: 0},
+		{
+			ref:              "ref=" + commit,
+			gitPureGo:        true,
+			expectedExitCode: 1,
+			expectedStdout:   `b.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".`,
+		},
+		// the system git is used without the flag, as BUF_INPUT_GIT_PURE_GO is false
+		{ref: "ref=" + commit, gitPureGo: false, expectedExitCode: 1},
+	} {
+		args := []string{"check", "lint", "--input", gitURL + "#" + testCase.ref}
+		if testCase.gitPureGo {
+			args = append(args, "--git-pure-go")
+		}
+		stdout := bytes.NewBuffer(nil)
+		stderr := bytes.NewBuffer(nil)
+		exitCode := clicobra.Run(
+			newRootCommand("test"),
+			"test",
+			clienv.NewEnv(
+				args,
+				nil,
+				stdout,
+				stderr,
+				map[string]string{
+					"HOME":                  dirPath,
+					"BUF_INPUT_GIT_PURE_GO": "false",
+				},
+			),
+		)
+		assert.Equal(t, testCase.expectedExitCode, exitCode, testCase.ref, utilstring.TrimLines(stderr.String()))
+		assert.Equal(t, testCase.expectedStdout, utilstring.TrimLines(stdout.String()), testCase.ref)
+		if !testCase.gitPureGo {
+			assert.Contains(t, stderr.String(), "could not clone", testCase.ref)
+		}
+	}
+}
+
+func TestCheckLintAnnotateAuthors1(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "buf.yaml"), []byte("lint:\n  use:\n    - FIELD_LOWER_SNAKE_CASE\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "a.proto"), []byte("syntax = \"proto3\";\n\npackage a;\n\nmessage Foo {\n  int64 oneTwo = 1;\n}\n"), 0644))
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial", "--date", "2020-01-02T03:04:05Z"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dirPath
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = dirPath
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	commit := strings.TrimSpace(string(output))
+	// the second field is not committed, so has no author
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "a.proto"), []byte("syntax = \"proto3\";\n\npackage a;\n\nmessage Foo {\n  int64 oneTwo = 1;\n  int64 threeFour = 2;\n}\n"), 0644))
+	filePath := filepath.Join(dirPath, "a.proto")
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		1,
+		fmt.Sprintf(
+			`{"path":%q,"start_line":6,"start_column":9,"end_line":6,"end_column":15,"type":"FIELD_LOWER_SNAKE_CASE","message":"Field name \"oneTwo\" should be lower_snake_case, such as \"one_two\".","author":"test","author_mail":"test@example.com","author_time":"2020-01-02T03:04:05Z","commit":%q}
+			{"path":%q,"start_line":7,"start_column":9,"end_line":7,"end_column":18,"type":"FIELD_LOWER_SNAKE_CASE","message":"Field name \"threeFour\" should be lower_snake_case, such as \"three_four\"."}`,
+			filePath,
+			commit,
+			filePath,
+		),
+		"check",
+		"lint",
+		"--input",
+		dirPath,
+		"--error-format",
+		"json",
+		"--annotate-authors",
+	)
+}
+
+func TestCheckLintAnnotateAuthors2(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--annotate-authors",
+	)
+}
+
+func TestCheckLintTUI1(t *testing.T) {
+	// stdin and stdout are not a terminal in tests
+	testRun(
+		t,
+		1,
+		``,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--tui",
+	)
+}
+
+func TestCheckLintTUI2(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--error-format",
+		"json",
+		"--tui",
+	)
+}
+
+func TestCheckDaemon1(t *testing.T) {
+	t.Parallel()
+	filePath, err := filepath.Abs(filepath.Join("testdata", "fail", "buf", "buf.proto"))
+	require.NoError(t, err)
+	uri := "file://" + filepath.ToSlash(filePath)
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	exitCode := clicobra.Run(
+		newRootCommand("test"),
+		"test",
+		clienv.NewEnv(
+			[]string{"check", "daemon", "--input", filepath.Join("testdata", "fail")},
+			strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"shutdown"}`+"\n"),
+			stdout,
+			stderr,
+			nil,
+		),
+	)
+	require.Equal(t, 0, exitCode, stderr.String())
+	assert.Equal(
+		t,
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"`+uri+`","diagnostics":[{"range":{"start":{"line":2,"character":0},"end":{"line":2,"character":14}},"severity":1,"code":"PACKAGE_DIRECTORY_MATCH","source":"buf","message":"Files with package \"other\" must be within a directory \"other\" relative to root but were in directory \"buf\"."},{"range":{"start":{"line":5,"character":8},"end":{"line":5,"character":14}},"severity":1,"code":"FIELD_LOWER_SNAKE_CASE","source":"buf","message":"Field name \"oneTwo\" should be lower_snake_case, such as \"one_two\"."}]}}
+{"jsonrpc":"2.0","method":"buf/checkCompleted","params":{"diagnostics":2}}
+{"jsonrpc":"2.0","id":1,"result":null}
+`,
+		stdout.String(),
+	)
+}
+
+func TestCheckDaemon2(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"check",
+		"daemon",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--client",
+		"lsp",
+	)
+}
+
+func TestCheckLsLintIgnores1(t *testing.T) {
+	t.Parallel()
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	// use an image so that there is no git blame information
+	imageFilePath := filepath.Join(dirPath, "image.bin")
+	testRunCmdNoParallel(t, newRootCommand("test"), 0, ``, "image", "build", "-o", imageFilePath, "--source", filepath.Join("..", "..", "bufcheck", "buflint", "testdata", "comment_ignores"))
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		0,
+		`{"path":"a.proto","line":6,"id":"FIELD_LOWER_SNAKE_CASE"}
+		{"path":"a.proto","line":12,"id":"FIELD_LOWER_SNAKE_CASE"}
+		{"path":"a.proto","line":17,"id":"MESSAGE_PASCAL_CASE"}`,
+		"check",
+		"ls-lint-ignores",
+		"--input",
+		imageFilePath,
+		"--format",
+		"json",
+	)
+}
+
+func TestCheckLintCache1(t *testing.T) {
+	t.Parallel()
+	cacheDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(cacheDirPath)) }()
+	expectedStdout := `
+		testdata/fail/buf/buf.proto:3:1:Files with package "other" must be within a directory "other" relative to root but were in directory "buf".
+		testdata/fail/buf/buf.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".
+		`
+	// the first run populates the cache, the second run uses the cache
+	for i := 0; i < 2; i++ {
+		stdout := bytes.NewBuffer(nil)
+		stderr := bytes.NewBuffer(nil)
+		exitCode := clicobra.Run(
+			newRootCommand("test"),
+			"test",
+			clienv.NewEnv(
+				[]string{
+					"check",
+					"lint",
+					"--input",
+					filepath.Join("testdata", "fail"),
+					"--cache",
+				},
+				nil,
+				stdout,
+				stderr,
+				map[string]string{
+					"XDG_CACHE_HOME": cacheDirPath,
+				},
+			),
+		)
+		assert.Equal(t, 1, exitCode, utilstring.TrimLines(stderr.String()))
+		assert.Equal(t, utilstring.TrimLines(expectedStdout), utilstring.TrimLines(stdout.String()), utilstring.TrimLines(stderr.String()))
+		fileInfos, err := ioutil.ReadDir(filepath.Join(cacheDirPath, "buf", "lint"))
+		require.NoError(t, err)
+		assert.Len(t, fileInfos, 1)
+	}
+}
+
+func TestCheckLsLintCheckers1(t *testing.T) {
+	testRun(
+		t,
+		0,
+		`
+		ID                       CATEGORIES  PURPOSE
+		RPC_NO_CLIENT_STREAMING  UNARY_RPC   Checks that RPCs are not client streaming.
+		RPC_NO_SERVER_STREAMING  UNARY_RPC   Checks that RPCs are not server streaming.
+		`,
+		"check",
+		"ls-lint-checkers",
+		"--all",
+		"--category",
+		"UNARY_RPC",
+	)
+}
+
+func TestCheckLsLintCheckers2(t *testing.T) {
+	testRun(
+		t,
+		0,
+		`
+		ID                       CATEGORIES                            PURPOSE
+		PACKAGE_DIRECTORY_MATCH  MINIMAL, BASIC, DEFAULT, FILE_LAYOUT  Checks that all files with are in a directory that matches their package name.
+		ENUM_NO_ALLOW_ALIAS      MINIMAL, BASIC, DEFAULT, SENSIBLE     Checks that enums do not have the allow_alias option set.
+		`,
+		"check",
+		"ls-lint-checkers",
+		"--config",
+		filepath.Join("testdata", "small_list_checkers", "buf.yaml"),
+	)
+}
+
+func TestCheckLsLintCheckers3(t *testing.T) {
+	testRun(
+		t,
+		0,
+		`
+		ID                       CATEGORIES  PURPOSE
+		RPC_NO_SERVER_STREAMING  UNARY_RPC   Checks that RPCs are not server streaming.
+		`,
+		"check",
+		"ls-lint-checkers",
+		"--all",
+		"--checker",
+		"RPC_NO_SERVER_STREAMING",
+	)
+}
+
+func TestCheckLsBreakingCheckers1(t *testing.T) {
+	testRun(
+		t,
+		0,
+		`
+		ID                                           CATEGORIES                                PURPOSE
+		ENUM_VALUE_SAME_NAME                         FILE, PACKAGE, WIRE_JSON                  Checks that enum values have the same name.
+		FIELD_SAME_JSON_NAME                         FILE, PACKAGE, WIRE_JSON                  Checks that fields have the same value for the json_name option.
+		FIELD_SAME_NAME                              FILE, PACKAGE, WIRE_JSON                  Checks that fields have the same names in a given message.
+		FIELD_SAME_LABEL                             FILE, PACKAGE, WIRE_JSON, WIRE            Checks that fields have the same labels in a given message.
+		FIELD_SAME_ONEOF                             FILE, PACKAGE, WIRE_JSON, WIRE            Checks that fields have the same oneofs in a given message.
+		FIELD_SAME_TYPE                              FILE, PACKAGE, WIRE_JSON, WIRE            Checks that fields have the same types in a given message.
+		MESSAGE_SAME_MESSAGE_SET_WIRE_FORMAT         FILE, PACKAGE, WIRE_JSON, WIRE            Checks that messages have the same value for the message_set_wire_format option.
+		RPC_SAME_CLIENT_STREAMING                    FILE, PACKAGE, WIRE_JSON, WIRE            Checks that rpcs have the same client streaming value.
+		RPC_SAME_IDEMPOTENCY_LEVEL                   FILE, PACKAGE, WIRE_JSON, WIRE            Checks that rpcs have the same value for the idempotency_level option.
+		RPC_SAME_REQUEST_TYPE                        FILE, PACKAGE, WIRE_JSON, WIRE            Checks that rpcs are have the same request type.
+		RPC_SAME_RESPONSE_TYPE                       FILE, PACKAGE, WIRE_JSON, WIRE            Checks that rpcs are have the same response type.
+		RPC_SAME_SERVER_STREAMING                    FILE, PACKAGE, WIRE_JSON, WIRE            Checks that rpcs have the same server streaming value.
+		RESERVED_ENUM_NO_DELETE                      FILE, PACKAGE, WIRE_JSON, WIRE, RESERVED  Checks that reserved ranges and names are not deleted from a given enum.
+		RESERVED_ENUM_NO_REUSE                       FILE, PACKAGE, WIRE_JSON, WIRE, RESERVED  Checks that previously reserved numbers and names are not used by enum values in a given enum.
+		RESERVED_MESSAGE_NO_DELETE                   FILE, PACKAGE, WIRE_JSON, WIRE, RESERVED  Checks that reserved ranges and names are not deleted from a given message.
+		RESERVED_MESSAGE_NO_REUSE                    FILE, PACKAGE, WIRE_JSON, WIRE, RESERVED  Checks that previously reserved numbers and names are not used by fields in a given message.
+		ENUM_VALUE_NO_DELETE_UNLESS_NAME_RESERVED    WIRE_JSON                                 Checks that enum values are not deleted from a given enum unless the name is reserved.
+		FIELD_NO_DELETE_UNLESS_NAME_RESERVED         WIRE_JSON                                 Checks that fields are not deleted from a given message unless the name is reserved.
+		ENUM_VALUE_NO_DELETE_UNLESS_NUMBER_RESERVED  WIRE_JSON, WIRE                           Checks that enum values are not deleted from a given enum unless the number is reserved.
+		FIELD_NO_DELETE_UNLESS_NUMBER_RESERVED       WIRE_JSON, WIRE                           Checks that fields are not deleted from a given message unless the number is reserved.
+		`,
+		"check",
+		"ls-breaking-checkers",
+		"--all",
+		"--category",
+		"WIRE_JSON",
+	)
+}
+
+func TestCheckLsBreakingCheckers2(t *testing.T) {
+	testRun(
+		t,
+		0,
+		`
+		ID                    CATEGORIES     PURPOSE
+		ENUM_VALUE_NO_DELETE  FILE, PACKAGE  Checks that enum values are not deleted from a given enum.
+		FIELD_SAME_JSTYPE     FILE, PACKAGE  Checks that fields have the same value for the jstype option.
+		`,
+		"check",
+		"ls-breaking-checkers",
+		"--config",
+		filepath.Join("testdata", "small_list_checkers", "buf.yaml"),
+	)
+}
+
+func TestCheckLsLintCheckersDoc1(t *testing.T) {
+	testRun(
+		t,
+		0,
+		`
+		{"id":"RPC_NO_CLIENT_STREAMING","categories":["UNARY_RPC"],"purpose":"Checks that RPCs are not client streaming.","description":"RPCs must not be client streaming.","rationale":"Streaming RPCs are not supported by all RPC frameworks and proxies, and\nare more difficult to operate than unary RPCs.","examples":[{"description":"This fails:","content":"rpc Upload(stream UploadRequest) returns (UploadResponse);"},{"description":"This passes:","content":"rpc Upload(UploadRequest) returns (UploadResponse);"}]}
+		{"id":"RPC_NO_SERVER_STREAMING","categories":["UNARY_RPC"],"purpose":"Checks that RPCs are not server streaming.","description":"RPCs must not be server streaming.","rationale":"Streaming RPCs are not supported by all RPC frameworks and proxies, and\nare more difficult to operate than unary RPCs.","examples":[{"description":"This fails:","content":"rpc Download(DownloadRequest) returns (stream DownloadResponse);"},{"description":"This passes:","content":"rpc Download(DownloadRequest) returns (DownloadResponse);"}]}
+		`,
+		"check",
+		"ls-lint-checkers",
+		"--all",
+		"--category",
+		"UNARY_RPC",
+		"--doc",
+		"--format",
+		"json",
+	)
+}
+
+func TestCheckLsLintCheckersDoc2(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"check",
+		"ls-lint-checkers",
+		"--all",
+		"--doc",
+		"--format",
+		"yaml",
+	)
+}
+
+func TestLsFiles(t *testing.T) {
+	testRun(
+		t,
+		0,
+		`
+		testdata/success/buf/buf.proto
+		`,
+		"ls-files",
+		"--input",
+		filepath.Join("testdata", "success"),
+	)
+}
+
+func TestLsFilesFileSet(t *testing.T) {
+	t.Parallel()
+	fileSetJSON := `{"roots":["."],"files":[{"root_file_path":"buf/buf.proto","real_file_path":"buf/buf.proto"}]}`
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		0,
+		fileSetJSON,
+		"ls-files",
+		"--input",
+		filepath.Join("testdata", "success"),
+		"--format",
+		"json",
+	)
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	fileSetFilePath := filepath.Join(dirPath, "fileset.json")
+	require.NoError(t, ioutil.WriteFile(fileSetFilePath, []byte(fileSetJSON), 0644))
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		0,
+		``,
+		"image",
+		"build",
+		"-o",
+		clios.DevNull,
+		"--source",
+		filepath.Join("testdata", "success"),
+		"--file-set",
+		fileSetFilePath,
+	)
+	// the file set is used instead of searching the input, so a missing file fails the build
+	missingFileSetFilePath := filepath.Join(dirPath, "missing.json")
+	require.NoError(
+		t,
+		ioutil.WriteFile(
+			missingFileSetFilePath,
+			[]byte(`{"roots":["."],"files":[{"root_file_path":"buf/missing.proto","real_file_path":"buf/missing.proto"}]}`),
+			0644,
+		),
+	)
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		1,
+		``,
+		"image",
+		"build",
+		"-o",
+		clios.DevNull,
+		"--source",
+		filepath.Join("testdata", "success"),
+		"--file-set",
+		missingFileSetFilePath,
+	)
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		1,
+		``,
+		"ls-files",
+		"--input",
+		filepath.Join("testdata", "success"),
+		"--format",
+		"yaml",
+	)
+}
+
+func TestLsOptions(t *testing.T) {
+	testRun(
+		t,
+		0,
+		`
+		{"name":"foo.owner","extendee":"google.protobuf.MessageOptions","number":50001,"defining_file":"testdata/options/foo/options.proto","count":1,"files":["testdata/options/foo/a.proto"]}
+		{"name":"foo.sensitive","extendee":"google.protobuf.FieldOptions","number":50000,"defining_file":"testdata/options/foo/options.proto","count":2,"files":["testdata/options/foo/a.proto"]}
+		`,
+		"ls-options",
+		"--input",
+		filepath.Join("testdata", "options"),
+		"--format",
+		"json",
+	)
+}
+
+// testRunRuleTiming runs the command, which must print nothing to stdout, and
+// returns the checker IDs printed by --rule-timing to stderr.
+func testRunRuleTiming(t *testing.T, expectedExitCode int, args ...string) []string {
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	exitCode := clicobra.Run(
+		newRootCommand("test"),
+		"test",
+		clienv.NewEnv(args, nil, stdout, stderr, nil),
+	)
+	require.Equal(t, expectedExitCode, exitCode, utilstring.TrimLines(stderr.String()))
+	assert.Equal(t, "", stdout.String())
+	lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+	require.True(t, len(lines) > 2, stderr.String())
+	assert.Equal(t, []string{"ID", "DURATION"}, strings.Fields(lines[0]))
+	var ids []string
+	var previousDuration time.Duration
+	for i, line := range lines[1:] {
+		fields := strings.Fields(line)
+		require.Len(t, fields, 2, line)
+		duration, err := time.ParseDuration(fields[1])
+		require.NoError(t, err, line)
+		if i == len(lines)-2 {
+			assert.Equal(t, "TOTAL", fields[0])
+			continue
+		}
+		if i > 0 {
+			assert.True(t, duration <= previousDuration, line)
+		}
+		previousDuration = duration
+		ids = append(ids, fields[0])
+	}
+	return ids
+}
+
+func testRun(t *testing.T, expectedExitCode int, expectedStdout string, args ...string) {
+	testRunCmd(
+		t,
+		newRootCommand("test"),
+		expectedExitCode,
+		expectedStdout,
+		args...,
+	)
+}
+
+// testRunUnordered is testRun for commands whose lines of output are not in a
+// deterministic order.
+func testRunUnordered(t *testing.T, expectedExitCode int, expectedStdout string, args ...string) {
+	t.Parallel()
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	exitCode := clicobra.Run(
+		newRootCommand("test"),
+		"test",
+		clienv.NewEnv(
+			args,
+			nil,
+			stdout,
+			stderr,
+			nil,
+		),
+	)
+	assert.Equal(t, expectedExitCode, exitCode, utilstring.TrimLines(stderr.String()))
+	if exitCode == expectedExitCode {
+		expectedLines := strings.Split(utilstring.TrimLines(expectedStdout), "\n")
+		lines := strings.Split(utilstring.TrimLines(stdout.String()), "\n")
+		assert.ElementsMatch(t, expectedLines, lines, utilstring.TrimLines(stderr.String()))
+	}
+}
+
+func testRunProfile(t *testing.T, expectedExitCode int, expectedStdout string, args ...string) {
+	profileDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(profileDirPath)) }()
+	testRunCmd(
+		t,
+		newRootCommand("test"),
+		0,
+		``,
+		append(
+			args,
+			"--profile",
+			fmt.Sprintf("--profile-path=%s", profileDirPath),
+			"--profile-loops=1",
+			"--profile-type=cpu",
+		)...,
+	)
+}
+
+func testRunCmd(t *testing.T, cmd *clicobra.Command, expectedExitCode int, expectedStdout string, args ...string) {
+	t.Parallel()
+	testRunCmdNoParallel(t, cmd, expectedExitCode, expectedStdout, args...)
+}
+
+// testRunCmdNoParallel is testRunCmd for tests that run multiple commands in sequence.
+func testRunCmdNoParallel(t *testing.T, cmd *clicobra.Command, expectedExitCode int, expectedStdout string, args ...string) {
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	exitCode := clicobra.Run(
+		cmd,
+		"test",
+		clienv.NewEnv(
+			args,
+			nil,
+			stdout,
+			stderr,
+			nil,
+		),
+	)
+	assert.Equal(t, expectedExitCode, exitCode, utilstring.TrimLines(stderr.String()))
+	if exitCode == expectedExitCode {
+		assert.Equal(t, utilstring.TrimLines(expectedStdout), utilstring.TrimLines(stdout.String()), utilstring.TrimLines(stderr.String()))
+	}
+}
+
+func TestConfigEffective1(t *testing.T) {
+	testRun(
+		t,
+		0,
+		`
+		build:
+		  roots: # config data
+		  - proto
+		breaking:
+		  use: # default
+		  - FILE
+		lint:
+		  use: # config data
+		  - BASIC
+		  except: # config data
+		  - FILE_LAYOUT
+		`,
+		"config",
+		"effective",
+		`{"build":{"roots":["proto"]},"lint":{"use":["BASIC"],"except":["FILE_LAYOUT"]}}`,
+	)
+}
+
+func TestConfigEffectiveExtends(t *testing.T) {
+	t.Parallel()
+	cacheDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(cacheDirPath)) }()
+	extendsData := []byte(`lint:
+  use:
+    - BASIC
+  except:
+    - FILE_LAYOUT
+  service_suffix: API
+`)
+	sum := sha256.Sum256(extendsData)
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				_, _ = responseWriter.Write(extendsData)
+			},
+		),
+	)
+	defer server.Close()
+	extendsURL := server.URL + "/policy.yaml"
+	extends := extendsURL + "#sha256=" + hex.EncodeToString(sum[:])
+
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	exitCode := clicobra.Run(
+		newRootCommand("test"),
+		"test",
+		clienv.NewEnv(
+			[]string{
+				"config",
+				"effective",
+				fmt.Sprintf(`{"lint":{"extends":"%s","except":["PACKAGE_DIRECTORY_MATCH"]}}`, extends),
+			},
+			nil,
+			stdout,
+			stderr,
+			map[string]string{
+				"XDG_CACHE_HOME": cacheDirPath,
+			},
+		),
+	)
+	assert.Equal(t, 0, exitCode, utilstring.TrimLines(stderr.String()))
+	assert.Equal(
+		t,
+		utilstring.TrimLines(
+			fmt.Sprintf(
+				`
+				breaking:
+				  use: # default
+				  - FILE
+				lint:
+				  use: # %s
+				  - BASIC
+				  except: # config data
+				  - PACKAGE_DIRECTORY_MATCH
+				  service_suffix: API # %s
+				  extends: %s # config data
+				`,
+				extendsURL,
+				extendsURL,
+				extends,
+			),
+		),
+		utilstring.TrimLines(stdout.String()),
+	)
+}
+
+func TestConfigDiff1(t *testing.T) {
+	testRun(
+		t,
+		0,
+		`build.excludes: +proto/vendor
+		lint.checkers: -ENUM_ZERO_VALUE_SUFFIX
+		lint.ignore_only: -ENUM_ZERO_VALUE_SUFFIX=proto/a
+		lint.allow_comment_ignores: +true
+		lint.allow_comment_ignores: -false`,
+		"config",
+		"diff",
+		filepath.Join("testdata", "config_diff", "old.yaml"),
+		filepath.Join("testdata", "config_diff", "new.yaml"),
+	)
+}
+
+func TestConfigDiff2(t *testing.T) {
+	testRun(
+		t,
+		0,
+		``,
+		"config",
+		"diff",
+		filepath.Join("testdata", "config_diff", "old.yaml"),
+		filepath.Join("testdata", "config_diff", "old.yaml"),
+	)
+}
+
+func TestConfigMigratePrototool(t *testing.T) {
+	testRun(
+		t,
+		0,
+		`
+		build:
+		  excludes:
+		  - vendor
+		lint:
+		  use:
+		  - DEFAULT
+		  - RPC_NO_CLIENT_STREAMING
+		  - RPC_NO_SERVER_STREAMING
+		  - COMMENT_SERVICE
+		  except:
+		  - ENUM_VALUE_PREFIX
+		  ignore_only:
+		    RPC_PASCAL_CASE:
+		    - foo/v1/foo.proto
+		`,
+		"config",
+		"migrate",
+		filepath.Join("testdata", "migrate", "prototool.yaml"),
+	)
+}
+
+func TestConfigMigrateProtolint(t *testing.T) {
+	testRun(
+		t,
+		0,
+		`
+		build:
+		  excludes:
+		  - vendor
+		lint:
+		  use:
+		  - FIELD_LOWER_SNAKE_CASE
+		  - FILE_MAX_LINE_LENGTH
+		  - ENUM_ZERO_VALUE_SUFFIX
+		  ignore:
+		  - foo/bar.proto
+		  enum_zero_value_suffix: _INVALID
+		  max_line_length: 80
+		`,
+		"config",
+		"migrate",
+		filepath.Join("testdata", "migrate", ".protolint.yaml"),
+	)
+}
+
+func TestFailConfigMigrateUnknownFile(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"config",
+		"migrate",
+		filepath.Join("testdata", "config_diff", "old.yaml"),
+	)
+}
+
+func TestCompletion(t *testing.T) {
+	t.Parallel()
+	testRunCmdNoParallel(t, newRootCommand("test"), 0, `ls-files
+		ls-options
+		ls-packages
+		ls-services
+		ls-methods
+		lsp`, "completion", "__complete", "0", "--", "ls")
+	testRunCmdNoParallel(t, newRootCommand("test"), 0, `--error-format
+		--error-format-template`, "completion", "__complete", "2", "--", "check", "lint", "--error-f")
+	testRunCmdNoParallel(t, newRootCommand("test"), 0, `json
+		jsonl
+		junit`, "completion", "__complete", "3", "--", "check", "lint", "--error-format", "j")
+	testRunCmdNoParallel(t, newRootCommand("test"), 0, `--error-format=json
+		--error-format=jsonl`, "completion", "__complete", "2", "--", "check", "lint", "--error-format=js")
+	testRunCmdNoParallel(t, newRootCommand("test"), 0, `foo#format=bin
+		foo#format=bingz`, "completion", "__complete", "3", "--", "check", "lint", "--input", "foo#format=b")
+	testRunCmdNoParallel(t, newRootCommand("test"), 0, ``, "completion", "__complete", "3", "--", "check", "lint", "--input", "fo")
+	testRunCmdNoParallel(t, newRootCommand("test"), 0, `FILE_LAYOUT`, "completion", "__complete", "3", "--", "check", "ls-lint-checkers", "--category", "FILE_")
+	testRunCmdNoParallel(t, newRootCommand("test"), 0, `WIRE,WIRE_JSON`, "completion", "__complete", "3", "--", "check", "ls-breaking-checkers", "--category", "WIRE,WIRE_")
+	testRunCmdNoParallel(t, newRootCommand("test"), 0, `FIELD_LOWER_SNAKE_CASE`, "completion", "__complete", "3", "--", "check", "ls-lint-checkers", "--checker", "FIELD_L")
+	testRunCmdNoParallel(t, newRootCommand("test"), 0, `fish`, "completion", "__complete", "1", "--", "completion", "f")
+	testRunCmdNoParallel(t, newRootCommand("test"), 0, `lint
+		breaking
+		ls-lint-checkers
+		ls-breaking-checkers
+		ls-lint-ignores
+		daemon`, "completion", "__complete", "1", "--", "check")
+}
+
+func TestCompletionScript(t *testing.T) {
+	t.Parallel()
+	for _, shell := range []string{"bash", "zsh", "fish", "powershell"} {
+		stdout := bytes.NewBuffer(nil)
+		stderr := bytes.NewBuffer(nil)
+		exitCode := clicobra.Run(
+			newRootCommand("test"),
+			"test",
+			clienv.NewEnv([]string{"completion", shell}, nil, stdout, stderr, nil),
+		)
+		assert.Equal(t, 0, exitCode, stderr.String())
+		assert.Contains(t, stdout.String(), "completion __complete", shell)
+	}
+}
+
+func TestFailCompletionUnknownShell(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"completion",
+		"tcsh",
+	)
+}
+
+func TestFailWatchNotDirectory(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "doc", "a", "v1", "a.proto"),
+		"--watch",
+	)
+}
+
+func TestFailLspNotDirectory(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"lsp",
+		"--input",
+		filepath.Join("testdata", "doc", "a", "v1", "a.proto"),
+	)
+}
+
+func TestLspUnsavedChanges(t *testing.T) {
+	t.Parallel()
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "buf.yaml"), []byte("lint:\n  use:\n    - FIELD_LOWER_SNAKE_CASE\n"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(dirPath, "a"), 0755))
+	filePath := filepath.Join(dirPath, "a", "a.proto")
+	require.NoError(t, ioutil.WriteFile(filePath, []byte("syntax = \"proto3\";\n\npackage a;\n\nmessage Foo {\n  int64 one_two = 1;\n}\n"), 0644))
+	uri, err := utillsp.PathToURI(filePath)
+	require.NoError(t, err)
+
+	stdin := bytes.NewBuffer(nil)
+	for _, message := range []string{
+		`{"jsonrpc":"2.0","method":"initialized","params":{}}`,
+		// the file on disk is not changed, only the contents in the client
+		`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"` + uri + `","languageId":"proto","version":1,"text":"syntax = \"proto3\";\n\npackage a;\n\nmessage Foo {\n  int64 one_two = 1;\n}\n"}}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":"` + uri + `","version":2},"contentChanges":[{"text":"syntax = \"proto3\";\n\npackage a;\n\nmessage Foo {\n  int64 oneTwo = 1;\n}\n"}]}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didClose","params":{"textDocument":{"uri":"` + uri + `"}}}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	} {
+		_, err := fmt.Fprintf(stdin, "Content-Length: %d\r\n\r\n%s", len(message), message)
+		require.NoError(t, err)
+	}
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	exitCode := clicobra.Run(
+		newRootCommand("test"),
+		"test",
+		clienv.NewEnv(
+			[]string{"lsp", "--input", dirPath},
+			stdin,
+			stdout,
+			stderr,
+			nil,
+		),
+	)
+	assert.Equal(t, 0, exitCode, utilstring.TrimLines(stderr.String()))
+	reader := bufio.NewReader(stdout)
+	for _, expected := range []string{
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"` + uri + `","diagnostics":[{"range":{"start":{"line":5,"character":8},"end":{"line":5,"character":14}},"severity":1,"code":"FIELD_LOWER_SNAKE_CASE","source":"buf","message":"Field name \"oneTwo\" should be lower_snake_case, such as \"one_two\"."}]}}`,
+		// closing the file without saving checks the file on disk again
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"` + uri + `","diagnostics":[]}}`,
+	} {
+		data, err := utillsp.ReadMessage(reader)
+		require.NoError(t, err)
+		assert.JSONEq(t, expected, string(data))
+	}
+	_, err = utillsp.ReadMessage(reader)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestPushPull(t *testing.T) {
+	t.Parallel()
+	var lock sync.Mutex
+	pathToData := make(map[string][]byte)
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				lock.Lock()
+				defer lock.Unlock()
+				switch request.Method {
+				case http.MethodGet:
+					data, ok := pathToData[request.URL.Path]
+					if !ok {
+						responseWriter.WriteHeader(http.StatusNotFound)
+						return
+					}
+					_, _ = responseWriter.Write(data)
+				case http.MethodPut:
+					if _, ok := pathToData[request.URL.Path]; ok {
+						responseWriter.WriteHeader(http.StatusConflict)
+						return
+					}
+					data, err := ioutil.ReadAll(request.Body)
+					assert.NoError(t, err)
+					pathToData[request.URL.Path] = data
+					responseWriter.WriteHeader(http.StatusCreated)
+				}
+			},
+		),
+	)
+	defer server.Close()
+	module := "module://" + strings.TrimPrefix(server.URL, "http://") + "/foo/fail"
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tempDirPath))
+	}()
+	imagePath := filepath.Join(tempDirPath, "image.bin")
+	configPath := filepath.Join(tempDirPath, "buf.yaml")
+	// the lint config of the module is BASIC, not the default
+	lintOutput := `buf/buf.proto:3:1:Files with package "other" must be within a directory "other" relative to root but were in directory "buf".
+		buf/buf.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".`
+
+	testRunCmdNoParallel(t, newRootCommand("test"), 0, ``, "push", module+":v1", "--source", filepath.Join("testdata", "fail"))
+	testRunCmdNoParallel(t, newRootCommand("test"), 1, ``, "push", module+":v1", "--source", filepath.Join("testdata", "fail"))
+	testRunCmdNoParallel(t, newRootCommand("test"), 1, lintOutput, "check", "lint", "--input", module+":v1")
+	testRunCmdNoParallel(t, newRootCommand("test"), 0, ``, "pull", module+":v1", "-o", imagePath, "--config-output", configPath)
+	testRunCmdNoParallel(t, newRootCommand("test"), 1, lintOutput, "check", "lint", "--input", imagePath, "--input-config", configPath)
+	testRunCmdNoParallel(t, newRootCommand("test"), 0, ``, "check", "breaking", "--input", filepath.Join("testdata", "fail"), "--against-input", module+":v1")
+	testRunCmdNoParallel(t, newRootCommand("test"), 1, ``, "pull", module+":v2", "-o", imagePath)
+	testRunCmdNoParallel(t, newRootCommand("test"), 1, ``, "image", "build", "--source", filepath.Join("testdata", "fail"), "-o", module+":v2")
+	configData, err := ioutil.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "lint:\n  use:\n  - BASIC\n", string(configData))
+}
+
+func TestFailPushNotModule(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"push",
+		"buf.example.com/foo/bar:v1",
+	)
+}
+
+func testTarGz(t *testing.T, pathToContent map[string]string) []byte {
+	buffer := bytes.NewBuffer(nil)
+	gzipWriter := gzip.NewWriter(buffer)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for path, content := range pathToContent {
+		require.NoError(
+			t,
+			tarWriter.WriteHeader(
+				&tar.Header{
+					Typeflag: tar.TypeReg,
+					Name:     path,
+					Mode:     0644,
+					Size:     int64(len(content)),
+				},
+			),
+		)
+		_, err := tarWriter.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, gzipWriter.Close())
+	return buffer.Bytes()
+}
+
+// testGetRelFilePaths returns the sorted slash paths of the regular files
+// within the directory, relative to the directory.
+func testGetRelFilePaths(t *testing.T, dirPath string) []string {
+	var relFilePaths []string
+	require.NoError(
+		t,
+		filepath.Walk(
+			dirPath,
+			func(path string, fileInfo os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if !fileInfo.Mode().IsRegular() {
+					return nil
+				}
+				relFilePath, err := filepath.Rel(dirPath, path)
+				if err != nil {
+					return err
+				}
+				relFilePaths = append(relFilePaths, filepath.ToSlash(relFilePath))
+				return nil
+			},
+		),
+	)
+	return relFilePaths
+}
+
+func TestTest(t *testing.T) {
+	testRun(
+		t,
+		0,
+		`
+		PASS billing
+		PASS small
+		`,
+		"test",
+		"--input",
+		filepath.Join("testdata", "test"),
+	)
+}
+
+func TestTestJSON(t *testing.T) {
+	testRun(
+		t,
+		0,
+		`
+		{"name":"billing","passed":true}
+		{"name":"small","passed":true}
+		`,
+		"test",
+		"--input",
+		filepath.Join("testdata", "test"),
+		"--format",
+		"json",
+	)
+}
+
+func TestFailTest(t *testing.T) {
+	testRun(
+		t,
+		1,
+		`
+		FAIL billing
+		    Service "acme.v1.Payments" is not defined.
+		    Enum "acme.v1.Status" is not defined.
+		FAIL small
+		    Message "acme.v1.Invoice" has 3 fields, which is more than the maximum of 2.
+		PASS other
+		FAIL missing
+		    Package "acme.v2" is not defined.
+		`,
+		"test",
+		"--input",
+		filepath.Join("testdata", "test"),
+		"--input-config",
+		`{"tests":[{"name":"billing","package":"acme.v1","contains_services":["Billing","Payments"],"contains_enums":["Status"]},{"name":"small","max_message_fields":2},{"name":"other","contains_messages":["acme.v1.GetInvoiceRequest"]},{"name":"missing","package":"acme.v2","contains_messages":["Invoice"]}]}`,
+	)
+}
+
+func TestFailTestNoTests(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"test",
+		"--input",
+		filepath.Join("testdata", "success"),
+	)
+}
+
+func TestQuery(t *testing.T) {
+	testRun(
+		t,
+		0,
+		`
+		testdata/query/a/v1/a.proto:7:3:a.v1.Upload.data
+		testdata/query/a/v1/a.proto:11:5:a.v1.Upload.Chunk.data
+		`,
+		"query",
+		"--input",
+		filepath.Join("testdata", "query"),
+		`fields where type == "bytes" && !(comment contains "size")`,
+	)
+}
+
+func TestQueryJSON(t *testing.T) {
+	testRun(
+		t,
+		0,
+		`
+		{"kind":"messages","path":"testdata/query/a/v1/a.proto","start_line":10,"start_column":3,"full_name":"a.v1.Upload.Chunk","attributes":{"comment":"","field_count":1,"file":"a/v1/a.proto","full_name":"a.v1.Upload.Chunk","name":"Chunk","nested":true,"package":"a.v1"}}
+		`,
+		"query",
+		"--input",
+		filepath.Join("testdata", "query"),
+		"--format",
+		"json",
+		"messages where nested",
+	)
+}
+
+func TestFailQuery(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"query",
+		"--input",
+		filepath.Join("testdata", "query"),
+		"fields where type == 1",
+	)
+}
+
+func TestFailGenerateTemplateNotFound(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"generate",
+		"--input",
+		filepath.Join("testdata", "query"),
+		"--template",
+		filepath.Join("testdata", "query", "buf.gen.yaml"),
+	)
+}
+
+func TestFailGenerateTemplateInvalid(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"generate",
+		"--input",
+		filepath.Join("testdata", "query"),
+		"--template",
+		`{"plugins":[{"name":"go"}]}`,
+	)
+}
+
+func TestFormat(t *testing.T) {
+	testRun(
+		t,
+		0,
+		`
+		syntax = "proto3";
+
+		package a.v1;
+
+		import "google/protobuf/timestamp.proto";
+
+		option go_package = "av1";
+		option java_package = "com.a.v1";
+
+		// Event is an event.
+		message Event {
+		  google.protobuf.Timestamp time = 1; // when
+		  map<string, string> labels = 2 [deprecated = true];
+		}
+		`,
+		"format",
+		"--input",
+		filepath.Join("testdata", "format"),
+	)
+}
+
+func TestFailFormatExitCode(t *testing.T) {
+	testRun(
+		t,
+		1,
+		`
+		syntax = "proto3";
+
+		package a.v1;
+
+		import "google/protobuf/timestamp.proto";
+
+		option go_package = "av1";
+		option java_package = "com.a.v1";
+
+		// Event is an event.
+		message Event {
+		  google.protobuf.Timestamp time = 1; // when
+		  map<string, string> labels = 2 [deprecated = true];
+		}
+		`,
+		"format",
+		"--input",
+		filepath.Join("testdata", "format"),
+		"--exit-code",
 	)
 }
 
-func TestCheckLsLintCheckers1(t *testing.T) {
+func TestFormatWrite(t *testing.T) {
+	t.Parallel()
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	filePath := filepath.Join(dirPath, "a.proto")
+	require.NoError(t, ioutil.WriteFile(filePath, []byte("syntax=\"proto3\";\npackage a;\nmessage Foo{int64 one=1;}\n"), 0644))
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		1,
+		``,
+		"format",
+		"--input",
+		dirPath,
+		"-w",
+		"--exit-code",
+	)
+	data, err := ioutil.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`syntax = "proto3";
+
+package a;
+
+message Foo {
+  int64 one = 1;
+}
+`,
+		string(data),
+	)
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		0,
+		``,
+		"format",
+		"--input",
+		dirPath,
+		"-d",
+		"--exit-code",
+	)
+}
+
+func TestFailFormatNotDirectory(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"format",
+		"--input",
+		filepath.Join("testdata", "format", "a", "v1", "a.proto"),
+	)
+}
+
+func TestLsPackages(t *testing.T) {
 	testRun(
 		t,
 		0,
 		`
-		ID                       CATEGORIES  PURPOSE
-		RPC_NO_CLIENT_STREAMING  UNARY_RPC   Checks that RPCs are not client streaming.
-		RPC_NO_SERVER_STREAMING  UNARY_RPC   Checks that RPCs are not server streaming.
+		PACKAGE  FILES
+		a.v1     testdata/shards/a/v1/a.proto
+		b.v1     testdata/shards/b/v1/b.proto
+		c.v1     testdata/shards/c/v1/c.proto
 		`,
-		"check",
-		"ls-lint-checkers",
-		"--all",
-		"--category",
-		"UNARY_RPC",
+		"ls-packages",
+		"--input",
+		filepath.Join("testdata", "shards"),
 	)
 }
 
-func TestCheckLsLintCheckers2(t *testing.T) {
+func TestLsServices(t *testing.T) {
 	testRun(
 		t,
 		0,
 		`
-		ID                       CATEGORIES                            PURPOSE
-		PACKAGE_DIRECTORY_MATCH  MINIMAL, BASIC, DEFAULT, FILE_LAYOUT  Checks that all files with are in a directory that matches their package name.
-		ENUM_NO_ALLOW_ALIAS      MINIMAL, BASIC, DEFAULT, SENSIBLE     Checks that enums do not have the allow_alias option set.
+		SERVICE          FILE                            METHODS
+		a.v1.FooService  testdata/services/a/v1/a.proto  4
+		b.v1.BarService  testdata/services/b/v1/b.proto  1
 		`,
-		"check",
-		"ls-lint-checkers",
-		"--config",
-		filepath.Join("testdata", "small_list_checkers", "buf.yaml"),
+		"ls-services",
+		"--input",
+		filepath.Join("testdata", "services"),
 	)
 }
 
-func TestCheckLsBreakingCheckers1(t *testing.T) {
+func TestLsMethods(t *testing.T) {
 	testRun(
 		t,
 		0,
 		`
-		ID                                           CATEGORIES                      PURPOSE
-		ENUM_VALUE_SAME_NAME                         FILE, PACKAGE, WIRE_JSON        Checks that enum values have the same name.
-		FIELD_SAME_JSON_NAME                         FILE, PACKAGE, WIRE_JSON        Checks that fields have the same value for the json_name option.
-		FIELD_SAME_NAME                              FILE, PACKAGE, WIRE_JSON        Checks that fields have the same names in a given message.
-		FIELD_SAME_LABEL                             FILE, PACKAGE, WIRE_JSON, WIRE  Checks that fields have the same labels in a given message.
-		FIELD_SAME_ONEOF                             FILE, PACKAGE, WIRE_JSON, WIRE  Checks that fields have the same oneofs in a given message.
-		FIELD_SAME_TYPE                              FILE, PACKAGE, WIRE_JSON, WIRE  Checks that fields have the same types in a given message.
-		MESSAGE_SAME_MESSAGE_SET_WIRE_FORMAT         FILE, PACKAGE, WIRE_JSON, WIRE  Checks that messages have the same value for the message_set_wire_format option.
-		RESERVED_ENUM_NO_DELETE                      FILE, PACKAGE, WIRE_JSON, WIRE  Checks that reserved ranges and names are not deleted from a given enum.
-		RESERVED_MESSAGE_NO_DELETE                   FILE, PACKAGE, WIRE_JSON, WIRE  Checks that reserved ranges and names are not deleted from a given message.
-		RPC_SAME_CLIENT_STREAMING                    FILE, PACKAGE, WIRE_JSON, WIRE  Checks that rpcs have the same client streaming value.
-		RPC_SAME_IDEMPOTENCY_LEVEL                   FILE, PACKAGE, WIRE_JSON, WIRE  Checks that rpcs have the same value for the idempotency_level option.
-		RPC_SAME_REQUEST_TYPE                        FILE, PACKAGE, WIRE_JSON, WIRE  Checks that rpcs are have the same request type.
-		RPC_SAME_RESPONSE_TYPE                       FILE, PACKAGE, WIRE_JSON, WIRE  Checks that rpcs are have the same response type.
-		RPC_SAME_SERVER_STREAMING                    FILE, PACKAGE, WIRE_JSON, WIRE  Checks that rpcs have the same server streaming value.
-		ENUM_VALUE_NO_DELETE_UNLESS_NAME_RESERVED    WIRE_JSON                       Checks that enum values are not deleted from a given enum unless the name is reserved.
-		FIELD_NO_DELETE_UNLESS_NAME_RESERVED         WIRE_JSON                       Checks that fields are not deleted from a given message unless the name is reserved.
-		ENUM_VALUE_NO_DELETE_UNLESS_NUMBER_RESERVED  WIRE_JSON, WIRE                 Checks that enum values are not deleted from a given enum unless the number is reserved.
-		FIELD_NO_DELETE_UNLESS_NUMBER_RESERVED       WIRE_JSON, WIRE                 Checks that fields are not deleted from a given message unless the number is reserved.
+		METHOD                      REQUEST                RESPONSE               STREAMING
+		a.v1.FooService.GetFoo      a.v1.GetFooRequest     a.v1.GetFooResponse    unary
+		a.v1.FooService.UploadFoos  b.v1.Foo               a.v1.GetFooResponse    client
+		a.v1.FooService.WatchFoos   a.v1.GetFooRequest     b.v1.Foo               server
+		a.v1.FooService.SyncFoos    b.v1.Foo               b.v1.Foo               bidi
+		b.v1.BarService.Ping        google.protobuf.Empty  google.protobuf.Empty  unary
 		`,
-		"check",
-		"ls-breaking-checkers",
-		"--all",
-		"--category",
-		"WIRE_JSON",
+		"ls-methods",
+		"--input",
+		filepath.Join("testdata", "services"),
 	)
 }
 
-func TestCheckLsBreakingCheckers2(t *testing.T) {
+func TestLsMethodsJSON(t *testing.T) {
 	testRun(
 		t,
 		0,
 		`
-		ID                    CATEGORIES     PURPOSE
-		ENUM_VALUE_NO_DELETE  FILE, PACKAGE  Checks that enum values are not deleted from a given enum.
-		FIELD_SAME_JSTYPE     FILE, PACKAGE  Checks that fields have the same value for the jstype option.
+		{"name":"a.v1.FooService.GetFoo","service":"a.v1.FooService","file":"testdata/services/a/v1/a.proto","input_type":"a.v1.GetFooRequest","output_type":"a.v1.GetFooResponse"}
+		{"name":"a.v1.FooService.UploadFoos","service":"a.v1.FooService","file":"testdata/services/a/v1/a.proto","input_type":"b.v1.Foo","output_type":"a.v1.GetFooResponse","client_streaming":true}
+		{"name":"a.v1.FooService.WatchFoos","service":"a.v1.FooService","file":"testdata/services/a/v1/a.proto","input_type":"a.v1.GetFooRequest","output_type":"b.v1.Foo","server_streaming":true}
+		{"name":"a.v1.FooService.SyncFoos","service":"a.v1.FooService","file":"testdata/services/a/v1/a.proto","input_type":"b.v1.Foo","output_type":"b.v1.Foo","client_streaming":true,"server_streaming":true}
+		{"name":"b.v1.BarService.Ping","service":"b.v1.BarService","file":"testdata/services/b/v1/b.proto","input_type":"google.protobuf.Empty","output_type":"google.protobuf.Empty"}
 		`,
-		"check",
-		"ls-breaking-checkers",
-		"--config",
-		filepath.Join("testdata", "small_list_checkers", "buf.yaml"),
+		"ls-methods",
+		"--input",
+		filepath.Join("testdata", "services"),
+		"--format",
+		"json",
 	)
 }
 
-func TestLsFiles(t *testing.T) {
-	testRun(
+func TestConvert(t *testing.T) {
+	t.Parallel()
+	tmpDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDirPath)) }()
+	jsonFilePath := filepath.Join(tmpDirPath, "message.json")
+	binFilePath := filepath.Join(tmpDirPath, "message.bin")
+	require.NoError(t, ioutil.WriteFile(jsonFilePath, []byte(`{"foo": {"name": "bar"}}`), 0644))
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		0,
+		``,
+		"convert",
+		"--input",
+		filepath.Join("testdata", "services"),
+		"--type",
+		"a.v1.GetFooResponse",
+		"--from",
+		jsonFilePath,
+		"--to",
+		binFilePath,
+	)
+	data, err := ioutil.ReadFile(binFilePath)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x0a, 0x05, 0x0a, 0x03, 'b', 'a', 'r'}, data)
+	testRunCmdNoParallel(
 		t,
+		newRootCommand("test"),
 		0,
 		`
-		testdata/success/buf/buf.proto
+		foo: <
+		  name: "bar"
+		>
 		`,
-		"ls-files",
+		"convert",
 		"--input",
-		filepath.Join("testdata", "success"),
+		filepath.Join("testdata", "services"),
+		"--type",
+		".a.v1.GetFooResponse",
+		"--from",
+		binFilePath,
+		"--to",
+		"-#format=txt",
+	)
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		1,
+		``,
+		"convert",
+		"--input",
+		filepath.Join("testdata", "services"),
+		"--type",
+		"a.v1.FooService",
+		"--from",
+		binFilePath,
 	)
 }
 
-func testRun(t *testing.T, expectedExitCode int, expectedStdout string, args ...string) {
-	testRunCmd(
+func TestFailConvertNoType(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"convert",
+		"--input",
+		filepath.Join("testdata", "services"),
+	)
+}
+
+func TestCurl(t *testing.T) {
+	t.Parallel()
+	handler := http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != "/a.v1.FooService/GetFoo" || request.Header.Get("X-Foo") != "bar, baz" {
+			responseWriter.Header().Set("Content-Type", "application/grpc")
+			responseWriter.Header().Set("Grpc-Status", "12")
+			return
+		}
+		responseWriter.Header().Set("Content-Type", "application/grpc")
+		responseWriter.Header().Set("Trailer", "Grpc-Status")
+		// GetFooResponse{foo: {name: "bar"}}
+		_, _ = responseWriter.Write([]byte{0, 0, 0, 0, 7, 0x0a, 0x05, 0x0a, 0x03, 'b', 'a', 'r'})
+		responseWriter.Header().Set("Grpc-Status", "0")
+	})
+	plaintextServer := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+	defer plaintextServer.Close()
+	tlsServer := httptest.NewUnstartedServer(handler)
+	tlsServer.EnableHTTP2 = true
+	// the call without --insecure fails the handshake, which is otherwise logged
+	tlsServer.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+	expectedStdout := `
+		{
+		  "foo": {
+		    "name": "bar"
+		  }
+		}
+		`
+	testRunCmdNoParallel(
 		t,
 		newRootCommand("test"),
-		expectedExitCode,
+		0,
 		expectedStdout,
-		args...,
+		"curl",
+		strings.TrimPrefix(plaintextServer.URL, "http://"),
+		"--input",
+		filepath.Join("testdata", "services"),
+		"--plaintext",
+		"--method",
+		"a.v1.FooService/GetFoo",
+		"-d",
+		`{"name": "bar"}`,
+		"-H",
+		"X-Foo: bar, baz",
+	)
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		0,
+		expectedStdout,
+		"curl",
+		strings.TrimPrefix(tlsServer.URL, "https://"),
+		"--input",
+		filepath.Join("testdata", "services"),
+		"--insecure",
+		"--method",
+		"a.v1.FooService/GetFoo",
+		"-H",
+		"X-Foo: bar, baz",
+	)
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		1,
+		``,
+		"curl",
+		strings.TrimPrefix(tlsServer.URL, "https://"),
+		"--input",
+		filepath.Join("testdata", "services"),
+		"--method",
+		"a.v1.FooService/GetFoo",
+	)
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		1,
+		``,
+		"curl",
+		strings.TrimPrefix(plaintextServer.URL, "http://"),
+		"--input",
+		filepath.Join("testdata", "services"),
+		"--plaintext",
+		"--method",
+		"a.v1.FooService/WatchFoos",
 	)
 }
 
-func testRunProfile(t *testing.T, expectedExitCode int, expectedStdout string, args ...string) {
-	profileDirPath, err := ioutil.TempDir("", "")
+func TestFailCurlNoMethod(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"curl",
+		"localhost:8080",
+		"--input",
+		filepath.Join("testdata", "services"),
+	)
+}
+
+func TestExport(t *testing.T) {
+	t.Parallel()
+	tmpDirPath, err := ioutil.TempDir("", "")
 	require.NoError(t, err)
-	defer func() { assert.NoError(t, os.RemoveAll(profileDirPath)) }()
-	testRunCmd(
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDirPath)) }()
+	allDirPath := filepath.Join(tmpDirPath, "all")
+	testRunCmdNoParallel(
 		t,
 		newRootCommand("test"),
 		0,
 		``,
-		append(
-			args,
-			"--profile",
-			fmt.Sprintf("--profile-path=%s", profileDirPath),
-			"--profile-loops=1",
-			"--profile-type=cpu",
-		)...,
+		"export",
+		"--input",
+		filepath.Join("testdata", "export"),
+		"-o",
+		allDirPath,
+	)
+	assert.Equal(
+		t,
+		[]string{
+			"a/v1/a.proto",
+			"a/v1/c.proto",
+			"b/v1/b.proto",
+		},
+		testGetRelFilePaths(t, allDirPath),
+	)
+	expectedData, err := ioutil.ReadFile(filepath.Join("testdata", "export", "proto", "a", "v1", "a.proto"))
+	require.NoError(t, err)
+	data, err := ioutil.ReadFile(filepath.Join(allDirPath, "a", "v1", "a.proto"))
+	require.NoError(t, err)
+	assert.Equal(t, string(expectedData), string(data))
+
+	fileDirPath := filepath.Join(tmpDirPath, "file")
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		0,
+		``,
+		"export",
+		"--input",
+		filepath.Join("testdata", "export"),
+		"--file",
+		filepath.Join("testdata", "export", "proto", "a", "v1", "a.proto"),
+		"-o",
+		fileDirPath,
+	)
+	assert.Equal(
+		t,
+		[]string{
+			"a/v1/a.proto",
+			"b/v1/b.proto",
+		},
+		testGetRelFilePaths(t, fileDirPath),
+	)
+
+	// imports without a source are printed from their descriptors
+	servicesDirPath := filepath.Join(tmpDirPath, "services")
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		0,
+		``,
+		"export",
+		"--input",
+		filepath.Join("testdata", "services"),
+		"-o",
+		servicesDirPath,
+	)
+	assert.Equal(
+		t,
+		[]string{
+			"a/v1/a.proto",
+			"b/v1/b.proto",
+			"google/protobuf/empty.proto",
+		},
+		testGetRelFilePaths(t, servicesDirPath),
 	)
+	data, err = ioutil.ReadFile(filepath.Join(servicesDirPath, "google", "protobuf", "empty.proto"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "message Empty {")
 }
 
-func testRunCmd(t *testing.T, cmd *clicobra.Command, expectedExitCode int, expectedStdout string, args ...string) {
+func TestFailExportNoOutput(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"export",
+		"--input",
+		filepath.Join("testdata", "export"),
+	)
+}
+
+func TestDoc(t *testing.T) {
 	t.Parallel()
-	stdout := bytes.NewBuffer(nil)
-	stderr := bytes.NewBuffer(nil)
-	exitCode := clicobra.Run(
-		cmd,
-		"test",
-		clienv.NewEnv(
-			args,
-			nil,
-			stdout,
-			stderr,
-			nil,
-		),
+	tmpDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDirPath)) }()
+	templateFilePath := filepath.Join(tmpDirPath, "template.txt")
+	require.NoError(t, ioutil.WriteFile(templateFilePath, []byte(`{{range .Files}}{{range .Messages}}{{.FullName}}: {{.Description}}
+{{end}}{{end}}`), 0644))
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		0,
+		`
+		a.v1.GetFooRequest: GetFooRequest is the request for GetFoo.
+		a.v1.Foo: Foo is a foo.
+		`,
+		"doc",
+		"--input",
+		filepath.Join("testdata", "doc"),
+		"--template",
+		templateFilePath,
 	)
-	assert.Equal(t, expectedExitCode, exitCode, utilstring.TrimLines(stderr.String()))
-	if exitCode == expectedExitCode {
-		assert.Equal(t, utilstring.TrimLines(expectedStdout), utilstring.TrimLines(stdout.String()), utilstring.TrimLines(stderr.String()))
+	outputFilePath := filepath.Join(tmpDirPath, "doc.md")
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		0,
+		``,
+		"doc",
+		"--input",
+		filepath.Join("testdata", "doc"),
+		"-o",
+		outputFilePath,
+	)
+	data, err := ioutil.ReadFile(outputFilePath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "| GetFoo | [a.v1.GetFooRequest](#a_v1_GetFooRequest) | [a.v1.Foo](#a_v1_Foo) | GetFoo gets a Foo. |")
+	assert.Contains(t, string(data), "| kinds | [map<string, a.v1.Kind>](#a_v1_Kind) |  |  |")
+	assert.Contains(t, string(data), "| KIND_BIG | 1 | A big Foo. |")
+}
+
+func TestFailDocJSONTemplate(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"doc",
+		"--input",
+		filepath.Join("testdata", "doc"),
+		"--format",
+		"json",
+		"--template",
+		filepath.Join("testdata", "doc", "a", "v1", "a.proto"),
+	)
+}
+
+func TestInit(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		pathToContent    map[string]string
+		args             []string
+		stdin            string
+		expectedExitCode int
+		expectedConfig   string
+	}{
+		{
+			// roots are the directories the package directories are in, and hidden directories are skipped
+			pathToContent: map[string]string{
+				"proto/foo/v1/foo.proto":      `syntax = "proto3"; package foo.v1;`,
+				"proto/foo/v1/bar/bar.proto":  `syntax = "proto3"; package foo.v1.bar;`,
+				"vendor/google/type/a.proto":  "syntax = \"proto3\";\n\npackage google.type;\n",
+				".git/foo/v1/foo.proto":       `syntax = "proto3"; package foo.v1;`,
+				"other/not_a_proto_file.txt":  `package other;`,
+				"proto/foo/v1/foo_test.proto": `syntax = "proto3"; package foo.v1;`,
+			},
+			expectedConfig: `build:
+  roots:
+  - proto
+  - vendor
+lint:
+  use:
+  - DEFAULT
+breaking:
+  use:
+  - FILE
+`,
+		},
+		{
+			// a file whose package does not match its directory makes the directory the root
+			pathToContent: map[string]string{
+				"proto/foo/v1/foo.proto": `syntax = "proto3"; package foo.v1;`,
+				"bar/bar.proto":          `syntax = "proto3"; package other;`,
+			},
+			expectedConfig: `lint:
+  use:
+  - DEFAULT
+breaking:
+  use:
+  - FILE
+`,
+		},
+		{
+			pathToContent: map[string]string{
+				"proto/foo/v1/foo.proto": `syntax = "proto3"; package foo.v1;`,
+			},
+			args:  []string{"--interactive"},
+			stdin: "proto, vendor\nDEFAULT COMMENTS\n\n",
+			expectedConfig: `build:
+  roots:
+  - proto
+  - vendor
+lint:
+  use:
+  - DEFAULT
+  - COMMENTS
+breaking:
+  use:
+  - FILE
+`,
+		},
+		{
+			pathToContent: map[string]string{
+				"proto/foo/v1/foo.proto": `syntax = "proto3"; package foo.v1;`,
+			},
+			args:             []string{"--interactive"},
+			stdin:            "\nNOT_A_CHECKER\n",
+			expectedExitCode: 1,
+		},
+		{
+			pathToContent: map[string]string{
+				"buf.yaml":               "version: 1\n",
+				"proto/foo/v1/foo.proto": `syntax = "proto3"; package foo.v1;`,
+			},
+			expectedExitCode: 1,
+			expectedConfig:   "version: 1\n",
+		},
+		{
+			pathToContent: map[string]string{
+				"buf.yaml":               "version: 1\n",
+				"proto/foo/v1/foo.proto": `syntax = "proto3"; package foo.v1;`,
+			},
+			args: []string{"--force"},
+			expectedConfig: `build:
+  roots:
+  - proto
+lint:
+  use:
+  - DEFAULT
+breaking:
+  use:
+  - FILE
+`,
+		},
+	} {
+		tmpDirPath, err := ioutil.TempDir("", "")
+		require.NoError(t, err)
+		for path, content := range testCase.pathToContent {
+			filePath := filepath.Join(tmpDirPath, filepath.FromSlash(path))
+			require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+			require.NoError(t, ioutil.WriteFile(filePath, []byte(content), 0644))
+		}
+		stdout := bytes.NewBuffer(nil)
+		stderr := bytes.NewBuffer(nil)
+		exitCode := clicobra.Run(
+			newRootCommand("test"),
+			"test",
+			clienv.NewEnv(
+				append([]string{"init", tmpDirPath}, testCase.args...),
+				strings.NewReader(testCase.stdin),
+				stdout,
+				stderr,
+				nil,
+			),
+		)
+		assert.Equal(t, testCase.expectedExitCode, exitCode, utilstring.TrimLines(stderr.String()))
+		if testCase.expectedConfig != "" {
+			data, err := ioutil.ReadFile(filepath.Join(tmpDirPath, "buf.yaml"))
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedConfig, string(data))
+		}
+		assert.NoError(t, os.RemoveAll(tmpDirPath))
 	}
 }
+
+func TestImageLsPackages(t *testing.T) {
+	t.Parallel()
+	tmpDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDirPath)) }()
+	imageFilePath := filepath.Join(tmpDirPath, "image.bin")
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		0,
+		``,
+		"image",
+		"build",
+		"--source",
+		filepath.Join("testdata", "shards"),
+		"-o",
+		imageFilePath,
+	)
+	testRunCmdNoParallel(
+		t,
+		newRootCommand("test"),
+		0,
+		`
+		{"name":"a.v1","files":["a/v1/a.proto"]}
+		{"name":"b.v1","files":["b/v1/b.proto"]}
+		{"name":"c.v1","files":["c/v1/c.proto"]}
+		`,
+		"image",
+		"ls-packages",
+		"--image",
+		imageFilePath,
+		"--format",
+		"json",
+	)
+}
+
+func TestFailImageLsPackagesSource(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"image",
+		"ls-packages",
+		"--image",
+		filepath.Join("testdata", "shards"),
+	)
+}
+
+func TestFailImageLsPackagesNoImage(t *testing.T) {
+	testRun(
+		t,
+		1,
+		``,
+		"image",
+		"ls-packages",
+	)
+}
@@ -14,9 +14,28 @@ func newRootCommand(use string, options ...RootCommandOption) *clicobra.Command
 			newImageCmd(flags),
 			newCheckCmd(flags),
 			newLsFilesCmd(flags),
+			newLsOptionsCmd(flags),
+			newLsPackagesCmd(flags),
+			newLsServicesCmd(flags),
+			newLsMethodsCmd(flags),
+			newTestCmd(flags),
+			newQueryCmd(flags),
+			newGenerateCmd(flags),
+			newFormatCmd(flags),
+			newConvertCmd(flags),
+			newExportCmd(flags),
+			newCurlCmd(flags),
+			newDocCmd(flags),
+			newLspCmd(flags),
+			newInitCmd(flags),
+			newConfigCmd(flags),
+			newPushCmd(flags),
+			newPullCmd(flags),
 		},
 		BindFlags: flags.bindRootCommandFlags,
 	}
+	// the completion command completes the commands of the root command
+	rootCommand.SubCommands = append(rootCommand.SubCommands, newCompletionCmd(flags, rootCommand))
 	for _, option := range options {
 		option(rootCommand, flags)
 	}
@@ -29,6 +48,7 @@ func newImageCmd(flags *Flags) *clicobra.Command {
 		Short: "Work with Images and FileDescriptorSets.",
 		SubCommands: []*clicobra.Command{
 			newImageBuildCmd(flags),
+			newImageLsPackagesCmd(flags),
 		},
 	}
 }
@@ -38,19 +58,44 @@ func newImageBuildCmd(flags *Flags) *clicobra.Command {
 		Use:   "build",
 		Short: "Build all files from the input location  and output an Image or FileDescriptorSet.",
 		Args:  cobra.NoArgs,
-		Run:   flags.newRunFunc(imageBuild),
+		Run:   flags.newWatchRunFunc(imageBuild, imageBuildInputFlagName),
 		BindFlags: func(flagSet *pflag.FlagSet) {
 			flags.bindImageBuildInput(flagSet)
 			flags.bindImageBuildConfig(flagSet)
 			flags.bindImageBuildOutput(flagSet)
+			flags.bindImageBuildOutputFormat(flagSet)
+			flags.bindImageBuildWriteChecksum(flagSet)
 			flags.bindImageBuildAsFileDescriptorSet(flagSet)
 			flags.bindImageBuildExcludeImports(flagSet)
 			flags.bindImageBuildExcludeSourceInfo(flagSet)
+			flags.bindImageBuildNormalize(flagSet)
+			flags.bindWatch(flagSet)
+			flags.bindDependencyImages(flagSet)
+			flags.bindFileSet(flagSet)
+			flags.bindRequirePinned(flagSet)
+			flags.bindAllowSymlinks(flagSet)
+			flags.bindFollowSymlinks(flagSet)
+			flags.bindGitPureGo(flagSet)
+			flags.bindHTTP(flagSet)
 			flags.bindImageBuildErrorFormat(flagSet)
 		},
 	}
 }
 
+func newImageLsPackagesCmd(flags *Flags) *clicobra.Command {
+	return &clicobra.Command{
+		Use:   "ls-packages",
+		Short: "List all packages in the image with the files in each package.",
+		Long:  "All files of the image are listed, including imports if the image includes imports.",
+		Args:  cobra.NoArgs,
+		Run:   flags.newRunFunc(imageLsPackages),
+		BindFlags: func(flagSet *pflag.FlagSet) {
+			flags.bindImageLsPackagesImage(flagSet)
+			flags.bindImageLsPackagesFormat(flagSet)
+		},
+	}
+}
+
 func newCheckCmd(flags *Flags) *clicobra.Command {
 	return &clicobra.Command{
 		Use:   "check",
@@ -60,6 +105,8 @@ func newCheckCmd(flags *Flags) *clicobra.Command {
 			newCheckBreakingCmd(flags),
 			newCheckLsLintCheckersCmd(flags),
 			newCheckLsBreakingCheckersCmd(flags),
+			newCheckLsLintIgnoresCmd(flags),
+			newCheckDaemonCmd(flags),
 		},
 	}
 }
@@ -69,12 +116,28 @@ func newCheckLintCmd(flags *Flags) *clicobra.Command {
 		Use:   "lint",
 		Short: "Check that the input location passes lint checks.",
 		Args:  cobra.NoArgs,
-		Run:   flags.newRunFunc(checkLint),
+		Run:   flags.newWatchRunFunc(checkLint, checkLintInputFlagName),
 		BindFlags: func(flagSet *pflag.FlagSet) {
 			flags.bindCheckLintInput(flagSet)
 			flags.bindCheckLintConfig(flagSet)
+			flags.bindCheckLintChangedSince(flagSet)
+			flags.bindCheckLintCache(flagSet)
+			flags.bindDependencyImages(flagSet)
+			flags.bindFileSet(flagSet)
+			flags.bindRequirePinned(flagSet)
+			flags.bindAllowSymlinks(flagSet)
+			flags.bindFollowSymlinks(flagSet)
+			flags.bindGitPureGo(flagSet)
+			flags.bindHTTP(flagSet)
 			flags.bindCheckFiles(flagSet)
 			flags.bindCheckLintErrorFormat(flagSet)
+			flags.bindCheckErrorFormatTemplate(flagSet)
+			flags.bindCheckAnnotateAuthors(flagSet)
+			flags.bindCheckLintTUI(flagSet)
+			flags.bindCheckLintFix(flagSet)
+			flags.bindCheckLintWriteBaseline(flagSet)
+			flags.bindCheckRuleTiming(flagSet)
+			flags.bindWatch(flagSet)
 		},
 	}
 }
@@ -91,9 +154,28 @@ func newCheckBreakingCmd(flags *Flags) *clicobra.Command {
 			flags.bindCheckBreakingAgainstInput(flagSet)
 			flags.bindCheckBreakingAgainstConfig(flagSet)
 			flags.bindCheckBreakingLimitToInputFiles(flagSet)
+			flags.bindCheckBreakingAgainstReport(flagSet)
+			flags.bindCheckBreakingReportOutput(flagSet)
+			flags.bindCheckBreakingAgainstStamps(flagSet)
+			flags.bindCheckBreakingStampsOutput(flagSet)
+			flags.bindCheckBreakingExemptions(flagSet)
+			flags.bindCheckBreakingAgainstCache(flagSet)
+			flags.bindCheckBreakingSummary(flagSet)
+			flags.bindCheckBreakingExitCodeOnly(flagSet)
+			flags.bindCheckBreakingAllowIfEnv(flagSet)
 			flags.bindCheckBreakingExcludeImports(flagSet)
+			flags.bindDependencyImages(flagSet)
+			flags.bindFileSet(flagSet)
+			flags.bindRequirePinned(flagSet)
+			flags.bindAllowSymlinks(flagSet)
+			flags.bindFollowSymlinks(flagSet)
+			flags.bindGitPureGo(flagSet)
+			flags.bindHTTP(flagSet)
 			flags.bindCheckFiles(flagSet)
 			flags.bindCheckBreakingErrorFormat(flagSet)
+			flags.bindCheckErrorFormatTemplate(flagSet)
+			flags.bindCheckAnnotateAuthors(flagSet)
+			flags.bindCheckRuleTiming(flagSet)
 		},
 	}
 }
@@ -108,7 +190,9 @@ func newCheckLsLintCheckersCmd(flags *Flags) *clicobra.Command {
 			flags.bindCheckLsCheckersConfig(flagSet)
 			flags.bindCheckLsCheckersAll(flagSet)
 			flags.bindCheckLsCheckersCategories(flagSet)
+			flags.bindCheckLsCheckersIDs(flagSet)
 			flags.bindCheckLsCheckersFormat(flagSet)
+			flags.bindCheckLsCheckersDoc(flagSet)
 		},
 	}
 }
@@ -123,7 +207,44 @@ func newCheckLsBreakingCheckersCmd(flags *Flags) *clicobra.Command {
 			flags.bindCheckLsCheckersConfig(flagSet)
 			flags.bindCheckLsCheckersAll(flagSet)
 			flags.bindCheckLsCheckersCategories(flagSet)
+			flags.bindCheckLsCheckersIDs(flagSet)
 			flags.bindCheckLsCheckersFormat(flagSet)
+			flags.bindCheckLsCheckersDoc(flagSet)
+		},
+	}
+}
+
+func newCheckLsLintIgnoresCmd(flags *Flags) *clicobra.Command {
+	return &clicobra.Command{
+		Use:   "ls-lint-ignores",
+		Short: "List lint comment ignores with their git author and age.",
+		Args:  cobra.NoArgs,
+		Run:   flags.newRunFunc(checkLsLintIgnores),
+		BindFlags: func(flagSet *pflag.FlagSet) {
+			flags.bindCheckLsLintIgnoresInput(flagSet)
+			flags.bindCheckLsLintIgnoresConfig(flagSet)
+			flags.bindCheckLsLintIgnoresFormat(flagSet)
+		},
+	}
+}
+
+func newCheckDaemonCmd(flags *Flags) *clicobra.Command {
+	return &clicobra.Command{
+		Use:   "daemon",
+		Short: "Lint the input directory whenever a file changes and push the results to a client.",
+		Long: `Lint violations are sent as JSON-RPC 2.0 textDocument/publishDiagnostics notifications
+over stdout, one message per line, in the same shape as the Language Server Protocol.
+The daemon stops when stdin is closed or when a shutdown request is received.
+
+The daemon also stops when --timeout expires, so --timeout=0 should usually be set.`,
+		Args: cobra.NoArgs,
+		Run:  flags.newRunFunc(checkDaemon),
+		BindFlags: func(flagSet *pflag.FlagSet) {
+			flags.bindCheckDaemonInput(flagSet)
+			flags.bindCheckDaemonConfig(flagSet)
+			flags.bindCheckDaemonClient(flagSet)
+			flags.bindCheckDaemonPollInterval(flagSet)
+			flags.bindCheckDaemonDebugAddress(flagSet)
 		},
 	}
 }
@@ -137,6 +258,453 @@ func newLsFilesCmd(flags *Flags) *clicobra.Command {
 		BindFlags: func(flagSet *pflag.FlagSet) {
 			flags.bindLsFilesInput(flagSet)
 			flags.bindLsFilesConfig(flagSet)
+			flags.bindLsFilesFormat(flagSet)
+		},
+	}
+}
+
+func newLsOptionsCmd(flags *Flags) *clicobra.Command {
+	return &clicobra.Command{
+		Use:   "ls-options",
+		Short: "List the custom options set in the input location with their use counts and defining files.",
+		Long: `Each custom option is printed with the number of options it is set on, the file
+that defines it, and the files that set it. Only options set in the input location itself
+are counted, but options are resolved against its imports.`,
+		Args: cobra.NoArgs,
+		Run:  flags.newRunFunc(lsOptions),
+		BindFlags: func(flagSet *pflag.FlagSet) {
+			flags.bindLsOptionsInput(flagSet)
+			flags.bindLsOptionsConfig(flagSet)
+			flags.bindLsOptionsFormat(flagSet)
+			flags.bindDependencyImages(flagSet)
+		},
+	}
+}
+
+func newLsPackagesCmd(flags *Flags) *clicobra.Command {
+	return &clicobra.Command{
+		Use:   "ls-packages",
+		Short: "List all packages for the source location with the files in each package.",
+		Long:  "Imports are not listed. Use buf image ls-packages to list the packages of an image.",
+		Args:  cobra.NoArgs,
+		Run:   flags.newRunFunc(lsPackages),
+		BindFlags: func(flagSet *pflag.FlagSet) {
+			flags.bindLsPackagesInput(flagSet)
+			flags.bindLsPackagesConfig(flagSet)
+			flags.bindLsPackagesFormat(flagSet)
+		},
+	}
+}
+
+func newLsServicesCmd(flags *Flags) *clicobra.Command {
+	return &clicobra.Command{
+		Use:   "ls-services",
+		Short: "List all services for the input location with the number of methods of each service.",
+		Long:  "Services of imports are not listed. Use buf ls-methods to list the methods of each service.",
+		Args:  cobra.NoArgs,
+		Run:   flags.newRunFunc(lsServices),
+		BindFlags: func(flagSet *pflag.FlagSet) {
+			flags.bindLsServicesInput(flagSet)
+			flags.bindLsServicesConfig(flagSet)
+			flags.bindLsServicesFormat(flagSet)
+		},
+	}
+}
+
+func newLsMethodsCmd(flags *Flags) *clicobra.Command {
+	return &clicobra.Command{
+		Use:   "ls-methods",
+		Short: "List all methods of all services for the input location with their request and response types.",
+		Long: `Methods of services of imports are not listed.
+
+The streaming column of the text format is one of unary, client, server, or bidi.`,
+		Args: cobra.NoArgs,
+		Run:  flags.newRunFunc(lsMethods),
+		BindFlags: func(flagSet *pflag.FlagSet) {
+			flags.bindLsMethodsInput(flagSet)
+			flags.bindLsMethodsConfig(flagSet)
+			flags.bindLsMethodsFormat(flagSet)
+		},
+	}
+}
+
+func newTestCmd(flags *Flags) *clicobra.Command {
+	return &clicobra.Command{
+		Use:   "test",
+		Short: "Run the assertions in the tests section of the config against the input location.",
+		Long: `Each test has a name and a set of assertions, optionally limited to a package:
+
+tests:
+  - name: billing
+    package: acme.v1
+    contains_services:
+      - Billing
+    contains_messages:
+      - Invoice
+      - Invoice.LineItem
+  - name: small-messages
+    max_message_fields: 64
+
+Names are relative to the package if set. The assertions are contains_services,
+contains_messages, contains_enums, max_message_fields, max_service_rpcs, and
+max_enum_values. Imports are not tested. Exits with a non-zero exit code if any test fails.`,
+		Args: cobra.NoArgs,
+		Run:  flags.newRunFunc(test),
+		BindFlags: func(flagSet *pflag.FlagSet) {
+			flags.bindTestInput(flagSet)
+			flags.bindTestConfig(flagSet)
+			flags.bindTestFormat(flagSet)
+		},
+	}
+}
+
+func newQueryCmd(flags *Flags) *clicobra.Command {
+	return &clicobra.Command{
+		Use:   "query <query>",
+		Short: "Print the elements of the input location that match a query.",
+		Long: `The query selects a kind of element, optionally filtered by an expression over the
+attributes of the elements, for example to find bytes fields without a comment about their size:
+
+buf query 'fields where type == "bytes" && !(comment contains "size")'
+
+The kinds are files, messages, fields, enums, enum_values, services, and rpcs. All kinds other
+than files have the attributes file, package, name, full_name, and comment. Additionally:
+
+files:       file, package, syntax, go_package, java_package
+messages:    nested, field_count
+fields:      message, number, type, type_name, label, json_name, oneof
+enums:       value_count
+enum_values: enum, number
+services:    rpc_count
+rpcs:        service, input_type, output_type, client_streaming, server_streaming
+
+Expressions support the operators ==, !=, <, <=, >, >=, contains, startsWith, endsWith,
+matches, !, &&, ||, and parentheses. Values are strings in double quotes, integers, true,
+and false. The right operand of matches is a regular expression. Imports are not queried.`,
+		Args: cobra.ExactArgs(1),
+		Run:  flags.newRunFunc(query),
+		BindFlags: func(flagSet *pflag.FlagSet) {
+			flags.bindQueryInput(flagSet)
+			flags.bindQueryConfig(flagSet)
+			flags.bindQueryFormat(flagSet)
 		},
 	}
 }
+
+func newGenerateCmd(flags *Flags) *clicobra.Command {
+	return &clicobra.Command{
+		Use:   "generate",
+		Short: "Generate code with protoc plugins for the input location.",
+		Long: `The plugins are configured in a generation template, by default buf.gen.yaml:
+
+plugins:
+  - name: go
+    out: gen/go
+    opt: paths=source_relative
+  - path: bin/protoc-gen-foo
+    out: gen/foo
+
+A plugin with a name runs protoc-gen-NAME from the PATH, and a plugin with a path runs
+that executable. Each plugin is sent a CodeGeneratorRequest with the files of the input
+location as the files to generate and opt as the parameter, and the generated files are
+written to the out directory. Relative paths are relative to the current directory.
+Imports are included in the request but code is not generated for them.
+
+Plugins run in order, and insertion points are supported for plugins with the same out
+directory. Files are only written once all plugins succeed.`,
+		Args: cobra.NoArgs,
+		Run:  flags.newRunFunc(generate),
+		BindFlags: func(flagSet *pflag.FlagSet) {
+			flags.bindGenerateInput(flagSet)
+			flags.bindGenerateConfig(flagSet)
+			flags.bindGenerateTemplate(flagSet)
+		},
+	}
+}
+
+func newFormatCmd(flags *Flags) *clicobra.Command {
+	return &clicobra.Command{
+		Use:   "format",
+		Short: "Format the Protobuf files of the source directory in a canonical style.",
+		Long: `The canonical style uses two spaces of indentation, puts syntax, package, imports
+sorted by path, and options sorted by name at the top of each file, puts options
+sorted by name at the top of each message, enum, and service, and uses consistent
+spacing within declarations. Comments and line breaks within declarations are kept.
+
+By default, the formatted files are printed. Use --write to format the files in place,
+--diff to print diffs of the files that are not formatted, and --exit-code to check
+that all files are formatted in CI.`,
+		Args: cobra.NoArgs,
+		Run:  flags.newRunFunc(format),
+		BindFlags: func(flagSet *pflag.FlagSet) {
+			flags.bindFormatInput(flagSet)
+			flags.bindFormatConfig(flagSet)
+			flags.bindFormatWrite(flagSet)
+			flags.bindFormatDiff(flagSet)
+			flags.bindFormatExitCode(flagSet)
+		},
+	}
+}
+
+func newConvertCmd(flags *Flags) *clicobra.Command {
+	return &clicobra.Command{
+		Use:   "convert",
+		Short: "Convert a message between the bin, json, and txt formats using the types of the input location.",
+		Long: `The message is read from --from and written to --to, by default from stdin and to stdout as json:
+
+buf convert --type foo.v1.Bar --from payload.bin --to -#format=json
+
+Imports of the input location are included, so any message type that the files of
+the input location can reference can be converted.`,
+		Args: cobra.NoArgs,
+		Run:  flags.newRunFunc(convert),
+		BindFlags: func(flagSet *pflag.FlagSet) {
+			flags.bindConvertInput(flagSet)
+			flags.bindConvertConfig(flagSet)
+			flags.bindConvertType(flagSet)
+			flags.bindConvertFrom(flagSet)
+			flags.bindConvertTo(flagSet)
+		},
+	}
+}
+
+func newExportCmd(flags *Flags) *clicobra.Command {
+	return &clicobra.Command{
+		Use:   "export",
+		Short: "Export the files of the source location and all files they import.",
+		Long: `The files are written with paths relative to the roots, so that the output is a single
+self-contained root that can be used with protoc -I or an IDE.
+
+Files are copied exactly from the source location or the dependencies of the configuration.
+Imports without a source, such as the well-known types or the files of --dep-image images,
+are printed from their descriptors.`,
+		Args: cobra.NoArgs,
+		Run:  flags.newRunFunc(export),
+		BindFlags: func(flagSet *pflag.FlagSet) {
+			flags.bindExportInput(flagSet)
+			flags.bindExportConfig(flagSet)
+			flags.bindExportOutput(flagSet)
+			flags.bindExportFiles(flagSet)
+			flags.bindDependencyImages(flagSet)
+		},
+	}
+}
+
+func newCurlCmd(flags *Flags) *clicobra.Command {
+	return &clicobra.Command{
+		Use:   "curl address",
+		Short: "Call a gRPC method using the types of the input location.",
+		Long: `The address is host:port. The method and its request and response types are read from
+the input location, so the server does not need to support server reflection:
+
+buf curl localhost:8080 --plaintext --method foo.v1.FooService/GetFoo -d '{"name": "bar"}'
+
+Each response message is printed as JSON. Imports of the input location are included.`,
+		Args: cobra.ExactArgs(1),
+		Run:  flags.newRunFunc(curl),
+		BindFlags: func(flagSet *pflag.FlagSet) {
+			flags.bindCurlInput(flagSet)
+			flags.bindCurlConfig(flagSet)
+			flags.bindCurlMethod(flagSet)
+			flags.bindCurlData(flagSet)
+			flags.bindCurlHeaders(flagSet)
+			flags.bindCurlPlaintext(flagSet)
+			flags.bindCurlInsecure(flagSet)
+			flags.bindCurlCACert(flagSet)
+			flags.bindCurlCert(flagSet)
+			flags.bindCurlKey(flagSet)
+			flags.bindCurlServerName(flagSet)
+		},
+	}
+}
+
+func newDocCmd(flags *Flags) *clicobra.Command {
+	return &clicobra.Command{
+		Use:   "doc",
+		Short: "Render documentation for the files of the input location.",
+		Long: `The documentation includes the services, messages, and enums of each file, with their comments.
+Imports are not documented.
+
+The html and markdown formats are rendered with a default template that can be replaced with --template.
+Templates are executed with the same data that is printed with --format=json, and can use the functions
+anchor, which returns the anchor for a full name or file name, and oneline, which joins the lines of
+a comment for markdown tables.`,
+		Args: cobra.NoArgs,
+		Run:  flags.newRunFunc(doc),
+		BindFlags: func(flagSet *pflag.FlagSet) {
+			flags.bindDocInput(flagSet)
+			flags.bindDocConfig(flagSet)
+			flags.bindDocFormat(flagSet)
+			flags.bindDocTemplate(flagSet)
+			flags.bindDocOutput(flagSet)
+		},
+	}
+}
+
+func newLspCmd(flags *Flags) *clicobra.Command {
+	return &clicobra.Command{
+		Use:   "lsp",
+		Short: "Run a Language Server Protocol server for the input directory over stdin and stdout.",
+		Long: `Build errors and lint violations are published as diagnostics when the client is initialized and whenever a file is opened, changed, saved, or closed.
+The contents of the files open in the client are checked, so unsaved changes are checked as they are made.
+Go to definition and hover are supported for the messages and enums referenced by fields and methods, including those defined in imports.
+Definitions and hovers use the last successful build, so positions are those of the files as of the last change that built.
+
+The server stops when the client sends the exit notification or closes stdin.
+The server also stops when --timeout expires, so --timeout=0 should usually be set.`,
+		Args: cobra.NoArgs,
+		Run:  flags.newRunFunc(lsp),
+		BindFlags: func(flagSet *pflag.FlagSet) {
+			flags.bindLspInput(flagSet)
+			flags.bindLspConfig(flagSet)
+		},
+	}
+}
+
+func newInitCmd(flags *Flags) *clicobra.Command {
+	return &clicobra.Command{
+		Use:   "init [dir]",
+		Short: "Write a starter buf.yaml with roots inferred from the .proto files of the directory.",
+		Long: `If no argument is given, the current directory is used.
+
+The root of each .proto file is the directory its package directory is in, such as proto
+for proto/foo/v1/foo.proto with package foo.v1. If any file does not follow this layout,
+the directory itself is the root and no roots are written. Hidden directories are skipped.
+
+The config uses the default lint and breaking categories, which are written out so they
+can be edited. With --interactive, the roots and the categories or checkers are prompted for.`,
+		Args: cobra.MaximumNArgs(1),
+		Run:  flags.newRunFunc(initConfig),
+		BindFlags: func(flagSet *pflag.FlagSet) {
+			flags.bindInitInteractive(flagSet)
+			flags.bindInitForce(flagSet)
+		},
+	}
+}
+
+func newConfigCmd(flags *Flags) *clicobra.Command {
+	return &clicobra.Command{
+		Use:   "config",
+		Short: "Work with configuration.",
+		SubCommands: []*clicobra.Command{
+			newConfigDiffCmd(flags),
+			newConfigEffectiveCmd(flags),
+			newConfigMigrateCmd(flags),
+		},
+	}
+}
+
+func newPushCmd(flags *Flags) *clicobra.Command {
+	return &clicobra.Command{
+		Use:   "push module://remote/owner/name:version",
+		Short: "Build the source and push the image and config as a version of a module.",
+		Long: `The image includes imports and source info. The lint and breaking sections of the config are
+pushed with the image, so that lint and breaking change checks of the module use the same config.
+
+The module can then be used by other repositories as an input or with --dep-image:
+
+buf push module://buf.example.com/acme/weather:v1.0.0
+buf check breaking --against-input module://buf.example.com/acme/weather:v1.0.0
+
+The registry is accessed at https://remote, or http://remote for localhost.
+If $BUF_REGISTRY_TOKEN has a token for the remote, it is sent as a bearer token. $BUF_REGISTRY_TOKEN
+is a comma-separated list of token@remote, such as token@buf.example.com, so that tokens are never
+sent to other registries.
+Versions cannot be changed once pushed.`,
+		Args: cobra.ExactArgs(1),
+		Run:  flags.newRunFunc(push),
+		BindFlags: func(flagSet *pflag.FlagSet) {
+			flags.bindPushSource(flagSet)
+			flags.bindPushSourceConfig(flagSet)
+			flags.bindDependencyImages(flagSet)
+			flags.bindHTTP(flagSet)
+		},
+	}
+}
+
+func newPullCmd(flags *Flags) *clicobra.Command {
+	return &clicobra.Command{
+		Use:   "pull module://remote/owner/name:version",
+		Short: "Pull the image and config of a version of a module.",
+		Long: `Modules can also be used directly as inputs with module://remote/owner/name:version.
+Pull writes the module to local files, for example to check them in or to use them without the registry:
+
+buf pull module://buf.example.com/acme/weather:v1.0.0 -o weather.bin --config-output weather.yaml
+
+The registry is accessed at https://remote, or http://remote for localhost.
+If $BUF_REGISTRY_TOKEN has a token for the remote, it is sent as a bearer token. $BUF_REGISTRY_TOKEN
+is a comma-separated list of token@remote, such as token@buf.example.com, so that tokens are never
+sent to other registries.`,
+		Args: cobra.ExactArgs(1),
+		Run:  flags.newRunFunc(pull),
+		BindFlags: func(flagSet *pflag.FlagSet) {
+			flags.bindPullOutput(flagSet)
+			flags.bindPullConfigOutput(flagSet)
+			flags.bindHTTP(flagSet)
+		},
+	}
+}
+
+func newCompletionCmd(flags *Flags, rootCommand *clicobra.Command) *clicobra.Command {
+	return &clicobra.Command{
+		Use:   "completion bash|zsh|fish|powershell",
+		Short: "Print a shell completion script.",
+		Long: `Commands, flags, and the values of flags are completed, including checker categories and ids, and the formats of inputs after #.
+
+For bash, add the following to ~/.bashrc:
+
+  source <(buf completion bash)
+
+For zsh, write the script to a file named _buf in a directory in $fpath.
+For fish, write the script to ~/.config/fish/completions/buf.fish.
+For powershell, add the following to your profile:
+
+  buf completion powershell | Out-String | Invoke-Expression`,
+		Args: cobra.MinimumNArgs(1),
+		Run:  flags.newRunFunc(newCompletionRunFunc(rootCommand)),
+	}
+}
+
+func newConfigEffectiveCmd(flags *Flags) *clicobra.Command {
+	return &clicobra.Command{
+		Use:   "effective [config]",
+		Short: "Print the effective configuration, with the source of each value.",
+		Long: `The argument is a configuration file or data, in the same format as --input-config.
+If no argument is given, buf.yaml in the current directory is used.
+
+The lint configuration includes the values of the configuration it extends with lint.extends, if any.
+Each value has a comment with the configuration that set it, or "default" for the checkers used if use is not set.`,
+		Args: cobra.MaximumNArgs(1),
+		Run:  flags.newRunFunc(configEffective),
+	}
+}
+
+func newConfigDiffCmd(flags *Flags) *clicobra.Command {
+	return &clicobra.Command{
+		Use:   "diff old new",
+		Short: "Print the differences in the effective lint, breaking, and build settings between two configurations.",
+		Long: `Both arguments are configuration files or data, in the same format as --input-config.
+
+Checkers are compared after resolving categories, so only checkers that are actually enabled or disabled are printed.`,
+		Args: cobra.ExactArgs(2),
+		Run:  flags.newRunFunc(configDiff),
+		BindFlags: func(flagSet *pflag.FlagSet) {
+			flags.bindConfigDiffFormat(flagSet)
+		},
+	}
+}
+
+func newConfigMigrateCmd(flags *Flags) *clicobra.Command {
+	return &clicobra.Command{
+		Use:   "migrate [file]",
+		Short: "Print a buf.yaml migrated from a prototool or protolint configuration.",
+		Long: `The argument is the configuration file to migrate. If no argument is given, the first of
+prototool.yaml, prototool.json, .protolint.yaml, .protolint.yml, protolint.yaml, and protolint.yml
+that exists in the current directory is used.
+
+Lint rules are migrated to the equivalent checkers, and settings that cannot be migrated exactly,
+such as rules without an equivalent checker, are printed to stderr.`,
+		Args: cobra.MaximumNArgs(1),
+		Run:  flags.newRunFunc(configMigrate),
+	}
+}
@@ -0,0 +1,379 @@
+package buf
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/buf/bufcheck"
+	"github.com/bufbuild/buf/internal/buf/bufcheck/bufbreaking"
+	"github.com/bufbuild/buf/internal/buf/bufcheck/buflint"
+	"github.com/bufbuild/cli/clicobra"
+	"github.com/bufbuild/cli/clienv"
+	"github.com/spf13/pflag"
+	"go.uber.org/zap"
+)
+
+// completeArg is the first argument to the completion command that the
+// completion scripts use to get the completions for the words on the command line.
+const completeArg = "__complete"
+
+var (
+	completionShellToScriptTemplate = map[string]string{
+		"bash":       bashCompletionScriptTemplate,
+		"zsh":        zshCompletionScriptTemplate,
+		"fish":       fishCompletionScriptTemplate,
+		"powershell": powershellCompletionScriptTemplate,
+	}
+	completionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+	// flagUsageValuesRegexp matches the values listed in the usage of a flag,
+	// such as "Must be one of [text,json]". If the first group matches, the
+	// values are input formats, such as "Must be one of format [bin,json]".
+	flagUsageValuesRegexp = regexp.MustCompile(`one of (format )?\[([^\]]*)\]`)
+	nonIdentifierRegexp   = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+	// flagNameToGetCompletions are the flags whose values are completed
+	// with a function of the names of the commands on the command line.
+	flagNameToGetCompletions = map[string]func([]string) ([]string, error){
+		"category": getCheckerCategoryCompletions,
+		"checker":  getCheckerIDCompletions,
+	}
+)
+
+// newCompletionRunFunc returns the run function for the completion command.
+//
+// This takes the root command so that the completions are those of the
+// command tree that is actually run.
+func newCompletionRunFunc(
+	rootCommand *clicobra.Command,
+) func(context.Context, clienv.Env, *Flags, *zap.Logger) error {
+	return func(
+		ctx context.Context,
+		cliEnv clienv.Env,
+		flags *Flags,
+		logger *zap.Logger,
+	) (retErr error) {
+		args := cliEnv.Args()
+		if args[0] == completeArg {
+			if len(args) < 2 {
+				return fmt.Errorf("%s requires the index of the word to complete", completeArg)
+			}
+			index, err := strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("%s: invalid index %q", completeArg, args[1])
+			}
+			completions, err := getCompletions(rootCommand, args[2:], index)
+			if err != nil {
+				return err
+			}
+			for _, completion := range completions {
+				if _, err := fmt.Fprintln(cliEnv.Stdout(), completion); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if len(args) != 1 {
+			return fmt.Errorf("accepts 1 arg, received %d", len(args))
+		}
+		scriptTemplate, ok := completionShellToScriptTemplate[args[0]]
+		if !ok {
+			return fmt.Errorf("unknown shell %q, must be one of [%s]", args[0], strings.Join(completionShells, ","))
+		}
+		replacer := strings.NewReplacer(
+			"{{name}}", rootCommand.Use,
+			"{{function}}", nonIdentifierRegexp.ReplaceAllString(rootCommand.Use, "_"),
+		)
+		_, err := replacer.WriteString(cliEnv.Stdout(), scriptTemplate)
+		return err
+	}
+}
+
+// getCompletions gets the completions for the word at the index in the words
+// after the root command.
+//
+// If the index is the number of words, the word to complete is empty. This
+// is so that scripts do not need to pass empty arguments, which some shells drop.
+//
+// No completions means that the shell should complete files.
+func getCompletions(rootCommand *clicobra.Command, words []string, index int) ([]string, error) {
+	if index < 0 || index > len(words) {
+		return nil, fmt.Errorf("%s: index %d out of range", completeArg, index)
+	}
+	toComplete := ""
+	if index < len(words) {
+		toComplete = words[index]
+	}
+	command := rootCommand
+	commandNames := []string{}
+	flagSet := newCompletionFlagSet(command)
+	var positionalArgs []string
+	var valueFlag *pflag.Flag
+	for _, word := range words[:index] {
+		if valueFlag != nil {
+			valueFlag = nil
+			continue
+		}
+		if word == "--" || !strings.HasPrefix(word, "-") {
+			if subCommand := getSubCommand(command, word); subCommand != nil && len(positionalArgs) == 0 {
+				command = subCommand
+				commandNames = append(commandNames, getCommandName(subCommand))
+				flagSet.AddFlagSet(newCompletionFlagSet(subCommand))
+			} else {
+				positionalArgs = append(positionalArgs, word)
+			}
+			continue
+		}
+		if flag := lookupCompletionFlag(flagSet, word); flag != nil && flag.NoOptDefVal == "" && !strings.Contains(word, "=") {
+			valueFlag = flag
+		}
+	}
+	if valueFlag != nil {
+		return getFlagValueCompletions(valueFlag, commandNames, "", toComplete)
+	}
+	if strings.HasPrefix(toComplete, "--") && strings.Contains(toComplete, "=") {
+		split := strings.SplitN(toComplete, "=", 2)
+		flag := flagSet.Lookup(strings.TrimPrefix(split[0], "--"))
+		if flag == nil {
+			return nil, nil
+		}
+		return getFlagValueCompletions(flag, commandNames, split[0]+"=", split[1])
+	}
+	if strings.HasPrefix(toComplete, "-") {
+		completions := []string{"--help"}
+		flagSet.VisitAll(func(flag *pflag.Flag) {
+			if flag.Hidden || flag.Deprecated != "" {
+				return
+			}
+			completions = append(completions, "--"+flag.Name)
+		})
+		return filterCompletions(completions, toComplete), nil
+	}
+	if len(positionalArgs) == 0 && len(command.SubCommands) > 0 {
+		completions := make([]string, 0, len(command.SubCommands))
+		for _, subCommand := range command.SubCommands {
+			completions = append(completions, getCommandName(subCommand))
+		}
+		return filterCompletions(completions, toComplete), nil
+	}
+	if len(commandNames) == 1 && commandNames[0] == "completion" && len(positionalArgs) == 0 {
+		return filterCompletions(completionShells, toComplete), nil
+	}
+	return nil, nil
+}
+
+// getFlagValueCompletions gets the completions for the value of the flag.
+//
+// The prefix is prepended to each completion, and is non-empty if the flag
+// and the value are in the same word.
+func getFlagValueCompletions(
+	flag *pflag.Flag,
+	commandNames []string,
+	prefix string,
+	toComplete string,
+) ([]string, error) {
+	// slice flags take comma-separated values, so complete the last value
+	if strings.HasSuffix(flag.Value.Type(), "Slice") {
+		if commaIndex := strings.LastIndex(toComplete, ","); commaIndex >= 0 {
+			prefix += toComplete[:commaIndex+1]
+			toComplete = toComplete[commaIndex+1:]
+		}
+	}
+	var values []string
+	if getCompletions, ok := flagNameToGetCompletions[flag.Name]; ok {
+		var err error
+		values, err = getCompletions(commandNames)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, match := range flagUsageValuesRegexp.FindAllStringSubmatch(flag.Usage, -1) {
+		// input formats are only completed after the # of an input
+		// such as path/to/file#format=bin, otherwise files are completed
+		if match[1] != "" {
+			hashIndex := strings.Index(toComplete, "#")
+			if hashIndex < 0 {
+				continue
+			}
+			for _, format := range strings.Split(match[2], ",") {
+				values = append(values, toComplete[:hashIndex]+"#format="+format)
+			}
+			continue
+		}
+		values = append(values, strings.Split(match[2], ",")...)
+	}
+	completions := filterCompletions(values, toComplete)
+	for i, completion := range completions {
+		completions[i] = prefix + completion
+	}
+	return completions, nil
+}
+
+func getCheckerCategoryCompletions(commandNames []string) ([]string, error) {
+	checkers, err := getCompletionCheckers(commandNames)
+	if err != nil {
+		return nil, err
+	}
+	var categories []string
+	seenCategories := make(map[string]struct{})
+	for _, checker := range checkers {
+		for _, category := range checker.Categories() {
+			if _, ok := seenCategories[category]; !ok {
+				seenCategories[category] = struct{}{}
+				categories = append(categories, category)
+			}
+		}
+	}
+	return categories, nil
+}
+
+func getCheckerIDCompletions(commandNames []string) ([]string, error) {
+	checkers, err := getCompletionCheckers(commandNames)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(checkers))
+	for _, checker := range checkers {
+		ids = append(ids, checker.ID())
+	}
+	return ids, nil
+}
+
+// getCompletionCheckers gets all the breaking checkers for breaking commands,
+// and all the lint checkers otherwise.
+func getCompletionCheckers(commandNames []string) ([]bufcheck.Checker, error) {
+	for _, commandName := range commandNames {
+		if strings.Contains(commandName, "breaking") {
+			return bufbreaking.GetAllCheckers()
+		}
+	}
+	return buflint.GetAllCheckers()
+}
+
+// newCompletionFlagSet returns a new flag set with the flags of the command.
+//
+// This binds the flags of the command again, which is fine as the flags
+// are bound with the same defaults when the command is built.
+func newCompletionFlagSet(command *clicobra.Command) *pflag.FlagSet {
+	flagSet := pflag.NewFlagSet(command.Use, pflag.ContinueOnError)
+	if command.BindFlags != nil {
+		command.BindFlags(flagSet)
+	}
+	return flagSet
+}
+
+// lookupCompletionFlag looks up the flag for a word such as --flag, --flag=value, or -f.
+func lookupCompletionFlag(flagSet *pflag.FlagSet, word string) *pflag.Flag {
+	if strings.HasPrefix(word, "--") {
+		return flagSet.Lookup(strings.SplitN(strings.TrimPrefix(word, "--"), "=", 2)[0])
+	}
+	if len(word) == 2 {
+		return flagSet.ShorthandLookup(word[1:])
+	}
+	return nil
+}
+
+func getSubCommand(command *clicobra.Command, name string) *clicobra.Command {
+	for _, subCommand := range command.SubCommands {
+		if getCommandName(subCommand) == name {
+			return subCommand
+		}
+	}
+	return nil
+}
+
+// getCommandName gets the name of the command, which is the first word of Use.
+func getCommandName(command *clicobra.Command) string {
+	return strings.Fields(command.Use)[0]
+}
+
+// filterCompletions filters the completions to those that start with the
+// word to complete, removing duplicates.
+func filterCompletions(completions []string, toComplete string) []string {
+	var filtered []string
+	seenCompletions := make(map[string]struct{})
+	for _, completion := range completions {
+		if _, ok := seenCompletions[completion]; ok {
+			continue
+		}
+		seenCompletions[completion] = struct{}{}
+		if strings.HasPrefix(completion, toComplete) {
+			filtered = append(filtered, completion)
+		}
+	}
+	return filtered
+}
+
+// The completion scripts call the completion command with __complete, the
+// index of the word to complete, and the words after the command name,
+// and complete files if there are no completions.
+
+const bashCompletionScriptTemplate = `# bash completion for {{name}}
+
+_{{function}}_complete() {
+  local line="${COMP_LINE:0:$COMP_POINT}"
+  local -a words
+  read -r -a words <<< "$line"
+  local index=$((${#words[@]} - 1))
+  local cur=""
+  if [[ "$line" =~ [[:space:]]$ ]]; then
+    index=${#words[@]}
+  else
+    cur="${words[$index]}"
+  fi
+  local IFS=$'\n'
+  COMPREPLY=($("${words[0]}" completion __complete "$((index - 1))" -- "${words[@]:1}" 2>/dev/null))
+  # bash splits words on = and :, so remove the part of the word before them
+  if [[ "$cur" == *[=:]* ]]; then
+    local prefix="${cur%"${cur##*[=:]}"}"
+    COMPREPLY=("${COMPREPLY[@]#"$prefix"}")
+  fi
+}
+
+complete -o default -F _{{function}}_complete {{name}}
+`
+
+const zshCompletionScriptTemplate = `#compdef {{name}}
+
+_{{function}}() {
+  local -a completions
+  completions=("${(@f)$("${words[1]}" completion __complete "$((CURRENT - 2))" -- "${(@)words[2,$CURRENT]}" 2>/dev/null)}")
+  if [[ -z "${completions[1]}" ]]; then
+    _files
+    return
+  fi
+  compadd -- "${completions[@]}"
+}
+
+compdef _{{function}} {{name}}
+`
+
+const fishCompletionScriptTemplate = `# fish completion for {{name}}
+
+function __{{function}}_complete
+    set -l args (commandline -opc)
+    set -e args[1]
+    {{name}} completion __complete (count $args) -- $args (commandline -ct) 2>/dev/null
+end
+
+complete -c {{name}} -f -n 'test (count (__{{function}}_complete)) -gt 0' -a '(__{{function}}_complete)'
+complete -c {{name}} -F -n 'test (count (__{{function}}_complete)) -eq 0'
+`
+
+const powershellCompletionScriptTemplate = `# powershell completion for {{name}}
+
+Register-ArgumentCompleter -Native -CommandName '{{name}}' -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements | Select-Object -Skip 1 | Where-Object { $_.Extent.StartOffset -lt $cursorPosition } | ForEach-Object { $_.Extent.Text })
+    $index = $words.Count
+    if ($wordToComplete -ne '') {
+        $index = $index - 1
+    }
+    & '{{name}}' completion __complete $index -- @words 2>$null | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`
@@ -3,35 +3,160 @@ package buf
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
 	"time"
 
+	"github.com/bufbuild/buf/internal/buf/bufconvert"
+	"github.com/bufbuild/buf/internal/buf/bufdaemon"
+	"github.com/bufbuild/buf/internal/buf/bufdoc"
+	"github.com/bufbuild/buf/internal/buf/bufgen"
 	"github.com/bufbuild/buf/internal/buf/bufos"
+	"github.com/bufbuild/buf/internal/buf/bufwatch"
+	"github.com/bufbuild/buf/internal/buf/cmd/internal"
 	"github.com/bufbuild/cli/clienv"
 	"github.com/bufbuild/cli/clipflag"
 	"github.com/spf13/pflag"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 const (
-	imageBuildInputFlagName  = "source"
-	imageBuildConfigFlagName = "source-config"
-	imageBuildOutputFlagName = "output"
-
-	checkLintInputFlagName  = "input"
-	checkLintConfigFlagName = "input-config"
-
-	checkBreakingInputFlagName         = "input"
-	checkBreakingConfigFlagName        = "input-config"
-	checkBreakingAgainstInputFlagName  = "against-input"
-	checkBreakingAgainstConfigFlagName = "against-input-config"
+	imageBuildInputFlagName         = "source"
+	imageBuildConfigFlagName        = "source-config"
+	imageBuildOutputFlagName        = "output"
+	imageBuildOutputFormatFlagName  = "output-format"
+	imageBuildWriteChecksumFlagName = "write-checksum"
+
+	imageLsPackagesImageFlagName  = "image"
+	imageLsPackagesFormatFlagName = "format"
+
+	checkLintInputFlagName         = "input"
+	checkLintConfigFlagName        = "input-config"
+	checkLintChangedSinceFlagName  = "changed-since"
+	checkLintTUIFlagName           = "tui"
+	checkLintFixFlagName           = "fix"
+	checkLintWriteBaselineFlagName = "write-baseline"
+
+	checkDaemonInputFlagName  = "input"
+	checkDaemonConfigFlagName = "input-config"
+	checkDaemonClientFlagName = "client"
+
+	lspInputFlagName  = "input"
+	lspConfigFlagName = "input-config"
+
+	checkBreakingInputFlagName           = "input"
+	checkBreakingConfigFlagName          = "input-config"
+	checkBreakingAgainstInputFlagName    = "against-input"
+	checkBreakingAgainstConfigFlagName   = "against-input-config"
+	checkBreakingAgainstReportFlagName   = "against-report"
+	checkBreakingReportOutputFlagName    = "report-output"
+	checkBreakingAgainstStampsFlagName   = "against-stamps"
+	checkBreakingStampsOutputFlagName    = "stamps-output"
+	checkBreakingExemptionsFlagName      = "exemptions"
+	checkBreakingAgainstCacheDirFlagName = "against-cache-dir"
+	checkBreakingAgainstCacheTTLFlagName = "against-cache-ttl"
+	checkBreakingSummaryFlagName         = "summary"
+	checkBreakingExitCodeOnlyFlagName    = "exit-code-only"
+	checkBreakingAllowIfEnvFlagName      = "allow-breaking-if-env"
+
+	checkLsLintIgnoresInputFlagName  = "input"
+	checkLsLintIgnoresConfigFlagName = "input-config"
+	checkLsLintIgnoresFormatFlagName = "format"
 
 	lsFilesInputFlagName  = "input"
 	lsFilesConfigFlagName = "input-config"
+	lsFilesFormatFlagName = "format"
+
+	lsOptionsInputFlagName  = "input"
+	lsOptionsConfigFlagName = "input-config"
+	lsOptionsFormatFlagName = "format"
+
+	lsPackagesInputFlagName  = "input"
+	lsPackagesConfigFlagName = "input-config"
+	lsPackagesFormatFlagName = "format"
+
+	lsServicesInputFlagName  = "input"
+	lsServicesConfigFlagName = "input-config"
+	lsServicesFormatFlagName = "format"
+
+	lsMethodsInputFlagName  = "input"
+	lsMethodsConfigFlagName = "input-config"
+	lsMethodsFormatFlagName = "format"
+
+	testInputFlagName  = "input"
+	testConfigFlagName = "input-config"
+	testFormatFlagName = "format"
+
+	queryInputFlagName  = "input"
+	queryConfigFlagName = "input-config"
+	queryFormatFlagName = "format"
+
+	generateInputFlagName    = "input"
+	generateConfigFlagName   = "input-config"
+	generateTemplateFlagName = "template"
+
+	formatInputFlagName    = "input"
+	formatConfigFlagName   = "input-config"
+	formatWriteFlagName    = "write"
+	formatDiffFlagName     = "diff"
+	formatExitCodeFlagName = "exit-code"
+
+	convertInputFlagName  = "input"
+	convertConfigFlagName = "input-config"
+	convertTypeFlagName   = "type"
+	convertFromFlagName   = "from"
+	convertToFlagName     = "to"
+
+	exportInputFlagName  = "input"
+	exportConfigFlagName = "input-config"
+	exportOutputFlagName = "output"
+	exportFileFlagName   = "file"
+
+	curlInputFlagName      = "input"
+	curlConfigFlagName     = "input-config"
+	curlMethodFlagName     = "method"
+	curlDataFlagName       = "data"
+	curlHeaderFlagName     = "header"
+	curlPlaintextFlagName  = "plaintext"
+	curlInsecureFlagName   = "insecure"
+	curlCACertFlagName     = "cacert"
+	curlCertFlagName       = "cert"
+	curlKeyFlagName        = "key"
+	curlServerNameFlagName = "server-name"
+
+	docInputFlagName    = "input"
+	docConfigFlagName   = "input-config"
+	docFormatFlagName   = "format"
+	docTemplateFlagName = "template"
+	docOutputFlagName   = "output"
+
+	initInteractiveFlagName = "interactive"
+	initForceFlagName       = "force"
+
+	pushSourceFlagName       = "source"
+	pushSourceConfigFlagName = "source-config"
+
+	pullOutputFlagName       = "output"
+	pullConfigOutputFlagName = "config-output"
 
 	checkLsCheckersConfigFlagName = "config"
 
+	dependencyImageFlagName       = "dep-image"
+	fileSetFlagName               = "file-set"
+	requirePinnedFlagName         = "require-pinned"
+	allowSymlinksFlagName         = "allow-symlinks"
+	followSymlinksFlagName        = "follow-symlinks"
+	gitPureGoFlagName             = "git-pure-go"
+	httpTimeoutFlagName           = "http-timeout"
+	httpRetriesFlagName           = "http-retries"
+	annotateAuthorsFlagName       = "annotate-authors"
+	ruleTimingFlagName            = "rule-timing"
+	watchFlagName                 = "watch"
 	errorFormatFlagName           = "error-format"
+	errorFormatTemplateFlagName   = "error-format-template"
 	checkLsCheckersFormatFlagName = "format"
+	configDiffFormatFlagName      = "format"
 )
 
 // Flags are flags for the buf CLI.
@@ -41,23 +166,99 @@ type Flags struct {
 	Config        string
 	AgainstConfig string
 
-	Input        string
-	AgainstInput string
+	Input         string
+	AgainstInputs []string
 
-	Output              string
+	Outputs             []string
+	OutputFormat        string
+	WriteChecksum       string
 	AsFileDescriptorSet bool
 
 	ExcludeImports    bool
 	ExcludeSourceInfo bool
+	Normalize         bool
 
 	Files             []string
 	LimitToInputFiles bool
 
+	DependencyImages []string
+	FileSet          string
+	RequirePinned    bool
+	AllowSymlinks    bool
+	FollowSymlinks   bool
+	GitPureGo        bool
+	HTTPTimeout      time.Duration
+	HTTPRetries      int
+
+	ChangedSince    string
+	Cache           bool
+	AnnotateAuthors bool
+	TUI             bool
+	Fix             bool
+	WriteBaseline   string
+
+	Client       string
+	PollInterval time.Duration
+	DebugAddress string
+
+	AgainstReport string
+	ReportOutput  string
+	AgainstStamps string
+	StampsOutput  string
+	Exemptions    string
+
+	AgainstCacheDir string
+	AgainstCacheTTL time.Duration
+
+	Summary      bool
+	ExitCodeOnly bool
+
+	AllowBreakingIfEnv string
+
+	RuleTiming bool
+
+	Watch bool
+
 	CheckerAll        bool
 	CheckerCategories []string
+	CheckerIDs        []string
+	CheckerDoc        bool
+
+	ErrorFormat         string
+	ErrorFormatTemplate string
+	Format              string
+
+	Template string
+
+	Type string
+	From string
+	To   string
+
+	Output string
+
+	Method     string
+	Data       string
+	Headers    []string
+	Plaintext  bool
+	Insecure   bool
+	CACert     string
+	Cert       string
+	Key        string
+	ServerName string
 
-	ErrorFormat string
-	Format      string
+	// these are separate from Format, Template, and Output as the defaults differ
+	DocFormat   string
+	DocTemplate string
+	DocOutput   string
+
+	Interactive bool
+	Force       bool
+
+	ConfigOutput string
+
+	Write    bool
+	Diff     bool
+	ExitCode bool
 }
 
 // newFlags returns a new Flags.
@@ -96,6 +297,50 @@ func (f *Flags) newRunFunc(
 	)
 }
 
+// newWatchRunFunc creates a new run function that, if --watch is set, runs again
+// whenever a .proto file or buf.yaml within the input directory changes.
+//
+// Each run has its own timeout. Errors are printed and do not stop watching.
+// The screen is cleared before each run if stdout is a terminal.
+func (f *Flags) newWatchRunFunc(
+	fn func(
+		context.Context,
+		clienv.Env,
+		*Flags,
+		*zap.Logger,
+	) error,
+	inputFlagName string,
+) func(clienv.Env) error {
+	runFunc := f.newRunFunc(fn)
+	return func(cliEnv clienv.Env) error {
+		if !f.Watch {
+			return runFunc(cliEnv)
+		}
+		if fileInfo, err := os.Stat(f.Input); err != nil || !fileInfo.IsDir() {
+			return fmt.Errorf("--%s requires --%s to be a directory", watchFlagName, inputFlagName)
+		}
+		stdoutFile, ok := cliEnv.Stdout().(*os.File)
+		clearScreen := ok && terminal.IsTerminal(int(stdoutFile.Fd()))
+		return bufwatch.NewWatcher(f.Input).Watch(
+			context.Background(),
+			func(context.Context) error {
+				if clearScreen {
+					if _, err := io.WriteString(cliEnv.Stdout(), "\x1b[H\x1b[2J"); err != nil {
+						return err
+					}
+				}
+				if err := runFunc(cliEnv); err != nil && err.Error() != "" {
+					if _, err := fmt.Fprintln(cliEnv.Stderr(), err.Error()); err != nil {
+						return err
+					}
+				}
+				_, err := fmt.Fprintf(cliEnv.Stderr(), "Watching %s for changes. Press Ctrl-C to stop.\n", f.Input)
+				return err
+			},
+		)
+	}
+}
+
 func (f *Flags) bindRootCommandFlags(flagSet *pflag.FlagSet) {
 	f.baseFlags.BindRootCommandFlags(flagSet)
 }
@@ -109,7 +354,43 @@ func (f *Flags) bindImageBuildConfig(flagSet *pflag.FlagSet) {
 }
 
 func (f *Flags) bindImageBuildOutput(flagSet *pflag.FlagSet) {
-	flagSet.StringVarP(&f.Output, imageBuildOutputFlagName, "o", "", fmt.Sprintf(`Required. The location to write the image. Must be one of format %s.`, bufos.ImageFormatsToString()))
+	flagSet.StringArrayVarP(&f.Outputs, imageBuildOutputFlagName, "o", nil, fmt.Sprintf(`Required. The location to write the image. Must be one of format %s.
+
+The location may be an S3 object such as s3://bucket/image.bin or a GCS object such as gs://bucket/image.bin,
+which are written using the standard AWS credentials and the application default credentials respectively.
+
+May be specified multiple times to write the image to multiple locations or in multiple
+formats from a single build, for example "-o image.bin -o image.json.gz -o image.yaml".
+At most one location may be stdout.
+
+The gzip compression level of bingz, jsongz and yamlgz outputs may be set from 1 (fastest)
+to 9 (smallest) with the level option, for example "-o image.bin.gz#level=9".
+
+Local files may be split along package boundaries into a number of shards of roughly equal size
+with the shards option, for example "-o image.bin#shards=4" writes image-00000-of-00004.bin through
+image-00003-of-00004.bin, and image.manifest.json which lists the packages, files and dependencies
+of each shard, so that the image can be loaded incrementally.
+
+Locations may contain the template variables {{.GitSHA}}, {{.Timestamp}} and {{.ConfigHash}},
+for example "-o images/image-{{.GitSHA}}.bin". GitSHA is the HEAD commit of the git repository
+of the input directory, Timestamp is the UTC time of the build such as 20200102T150405Z, and
+ConfigHash is the SHA256 digest of the config.`, bufos.ImageFormatsToString()))
+}
+
+func (f *Flags) bindImageBuildWriteChecksum(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.WriteChecksum, imageBuildWriteChecksumFlagName, "", `The checksum algorithm to write a checksum sidecar file with for every location. Must be one of [sha256].
+
+For example "-o image.bin --write-checksum sha256" also writes image.bin.sha256, in the format
+of sha256sum. Every location must be a local file. Images read from local files are verified
+against their sidecar file if it exists, and any image can be verified with the sha256 option,
+for example "--dep-image image.bin#sha256=<checksum>".`)
+}
+
+func (f *Flags) bindImageBuildOutputFormat(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.OutputFormat, imageBuildOutputFormatFlagName, "", fmt.Sprintf(`The format to write the image in, instead of the format derived from the extension of the output. Must be one of %s.
+
+This applies to every location, and is useful with "--output -" to write any format to
+stdout, which is written as bin otherwise.`, bufos.ImageFormatsToString()))
 }
 
 func (f *Flags) bindImageBuildAsFileDescriptorSet(flagSet *pflag.FlagSet) {
@@ -127,8 +408,23 @@ func (f *Flags) bindImageBuildExcludeSourceInfo(flagSet *pflag.FlagSet) {
 	flagSet.BoolVar(&f.ExcludeSourceInfo, "exclude-source-info", false, "Exclude source info.")
 }
 
+func (f *Flags) bindImageBuildNormalize(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.Normalize, "normalize", false, `Normalize the image.
+
+Files are sorted topologically, duplicate imports are removed, and options are sorted,
+so that images built from the same files are identical and diff cleanly.`)
+}
+
 func (f *Flags) bindImageBuildErrorFormat(flagSet *pflag.FlagSet) {
-	flagSet.StringVar(&f.ErrorFormat, errorFormatFlagName, "text", "The format for build errors, printed to stderr. Must be one of [text,json].")
+	flagSet.StringVar(&f.ErrorFormat, errorFormatFlagName, "text", "The format for build errors, printed to stderr. Must be one of [text,json,jsonl]. The json and jsonl formats both print one JSON object per line.")
+}
+
+func (f *Flags) bindImageLsPackagesImage(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Input, imageLsPackagesImageFlagName, "", fmt.Sprintf(`The image to list the packages of. Must be one of format %s.`, bufos.ImageFormatsToString()))
+}
+
+func (f *Flags) bindImageLsPackagesFormat(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Format, imageLsPackagesFormatFlagName, "text", "The format to print packages as. Must be one of [text,json].")
 }
 
 func (f *Flags) bindCheckLintInput(flagSet *pflag.FlagSet) {
@@ -148,7 +444,13 @@ func (f *Flags) bindCheckBreakingConfig(flagSet *pflag.FlagSet) {
 }
 
 func (f *Flags) bindCheckBreakingAgainstInput(flagSet *pflag.FlagSet) {
-	flagSet.StringVar(&f.AgainstInput, checkBreakingAgainstInputFlagName, "", fmt.Sprintf(`Required. The source or image to check against. Must be one of format %s.`, bufos.AllFormatsToString()))
+	flagSet.StringArrayVar(&f.AgainstInputs, checkBreakingAgainstInputFlagName, nil, fmt.Sprintf(`Required. The source or image to check against. Must be one of format %s.
+
+May be specified multiple times to check against multiple previous versions in one run,
+in which case each violation is attributed to the source or image it breaks against.
+
+May also be alias:name to use the input named name in the inputs section of the config
+of --%s.`, bufos.AllFormatsToString(), checkBreakingInputFlagName))
 }
 
 func (f *Flags) bindCheckBreakingAgainstConfig(flagSet *pflag.FlagSet) {
@@ -158,23 +460,218 @@ func (f *Flags) bindCheckBreakingAgainstConfig(flagSet *pflag.FlagSet) {
 func (f *Flags) bindCheckBreakingLimitToInputFiles(flagSet *pflag.FlagSet) {
 	flagSet.BoolVar(&f.LimitToInputFiles, "limit-to-input-files", false, `Only run breaking checks against the files in the input.
 This has the effect of filtering the against input to only contain the files in the input.
+Files that were renamed in the input, as detected by their contents and top-level symbols,
+are compared against their previous paths.
 Overrides --file.`)
 }
 
+func (f *Flags) bindCheckBreakingAgainstReport(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.AgainstReport, checkBreakingAgainstReportFlagName, "", `The path to a report from a previous run, as written by --report-output.
+Packages that have not changed on either the input or the against input since the report was written are not checked again,
+and the results from the report are used for these packages instead. If the file does not exist, all packages are checked.`)
+}
+
+func (f *Flags) bindCheckBreakingReportOutput(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.ReportOutput, checkBreakingReportOutputFlagName, "", `The path to write a report of this run to, for use with --against-report.
+This may be the same path as --against-report.`)
+}
+
+func (f *Flags) bindCheckBreakingAgainstStamps(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.AgainstStamps, checkBreakingAgainstStampsFlagName, "", `The path to a compatibility stamp manifest from a previous run, as written by --stamps-output.
+Stamps for packages with no breaking changes are kept, and stamps for packages with breaking changes are updated.
+The deprecated enum values recorded in the manifest are used for enum_value_delete_grace_releases.
+If the file does not exist, all packages get an initial stamp.`)
+}
+
+func (f *Flags) bindCheckBreakingStampsOutput(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.StampsOutput, checkBreakingStampsOutputFlagName, "", `The path to write a compatibility stamp manifest to, containing a stamp per package.
+This may be the same path as --against-stamps.`)
+}
+
+func (f *Flags) bindCheckBreakingExemptions(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Exemptions, checkBreakingExemptionsFlagName, "", `The path to a JSON or YAML file of time-boxed exemptions for specific breaking changes.
+Each exemption has a checker id, the fully-qualified name of the element the violation is reported on, and an expires date in the form YYYY-MM-DD.
+Exempted violations are printed but do not fail the check until the day after they expire.`)
+}
+
+func (f *Flags) bindCheckBreakingAgainstCache(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.AgainstCacheDir, checkBreakingAgainstCacheDirFlagName, "", `The directory to cache against inputs downloaded over HTTP or HTTPS in.
+Cached inputs are revalidated with conditional requests, so unchanged inputs are not downloaded again.
+Defaults to $XDG_CACHE_HOME/buf/http.`)
+	flagSet.DurationVar(&f.AgainstCacheTTL, checkBreakingAgainstCacheTTLFlagName, 0, `The duration to use cached against inputs for without revalidating them.
+By default, cached against inputs are always revalidated.`)
+}
+
+func (f *Flags) bindCheckBreakingSummary(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.Summary, checkBreakingSummaryFlagName, false, `Print the number of violations for each checker id instead of the violations.`)
+}
+
+func (f *Flags) bindCheckBreakingExitCodeOnly(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.ExitCodeOnly, checkBreakingExitCodeOnlyFlagName, false, `Do not print the violations, only exit with a non-zero exit code if there are any.
+Errors that prevent the check from running are still printed.`)
+}
+
+func (f *Flags) bindCheckBreakingAllowIfEnv(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.AllowBreakingIfEnv, checkBreakingAllowIfEnvFlagName, "", `The name of an environment variable that allows breaking changes if it is set to a non-empty value.
+Breaking changes are still printed, but do not result in a non-zero exit code. This is intended to be set
+by a protected CI workflow, for example when a pull request has an allowed-breaking-change label.
+With --error-format=json, each printed violation has an allowed_by_env field with the name of the variable.`)
+}
+
 func (f *Flags) bindCheckBreakingExcludeImports(flagSet *pflag.FlagSet) {
 	flagSet.BoolVar(&f.ExcludeImports, "exclude-imports", false, "Exclude imports from breaking change detection.")
 }
 
+func (f *Flags) bindDependencyImages(flagSet *pflag.FlagSet) {
+	flagSet.StringSliceVar(&f.DependencyImages, dependencyImageFlagName, nil, fmt.Sprintf(`Images to use only for resolving imports. Must be one of format %s.
+
+Files within these images are not built or checked. If the input is an image, the files these images provide for its
+imports are added to it as imports, like protoc's --descriptor_set_in. This flag can be specified multiple times.`, bufos.ImageFormatsToString()))
+}
+
+func (f *Flags) bindFileSet(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.FileSet, fileSetFlagName, "", `A file set produced by ls-files --format json to use instead of searching the input for files.
+
+The file set must have been produced for the same input and config. This is ignored if --file is specified.`)
+}
+
+func (f *Flags) bindRequirePinned(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.RequirePinned, requirePinnedFlagName, false, `Require git inputs to be pinned to a full commit hash with ref=<sha>. Branches and tags are refused.`)
+}
+
+func (f *Flags) bindAllowSymlinks(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.AllowSymlinks, allowSymlinksFlagName, false, `Skip symlinks and hard links within tarball and zip inputs that point outside of the archive instead of failing.
+
+Entries with absolute paths or ".." components are always refused.`)
+}
+
+func (f *Flags) bindFollowSymlinks(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.FollowSymlinks, followSymlinksFlagName, false, `Follow symlinks to files and directories within directory inputs instead of skipping them.
+
+Symlinks to a directory that contains the symlink are skipped to prevent cycles.`)
+}
+
+func (f *Flags) bindGitPureGo(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.GitPureGo, gitPureGoFlagName, false, `Read file:// git inputs with the built-in git implementation instead of the system git.
+
+This is the same as setting BUF_INPUT_GIT_PURE_GO=true, and is useful in minimal containers without git.`)
+}
+
+func (f *Flags) bindHTTP(flagSet *pflag.FlagSet) {
+	flagSet.DurationVar(&f.HTTPTimeout, httpTimeoutFlagName, internal.DefaultHTTPTimeout, `The timeout for each attempt of an HTTP or HTTPS request for remote inputs and outputs.
+
+The proxies specified by HTTP_PROXY, HTTPS_PROXY, and NO_PROXY are used for these requests.`)
+	flagSet.IntVar(&f.HTTPRetries, httpRetriesFlagName, 0, `The number of times to retry HTTP or HTTPS requests for remote inputs and outputs that fail with a network error,
+a timeout, or a 429, 500, 502, 503, or 504 status code. Retries use exponential backoff starting at one second.`)
+}
+
 func (f *Flags) bindCheckFiles(flagSet *pflag.FlagSet) {
 	flagSet.StringSliceVar(&f.Files, "file", nil, `Limit to specific files. This is an advanced feature and is not recommended.`)
 }
 
 func (f *Flags) bindCheckBreakingErrorFormat(flagSet *pflag.FlagSet) {
-	flagSet.StringVar(&f.ErrorFormat, errorFormatFlagName, "text", "The format for build errors or check violations, printed to stdout. Must be one of [text,json].")
+	flagSet.StringVar(&f.ErrorFormat, errorFormatFlagName, "text", "The format for build errors or check violations, printed to stdout. Must be one of [text,json,jsonl,junit,template]. The json and jsonl formats both print one JSON object per line, but jsonl prints each check violation as soon as it is found, unsorted.")
+}
+
+func (f *Flags) bindCheckAnnotateAuthors(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.AnnotateAuthors, annotateAuthorsFlagName, false, fmt.Sprintf(`Add the git author and commit that last modified the line of each check violation. Requires --%s=json.
+
+Violations in files that are not committed to a git repository are printed without an author.
+This runs git, which can be set with BUF_GIT_PATH.`, errorFormatFlagName))
+}
+
+func (f *Flags) bindCheckRuleTiming(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.RuleTiming, ruleTimingFlagName, false, `Print the total execution time of each checker to stderr, sorted by the longest first.
+
+Use this to find slow checkers on large inputs.`)
+}
+
+func (f *Flags) bindWatch(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.Watch, watchFlagName, false, `Run again whenever a .proto file or buf.yaml within the input directory changes, until interrupted.
+
+The screen is cleared before each run if stdout is a terminal. --timeout applies to each run.`)
+}
+
+func (f *Flags) bindCheckLintChangedSince(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.ChangedSince, checkLintChangedSinceFlagName, "", `Only report lint violations for files changed since this git ref, for example origin/master.
+
+All files are still built. The input must be a directory within a git repository.
+This runs git, which can be set with BUF_GIT_PATH.`)
+}
+
+func (f *Flags) bindCheckLintCache(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.Cache, "cache", false, `Cache lint results per file and skip re-linting unchanged files on subsequent runs.
+
+Results are cached in $XDG_CACHE_HOME/buf/lint.`)
+}
+
+func (f *Flags) bindCheckLintTUI(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.TUI, checkLintTUIFlagName, false, `Browse lint violations in an interactive terminal UI instead of printing them.
+
+Violations are grouped by file or by lint checker, with a preview of the source for the selected violation. Stdin and stdout must be a terminal.`)
+}
+
+func (f *Flags) bindCheckLintFix(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.Fix, checkLintFixFlagName, false, `Fix the lint violations that can be fixed automatically by rewriting the files, and then lint again.
+
+Only FIELD_ORDERED, IMPORT_ORDERED, FILE_LICENSE_HEADER, and FILE_NO_BOM_CRLF violations can currently be fixed. The input must be a directory.`)
+}
+
+func (f *Flags) bindCheckLintWriteBaseline(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.WriteBaseline, checkLintWriteBaselineFlagName, "", `Write all current lint violations to this baseline file instead of printing them.
+
+Reference the baseline file with the lint.baseline config option, relative to the input directory, to suppress these violations while still failing on any new violations.`)
+}
+
+func (f *Flags) bindCheckDaemonInput(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Input, checkDaemonInputFlagName, ".", `The directory to lint.`)
+}
+
+func (f *Flags) bindCheckDaemonConfig(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Config, checkDaemonConfigFlagName, "", `The config file or data to use.`)
+}
+
+func (f *Flags) bindCheckDaemonClient(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Client, checkDaemonClientFlagName, "json-rpc", "The protocol used to talk to the client over stdin and stdout. Must be one of [json-rpc].")
+}
+
+func (f *Flags) bindCheckDaemonPollInterval(flagSet *pflag.FlagSet) {
+	flagSet.DurationVar(&f.PollInterval, "poll-interval", bufdaemon.DefaultPollInterval, `The interval between checking the directory for file changes if filesystem notifications are not available.`)
+}
+
+func (f *Flags) bindCheckDaemonDebugAddress(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.DebugAddress, "debug-address", "", `If set, serve memory and check statistics as JSON at /debug/vars on this address, for example "localhost:6060".`)
+}
+
+func (f *Flags) bindLspInput(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Input, lspInputFlagName, ".", `The directory to serve.`)
+}
+
+func (f *Flags) bindLspConfig(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Config, lspConfigFlagName, "", `The config file or data to use.`)
 }
 
 func (f *Flags) bindCheckLintErrorFormat(flagSet *pflag.FlagSet) {
-	flagSet.StringVar(&f.ErrorFormat, errorFormatFlagName, "text", "The format for build errors or check violations, printed to stdout. Must be one of [text,json,config-ignore-yaml].")
+	flagSet.StringVar(&f.ErrorFormat, errorFormatFlagName, "text", "The format for build errors or check violations, printed to stdout. Must be one of [text,json,jsonl,config-ignore-yaml,junit,template]. The json and jsonl formats both print one JSON object per line, but jsonl prints each check violation as soon as it is found, unsorted.")
+}
+
+func (f *Flags) bindCheckErrorFormatTemplate(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.ErrorFormatTemplate, errorFormatTemplateFlagName, "", fmt.Sprintf(`The Go template used to print each build error or check violation. Requires --%s=template.
+
+The fields available are .Path, .StartLine, .StartColumn, .EndLine, .EndColumn, .Type, and .Message, for example:
+'{{.Path}}:{{.StartLine}} [{{.Type}}] {{.Message}}'`, errorFormatFlagName))
+}
+
+func (f *Flags) bindCheckLsLintIgnoresInput(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Input, checkLsLintIgnoresInputFlagName, ".", fmt.Sprintf(`The source or image to list the comment ignores from. Must be one of format %s.`, bufos.AllFormatsToString()))
+}
+
+func (f *Flags) bindCheckLsLintIgnoresConfig(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Config, checkLsLintIgnoresConfigFlagName, "", `The config file or data to use.`)
+}
+
+func (f *Flags) bindCheckLsLintIgnoresFormat(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Format, checkLsLintIgnoresFormatFlagName, "text", "The format to print comment ignores as. Must be one of [text,json].")
 }
 
 func (f *Flags) bindLsFilesInput(flagSet *pflag.FlagSet) {
@@ -185,6 +682,249 @@ func (f *Flags) bindLsFilesConfig(flagSet *pflag.FlagSet) {
 	flagSet.StringVar(&f.Config, lsFilesConfigFlagName, "", `The config file or data to use.`)
 }
 
+func (f *Flags) bindLsFilesFormat(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Format, lsFilesFormatFlagName, "text", `The format to print files as. Must be one of [text,json].
+
+The json format prints the file set of a source, which can be passed to --file-set.`)
+}
+
+func (f *Flags) bindLsOptionsInput(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Input, lsOptionsInputFlagName, ".", fmt.Sprintf(`The source or image to list the custom options from. Must be one of format %s.`, bufos.AllFormatsToString()))
+}
+
+func (f *Flags) bindLsOptionsConfig(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Config, lsOptionsConfigFlagName, "", `The config file or data to use.`)
+}
+
+func (f *Flags) bindLsOptionsFormat(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Format, lsOptionsFormatFlagName, "text", "The format to print custom options as. Must be one of [text,json].")
+}
+
+func (f *Flags) bindLsPackagesInput(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Input, lsPackagesInputFlagName, ".", fmt.Sprintf(`The source to list the packages of. Must be one of format %s.`, bufos.SourceFormatsToString()))
+}
+
+func (f *Flags) bindLsPackagesConfig(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Config, lsPackagesConfigFlagName, "", `The config file or data to use.`)
+}
+
+func (f *Flags) bindLsPackagesFormat(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Format, lsPackagesFormatFlagName, "text", "The format to print packages as. Must be one of [text,json].")
+}
+
+func (f *Flags) bindLsServicesInput(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Input, lsServicesInputFlagName, ".", fmt.Sprintf(`The source or image to list the services of. Must be one of format %s.`, bufos.AllFormatsToString()))
+}
+
+func (f *Flags) bindLsServicesConfig(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Config, lsServicesConfigFlagName, "", `The config file or data to use.`)
+}
+
+func (f *Flags) bindLsServicesFormat(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Format, lsServicesFormatFlagName, "text", "The format to print services as. Must be one of [text,json].")
+}
+
+func (f *Flags) bindLsMethodsInput(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Input, lsMethodsInputFlagName, ".", fmt.Sprintf(`The source or image to list the methods of. Must be one of format %s.`, bufos.AllFormatsToString()))
+}
+
+func (f *Flags) bindLsMethodsConfig(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Config, lsMethodsConfigFlagName, "", `The config file or data to use.`)
+}
+
+func (f *Flags) bindLsMethodsFormat(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Format, lsMethodsFormatFlagName, "text", "The format to print methods as. Must be one of [text,json].")
+}
+
+func (f *Flags) bindTestInput(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Input, testInputFlagName, ".", fmt.Sprintf(`The source or image to run the tests against. Must be one of format %s.`, bufos.AllFormatsToString()))
+}
+
+func (f *Flags) bindTestConfig(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Config, testConfigFlagName, "", `The config file or data to use. The tests are read from the tests section.`)
+}
+
+func (f *Flags) bindTestFormat(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Format, testFormatFlagName, "text", "The format to print test results as. Must be one of [text,json].")
+}
+
+func (f *Flags) bindQueryInput(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Input, queryInputFlagName, ".", fmt.Sprintf(`The source or image to query. Must be one of format %s.`, bufos.AllFormatsToString()))
+}
+
+func (f *Flags) bindQueryConfig(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Config, queryConfigFlagName, "", `The config file or data to use.`)
+}
+
+func (f *Flags) bindQueryFormat(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Format, queryFormatFlagName, "text", `The format to print matching elements as. Must be one of [text,json].
+The json format prints one element per line with all of its attributes.`)
+}
+
+func (f *Flags) bindGenerateInput(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Input, generateInputFlagName, ".", fmt.Sprintf(`The source or image to generate code for. Must be one of format %s.`, bufos.AllFormatsToString()))
+}
+
+func (f *Flags) bindGenerateConfig(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Config, generateConfigFlagName, "", `The config file or data to use.`)
+}
+
+func (f *Flags) bindGenerateTemplate(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Template, generateTemplateFlagName, bufgen.TemplateFilePath, `The generation template file or data to use. Files must have the extension .yaml or .json.`)
+}
+
+func (f *Flags) bindFormatInput(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Input, formatInputFlagName, ".", `The source directory to format.`)
+}
+
+func (f *Flags) bindFormatConfig(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Config, formatConfigFlagName, "", `The config file or data to use.`)
+}
+
+func (f *Flags) bindFormatWrite(flagSet *pflag.FlagSet) {
+	flagSet.BoolVarP(&f.Write, formatWriteFlagName, "w", false, `Write the formatted files in place instead of printing them.`)
+}
+
+func (f *Flags) bindFormatDiff(flagSet *pflag.FlagSet) {
+	flagSet.BoolVarP(&f.Diff, formatDiffFlagName, "d", false, `Print diffs of the files that are not formatted instead of printing the formatted files.`)
+}
+
+func (f *Flags) bindFormatExitCode(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.ExitCode, formatExitCodeFlagName, false, `Exit with a non-zero exit code if any file is not formatted, even if it was written.`)
+}
+
+func (f *Flags) bindConvertInput(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Input, convertInputFlagName, ".", fmt.Sprintf(`The source or image with the type of the message. Must be one of format %s.`, bufos.AllFormatsToString()))
+}
+
+func (f *Flags) bindConvertConfig(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Config, convertConfigFlagName, "", `The config file or data to use.`)
+}
+
+func (f *Flags) bindConvertType(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Type, convertTypeFlagName, "", `The fully-qualified name of the message type, such as foo.v1.Bar. Required.`)
+}
+
+func (f *Flags) bindConvertFrom(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.From, convertFromFlagName, "-", fmt.Sprintf(`The file to read the message from, or - for stdin.
+The format is derived from the extension, with .json for json, .txt for text, and otherwise bin.
+The format can be overridden with #format=FORMAT, where FORMAT is one of %s.`, bufconvert.MessageEncodingsToString()))
+}
+
+func (f *Flags) bindConvertTo(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.To, convertToFlagName, "-#format=json", fmt.Sprintf(`The file to write the message to, or - for stdout.
+The format is derived from the extension, with .json for json, .txt for text, and otherwise bin.
+The format can be overridden with #format=FORMAT, where FORMAT is one of %s.`, bufconvert.MessageEncodingsToString()))
+}
+
+func (f *Flags) bindExportInput(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Input, exportInputFlagName, ".", fmt.Sprintf(`The source to export. Must be one of format %s.`, bufos.SourceFormatsToString()))
+}
+
+func (f *Flags) bindExportConfig(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Config, exportConfigFlagName, "", `The config file or data to use.`)
+}
+
+func (f *Flags) bindExportOutput(flagSet *pflag.FlagSet) {
+	flagSet.StringVarP(&f.Output, exportOutputFlagName, "o", "", `Required. The location to write the files to.
+Paths ending in .zip are written as zip archives, paths ending in .tar, .tar.gz, or .tgz as tarballs,
+and other paths as directories. If this is -, a tarball is written to stdout.`)
+}
+
+func (f *Flags) bindExportFiles(flagSet *pflag.FlagSet) {
+	flagSet.StringSliceVar(&f.Files, exportFileFlagName, nil, `Limit to specific files and the files they import.`)
+}
+
+func (f *Flags) bindCurlInput(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Input, curlInputFlagName, ".", fmt.Sprintf(`The source or image with the service of the method. Must be one of format %s.`, bufos.AllFormatsToString()))
+}
+
+func (f *Flags) bindCurlConfig(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Config, curlConfigFlagName, "", `The config file or data to use.`)
+}
+
+func (f *Flags) bindCurlMethod(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Method, curlMethodFlagName, "", `The method to call, such as foo.v1.FooService/GetFoo. Required.`)
+}
+
+func (f *Flags) bindCurlData(flagSet *pflag.FlagSet) {
+	flagSet.StringVarP(&f.Data, curlDataFlagName, "d", "", `The JSON request. If this starts with @, the request is read from the file, or from stdin for @-.
+Client streaming methods take a stream of JSON messages. If not set, the request is an empty message.`)
+}
+
+func (f *Flags) bindCurlHeaders(flagSet *pflag.FlagSet) {
+	flagSet.StringArrayVarP(&f.Headers, curlHeaderFlagName, "H", nil, `A header to send with the request, of the form "key: value". May be specified multiple times.`)
+}
+
+func (f *Flags) bindCurlPlaintext(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.Plaintext, curlPlaintextFlagName, false, `Use HTTP/2 without TLS.`)
+}
+
+func (f *Flags) bindCurlInsecure(flagSet *pflag.FlagSet) {
+	flagSet.BoolVarP(&f.Insecure, curlInsecureFlagName, "k", false, `Do not verify the certificate of the server.`)
+}
+
+func (f *Flags) bindCurlCACert(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.CACert, curlCACertFlagName, "", `The PEM file with the certificate authorities to verify the server with, instead of the system roots.`)
+}
+
+func (f *Flags) bindCurlCert(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Cert, curlCertFlagName, "", fmt.Sprintf(`The PEM file with the client certificate. Must be specified with --%s.`, curlKeyFlagName))
+}
+
+func (f *Flags) bindCurlKey(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Key, curlKeyFlagName, "", fmt.Sprintf(`The PEM file with the private key of the client certificate. Must be specified with --%s.`, curlCertFlagName))
+}
+
+func (f *Flags) bindCurlServerName(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.ServerName, curlServerNameFlagName, "", `The server name to verify the certificate of the server against, instead of the host of the address.`)
+}
+
+func (f *Flags) bindDocInput(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Input, docInputFlagName, ".", fmt.Sprintf(`The source or image to document. Must be one of format %s.`, bufos.AllFormatsToString()))
+}
+
+func (f *Flags) bindDocConfig(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Config, docConfigFlagName, "", `The config file or data to use.`)
+}
+
+func (f *Flags) bindDocFormat(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.DocFormat, docFormatFlagName, bufdoc.FormatMarkdown, fmt.Sprintf(`The format to render the documentation as. Must be one of %s.`, bufdoc.FormatsToString()))
+}
+
+func (f *Flags) bindDocTemplate(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.DocTemplate, docTemplateFlagName, "", fmt.Sprintf(`The template file to render the documentation with, instead of the default template of the format.
+Templates are Go templates, using html/template for html and text/template for markdown. Cannot be used with --%s=%s.`, docFormatFlagName, bufdoc.FormatJSON))
+}
+
+func (f *Flags) bindDocOutput(flagSet *pflag.FlagSet) {
+	flagSet.StringVarP(&f.DocOutput, docOutputFlagName, "o", "-", `The file to write the documentation to, or - for stdout.`)
+}
+
+func (f *Flags) bindInitInteractive(flagSet *pflag.FlagSet) {
+	flagSet.BoolVarP(&f.Interactive, initInteractiveFlagName, "i", false, `Prompt for the roots and the lint and breaking checkers instead of using the inferred roots and the defaults.`)
+}
+
+func (f *Flags) bindInitForce(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.Force, initForceFlagName, false, `Overwrite the config file if it already exists.`)
+}
+
+func (f *Flags) bindPushSource(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Input, pushSourceFlagName, ".", fmt.Sprintf(`The source to build and push. Must be one of format %s.`, bufos.SourceFormatsToString()))
+}
+
+func (f *Flags) bindPushSourceConfig(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Config, pushSourceConfigFlagName, "", `The config file or data to use.`)
+}
+
+func (f *Flags) bindPullOutput(flagSet *pflag.FlagSet) {
+	flagSet.StringVarP(&f.Output, pullOutputFlagName, "o", "", fmt.Sprintf(`Required. The location to write the image of the module. Must be one of format %s.`, bufos.ImageFormatsToString()))
+}
+
+func (f *Flags) bindPullConfigOutput(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.ConfigOutput, pullConfigOutputFlagName, "", `The file to write the config of the module to, such as buf.yaml. If not set, the config is not written.`)
+}
+
 func (f *Flags) bindCheckLsCheckersConfig(flagSet *pflag.FlagSet) {
 	flagSet.StringVar(&f.Config, checkLsCheckersConfigFlagName, "", `The config file or data to use. If --all is specified, this is ignored.`)
 }
@@ -197,6 +937,18 @@ func (f *Flags) bindCheckLsCheckersCategories(flagSet *pflag.FlagSet) {
 	flagSet.StringSliceVar(&f.CheckerCategories, "category", nil, "Only list the checkers in these categories.")
 }
 
+func (f *Flags) bindCheckLsCheckersIDs(flagSet *pflag.FlagSet) {
+	flagSet.StringSliceVar(&f.CheckerIDs, "checker", nil, "Only list the checkers with these ids.")
+}
+
 func (f *Flags) bindCheckLsCheckersFormat(flagSet *pflag.FlagSet) {
-	flagSet.StringVar(&f.Format, checkLsCheckersFormatFlagName, "text", "The format to print checkers as. Must be one of [text,json].")
+	flagSet.StringVar(&f.Format, checkLsCheckersFormatFlagName, "text", "The format to print checkers as. Must be one of [text,json], or one of [text,json,markdown] if --doc is specified.")
+}
+
+func (f *Flags) bindCheckLsCheckersDoc(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.CheckerDoc, "doc", false, "Print the full documentation for each checker, including the rationale, configuration options, and examples.")
+}
+
+func (f *Flags) bindConfigDiffFormat(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Format, configDiffFormatFlagName, "text", "The format to print differences as. Must be one of [text,json].")
 }
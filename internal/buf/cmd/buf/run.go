@@ -1,18 +1,59 @@
 package buf
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+	"unicode"
 
+	"github.com/bufbuild/buf/internal/buf/bufassert"
 	"github.com/bufbuild/buf/internal/buf/bufbuild"
 	"github.com/bufbuild/buf/internal/buf/bufcheck"
 	"github.com/bufbuild/buf/internal/buf/bufcheck/bufbreaking"
 	"github.com/bufbuild/buf/internal/buf/bufcheck/buflint"
 	"github.com/bufbuild/buf/internal/buf/bufconfig"
+	"github.com/bufbuild/buf/internal/buf/bufconvert"
+	"github.com/bufbuild/buf/internal/buf/bufcurl"
+	"github.com/bufbuild/buf/internal/buf/bufdaemon"
+	"github.com/bufbuild/buf/internal/buf/bufdoc"
+	"github.com/bufbuild/buf/internal/buf/bufformat"
+	"github.com/bufbuild/buf/internal/buf/bufgen"
+	"github.com/bufbuild/buf/internal/buf/buflsp"
+	"github.com/bufbuild/buf/internal/buf/bufos"
+	"github.com/bufbuild/buf/internal/buf/bufplugin"
+	"github.com/bufbuild/buf/internal/buf/bufquery"
+	"github.com/bufbuild/buf/internal/buf/bufregistry"
+	"github.com/bufbuild/buf/internal/buf/buftui"
 	"github.com/bufbuild/buf/internal/buf/cmd/internal"
+	"github.com/bufbuild/buf/internal/buf/ext/extimage"
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
 	"github.com/bufbuild/buf/internal/pkg/ext/extfile"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagemem"
+	"github.com/bufbuild/buf/internal/pkg/storage/storageos"
+	"github.com/bufbuild/buf/internal/pkg/storage/storageutil"
+	"github.com/bufbuild/buf/internal/pkg/util/utildiff"
+	"github.com/bufbuild/buf/internal/pkg/util/utilencoding"
+	"github.com/bufbuild/buf/internal/pkg/util/utilstring"
 	"github.com/bufbuild/cli/clienv"
+	"github.com/bufbuild/cli/clios"
+	"github.com/golang/protobuf/proto"
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
 )
 
@@ -22,10 +63,24 @@ func imageBuild(
 	flags *Flags,
 	logger *zap.Logger,
 ) (retErr error) {
-	if flags.Output == "" {
+	if len(flags.Outputs) == 0 {
 		return fmt.Errorf("--%s is required", imageBuildOutputFlagName)
 	}
-	asJSON, err := internal.IsFormatJSON(errorFormatFlagName, flags.ErrorFormat)
+	stdoutCount := 0
+	for _, output := range flags.Outputs {
+		if strings.TrimSpace(strings.SplitN(output, "#", 2)[0]) == "-" {
+			stdoutCount++
+		}
+	}
+	if stdoutCount > 1 {
+		return fmt.Errorf("--%s: only one location may be stdout", imageBuildOutputFlagName)
+	}
+	format, err := internal.ParseFormat(errorFormatFlagName, flags.ErrorFormat, internal.BasicFormats...)
+	if err != nil {
+		return err
+	}
+	asJSON := format.IsJSON()
+	httpClient, err := newHTTPClient(cliEnv, flags, logger)
 	if err != nil {
 		return err
 	}
@@ -33,6 +88,13 @@ func imageBuild(
 		logger,
 		imageBuildInputFlagName,
 		imageBuildConfigFlagName,
+		bufos.EnvReaderWithDependencyImages(dependencyImageFlagName, flags.DependencyImages...),
+		bufos.EnvReaderWithProtoFileSet(fileSetFlagName, flags.FileSet),
+		bufos.EnvReaderWithRequirePinned(requirePinnedFlagName, flags.RequirePinned),
+		bufos.EnvReaderWithAllowSymlinks(allowSymlinksFlagName, flags.AllowSymlinks),
+		bufos.EnvReaderWithFollowSymlinks(flags.FollowSymlinks),
+		bufos.EnvReaderWithGitPureGo(flags.GitPureGo),
+		bufos.EnvReaderWithHTTPClient(httpClient),
 		// must be source only
 	).ReadSourceEnv(
 		ctx,
@@ -55,16 +117,98 @@ func imageBuild(
 		}
 		return errors.New("")
 	}
-	return internal.NewBufosImageWriter(
+	if flags.Normalize {
+		env.Image, err = extimage.ImageNormalized(env.Image)
+		if err != nil {
+			return err
+		}
+	}
+	imageWriter := internal.NewBufosImageWriter(
 		logger,
 		imageBuildOutputFlagName,
-	).WriteImage(
-		ctx,
-		cliEnv.Stdout(),
-		flags.Output,
-		flags.AsFileDescriptorSet,
-		env.Image,
+		bufos.ImageWriterWithFormatOverride(imageBuildOutputFormatFlagName, flags.OutputFormat),
+		bufos.ImageWriterWithChecksum(imageBuildWriteChecksumFlagName, flags.WriteChecksum),
+		bufos.ImageWriterWithHTTPClient(httpClient),
 	)
+	outputTemplateData := newOutputTemplateData(ctx, cliEnv, flags.Input, env.Config)
+	for _, output := range flags.Outputs {
+		output, err := executeOutputTemplate(output, outputTemplateData)
+		if err != nil {
+			return err
+		}
+		if err := imageWriter.WriteImage(
+			ctx,
+			cliEnv.Stdout(),
+			cliEnv.Getenv,
+			output,
+			flags.AsFileDescriptorSet,
+			env.Image,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outputTemplateData is the data for templates within image outputs.
+//
+// The values are methods so that they are only computed if used, as
+// GitSHA requires git to be installed.
+type outputTemplateData struct {
+	ctx       context.Context
+	cliEnv    clienv.Env
+	input     string
+	config    *bufconfig.Config
+	timestamp time.Time
+}
+
+func newOutputTemplateData(ctx context.Context, cliEnv clienv.Env, input string, config *bufconfig.Config) *outputTemplateData {
+	return &outputTemplateData{
+		ctx:    ctx,
+		cliEnv: cliEnv,
+		input:  input,
+		config: config,
+		// every output of a build has the same timestamp
+		timestamp: time.Now().UTC(),
+	}
+}
+
+// GitSHA returns the HEAD commit of the git repository of the input directory,
+// or of the current directory if the input is not a directory.
+func (o *outputTemplateData) GitSHA() (string, error) {
+	dirPath := "."
+	if inputPath := strings.TrimSpace(strings.SplitN(o.input, "#", 2)[0]); inputPath != "" {
+		if fileInfo, err := os.Stat(inputPath); err == nil && fileInfo.IsDir() {
+			dirPath = inputPath
+		}
+	}
+	return internal.GitHeadCommit(o.ctx, o.cliEnv.Getenv, dirPath)
+}
+
+// Timestamp returns the UTC time of the build, such as 20200102T150405Z.
+func (o *outputTemplateData) Timestamp() string {
+	return o.timestamp.Format("20060102T150405Z")
+}
+
+// ConfigHash returns the SHA256 digest of the config.
+func (o *outputTemplateData) ConfigHash() (string, error) {
+	return bufconfig.ConfigDigest(o.config)
+}
+
+// executeOutputTemplate executes the output as a template if it contains {{.
+func executeOutputTemplate(output string, data *outputTemplateData) (string, error) {
+	if !strings.Contains(output, "{{") {
+		return output, nil
+	}
+	tmpl, err := template.New(imageBuildOutputFlagName).Parse(output)
+	if err != nil {
+		return "", fmt.Errorf("--%s: %v", imageBuildOutputFlagName, err)
+	}
+	buffer := bytes.NewBuffer(nil)
+	if err := tmpl.Execute(buffer, data); err != nil {
+		return "", fmt.Errorf("--%s: %v", imageBuildOutputFlagName, err)
+	}
+	return buffer.String(), nil
 }
 
 func checkLint(
@@ -73,48 +217,102 @@ func checkLint(
 	flags *Flags,
 	logger *zap.Logger,
 ) (retErr error) {
-	asJSON, err := internal.IsLintFormatJSON(errorFormatFlagName, flags.ErrorFormat)
+	format, err := internal.ParseFormat(errorFormatFlagName, flags.ErrorFormat, internal.LintFormats...)
 	if err != nil {
 		return err
 	}
-	asConfigIgnoreYAML, err := internal.IsLintFormatConfigIgnoreYAML(errorFormatFlagName, flags.ErrorFormat)
+	errorFormatTemplate, err := getErrorFormatTemplate(flags, format)
 	if err != nil {
 		return err
 	}
-	env, fileAnnotations, err := internal.NewBufosEnvReader(
-		logger,
-		checkLintInputFlagName,
-		checkLintConfigFlagName,
-	).ReadEnv(
-		ctx,
-		cliEnv.Stdin(),
-		cliEnv.Getenv,
-		flags.Input,
-		flags.Config,
-		flags.Files, // we filter checks for files
-		false,       // input files must exist
-		false,       // do not want to include imports
-		true,        // we must include source info for linting
-	)
+	if flags.AnnotateAuthors && !format.IsJSON() {
+		return fmt.Errorf("--%s requires --%s=json", annotateAuthorsFlagName, errorFormatFlagName)
+	}
+	var tuiIn *os.File
+	var tuiOut *os.File
+	if flags.TUI {
+		if format != internal.FormatText {
+			return fmt.Errorf("--%s cannot be used with --%s=%s", checkLintTUIFlagName, errorFormatFlagName, flags.ErrorFormat)
+		}
+		tuiIn, tuiOut, err = getTUIFiles(cliEnv)
+		if err != nil {
+			return err
+		}
+	}
+	if flags.Fix {
+		if fileInfo, err := os.Stat(flags.Input); err != nil || !fileInfo.IsDir() {
+			return fmt.Errorf("--%s requires --%s to be a directory", checkLintFixFlagName, checkLintInputFlagName)
+		}
+	}
+	if format == internal.FormatJSONL {
+		if err := checkFlagsNotSetForStreaming(
+			map[string]bool{
+				checkLintFixFlagName:           flags.Fix,
+				checkLintWriteBaselineFlagName: flags.WriteBaseline != "",
+				annotateAuthorsFlagName:        flags.AnnotateAuthors,
+				"cache":                        flags.Cache,
+			},
+		); err != nil {
+			return err
+		}
+	}
+	httpClient, err := newHTTPClient(cliEnv, flags, logger)
 	if err != nil {
 		return err
 	}
-	if len(fileAnnotations) > 0 {
-		if err := extfile.PrintFileAnnotations(cliEnv.Stdout(), fileAnnotations, asJSON); err != nil {
+	var timings *checkerTimings
+	if flags.RuleTiming {
+		timings = newCheckerTimings()
+		defer func() {
+			retErr = multierr.Append(retErr, timings.print(cliEnv.Stderr()))
+		}()
+	}
+	if format == internal.FormatJSONL {
+		return checkLintStream(ctx, cliEnv, flags, logger, httpClient, timings)
+	}
+	env, fileAnnotations, err := readLintEnvAndCheck(ctx, cliEnv, flags, logger, httpClient, timings)
+	if err != nil {
+		return err
+	}
+	if env != nil && flags.Fix && len(fileAnnotations) > 0 {
+		numFixedFiles, err := fixLintFileAnnotations(env, fileAnnotations)
+		if err != nil {
+			return err
+		}
+		logger.Debug("lint_fix", zap.Int("num_fixed_files", numFixedFiles))
+		if numFixedFiles > 0 {
+			// the fixed files need to be built and linted again
+			env, fileAnnotations, err = readLintEnvAndCheck(ctx, cliEnv, flags, logger, httpClient, timings)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if env == nil {
+		if err := printCheckFileAnnotations(cliEnv.Stdout(), fileAnnotations, format, errorFormatTemplate); err != nil {
 			return err
 		}
 		return errors.New("")
 	}
-	fileAnnotations, err = internal.NewBuflintHandler(logger).LintCheck(
-		ctx,
-		env.Config.Lint,
-		env.Image,
-	)
-	if err != nil {
-		return err
+	if flags.WriteBaseline != "" {
+		// the baseline must be written before the paths are fixed
+		return writeJSONFile(checkLintWriteBaselineFlagName, flags.WriteBaseline, buflint.NewBaseline(fileAnnotations))
+	}
+	if env.Config.Lint.Baseline != "" {
+		baseline, err := readLintBaseline(flags, env)
+		if err != nil {
+			return err
+		}
+		fileAnnotations = baseline.Filter(fileAnnotations)
+	}
+	if flags.ChangedSince != "" {
+		fileAnnotations, err = filterFileAnnotationsChangedSince(ctx, cliEnv, flags, env, fileAnnotations)
+		if err != nil {
+			return err
+		}
 	}
 	if len(fileAnnotations) > 0 {
-		if asConfigIgnoreYAML {
+		if format == internal.FormatConfigIgnoreYAML {
 			if err := bufconfig.PrintFileAnnotationsLintConfigIgnoreYAML(cliEnv.Stdout(), fileAnnotations); err != nil {
 				return err
 			}
@@ -122,32 +320,180 @@ func checkLint(
 			if err := bufbuild.FixFileAnnotationPaths(env.Resolver, fileAnnotations); err != nil {
 				return err
 			}
-			if err := extfile.PrintFileAnnotations(cliEnv.Stdout(), fileAnnotations, asJSON); err != nil {
-				return err
+			if flags.TUI {
+				if err := buftui.Run(tuiIn, tuiOut, fileAnnotations, ioutil.ReadFile); err != nil {
+					return err
+				}
+			} else if flags.AnnotateAuthors {
+				if err := printFileAnnotationsWithAuthors(ctx, logger, cliEnv.Getenv, cliEnv.Stdout(), fileAnnotations, nil, ""); err != nil {
+					return err
+				}
+			} else {
+				if err := printCheckFileAnnotations(cliEnv.Stdout(), fileAnnotations, format, errorFormatTemplate); err != nil {
+					return err
+				}
 			}
 		}
+		if env.Config.Lint.FailurePolicy.ShouldFail(fileAnnotations) {
+			return errors.New("")
+		}
+		return nil
+	}
+	if format == internal.FormatJUnit {
+		// the report is printed for passing runs as well so that CI systems record the checks
+		return printCheckFileAnnotations(cliEnv.Stdout(), nil, format, errorFormatTemplate)
+	}
+	return nil
+}
+
+// checkLintStream runs the lint checks for --error-format=jsonl, printing each
+// FileAnnotation as soon as the checker that produced it completes.
+//
+// The FileAnnotations are not held in memory, so they are not sorted. The timings can be nil.
+func checkLintStream(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+	httpClient *http.Client,
+	timings *checkerTimings,
+) error {
+	env, fileAnnotations, err := readLintEnv(ctx, cliEnv, flags, logger, httpClient)
+	if err != nil {
+		return err
+	}
+	if env == nil {
+		if err := extfile.PrintFileAnnotations(cliEnv.Stdout(), fileAnnotations, true); err != nil {
+			return err
+		}
+		return errors.New("")
+	}
+	var isNotSuppressed func(*filev1beta1.FileAnnotation) bool
+	if env.Config.Lint.Baseline != "" {
+		baseline, err := readLintBaseline(flags, env)
+		if err != nil {
+			return err
+		}
+		isNotSuppressed = baseline.NewFilterFunc()
+	}
+	var isChanged func(*filev1beta1.FileAnnotation) (bool, error)
+	if flags.ChangedSince != "" {
+		isChanged, err = newChangedSinceFilterFunc(ctx, cliEnv, flags, env)
+		if err != nil {
+			return err
+		}
+	}
+	typeToCount := make(map[string]int)
+	if err := internal.NewBuflintHandler(logger, getLintRunnerOptions(env, flags.Input, timings)...).LintCheckStream(
+		ctx,
+		env.Config.Lint,
+		env.Image,
+		func(fileAnnotation *filev1beta1.FileAnnotation) error {
+			if isNotSuppressed != nil && !isNotSuppressed(fileAnnotation) {
+				return nil
+			}
+			if isChanged != nil {
+				ok, err := isChanged(fileAnnotation)
+				if err != nil || !ok {
+					return err
+				}
+			}
+			typeToCount[fileAnnotation.Type]++
+			fileAnnotations := []*filev1beta1.FileAnnotation{fileAnnotation}
+			if err := bufbuild.FixFileAnnotationPaths(env.Resolver, fileAnnotations); err != nil {
+				return err
+			}
+			return extfile.PrintFileAnnotations(cliEnv.Stdout(), fileAnnotations, true)
+		},
+	); err != nil {
+		return err
+	}
+	if env.Config.Lint.FailurePolicy.ShouldFailTypeCounts(typeToCount) {
 		return errors.New("")
 	}
 	return nil
 }
 
-func checkBreaking(
+// checkFlagsNotSetForStreaming returns an error if any of the flags are set, as they
+// need all the FileAnnotations and cannot be used with --error-format=jsonl.
+func checkFlagsNotSetForStreaming(flagNameToIsSet map[string]bool) error {
+	flagNames := make([]string, 0, len(flagNameToIsSet))
+	for flagName, isSet := range flagNameToIsSet {
+		if isSet {
+			flagNames = append(flagNames, flagName)
+		}
+	}
+	if len(flagNames) == 0 {
+		return nil
+	}
+	sort.Strings(flagNames)
+	return fmt.Errorf("--%s cannot be used with --%s=jsonl", flagNames[0], errorFormatFlagName)
+}
+
+// readLintEnvAndCheck reads the env and runs the lint checks.
+//
+// If the input fails to build, the env is nil and the build FileAnnotations are returned.
+// The timings can be nil.
+func readLintEnvAndCheck(
 	ctx context.Context,
 	cliEnv clienv.Env,
 	flags *Flags,
 	logger *zap.Logger,
-) (retErr error) {
-	if flags.AgainstInput == "" {
-		return fmt.Errorf("--%s is required", checkBreakingAgainstInputFlagName)
+	httpClient *http.Client,
+	timings *checkerTimings,
+) (*bufos.Env, []*filev1beta1.FileAnnotation, error) {
+	env, fileAnnotations, err := readLintEnv(ctx, cliEnv, flags, logger, httpClient)
+	if err != nil || env == nil {
+		return nil, fileAnnotations, err
+	}
+	if flags.Cache {
+		cacheDirPath, err := clios.XdgCacheHome(cliEnv.Getenv)
+		if err != nil {
+			return nil, nil, err
+		}
+		fileAnnotations, err = internal.NewBuflintHandler(logger, getLintRunnerOptions(env, flags.Input, timings)...).LintCheckWithCache(
+			ctx,
+			env.Config.Lint,
+			env.Image,
+			filepath.Join(cacheDirPath, "buf", "lint"),
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		return env, fileAnnotations, nil
 	}
-	asJSON, err := internal.IsFormatJSON(errorFormatFlagName, flags.ErrorFormat)
+	fileAnnotations, err = internal.NewBuflintHandler(logger, getLintRunnerOptions(env, flags.Input, timings)...).LintCheck(
+		ctx,
+		env.Config.Lint,
+		env.Image,
+	)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
+	return env, fileAnnotations, nil
+}
+
+// readLintEnv reads the env to lint.
+//
+// If the input fails to build, the env is nil and the build FileAnnotations are returned.
+func readLintEnv(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+	httpClient *http.Client,
+) (*bufos.Env, []*filev1beta1.FileAnnotation, error) {
 	env, fileAnnotations, err := internal.NewBufosEnvReader(
 		logger,
-		checkBreakingInputFlagName,
-		checkBreakingConfigFlagName,
+		checkLintInputFlagName,
+		checkLintConfigFlagName,
+		bufos.EnvReaderWithDependencyImages(dependencyImageFlagName, flags.DependencyImages...),
+		bufos.EnvReaderWithProtoFileSet(fileSetFlagName, flags.FileSet),
+		bufos.EnvReaderWithRequirePinned(requirePinnedFlagName, flags.RequirePinned),
+		bufos.EnvReaderWithAllowSymlinks(allowSymlinksFlagName, flags.AllowSymlinks),
+		bufos.EnvReaderWithFollowSymlinks(flags.FollowSymlinks),
+		bufos.EnvReaderWithGitPureGo(flags.GitPureGo),
+		bufos.EnvReaderWithHTTPClient(httpClient),
 	).ReadEnv(
 		ctx,
 		cliEnv.Stdin(),
@@ -155,176 +501,2812 @@ func checkBreaking(
 		flags.Input,
 		flags.Config,
 		flags.Files, // we filter checks for files
-		false,       // files specified must exist on the main input
-		!flags.ExcludeImports,
-		true, // we must include source info for this side of the check
+		false,       // input files must exist
+		false,       // do not want to include imports
+		true,        // we must include source info for linting
 	)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	if len(fileAnnotations) > 0 {
-		if err := extfile.PrintFileAnnotations(cliEnv.Stdout(), fileAnnotations, asJSON); err != nil {
-			return err
-		}
-		return errors.New("")
+		return nil, fileAnnotations, nil
 	}
+	return env, nil, nil
+}
 
-	files := flags.Files
-	if flags.LimitToInputFiles {
-		fileDescriptors := env.Image.GetFile()
-		// we know that the file descriptors have unique names from validation
-		files = make([]string, len(fileDescriptors))
-		for i, fileDescriptor := range fileDescriptors {
-			// we know that the name is non-empty from validation
-			files[i] = fileDescriptor.GetName()
-		}
+// getLintRunnerOptions returns the lint RunnerOptions for the env read from the input.
+//
+// The source of files can only be read if the input is a directory. The timings can be nil.
+func getLintRunnerOptions(env *bufos.Env, input string, timings *checkerTimings) []buflint.RunnerOption {
+	var runnerOptions []buflint.RunnerOption
+	if timings != nil {
+		runnerOptions = append(runnerOptions, buflint.RunnerWithTimingFunc(timings.add))
 	}
-
-	againstEnv, fileAnnotations, err := internal.NewBufosEnvReader(
-		logger,
-		checkBreakingAgainstInputFlagName,
-		checkBreakingAgainstConfigFlagName,
-	).ReadEnv(
-		ctx,
-		cliEnv.Stdin(),
-		cliEnv.Getenv,
-		flags.AgainstInput,
-		flags.AgainstConfig,
-		files, // we filter checks for files
-		true,  // files are allowed to not exist on the against input
-		!flags.ExcludeImports,
-		false, // no need to include source info for against
+	if fileInfo, err := os.Stat(input); err != nil || !fileInfo.IsDir() || env.Resolver == nil {
+		return runnerOptions
+	}
+	return append(
+		runnerOptions,
+		buflint.RunnerWithReadFile(
+			func(rootFilePath string) ([]byte, error) {
+				realFilePath, err := env.Resolver.GetRealFilePath(rootFilePath)
+				if err != nil {
+					return nil, err
+				}
+				if realFilePath == "" {
+					return nil, fmt.Errorf("could not find the file for %q", rootFilePath)
+				}
+				return ioutil.ReadFile(realFilePath)
+			},
+		),
 	)
-	if err != nil {
-		return err
+}
+
+// fixLintFileAnnotations fixes the FileAnnotations that can be fixed and
+// writes the fixed files, returning the number of files written.
+//
+// The FileAnnotations must use the image file paths.
+func fixLintFileAnnotations(env *bufos.Env, fileAnnotations []*filev1beta1.FileAnnotation) (int, error) {
+	if env.Resolver == nil {
+		return 0, fmt.Errorf("--%s requires --%s to be a directory", checkLintFixFlagName, checkLintInputFlagName)
 	}
-	if len(fileAnnotations) > 0 {
-		// TODO: formalize this somewhere
-		for _, fileAnnotation := range fileAnnotations {
-			if fileAnnotation.Path != "" {
-				fileAnnotation.Path = fileAnnotation.Path + "@against"
-			}
+	getRealFilePath := func(rootFilePath string) (string, error) {
+		realFilePath, err := env.Resolver.GetRealFilePath(rootFilePath)
+		if err != nil {
+			return "", err
 		}
-		if err := extfile.PrintFileAnnotations(cliEnv.Stdout(), fileAnnotations, asJSON); err != nil {
-			return err
+		if realFilePath == "" {
+			return "", fmt.Errorf("could not find the file for %q", rootFilePath)
 		}
-		return errors.New("")
+		return realFilePath, nil
 	}
-	fileAnnotations, err = internal.NewBufbreakingHandler(logger).BreakingCheck(
-		ctx,
-		env.Config.Breaking,
-		againstEnv.Image,
+	pathToData, err := buflint.Fix(
+		env.Config.Lint,
 		env.Image,
+		fileAnnotations,
+		func(rootFilePath string) ([]byte, error) {
+			realFilePath, err := getRealFilePath(rootFilePath)
+			if err != nil {
+				return nil, err
+			}
+			return ioutil.ReadFile(realFilePath)
+		},
 	)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	if len(fileAnnotations) > 0 {
-		if err := bufbuild.FixFileAnnotationPaths(env.Resolver, fileAnnotations); err != nil {
-			return err
+	for rootFilePath, data := range pathToData {
+		realFilePath, err := getRealFilePath(rootFilePath)
+		if err != nil {
+			return 0, err
 		}
-		if err := extfile.PrintFileAnnotations(cliEnv.Stdout(), fileAnnotations, asJSON); err != nil {
-			return err
+		fileInfo, err := os.Stat(realFilePath)
+		if err != nil {
+			return 0, err
+		}
+		if err := ioutil.WriteFile(realFilePath, data, fileInfo.Mode()); err != nil {
+			return 0, err
 		}
-		return errors.New("")
 	}
-	return nil
+	return len(pathToData), nil
 }
 
-func checkLsLintCheckers(
+// getTUIFiles returns stdin and stdout as files for the terminal UI.
+//
+// Returns error if either is not a terminal.
+func getTUIFiles(cliEnv clienv.Env) (*os.File, *os.File, error) {
+	in, ok := cliEnv.Stdin().(*os.File)
+	if !ok {
+		return nil, nil, fmt.Errorf("--%s requires stdin to be a terminal", checkLintTUIFlagName)
+	}
+	out, ok := cliEnv.Stdout().(*os.File)
+	if !ok {
+		return nil, nil, fmt.Errorf("--%s requires stdout to be a terminal", checkLintTUIFlagName)
+	}
+	if !buftui.IsTerminal(in, out) {
+		return nil, nil, fmt.Errorf("--%s requires stdin and stdout to be a terminal", checkLintTUIFlagName)
+	}
+	return in, out, nil
+}
+
+func checkDaemon(
 	ctx context.Context,
 	cliEnv clienv.Env,
 	flags *Flags,
 	logger *zap.Logger,
-) (retErr error) {
-	asJSON, err := internal.IsFormatJSON(checkLsCheckersFormatFlagName, flags.Format)
+) error {
+	if flags.Client != "json-rpc" {
+		return fmt.Errorf("--%s: unknown client: %q", checkDaemonClientFlagName, flags.Client)
+	}
+	fileInfo, err := os.Stat(flags.Input)
 	if err != nil {
 		return err
 	}
-	var checkers []bufcheck.Checker
-	if flags.CheckerAll {
-		checkers, err = buflint.GetAllCheckers(flags.CheckerCategories...)
-		if err != nil {
-			return err
-		}
-	} else {
-		config, err := internal.NewBufosEnvReader(
+	if !fileInfo.IsDir() {
+		return fmt.Errorf("--%s must be a directory", checkDaemonInputFlagName)
+	}
+	checker := func(ctx context.Context) ([]*filev1beta1.FileAnnotation, error) {
+		env, fileAnnotations, err := internal.NewBufosEnvReader(
 			logger,
-			"",
-			checkLsCheckersConfigFlagName,
-		).GetConfig(
+			checkDaemonInputFlagName,
+			checkDaemonConfigFlagName,
+		).ReadEnv(
 			ctx,
+			cliEnv.Stdin(),
+			cliEnv.Getenv,
+			flags.Input,
 			flags.Config,
+			nil,   // we lint all files
+			false, // input files must exist
+			false, // do not want to include imports
+			true,  // we must include source info for linting
 		)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		checkers, err = config.Lint.GetCheckers(flags.CheckerCategories...)
+		if len(fileAnnotations) > 0 {
+			return fileAnnotations, nil
+		}
+		fileAnnotations, err = internal.NewBuflintHandler(logger, getLintRunnerOptions(env, flags.Input, nil)...).LintCheck(
+			ctx,
+			env.Config.Lint,
+			env.Image,
+		)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		if err := bufbuild.FixFileAnnotationPaths(env.Resolver, fileAnnotations); err != nil {
+			return nil, err
+		}
+		return fileAnnotations, nil
 	}
-	return bufcheck.PrintCheckers(cliEnv.Stdout(), checkers, asJSON)
+	return bufdaemon.NewDaemon(
+		logger,
+		flags.Input,
+		checker,
+		bufdaemon.DaemonWithPollInterval(flags.PollInterval),
+		bufdaemon.DaemonWithDebugAddress(flags.DebugAddress),
+	).Run(ctx, cliEnv.Stdin(), cliEnv.Stdout())
 }
 
-func checkLsBreakingCheckers(
+func lsp(
 	ctx context.Context,
 	cliEnv clienv.Env,
 	flags *Flags,
 	logger *zap.Logger,
-) (retErr error) {
-	asJSON, err := internal.IsFormatJSON(checkLsCheckersFormatFlagName, flags.Format)
+) error {
+	fileInfo, err := os.Stat(flags.Input)
 	if err != nil {
 		return err
 	}
-	var checkers []bufcheck.Checker
-	if flags.CheckerAll {
+	if !fileInfo.IsDir() {
+		return fmt.Errorf("--%s must be a directory", lspInputFlagName)
+	}
+	checker := func(ctx context.Context, pathToData map[string][]byte) (*buflsp.CheckResult, error) {
+		env, fileAnnotations, err := internal.NewBufosEnvReader(
+			logger,
+			lspInputFlagName,
+			lspConfigFlagName,
+			// files open in the client may have unsaved changes
+			bufos.EnvReaderWithOverlay(pathToData),
+		).ReadEnv(
+			ctx,
+			cliEnv.Stdin(),
+			cliEnv.Getenv,
+			flags.Input,
+			flags.Config,
+			nil,   // we lint all files
+			false, // input files must exist
+			true,  // we must include imports for definitions within imports
+			true,  // we must include source info for linting and definitions
+		)
+		if err != nil {
+			return nil, err
+		}
+		if len(fileAnnotations) > 0 {
+			return &buflsp.CheckResult{FileAnnotations: fileAnnotations}, nil
+		}
+		image, err := extimage.ImageWithoutImports(env.Image)
+		if err != nil {
+			return nil, err
+		}
+		fileAnnotations, err = internal.NewBuflintHandler(logger, getLintRunnerOptions(env, flags.Input, nil)...).LintCheck(
+			ctx,
+			env.Config.Lint,
+			image,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if err := bufbuild.FixFileAnnotationPaths(env.Resolver, fileAnnotations); err != nil {
+			return nil, err
+		}
+		return &buflsp.CheckResult{
+			Image:           env.Image,
+			Resolver:        env.Resolver,
+			FileAnnotations: fileAnnotations,
+		}, nil
+	}
+	return buflsp.NewServer(logger, checker).Serve(ctx, cliEnv.Stdin(), cliEnv.Stdout())
+}
+
+// filterFileAnnotationsChangedSince filters the FileAnnotations to those for files
+// that changed since the git ref given by --changed-since.
+//
+// FileAnnotations without a path are always kept.
+// readLintBaseline reads the baseline file referenced by the lint config.
+func readLintBaseline(flags *Flags, env *bufos.Env) (*buflint.Baseline, error) {
+	if fileInfo, err := os.Stat(flags.Input); err != nil || !fileInfo.IsDir() || env.Resolver == nil {
+		return nil, fmt.Errorf("lint.baseline requires --%s to be a directory", checkLintInputFlagName)
+	}
+	baselineFilePath := filepath.Join(flags.Input, filepath.FromSlash(env.Config.Lint.Baseline))
+	data, err := ioutil.ReadFile(baselineFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("lint.baseline: %v", err)
+	}
+	baseline := &buflint.Baseline{}
+	if err := utilencoding.UnmarshalJSONStrict(data, baseline); err != nil {
+		return nil, fmt.Errorf("lint.baseline: could not unmarshal %s: %v", baselineFilePath, err)
+	}
+	return baseline, nil
+}
+
+func filterFileAnnotationsChangedSince(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	env *bufos.Env,
+	fileAnnotations []*filev1beta1.FileAnnotation,
+) ([]*filev1beta1.FileAnnotation, error) {
+	isChanged, err := newChangedSinceFilterFunc(ctx, cliEnv, flags, env)
+	if err != nil {
+		return nil, err
+	}
+	filteredFileAnnotations := make([]*filev1beta1.FileAnnotation, 0, len(fileAnnotations))
+	for _, fileAnnotation := range fileAnnotations {
+		ok, err := isChanged(fileAnnotation)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			filteredFileAnnotations = append(filteredFileAnnotations, fileAnnotation)
+		}
+	}
+	return filteredFileAnnotations, nil
+}
+
+// newChangedSinceFilterFunc returns a function that returns true if the FileAnnotation
+// is for a file that changed since --changed-since, or is not for a file.
+//
+// The FileAnnotations must use the image file paths.
+func newChangedSinceFilterFunc(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	env *bufos.Env,
+) (func(*filev1beta1.FileAnnotation) (bool, error), error) {
+	if fileInfo, err := os.Stat(flags.Input); err != nil || !fileInfo.IsDir() || env.Resolver == nil {
+		return nil, fmt.Errorf("--%s requires --%s to be a directory", checkLintChangedSinceFlagName, checkLintInputFlagName)
+	}
+	changedFilePaths, err := internal.GitChangedFilePaths(ctx, cliEnv.Getenv, flags.Input, flags.ChangedSince)
+	if err != nil {
+		return nil, fmt.Errorf("--%s: %v", checkLintChangedSinceFlagName, err)
+	}
+	absChangedFilePaths := make(map[string]struct{}, len(changedFilePaths))
+	for changedFilePath := range changedFilePaths {
+		absChangedFilePath, err := filepath.Abs(changedFilePath)
+		if err != nil {
+			return nil, err
+		}
+		absChangedFilePaths[absChangedFilePath] = struct{}{}
+	}
+	return func(fileAnnotation *filev1beta1.FileAnnotation) (bool, error) {
+		if fileAnnotation.Path == "" {
+			return true, nil
+		}
+		realFilePath, err := env.Resolver.GetRealFilePath(fileAnnotation.Path)
+		if err != nil {
+			return false, err
+		}
+		absRealFilePath, err := filepath.Abs(realFilePath)
+		if err != nil {
+			return false, err
+		}
+		_, ok := absChangedFilePaths[absRealFilePath]
+		return ok, nil
+	}, nil
+}
+
+func checkBreaking(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+) (retErr error) {
+	if len(flags.AgainstInputs) == 0 {
+		return fmt.Errorf("--%s is required", checkBreakingAgainstInputFlagName)
+	}
+	if len(flags.AgainstInputs) > 1 {
+		// reports and stamps are relative to a single previous version
+		flagNameToValue := map[string]string{
+			checkBreakingAgainstReportFlagName: flags.AgainstReport,
+			checkBreakingReportOutputFlagName:  flags.ReportOutput,
+			checkBreakingAgainstStampsFlagName: flags.AgainstStamps,
+			checkBreakingStampsOutputFlagName:  flags.StampsOutput,
+		}
+		for _, flagName := range []string{
+			checkBreakingAgainstReportFlagName,
+			checkBreakingReportOutputFlagName,
+			checkBreakingAgainstStampsFlagName,
+			checkBreakingStampsOutputFlagName,
+		} {
+			if flagNameToValue[flagName] != "" {
+				return fmt.Errorf("--%s cannot be used with multiple --%s", flagName, checkBreakingAgainstInputFlagName)
+			}
+		}
+	}
+	format, err := internal.ParseFormat(errorFormatFlagName, flags.ErrorFormat, internal.BreakingFormats...)
+	if err != nil {
+		return err
+	}
+	asJSON := format.IsJSON()
+	errorFormatTemplate, err := getErrorFormatTemplate(flags, format)
+	if err != nil {
+		return err
+	}
+	if flags.AnnotateAuthors && !asJSON {
+		return fmt.Errorf("--%s requires --%s=json", annotateAuthorsFlagName, errorFormatFlagName)
+	}
+	if flags.Summary && flags.ExitCodeOnly {
+		return fmt.Errorf("cannot use both --%s and --%s", checkBreakingSummaryFlagName, checkBreakingExitCodeOnlyFlagName)
+	}
+	if flags.Summary || flags.ExitCodeOnly {
+		flagName := checkBreakingSummaryFlagName
+		if flags.ExitCodeOnly {
+			flagName = checkBreakingExitCodeOnlyFlagName
+		}
+		if flags.AnnotateAuthors {
+			return fmt.Errorf("--%s cannot be used with --%s", flagName, annotateAuthorsFlagName)
+		}
+		if flags.Summary && (format == internal.FormatJUnit || format == internal.FormatTemplate) {
+			return fmt.Errorf("--%s cannot be used with --%s=%s", flagName, errorFormatFlagName, flags.ErrorFormat)
+		}
+	}
+	if format == internal.FormatJSONL {
+		if err := checkFlagsNotSetForStreaming(
+			map[string]bool{
+				checkBreakingSummaryFlagName:       flags.Summary,
+				annotateAuthorsFlagName:            flags.AnnotateAuthors,
+				checkBreakingExemptionsFlagName:    flags.Exemptions != "",
+				checkBreakingAgainstReportFlagName: flags.AgainstReport != "",
+				checkBreakingReportOutputFlagName:  flags.ReportOutput != "",
+				checkBreakingAgainstStampsFlagName: flags.AgainstStamps != "",
+				checkBreakingStampsOutputFlagName:  flags.StampsOutput != "",
+			},
+		); err != nil {
+			return err
+		}
+	}
+	if flags.AgainstCacheTTL < 0 {
+		return fmt.Errorf("--%s must not be negative", checkBreakingAgainstCacheTTLFlagName)
+	}
+	httpClient, err := newHTTPClient(cliEnv, flags, logger)
+	if err != nil {
+		return err
+	}
+	var timings *checkerTimings
+	var runnerOptions []bufbreaking.RunnerOption
+	if flags.RuleTiming {
+		timings = newCheckerTimings()
+		runnerOptions = append(runnerOptions, bufbreaking.RunnerWithTimingFunc(timings.add))
+		defer func() {
+			retErr = multierr.Append(retErr, timings.print(cliEnv.Stderr()))
+		}()
+	}
+	var exemptions *bufbreaking.Exemptions
+	if flags.Exemptions != "" {
+		exemptions, err = readBreakingExemptions(flags)
+		if err != nil {
+			return err
+		}
+	}
+	env, fileAnnotations, err := internal.NewBufosEnvReader(
+		logger,
+		checkBreakingInputFlagName,
+		checkBreakingConfigFlagName,
+		bufos.EnvReaderWithDependencyImages(dependencyImageFlagName, flags.DependencyImages...),
+		bufos.EnvReaderWithProtoFileSet(fileSetFlagName, flags.FileSet),
+		bufos.EnvReaderWithRequirePinned(requirePinnedFlagName, flags.RequirePinned),
+		bufos.EnvReaderWithAllowSymlinks(allowSymlinksFlagName, flags.AllowSymlinks),
+		bufos.EnvReaderWithFollowSymlinks(flags.FollowSymlinks),
+		bufos.EnvReaderWithGitPureGo(flags.GitPureGo),
+		bufos.EnvReaderWithHTTPClient(httpClient),
+	).ReadEnv(
+		ctx,
+		cliEnv.Stdin(),
+		cliEnv.Getenv,
+		flags.Input,
+		flags.Config,
+		flags.Files, // we filter checks for files
+		false,       // files specified must exist on the main input
+		!flags.ExcludeImports,
+		true, // we must include source info for this side of the check
+	)
+	if err != nil {
+		return err
+	}
+	if len(fileAnnotations) > 0 {
+		if err := printCheckFileAnnotations(cliEnv.Stdout(), fileAnnotations, format, errorFormatTemplate); err != nil {
+			return err
+		}
+		return errors.New("")
+	}
+
+	files := flags.Files
+	if flags.LimitToInputFiles {
+		fileDescriptors := env.Image.GetFile()
+		// we know that the file descriptors have unique names from validation
+		files = make([]string, len(fileDescriptors))
+		for i, fileDescriptor := range fileDescriptors {
+			// we know that the name is non-empty from validation
+			files[i] = fileDescriptor.GetName()
+		}
+	}
+
+	if format == internal.FormatJSONL {
+		return checkBreakingStream(ctx, cliEnv, flags, logger, httpClient, env, files, runnerOptions...)
+	}
+	fileAnnotations = nil
+	for _, againstInput := range flags.AgainstInputs {
+		// aliases are resolved with the config of the input
+		resolvedAgainstInput, err := bufconfig.ResolveInputAlias(env.Config, againstInput)
+		if err != nil {
+			return fmt.Errorf("--%s: %v", checkBreakingAgainstInputFlagName, err)
+		}
+		againstFileAnnotations, err := checkBreakingAgainst(
+			ctx,
+			cliEnv,
+			flags,
+			logger,
+			httpClient,
+			env,
+			resolvedAgainstInput,
+			files,
+			func(fileAnnotations []*filev1beta1.FileAnnotation) error {
+				return printCheckFileAnnotations(cliEnv.Stdout(), fileAnnotations, format, errorFormatTemplate)
+			},
+			runnerOptions...,
+		)
+		if err != nil {
+			return err
+		}
+		if len(flags.AgainstInputs) > 1 {
+			for _, fileAnnotation := range againstFileAnnotations {
+				fileAnnotation.Message = fmt.Sprintf("Against %s: %s", againstInput, fileAnnotation.Message)
+			}
+		}
+		fileAnnotations = append(fileAnnotations, againstFileAnnotations...)
+	}
+	if len(fileAnnotations) > 0 {
+		// exempted violations are still printed, the exemptions must be applied before the paths are fixed
+		shouldFail, err := exemptions.ShouldFail(env.Image, fileAnnotations, time.Now())
+		if err != nil {
+			return err
+		}
+		// the name of the environment variable is recorded in the JSON output for auditing
+		var allowedByEnv string
+		if shouldFail && flags.AllowBreakingIfEnv != "" && cliEnv.Getenv(flags.AllowBreakingIfEnv) != "" {
+			allowedByEnv = flags.AllowBreakingIfEnv
+		}
+		var details []*bufbreaking.Details
+		if asJSON {
+			// the details must be found before the paths are fixed
+			details, err = bufbreaking.GetDetails(env.Config.Breaking, env.Image, fileAnnotations)
+			if err != nil {
+				return err
+			}
+		}
+		if err := bufbuild.FixFileAnnotationPaths(env.Resolver, fileAnnotations); err != nil {
+			return err
+		}
+		switch {
+		case flags.ExitCodeOnly:
+		case flags.Summary:
+			if err := printBreakingSummary(cliEnv.Stdout(), fileAnnotations, asJSON, allowedByEnv); err != nil {
+				return err
+			}
+		case flags.AnnotateAuthors:
+			if err := printFileAnnotationsWithAuthors(ctx, logger, cliEnv.Getenv, cliEnv.Stdout(), fileAnnotations, details, allowedByEnv); err != nil {
+				return err
+			}
+		case asJSON:
+			if err := printBreakingFileAnnotationsJSON(cliEnv.Stdout(), fileAnnotations, details, allowedByEnv); err != nil {
+				return err
+			}
+		default:
+			if err := printCheckFileAnnotations(cliEnv.Stdout(), fileAnnotations, format, errorFormatTemplate); err != nil {
+				return err
+			}
+		}
+		if allowedByEnv != "" {
+			// stderr since the violations may be parsed from stdout
+			_, err := fmt.Fprintf(cliEnv.Stderr(), "Breaking changes were allowed since %s is set.\n", allowedByEnv)
+			return err
+		}
+		if shouldFail {
+			return errors.New("")
+		}
+		return nil
+	}
+	if format == internal.FormatJUnit && !flags.ExitCodeOnly {
+		// the report is printed for passing runs as well so that CI systems record the checks
+		return printCheckFileAnnotations(cliEnv.Stdout(), nil, format, errorFormatTemplate)
+	}
+	return nil
+}
+
+// checkBreakingStream checks the env for breaking changes for --error-format=jsonl,
+// printing each FileAnnotation as soon as the checker that produced it completes.
+//
+// The FileAnnotations are not held in memory, so they are not sorted.
+func checkBreakingStream(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+	httpClient *http.Client,
+	env *bufos.Env,
+	files []string,
+	runnerOptions ...bufbreaking.RunnerOption,
+) error {
+	// without exemptions, any FileAnnotation fails the check, so this is known up front
+	var allowedByEnv string
+	if flags.AllowBreakingIfEnv != "" && cliEnv.Getenv(flags.AllowBreakingIfEnv) != "" {
+		allowedByEnv = flags.AllowBreakingIfEnv
+	}
+	getDetails := bufbreaking.NewGetDetailsFunc(env.Config.Breaking, env.Image)
+	breakingHandler := internal.NewBufbreakingHandler(logger, runnerOptions...)
+	numFileAnnotations := 0
+	for _, againstInput := range flags.AgainstInputs {
+		// aliases are resolved with the config of the input
+		resolvedAgainstInput, err := bufconfig.ResolveInputAlias(env.Config, againstInput)
+		if err != nil {
+			return fmt.Errorf("--%s: %v", checkBreakingAgainstInputFlagName, err)
+		}
+		againstEnv, err := readBreakingAgainstEnv(
+			ctx,
+			cliEnv,
+			flags,
+			logger,
+			httpClient,
+			env,
+			resolvedAgainstInput,
+			files,
+			func(fileAnnotations []*filev1beta1.FileAnnotation) error {
+				return extfile.PrintFileAnnotations(cliEnv.Stdout(), fileAnnotations, true)
+			},
+		)
+		if err != nil {
+			return err
+		}
+		if err := breakingHandler.BreakingCheckStream(
+			ctx,
+			env.Config.Breaking,
+			againstEnv.Image,
+			env.Image,
+			func(fileAnnotation *filev1beta1.FileAnnotation) error {
+				numFileAnnotations++
+				if flags.ExitCodeOnly {
+					return nil
+				}
+				// the details must be found before the path is fixed
+				details, err := getDetails(fileAnnotation)
+				if err != nil {
+					return err
+				}
+				if len(flags.AgainstInputs) > 1 {
+					fileAnnotation.Message = fmt.Sprintf("Against %s: %s", againstInput, fileAnnotation.Message)
+				}
+				fileAnnotations := []*filev1beta1.FileAnnotation{fileAnnotation}
+				if err := bufbuild.FixFileAnnotationPaths(env.Resolver, fileAnnotations); err != nil {
+					return err
+				}
+				return printBreakingFileAnnotationsJSON(cliEnv.Stdout(), fileAnnotations, []*bufbreaking.Details{details}, allowedByEnv)
+			},
+		); err != nil {
+			return err
+		}
+	}
+	if numFileAnnotations == 0 {
+		return nil
+	}
+	if allowedByEnv != "" {
+		// stderr since the violations may be parsed from stdout
+		_, err := fmt.Fprintf(cliEnv.Stderr(), "Breaking changes were allowed since %s is set.\n", allowedByEnv)
+		return err
+	}
+	return errors.New("")
+}
+
+// checkBreakingAgainst checks the env for breaking changes against the against input.
+//
+// If the against input has build errors, they are printed with printFileAnnotations
+// and an empty error is returned.
+func checkBreakingAgainst(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+	httpClient *http.Client,
+	env *bufos.Env,
+	againstInput string,
+	files []string,
+	printFileAnnotations func([]*filev1beta1.FileAnnotation) error,
+	runnerOptions ...bufbreaking.RunnerOption,
+) ([]*filev1beta1.FileAnnotation, error) {
+	againstEnv, err := readBreakingAgainstEnv(ctx, cliEnv, flags, logger, httpClient, env, againstInput, files, printFileAnnotations)
+	if err != nil {
+		return nil, err
+	}
+	breakingHandler := internal.NewBufbreakingHandler(logger, runnerOptions...)
+	var fileAnnotations []*filev1beta1.FileAnnotation
+	if flags.AgainstReport != "" || flags.ReportOutput != "" {
+		fileAnnotations, err = checkBreakingWithReport(ctx, flags, logger, env, againstEnv, runnerOptions...)
+	} else {
+		fileAnnotations, err = breakingHandler.BreakingCheck(
+			ctx,
+			env.Config.Breaking,
+			againstEnv.Image,
+			env.Image,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+	againstManifest, err := readBreakingAgainstStamps(flags)
+	if err != nil {
+		return nil, err
+	}
+	// this must be done before the stamps are written, so that a graced deletion
+	// does not change the stamp of the package
+	fileAnnotations, err = breakingHandler.ApplyEnumValueDeleteGrace(
+		ctx,
+		env.Config.Breaking,
+		againstManifest,
+		againstEnv.Image,
+		env.Image,
+		fileAnnotations,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if flags.StampsOutput != "" {
+		// the stamps must be written before the paths are fixed
+		if err := writeBreakingStamps(flags, env, againstEnv, againstManifest, fileAnnotations); err != nil {
+			return nil, err
+		}
+	}
+	return fileAnnotations, nil
+}
+
+// readBreakingAgainstEnv reads the env of the against input.
+//
+// If the against input has build errors, they are printed with printFileAnnotations
+// and an empty error is returned.
+func readBreakingAgainstEnv(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+	httpClient *http.Client,
+	env *bufos.Env,
+	againstInput string,
+	files []string,
+	printFileAnnotations func([]*filev1beta1.FileAnnotation) error,
+) (*bufos.Env, error) {
+	againstFiles := files
+	if flags.LimitToInputFiles {
+		// the against input is limited to the files after renamed files are detected
+		againstFiles = nil
+	}
+	againstEnv, fileAnnotations, err := internal.NewBufosEnvReader(
+		logger,
+		checkBreakingAgainstInputFlagName,
+		checkBreakingAgainstConfigFlagName,
+		bufos.EnvReaderWithDependencyImages(dependencyImageFlagName, flags.DependencyImages...),
+		bufos.EnvReaderWithRequirePinned(requirePinnedFlagName, flags.RequirePinned),
+		bufos.EnvReaderWithAllowSymlinks(allowSymlinksFlagName, flags.AllowSymlinks),
+		bufos.EnvReaderWithFollowSymlinks(flags.FollowSymlinks),
+		bufos.EnvReaderWithGitPureGo(flags.GitPureGo),
+		bufos.EnvReaderWithHTTPClient(httpClient),
+		bufos.EnvReaderWithHTTPCache(flags.AgainstCacheDir, flags.AgainstCacheTTL),
+	).ReadEnv(
+		ctx,
+		cliEnv.Stdin(),
+		cliEnv.Getenv,
+		againstInput,
+		flags.AgainstConfig,
+		againstFiles, // we filter checks for files
+		true,         // files are allowed to not exist on the against input
+		!flags.ExcludeImports,
+		false, // no need to include source info for against
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(fileAnnotations) > 0 {
+		// TODO: formalize this somewhere
+		for _, fileAnnotation := range fileAnnotations {
+			if fileAnnotation.Path != "" {
+				fileAnnotation.Path = fileAnnotation.Path + "@against"
+			}
+		}
+		if err := printFileAnnotations(fileAnnotations); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("")
+	}
+	if flags.LimitToInputFiles {
+		againstEnv.Image, err = getLimitedAgainstImage(logger, env.Image, againstEnv.Image, files)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return againstEnv, nil
+}
+
+// newHTTPClient returns the HTTP client for remote inputs and outputs.
+func newHTTPClient(cliEnv clienv.Env, flags *Flags, logger *zap.Logger) (*http.Client, error) {
+	if flags.HTTPTimeout < 0 {
+		return nil, fmt.Errorf("--%s must not be negative", httpTimeoutFlagName)
+	}
+	if flags.HTTPRetries < 0 {
+		return nil, fmt.Errorf("--%s must not be negative", httpRetriesFlagName)
+	}
+	return internal.NewHTTPClient(logger, cliEnv.Getenv, flags.HTTPTimeout, flags.HTTPRetries), nil
+}
+
+// getLimitedAgainstImage returns the against image with only the files, after
+// renaming the files of the against image that were renamed in the image.
+//
+// Without this, a renamed file is not compared against its previous path at all.
+func getLimitedAgainstImage(
+	logger *zap.Logger,
+	image *imagev1beta1.Image,
+	againstImage *imagev1beta1.Image,
+	files []string,
+) (*imagev1beta1.Image, error) {
+	previousNameToName, err := extimage.ImageFileRenames(againstImage, image)
+	if err != nil {
+		return nil, err
+	}
+	for previousName, name := range previousNameToName {
+		logger.Debug("detected_rename", zap.String("previous_path", previousName), zap.String("path", name))
+	}
+	againstImage, err = extimage.ImageWithRenamedFiles(againstImage, previousNameToName)
+	if err != nil {
+		return nil, err
+	}
+	return extimage.ImageWithSpecificNames(againstImage, true, files...)
+}
+
+func readBreakingExemptions(flags *Flags) (*bufbreaking.Exemptions, error) {
+	data, err := ioutil.ReadFile(flags.Exemptions)
+	if err != nil {
+		return nil, fmt.Errorf("--%s: %v", checkBreakingExemptionsFlagName, err)
+	}
+	exemptions, err := bufbreaking.ParseExemptions(data)
+	if err != nil {
+		return nil, fmt.Errorf("--%s: %v", checkBreakingExemptionsFlagName, err)
+	}
+	return exemptions, nil
+}
+
+type breakingSummaryEntry struct {
+	ID           string `json:"id,omitempty" yaml:"id,omitempty"`
+	Count        int    `json:"count,omitempty" yaml:"count,omitempty"`
+	AllowedByEnv string `json:"allowed_by_env,omitempty" yaml:"allowed_by_env,omitempty"`
+}
+
+// printBreakingSummary prints the number of FileAnnotations for each checker id,
+// sorted by descending count and then by id.
+//
+// As text, a final TOTAL row is printed. As JSON, one entry is printed per line.
+// If allowedByEnv is not empty, it is added to each JSON entry.
+func printBreakingSummary(writer io.Writer, fileAnnotations []*filev1beta1.FileAnnotation, asJSON bool, allowedByEnv string) (retErr error) {
+	idToEntry := make(map[string]*breakingSummaryEntry)
+	var entries []*breakingSummaryEntry
+	for _, fileAnnotation := range fileAnnotations {
+		entry, ok := idToEntry[fileAnnotation.GetType()]
+		if !ok {
+			entry = &breakingSummaryEntry{
+				ID:           fileAnnotation.GetType(),
+				AllowedByEnv: allowedByEnv,
+			}
+			idToEntry[entry.ID] = entry
+			entries = append(entries, entry)
+		}
+		entry.Count++
+	}
+	sort.Slice(
+		entries,
+		func(i int, j int) bool {
+			if entries[i].Count != entries[j].Count {
+				return entries[i].Count > entries[j].Count
+			}
+			return entries[i].ID < entries[j].ID
+		},
+	)
+	if asJSON {
+		for _, entry := range entries {
+			data, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(writer, string(data)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	tabWriter := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+	defer func() {
+		retErr = multierr.Append(retErr, tabWriter.Flush())
+	}()
+	if _, err := fmt.Fprintln(tabWriter, "ID\tCOUNT"); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if _, err := fmt.Fprintf(tabWriter, "%s\t%d\n", entry.ID, entry.Count); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(tabWriter, "TOTAL\t%d\n", len(fileAnnotations))
+	return err
+}
+
+// checkerTimings are the total execution times of checkers.
+type checkerTimings struct {
+	idToDuration map[string]time.Duration
+}
+
+func newCheckerTimings() *checkerTimings {
+	return &checkerTimings{
+		idToDuration: make(map[string]time.Duration),
+	}
+}
+
+func (c *checkerTimings) add(id string, duration time.Duration) {
+	c.idToDuration[id] += duration
+}
+
+// print prints the execution time of each checker, sorted by descending
+// duration and then by id, followed by a final TOTAL row.
+//
+// Nothing is printed if no checkers were run.
+func (c *checkerTimings) print(writer io.Writer) (retErr error) {
+	if len(c.idToDuration) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(c.idToDuration))
+	var total time.Duration
+	for id, duration := range c.idToDuration {
+		ids = append(ids, id)
+		total += duration
+	}
+	sort.Slice(
+		ids,
+		func(i int, j int) bool {
+			if c.idToDuration[ids[i]] != c.idToDuration[ids[j]] {
+				return c.idToDuration[ids[i]] > c.idToDuration[ids[j]]
+			}
+			return ids[i] < ids[j]
+		},
+	)
+	tabWriter := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+	defer func() {
+		retErr = multierr.Append(retErr, tabWriter.Flush())
+	}()
+	if _, err := fmt.Fprintln(tabWriter, "ID\tDURATION"); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if _, err := fmt.Fprintf(tabWriter, "%s\t%v\n", id, c.idToDuration[id].Round(time.Microsecond)); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(tabWriter, "TOTAL\t%v\n", total.Round(time.Microsecond))
+	return err
+}
+
+func checkBreakingWithReport(
+	ctx context.Context,
+	flags *Flags,
+	logger *zap.Logger,
+	env *bufos.Env,
+	againstEnv *bufos.Env,
+	runnerOptions ...bufbreaking.RunnerOption,
+) ([]*filev1beta1.FileAnnotation, error) {
+	var againstReport *bufbreaking.Report
+	if flags.AgainstReport != "" {
+		report := &bufbreaking.Report{}
+		exists, err := readJSONFile(checkBreakingAgainstReportFlagName, flags.AgainstReport, report)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			againstReport = report
+		}
+	}
+	fileAnnotations, report, err := internal.NewBufbreakingHandler(logger, runnerOptions...).BreakingCheckWithReport(
+		ctx,
+		env.Config.Breaking,
+		againstEnv.Image,
+		env.Image,
+		againstReport,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if flags.ReportOutput != "" {
+		// the report must be written before the paths are fixed
+		if err := writeJSONFile(checkBreakingReportOutputFlagName, flags.ReportOutput, report); err != nil {
+			return nil, err
+		}
+	}
+	return fileAnnotations, nil
+}
+
+func writeBreakingStamps(
+	flags *Flags,
+	env *bufos.Env,
+	againstEnv *bufos.Env,
+	againstManifest *bufbreaking.Manifest,
+	fileAnnotations []*filev1beta1.FileAnnotation,
+) error {
+	manifest, err := bufbreaking.NewManifest(againstManifest, againstEnv.Image, env.Image, fileAnnotations)
+	if err != nil {
+		return err
+	}
+	return writeJSONFile(checkBreakingStampsOutputFlagName, flags.StampsOutput, manifest)
+}
+
+// readBreakingAgainstStamps reads the manifest of --against-stamps.
+//
+// Returns nil if the flag is not set or the file does not exist.
+func readBreakingAgainstStamps(flags *Flags) (*bufbreaking.Manifest, error) {
+	if flags.AgainstStamps == "" {
+		return nil, nil
+	}
+	manifest := &bufbreaking.Manifest{}
+	exists, err := readJSONFile(checkBreakingAgainstStampsFlagName, flags.AgainstStamps, manifest)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, nil
+	}
+	return manifest, nil
+}
+
+// readJSONFile reads the JSON file at the path into v.
+//
+// Returns false if the file does not exist.
+func readJSONFile(flagName string, path string, v interface{}) (bool, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("--%s: %v", flagName, err)
+	}
+	if err := utilencoding.UnmarshalJSONStrict(data, v); err != nil {
+		return false, fmt.Errorf("--%s: could not unmarshal: %v", flagName, err)
+	}
+	return true, nil
+}
+
+// writeJSONFile writes v as JSON to the path.
+func writeJSONFile(flagName string, path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("--%s: %v", flagName, err)
+	}
+	return nil
+}
+
+// getErrorFormatTemplate returns the parsed template if the format is template.
+//
+// Returns nil if the format is not template.
+func getErrorFormatTemplate(flags *Flags, format internal.Format) (*template.Template, error) {
+	if format != internal.FormatTemplate {
+		if flags.ErrorFormatTemplate != "" {
+			return nil, fmt.Errorf("--%s requires --%s=template", errorFormatTemplateFlagName, errorFormatFlagName)
+		}
+		return nil, nil
+	}
+	if flags.ErrorFormatTemplate == "" {
+		return nil, fmt.Errorf("--%s=template requires --%s", errorFormatFlagName, errorFormatTemplateFlagName)
+	}
+	tmpl, err := template.New(errorFormatTemplateFlagName).Parse(flags.ErrorFormatTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("--%s: %v", errorFormatTemplateFlagName, err)
+	}
+	return tmpl, nil
+}
+
+// printCheckFileAnnotations prints the FileAnnotations for lint or breaking
+// using the format specified by the error format flag.
+func printCheckFileAnnotations(
+	writer io.Writer,
+	fileAnnotations []*filev1beta1.FileAnnotation,
+	format internal.Format,
+	errorFormatTemplate *template.Template,
+) error {
+	switch format {
+	case internal.FormatTemplate:
+		return extfile.PrintFileAnnotationsTemplate(writer, fileAnnotations, errorFormatTemplate)
+	case internal.FormatJUnit:
+		return extfile.PrintFileAnnotationsJUnit(writer, fileAnnotations)
+	default:
+		return extfile.PrintFileAnnotations(writer, fileAnnotations, format.IsJSON())
+	}
+}
+
+// printBreakingFileAnnotationsJSON prints the FileAnnotations as JSON with their
+// breaking details, which must have the same length as the FileAnnotations.
+func printBreakingFileAnnotationsJSON(
+	writer io.Writer,
+	fileAnnotations []*filev1beta1.FileAnnotation,
+	details []*bufbreaking.Details,
+	allowedByEnv string,
+) error {
+	for i, fileAnnotation := range fileAnnotations {
+		data, err := json.Marshal(
+			&fileAnnotationWithDetails{
+				FileAnnotation: fileAnnotation,
+				Details:        details[i],
+				AllowedByEnv:   allowedByEnv,
+			},
+		)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(writer, string(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileAnnotationWithDetails is a FileAnnotation with breaking details.
+//
+// AllowedByEnv is the name of the environment variable that allowed the breaking
+// change, if any.
+type fileAnnotationWithDetails struct {
+	*filev1beta1.FileAnnotation
+	*bufbreaking.Details
+	AllowedByEnv string `json:"allowed_by_env,omitempty" yaml:"allowed_by_env,omitempty"`
+}
+
+// printFileAnnotationsWithAuthors prints the FileAnnotations as JSON with the git
+// author and commit that last modified the start line of each FileAnnotation.
+//
+// The paths of the FileAnnotations must be real file paths. FileAnnotations that
+// cannot be attributed, for example because the file is not committed to a git
+// repository, are printed without an author.
+//
+// If details is not nil, it must have the same length as the FileAnnotations,
+// and the breaking details are printed as well. If allowedByEnv is not empty,
+// it is printed as the environment variable that allowed the breaking changes.
+func printFileAnnotationsWithAuthors(
+	ctx context.Context,
+	logger *zap.Logger,
+	getenv func(string) string,
+	writer io.Writer,
+	fileAnnotations []*filev1beta1.FileAnnotation,
+	details []*bufbreaking.Details,
+	allowedByEnv string,
+) error {
+	filePathToLineToGitBlame := make(map[string]map[int]*internal.GitBlame)
+	for i, fileAnnotation := range fileAnnotations {
+		fileAnnotationWithAuthor := &fileAnnotationWithAuthor{
+			FileAnnotation: fileAnnotation,
+			AllowedByEnv:   allowedByEnv,
+		}
+		if details != nil {
+			fileAnnotationWithAuthor.Details = details[i]
+		}
+		if fileAnnotation.Path != "" && fileAnnotation.StartLine != 0 {
+			lineToGitBlame, ok := filePathToLineToGitBlame[fileAnnotation.Path]
+			if !ok {
+				var err error
+				lineToGitBlame, err = internal.GitBlameFile(ctx, getenv, fileAnnotation.Path)
+				if err != nil {
+					logger.Debug("git_blame_failed", zap.String("path", fileAnnotation.Path), zap.Error(err))
+				}
+				// a nil map is stored on error so that we only try each file once
+				filePathToLineToGitBlame[fileAnnotation.Path] = lineToGitBlame
+			}
+			if gitBlame := lineToGitBlame[int(fileAnnotation.StartLine)]; gitBlame != nil {
+				fileAnnotationWithAuthor.Author = gitBlame.Author
+				fileAnnotationWithAuthor.AuthorMail = gitBlame.AuthorMail
+				fileAnnotationWithAuthor.AuthorTime = gitBlame.AuthorTime.Format(time.RFC3339)
+				fileAnnotationWithAuthor.Commit = gitBlame.Commit
+			}
+		}
+		data, err := json.Marshal(fileAnnotationWithAuthor)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(writer, string(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileAnnotationWithAuthor is a FileAnnotation with git blame information.
+//
+// The json tags of FileAnnotation match the JSON printed by extfile.PrintFileAnnotations,
+// so the author fields are added to the same JSON.
+type fileAnnotationWithAuthor struct {
+	*filev1beta1.FileAnnotation
+	*bufbreaking.Details
+	Author     string `json:"author,omitempty" yaml:"author,omitempty"`
+	AuthorMail string `json:"author_mail,omitempty" yaml:"author_mail,omitempty"`
+	AuthorTime string `json:"author_time,omitempty" yaml:"author_time,omitempty"`
+	Commit     string `json:"commit,omitempty" yaml:"commit,omitempty"`
+
+	AllowedByEnv string `json:"allowed_by_env,omitempty" yaml:"allowed_by_env,omitempty"`
+}
+
+func checkLsLintCheckers(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+) (retErr error) {
+	printCheckers, err := newPrintCheckersFunc(flags, buflint.GetCheckerDoc)
+	if err != nil {
+		return err
+	}
+	var checkers []bufcheck.Checker
+	if flags.CheckerAll {
+		checkers, err = buflint.GetAllCheckers(flags.CheckerCategories...)
+		if err != nil {
+			return err
+		}
+	} else {
+		config, err := internal.NewBufosEnvReader(
+			logger,
+			"",
+			checkLsCheckersConfigFlagName,
+		).GetConfig(
+			ctx,
+			cliEnv.Getenv,
+			flags.Config,
+		)
+		if err != nil {
+			return err
+		}
+		checkers, err = config.Lint.GetCheckers(flags.CheckerCategories...)
+		if err != nil {
+			return err
+		}
+	}
+	return printCheckers(cliEnv.Stdout(), filterCheckerIDs(checkers, flags.CheckerIDs))
+}
+
+func checkLsBreakingCheckers(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+) (retErr error) {
+	printCheckers, err := newPrintCheckersFunc(flags, bufbreaking.GetCheckerDoc)
+	if err != nil {
+		return err
+	}
+	var checkers []bufcheck.Checker
+	if flags.CheckerAll {
 		checkers, err = bufbreaking.GetAllCheckers(flags.CheckerCategories...)
 		if err != nil {
 			return err
 		}
 	} else {
-		config, err := internal.NewBufosEnvReader(
-			logger,
-			"",
-			checkLsCheckersConfigFlagName,
-		).GetConfig(
-			ctx,
-			flags.Config,
-		)
+		config, err := internal.NewBufosEnvReader(
+			logger,
+			"",
+			checkLsCheckersConfigFlagName,
+		).GetConfig(
+			ctx,
+			cliEnv.Getenv,
+			flags.Config,
+		)
+		if err != nil {
+			return err
+		}
+		checkers, err = config.Breaking.GetCheckers(flags.CheckerCategories...)
+		if err != nil {
+			return err
+		}
+	}
+	return printCheckers(cliEnv.Stdout(), filterCheckerIDs(checkers, flags.CheckerIDs))
+}
+
+// filterCheckerIDs filters the checkers to those with the ids, if any.
+func filterCheckerIDs(checkers []bufcheck.Checker, ids []string) []bufcheck.Checker {
+	if len(ids) == 0 {
+		return checkers
+	}
+	idsMap := utilstring.SliceToMap(ids)
+	var filteredCheckers []bufcheck.Checker
+	for _, checker := range checkers {
+		if _, ok := idsMap[checker.ID()]; ok {
+			filteredCheckers = append(filteredCheckers, checker)
+		}
+	}
+	return filteredCheckers
+}
+
+// newPrintCheckersFunc returns a function that prints checkers as specified
+// by the format and doc flags.
+func newPrintCheckersFunc(
+	flags *Flags,
+	getCheckerDoc func(string) *bufcheck.CheckerDoc,
+) (func(io.Writer, []bufcheck.Checker) error, error) {
+	if flags.CheckerDoc {
+		checkerDocFormat, err := bufcheck.ParseCheckerDocFormat(flags.Format)
+		if err != nil {
+			return nil, fmt.Errorf("--%s: %v", checkLsCheckersFormatFlagName, err)
+		}
+		return func(writer io.Writer, checkers []bufcheck.Checker) error {
+			return bufcheck.PrintCheckerDocs(writer, checkers, getCheckerDoc, checkerDocFormat)
+		}, nil
+	}
+	format, err := internal.ParseFormat(checkLsCheckersFormatFlagName, flags.Format, internal.BasicFormats...)
+	if err != nil {
+		return nil, err
+	}
+	asJSON := format.IsJSON()
+	return func(writer io.Writer, checkers []bufcheck.Checker) error {
+		return bufcheck.PrintCheckers(writer, checkers, asJSON)
+	}, nil
+}
+
+func checkLsLintIgnores(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+) (retErr error) {
+	format, err := internal.ParseFormat(checkLsLintIgnoresFormatFlagName, flags.Format, internal.BasicFormats...)
+	if err != nil {
+		return err
+	}
+	asJSON := format.IsJSON()
+	env, fileAnnotations, err := internal.NewBufosEnvReader(
+		logger,
+		checkLsLintIgnoresInputFlagName,
+		checkLsLintIgnoresConfigFlagName,
+	).ReadEnv(
+		ctx,
+		cliEnv.Stdin(),
+		cliEnv.Getenv,
+		flags.Input,
+		flags.Config,
+		nil,   // we want all files
+		false, // this is ignored since we do not specify specific files
+		false, // do not want to include imports
+		true,  // we must include source info to get comments
+	)
+	if err != nil {
+		return err
+	}
+	if len(fileAnnotations) > 0 {
+		if err := extfile.PrintFileAnnotations(cliEnv.Stdout(), fileAnnotations, false); err != nil {
+			return err
+		}
+		return errors.New("")
+	}
+	// git blame only works on a directory
+	fileInfo, err := os.Stat(flags.Input)
+	canBlame := err == nil && fileInfo.IsDir() && env.Resolver != nil
+	now := time.Now()
+	var entries []*lintIgnoreEntry
+	for _, commentIgnore := range buflint.GetCommentIgnores(env.Image) {
+		entry := &lintIgnoreEntry{
+			Path: commentIgnore.Path,
+			Line: commentIgnore.Line,
+			ID:   commentIgnore.ID,
+		}
+		if env.Resolver != nil {
+			entry.Path, err = env.Resolver.GetRealFilePath(commentIgnore.Path)
+			if err != nil {
+				return err
+			}
+		}
+		if canBlame {
+			gitBlame, err := internal.GitBlameLine(ctx, cliEnv.Getenv, entry.Path, entry.Line)
+			if err != nil {
+				logger.Debug("git_blame_failed", zap.String("path", entry.Path), zap.Error(err))
+			} else if gitBlame != nil {
+				entry.Author = gitBlame.Author
+				entry.AuthorTime = gitBlame.AuthorTime.Format(time.RFC3339)
+				entry.AgeDays = int(now.Sub(gitBlame.AuthorTime).Hours() / 24)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return printLintIgnoreEntries(cliEnv.Stdout(), entries, asJSON)
+}
+
+type lintIgnoreEntry struct {
+	Path       string `json:"path,omitempty" yaml:"path,omitempty"`
+	Line       int    `json:"line,omitempty" yaml:"line,omitempty"`
+	ID         string `json:"id,omitempty" yaml:"id,omitempty"`
+	Author     string `json:"author,omitempty" yaml:"author,omitempty"`
+	AuthorTime string `json:"author_time,omitempty" yaml:"author_time,omitempty"`
+	AgeDays    int    `json:"age_days,omitempty" yaml:"age_days,omitempty"`
+}
+
+func printLintIgnoreEntries(writer io.Writer, entries []*lintIgnoreEntry, asJSON bool) (retErr error) {
+	if len(entries) == 0 {
+		return nil
+	}
+	if !asJSON {
+		tabWriter := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+		defer func() {
+			retErr = multierr.Append(retErr, tabWriter.Flush())
+		}()
+		writer = tabWriter
+		if _, err := fmt.Fprintln(writer, "PATH\tID\tAUTHOR\tAGE"); err != nil {
+			return err
+		}
+	}
+	for _, entry := range entries {
+		if asJSON {
+			data, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(writer, string(data)); err != nil {
+				return err
+			}
+			continue
+		}
+		age := ""
+		if entry.AuthorTime != "" {
+			age = fmt.Sprintf("%dd", entry.AgeDays)
+		}
+		if _, err := fmt.Fprintf(writer, "%s:%d\t%s\t%s\t%s\n", entry.Path, entry.Line, entry.ID, entry.Author, age); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func configDiff(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+) (retErr error) {
+	format, err := internal.ParseFormat(configDiffFormatFlagName, flags.Format, internal.BasicFormats...)
+	if err != nil {
+		return err
+	}
+	asJSON := format.IsJSON()
+	args := cliEnv.Args()
+	envReader := internal.NewBufosEnvReader(logger, "", "")
+	oldConfig, err := envReader.GetConfig(ctx, cliEnv.Getenv, args[0])
+	if err != nil {
+		return err
+	}
+	newConfig, err := envReader.GetConfig(ctx, cliEnv.Getenv, args[1])
+	if err != nil {
+		return err
+	}
+	return bufconfig.PrintConfigDiff(cliEnv.Stdout(), bufconfig.GetConfigDiff(oldConfig, newConfig), asJSON)
+}
+
+func configEffective(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+) (retErr error) {
+	configOverride := ""
+	source := bufconfig.ConfigFilePath
+	if args := cliEnv.Args(); len(args) > 0 {
+		configOverride = args[0]
+		source = args[0]
+		switch filepath.Ext(configOverride) {
+		case ".json", ".yaml":
+		default:
+			source = "config data"
+		}
+	}
+	config, err := internal.NewBufosEnvReader(logger, "", "").GetConfig(ctx, cliEnv.Getenv, configOverride)
+	if err != nil {
+		return err
+	}
+	return bufconfig.PrintEffectiveConfig(cliEnv.Stdout(), config, source)
+}
+
+func configMigrate(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+) error {
+	filePath := ""
+	if args := cliEnv.Args(); len(args) > 0 {
+		filePath = args[0]
+	} else {
+		for _, migrateConfigFilePath := range bufconfig.MigrateConfigFilePaths {
+			if _, err := os.Stat(migrateConfigFilePath); err == nil {
+				filePath = migrateConfigFilePath
+				break
+			}
+		}
+		if filePath == "" {
+			return fmt.Errorf("no configuration to migrate, none of %s exist", strings.Join(bufconfig.MigrateConfigFilePaths, ", "))
+		}
+	}
+	format := bufconfig.MigrateFormatForFilePath(filePath)
+	if format == "" {
+		return fmt.Errorf("%s is not a prototool or protolint configuration, must be one of %s", filePath, strings.Join(bufconfig.MigrateConfigFilePaths, ", "))
+	}
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	migration, err := bufconfig.MigrateConfig(data, format)
+	if err != nil {
+		return fmt.Errorf("%s: %v", filePath, err)
+	}
+	if _, err := bufconfig.NewProvider(logger).GetConfigForData(migration.Data); err != nil {
+		return fmt.Errorf("%s: migrated config is invalid: %v", filePath, err)
+	}
+	for _, note := range migration.Notes {
+		if _, err := fmt.Fprintf(cliEnv.Stderr(), "%s: %s\n", filePath, note); err != nil {
+			return err
+		}
+	}
+	_, err = cliEnv.Stdout().Write(migration.Data)
+	return err
+}
+
+func lsFiles(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+) (retErr error) {
+	format, err := internal.ParseFormat(lsFilesFormatFlagName, flags.Format, internal.BasicFormats...)
+	if err != nil {
+		return err
+	}
+	asJSON := format.IsJSON()
+	envReader := internal.NewBufosEnvReader(
+		logger,
+		lsFilesInputFlagName,
+		lsFilesConfigFlagName,
+	)
+	if asJSON {
+		protoFileSet, err := envReader.GetProtoFileSet(
+			ctx,
+			cliEnv.Stdin(),
+			cliEnv.Getenv,
+			flags.Input,
+			flags.Config,
+		)
+		if err != nil {
+			return err
+		}
+		data, err := bufbuild.MarshalProtoFileSetJSON(protoFileSet)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(cliEnv.Stdout(), string(data))
+		return err
+	}
+	filePaths, err := envReader.ListFiles(
+		ctx,
+		cliEnv.Stdin(),
+		cliEnv.Getenv,
+		flags.Input,
+		flags.Config,
+	)
+	if err != nil {
+		return err
+	}
+	for _, filePath := range filePaths {
+		if _, err := fmt.Fprintln(cliEnv.Stdout(), filePath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func lsOptions(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+) (retErr error) {
+	format, err := internal.ParseFormat(lsOptionsFormatFlagName, flags.Format, internal.BasicFormats...)
+	if err != nil {
+		return err
+	}
+	asJSON := format.IsJSON()
+	env, fileAnnotations, err := internal.NewBufosEnvReader(
+		logger,
+		lsOptionsInputFlagName,
+		lsOptionsConfigFlagName,
+		bufos.EnvReaderWithDependencyImages(dependencyImageFlagName, flags.DependencyImages...),
+	).ReadEnv(
+		ctx,
+		cliEnv.Stdin(),
+		cliEnv.Getenv,
+		flags.Input,
+		flags.Config,
+		nil,   // we want all files
+		false, // this is ignored since we do not specify specific files
+		true,  // we must include imports to resolve the options
+		false, // we do not need source info
+	)
+	if err != nil {
+		return err
+	}
+	if len(fileAnnotations) > 0 {
+		if err := extfile.PrintFileAnnotations(cliEnv.Stdout(), fileAnnotations, false); err != nil {
+			return err
+		}
+		return errors.New("")
+	}
+	customOptions, err := extimage.ImageCustomOptions(env.Image)
+	if err != nil {
+		return err
+	}
+	if env.Resolver != nil {
+		for _, customOption := range customOptions {
+			if customOption.DefiningFile != "" {
+				customOption.DefiningFile, err = getRealFilePathOrName(env.Resolver, customOption.DefiningFile)
+				if err != nil {
+					return err
+				}
+			}
+			for i, file := range customOption.Files {
+				customOption.Files[i], err = getRealFilePathOrName(env.Resolver, file)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return printCustomOptions(cliEnv.Stdout(), customOptions, asJSON)
+}
+
+func lsPackages(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+) (retErr error) {
+	format, err := internal.ParseFormat(lsPackagesFormatFlagName, flags.Format, internal.BasicFormats...)
+	if err != nil {
+		return err
+	}
+	asJSON := format.IsJSON()
+	env, fileAnnotations, err := internal.NewBufosEnvReader(
+		logger,
+		lsPackagesInputFlagName,
+		lsPackagesConfigFlagName,
+		// must be source only
+	).ReadSourceEnv(
+		ctx,
+		cliEnv.Stdin(),
+		cliEnv.Getenv,
+		flags.Input,
+		flags.Config,
+		nil,   // we want all files
+		false, // this is ignored since we do not specify specific files
+		false, // imports are not listed
+		false, // we do not need source info
+	)
+	if err != nil {
+		return err
+	}
+	if len(fileAnnotations) > 0 {
+		if err := extfile.PrintFileAnnotations(cliEnv.Stdout(), fileAnnotations, false); err != nil {
+			return err
+		}
+		return errors.New("")
+	}
+	packages, err := extimage.ImagePackages(env.Image)
+	if err != nil {
+		return err
+	}
+	if env.Resolver != nil {
+		for _, pkg := range packages {
+			for i, file := range pkg.Files {
+				pkg.Files[i], err = getRealFilePathOrName(env.Resolver, file)
+				if err != nil {
+					return err
+				}
+			}
+			sort.Strings(pkg.Files)
+		}
+	}
+	return printPackages(cliEnv.Stdout(), packages, asJSON)
+}
+
+func imageLsPackages(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+) (retErr error) {
+	if flags.Input == "" {
+		return fmt.Errorf("--%s is required", imageLsPackagesImageFlagName)
+	}
+	format, err := internal.ParseFormat(imageLsPackagesFormatFlagName, flags.Format, internal.BasicFormats...)
+	if err != nil {
+		return err
+	}
+	asJSON := format.IsJSON()
+	env, err := internal.NewBufosEnvReader(
+		logger,
+		imageLsPackagesImageFlagName,
+		"",
+		// must be image only
+	).ReadImageEnv(
+		ctx,
+		cliEnv.Stdin(),
+		cliEnv.Getenv,
+		flags.Input,
+		"",    // we do not use a config
+		nil,   // we want all files
+		false, // this is ignored since we do not specify specific files
+		true,  // we list all packages of the image
+	)
+	if err != nil {
+		return err
+	}
+	packages, err := extimage.ImagePackages(env.Image)
+	if err != nil {
+		return err
+	}
+	return printPackages(cliEnv.Stdout(), packages, asJSON)
+}
+
+// printPackages prints each package with its files, with an empty package
+// printed as "-" in the text format.
+//
+// The json format prints one package per line.
+func printPackages(writer io.Writer, packages []*extimage.Package, asJSON bool) (retErr error) {
+	if len(packages) == 0 {
+		return nil
+	}
+	if !asJSON {
+		tabWriter := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+		defer func() {
+			retErr = multierr.Append(retErr, tabWriter.Flush())
+		}()
+		writer = tabWriter
+		if _, err := fmt.Fprintln(writer, "PACKAGE\tFILES"); err != nil {
+			return err
+		}
+	}
+	for _, pkg := range packages {
+		if asJSON {
+			data, err := json.Marshal(pkg)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(writer, string(data)); err != nil {
+				return err
+			}
+			continue
+		}
+		name := pkg.Name
+		if name == "" {
+			name = "-"
+		}
+		if _, err := fmt.Fprintf(writer, "%s\t%s\n", name, strings.Join(pkg.Files, ",")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func lsServices(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+) (retErr error) {
+	format, err := internal.ParseFormat(lsServicesFormatFlagName, flags.Format, internal.BasicFormats...)
+	if err != nil {
+		return err
+	}
+	asJSON := format.IsJSON()
+	services, err := getServices(ctx, cliEnv, flags, logger, lsServicesInputFlagName, lsServicesConfigFlagName)
+	if err != nil {
+		return err
+	}
+	return printServices(cliEnv.Stdout(), services, asJSON)
+}
+
+func lsMethods(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+) (retErr error) {
+	format, err := internal.ParseFormat(lsMethodsFormatFlagName, flags.Format, internal.BasicFormats...)
+	if err != nil {
+		return err
+	}
+	asJSON := format.IsJSON()
+	services, err := getServices(ctx, cliEnv, flags, logger, lsMethodsInputFlagName, lsMethodsConfigFlagName)
+	if err != nil {
+		return err
+	}
+	var methods []*extimage.Method
+	for _, service := range services {
+		methods = append(methods, service.Methods...)
+	}
+	return printMethods(cliEnv.Stdout(), methods, asJSON)
+}
+
+// getServices gets the services of the input, with the files mapped to their
+// real paths if the input is a source.
+//
+// If there are file annotations, they are printed and an error is returned.
+func getServices(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+	inputFlagName string,
+	configFlagName string,
+) ([]*extimage.Service, error) {
+	env, fileAnnotations, err := internal.NewBufosEnvReader(
+		logger,
+		inputFlagName,
+		configFlagName,
+	).ReadEnv(
+		ctx,
+		cliEnv.Stdin(),
+		cliEnv.Getenv,
+		flags.Input,
+		flags.Config,
+		nil,   // we want all files
+		false, // this is ignored since we do not specify specific files
+		false, // services of imports are not listed
+		false, // we do not need source info
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(fileAnnotations) > 0 {
+		if err := extfile.PrintFileAnnotations(cliEnv.Stdout(), fileAnnotations, false); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("")
+	}
+	services, err := extimage.ImageServices(env.Image)
+	if err != nil {
+		return nil, err
+	}
+	if env.Resolver != nil {
+		for _, service := range services {
+			service.File, err = getRealFilePathOrName(env.Resolver, service.File)
+			if err != nil {
+				return nil, err
+			}
+			for _, method := range service.Methods {
+				method.File = service.File
+			}
+		}
+	}
+	return services, nil
+}
+
+// printServices prints each service with its file and number of methods.
+//
+// The json format prints one service per line, including its methods.
+func printServices(writer io.Writer, services []*extimage.Service, asJSON bool) (retErr error) {
+	if len(services) == 0 {
+		return nil
+	}
+	if !asJSON {
+		tabWriter := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+		defer func() {
+			retErr = multierr.Append(retErr, tabWriter.Flush())
+		}()
+		writer = tabWriter
+		if _, err := fmt.Fprintln(writer, "SERVICE\tFILE\tMETHODS"); err != nil {
+			return err
+		}
+	}
+	for _, service := range services {
+		if asJSON {
+			data, err := json.Marshal(service)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(writer, string(data)); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := fmt.Fprintf(writer, "%s\t%s\t%d\n", service.Name, service.File, len(service.Methods)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printMethods prints each method with its request and response types and
+// whether it is unary, client streaming, server streaming, or bidi streaming.
+//
+// The json format prints one method per line.
+func printMethods(writer io.Writer, methods []*extimage.Method, asJSON bool) (retErr error) {
+	if len(methods) == 0 {
+		return nil
+	}
+	if !asJSON {
+		tabWriter := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+		defer func() {
+			retErr = multierr.Append(retErr, tabWriter.Flush())
+		}()
+		writer = tabWriter
+		if _, err := fmt.Fprintln(writer, "METHOD\tREQUEST\tRESPONSE\tSTREAMING"); err != nil {
+			return err
+		}
+	}
+	for _, method := range methods {
+		if asJSON {
+			data, err := json.Marshal(method)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(writer, string(data)); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", method.Name, method.InputType, method.OutputType, getStreamingString(method)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getStreamingString(method *extimage.Method) string {
+	switch {
+	case method.ClientStreaming && method.ServerStreaming:
+		return "bidi"
+	case method.ClientStreaming:
+		return "client"
+	case method.ServerStreaming:
+		return "server"
+	default:
+		return "unary"
+	}
+}
+
+func test(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+) (retErr error) {
+	format, err := internal.ParseFormat(testFormatFlagName, flags.Format, internal.BasicFormats...)
+	if err != nil {
+		return err
+	}
+	asJSON := format.IsJSON()
+	env, fileAnnotations, err := internal.NewBufosEnvReader(
+		logger,
+		testInputFlagName,
+		testConfigFlagName,
+	).ReadEnv(
+		ctx,
+		cliEnv.Stdin(),
+		cliEnv.Getenv,
+		flags.Input,
+		flags.Config,
+		nil,   // we want all files
+		false, // this is ignored since we do not specify specific files
+		false, // imports are not tested
+		false, // we do not need source info
+	)
+	if err != nil {
+		return err
+	}
+	if len(fileAnnotations) > 0 {
+		if err := extfile.PrintFileAnnotations(cliEnv.Stdout(), fileAnnotations, false); err != nil {
+			return err
+		}
+		return errors.New("")
+	}
+	if len(env.Config.Tests) == 0 {
+		return errors.New("no tests are defined in the tests section of the config")
+	}
+	results, err := bufassert.Run(env.Config.Tests, env.Image)
+	if err != nil {
+		return err
+	}
+	if err := printTestResults(cliEnv.Stdout(), results, asJSON); err != nil {
+		return err
+	}
+	for _, result := range results {
+		if !result.Passed() {
+			return errors.New("")
+		}
+	}
+	return nil
+}
+
+// printTestResults prints PASS or FAIL and the name of each test, followed by
+// the failures of the test if any.
+//
+// The json format prints one result per line.
+func printTestResults(writer io.Writer, results []*bufassert.Result, asJSON bool) error {
+	for _, result := range results {
+		if asJSON {
+			data, err := json.Marshal(newExternalTestResult(result))
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(writer, string(data)); err != nil {
+				return err
+			}
+			continue
+		}
+		status := "PASS"
+		if !result.Passed() {
+			status = "FAIL"
+		}
+		if _, err := fmt.Fprintf(writer, "%s %s\n", status, result.Name); err != nil {
+			return err
+		}
+		for _, failure := range result.Failures {
+			if _, err := fmt.Fprintf(writer, "    %s\n", failure); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type externalTestResult struct {
+	Name     string   `json:"name,omitempty" yaml:"name,omitempty"`
+	Passed   bool     `json:"passed" yaml:"passed"`
+	Failures []string `json:"failures,omitempty" yaml:"failures,omitempty"`
+}
+
+func newExternalTestResult(result *bufassert.Result) *externalTestResult {
+	return &externalTestResult{
+		Name:     result.Name,
+		Passed:   result.Passed(),
+		Failures: result.Failures,
+	}
+}
+
+func query(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+) (retErr error) {
+	format, err := internal.ParseFormat(queryFormatFlagName, flags.Format, internal.BasicFormats...)
+	if err != nil {
+		return err
+	}
+	asJSON := format.IsJSON()
+	q, err := bufquery.Parse(cliEnv.Args()[0])
+	if err != nil {
+		return fmt.Errorf("query: %v", err)
+	}
+	env, fileAnnotations, err := internal.NewBufosEnvReader(
+		logger,
+		queryInputFlagName,
+		queryConfigFlagName,
+	).ReadEnv(
+		ctx,
+		cliEnv.Stdin(),
+		cliEnv.Getenv,
+		flags.Input,
+		flags.Config,
+		nil,   // we want all files
+		false, // this is ignored since we do not specify specific files
+		false, // imports are not queried
+		true,  // we need source info for comments and locations
+	)
+	if err != nil {
+		return err
+	}
+	if len(fileAnnotations) > 0 {
+		if err := extfile.PrintFileAnnotations(cliEnv.Stdout(), fileAnnotations, false); err != nil {
+			return err
+		}
+		return errors.New("")
+	}
+	results, err := q.Run(ctx, env.Image)
+	if err != nil {
+		return err
+	}
+	if env.Resolver != nil {
+		for _, result := range results {
+			result.Path, err = getRealFilePathOrName(env.Resolver, result.Path)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return printQueryResults(cliEnv.Stdout(), results, asJSON)
+}
+
+// printQueryResults prints each result as path:line:column:full_name, or only
+// the path for files.
+//
+// The json format prints one result per line.
+func printQueryResults(writer io.Writer, results []*bufquery.Result, asJSON bool) error {
+	for _, result := range results {
+		if asJSON {
+			data, err := json.Marshal(result)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(writer, string(data)); err != nil {
+				return err
+			}
+			continue
+		}
+		if result.FullName == "" {
+			if _, err := fmt.Fprintln(writer, result.Path); err != nil {
+				return err
+			}
+			continue
+		}
+		line := result.StartLine
+		if line == 0 {
+			line = 1
+		}
+		column := result.StartColumn
+		if column == 0 {
+			column = 1
+		}
+		if _, err := fmt.Fprintf(writer, "%s:%d:%d:%s\n", result.Path, line, column, result.FullName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func generate(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+) (retErr error) {
+	config, err := getGenerateConfig(flags.Template)
+	if err != nil {
+		return err
+	}
+	env, fileAnnotations, err := internal.NewBufosEnvReader(
+		logger,
+		generateInputFlagName,
+		generateConfigFlagName,
+	).ReadEnv(
+		ctx,
+		cliEnv.Stdin(),
+		cliEnv.Getenv,
+		flags.Input,
+		flags.Config,
+		nil,   // we want all files
+		false, // this is ignored since we do not specify specific files
+		true,  // plugins need imports to resolve types
+		true,  // plugins use source info for comments
+	)
+	if err != nil {
+		return err
+	}
+	if len(fileAnnotations) > 0 {
+		if err := extfile.PrintFileAnnotations(cliEnv.Stdout(), fileAnnotations, false); err != nil {
+			return err
+		}
+		return errors.New("")
+	}
+	return bufgen.NewGenerator(
+		logger,
+		bufplugin.NewExecutor(logger),
+	).Generate(
+		ctx,
+		cliEnv.Getenv,
+		config,
+		env.Image,
+	)
+}
+
+// getGenerateConfig reads the generation template from the file if the value
+// has a .yaml or .json extension, and otherwise parses the value as data.
+func getGenerateConfig(value string) (*bufgen.Config, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, fmt.Errorf("--%s is required", generateTemplateFlagName)
+	}
+	data := []byte(value)
+	switch filepath.Ext(value) {
+	case ".json", ".yaml":
+		var err error
+		data, err = ioutil.ReadFile(value)
+		if err != nil {
+			return nil, fmt.Errorf("--%s: could not read file: %v", generateTemplateFlagName, err)
+		}
+	}
+	config, err := bufgen.GetConfigForData(data)
+	if err != nil {
+		return nil, fmt.Errorf("--%s: %v", generateTemplateFlagName, err)
+	}
+	return config, nil
+}
+
+func format(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+) (retErr error) {
+	if fileInfo, err := os.Stat(flags.Input); err != nil || !fileInfo.IsDir() {
+		return fmt.Errorf("--%s must be a directory", formatInputFlagName)
+	}
+	filePaths, err := internal.NewBufosEnvReader(
+		logger,
+		formatInputFlagName,
+		formatConfigFlagName,
+	).ListFiles(
+		ctx,
+		cliEnv.Stdin(),
+		cliEnv.Getenv,
+		flags.Input,
+		flags.Config,
+	)
+	if err != nil {
+		return err
+	}
+	numUnformattedFiles := 0
+	for _, filePath := range filePaths {
+		data, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		formatted, err := bufformat.Format(filePath, data)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(data, formatted) {
+			numUnformattedFiles++
+			if flags.Write {
+				fileInfo, err := os.Stat(filePath)
+				if err != nil {
+					return err
+				}
+				if err := ioutil.WriteFile(filePath, formatted, fileInfo.Mode()); err != nil {
+					return err
+				}
+			}
+			if flags.Diff {
+				diff, err := utildiff.Diff(data, formatted, filePath)
+				if err != nil {
+					return err
+				}
+				if _, err := cliEnv.Stdout().Write(diff); err != nil {
+					return err
+				}
+			}
+		}
+		if !flags.Write && !flags.Diff {
+			if _, err := cliEnv.Stdout().Write(formatted); err != nil {
+				return err
+			}
+		}
+	}
+	logger.Debug("format", zap.Int("num_unformatted_files", numUnformattedFiles))
+	if flags.ExitCode && numUnformattedFiles > 0 {
+		return errors.New("")
+	}
+	return nil
+}
+
+func convert(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+) (retErr error) {
+	if flags.Type == "" {
+		return fmt.Errorf("--%s is required", convertTypeFlagName)
+	}
+	fromMessageRef, err := bufconvert.ParseMessageRef(convertFromFlagName, flags.From)
+	if err != nil {
+		return err
+	}
+	toMessageRef, err := bufconvert.ParseMessageRef(convertToFlagName, flags.To)
+	if err != nil {
+		return err
+	}
+	if fromMessageRef.Path == "-" && strings.HasPrefix(strings.TrimSpace(flags.Input), "-") {
+		return fmt.Errorf("--%s and --%s cannot both read from stdin", convertInputFlagName, convertFromFlagName)
+	}
+	env, fileAnnotations, err := internal.NewBufosEnvReader(
+		logger,
+		convertInputFlagName,
+		convertConfigFlagName,
+	).ReadEnv(
+		ctx,
+		cliEnv.Stdin(),
+		cliEnv.Getenv,
+		flags.Input,
+		flags.Config,
+		nil,   // we want all files
+		false, // this is ignored since we do not specify specific files
+		true,  // message types can be defined in imports
+		false, // we do not need source info
+	)
+	if err != nil {
+		return err
+	}
+	if len(fileAnnotations) > 0 {
+		if err := extfile.PrintFileAnnotations(cliEnv.Stdout(), fileAnnotations, false); err != nil {
+			return err
+		}
+		return errors.New("")
+	}
+	converter, err := bufconvert.NewConverter(env.Image)
+	if err != nil {
+		return err
+	}
+	var data []byte
+	if fromMessageRef.Path == "-" {
+		data, err = ioutil.ReadAll(cliEnv.Stdin())
+	} else {
+		data, err = ioutil.ReadFile(fromMessageRef.Path)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: could not read message: %v", convertFromFlagName, err)
+	}
+	output, err := converter.Convert(flags.Type, data, fromMessageRef.Encoding, toMessageRef.Encoding)
+	if err != nil {
+		return err
+	}
+	if toMessageRef.Path == "-" {
+		_, err := cliEnv.Stdout().Write(output)
+		return err
+	}
+	return ioutil.WriteFile(toMessageRef.Path, output, 0644)
+}
+
+func export(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+) (retErr error) {
+	output := strings.TrimSpace(flags.Output)
+	if output == "" {
+		return fmt.Errorf("--%s is required", exportOutputFlagName)
+	}
+	bucket := storagemem.NewBucket()
+	defer func() {
+		retErr = multierr.Append(retErr, bucket.Close())
+	}()
+	fileAnnotations, err := internal.NewBufosEnvReader(
+		logger,
+		exportInputFlagName,
+		exportConfigFlagName,
+		bufos.EnvReaderWithDependencyImages(dependencyImageFlagName, flags.DependencyImages...),
+	).ExportSource(
+		ctx,
+		cliEnv.Stdin(),
+		cliEnv.Getenv,
+		flags.Input,
+		flags.Config,
+		flags.Files, // we filter on files
+		false,       // input files must exist
+		bucket,
+	)
+	if err != nil {
+		return err
+	}
+	if len(fileAnnotations) > 0 {
+		if err := extfile.PrintFileAnnotations(cliEnv.Stdout(), fileAnnotations, false); err != nil {
+			return err
+		}
+		return errors.New("")
+	}
+	if output == "-" {
+		return storageutil.Tar(ctx, cliEnv.Stdout(), bucket, "")
+	}
+	switch {
+	case strings.HasSuffix(output, ".zip"), strings.HasSuffix(output, ".tar"), strings.HasSuffix(output, ".tar.gz"), strings.HasSuffix(output, ".tgz"):
+		file, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			retErr = multierr.Append(retErr, file.Close())
+		}()
+		switch {
+		case strings.HasSuffix(output, ".zip"):
+			return storageutil.Zip(ctx, file, bucket, "")
+		case strings.HasSuffix(output, ".tar"):
+			return storageutil.Tar(ctx, file, bucket, "")
+		default:
+			return storageutil.Targz(ctx, file, bucket, "")
+		}
+	default:
+		if err := os.MkdirAll(output, 0755); err != nil {
+			return err
+		}
+		outputBucket, err := storageos.NewBucket(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			retErr = multierr.Append(retErr, outputBucket.Close())
+		}()
+		count, err := storageutil.Copy(ctx, bucket, outputBucket, "")
+		if err != nil {
+			return err
+		}
+		logger.Debug("export", zap.Int("num_files", count))
+		return nil
+	}
+}
+
+func curl(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+) (retErr error) {
+	if flags.Method == "" {
+		return fmt.Errorf("--%s is required", curlMethodFlagName)
+	}
+	header, err := getCurlHeader(flags.Headers)
+	if err != nil {
+		return err
+	}
+	invokerOptions := []bufcurl.InvokerOption{
+		bufcurl.InvokerWithHeader(header),
+	}
+	if flags.Plaintext {
+		if flags.Insecure || flags.CACert != "" || flags.Cert != "" || flags.Key != "" || flags.ServerName != "" {
+			return fmt.Errorf("--%s cannot be used with TLS flags", curlPlaintextFlagName)
+		}
+		invokerOptions = append(invokerOptions, bufcurl.InvokerWithPlaintext())
+	} else {
+		tlsConfig, err := getCurlTLSConfig(flags)
 		if err != nil {
 			return err
 		}
-		checkers, err = config.Breaking.GetCheckers(flags.CheckerCategories...)
+		invokerOptions = append(invokerOptions, bufcurl.InvokerWithTLSConfig(tlsConfig))
+	}
+	if strings.HasPrefix(strings.TrimSpace(flags.Data), "@-") && strings.HasPrefix(strings.TrimSpace(flags.Input), "-") {
+		return fmt.Errorf("--%s and --%s cannot both read from stdin", curlInputFlagName, curlDataFlagName)
+	}
+	requestData := []byte(flags.Data)
+	if strings.HasPrefix(flags.Data, "@") {
+		if path := strings.TrimPrefix(flags.Data, "@"); path == "-" {
+			requestData, err = ioutil.ReadAll(cliEnv.Stdin())
+		} else {
+			requestData, err = ioutil.ReadFile(path)
+		}
 		if err != nil {
+			return fmt.Errorf("--%s: could not read request: %v", curlDataFlagName, err)
+		}
+	}
+	env, fileAnnotations, err := internal.NewBufosEnvReader(
+		logger,
+		curlInputFlagName,
+		curlConfigFlagName,
+	).ReadEnv(
+		ctx,
+		cliEnv.Stdin(),
+		cliEnv.Getenv,
+		flags.Input,
+		flags.Config,
+		nil,   // we want all files
+		false, // this is ignored since we do not specify specific files
+		true,  // request and response types can be defined in imports
+		false, // we do not need source info
+	)
+	if err != nil {
+		return err
+	}
+	if len(fileAnnotations) > 0 {
+		if err := extfile.PrintFileAnnotations(cliEnv.Stdout(), fileAnnotations, false); err != nil {
 			return err
 		}
+		return errors.New("")
+	}
+	invoker, err := bufcurl.NewInvoker(logger, env.Image, invokerOptions...)
+	if err != nil {
+		return err
+	}
+	return invoker.Invoke(ctx, cliEnv.Args()[0], flags.Method, requestData, cliEnv.Stdout())
+}
+
+// getCurlHeader parses headers of the form "key: value".
+func getCurlHeader(values []string) (http.Header, error) {
+	header := make(http.Header)
+	for _, value := range values {
+		split := strings.SplitN(value, ":", 2)
+		if len(split) != 2 || strings.TrimSpace(split[0]) == "" {
+			return nil, fmt.Errorf("--%s: %q must be of the form \"key: value\"", curlHeaderFlagName, value)
+		}
+		header.Add(strings.TrimSpace(split[0]), strings.TrimSpace(split[1]))
 	}
-	return bufcheck.PrintCheckers(cliEnv.Stdout(), checkers, asJSON)
+	return header, nil
 }
 
-func lsFiles(
+func getCurlTLSConfig(flags *Flags) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: flags.Insecure,
+		ServerName:         flags.ServerName,
+	}
+	if flags.CACert != "" {
+		data, err := ioutil.ReadFile(flags.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("--%s: %v", curlCACertFlagName, err)
+		}
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("--%s: no certificates found in %s", curlCACertFlagName, flags.CACert)
+		}
+		tlsConfig.RootCAs = certPool
+	}
+	if (flags.Cert == "") != (flags.Key == "") {
+		return nil, fmt.Errorf("--%s and --%s must be specified together", curlCertFlagName, curlKeyFlagName)
+	}
+	if flags.Cert != "" {
+		certificate, err := tls.LoadX509KeyPair(flags.Cert, flags.Key)
+		if err != nil {
+			return nil, fmt.Errorf("--%s: %v", curlCertFlagName, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
+
+func doc(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+) error {
+	var templateData string
+	if flags.DocTemplate != "" {
+		data, err := ioutil.ReadFile(flags.DocTemplate)
+		if err != nil {
+			return fmt.Errorf("%s: could not read template: %v", docTemplateFlagName, err)
+		}
+		templateData = string(data)
+	}
+	env, fileAnnotations, err := internal.NewBufosEnvReader(
+		logger,
+		docInputFlagName,
+		docConfigFlagName,
+	).ReadEnv(
+		ctx,
+		cliEnv.Stdin(),
+		cliEnv.Getenv,
+		flags.Input,
+		flags.Config,
+		nil,   // we want all files
+		false, // this is ignored since we do not specify specific files
+		false, // imports are not documented
+		true,  // we need source info for comments
+	)
+	if err != nil {
+		return err
+	}
+	if len(fileAnnotations) > 0 {
+		if err := extfile.PrintFileAnnotations(cliEnv.Stdout(), fileAnnotations, false); err != nil {
+			return err
+		}
+		return errors.New("")
+	}
+	documentation, err := bufdoc.NewDoc(ctx, env.Image)
+	if err != nil {
+		return err
+	}
+	buffer := bytes.NewBuffer(nil)
+	if err := bufdoc.Render(buffer, documentation, flags.DocFormat, templateData); err != nil {
+		return err
+	}
+	if flags.DocOutput == "-" {
+		_, err := cliEnv.Stdout().Write(buffer.Bytes())
+		return err
+	}
+	return ioutil.WriteFile(flags.DocOutput, buffer.Bytes(), 0644)
+}
+
+func initConfig(
 	ctx context.Context,
 	cliEnv clienv.Env,
 	flags *Flags,
 	logger *zap.Logger,
 ) (retErr error) {
-	filePaths, err := internal.NewBufosEnvReader(
+	dirPath := "."
+	if args := cliEnv.Args(); len(args) > 0 {
+		dirPath = args[0]
+	}
+	configFilePath := filepath.Join(dirPath, bufconfig.ConfigFilePath)
+	if _, err := os.Stat(configFilePath); err == nil && !flags.Force {
+		return fmt.Errorf("%s already exists, use --%s to overwrite it", configFilePath, initForceFlagName)
+	}
+	bucket, err := storageos.NewReadBucket(dirPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, bucket.Close())
+	}()
+	roots, err := bufconfig.InferRoots(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	var lintUse []string
+	var breakingUse []string
+	if flags.Interactive {
+		reader := bufio.NewReader(cliEnv.Stdin())
+		roots, err = promptValues(reader, cliEnv.Stderr(), "Roots", roots, ".")
+		if err != nil {
+			return err
+		}
+		if len(roots) == 1 && roots[0] == "." {
+			roots = nil
+		}
+		lintUse, err = promptValues(reader, cliEnv.Stderr(), "Lint categories or checkers", buflint.GetDefaultCategories(), "")
+		if err != nil {
+			return err
+		}
+		breakingUse, err = promptValues(reader, cliEnv.Stderr(), "Breaking categories or checkers", bufbreaking.GetDefaultCategories(), "")
+		if err != nil {
+			return err
+		}
+	}
+	data, err := bufconfig.NewInitConfigData(roots, lintUse, breakingUse)
+	if err != nil {
+		return err
+	}
+	// make sure the prompted values are valid before writing
+	if _, err := bufconfig.NewProvider(logger).GetConfigForData(data); err != nil {
+		return err
+	}
+	logger.Debug("init", zap.String("path", configFilePath), zap.Strings("roots", roots))
+	return ioutil.WriteFile(configFilePath, data, 0644)
+}
+
+func push(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+) error {
+	moduleReference, err := parseModuleReferenceArg(cliEnv.Args()[0])
+	if err != nil {
+		return err
+	}
+	httpClient, err := newHTTPClient(cliEnv, flags, logger)
+	if err != nil {
+		return err
+	}
+	env, fileAnnotations, err := internal.NewBufosEnvReader(
 		logger,
-		lsFilesInputFlagName,
-		lsFilesConfigFlagName,
-	).ListFiles(
+		pushSourceFlagName,
+		pushSourceConfigFlagName,
+		bufos.EnvReaderWithDependencyImages(dependencyImageFlagName, flags.DependencyImages...),
+		bufos.EnvReaderWithHTTPClient(httpClient),
+	).ReadSourceEnv(
 		ctx,
 		cliEnv.Stdin(),
 		cliEnv.Getenv,
 		flags.Input,
 		flags.Config,
+		nil,   // we push all files
+		false, // this is ignored since we do not specify specific files
+		true,  // modules include imports so that they can be built on their own
+		true,  // modules include source info so that comments are available
 	)
 	if err != nil {
 		return err
 	}
-	for _, filePath := range filePaths {
-		if _, err := fmt.Fprintln(cliEnv.Stdout(), filePath); err != nil {
+	if len(fileAnnotations) > 0 {
+		if err := extfile.PrintFileAnnotations(cliEnv.Stdout(), fileAnnotations, false); err != nil {
+			return err
+		}
+		return errors.New("")
+	}
+	imageData, err := proto.Marshal(env.Image)
+	if err != nil {
+		return err
+	}
+	configData, err := bufconfig.NewModuleConfigData(env.Config)
+	if err != nil {
+		return err
+	}
+	logger.Debug("push", zap.Stringer("module", moduleReference), zap.Int("image_size", len(imageData)))
+	return bufregistry.NewClient(httpClient, cliEnv.Getenv).Push(
+		ctx,
+		moduleReference,
+		&bufregistry.Module{
+			Image:  imageData,
+			Config: configData,
+		},
+	)
+}
+
+func pull(
+	ctx context.Context,
+	cliEnv clienv.Env,
+	flags *Flags,
+	logger *zap.Logger,
+) error {
+	if strings.TrimSpace(flags.Output) == "" {
+		return fmt.Errorf("--%s is required", pullOutputFlagName)
+	}
+	moduleReference, err := parseModuleReferenceArg(cliEnv.Args()[0])
+	if err != nil {
+		return err
+	}
+	httpClient, err := newHTTPClient(cliEnv, flags, logger)
+	if err != nil {
+		return err
+	}
+	// the module is read as an input so that allowed hosts are respected and the image is validated
+	env, err := internal.NewBufosEnvReader(
+		logger,
+		"module",
+		"",
+		bufos.EnvReaderWithHTTPClient(httpClient),
+	).ReadImageEnv(
+		ctx,
+		cliEnv.Stdin(),
+		cliEnv.Getenv,
+		bufregistry.ModuleReferencePrefix+moduleReference.String(),
+		"",    // modules use the config they were pushed with
+		nil,   // we pull all files
+		false, // this is ignored since we do not specify specific files
+		true,  // include imports
+	)
+	if err != nil {
+		return err
+	}
+	if err := internal.NewBufosImageWriter(
+		logger,
+		pullOutputFlagName,
+		bufos.ImageWriterWithHTTPClient(httpClient),
+	).WriteImage(
+		ctx,
+		cliEnv.Stdout(),
+		cliEnv.Getenv,
+		flags.Output,
+		false,
+		env.Image,
+	); err != nil {
+		return err
+	}
+	if flags.ConfigOutput == "" {
+		return nil
+	}
+	configData, err := bufconfig.NewModuleConfigData(env.Config)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(flags.ConfigOutput, configData, 0644)
+}
+
+// parseModuleReferenceArg parses the module argument of buf push and buf pull.
+func parseModuleReferenceArg(value string) (*bufregistry.ModuleReference, error) {
+	if !strings.HasPrefix(value, bufregistry.ModuleReferencePrefix) {
+		return nil, fmt.Errorf("module %q must be of the form %sremote/owner/name:version", value, bufregistry.ModuleReferencePrefix)
+	}
+	return bufregistry.ParseModuleReference(strings.TrimPrefix(value, bufregistry.ModuleReferencePrefix))
+}
+
+// promptValues prints the prompt with the default values to the writer, and reads
+// a line of comma or space separated values from the reader.
+//
+// Returns the default values if the line is empty. If there are no default values,
+// emptyDefault is printed as the default instead.
+func promptValues(
+	reader *bufio.Reader,
+	writer io.Writer,
+	prompt string,
+	defaultValues []string,
+	emptyDefault string,
+) ([]string, error) {
+	defaultString := strings.Join(defaultValues, ",")
+	if defaultString == "" {
+		defaultString = emptyDefault
+	}
+	if _, err := fmt.Fprintf(writer, "%s [%s]: ", prompt, defaultString); err != nil {
+		return nil, err
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	values := strings.FieldsFunc(line, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	if len(values) == 0 {
+		return defaultValues, nil
+	}
+	return values, nil
+}
+
+// getRealFilePathOrName returns the real file path of the file, or the name if
+// the file is not within the input, such as for imports.
+func getRealFilePathOrName(resolver bufbuild.ProtoRealFilePathResolver, name string) (string, error) {
+	realFilePath, err := resolver.GetRealFilePath(name)
+	if err != nil {
+		return "", err
+	}
+	if realFilePath == "" {
+		return name, nil
+	}
+	return realFilePath, nil
+}
+
+func printCustomOptions(writer io.Writer, customOptions []*extimage.CustomOption, asJSON bool) (retErr error) {
+	if len(customOptions) == 0 {
+		return nil
+	}
+	if !asJSON {
+		tabWriter := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+		defer func() {
+			retErr = multierr.Append(retErr, tabWriter.Flush())
+		}()
+		writer = tabWriter
+		if _, err := fmt.Fprintln(writer, "NAME\tEXTENDEE\tNUMBER\tCOUNT\tDEFINED IN\tFILES"); err != nil {
+			return err
+		}
+	}
+	for _, customOption := range customOptions {
+		if asJSON {
+			data, err := json.Marshal(customOption)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(writer, string(data)); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := fmt.Fprintf(
+			writer,
+			"%s\t%s\t%d\t%d\t%s\t%s\n",
+			customOption.Name,
+			customOption.Extendee,
+			customOption.Number,
+			customOption.Count,
+			customOption.DefiningFile,
+			strings.Join(customOption.Files, ","),
+		); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,165 @@
+// Package bufwasm implements the functions exported by the buf WebAssembly module.
+//
+// This package only uses in-memory storage, and must not depend on the OS, so that
+// it can be compiled with GOOS=js GOARCH=wasm.
+package bufwasm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/bufbuild/buf/internal/buf/bufbuild"
+	"github.com/bufbuild/buf/internal/buf/bufcheck/buflint"
+	"github.com/bufbuild/buf/internal/buf/bufconfig"
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+	"github.com/bufbuild/buf/internal/pkg/storage"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagemem"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagepath"
+	"github.com/bufbuild/buf/internal/pkg/util/utilencoding"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+// LintRequest is a request to build and lint files.
+//
+// Files are the contents of the files by path. Config is the content of a buf.yaml
+// as YAML or JSON. If Config is empty, the buf.yaml within Files is used if present,
+// otherwise the default config is used.
+type LintRequest struct {
+	Files  map[string]string `json:"files,omitempty"`
+	Config string            `json:"config,omitempty"`
+}
+
+// LintResponse is the response to a LintRequest.
+//
+// If the files failed to build, FileAnnotations contains the build errors.
+// Failed is true if the FileAnnotations should fail the lint run according to
+// the lint failure policy. Build errors always fail.
+type LintResponse struct {
+	FileAnnotations []*filev1beta1.FileAnnotation `json:"file_annotations,omitempty"`
+	Failed          bool                          `json:"failed,omitempty"`
+	Error           string                        `json:"error,omitempty"`
+}
+
+// Lint builds and lints the files of the JSON LintRequest and returns the JSON LintResponse.
+//
+// Errors are returned in the Error field of the response.
+func Lint(requestData []byte) []byte {
+	response := &LintResponse{}
+	if err := lint(context.Background(), zap.NewNop(), requestData, response); err != nil {
+		response.Error = err.Error()
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		// this should never happen, but the caller always expects a JSON response
+		data, _ = json.Marshal(&LintResponse{Error: err.Error()})
+	}
+	return data
+}
+
+func lint(
+	ctx context.Context,
+	logger *zap.Logger,
+	requestData []byte,
+	response *LintResponse,
+) (retErr error) {
+	request := &LintRequest{}
+	if err := utilencoding.UnmarshalJSONStrict(requestData, request); err != nil {
+		return err
+	}
+	if len(request.Files) == 0 {
+		return errors.New("files are required")
+	}
+	bucket, err := newBucket(ctx, request.Files)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, bucket.Close())
+	}()
+	configProvider := bufconfig.NewProvider(logger)
+	var config *bufconfig.Config
+	if request.Config != "" {
+		config, err = configProvider.GetConfigForData([]byte(request.Config))
+	} else {
+		config, err = configProvider.GetConfigForBucket(ctx, bucket)
+	}
+	if err != nil {
+		return err
+	}
+	if config.LintExtends != "" {
+		// there is no network access to fetch the extended config
+		return errors.New("lint.extends is not supported")
+	}
+	buildHandler := bufbuild.NewHandler(logger)
+	protoFileSet, err := buildHandler.Files(
+		ctx,
+		bucket,
+		bufbuild.FilesOptions{
+			Roots:    config.Build.Roots,
+			Excludes: config.Build.Excludes,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	image, fileAnnotations, err := buildHandler.Build(
+		ctx,
+		bucket,
+		protoFileSet,
+		bufbuild.BuildOptions{
+			IncludeSourceInfo: true, // we must include source info for linting
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if len(fileAnnotations) > 0 {
+		response.FileAnnotations = fileAnnotations
+		response.Failed = true
+		return nil
+	}
+	fileAnnotations, err = buflint.NewHandler(
+		logger,
+		buflint.NewRunner(logger),
+	).LintCheck(
+		ctx,
+		config.Lint,
+		image,
+	)
+	if err != nil {
+		return err
+	}
+	if err := bufbuild.FixFileAnnotationPaths(protoFileSet, fileAnnotations); err != nil {
+		return err
+	}
+	response.FileAnnotations = fileAnnotations
+	response.Failed = config.Lint.FailurePolicy.ShouldFail(fileAnnotations)
+	return nil
+}
+
+// newBucket returns a new memory bucket with the files.
+func newBucket(ctx context.Context, pathToContent map[string]string) (_ storage.Bucket, retErr error) {
+	bucket := storagemem.NewBucket()
+	defer func() {
+		if retErr != nil {
+			retErr = multierr.Append(retErr, bucket.Close())
+		}
+	}()
+	for path, content := range pathToContent {
+		normalizedPath, err := storagepath.NormalizeAndValidate(path)
+		if err != nil {
+			return nil, err
+		}
+		writeObject, err := bucket.Put(ctx, normalizedPath, uint32(len(content)))
+		if err != nil {
+			return nil, err
+		}
+		_, err = writeObject.Write([]byte(content))
+		if err := multierr.Append(err, writeObject.Close()); err != nil {
+			return nil, err
+		}
+	}
+	return bucket, nil
+}
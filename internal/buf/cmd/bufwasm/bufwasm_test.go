@@ -0,0 +1,88 @@
+package bufwasm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+	response := testLint(
+		t,
+		&LintRequest{
+			Files: map[string]string{
+				"a/v1/a.proto": `syntax = "proto3"; package a.v1; message foo {}`,
+			},
+		},
+	)
+	assert.Empty(t, response.Error)
+	assert.True(t, response.Failed)
+	require.Len(t, response.FileAnnotations, 1)
+	assert.Equal(t, "MESSAGE_PASCAL_CASE", response.FileAnnotations[0].Type)
+	assert.Equal(t, "a/v1/a.proto", response.FileAnnotations[0].Path)
+
+	response = testLint(
+		t,
+		&LintRequest{
+			Files: map[string]string{
+				"a/v1/a.proto": `syntax = "proto3"; package a.v1; message foo {}`,
+				"buf.yaml":     "lint:\n  except:\n    - MESSAGE_PASCAL_CASE\n",
+			},
+		},
+	)
+	assert.Empty(t, response.Error)
+	assert.False(t, response.Failed)
+	assert.Empty(t, response.FileAnnotations)
+
+	response = testLint(
+		t,
+		&LintRequest{
+			Files: map[string]string{
+				"proto/a/v1/a.proto": `syntax = "proto3"; package a.v1; message foo {}`,
+			},
+			Config: `{"build":{"roots":["proto"]},"lint":{"use":["FIELD_LOWER_SNAKE_CASE"]}}`,
+		},
+	)
+	assert.Empty(t, response.Error)
+	assert.False(t, response.Failed)
+	assert.Empty(t, response.FileAnnotations)
+}
+
+func TestLintBuildError(t *testing.T) {
+	t.Parallel()
+	response := testLint(
+		t,
+		&LintRequest{
+			Files: map[string]string{
+				"a.proto": `syntax = "proto3"; message Foo { Bar one = 1; }`,
+			},
+		},
+	)
+	assert.Empty(t, response.Error)
+	assert.True(t, response.Failed)
+	require.NotEmpty(t, response.FileAnnotations)
+	assert.Equal(t, "a.proto", response.FileAnnotations[0].Path)
+}
+
+func TestLintError(t *testing.T) {
+	t.Parallel()
+	response := &LintResponse{}
+	require.NoError(t, json.Unmarshal(Lint([]byte(`{"foo":"bar"}`)), response))
+	assert.NotEmpty(t, response.Error)
+	response = testLint(t, &LintRequest{})
+	assert.Equal(t, "files are required", response.Error)
+	response = testLint(t, &LintRequest{Files: map[string]string{"../a.proto": ""}})
+	assert.NotEmpty(t, response.Error)
+}
+
+func testLint(t *testing.T, request *LintRequest) *LintResponse {
+	requestData, err := json.Marshal(request)
+	require.NoError(t, err)
+	response := &LintResponse{}
+	data := Lint(requestData)
+	require.NoError(t, json.Unmarshal(data, response), string(data))
+	return response
+}
@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// FormatText is a format.
+	FormatText Format = 1
+	// FormatJSON is a format.
+	FormatJSON Format = 2
+	// FormatJSONL is a format.
+	FormatJSONL Format = 3
+	// FormatConfigIgnoreYAML is a format.
+	FormatConfigIgnoreYAML Format = 4
+	// FormatJUnit is a format.
+	FormatJUnit Format = 5
+	// FormatTemplate is a format.
+	FormatTemplate Format = 6
+)
+
+var (
+	// BasicFormats are the formats of commands that only print text or JSON.
+	BasicFormats = []Format{
+		FormatText,
+		FormatJSON,
+		FormatJSONL,
+	}
+	// BreakingFormats are the formats of breaking change detection.
+	BreakingFormats = []Format{
+		FormatText,
+		FormatJSON,
+		FormatJSONL,
+		FormatJUnit,
+		FormatTemplate,
+	}
+	// LintFormats are the formats of linting.
+	LintFormats = []Format{
+		FormatText,
+		FormatJSON,
+		FormatJSONL,
+		FormatConfigIgnoreYAML,
+		FormatJUnit,
+		FormatTemplate,
+	}
+
+	formatToString = map[Format]string{
+		FormatText:             "text",
+		FormatJSON:             "json",
+		FormatJSONL:            "jsonl",
+		FormatConfigIgnoreYAML: "config-ignore-yaml",
+		FormatJUnit:            "junit",
+		FormatTemplate:         "template",
+	}
+	stringToFormat = map[string]Format{
+		"text":               FormatText,
+		"json":               FormatJSON,
+		"jsonl":              FormatJSONL,
+		"config-ignore-yaml": FormatConfigIgnoreYAML,
+		"junit":              FormatJUnit,
+		"template":           FormatTemplate,
+	}
+)
+
+// Format is an output format of a command.
+type Format int
+
+// String returns the string value of f.
+func (f Format) String() string {
+	s, ok := formatToString[f]
+	if !ok {
+		return strconv.Itoa(int(f))
+	}
+	return s
+}
+
+// IsJSON returns true if f prints JSON.
+//
+// This is true for both json and jsonl, as JSON is always printed with one
+// object per line.
+func (f Format) IsJSON() bool {
+	return f == FormatJSON || f == FormatJSONL
+}
+
+// ParseFormat parses the format of the flag.
+//
+// The empty string is text. Returns error if the format is not one of allowedFormats.
+func ParseFormat(flagName string, value string, allowedFormats ...Format) (Format, error) {
+	s := strings.TrimSpace(strings.ToLower(value))
+	if s == "" {
+		s = FormatText.String()
+	}
+	format, ok := stringToFormat[s]
+	if ok {
+		for _, allowedFormat := range allowedFormats {
+			if format == allowedFormat {
+				return format, nil
+			}
+		}
+	}
+	allowedStrings := make([]string, len(allowedFormats))
+	for i, allowedFormat := range allowedFormats {
+		allowedStrings[i] = allowedFormat.String()
+	}
+	return 0, fmt.Errorf("--%s: unknown format: %q, must be one of %s", flagName, s, strings.Join(allowedStrings, ", "))
+}
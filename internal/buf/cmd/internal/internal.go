@@ -1,8 +1,14 @@
 package internal
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,6 +17,7 @@ import (
 	"github.com/bufbuild/buf/internal/buf/bufcheck/buflint"
 	"github.com/bufbuild/buf/internal/buf/bufconfig"
 	"github.com/bufbuild/buf/internal/buf/bufos"
+	"github.com/bufbuild/buf/internal/pkg/util/utilhttp"
 	"go.uber.org/zap"
 )
 
@@ -20,10 +27,42 @@ const (
 	inputSSHKeyFileEnvKey         = "BUF_INPUT_SSH_KEY_FILE"
 	inputSSHKeyPassphraseEnvKey   = "BUF_INPUT_SSH_KEY_PASSPHRASE"
 	inputSSHKnownHostsFilesEnvKey = "BUF_INPUT_SSH_KNOWN_HOSTS_FILES"
+	inputGitPureGoEnvKey          = "BUF_INPUT_GIT_PURE_GO"
+	inputAllowedHostsEnvKey       = "BUF_INPUT_ALLOWED_HOSTS"
+	gitPathEnvKey                 = "BUF_GIT_PATH"
+
+	defaultGitPath = "git"
+)
+
+const (
+	// DefaultHTTPTimeout is the default timeout for HTTP requests for remote inputs and outputs.
+	DefaultHTTPTimeout = 5 * time.Second
+
+	httpInitialBackoff = time.Second
 )
 
 var defaultHTTPClient = &http.Client{
-	Timeout: 5 * time.Second,
+	Timeout: DefaultHTTPTimeout,
+}
+
+// NewHTTPClient returns a new HTTP client for remote inputs and outputs.
+//
+// Each attempt of a request is limited to the timeout, and requests that fail with
+// a network error or a retryable status code are retried up to retries times with
+// exponential backoff. Proxies are read from HTTP_PROXY, HTTPS_PROXY, and NO_PROXY
+// with getenv.
+func NewHTTPClient(
+	logger *zap.Logger,
+	getenv func(string) string,
+	timeout time.Duration,
+	retries int,
+) *http.Client {
+	return utilhttp.NewClient(
+		utilhttp.ClientWithLogger(logger.Named("http")),
+		utilhttp.ClientWithTimeout(timeout),
+		utilhttp.ClientWithRetries(retries, httpInitialBackoff),
+		utilhttp.ClientWithProxyFromEnv(getenv),
+	)
 }
 
 // NewBufosEnvReader returns a new bufos.EnvReader.
@@ -31,6 +70,7 @@ func NewBufosEnvReader(
 	logger *zap.Logger,
 	inputFlagName string,
 	configOverrideFlagName string,
+	options ...bufos.EnvReaderOption,
 ) bufos.EnvReader {
 	return bufos.NewEnvReader(
 		logger,
@@ -44,6 +84,9 @@ func NewBufosEnvReader(
 		inputSSHKeyFileEnvKey,
 		inputSSHKeyPassphraseEnvKey,
 		inputSSHKnownHostsFilesEnvKey,
+		inputGitPureGoEnvKey,
+		inputAllowedHostsEnvKey,
+		options...,
 	)
 }
 
@@ -51,74 +94,200 @@ func NewBufosEnvReader(
 func NewBufosImageWriter(
 	logger *zap.Logger,
 	outputFlagName string,
+	options ...bufos.ImageWriterOption,
 ) bufos.ImageWriter {
 	return bufos.NewImageWriter(
 		logger,
+		defaultHTTPClient,
 		outputFlagName,
+		options...,
 	)
 }
 
 // NewBuflintHandler returns a new buflint.Handler.
 func NewBuflintHandler(
 	logger *zap.Logger,
+	runnerOptions ...buflint.RunnerOption,
 ) buflint.Handler {
 	return buflint.NewHandler(
 		logger,
-		buflint.NewRunner(logger),
+		buflint.NewRunner(logger, runnerOptions...),
 	)
 }
 
 // NewBufbreakingHandler returns a new bufbreaking.Handler.
 func NewBufbreakingHandler(
 	logger *zap.Logger,
+	runnerOptions ...bufbreaking.RunnerOption,
 ) bufbreaking.Handler {
 	return bufbreaking.NewHandler(
 		logger,
-		bufbreaking.NewRunner(logger),
+		bufbreaking.NewRunner(logger, runnerOptions...),
 	)
 }
 
-// IsFormatJSON returns true if the format is JSON.
+// GitChangedFilePaths returns the paths of the files within the directory that
+// differ between the git ref and the working tree, including untracked files.
 //
-// This will probably eventually need to be split between the image/check flags
-// and the ls flags as we may have different formats for each.
-func IsFormatJSON(flagName string, format string) (bool, error) {
-	switch s := strings.TrimSpace(strings.ToLower(format)); s {
-	case "text", "":
-		return false, nil
-	case "json":
-		return true, nil
-	default:
-		return false, fmt.Errorf("--%s: unknown format: %q", flagName, s)
+// The returned paths are joined with dirPath and cleaned.
+// This requires git to be installed, see runGit.
+func GitChangedFilePaths(ctx context.Context, getenv func(string) string, dirPath string, ref string) (map[string]struct{}, error) {
+	diffOutput, err := runGit(ctx, getenv, dirPath, "diff", "--name-only", "--relative", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+	untrackedOutput, err := runGit(ctx, getenv, dirPath, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
 	}
+	filePaths := make(map[string]struct{})
+	for _, line := range append(strings.Split(diffOutput, "\n"), strings.Split(untrackedOutput, "\n")...) {
+		if line = strings.TrimSpace(line); line != "" {
+			filePaths[filepath.Join(dirPath, filepath.FromSlash(line))] = struct{}{}
+		}
+	}
+	return filePaths, nil
 }
 
-// IsLintFormatJSON returns true if the format is JSON for lint.
+// GitHeadCommit returns the hash of the HEAD commit of the git repository
+// that contains the directory.
 //
-// Also allows config-ignore-yaml.
-func IsLintFormatJSON(flagName string, format string) (bool, error) {
-	switch s := strings.TrimSpace(strings.ToLower(format)); s {
-	case "text", "":
-		return false, nil
-	case "json":
-		return true, nil
-	case "config-ignore-yaml":
-		return false, nil
-	default:
-		return false, fmt.Errorf("--%s: unknown format: %q", flagName, s)
+// This requires git to be installed, see runGit.
+func GitHeadCommit(ctx context.Context, getenv func(string) string, dirPath string) (string, error) {
+	output, err := runGit(ctx, getenv, dirPath, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
 	}
+	return strings.TrimSpace(output), nil
+}
+
+// GitBlame is the git blame information for a line.
+type GitBlame struct {
+	// Commit is the hash of the commit that last modified the line.
+	Commit string
+	// Author is the name of the author of the commit.
+	Author string
+	// AuthorMail is the email of the author of the commit, without angle brackets.
+	AuthorMail string
+	// AuthorTime is the author time of the commit.
+	AuthorTime time.Time
 }
 
-// IsLintFormatConfigIgnoreYAML returns true if the format is config-ignore-yaml.
-func IsLintFormatConfigIgnoreYAML(flagName string, format string) (bool, error) {
-	switch s := strings.TrimSpace(strings.ToLower(format)); s {
-	case "text", "":
-		return false, nil
-	case "json":
-		return false, nil
-	case "config-ignore-yaml":
-		return true, nil
-	default:
-		return false, fmt.Errorf("--%s: unknown format: %q", flagName, s)
+// GitBlameLine returns the git blame information for the given line of the file.
+//
+// The line is 1-indexed. This returns nil if the line is not committed.
+// This requires git to be installed, see runGit.
+func GitBlameLine(ctx context.Context, getenv func(string) string, filePath string, line int) (*GitBlame, error) {
+	lineToGitBlame, err := gitBlame(ctx, getenv, filePath, "-L", fmt.Sprintf("%d,%d", line, line))
+	if err != nil {
+		return nil, err
+	}
+	return lineToGitBlame[line], nil
+}
+
+// GitBlameFile returns the git blame information for all lines of the file.
+//
+// The map is keyed by the 1-indexed line. Lines that are not committed are not
+// in the map. This requires git to be installed, see runGit.
+func GitBlameFile(ctx context.Context, getenv func(string) string, filePath string) (map[int]*GitBlame, error) {
+	return gitBlame(ctx, getenv, filePath)
+}
+
+func gitBlame(ctx context.Context, getenv func(string) string, filePath string, args ...string) (map[int]*GitBlame, error) {
+	output, err := runGit(
+		ctx,
+		getenv,
+		filepath.Dir(filePath),
+		append(
+			append([]string{"blame", "--line-porcelain"}, args...),
+			"--",
+			filepath.Base(filePath),
+		)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	lineToGitBlame := make(map[int]*GitBlame)
+	var line int
+	var gitBlame *GitBlame
+	for _, outputLine := range strings.Split(output, "\n") {
+		switch {
+		case gitBlame == nil:
+			// the header line is "<commit> <original line> <final line> [<number of lines>]"
+			fields := strings.Fields(outputLine)
+			if len(fields) < 3 {
+				continue
+			}
+			line, err = strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("could not parse git blame output: %v", err)
+			}
+			gitBlame = &GitBlame{
+				Commit: fields[0],
+			}
+		case strings.HasPrefix(outputLine, "\t"):
+			// the content line ends the entry for the line
+			// uncommitted lines have a commit hash of all zeros
+			if strings.Trim(gitBlame.Commit, "0") != "" {
+				lineToGitBlame[line] = gitBlame
+			}
+			gitBlame = nil
+		case strings.HasPrefix(outputLine, "author "):
+			gitBlame.Author = strings.TrimPrefix(outputLine, "author ")
+		case strings.HasPrefix(outputLine, "author-mail "):
+			gitBlame.AuthorMail = strings.Trim(strings.TrimPrefix(outputLine, "author-mail "), "<>")
+		case strings.HasPrefix(outputLine, "author-time "):
+			unixSeconds, err := strconv.ParseInt(strings.TrimPrefix(outputLine, "author-time "), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse git blame output: %v", err)
+			}
+			gitBlame.AuthorTime = time.Unix(unixSeconds, 0).UTC()
+		}
+	}
+	return lineToGitBlame, nil
+}
+
+// runGit runs git with the args in the directory and returns the stdout.
+//
+// The git binary is read from BUF_GIT_PATH with getenv, and defaults to git
+// on the PATH. If the context is done, git and any processes it started are
+// killed. The stderr of git is included in the returned error.
+func runGit(ctx context.Context, getenv func(string) string, dirPath string, args ...string) (string, error) {
+	gitPath := getenv(gitPathEnvKey)
+	if gitPath == "" {
+		gitPath = defaultGitPath
+	}
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	cmd := exec.Command(gitPath, args...)
+	cmd.Dir = dirPath
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", fmt.Errorf("git %s: %v (install git or set %s to the path of the git binary)", strings.Join(args, " "), err, gitPathEnvKey)
+		}
+		return "", fmt.Errorf("git %s: %v", strings.Join(args, " "), err)
+	}
+	doneC := make(chan struct{})
+	killedC := make(chan struct{})
+	go func() {
+		defer close(killedC)
+		select {
+		case <-ctx.Done():
+			_ = killProcessGroup(cmd)
+		case <-doneC:
+		}
+	}()
+	err := cmd.Wait()
+	close(doneC)
+	<-killedC
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return "", fmt.Errorf("git %s: %v", strings.Join(args, " "), ctxErr)
+	}
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
 	}
+	return stdout.String(), nil
 }
@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunGit(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("test scripts require a shell")
+	}
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	okGitPath := testWriteScript(t, dirPath, "ok", `echo "$@"`)
+	failGitPath := testWriteScript(t, dirPath, "fail", `echo "fatal: bad revision" >&2; exit 128`)
+	// the child keeps the output open, so this only returns if the child is killed as well
+	hangGitPath := testWriteScript(t, dirPath, "hang", `sleep 60 & wait`)
+
+	output, err := runGit(context.Background(), testGetenv(okGitPath), dirPath, "diff", "HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, "diff HEAD\n", output)
+
+	_, err = runGit(context.Background(), testGetenv(failGitPath), dirPath, "diff", "HEAD")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "fatal: bad revision")
+
+	_, err = runGit(context.Background(), testGetenv(filepath.Join(dirPath, "missing")), dirPath, "diff", "HEAD")
+	require.Error(t, err)
+
+	_, err = runGit(context.Background(), testGetenv("buf-test-git-not-found"), dirPath, "diff", "HEAD")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), gitPathEnvKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = runGit(ctx, testGetenv(hangGitPath), dirPath, "diff", "HEAD")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), context.DeadlineExceeded.Error())
+	assert.True(t, time.Since(start) < 30*time.Second)
+}
+
+func TestParseFormat(t *testing.T) {
+	t.Parallel()
+	format, err := ParseFormat("error-format", "", LintFormats...)
+	require.NoError(t, err)
+	assert.Equal(t, FormatText, format)
+	format, err = ParseFormat("error-format", " JUnit ", LintFormats...)
+	require.NoError(t, err)
+	assert.Equal(t, FormatJUnit, format)
+	assert.False(t, format.IsJSON())
+	format, err = ParseFormat("error-format", "jsonl", BasicFormats...)
+	require.NoError(t, err)
+	assert.Equal(t, FormatJSONL, format)
+	assert.True(t, format.IsJSON())
+	_, err = ParseFormat("error-format", "config-ignore-yaml", BreakingFormats...)
+	assert.EqualError(t, err, `--error-format: unknown format: "config-ignore-yaml", must be one of text, json, jsonl, junit, template`)
+	_, err = ParseFormat("format", "foo", BasicFormats...)
+	assert.EqualError(t, err, `--format: unknown format: "foo", must be one of text, json, jsonl`)
+}
+
+func testWriteScript(t *testing.T, dirPath string, name string, script string) string {
+	filePath := filepath.Join(dirPath, name)
+	require.NoError(t, ioutil.WriteFile(filePath, []byte("#!/bin/sh\n"+script+"\n"), 0755))
+	return filePath
+}
+
+func testGetenv(gitPath string) func(string) string {
+	return func(key string) string {
+		if key == gitPathEnvKey {
+			return gitPath
+		}
+		return ""
+	}
+}
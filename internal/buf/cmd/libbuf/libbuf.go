@@ -0,0 +1,375 @@
+package libbuf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bufbuild/buf/internal/buf/bufbuild"
+	"github.com/bufbuild/buf/internal/buf/bufconfig"
+	"github.com/bufbuild/buf/internal/buf/cmd/internal"
+	"github.com/bufbuild/buf/internal/buf/ext/extimage"
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+	"github.com/bufbuild/buf/internal/pkg/util/utilencoding"
+	"github.com/bufbuild/cli/clizap"
+	"github.com/golang/protobuf/proto"
+	"go.uber.org/zap"
+)
+
+const defaultTimeout = 2 * time.Minute
+
+// Options are the options common to all requests.
+type Options struct {
+	LogLevel  string        `json:"log_level,omitempty"`
+	LogFormat string        `json:"log_format,omitempty"`
+	Timeout   time.Duration `json:"timeout,omitempty"`
+}
+
+// BuildRequest is a request to build an image.
+//
+// Input is a source input, the same as the value of --input for buf image build,
+// and defaults to ".". Config is either a config override as a JSON object or
+// the path to a config file, the same as the value of --config.
+type BuildRequest struct {
+	Options
+
+	Input               string          `json:"input,omitempty"`
+	Config              json.RawMessage `json:"config,omitempty"`
+	ExcludeImports      bool            `json:"exclude_imports,omitempty"`
+	ExcludeSourceInfo   bool            `json:"exclude_source_info,omitempty"`
+	AsFileDescriptorSet bool            `json:"as_file_descriptor_set,omitempty"`
+}
+
+// BuildResponse is the response to a BuildRequest.
+//
+// If the input failed to build, FileAnnotations contains the build errors and Image is empty.
+type BuildResponse struct {
+	// Image is the serialized Image, or FileDescriptorSet if AsFileDescriptorSet was set.
+	Image           []byte                        `json:"image,omitempty"`
+	FileAnnotations []*filev1beta1.FileAnnotation `json:"file_annotations,omitempty"`
+	Error           string                        `json:"error,omitempty"`
+}
+
+// LintRequest is a request to run the lint checks.
+//
+// Input and Config are the same as for BuildRequest.
+// If Files is set, only these files are checked.
+type LintRequest struct {
+	Options
+
+	Input  string          `json:"input,omitempty"`
+	Config json.RawMessage `json:"config,omitempty"`
+	Files  []string        `json:"files,omitempty"`
+}
+
+// LintResponse is the response to a LintRequest.
+//
+// Failed is true if the FileAnnotations should fail the lint run according
+// to the lint failure policy. Build errors always fail.
+type LintResponse struct {
+	FileAnnotations []*filev1beta1.FileAnnotation `json:"file_annotations,omitempty"`
+	Failed          bool                          `json:"failed,omitempty"`
+	Error           string                        `json:"error,omitempty"`
+}
+
+// BreakingRequest is a request to run the breaking change checks.
+//
+// Input and Config are the same as for BuildRequest. AgainstInput is required,
+// and can be any source or image input, or an alias:name input alias defined
+// in the config of the input.
+type BreakingRequest struct {
+	Options
+
+	Input          string          `json:"input,omitempty"`
+	Config         json.RawMessage `json:"config,omitempty"`
+	AgainstInput   string          `json:"against_input,omitempty"`
+	AgainstConfig  json.RawMessage `json:"against_config,omitempty"`
+	Files          []string        `json:"files,omitempty"`
+	ExcludeImports bool            `json:"exclude_imports,omitempty"`
+}
+
+// BreakingResponse is the response to a BreakingRequest.
+//
+// Failed is true if there are any FileAnnotations.
+type BreakingResponse struct {
+	FileAnnotations []*filev1beta1.FileAnnotation `json:"file_annotations,omitempty"`
+	Failed          bool                          `json:"failed,omitempty"`
+	Error           string                        `json:"error,omitempty"`
+}
+
+// Build builds an image for the JSON BuildRequest and returns the JSON BuildResponse.
+//
+// Errors are returned in the Error field of the response.
+func Build(requestData []byte) []byte {
+	request := &BuildRequest{}
+	response := &BuildResponse{}
+	if err := handle(
+		requestData,
+		request,
+		&request.Options,
+		func(ctx context.Context, logger *zap.Logger) error {
+			return build(ctx, logger, request, response)
+		},
+	); err != nil {
+		response.Error = err.Error()
+	}
+	return marshalResponse(response)
+}
+
+// Lint runs the lint checks for the JSON LintRequest and returns the JSON LintResponse.
+//
+// Errors are returned in the Error field of the response.
+func Lint(requestData []byte) []byte {
+	request := &LintRequest{}
+	response := &LintResponse{}
+	if err := handle(
+		requestData,
+		request,
+		&request.Options,
+		func(ctx context.Context, logger *zap.Logger) error {
+			return lint(ctx, logger, request, response)
+		},
+	); err != nil {
+		response.Error = err.Error()
+	}
+	return marshalResponse(response)
+}
+
+// Breaking runs the breaking change checks for the JSON BreakingRequest and
+// returns the JSON BreakingResponse.
+//
+// Errors are returned in the Error field of the response.
+func Breaking(requestData []byte) []byte {
+	request := &BreakingRequest{}
+	response := &BreakingResponse{}
+	if err := handle(
+		requestData,
+		request,
+		&request.Options,
+		func(ctx context.Context, logger *zap.Logger) error {
+			return breaking(ctx, logger, request, response)
+		},
+	); err != nil {
+		response.Error = err.Error()
+	}
+	return marshalResponse(response)
+}
+
+func build(
+	ctx context.Context,
+	logger *zap.Logger,
+	request *BuildRequest,
+	response *BuildResponse,
+) error {
+	env, fileAnnotations, err := internal.NewBufosEnvReader(
+		logger,
+		"input",
+		"config",
+	).ReadSourceEnv(
+		ctx,
+		strings.NewReader(""),
+		os.Getenv,
+		getInput(request.Input),
+		utilencoding.GetJSONStringOrStringValue(request.Config),
+		nil,   // we do not filter files for images
+		false, // this is ignored since we do not specify specific files
+		!request.ExcludeImports,
+		!request.ExcludeSourceInfo,
+	)
+	if err != nil {
+		return err
+	}
+	if len(fileAnnotations) > 0 {
+		response.FileAnnotations = fileAnnotations
+		return nil
+	}
+	var message proto.Message = env.Image
+	if request.AsFileDescriptorSet {
+		message, err = extimage.ImageToFileDescriptorSet(env.Image)
+		if err != nil {
+			return err
+		}
+	}
+	response.Image, err = proto.Marshal(message)
+	return err
+}
+
+func lint(
+	ctx context.Context,
+	logger *zap.Logger,
+	request *LintRequest,
+	response *LintResponse,
+) error {
+	env, fileAnnotations, err := internal.NewBufosEnvReader(
+		logger,
+		"input",
+		"config",
+	).ReadEnv(
+		ctx,
+		strings.NewReader(""),
+		os.Getenv,
+		getInput(request.Input),
+		utilencoding.GetJSONStringOrStringValue(request.Config),
+		request.Files, // we filter checks for files
+		false,         // input files must exist
+		false,         // do not want to include imports
+		true,          // we must include source info for linting
+	)
+	if err != nil {
+		return err
+	}
+	if len(fileAnnotations) > 0 {
+		response.FileAnnotations = fileAnnotations
+		response.Failed = true
+		return nil
+	}
+	fileAnnotations, err = internal.NewBuflintHandler(logger).LintCheck(
+		ctx,
+		env.Config.Lint,
+		env.Image,
+	)
+	if err != nil {
+		return err
+	}
+	if err := bufbuild.FixFileAnnotationPaths(env.Resolver, fileAnnotations); err != nil {
+		return err
+	}
+	response.FileAnnotations = fileAnnotations
+	response.Failed = env.Config.Lint.FailurePolicy.ShouldFail(fileAnnotations)
+	return nil
+}
+
+func breaking(
+	ctx context.Context,
+	logger *zap.Logger,
+	request *BreakingRequest,
+	response *BreakingResponse,
+) error {
+	if request.AgainstInput == "" {
+		return errors.New("against_input is required")
+	}
+	env, fileAnnotations, err := internal.NewBufosEnvReader(
+		logger,
+		"input",
+		"config",
+	).ReadEnv(
+		ctx,
+		strings.NewReader(""),
+		os.Getenv,
+		getInput(request.Input),
+		utilencoding.GetJSONStringOrStringValue(request.Config),
+		request.Files, // we filter checks for files
+		false,         // files specified must exist on the main input
+		!request.ExcludeImports,
+		true, // we must include source info for this side of the check
+	)
+	if err != nil {
+		return err
+	}
+	if len(fileAnnotations) > 0 {
+		response.FileAnnotations = fileAnnotations
+		response.Failed = true
+		return nil
+	}
+	// aliases are resolved with the config of the input
+	againstInput, err := bufconfig.ResolveInputAlias(env.Config, request.AgainstInput)
+	if err != nil {
+		return fmt.Errorf("against_input: %v", err)
+	}
+	againstEnv, fileAnnotations, err := internal.NewBufosEnvReader(
+		logger,
+		"against_input",
+		"against_config",
+	).ReadEnv(
+		ctx,
+		strings.NewReader(""),
+		os.Getenv,
+		againstInput,
+		utilencoding.GetJSONStringOrStringValue(request.AgainstConfig),
+		request.Files, // we filter checks for files
+		true,          // files are allowed to not exist on the against input
+		!request.ExcludeImports,
+		false, // no need to include source info for against
+	)
+	if err != nil {
+		return err
+	}
+	if len(fileAnnotations) > 0 {
+		// this matches buf check breaking
+		for _, fileAnnotation := range fileAnnotations {
+			if fileAnnotation.Path != "" {
+				fileAnnotation.Path = fileAnnotation.Path + "@against"
+			}
+		}
+		response.FileAnnotations = fileAnnotations
+		response.Failed = true
+		return nil
+	}
+	fileAnnotations, err = internal.NewBufbreakingHandler(logger).BreakingCheck(
+		ctx,
+		env.Config.Breaking,
+		againstEnv.Image,
+		env.Image,
+	)
+	if err != nil {
+		return err
+	}
+	if err := bufbuild.FixFileAnnotationPaths(env.Resolver, fileAnnotations); err != nil {
+		return err
+	}
+	response.FileAnnotations = fileAnnotations
+	response.Failed = len(fileAnnotations) > 0
+	return nil
+}
+
+// handle unmarshals the request, and calls f with a logger and a context with
+// the timeout of the options.
+//
+// The options must be part of the request.
+func handle(
+	requestData []byte,
+	request interface{},
+	options *Options,
+	f func(context.Context, *zap.Logger) error,
+) error {
+	if err := utilencoding.UnmarshalJSONStrict(requestData, request); err != nil {
+		return err
+	}
+	timeout := options.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	logger, err := clizap.NewLogger(os.Stderr, options.LogLevel, options.LogFormat)
+	if err != nil {
+		return err
+	}
+	return f(ctx, logger)
+}
+
+func marshalResponse(response interface{}) []byte {
+	data, err := json.Marshal(response)
+	if err != nil {
+		// this should never happen, but the caller always expects a JSON response
+		data, _ = json.Marshal(
+			&struct {
+				Error string `json:"error"`
+			}{
+				Error: err.Error(),
+			},
+		)
+	}
+	return data
+}
+
+func getInput(input string) string {
+	if input == "" {
+		return "."
+	}
+	return input
+}
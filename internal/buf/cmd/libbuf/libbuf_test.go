@@ -0,0 +1,146 @@
+package libbuf
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuild(t *testing.T) {
+	t.Parallel()
+	dirPath := testWriteFiles(t, map[string]string{"a/v1/a.proto": `syntax = "proto3"; package a.v1; message Foo { string one = 1; }`})
+	defer func() {
+		assert.NoError(t, os.RemoveAll(dirPath))
+	}()
+
+	response := &BuildResponse{}
+	testUnmarshalResponse(t, Build([]byte(`{"input":"`+dirPath+`"}`)), response)
+	assert.Empty(t, response.Error)
+	assert.Empty(t, response.FileAnnotations)
+	image := &imagev1beta1.Image{}
+	require.NoError(t, proto.Unmarshal(response.Image, image))
+	require.Len(t, image.GetFile(), 1)
+	assert.Equal(t, "a/v1/a.proto", image.GetFile()[0].GetName())
+
+	response = &BuildResponse{}
+	testUnmarshalResponse(t, Build([]byte(`{"input":"`+dirPath+`","foo":"bar"}`)), response)
+	assert.NotEmpty(t, response.Error)
+	assert.Empty(t, response.Image)
+}
+
+func TestBuildFileAnnotations(t *testing.T) {
+	t.Parallel()
+	dirPath := testWriteFiles(t, map[string]string{"a.proto": `syntax = "proto3"; message Foo { Bar one = 1; }`})
+	defer func() {
+		assert.NoError(t, os.RemoveAll(dirPath))
+	}()
+
+	response := &BuildResponse{}
+	testUnmarshalResponse(t, Build([]byte(`{"input":"`+dirPath+`"}`)), response)
+	assert.Empty(t, response.Error)
+	assert.Empty(t, response.Image)
+	assert.NotEmpty(t, response.FileAnnotations)
+}
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+	dirPath := testWriteFiles(t, map[string]string{"a/v1/a.proto": `syntax = "proto3"; package a.v1; message foo {}`})
+	defer func() {
+		assert.NoError(t, os.RemoveAll(dirPath))
+	}()
+
+	response := &LintResponse{}
+	testUnmarshalResponse(
+		t,
+		Lint([]byte(`{"input":"`+dirPath+`","config":{"lint":{"use":["MESSAGE_PASCAL_CASE"]}}}`)),
+		response,
+	)
+	assert.Empty(t, response.Error)
+	assert.True(t, response.Failed)
+	require.Len(t, response.FileAnnotations, 1)
+	assert.Equal(t, "MESSAGE_PASCAL_CASE", response.FileAnnotations[0].Type)
+	assert.Equal(t, filepath.Join(dirPath, "a", "v1", "a.proto"), response.FileAnnotations[0].Path)
+
+	response = &LintResponse{}
+	testUnmarshalResponse(
+		t,
+		Lint([]byte(`{"input":"`+dirPath+`","config":{"lint":{"use":["FIELD_LOWER_SNAKE_CASE"]}}}`)),
+		response,
+	)
+	assert.Empty(t, response.Error)
+	assert.False(t, response.Failed)
+	assert.Empty(t, response.FileAnnotations)
+}
+
+func TestBreaking(t *testing.T) {
+	t.Parallel()
+	dirPath := testWriteFiles(t, map[string]string{"a/v1/a.proto": `syntax = "proto3"; package a.v1; message Foo {}`})
+	defer func() {
+		assert.NoError(t, os.RemoveAll(dirPath))
+	}()
+	againstDirPath := testWriteFiles(t, map[string]string{"a/v1/a.proto": `syntax = "proto3"; package a.v1; message Foo { string one = 1; }`})
+	defer func() {
+		assert.NoError(t, os.RemoveAll(againstDirPath))
+	}()
+
+	response := &BreakingResponse{}
+	testUnmarshalResponse(
+		t,
+		Breaking([]byte(`{"input":"`+dirPath+`","against_input":"`+againstDirPath+`"}`)),
+		response,
+	)
+	assert.Empty(t, response.Error)
+	assert.True(t, response.Failed)
+	require.Len(t, response.FileAnnotations, 1)
+	assert.Equal(t, "FIELD_NO_DELETE", response.FileAnnotations[0].Type)
+
+	response = &BreakingResponse{}
+	testUnmarshalResponse(
+		t,
+		Breaking([]byte(`{"input":"`+dirPath+`","against_input":"`+dirPath+`"}`)),
+		response,
+	)
+	assert.Empty(t, response.Error)
+	assert.False(t, response.Failed)
+
+	response = &BreakingResponse{}
+	testUnmarshalResponse(
+		t,
+		Breaking([]byte(`{"input":"`+dirPath+`","config":{"inputs":{"previous":"`+againstDirPath+`"}},"against_input":"alias:previous"}`)),
+		response,
+	)
+	assert.Empty(t, response.Error)
+	assert.True(t, response.Failed)
+	require.Len(t, response.FileAnnotations, 1)
+	assert.Equal(t, "FIELD_NO_DELETE", response.FileAnnotations[0].Type)
+
+	response = &BreakingResponse{}
+	testUnmarshalResponse(t, Breaking([]byte(`{"input":"`+dirPath+`","against_input":"alias:previous"}`)), response)
+	assert.Contains(t, response.Error, `unknown input alias "previous"`)
+
+	response = &BreakingResponse{}
+	testUnmarshalResponse(t, Breaking([]byte(`{"input":"`+dirPath+`"}`)), response)
+	assert.Equal(t, "against_input is required", response.Error)
+}
+
+func testWriteFiles(t *testing.T, pathToContent map[string]string) string {
+	dirPath, err := ioutil.TempDir("", "libbuf")
+	require.NoError(t, err)
+	for path, content := range pathToContent {
+		filePath := filepath.Join(dirPath, filepath.FromSlash(path))
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+		require.NoError(t, ioutil.WriteFile(filePath, []byte(content), 0600))
+	}
+	return dirPath
+}
+
+func testUnmarshalResponse(t *testing.T, data []byte, response interface{}) {
+	require.NoError(t, json.Unmarshal(data, response), string(data))
+}
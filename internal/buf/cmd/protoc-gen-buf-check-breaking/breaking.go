@@ -75,7 +75,7 @@ func Handle(
 		return
 	}
 	envReader = internal.NewBufosEnvReader(logger, "", "input_config")
-	config, err := envReader.GetConfig(ctx, utilencoding.GetJSONStringOrStringValue(externalConfig.InputConfig))
+	config, err := envReader.GetConfig(ctx, env.Getenv, utilencoding.GetJSONStringOrStringValue(externalConfig.InputConfig))
 	if err != nil {
 		responseWriter.WriteError(err.Error())
 		return
@@ -95,13 +95,13 @@ func Handle(
 		responseWriter.WriteError(err.Error())
 		return
 	}
-	asJSON, err := internal.IsFormatJSON("error_format", externalConfig.ErrorFormat)
+	format, err := internal.ParseFormat("error_format", externalConfig.ErrorFormat, internal.BasicFormats...)
 	if err != nil {
 		responseWriter.WriteError(err.Error())
 		return
 	}
 	buffer := bytes.NewBuffer(nil)
-	if err := extfile.PrintFileAnnotations(buffer, fileAnnotations, asJSON); err != nil {
+	if err := extfile.PrintFileAnnotations(buffer, fileAnnotations, format.IsJSON()); err != nil {
 		responseWriter.WriteError(err.Error())
 		return
 	}
@@ -51,7 +51,7 @@ func Handle(
 		return
 	}
 	envReader := internal.NewBufosEnvReader(logger, "", "input_config")
-	config, err := envReader.GetConfig(ctx, utilencoding.GetJSONStringOrStringValue(externalConfig.InputConfig))
+	config, err := envReader.GetConfig(ctx, env.Getenv, utilencoding.GetJSONStringOrStringValue(externalConfig.InputConfig))
 	if err != nil {
 		responseWriter.WriteError(err.Error())
 		return
@@ -70,28 +70,35 @@ func Handle(
 		responseWriter.WriteError(err.Error())
 		return
 	}
-	asJSON, err := internal.IsLintFormatJSON("error_format", externalConfig.ErrorFormat)
-	if err != nil {
-		responseWriter.WriteError(err.Error())
-		return
-	}
-	asConfigIgnoreYAML, err := internal.IsLintFormatConfigIgnoreYAML("error_format", externalConfig.ErrorFormat)
+	format, err := internal.ParseFormat(
+		"error_format",
+		externalConfig.ErrorFormat,
+		internal.FormatText,
+		internal.FormatJSON,
+		internal.FormatJSONL,
+		internal.FormatConfigIgnoreYAML,
+	)
 	if err != nil {
 		responseWriter.WriteError(err.Error())
 		return
 	}
 	buffer := bytes.NewBuffer(nil)
-	if asConfigIgnoreYAML {
+	if format == internal.FormatConfigIgnoreYAML {
 		if err := bufconfig.PrintFileAnnotationsLintConfigIgnoreYAML(buffer, fileAnnotations); err != nil {
 			responseWriter.WriteError(err.Error())
 			return
 		}
 	} else {
-		if err := extfile.PrintFileAnnotations(buffer, fileAnnotations, asJSON); err != nil {
+		if err := extfile.PrintFileAnnotations(buffer, fileAnnotations, format.IsJSON()); err != nil {
 			responseWriter.WriteError(err.Error())
 			return
 		}
 	}
+	if !config.Lint.FailurePolicy.ShouldFail(fileAnnotations) {
+		// the violations are allowed by the failure policy, so only print them
+		_, _ = env.Stderr().Write(buffer.Bytes())
+		return
+	}
 	responseWriter.WriteError(buffer.String())
 }
 
@@ -1,13 +1,19 @@
 package extimage
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"reflect"
 	"sort"
+	"strings"
 
 	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
 	"github.com/bufbuild/buf/internal/pkg/ext/extdescriptor"
 	"github.com/bufbuild/buf/internal/pkg/storage/storagepath"
+	"github.com/bufbuild/buf/internal/pkg/util/utilproto"
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	plugin_go "github.com/golang/protobuf/protoc-gen-go/plugin"
@@ -203,6 +209,354 @@ func ImageWithSpecificNames(
 	return newImage, nil
 }
 
+// ImageNormalized returns a copy of the Image in a canonical form.
+//
+// Files are sorted topologically, so that every file comes after the files it imports,
+// with ties broken by name. Duplicate imports within a file are removed, along with
+// their source code info. The unrecognized fields of all options are sorted by field
+// number, as they are otherwise kept in the order of the original source.
+//
+// The result only depends on the contents of the Files and which Files are imports,
+// so images built from the same files in any order marshal to the same bytes if
+// marshaled deterministically.
+//
+// Backing FileDescriptorProtos are copied.
+//
+// Validates the input and output.
+func ImageNormalized(image *imagev1beta1.Image) (*imagev1beta1.Image, error) {
+	if err := ValidateImage(image); err != nil {
+		return nil, err
+	}
+	importFileIndexes := make(map[int]struct{})
+	for _, imageImportRef := range image.GetBufbuildImageExtension().GetImageImportRefs() {
+		importFileIndexes[int(imageImportRef.GetFileIndex())] = struct{}{}
+	}
+	sortedFileIndexes, err := getTopologicalFileIndexes(image.File)
+	if err != nil {
+		return nil, err
+	}
+	newImage := &imagev1beta1.Image{
+		File: make([]*descriptor.FileDescriptorProto, 0, len(image.File)),
+	}
+	if image.BufbuildImageExtension != nil {
+		newImage.BufbuildImageExtension = &imagev1beta1.ImageExtension{
+			ImageImportRefs: make([]*imagev1beta1.ImageImportRef, 0, len(importFileIndexes)),
+		}
+	}
+	for _, fileIndex := range sortedFileIndexes {
+		file := proto.Clone(image.File[fileIndex]).(*descriptor.FileDescriptorProto)
+		removeDuplicateDependencies(file)
+		if err := normalizeFileOptions(file); err != nil {
+			return nil, fmt.Errorf("%s: %v", file.GetName(), err)
+		}
+		newImage.File = append(newImage.File, file)
+		if _, isImport := importFileIndexes[fileIndex]; isImport {
+			newImage.BufbuildImageExtension.ImageImportRefs = append(
+				newImage.BufbuildImageExtension.ImageImportRefs,
+				&imagev1beta1.ImageImportRef{
+					FileIndex: proto.Uint32(uint32(len(newImage.File) - 1)),
+				},
+			)
+		}
+	}
+	if err := ValidateImage(newImage); err != nil {
+		return nil, err
+	}
+	return newImage, nil
+}
+
+// ImagePackageDigests returns a map from package to the hex-encoded sha256 digest
+// of the Files in the package.
+//
+// Files are deterministically marshalled in sorted order by name, including source
+// code info, so any change to a File within a package will change the digest.
+// Files without a package are mapped to the empty string.
+//
+// Validates the input.
+func ImagePackageDigests(image *imagev1beta1.Image) (map[string]string, error) {
+	if err := ValidateImage(image); err != nil {
+		return nil, err
+	}
+	packageToFiles := make(map[string][]*descriptor.FileDescriptorProto)
+	for _, file := range image.File {
+		packageToFiles[file.GetPackage()] = append(packageToFiles[file.GetPackage()], file)
+	}
+	packageToDigest := make(map[string]string, len(packageToFiles))
+	for pkg, files := range packageToFiles {
+		sort.Slice(files, func(i int, j int) bool { return files[i].GetName() < files[j].GetName() })
+		hash := sha256.New()
+		for _, file := range files {
+			buffer := proto.NewBuffer(nil)
+			buffer.SetDeterministic(true)
+			if err := buffer.Marshal(file); err != nil {
+				return nil, err
+			}
+			_, _ = hash.Write([]byte(file.GetName()))
+			_, _ = hash.Write(buffer.Bytes())
+		}
+		packageToDigest[pkg] = hex.EncodeToString(hash.Sum(nil))
+	}
+	return packageToDigest, nil
+}
+
+// ImageFileDigests returns a map from file name to the hex-encoded sha256 digest
+// of the file.
+//
+// The digest covers the name and the deterministic marshaling of the file, so any change
+// to the file including source code info will result in a new digest.
+func ImageFileDigests(image *imagev1beta1.Image) (map[string]string, error) {
+	if err := ValidateImage(image); err != nil {
+		return nil, err
+	}
+	fileToDigest := make(map[string]string, len(image.File))
+	for _, file := range image.File {
+		buffer := proto.NewBuffer(nil)
+		buffer.SetDeterministic(true)
+		if err := buffer.Marshal(file); err != nil {
+			return nil, err
+		}
+		hash := sha256.New()
+		_, _ = hash.Write([]byte(file.GetName()))
+		_, _ = hash.Write(buffer.Bytes())
+		fileToDigest[file.GetName()] = hex.EncodeToString(hash.Sum(nil))
+	}
+	return fileToDigest, nil
+}
+
+// ImageFileRenames returns a map from the names of the Files in the previous Image
+// to the names of the Files in the Image that they were renamed to.
+//
+// Only non-import Files that exist in one Image but not the other are considered.
+// A File is renamed if its content excluding the name and source code info is
+// identical to a previous File, or otherwise if the similarity of the top-level
+// symbols of the Files is at least 0.5. The similarity is the number of shared
+// fully-qualified names divided by the number of distinct names across both Files.
+// Each File is part of at most one rename, and the most similar Files are matched first.
+//
+// Validates the input.
+func ImageFileRenames(
+	previousImage *imagev1beta1.Image,
+	image *imagev1beta1.Image,
+) (map[string]string, error) {
+	if err := ValidateImage(previousImage); err != nil {
+		return nil, err
+	}
+	if err := ValidateImage(image); err != nil {
+		return nil, err
+	}
+	previousFiles := getNonImportFilesNotInImage(previousImage, image)
+	files := getNonImportFilesNotInImage(image, previousImage)
+	if len(previousFiles) == 0 || len(files) == 0 {
+		return nil, nil
+	}
+	var candidates []*renameCandidate
+	for _, previousFile := range previousFiles {
+		previousContent, err := getFileRenameContent(previousFile)
+		if err != nil {
+			return nil, err
+		}
+		previousSymbols := getFileTopLevelSymbols(previousFile)
+		for _, file := range files {
+			content, err := getFileRenameContent(file)
+			if err != nil {
+				return nil, err
+			}
+			similarity := getSymbolSimilarity(previousSymbols, getFileTopLevelSymbols(file))
+			if bytes.Equal(previousContent, content) {
+				similarity = 1
+			}
+			if similarity >= renameSimilarityThreshold {
+				candidates = append(
+					candidates,
+					&renameCandidate{
+						previousName: previousFile.GetName(),
+						name:         file.GetName(),
+						similarity:   similarity,
+					},
+				)
+			}
+		}
+	}
+	sort.Slice(
+		candidates,
+		func(i int, j int) bool {
+			if candidates[i].similarity != candidates[j].similarity {
+				return candidates[i].similarity > candidates[j].similarity
+			}
+			if candidates[i].previousName != candidates[j].previousName {
+				return candidates[i].previousName < candidates[j].previousName
+			}
+			return candidates[i].name < candidates[j].name
+		},
+	)
+	previousNameToName := make(map[string]string)
+	renamedNames := make(map[string]struct{})
+	for _, candidate := range candidates {
+		if _, ok := previousNameToName[candidate.previousName]; ok {
+			continue
+		}
+		if _, ok := renamedNames[candidate.name]; ok {
+			continue
+		}
+		previousNameToName[candidate.previousName] = candidate.name
+		renamedNames[candidate.name] = struct{}{}
+	}
+	return previousNameToName, nil
+}
+
+// ImageWithRenamedFiles returns a copy of the Image with the Files renamed according
+// to the map from old name to new name.
+//
+// Dependencies on renamed Files are updated to the new names.
+// Backing FileDescriptorProtos are copied if they are modified, otherwise only the
+// references are copied.
+//
+// Validates the input and output.
+func ImageWithRenamedFiles(
+	image *imagev1beta1.Image,
+	oldNameToNewName map[string]string,
+) (*imagev1beta1.Image, error) {
+	if err := ValidateImage(image); err != nil {
+		return nil, err
+	}
+	// If no modifications would be made, then we return the original
+	if len(oldNameToNewName) == 0 {
+		return image, nil
+	}
+	newImage := &imagev1beta1.Image{
+		File:                   make([]*descriptor.FileDescriptorProto, len(image.File)),
+		BufbuildImageExtension: image.BufbuildImageExtension,
+	}
+	for i, file := range image.File {
+		newImage.File[i] = file
+		newName, renamed := oldNameToNewName[file.GetName()]
+		dependencyRenamed := false
+		for _, dependency := range file.Dependency {
+			if _, ok := oldNameToNewName[dependency]; ok {
+				dependencyRenamed = true
+				break
+			}
+		}
+		if !renamed && !dependencyRenamed {
+			continue
+		}
+		newFile := proto.Clone(file).(*descriptor.FileDescriptorProto)
+		if renamed {
+			newFile.Name = proto.String(newName)
+		}
+		for j, dependency := range newFile.Dependency {
+			if newDependency, ok := oldNameToNewName[dependency]; ok {
+				newFile.Dependency[j] = newDependency
+			}
+		}
+		newImage.File[i] = newFile
+	}
+	if err := ValidateImage(newImage); err != nil {
+		return nil, err
+	}
+	return newImage, nil
+}
+
+// ImageWithDependencyImages returns a copy of the Image with the imports of its Files
+// that are not within the Image added from the dependency Images, like the
+// descriptor_set_in flag of protoc.
+//
+// Imports are added transitively and are marked as imports. They are added
+// before the Files of the Image, with every File after the Files it imports.
+// If a File is within more than one dependency Image, all copies must be equal.
+// Returns error if an import is not within the Image or the dependency Images.
+// If there are no dependency Images, returns the original Image.
+// Backing FileDescriptorProtos are not copied, only the references are copied.
+//
+// Validates the input and output.
+func ImageWithDependencyImages(
+	image *imagev1beta1.Image,
+	dependencyImages ...*imagev1beta1.Image,
+) (*imagev1beta1.Image, error) {
+	if err := ValidateImage(image); err != nil {
+		return nil, err
+	}
+	if len(dependencyImages) == 0 {
+		return image, nil
+	}
+	nameToDependencyFile := make(map[string]*descriptor.FileDescriptorProto)
+	for _, dependencyImage := range dependencyImages {
+		if err := ValidateImage(dependencyImage); err != nil {
+			return nil, err
+		}
+		for _, file := range dependencyImage.File {
+			if existingFile, ok := nameToDependencyFile[file.GetName()]; ok {
+				if !proto.Equal(existingFile, file) {
+					return nil, fmt.Errorf("file %q is within multiple dependency images with different contents", file.GetName())
+				}
+				continue
+			}
+			nameToDependencyFile[file.GetName()] = file
+		}
+	}
+	seenNames := make(map[string]struct{}, len(image.File))
+	for _, file := range image.File {
+		seenNames[file.GetName()] = struct{}{}
+	}
+	var importFiles []*descriptor.FileDescriptorProto
+	var addImports func(*descriptor.FileDescriptorProto) error
+	addImports = func(file *descriptor.FileDescriptorProto) error {
+		for _, dependency := range file.Dependency {
+			if _, ok := seenNames[dependency]; ok {
+				continue
+			}
+			dependencyFile, ok := nameToDependencyFile[dependency]
+			if !ok {
+				return fmt.Errorf("import %q of file %q is not within the image or the dependency images", dependency, file.GetName())
+			}
+			seenNames[dependency] = struct{}{}
+			if err := addImports(dependencyFile); err != nil {
+				return err
+			}
+			importFiles = append(importFiles, dependencyFile)
+		}
+		return nil
+	}
+	for _, file := range image.File {
+		if err := addImports(file); err != nil {
+			return nil, err
+		}
+	}
+	if len(importFiles) == 0 {
+		return image, nil
+	}
+	newImage := &imagev1beta1.Image{
+		File: append(importFiles, image.File...),
+		BufbuildImageExtension: &imagev1beta1.ImageExtension{
+			ImageImportRefs: make(
+				[]*imagev1beta1.ImageImportRef,
+				0,
+				len(importFiles)+len(image.GetBufbuildImageExtension().GetImageImportRefs()),
+			),
+		},
+	}
+	for i := range importFiles {
+		newImage.BufbuildImageExtension.ImageImportRefs = append(
+			newImage.BufbuildImageExtension.ImageImportRefs,
+			&imagev1beta1.ImageImportRef{
+				FileIndex: proto.Uint32(uint32(i)),
+			},
+		)
+	}
+	for _, imageImportRef := range image.GetBufbuildImageExtension().GetImageImportRefs() {
+		newImage.BufbuildImageExtension.ImageImportRefs = append(
+			newImage.BufbuildImageExtension.ImageImportRefs,
+			&imagev1beta1.ImageImportRef{
+				FileIndex: proto.Uint32(imageImportRef.GetFileIndex() + uint32(len(importFiles))),
+			},
+		)
+	}
+	if err := ValidateImage(newImage); err != nil {
+		return nil, err
+	}
+	return newImage, nil
+}
+
 // ImageToFileDescriptorSet converts the Image to a native FileDescriptorSet.
 //
 // This strips the backing ImageExtension.
@@ -279,3 +633,792 @@ func CodeGeneratorRequestToImage(request *plugin_go.CodeGeneratorRequest) (*imag
 	}
 	return ImageWithSpecificNames(image, false, request.FileToGenerate...)
 }
+
+// CustomOption is a custom option extension that is set within an Image.
+type CustomOption struct {
+	// Name is the fully-qualified name of the extension, without a leading period.
+	//
+	// This is empty if the extension is not defined within the Image.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Extendee is the fully-qualified name of the options message the extension
+	// extends, without a leading period, i.e. google.protobuf.FieldOptions.
+	Extendee string `json:"extendee,omitempty" yaml:"extendee,omitempty"`
+	// Number is the field number of the extension.
+	Number int `json:"number,omitempty" yaml:"number,omitempty"`
+	// DefiningFile is the name of the File that defines the extension.
+	//
+	// This is empty if the extension is not defined within the Image.
+	DefiningFile string `json:"defining_file,omitempty" yaml:"defining_file,omitempty"`
+	// Count is the number of options messages that set the extension.
+	Count int `json:"count,omitempty" yaml:"count,omitempty"`
+	// Files are the sorted names of the Files that set the extension.
+	Files []string `json:"files,omitempty" yaml:"files,omitempty"`
+}
+
+// ImageCustomOptions returns the custom options set on the non-import Files of the
+// Image, sorted by name, extendee, and number.
+//
+// Extensions are resolved against all Files of the Image, including imports, so
+// the Image should include imports for the names and defining files to be set.
+//
+// Validates the input.
+func ImageCustomOptions(image *imagev1beta1.Image) ([]*CustomOption, error) {
+	if err := ValidateImage(image); err != nil {
+		return nil, err
+	}
+	importNames, err := ImageImportNames(image)
+	if err != nil {
+		return nil, err
+	}
+	importNameMap := make(map[string]struct{}, len(importNames))
+	for _, importName := range importNames {
+		importNameMap[importName] = struct{}{}
+	}
+	keyToCustomOption := make(map[customOptionKey]*CustomOption)
+	for _, file := range image.File {
+		forEachFileExtension(file, func(scope string, extension *descriptor.FieldDescriptorProto) {
+			keyToCustomOption[customOptionKey{
+				extendee: strings.TrimPrefix(extension.GetExtendee(), "."),
+				number:   int(extension.GetNumber()),
+			}] = &CustomOption{
+				Name:         joinFullName(scope, extension.GetName()),
+				Extendee:     strings.TrimPrefix(extension.GetExtendee(), "."),
+				Number:       int(extension.GetNumber()),
+				DefiningFile: file.GetName(),
+			}
+		})
+	}
+	keyToFileNameMap := make(map[customOptionKey]map[string]struct{})
+	for _, file := range image.File {
+		if _, ok := importNameMap[file.GetName()]; ok {
+			continue
+		}
+		var forEachErr error
+		forEachFileOptions(file, func(extendee string, options proto.Message) {
+			if forEachErr != nil {
+				return
+			}
+			numbers, err := getCustomOptionNumbers(options)
+			if err != nil {
+				forEachErr = fmt.Errorf("could not parse options in %q: %v", file.GetName(), err)
+				return
+			}
+			for _, number := range numbers {
+				key := customOptionKey{
+					extendee: extendee,
+					number:   number,
+				}
+				customOption, ok := keyToCustomOption[key]
+				if !ok {
+					customOption = &CustomOption{
+						Extendee: extendee,
+						Number:   number,
+					}
+					keyToCustomOption[key] = customOption
+				}
+				customOption.Count++
+				if _, ok := keyToFileNameMap[key]; !ok {
+					keyToFileNameMap[key] = make(map[string]struct{})
+				}
+				keyToFileNameMap[key][file.GetName()] = struct{}{}
+			}
+		})
+		if forEachErr != nil {
+			return nil, forEachErr
+		}
+	}
+	customOptions := make([]*CustomOption, 0, len(keyToFileNameMap))
+	for key, fileNameMap := range keyToFileNameMap {
+		customOption := keyToCustomOption[key]
+		for fileName := range fileNameMap {
+			customOption.Files = append(customOption.Files, fileName)
+		}
+		sort.Strings(customOption.Files)
+		customOptions = append(customOptions, customOption)
+	}
+	sort.Slice(
+		customOptions,
+		func(i int, j int) bool {
+			one := customOptions[i]
+			two := customOptions[j]
+			if one.Name != two.Name {
+				return one.Name < two.Name
+			}
+			if one.Extendee != two.Extendee {
+				return one.Extendee < two.Extendee
+			}
+			return one.Number < two.Number
+		},
+	)
+	return customOptions, nil
+}
+
+// Package is a package of an Image.
+type Package struct {
+	// Name is the name of the package, which is empty for files without a package.
+	Name string `json:"name" yaml:"name"`
+	// Files are the sorted names of the Files in the package.
+	Files []string `json:"files,omitempty" yaml:"files,omitempty"`
+}
+
+// ImagePackages returns the packages of all Files of the Image, sorted by name.
+//
+// Validates the input.
+func ImagePackages(image *imagev1beta1.Image) ([]*Package, error) {
+	if err := ValidateImage(image); err != nil {
+		return nil, err
+	}
+	nameToPackage := make(map[string]*Package)
+	for _, file := range image.GetFile() {
+		pkg, ok := nameToPackage[file.GetPackage()]
+		if !ok {
+			pkg = &Package{
+				Name: file.GetPackage(),
+			}
+			nameToPackage[pkg.Name] = pkg
+		}
+		pkg.Files = append(pkg.Files, file.GetName())
+	}
+	packages := make([]*Package, 0, len(nameToPackage))
+	for _, pkg := range nameToPackage {
+		sort.Strings(pkg.Files)
+		packages = append(packages, pkg)
+	}
+	sort.Slice(packages, func(i int, j int) bool {
+		return packages[i].Name < packages[j].Name
+	})
+	return packages, nil
+}
+
+// Service is a service of an Image.
+type Service struct {
+	// Name is the fully-qualified name of the service, without a leading period.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// File is the name of the File that defines the service.
+	File string `json:"file,omitempty" yaml:"file,omitempty"`
+	// Methods are the methods of the service in the order they are defined.
+	Methods []*Method `json:"methods,omitempty" yaml:"methods,omitempty"`
+}
+
+// Method is a method of a service of an Image.
+type Method struct {
+	// Name is the fully-qualified name of the method, without a leading period,
+	// i.e. foo.v1.FooService.GetFoo.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Service is the fully-qualified name of the service of the method, without a leading period.
+	Service string `json:"service,omitempty" yaml:"service,omitempty"`
+	// File is the name of the File that defines the method.
+	File string `json:"file,omitempty" yaml:"file,omitempty"`
+	// InputType is the fully-qualified name of the request message, without a leading period.
+	InputType string `json:"input_type,omitempty" yaml:"input_type,omitempty"`
+	// OutputType is the fully-qualified name of the response message, without a leading period.
+	OutputType      string `json:"output_type,omitempty" yaml:"output_type,omitempty"`
+	ClientStreaming bool   `json:"client_streaming,omitempty" yaml:"client_streaming,omitempty"`
+	ServerStreaming bool   `json:"server_streaming,omitempty" yaml:"server_streaming,omitempty"`
+}
+
+// ImageServices returns the services of all Files of the Image, sorted by name.
+//
+// Validates the input.
+func ImageServices(image *imagev1beta1.Image) ([]*Service, error) {
+	if err := ValidateImage(image); err != nil {
+		return nil, err
+	}
+	var services []*Service
+	for _, file := range image.GetFile() {
+		for _, serviceDescriptor := range file.GetService() {
+			service := &Service{
+				Name: joinFullName(file.GetPackage(), serviceDescriptor.GetName()),
+				File: file.GetName(),
+			}
+			for _, methodDescriptor := range serviceDescriptor.GetMethod() {
+				service.Methods = append(
+					service.Methods,
+					&Method{
+						Name:            service.Name + "." + methodDescriptor.GetName(),
+						Service:         service.Name,
+						File:            file.GetName(),
+						InputType:       strings.TrimPrefix(methodDescriptor.GetInputType(), "."),
+						OutputType:      strings.TrimPrefix(methodDescriptor.GetOutputType(), "."),
+						ClientStreaming: methodDescriptor.GetClientStreaming(),
+						ServerStreaming: methodDescriptor.GetServerStreaming(),
+					},
+				)
+			}
+			services = append(services, service)
+		}
+	}
+	sort.Slice(services, func(i int, j int) bool {
+		return services[i].Name < services[j].Name
+	})
+	return services, nil
+}
+
+// ImageShard is a part of an Image split along package boundaries.
+type ImageShard struct {
+	// Image contains the Files of the packages of the shard.
+	//
+	// Files that are imports of the original Image are imports of the shard.
+	Image *imagev1beta1.Image
+	// Packages are the packages of the Files of the shard, in the order of the Files.
+	//
+	// Files without a package are in the package with the empty name.
+	Packages []string
+	// Dependencies are the sorted indexes of the other shards that contain Files
+	// imported by the Files of the shard.
+	Dependencies []int
+}
+
+// ImageShards splits the Image into the given number of shards along package
+// boundaries, so that every package is in exactly one shard.
+//
+// The Files are sorted so that every File comes after the Files it imports, and
+// the packages are assigned in that order to shards of roughly equal serialized
+// size, so shards mostly import Files of earlier shards. Packages that import each
+// other can result in a shard depending on a later shard.
+//
+// Backing FileDescriptorProtos are not copied, only the references are copied.
+//
+// Validates the input and output.
+func ImageShards(image *imagev1beta1.Image, numShards int) ([]*ImageShard, error) {
+	if err := ValidateImage(image); err != nil {
+		return nil, err
+	}
+	if numShards < 1 {
+		return nil, fmt.Errorf("number of shards must be positive but was %d", numShards)
+	}
+	importFileIndexes := make(map[int]struct{})
+	for _, imageImportRef := range image.GetBufbuildImageExtension().GetImageImportRefs() {
+		importFileIndexes[int(imageImportRef.GetFileIndex())] = struct{}{}
+	}
+	topologicalFileIndexes, err := getTopologicalFileIndexes(image.File)
+	if err != nil {
+		return nil, err
+	}
+	var packages []string
+	packageToFileIndexes := make(map[string][]int)
+	packageToSize := make(map[string]int)
+	totalSize := 0
+	for _, fileIndex := range topologicalFileIndexes {
+		file := image.File[fileIndex]
+		pkg := file.GetPackage()
+		if _, ok := packageToFileIndexes[pkg]; !ok {
+			packages = append(packages, pkg)
+		}
+		packageToFileIndexes[pkg] = append(packageToFileIndexes[pkg], fileIndex)
+		size := proto.Size(file)
+		packageToSize[pkg] += size
+		totalSize += size
+	}
+	if len(packages) < numShards {
+		return nil, fmt.Errorf("cannot split an image with %d packages into %d shards", len(packages), numShards)
+	}
+
+	// the shard k ideally starts at totalSize*k/numShards bytes, but every shard
+	// must have at least one package
+	var shardPackages [][]string
+	size := 0
+	for i, pkg := range packages {
+		numRemainingShards := numShards - len(shardPackages)
+		if len(shardPackages) == 0 ||
+			(numRemainingShards > 0 &&
+				(size >= totalSize*len(shardPackages)/numShards || len(packages)-i == numRemainingShards)) {
+			shardPackages = append(shardPackages, nil)
+		}
+		shardPackages[len(shardPackages)-1] = append(shardPackages[len(shardPackages)-1], pkg)
+		size += packageToSize[pkg]
+	}
+
+	nameToShardIndex := make(map[string]int, len(image.File))
+	for shardIndex, pkgs := range shardPackages {
+		for _, pkg := range pkgs {
+			for _, fileIndex := range packageToFileIndexes[pkg] {
+				nameToShardIndex[image.File[fileIndex].GetName()] = shardIndex
+			}
+		}
+	}
+	imageShards := make([]*ImageShard, 0, len(shardPackages))
+	for shardIndex, pkgs := range shardPackages {
+		shardImage := &imagev1beta1.Image{}
+		dependencyMap := make(map[int]struct{})
+		for _, pkg := range pkgs {
+			for _, fileIndex := range packageToFileIndexes[pkg] {
+				file := image.File[fileIndex]
+				if _, isImport := importFileIndexes[fileIndex]; isImport {
+					if shardImage.BufbuildImageExtension == nil {
+						shardImage.BufbuildImageExtension = &imagev1beta1.ImageExtension{}
+					}
+					shardImage.BufbuildImageExtension.ImageImportRefs = append(
+						shardImage.BufbuildImageExtension.ImageImportRefs,
+						&imagev1beta1.ImageImportRef{
+							FileIndex: proto.Uint32(uint32(len(shardImage.File))),
+						},
+					)
+				}
+				shardImage.File = append(shardImage.File, file)
+				for _, dependency := range file.GetDependency() {
+					if dependencyShardIndex, ok := nameToShardIndex[dependency]; ok && dependencyShardIndex != shardIndex {
+						dependencyMap[dependencyShardIndex] = struct{}{}
+					}
+				}
+			}
+		}
+		if err := ValidateImage(shardImage); err != nil {
+			return nil, err
+		}
+		dependencies := make([]int, 0, len(dependencyMap))
+		for dependency := range dependencyMap {
+			dependencies = append(dependencies, dependency)
+		}
+		sort.Ints(dependencies)
+		imageShards = append(
+			imageShards,
+			&ImageShard{
+				Image:        shardImage,
+				Packages:     pkgs,
+				Dependencies: dependencies,
+			},
+		)
+	}
+	return imageShards, nil
+}
+
+// getTopologicalFileIndexes returns the indexes of the files sorted so that every
+// file comes after the files it imports, with ties broken by name.
+//
+// Imports that are not within the files are ignored.
+func getTopologicalFileIndexes(files []*descriptor.FileDescriptorProto) ([]int, error) {
+	nameToIndex := make(map[string]int, len(files))
+	names := make([]string, len(files))
+	for i, file := range files {
+		nameToIndex[file.GetName()] = i
+		names[i] = file.GetName()
+	}
+	sort.Strings(names)
+	// 1 is visiting, 2 is visited
+	states := make([]int, len(files))
+	sortedIndexes := make([]int, 0, len(files))
+	var visit func(int) error
+	visit = func(index int) error {
+		switch states[index] {
+		case 1:
+			return fmt.Errorf("import cycle including %s", files[index].GetName())
+		case 2:
+			return nil
+		}
+		states[index] = 1
+		dependencies := append([]string{}, files[index].GetDependency()...)
+		sort.Strings(dependencies)
+		for _, dependency := range dependencies {
+			if dependencyIndex, ok := nameToIndex[dependency]; ok {
+				if err := visit(dependencyIndex); err != nil {
+					return err
+				}
+			}
+		}
+		states[index] = 2
+		sortedIndexes = append(sortedIndexes, index)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(nameToIndex[name]); err != nil {
+			return nil, err
+		}
+	}
+	return sortedIndexes, nil
+}
+
+// removeDuplicateDependencies removes the duplicate imports of the file.
+//
+// The public and weak dependency indexes and the source code info are updated to match.
+func removeDuplicateDependencies(file *descriptor.FileDescriptorProto) {
+	// the index of each dependency within the new dependencies, or -1 if removed
+	dependencyIndexes := make([]int32, len(file.Dependency))
+	dependencyToIndex := make(map[string]int32, len(file.Dependency))
+	dependencies := make([]string, 0, len(file.Dependency))
+	for i, dependency := range file.Dependency {
+		if _, ok := dependencyToIndex[dependency]; ok {
+			dependencyIndexes[i] = -1
+			continue
+		}
+		dependencyToIndex[dependency] = int32(len(dependencies))
+		dependencyIndexes[i] = int32(len(dependencies))
+		dependencies = append(dependencies, dependency)
+	}
+	if len(dependencies) == len(file.Dependency) {
+		return
+	}
+	publicDependencies, publicDependencyIndexes := remapDependencyIndexes(file.Dependency, file.PublicDependency, dependencyToIndex)
+	weakDependencies, weakDependencyIndexes := remapDependencyIndexes(file.Dependency, file.WeakDependency, dependencyToIndex)
+	file.Dependency = dependencies
+	file.PublicDependency = publicDependencies
+	file.WeakDependency = weakDependencies
+	if file.SourceCodeInfo == nil {
+		return
+	}
+	// the paths of the dependency, public_dependency, and weak_dependency fields
+	pathFieldNumberToIndexes := map[int32][]int32{
+		3:  dependencyIndexes,
+		10: publicDependencyIndexes,
+		11: weakDependencyIndexes,
+	}
+	locations := make([]*descriptor.SourceCodeInfo_Location, 0, len(file.SourceCodeInfo.Location))
+	for _, location := range file.SourceCodeInfo.Location {
+		if len(location.Path) >= 2 {
+			if indexes, ok := pathFieldNumberToIndexes[location.Path[0]]; ok {
+				oldIndex := location.Path[1]
+				if oldIndex < 0 || int(oldIndex) >= len(indexes) || indexes[oldIndex] < 0 {
+					continue
+				}
+				location.Path[1] = indexes[oldIndex]
+			}
+		}
+		locations = append(locations, location)
+	}
+	file.SourceCodeInfo.Location = locations
+}
+
+// remapDependencyIndexes remaps the public or weak dependency indexes from the old
+// dependencies to the new dependencies, removing duplicates.
+//
+// Also returns the index of each old public or weak dependency index within the
+// new ones, or -1 if removed.
+func remapDependencyIndexes(
+	oldDependencies []string,
+	oldDependencyIndexes []int32,
+	dependencyToIndex map[string]int32,
+) ([]int32, []int32) {
+	var newDependencyIndexes []int32
+	indexes := make([]int32, len(oldDependencyIndexes))
+	seenDependencyIndexes := make(map[int32]struct{}, len(oldDependencyIndexes))
+	for i, oldDependencyIndex := range oldDependencyIndexes {
+		indexes[i] = -1
+		if oldDependencyIndex < 0 || int(oldDependencyIndex) >= len(oldDependencies) {
+			continue
+		}
+		newDependencyIndex := dependencyToIndex[oldDependencies[oldDependencyIndex]]
+		if _, ok := seenDependencyIndexes[newDependencyIndex]; ok {
+			continue
+		}
+		seenDependencyIndexes[newDependencyIndex] = struct{}{}
+		indexes[i] = int32(len(newDependencyIndexes))
+		newDependencyIndexes = append(newDependencyIndexes, newDependencyIndex)
+	}
+	return newDependencyIndexes, indexes
+}
+
+// normalizeFileOptions sorts the unrecognized fields of all options within the file.
+func normalizeFileOptions(file *descriptor.FileDescriptorProto) error {
+	if err := normalizeOptions(file.Options); err != nil {
+		return err
+	}
+	for _, message := range file.MessageType {
+		if err := normalizeMessageOptions(message); err != nil {
+			return err
+		}
+	}
+	for _, enum := range file.EnumType {
+		if err := normalizeEnumOptions(enum); err != nil {
+			return err
+		}
+	}
+	for _, service := range file.Service {
+		if err := normalizeOptions(service.Options); err != nil {
+			return err
+		}
+		for _, method := range service.Method {
+			if err := normalizeOptions(method.Options); err != nil {
+				return err
+			}
+		}
+	}
+	for _, extension := range file.Extension {
+		if err := normalizeOptions(extension.Options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func normalizeMessageOptions(message *descriptor.DescriptorProto) error {
+	if err := normalizeOptions(message.Options); err != nil {
+		return err
+	}
+	for _, field := range append(append([]*descriptor.FieldDescriptorProto{}, message.Field...), message.Extension...) {
+		if err := normalizeOptions(field.Options); err != nil {
+			return err
+		}
+	}
+	for _, oneof := range message.OneofDecl {
+		if err := normalizeOptions(oneof.Options); err != nil {
+			return err
+		}
+	}
+	for _, extensionRange := range message.ExtensionRange {
+		if err := normalizeOptions(extensionRange.Options); err != nil {
+			return err
+		}
+	}
+	for _, nestedMessage := range message.NestedType {
+		if err := normalizeMessageOptions(nestedMessage); err != nil {
+			return err
+		}
+	}
+	for _, enum := range message.EnumType {
+		if err := normalizeEnumOptions(enum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func normalizeEnumOptions(enum *descriptor.EnumDescriptorProto) error {
+	if err := normalizeOptions(enum.Options); err != nil {
+		return err
+	}
+	for _, value := range enum.Value {
+		if err := normalizeOptions(value.Options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizeOptions sorts the unrecognized fields of the options by field number.
+//
+// The order of unrecognized fields with the same number is preserved, as this is
+// the order of the elements of repeated fields. Extensions do not need to be sorted,
+// as they are always marshaled in order of field number. The options can be a nil pointer.
+func normalizeOptions(options proto.Message) error {
+	value := reflect.ValueOf(options)
+	if options == nil || value.IsNil() {
+		return nil
+	}
+	unrecognized := value.Elem().FieldByName("XXX_unrecognized")
+	if !unrecognized.IsValid() || unrecognized.Len() == 0 {
+		return nil
+	}
+	wireFields, err := utilproto.SplitWire(unrecognized.Bytes())
+	if err != nil {
+		return fmt.Errorf("could not parse unrecognized options: %v", err)
+	}
+	sort.SliceStable(
+		wireFields,
+		func(i int, j int) bool {
+			return wireFields[i].Number < wireFields[j].Number
+		},
+	)
+	data := make([]byte, 0, unrecognized.Len())
+	for _, wireField := range wireFields {
+		data = append(data, wireField.Data...)
+	}
+	unrecognized.SetBytes(data)
+	return nil
+}
+
+// renameSimilarityThreshold is the minimum similarity of the top-level symbols
+// of two Files for one to be considered a rename of the other.
+const renameSimilarityThreshold = 0.5
+
+type renameCandidate struct {
+	previousName string
+	name         string
+	similarity   float64
+}
+
+// getNonImportFilesNotInImage returns the non-import Files of the Image whose
+// names are not in the other Image.
+func getNonImportFilesNotInImage(
+	image *imagev1beta1.Image,
+	otherImage *imagev1beta1.Image,
+) []*descriptor.FileDescriptorProto {
+	otherNames := make(map[string]struct{}, len(otherImage.File))
+	for _, file := range otherImage.File {
+		otherNames[file.GetName()] = struct{}{}
+	}
+	importFileIndexes := make(map[int]struct{})
+	for _, imageImportRef := range image.GetBufbuildImageExtension().GetImageImportRefs() {
+		importFileIndexes[int(imageImportRef.GetFileIndex())] = struct{}{}
+	}
+	var files []*descriptor.FileDescriptorProto
+	for i, file := range image.File {
+		if _, isImport := importFileIndexes[i]; isImport {
+			continue
+		}
+		if _, ok := otherNames[file.GetName()]; ok {
+			continue
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+// getFileRenameContent returns the deterministic marshaling of the File
+// without its name and source code info.
+func getFileRenameContent(file *descriptor.FileDescriptorProto) ([]byte, error) {
+	file = proto.Clone(file).(*descriptor.FileDescriptorProto)
+	file.Name = nil
+	file.SourceCodeInfo = nil
+	buffer := proto.NewBuffer(nil)
+	buffer.SetDeterministic(true)
+	if err := buffer.Marshal(file); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// getFileTopLevelSymbols returns the fully-qualified names of the top-level
+// messages, enums, services, and extensions of the File.
+func getFileTopLevelSymbols(file *descriptor.FileDescriptorProto) map[string]struct{} {
+	prefix := ""
+	if file.GetPackage() != "" {
+		prefix = file.GetPackage() + "."
+	}
+	symbols := make(map[string]struct{})
+	for _, message := range file.GetMessageType() {
+		symbols[prefix+message.GetName()] = struct{}{}
+	}
+	for _, enum := range file.GetEnumType() {
+		symbols[prefix+enum.GetName()] = struct{}{}
+	}
+	for _, service := range file.GetService() {
+		symbols[prefix+service.GetName()] = struct{}{}
+	}
+	for _, extension := range file.GetExtension() {
+		symbols[prefix+extension.GetName()] = struct{}{}
+	}
+	return symbols
+}
+
+// getSymbolSimilarity returns the number of shared symbols divided by the number
+// of distinct symbols, or 0 if there are no symbols.
+func getSymbolSimilarity(one map[string]struct{}, two map[string]struct{}) float64 {
+	shared := 0
+	for symbol := range one {
+		if _, ok := two[symbol]; ok {
+			shared++
+		}
+	}
+	distinct := len(one) + len(two) - shared
+	if distinct == 0 {
+		return 0
+	}
+	return float64(shared) / float64(distinct)
+}
+
+// minCustomOptionNumber is the start of the extension range of all options messages.
+const minCustomOptionNumber = 1000
+
+type customOptionKey struct {
+	extendee string
+	number   int
+}
+
+// getCustomOptionNumbers returns the unique numbers of the extensions set on the
+// options message, in the order they first appear.
+func getCustomOptionNumbers(options proto.Message) ([]int, error) {
+	if options == nil || reflect.ValueOf(options).IsNil() {
+		return nil, nil
+	}
+	// this includes extensions and unrecognized fields
+	data, err := proto.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+	wireFields, err := utilproto.SplitWire(data)
+	if err != nil {
+		return nil, err
+	}
+	var numbers []int
+	numberMap := make(map[int]struct{})
+	for _, wireField := range wireFields {
+		if wireField.Number < minCustomOptionNumber {
+			continue
+		}
+		if _, ok := numberMap[wireField.Number]; ok {
+			continue
+		}
+		numberMap[wireField.Number] = struct{}{}
+		numbers = append(numbers, wireField.Number)
+	}
+	return numbers, nil
+}
+
+// forEachFileExtension calls f for each extension defined in the File, including
+// extensions nested in messages, with the fully-qualified name of the enclosing scope.
+func forEachFileExtension(file *descriptor.FileDescriptorProto, f func(string, *descriptor.FieldDescriptorProto)) {
+	for _, extension := range file.GetExtension() {
+		f(file.GetPackage(), extension)
+	}
+	for _, message := range file.GetMessageType() {
+		forEachMessageExtension(joinFullName(file.GetPackage(), message.GetName()), message, f)
+	}
+}
+
+func forEachMessageExtension(messageName string, message *descriptor.DescriptorProto, f func(string, *descriptor.FieldDescriptorProto)) {
+	for _, extension := range message.GetExtension() {
+		f(messageName, extension)
+	}
+	for _, nestedMessage := range message.GetNestedType() {
+		forEachMessageExtension(joinFullName(messageName, nestedMessage.GetName()), nestedMessage, f)
+	}
+}
+
+// forEachFileOptions calls f for each options message in the File with the
+// fully-qualified name of the options message type.
+//
+// The options messages may be nil.
+func forEachFileOptions(file *descriptor.FileDescriptorProto, f func(string, proto.Message)) {
+	f("google.protobuf.FileOptions", file.GetOptions())
+	for _, extension := range file.GetExtension() {
+		f("google.protobuf.FieldOptions", extension.GetOptions())
+	}
+	for _, message := range file.GetMessageType() {
+		forEachMessageOptions(message, f)
+	}
+	for _, enum := range file.GetEnumType() {
+		forEachEnumOptions(enum, f)
+	}
+	for _, service := range file.GetService() {
+		f("google.protobuf.ServiceOptions", service.GetOptions())
+		for _, method := range service.GetMethod() {
+			f("google.protobuf.MethodOptions", method.GetOptions())
+		}
+	}
+}
+
+func forEachMessageOptions(message *descriptor.DescriptorProto, f func(string, proto.Message)) {
+	f("google.protobuf.MessageOptions", message.GetOptions())
+	for _, field := range message.GetField() {
+		f("google.protobuf.FieldOptions", field.GetOptions())
+	}
+	for _, extension := range message.GetExtension() {
+		f("google.protobuf.FieldOptions", extension.GetOptions())
+	}
+	for _, oneof := range message.GetOneofDecl() {
+		f("google.protobuf.OneofOptions", oneof.GetOptions())
+	}
+	for _, extensionRange := range message.GetExtensionRange() {
+		f("google.protobuf.ExtensionRangeOptions", extensionRange.GetOptions())
+	}
+	for _, nestedMessage := range message.GetNestedType() {
+		forEachMessageOptions(nestedMessage, f)
+	}
+	for _, enum := range message.GetEnumType() {
+		forEachEnumOptions(enum, f)
+	}
+}
+
+func forEachEnumOptions(enum *descriptor.EnumDescriptorProto, f func(string, proto.Message)) {
+	f("google.protobuf.EnumOptions", enum.GetOptions())
+	for _, value := range enum.GetValue() {
+		f("google.protobuf.EnumValueOptions", value.GetOptions())
+	}
+}
+
+func joinFullName(scope string, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
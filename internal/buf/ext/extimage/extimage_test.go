@@ -0,0 +1,595 @@
+package extimage
+
+import (
+	"testing"
+
+	imagev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1beta1"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageNormalized(t *testing.T) {
+	t.Parallel()
+	// field 50001 with varint 1, then field 50000 with varint 2
+	unsortedOptions := []byte{0x88, 0xb5, 0x18, 0x01, 0x80, 0xb5, 0x18, 0x02}
+	sortedOptions := []byte{0x80, 0xb5, 0x18, 0x02, 0x88, 0xb5, 0x18, 0x01}
+	image := &imagev1beta1.Image{
+		File: []*descriptor.FileDescriptorProto{
+			{
+				Name:             proto.String("c.proto"),
+				Dependency:       []string{"b.proto", "a.proto", "b.proto"},
+				PublicDependency: []int32{0, 2},
+				Options: &descriptor.FileOptions{
+					XXX_unrecognized: unsortedOptions,
+				},
+				SourceCodeInfo: &descriptor.SourceCodeInfo{
+					Location: []*descriptor.SourceCodeInfo_Location{
+						{Path: []int32{3, 0}},
+						{Path: []int32{3, 1}},
+						{Path: []int32{3, 2}},
+						{Path: []int32{10, 1}},
+					},
+				},
+			},
+			{
+				Name:       proto.String("b.proto"),
+				Dependency: []string{"a.proto"},
+			},
+			{
+				Name: proto.String("a.proto"),
+			},
+		},
+		BufbuildImageExtension: &imagev1beta1.ImageExtension{
+			ImageImportRefs: []*imagev1beta1.ImageImportRef{
+				{FileIndex: proto.Uint32(2)},
+			},
+		},
+	}
+	imageData, err := proto.Marshal(image)
+	require.NoError(t, err)
+
+	normalizedImage, err := ImageNormalized(image)
+	require.NoError(t, err)
+	// the input is not modified
+	newImageData, err := proto.Marshal(image)
+	require.NoError(t, err)
+	assert.Equal(t, imageData, newImageData)
+
+	require.Len(t, normalizedImage.File, 3)
+	assert.Equal(t, "a.proto", normalizedImage.File[0].GetName())
+	assert.Equal(t, "b.proto", normalizedImage.File[1].GetName())
+	assert.Equal(t, "c.proto", normalizedImage.File[2].GetName())
+	require.Len(t, normalizedImage.GetBufbuildImageExtension().GetImageImportRefs(), 1)
+	assert.Equal(t, uint32(0), normalizedImage.GetBufbuildImageExtension().GetImageImportRefs()[0].GetFileIndex())
+	file := normalizedImage.File[2]
+	assert.Equal(t, []string{"b.proto", "a.proto"}, file.Dependency)
+	assert.Equal(t, []int32{0}, file.PublicDependency)
+	assert.Equal(t, sortedOptions, file.GetOptions().XXX_unrecognized)
+	var paths [][]int32
+	for _, location := range file.GetSourceCodeInfo().GetLocation() {
+		paths = append(paths, location.Path)
+	}
+	assert.Equal(t, [][]int32{{3, 0}, {3, 1}}, paths)
+
+	// the result does not depend on the order of the input
+	reversedImage := &imagev1beta1.Image{
+		File: []*descriptor.FileDescriptorProto{image.File[2], image.File[0], image.File[1]},
+		BufbuildImageExtension: &imagev1beta1.ImageExtension{
+			ImageImportRefs: []*imagev1beta1.ImageImportRef{
+				{FileIndex: proto.Uint32(0)},
+			},
+		},
+	}
+	normalizedReversedImage, err := ImageNormalized(reversedImage)
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(normalizedImage, normalizedReversedImage))
+}
+
+func TestImageFileRenames(t *testing.T) {
+	t.Parallel()
+	previousImage := &imagev1beta1.Image{
+		File: []*descriptor.FileDescriptorProto{
+			{
+				Name: proto.String("a.proto"),
+			},
+			{
+				Name:   proto.String("old1.proto"),
+				Syntax: proto.String("proto3"),
+			},
+			{
+				Name:       proto.String("old2.proto"),
+				Package:    proto.String("foo"),
+				Dependency: []string{"old1.proto"},
+				MessageType: []*descriptor.DescriptorProto{
+					{Name: proto.String("Foo")},
+					{Name: proto.String("Bar")},
+				},
+			},
+			{
+				Name:        proto.String("old3.proto"),
+				Package:     proto.String("foo"),
+				MessageType: []*descriptor.DescriptorProto{{Name: proto.String("Baz")}},
+			},
+			{
+				Name: proto.String("same.proto"),
+			},
+		},
+		BufbuildImageExtension: &imagev1beta1.ImageExtension{
+			ImageImportRefs: []*imagev1beta1.ImageImportRef{
+				{FileIndex: proto.Uint32(0)},
+			},
+		},
+	}
+	image := &imagev1beta1.Image{
+		File: []*descriptor.FileDescriptorProto{
+			{
+				// identical content
+				Name:   proto.String("new1.proto"),
+				Syntax: proto.String("proto3"),
+			},
+			{
+				// two of three symbols are shared
+				Name:       proto.String("new2.proto"),
+				Package:    proto.String("foo"),
+				Dependency: []string{"new1.proto"},
+				MessageType: []*descriptor.DescriptorProto{
+					{Name: proto.String("Foo")},
+					{Name: proto.String("Bar")},
+					{Name: proto.String("Bat")},
+				},
+			},
+			{
+				// all symbols are shared
+				Name:    proto.String("new3.proto"),
+				Package: proto.String("foo"),
+				MessageType: []*descriptor.DescriptorProto{
+					{Name: proto.String("Baz")},
+				},
+			},
+			{
+				// one of two symbols is shared, but old3.proto is a better match for new3.proto
+				Name:    proto.String("new4.proto"),
+				Package: proto.String("foo"),
+				MessageType: []*descriptor.DescriptorProto{
+					{Name: proto.String("Baz")},
+					{Name: proto.String("Ban")},
+				},
+			},
+			{
+				Name: proto.String("same.proto"),
+			},
+			{
+				// imports are never renamed
+				Name: proto.String("b.proto"),
+			},
+		},
+		BufbuildImageExtension: &imagev1beta1.ImageExtension{
+			ImageImportRefs: []*imagev1beta1.ImageImportRef{
+				{FileIndex: proto.Uint32(5)},
+			},
+		},
+	}
+	previousNameToName, err := ImageFileRenames(previousImage, image)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		map[string]string{
+			"old1.proto": "new1.proto",
+			"old2.proto": "new2.proto",
+			"old3.proto": "new3.proto",
+		},
+		previousNameToName,
+	)
+
+	renamedImage, err := ImageWithRenamedFiles(previousImage, previousNameToName)
+	require.NoError(t, err)
+	require.Len(t, renamedImage.File, 5)
+	assert.Equal(t, "a.proto", renamedImage.File[0].GetName())
+	assert.Equal(t, "new1.proto", renamedImage.File[1].GetName())
+	assert.Equal(t, "new2.proto", renamedImage.File[2].GetName())
+	assert.Equal(t, []string{"new1.proto"}, renamedImage.File[2].GetDependency())
+	assert.Equal(t, "new3.proto", renamedImage.File[3].GetName())
+	assert.Equal(t, "same.proto", renamedImage.File[4].GetName())
+	// the input is not modified
+	assert.Equal(t, "old2.proto", previousImage.File[2].GetName())
+	assert.Equal(t, []string{"old1.proto"}, previousImage.File[2].GetDependency())
+}
+
+func TestImageCustomOptions(t *testing.T) {
+	t.Parallel()
+	// field 50000 with varint 1
+	option50000 := []byte{0x80, 0xb5, 0x18, 0x01}
+	// field 50001 with varint 1
+	option50001 := []byte{0x88, 0xb5, 0x18, 0x01}
+	// field 50002 with varint 1
+	option50002 := []byte{0x90, 0xb5, 0x18, 0x01}
+	image := &imagev1beta1.Image{
+		File: []*descriptor.FileDescriptorProto{
+			{
+				Name:    proto.String("options.proto"),
+				Package: proto.String("foo"),
+				Extension: []*descriptor.FieldDescriptorProto{
+					{
+						Name:     proto.String("field_option"),
+						Number:   proto.Int32(50000),
+						Extendee: proto.String(".google.protobuf.FieldOptions"),
+					},
+					{
+						Name:     proto.String("unused_option"),
+						Number:   proto.Int32(50003),
+						Extendee: proto.String(".google.protobuf.FieldOptions"),
+					},
+				},
+				MessageType: []*descriptor.DescriptorProto{
+					{
+						Name: proto.String("Scope"),
+						Extension: []*descriptor.FieldDescriptorProto{
+							{
+								Name:     proto.String("message_option"),
+								Number:   proto.Int32(50001),
+								Extendee: proto.String(".google.protobuf.MessageOptions"),
+							},
+						},
+						Options: &descriptor.MessageOptions{
+							XXX_unrecognized: option50001,
+						},
+					},
+				},
+			},
+			{
+				Name:       proto.String("a.proto"),
+				Dependency: []string{"options.proto"},
+				Options: &descriptor.FileOptions{
+					XXX_unrecognized: option50002,
+				},
+				MessageType: []*descriptor.DescriptorProto{
+					{
+						Name: proto.String("A"),
+						Field: []*descriptor.FieldDescriptorProto{
+							{
+								Name: proto.String("one"),
+								Options: &descriptor.FieldOptions{
+									// repeated values count once
+									XXX_unrecognized: append(append([]byte{}, option50000...), option50000...),
+								},
+							},
+							{
+								Name: proto.String("two"),
+								Options: &descriptor.FieldOptions{
+									XXX_unrecognized: option50000,
+								},
+							},
+						},
+						Options: &descriptor.MessageOptions{
+							XXX_unrecognized: option50001,
+						},
+					},
+				},
+			},
+			{
+				Name:       proto.String("b.proto"),
+				Dependency: []string{"options.proto"},
+				EnumType: []*descriptor.EnumDescriptorProto{
+					{
+						Name: proto.String("B"),
+						Value: []*descriptor.EnumValueDescriptorProto{
+							{
+								Name:   proto.String("B_UNSPECIFIED"),
+								Number: proto.Int32(0),
+								Options: &descriptor.EnumValueOptions{
+									// this is not a FieldOptions extension
+									XXX_unrecognized: option50000,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		BufbuildImageExtension: &imagev1beta1.ImageExtension{
+			ImageImportRefs: []*imagev1beta1.ImageImportRef{
+				{FileIndex: proto.Uint32(0)},
+			},
+		},
+	}
+	customOptions, err := ImageCustomOptions(image)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]*CustomOption{
+			{
+				Extendee: "google.protobuf.EnumValueOptions",
+				Number:   50000,
+				Count:    1,
+				Files:    []string{"b.proto"},
+			},
+			{
+				Extendee: "google.protobuf.FileOptions",
+				Number:   50002,
+				Count:    1,
+				Files:    []string{"a.proto"},
+			},
+			{
+				Name:         "foo.Scope.message_option",
+				Extendee:     "google.protobuf.MessageOptions",
+				Number:       50001,
+				DefiningFile: "options.proto",
+				Count:        1,
+				Files:        []string{"a.proto"},
+			},
+			{
+				Name:         "foo.field_option",
+				Extendee:     "google.protobuf.FieldOptions",
+				Number:       50000,
+				DefiningFile: "options.proto",
+				Count:        2,
+				Files:        []string{"a.proto"},
+			},
+		},
+		customOptions,
+	)
+}
+
+func TestImageWithDependencyImages(t *testing.T) {
+	t.Parallel()
+	aFile := &descriptor.FileDescriptorProto{
+		Name: proto.String("a.proto"),
+	}
+	bFile := &descriptor.FileDescriptorProto{
+		Name:       proto.String("b.proto"),
+		Dependency: []string{"a.proto"},
+	}
+	cFile := &descriptor.FileDescriptorProto{
+		Name:       proto.String("c.proto"),
+		Dependency: []string{"b.proto"},
+	}
+	dFile := &descriptor.FileDescriptorProto{
+		Name:       proto.String("d.proto"),
+		Dependency: []string{"c.proto", "a.proto"},
+	}
+	image := &imagev1beta1.Image{
+		File: []*descriptor.FileDescriptorProto{cFile, dFile},
+		BufbuildImageExtension: &imagev1beta1.ImageExtension{
+			ImageImportRefs: []*imagev1beta1.ImageImportRef{
+				{FileIndex: proto.Uint32(0)},
+			},
+		},
+	}
+	newImage, err := ImageWithDependencyImages(
+		image,
+		&imagev1beta1.Image{
+			File: []*descriptor.FileDescriptorProto{bFile},
+		},
+		&imagev1beta1.Image{
+			File: []*descriptor.FileDescriptorProto{aFile, bFile},
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []*descriptor.FileDescriptorProto{aFile, bFile, cFile, dFile}, newImage.GetFile())
+	importNames, err := ImageImportNames(newImage)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.proto", "b.proto", "c.proto"}, importNames)
+
+	// the image is not modified if it has all of its imports
+	newImage, err = ImageWithDependencyImages(
+		newImage,
+		&imagev1beta1.Image{
+			File: []*descriptor.FileDescriptorProto{aFile},
+		},
+	)
+	require.NoError(t, err)
+	assert.Len(t, newImage.GetFile(), 4)
+
+	_, err = ImageWithDependencyImages(
+		image,
+		&imagev1beta1.Image{
+			File: []*descriptor.FileDescriptorProto{bFile},
+		},
+	)
+	assert.Error(t, err)
+	_, err = ImageWithDependencyImages(
+		image,
+		&imagev1beta1.Image{
+			File: []*descriptor.FileDescriptorProto{aFile, bFile},
+		},
+		&imagev1beta1.Image{
+			File: []*descriptor.FileDescriptorProto{
+				{
+					Name:    proto.String("a.proto"),
+					Package: proto.String("a"),
+				},
+			},
+		},
+	)
+	assert.Error(t, err)
+}
+
+func TestImageShards(t *testing.T) {
+	t.Parallel()
+	fileA := &descriptor.FileDescriptorProto{
+		Name:    proto.String("a.proto"),
+		Package: proto.String("a"),
+	}
+	fileB := &descriptor.FileDescriptorProto{
+		Name:       proto.String("b.proto"),
+		Package:    proto.String("b"),
+		Dependency: []string{"a.proto"},
+	}
+	fileC1 := &descriptor.FileDescriptorProto{
+		Name:       proto.String("c1.proto"),
+		Package:    proto.String("c"),
+		Dependency: []string{"b.proto"},
+	}
+	fileC2 := &descriptor.FileDescriptorProto{
+		Name:    proto.String("c2.proto"),
+		Package: proto.String("c"),
+	}
+	fileD := &descriptor.FileDescriptorProto{
+		Name: proto.String("d.proto"),
+	}
+	image := &imagev1beta1.Image{
+		File: []*descriptor.FileDescriptorProto{fileD, fileC2, fileC1, fileB, fileA},
+		BufbuildImageExtension: &imagev1beta1.ImageExtension{
+			ImageImportRefs: []*imagev1beta1.ImageImportRef{
+				{FileIndex: proto.Uint32(0)},
+			},
+		},
+	}
+
+	imageShards, err := ImageShards(image, 4)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]*ImageShard{
+			{
+				Image: &imagev1beta1.Image{
+					File: []*descriptor.FileDescriptorProto{fileA},
+				},
+				Packages:     []string{"a"},
+				Dependencies: []int{},
+			},
+			{
+				Image: &imagev1beta1.Image{
+					File: []*descriptor.FileDescriptorProto{fileB},
+				},
+				Packages:     []string{"b"},
+				Dependencies: []int{0},
+			},
+			{
+				Image: &imagev1beta1.Image{
+					File: []*descriptor.FileDescriptorProto{fileC1, fileC2},
+				},
+				Packages:     []string{"c"},
+				Dependencies: []int{1},
+			},
+			{
+				Image: &imagev1beta1.Image{
+					File: []*descriptor.FileDescriptorProto{fileD},
+					BufbuildImageExtension: &imagev1beta1.ImageExtension{
+						ImageImportRefs: []*imagev1beta1.ImageImportRef{
+							{FileIndex: proto.Uint32(0)},
+						},
+					},
+				},
+				Packages:     []string{""},
+				Dependencies: []int{},
+			},
+		},
+		imageShards,
+	)
+
+	imageShards, err = ImageShards(image, 1)
+	require.NoError(t, err)
+	require.Len(t, imageShards, 1)
+	assert.Equal(t, []string{"a", "b", "c", ""}, imageShards[0].Packages)
+	assert.Len(t, imageShards[0].Image.File, 5)
+
+	_, err = ImageShards(image, 5)
+	assert.Error(t, err)
+	_, err = ImageShards(image, 0)
+	assert.Error(t, err)
+}
+
+func TestImagePackages(t *testing.T) {
+	t.Parallel()
+	image := &imagev1beta1.Image{
+		File: []*descriptor.FileDescriptorProto{
+			{
+				Name:    proto.String("b/v1/b2.proto"),
+				Package: proto.String("b.v1"),
+			},
+			{
+				Name: proto.String("none.proto"),
+			},
+			{
+				Name:    proto.String("b/v1/b1.proto"),
+				Package: proto.String("b.v1"),
+			},
+			{
+				Name:    proto.String("a/v1/a.proto"),
+				Package: proto.String("a.v1"),
+			},
+		},
+	}
+	packages, err := ImagePackages(image)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]*Package{
+			{
+				Name:  "",
+				Files: []string{"none.proto"},
+			},
+			{
+				Name:  "a.v1",
+				Files: []string{"a/v1/a.proto"},
+			},
+			{
+				Name:  "b.v1",
+				Files: []string{"b/v1/b1.proto", "b/v1/b2.proto"},
+			},
+		},
+		packages,
+	)
+}
+
+func TestImageServices(t *testing.T) {
+	t.Parallel()
+	image := &imagev1beta1.Image{
+		File: []*descriptor.FileDescriptorProto{
+			{
+				Name:    proto.String("b/v1/b.proto"),
+				Package: proto.String("b.v1"),
+				Service: []*descriptor.ServiceDescriptorProto{
+					{
+						Name: proto.String("BService"),
+						Method: []*descriptor.MethodDescriptorProto{
+							{
+								Name:            proto.String("Watch"),
+								InputType:       proto.String(".b.v1.WatchRequest"),
+								OutputType:      proto.String(".b.v1.WatchResponse"),
+								ServerStreaming: proto.Bool(true),
+							},
+						},
+					},
+				},
+			},
+			{
+				Name: proto.String("a.proto"),
+				Service: []*descriptor.ServiceDescriptorProto{
+					{
+						Name: proto.String("AService"),
+					},
+				},
+			},
+		},
+	}
+	services, err := ImageServices(image)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]*Service{
+			{
+				Name: "AService",
+				File: "a.proto",
+			},
+			{
+				Name: "b.v1.BService",
+				File: "b/v1/b.proto",
+				Methods: []*Method{
+					{
+						Name:            "b.v1.BService.Watch",
+						Service:         "b.v1.BService",
+						File:            "b/v1/b.proto",
+						InputType:       "b.v1.WatchRequest",
+						OutputType:      "b.v1.WatchResponse",
+						ServerStreaming: true,
+					},
+				},
+			},
+		},
+		services,
+	)
+}
@@ -2,10 +2,12 @@ package extfile
 
 import (
 	"bytes"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"sort"
 	"strconv"
+	"text/template"
 
 	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
 	"github.com/golang/protobuf/jsonpb"
@@ -142,3 +144,122 @@ func PrintFileAnnotations(writer io.Writer, fileAnnotations []*filev1beta1.FileA
 	}
 	return nil
 }
+
+// PrintFileAnnotationsTemplate prints the FileAnnotations to the Writer using the template.
+//
+// The template is executed with each FileAnnotation, and each result is followed by a newline.
+func PrintFileAnnotationsTemplate(writer io.Writer, fileAnnotations []*filev1beta1.FileAnnotation, tmpl *template.Template) error {
+	buffer := bytes.NewBuffer(nil)
+	for _, fileAnnotation := range fileAnnotations {
+		buffer.Reset()
+		if err := tmpl.Execute(buffer, fileAnnotation); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(writer, buffer.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PrintFileAnnotationsJUnit prints the FileAnnotations to the Writer as JUnit XML.
+//
+// Each path is a testsuite, and each FileAnnotation is a failed testcase within
+// the testsuite for its path. The FileAnnotations are expected to be sorted.
+//
+// A report is always printed. If there are no FileAnnotations, the report has a
+// single passing testcase, so that CI systems record that the checks ran.
+func PrintFileAnnotationsJUnit(writer io.Writer, fileAnnotations []*filev1beta1.FileAnnotation) error {
+	testsuites := &junitTestsuites{}
+	if len(fileAnnotations) == 0 {
+		testsuites.Tests = 1
+		testsuites.Testsuites = []*junitTestsuite{
+			{
+				Name:  "<input>",
+				Tests: 1,
+				Testcases: []*junitTestcase{
+					{
+						Name:      "PASS",
+						Classname: "<input>",
+					},
+				},
+			},
+		}
+	}
+	pathToTestsuite := make(map[string]*junitTestsuite)
+	for _, fileAnnotation := range fileAnnotations {
+		path := fileAnnotation.GetPath()
+		if path == "" {
+			path = "<input>"
+		}
+		testsuite, ok := pathToTestsuite[path]
+		if !ok {
+			testsuite = &junitTestsuite{
+				Name: path,
+			}
+			pathToTestsuite[path] = testsuite
+			testsuites.Testsuites = append(testsuites.Testsuites, testsuite)
+		}
+		failureType := fileAnnotation.GetType()
+		if failureType == "" {
+			failureType = "FAILURE"
+		}
+		testsuite.Tests++
+		testsuite.Failures++
+		testsuite.Testcases = append(
+			testsuite.Testcases,
+			&junitTestcase{
+				Name: fmt.Sprintf(
+					"%s:%d:%d",
+					failureType,
+					fileAnnotation.GetStartLine(),
+					fileAnnotation.GetStartColumn(),
+				),
+				Classname: path,
+				Failure: &junitFailure{
+					Message: FileAnnotationToString(fileAnnotation),
+					Type:    failureType,
+				},
+			},
+		)
+		testsuites.Tests++
+		testsuites.Failures++
+	}
+	data, err := xml.MarshalIndent(testsuites, "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(writer)
+	return err
+}
+
+type junitTestsuites struct {
+	XMLName    xml.Name          `xml:"testsuites"`
+	Tests      int               `xml:"tests,attr"`
+	Failures   int               `xml:"failures,attr"`
+	Testsuites []*junitTestsuite `xml:"testsuite"`
+}
+
+type junitTestsuite struct {
+	Name      string           `xml:"name,attr"`
+	Tests     int              `xml:"tests,attr"`
+	Failures  int              `xml:"failures,attr"`
+	Testcases []*junitTestcase `xml:"testcase"`
+}
+
+type junitTestcase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+}
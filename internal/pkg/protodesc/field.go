@@ -2,6 +2,7 @@ package protodesc
 
 type field struct {
 	namedDescriptor
+	optionsDescriptor
 
 	message      Message
 	number       int
@@ -10,6 +11,7 @@ type field struct {
 	typeName     string
 	oneofIndex   *int32
 	jsonName     string
+	extendee     string
 	jsType       FieldOptionsJSType
 	cType        FieldOptionsCType
 	packed       *bool
@@ -24,6 +26,7 @@ type field struct {
 
 func newField(
 	namedDescriptor namedDescriptor,
+	optionsDescriptor optionsDescriptor,
 	message Message,
 	number int,
 	label FieldDescriptorProtoLabel,
@@ -31,6 +34,7 @@ func newField(
 	typeName string,
 	oneofIndex *int32,
 	jsonName string,
+	extendee string,
 	jsType FieldOptionsJSType,
 	cType FieldOptionsCType,
 	packed *bool,
@@ -43,24 +47,26 @@ func newField(
 	packedPath []int32,
 ) *field {
 	return &field{
-		namedDescriptor: namedDescriptor,
-		message:         message,
-		number:          number,
-		label:           label,
-		typ:             typ,
-		typeName:        typeName,
-		oneofIndex:      oneofIndex,
-		jsonName:        jsonName,
-		jsType:          jsType,
-		cType:           cType,
-		packed:          packed,
-		numberPath:      numberPath,
-		typePath:        typePath,
-		typeNamePath:    typeNamePath,
-		jsonNamePath:    jsonNamePath,
-		jsTypePath:      jsTypePath,
-		cTypePath:       cTypePath,
-		packedPath:      packedPath,
+		namedDescriptor:   namedDescriptor,
+		optionsDescriptor: optionsDescriptor,
+		message:           message,
+		number:            number,
+		label:             label,
+		typ:               typ,
+		typeName:          typeName,
+		oneofIndex:        oneofIndex,
+		jsonName:          jsonName,
+		extendee:          extendee,
+		jsType:            jsType,
+		cType:             cType,
+		packed:            packed,
+		numberPath:        numberPath,
+		typePath:          typePath,
+		typeNamePath:      typeNamePath,
+		jsonNamePath:      jsonNamePath,
+		jsTypePath:        jsTypePath,
+		cTypePath:         cTypePath,
+		packedPath:        packedPath,
 	}
 }
 
@@ -95,6 +101,10 @@ func (f *field) JSONName() string {
 	return f.jsonName
 }
 
+func (f *field) Extendee() string {
+	return f.extendee
+}
+
 func (f *field) JSType() FieldOptionsJSType {
 	return f.jsType
 }
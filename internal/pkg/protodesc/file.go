@@ -6,6 +6,7 @@ import (
 
 type file struct {
 	descriptor
+	optionsDescriptor
 
 	fileDescriptorProto *protobufdescriptor.FileDescriptorProto
 
@@ -14,6 +15,7 @@ type file struct {
 	messages    []Message
 	enums       []Enum
 	services    []Service
+	extensions  []Field
 
 	optimizeMode FileOptionsOptimizeMode
 }
@@ -42,6 +44,18 @@ func (f *file) Services() []Service {
 	return f.services
 }
 
+func (f *file) Extensions() []Field {
+	return f.extensions
+}
+
+func (f *file) Locations() []Location {
+	return f.locationStore.getAllLocations(nil)
+}
+
+func (f *file) OptionLocations() []Location {
+	return f.locationStore.getAllLocations(isOptionPath)
+}
+
 func (f *file) CsharpNamespace() string {
 	return f.fileDescriptorProto.GetOptions().GetCsharpNamespace()
 }
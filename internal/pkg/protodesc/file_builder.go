@@ -16,6 +16,7 @@ type fileBuilder struct {
 	messages    []Message
 	enums       []Enum
 	services    []Service
+	extensions  []Field
 }
 
 func newFileBuilder(fileDescriptorProto *protobufdescriptor.FileDescriptorProto) *fileBuilder {
@@ -114,18 +115,38 @@ func (f *fileBuilder) toFile() (*file, error) {
 		}
 		f.services = append(f.services, service)
 	}
+	for extensionIndex, fieldDescriptorProto := range f.fileDescriptorProto.GetExtension() {
+		extension, err := f.populateExtension(
+			fieldDescriptorProto,
+			extensionIndex,
+		)
+		if err != nil {
+			return nil, err
+		}
+		f.extensions = append(f.extensions, extension)
+	}
 	optimizeMode, err := getFileOptionsOptimizeMode(f.fileDescriptorProto.GetOptions().GetOptimizeFor())
 	if err != nil {
 		return nil, err
 	}
+	optionsDescriptor, err := newOptionsDescriptor(
+		f.descriptor,
+		f.fileDescriptorProto.GetOptions(),
+		getFileOptionsPath(),
+	)
+	if err != nil {
+		return nil, err
+	}
 	return &file{
 		descriptor:          f.descriptor,
+		optionsDescriptor:   optionsDescriptor,
 		fileDescriptorProto: f.fileDescriptorProto,
 		syntax:              f.syntax,
 		fileImports:         f.fileImports,
 		messages:            f.messages,
 		enums:               f.enums,
 		services:            f.services,
+		extensions:          f.extensions,
 		optimizeMode:        optimizeMode,
 	}, nil
 }
@@ -230,8 +251,17 @@ func (f *fileBuilder) populateMessage(
 	if err != nil {
 		return nil, err
 	}
+	messageOptionsDescriptor, err := newOptionsDescriptor(
+		f.descriptor,
+		descriptorProto.GetOptions(),
+		getMessageOptionsPath(topLevelMessageIndex, nestedMessageIndexes...),
+	)
+	if err != nil {
+		return nil, err
+	}
 	message := newMessage(
 		messageNamedDescriptor,
+		messageOptionsDescriptor,
 		parent,
 		descriptorProto.GetOptions().GetMapEntry(),
 		descriptorProto.GetOptions().GetMessageSetWireFormat(),
@@ -273,8 +303,17 @@ func (f *fileBuilder) populateMessage(
 		if err != nil {
 			return nil, err
 		}
+		fieldOptionsDescriptor, err := newOptionsDescriptor(
+			f.descriptor,
+			fieldDescriptorProto.GetOptions(),
+			getMessageFieldOptionsPath(fieldIndex, topLevelMessageIndex, nestedMessageIndexes...),
+		)
+		if err != nil {
+			return nil, err
+		}
 		field := newField(
 			fieldNamedDescriptor,
+			fieldOptionsDescriptor,
 			message,
 			int(fieldDescriptorProto.GetNumber()),
 			label,
@@ -282,6 +321,7 @@ func (f *fileBuilder) populateMessage(
 			fieldDescriptorProto.GetTypeName(),
 			fieldDescriptorProto.OneofIndex,
 			fieldDescriptorProto.GetJsonName(),
+			fieldDescriptorProto.GetExtendee(),
 			jsType,
 			cType,
 			packed,
@@ -329,8 +369,17 @@ func (f *fileBuilder) populateMessage(
 		if err != nil {
 			return nil, err
 		}
+		fieldOptionsDescriptor, err := newOptionsDescriptor(
+			f.descriptor,
+			fieldDescriptorProto.GetOptions(),
+			getMessageExtensionOptionsPath(fieldIndex, topLevelMessageIndex, nestedMessageIndexes...),
+		)
+		if err != nil {
+			return nil, err
+		}
 		field := newField(
 			fieldNamedDescriptor,
+			fieldOptionsDescriptor,
 			message,
 			int(fieldDescriptorProto.GetNumber()),
 			label,
@@ -338,6 +387,7 @@ func (f *fileBuilder) populateMessage(
 			fieldDescriptorProto.GetTypeName(),
 			fieldDescriptorProto.OneofIndex,
 			fieldDescriptorProto.GetJsonName(),
+			fieldDescriptorProto.GetExtendee(),
 			jsType,
 			cType,
 			packed,
@@ -439,6 +489,74 @@ func (f *fileBuilder) populateMessage(
 	return message, nil
 }
 
+func (f *fileBuilder) populateExtension(
+	fieldDescriptorProto *protobufdescriptor.FieldDescriptorProto,
+	extensionIndex int,
+) (Field, error) {
+	fieldNamedDescriptor, err := newNamedDescriptor(
+		newLocationDescriptor(
+			f.descriptor,
+			getFileExtensionPath(extensionIndex),
+		),
+		fieldDescriptorProto.GetName(),
+		getFileExtensionNamePath(extensionIndex),
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var packed *bool
+	if fieldDescriptorProto.Options != nil {
+		packed = fieldDescriptorProto.GetOptions().Packed
+	}
+	label, err := getFieldDescriptorProtoLabel(fieldDescriptorProto.GetLabel())
+	if err != nil {
+		return nil, err
+	}
+	typ, err := getFieldDescriptorProtoType(fieldDescriptorProto.GetType())
+	if err != nil {
+		return nil, err
+	}
+	jsType, err := getFieldOptionsJSType(fieldDescriptorProto.GetOptions().GetJstype())
+	if err != nil {
+		return nil, err
+	}
+	cType, err := getFieldOptionsCType(fieldDescriptorProto.GetOptions().GetCtype())
+	if err != nil {
+		return nil, err
+	}
+	fieldOptionsDescriptor, err := newOptionsDescriptor(
+		f.descriptor,
+		fieldDescriptorProto.GetOptions(),
+		getFileExtensionOptionsPath(extensionIndex),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return newField(
+		fieldNamedDescriptor,
+		fieldOptionsDescriptor,
+		nil,
+		int(fieldDescriptorProto.GetNumber()),
+		label,
+		typ,
+		fieldDescriptorProto.GetTypeName(),
+		fieldDescriptorProto.OneofIndex,
+		fieldDescriptorProto.GetJsonName(),
+		fieldDescriptorProto.GetExtendee(),
+		jsType,
+		cType,
+		packed,
+		getFileExtensionNumberPath(extensionIndex),
+		getFileExtensionTypePath(extensionIndex),
+		getFileExtensionTypeNamePath(extensionIndex),
+		getFileExtensionJSONNamePath(extensionIndex),
+		getFileExtensionJSTypePath(extensionIndex),
+		getFileExtensionCTypePath(extensionIndex),
+		getFileExtensionPackedPath(extensionIndex),
+	), nil
+}
+
 func (f *fileBuilder) populateService(
 	serviceDescriptorProto *protobufdescriptor.ServiceDescriptorProto,
 	serviceIndex int,
@@ -475,8 +593,17 @@ func (f *fileBuilder) populateService(
 		if err != nil {
 			return nil, err
 		}
+		methodOptionsDescriptor, err := newOptionsDescriptor(
+			f.descriptor,
+			methodDescriptorProto.GetOptions(),
+			getMethodOptionsPath(serviceIndex, methodIndex),
+		)
+		if err != nil {
+			return nil, err
+		}
 		method, err := newMethod(
 			methodNamedDescriptor,
+			methodOptionsDescriptor,
 			service,
 			methodDescriptorProto.GetInputType(),
 			methodDescriptorProto.GetOutputType(),
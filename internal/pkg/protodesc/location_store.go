@@ -76,6 +76,18 @@ func (l *locationStore) getLocationByPathKey(pathKey string) Location {
 	return location
 }
 
+// getAllLocations returns the locations for all source code info locations
+// whose paths match the filter, or all locations if filter is nil.
+func (l *locationStore) getAllLocations(filter func([]int32) bool) []Location {
+	var locations []Location
+	for _, sourceCodeInfoLocation := range l.sourceCodeInfoLocations {
+		if filter == nil || filter(sourceCodeInfoLocation.Path) {
+			locations = append(locations, newLocation(sourceCodeInfoLocation))
+		}
+	}
+	return locations
+}
+
 func getPathKey(path []int32) string {
 	key := make([]byte, len(path)*4)
 	j := 0
@@ -2,6 +2,7 @@ package protodesc
 
 type message struct {
 	namedDescriptor
+	optionsDescriptor
 
 	fields                           []Field
 	extensions                       []Field
@@ -21,6 +22,7 @@ type message struct {
 
 func newMessage(
 	namedDescriptor namedDescriptor,
+	optionsDescriptor optionsDescriptor,
 	parent Message,
 	isMapEntry bool,
 	messageSetWireFormat bool,
@@ -30,6 +32,8 @@ func newMessage(
 ) *message {
 	return &message{
 		namedDescriptor:                  namedDescriptor,
+		optionsDescriptor:                optionsDescriptor,
+		parent:                           parent,
 		isMapEntry:                       isMapEntry,
 		messageSetWireFormat:             messageSetWireFormat,
 		noStandardDescriptorAccessor:     noStandardDescriptorAccessor,
@@ -4,6 +4,7 @@ import "fmt"
 
 type method struct {
 	namedDescriptor
+	optionsDescriptor
 
 	service              Service
 	inputTypeName        string
@@ -18,6 +19,7 @@ type method struct {
 
 func newMethod(
 	namedDescriptor namedDescriptor,
+	optionsDescriptor optionsDescriptor,
 	service Service,
 	inputTypeName string,
 	outputTypeName string,
@@ -36,6 +38,7 @@ func newMethod(
 	}
 	return &method{
 		namedDescriptor:      namedDescriptor,
+		optionsDescriptor:    optionsDescriptor,
 		service:              service,
 		inputTypeName:        inputTypeName,
 		outputTypeName:       outputTypeName,
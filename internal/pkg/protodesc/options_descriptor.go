@@ -0,0 +1,67 @@
+package protodesc
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/bufbuild/buf/internal/pkg/util/utilproto"
+	"github.com/golang/protobuf/proto"
+)
+
+type optionsDescriptor struct {
+	optionsLocationStore *locationStore
+	numberToData         map[int][]byte
+	optionsPath          []int32
+}
+
+// newOptionsDescriptor returns a new optionsDescriptor.
+//
+// The options can be a nil pointer. The optionsPath is the path of the options
+// field of the descriptor, i.e. []int32{8} for a file.
+func newOptionsDescriptor(
+	descriptor descriptor,
+	options proto.Message,
+	optionsPath []int32,
+) (optionsDescriptor, error) {
+	optionsDescriptor := optionsDescriptor{
+		optionsLocationStore: descriptor.locationStore,
+		optionsPath:          optionsPath,
+	}
+	if options == nil || reflect.ValueOf(options).IsNil() {
+		return optionsDescriptor, nil
+	}
+	// this includes extensions and unrecognized fields
+	data, err := proto.Marshal(options)
+	if err != nil {
+		return optionsDescriptor, err
+	}
+	numberToData, err := getNumberToData(data)
+	if err != nil {
+		return optionsDescriptor, fmt.Errorf("could not parse options in %q: %v", descriptor.filePath, err)
+	}
+	optionsDescriptor.numberToData = numberToData
+	return optionsDescriptor, nil
+}
+
+func (o *optionsDescriptor) OptionNumberToData() map[int][]byte {
+	return o.numberToData
+}
+
+func (o *optionsDescriptor) OptionLocation(number int) Location {
+	return o.optionsLocationStore.getLocation(append(append([]int32{}, o.optionsPath...), int32(number)))
+}
+
+// getNumberToData splits the wire format data by field number.
+//
+// Each value contains the tags and values of all occurrences of the field, in order.
+func getNumberToData(data []byte) (map[int][]byte, error) {
+	wireFields, err := utilproto.SplitWire(data)
+	if err != nil {
+		return nil, err
+	}
+	numberToData := make(map[int][]byte)
+	for _, wireField := range wireFields {
+		numberToData[wireField.Number] = append(numberToData[wireField.Number], wireField.Data...)
+	}
+	return numberToData, nil
+}
@@ -23,10 +23,121 @@ var (
 	syntaxPathKey               = getPathKey([]int32{12})
 )
 
+type pathType int
+
+const (
+	pathTypeFile pathType = iota + 1
+	pathTypeMessage
+	pathTypeField
+	pathTypeOneof
+	pathTypeEnum
+	pathTypeEnumValue
+	pathTypeService
+	pathTypeMethod
+)
+
+var (
+	// pathTypeToOptionsFieldNumber is the field number of the options within each descriptor type.
+	pathTypeToOptionsFieldNumber = map[pathType]int32{
+		pathTypeFile:      8,
+		pathTypeMessage:   7,
+		pathTypeField:     8,
+		pathTypeOneof:     2,
+		pathTypeEnum:      3,
+		pathTypeEnumValue: 3,
+		pathTypeService:   3,
+		pathTypeMethod:    4,
+	}
+	// pathTypeToFieldNumberToChildPathType are the repeated fields of each descriptor
+	// type that contain other descriptor types.
+	pathTypeToFieldNumberToChildPathType = map[pathType]map[int32]pathType{
+		pathTypeFile: {
+			4: pathTypeMessage,
+			5: pathTypeEnum,
+			6: pathTypeService,
+			7: pathTypeField,
+		},
+		pathTypeMessage: {
+			2: pathTypeField,
+			3: pathTypeMessage,
+			4: pathTypeEnum,
+			6: pathTypeField,
+			8: pathTypeOneof,
+		},
+		pathTypeEnum: {
+			2: pathTypeEnumValue,
+		},
+		pathTypeService: {
+			2: pathTypeMethod,
+		},
+	}
+)
+
+// isOptionPath returns true if the source code info path from a file is
+// within the options of the file or of any nested descriptor.
+func isOptionPath(path []int32) bool {
+	pathType := pathTypeFile
+	for i := 0; i < len(path); i += 2 {
+		if path[i] == pathTypeToOptionsFieldNumber[pathType] {
+			return true
+		}
+		childPathType, ok := pathTypeToFieldNumberToChildPathType[pathType][path[i]]
+		if !ok {
+			return false
+		}
+		pathType = childPathType
+	}
+	return false
+}
+
 func getDependencyPath(dependencyIndex int) []int32 {
 	return []int32{3, int32(dependencyIndex)}
 }
 
+func getFileOptionsPath() []int32 {
+	return []int32{8}
+}
+
+func getFileExtensionPath(extensionIndex int) []int32 {
+	return []int32{7, int32(extensionIndex)}
+}
+
+func getFileExtensionNamePath(extensionIndex int) []int32 {
+	return append(getFileExtensionPath(extensionIndex), 1)
+}
+
+func getFileExtensionNumberPath(extensionIndex int) []int32 {
+	return append(getFileExtensionPath(extensionIndex), 3)
+}
+
+func getFileExtensionTypePath(extensionIndex int) []int32 {
+	return append(getFileExtensionPath(extensionIndex), 5)
+}
+
+func getFileExtensionTypeNamePath(extensionIndex int) []int32 {
+	return append(getFileExtensionPath(extensionIndex), 6)
+}
+
+func getFileExtensionJSONNamePath(extensionIndex int) []int32 {
+	return append(getFileExtensionPath(extensionIndex), 10)
+}
+
+func getFileExtensionOptionsPath(extensionIndex int) []int32 {
+	return append(getFileExtensionPath(extensionIndex), 8)
+}
+
+func getFileExtensionJSTypePath(extensionIndex int) []int32 {
+	return append(getFileExtensionPath(extensionIndex), 8, 6)
+}
+
+func getFileExtensionCTypePath(extensionIndex int) []int32 {
+	return append(getFileExtensionPath(extensionIndex), 8, 1)
+}
+
+func getFileExtensionPackedPath(extensionIndex int) []int32 {
+	return append(getFileExtensionPath(extensionIndex), 8, 2)
+}
+
 func getMessagePath(topLevelMessageIndex int, nestedMessageIndexes ...int) []int32 {
 	path := []int32{4, int32(topLevelMessageIndex)}
 	for _, nestedMessageIndex := range nestedMessageIndexes {
@@ -39,6 +150,10 @@ func getMessageNamePath(messageIndex int, nestedMessageIndexes ...int) []int32 {
 	return append(getMessagePath(messageIndex, nestedMessageIndexes...), 1)
 }
 
+func getMessageOptionsPath(messageIndex int, nestedMessageIndexes ...int) []int32 {
+	return append(getMessagePath(messageIndex, nestedMessageIndexes...), 7)
+}
+
 func getMessageMessageSetWireFormatPath(messageIndex int, nestedMessageIndexes ...int) []int32 {
 	return append(getMessagePath(messageIndex, nestedMessageIndexes...), 7, 1)
 }
@@ -71,6 +186,10 @@ func getMessageFieldJSONNamePath(fieldIndex int, topLevelMessageIndex int, neste
 	return append(getMessageFieldPath(fieldIndex, topLevelMessageIndex, nestedMessageIndexes...), 10)
 }
 
+func getMessageFieldOptionsPath(fieldIndex int, topLevelMessageIndex int, nestedMessageIndexes ...int) []int32 {
+	return append(getMessageFieldPath(fieldIndex, topLevelMessageIndex, nestedMessageIndexes...), 8)
+}
+
 func getMessageFieldJSTypePath(fieldIndex int, topLevelMessageIndex int, nestedMessageIndexes ...int) []int32 {
 	return append(getMessageFieldPath(fieldIndex, topLevelMessageIndex, nestedMessageIndexes...), 8, 6)
 }
@@ -107,6 +226,10 @@ func getMessageExtensionJSONNamePath(extensionIndex int, topLevelMessageIndex in
 	return append(getMessageExtensionPath(extensionIndex, topLevelMessageIndex, nestedMessageIndexes...), 10)
 }
 
+func getMessageExtensionOptionsPath(extensionIndex int, topLevelMessageIndex int, nestedMessageIndexes ...int) []int32 {
+	return append(getMessageExtensionPath(extensionIndex, topLevelMessageIndex, nestedMessageIndexes...), 8)
+}
+
 func getMessageExtensionJSTypePath(extensionIndex int, topLevelMessageIndex int, nestedMessageIndexes ...int) []int32 {
 	return append(getMessageExtensionPath(extensionIndex, topLevelMessageIndex, nestedMessageIndexes...), 8, 6)
 }
@@ -201,6 +324,10 @@ func getMethodOutputTypePath(serviceIndex int, methodIndex int) []int32 {
 	return append(getMethodPath(serviceIndex, methodIndex), 3)
 }
 
+func getMethodOptionsPath(serviceIndex int, methodIndex int) []int32 {
+	return append(getMethodPath(serviceIndex, methodIndex), 4)
+}
+
 func getMethodIdempotencyLevelPath(serviceIndex int, methodIndex int) []int32 {
 	return append(getMethodPath(serviceIndex, methodIndex), 4, 34)
 }
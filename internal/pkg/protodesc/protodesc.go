@@ -91,6 +91,22 @@ type NamedDescriptor interface {
 	NameLocation() Location
 }
 
+// OptionsDescriptor is the base interface for a descriptor type with options.
+type OptionsDescriptor interface {
+	// OptionNumberToData returns the wire format encoding of the set options by
+	// field number, including custom options.
+	//
+	// Each value contains the tags and values of all occurrences of the field, in order.
+	// Standard options can be compared by their field number in descriptor.proto, and
+	// custom options by the field number of their extension.
+	// NOT a copy. Do not modify.
+	OptionNumberToData() map[int][]byte
+	// OptionLocation returns the location of the option with the field number.
+	//
+	// Can return nil.
+	OptionLocation(number int) Location
+}
+
 // ContainerDescriptor contains Enums and Messages.
 type ContainerDescriptor interface {
 	Enums() []Enum
@@ -118,10 +134,13 @@ type File interface {
 	Descriptor
 	// Top-level only.
 	ContainerDescriptor
+	OptionsDescriptor
 
 	Syntax() Syntax
 	FileImports() []FileImport
 	Services() []Service
+	// Top-level only.
+	Extensions() []Field
 
 	CsharpNamespace() string
 	GoPackage() string
@@ -164,6 +183,16 @@ type File interface {
 	PyGenericServicesLocation() Location
 	PhpGenericServicesLocation() Location
 	CcEnableArenasLocation() Location
+
+	// Locations returns the locations of all elements within the file,
+	// in the order of the source code info.
+	//
+	// This includes the location of the file itself if present, which
+	// spans from the start of the file to the end of the last element.
+	Locations() []Location
+	// OptionLocations returns the locations of all options within the file,
+	// including the options of nested elements, in the order of the source code info.
+	OptionLocations() []Location
 }
 
 // FileImport is a file import descriptor.
@@ -234,6 +263,7 @@ type Message interface {
 	// Only those directly nested under this message.
 	ContainerDescriptor
 	ReservedDescriptor
+	OptionsDescriptor
 
 	// Includes fields in oneofs.
 	Fields() []Field
@@ -254,7 +284,9 @@ type Message interface {
 // Field is a field descriptor.
 type Field interface {
 	NamedDescriptor
+	OptionsDescriptor
 
+	// Will return nil if this is a top-level extension
 	Message() Message
 	Number() int
 	Label() FieldDescriptorProtoLabel
@@ -262,6 +294,9 @@ type Field interface {
 	TypeName() string
 	OneofIndex() (int, bool)
 	JSONName() string
+	// Only set for extensions.
+	// Fully-qualified with a leading period, i.e. .google.protobuf.FieldOptions.
+	Extendee() string
 	JSType() FieldOptionsJSType
 	CType() FieldOptionsCType
 	// Set vs unset matters for packed
@@ -294,6 +329,7 @@ type Service interface {
 // Method is a method descriptor.
 type Method interface {
 	NamedDescriptor
+	OptionsDescriptor
 
 	Service() Service
 	InputTypeName() string
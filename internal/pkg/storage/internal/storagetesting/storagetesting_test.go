@@ -1,11 +1,14 @@
 package storagetesting
 
 import (
+	"archive/tar"
+	"archive/zip"
 	"bytes"
 	"context"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/bufbuild/buf/internal/pkg/storage"
@@ -16,6 +19,7 @@ import (
 	"github.com/bufbuild/buf/internal/pkg/storage/storagepath"
 	"github.com/bufbuild/buf/internal/pkg/storage/storageutil"
 	"github.com/bufbuild/buf/internal/pkg/util/utilstring"
+	"github.com/klauspost/compress/zstd"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -30,6 +34,15 @@ package foo;
 `
 )
 
+const (
+	archiveTypeNone archiveType = iota
+	archiveTypeTar
+	archiveTypeTarZst
+	archiveTypeZip
+)
+
+type archiveType int
+
 func TestBasic1(t *testing.T) {
 	testBasic(
 		t,
@@ -198,6 +211,10 @@ func TestGitClone(t *testing.T) {
 		"",
 		absGitPath,
 		storagegitplumbing.NewBranchRefName("master"),
+		0,
+		false,
+		nil,
+		"",
 		"",
 		"",
 		"",
@@ -219,6 +236,223 @@ func TestGitClone(t *testing.T) {
 	assert.NoError(t, bucket.Close())
 }
 
+func TestUntarPolicy(t *testing.T) {
+	testUntarPolicy(t, "/etc/passwd.proto", tar.TypeReg, "", false, true)
+	testUntarPolicy(t, "../foo.proto", tar.TypeReg, "", false, true)
+	testUntarPolicy(t, "foo/../../foo.proto", tar.TypeReg, "", false, true)
+	testUntarPolicy(t, "foo/../bar.proto", tar.TypeReg, "", false, true)
+	testUntarPolicy(t, "foo/bar.proto", tar.TypeReg, "", false, false)
+	testUntarPolicy(t, "foo/bar.proto", tar.TypeSymlink, "/etc/passwd", false, true)
+	testUntarPolicy(t, "foo/bar.proto", tar.TypeSymlink, "../../etc/passwd", false, true)
+	testUntarPolicy(t, "foo/bar.proto", tar.TypeSymlink, "../baz.proto", false, false)
+	testUntarPolicy(t, "foo/bar.proto", tar.TypeSymlink, "/etc/passwd", true, false)
+	testUntarPolicy(t, "foo/bar.proto", tar.TypeLink, "../etc/passwd", false, true)
+	testUntarPolicy(t, "foo/bar.proto", tar.TypeLink, "foo/baz.proto", false, false)
+	testUntarPolicy(t, "foo/bar.proto", tar.TypeLink, "../etc/passwd", true, false)
+	// allowing symlinks never allows bad entry names
+	testUntarPolicy(t, "../foo.proto", tar.TypeSymlink, "foo.proto", true, true)
+}
+
+func TestUnzipPolicy(t *testing.T) {
+	testUnzipPolicy(t, "/etc/passwd.proto", "", false, true)
+	testUnzipPolicy(t, "foo/../../foo.proto", "", false, true)
+	testUnzipPolicy(t, "foo/bar.proto", "", false, false)
+	testUnzipPolicy(t, "foo/bar.proto", "/etc/passwd", false, true)
+	testUnzipPolicy(t, "foo/bar.proto", "../../etc/passwd", false, true)
+	testUnzipPolicy(t, "foo/bar.proto", "baz.proto", false, false)
+	testUnzipPolicy(t, "foo/bar.proto", "../../etc/passwd", true, false)
+}
+
+func TestOSFollowSymlinks(t *testing.T) {
+	// symlinks to files outside the bucket are followed
+	testOSSymlinks(
+		t,
+		map[string]string{
+			"in/a/a.proto":  "a",
+			"out/b.proto":   "b",
+			"out/c/c.proto": "c",
+		},
+		map[string]string{
+			"in/a/b.proto": "../../out/b.proto",
+			"in/c":         "../out/c",
+			"in/d.proto":   "nonexistent.proto",
+		},
+		[]string{
+			"a/a.proto",
+			"a/b.proto",
+			"c/c.proto",
+		},
+		[]string{
+			"a/a.proto",
+		},
+	)
+	// cycles are skipped
+	testOSSymlinks(
+		t,
+		map[string]string{
+			"in/a/a.proto": "a",
+		},
+		map[string]string{
+			"in/a/self": ".",
+			"in/a/root": "..",
+			"in/b":      "a",
+		},
+		[]string{
+			"a/a.proto",
+			"b/a.proto",
+		},
+		[]string{
+			"a/a.proto",
+		},
+	)
+}
+
+func testOSSymlinks(
+	t *testing.T,
+	pathToContent map[string]string,
+	pathToSymlinkTarget map[string]string,
+	expectedFollowPaths []string,
+	expectedPaths []string,
+) {
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		// won't work with requires but just temporary directory
+		require.NoError(t, os.RemoveAll(tempDirPath))
+	}()
+	for path, content := range pathToContent {
+		path = filepath.Join(tempDirPath, filepath.FromSlash(path))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+	for path, target := range pathToSymlinkTarget {
+		path = filepath.Join(tempDirPath, filepath.FromSlash(path))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		if err := os.Symlink(filepath.FromSlash(target), path); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+	rootPath := filepath.Join(tempDirPath, "in")
+
+	bucket, err := storageos.NewReadBucket(rootPath, storageos.BucketWithFollowSymlinks())
+	require.NoError(t, err)
+	assert.Equal(t, expectedFollowPaths, testWalkPaths(t, bucket))
+	for _, path := range expectedFollowPaths {
+		_, err := bucket.Get(context.Background(), path)
+		assert.NoError(t, err)
+	}
+
+	bucket, err = storageos.NewReadBucket(rootPath)
+	require.NoError(t, err)
+	assert.Equal(t, expectedPaths, testWalkPaths(t, bucket))
+}
+
+func testWalkPaths(t *testing.T, bucket storage.ReadBucket) []string {
+	var paths []string
+	require.NoError(
+		t,
+		bucket.Walk(
+			context.Background(),
+			"",
+			func(path string) error {
+				paths = append(paths, path)
+				return nil
+			},
+		),
+	)
+	return paths
+}
+
+func testUntarPolicy(
+	t *testing.T,
+	entryName string,
+	typeflag byte,
+	linkname string,
+	allowSymlinks bool,
+	expectPolicyError bool,
+) {
+	t.Run(entryName+"->"+linkname, func(t *testing.T) {
+		buffer := bytes.NewBuffer(nil)
+		tarWriter := tar.NewWriter(buffer)
+		tarHeader := &tar.Header{
+			Typeflag: typeflag,
+			Name:     entryName,
+			Linkname: linkname,
+			Mode:     0644,
+		}
+		if typeflag == tar.TypeReg {
+			tarHeader.Size = int64(len(testProtoContent))
+		}
+		require.NoError(t, tarWriter.WriteHeader(tarHeader))
+		if typeflag == tar.TypeReg {
+			_, err := tarWriter.Write([]byte(testProtoContent))
+			require.NoError(t, err)
+		}
+		require.NoError(t, tarWriter.Close())
+		bucket := storagemem.NewBucket()
+		err := storageutil.Untar(context.Background(), buffer, bucket, allowSymlinks)
+		if expectPolicyError {
+			assert.True(t, storageutil.IsPolicyError(err), "expected policy error but got %v", err)
+			if err != nil {
+				assert.Contains(t, err.Error(), entryName)
+			}
+			// only links are allowed by allowSymlinks, bad entry names never are
+			assert.Equal(t, typeflag != tar.TypeReg && !strings.Contains(entryName, ".."), storageutil.IsLinkPolicyError(err))
+		} else {
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, bucket.Close())
+	})
+}
+
+func testUnzipPolicy(
+	t *testing.T,
+	entryName string,
+	linkname string,
+	allowSymlinks bool,
+	expectPolicyError bool,
+) {
+	t.Run(entryName+"->"+linkname, func(t *testing.T) {
+		buffer := bytes.NewBuffer(nil)
+		zipWriter := zip.NewWriter(buffer)
+		fileHeader := &zip.FileHeader{
+			Name:   entryName,
+			Method: zip.Deflate,
+		}
+		content := testProtoContent
+		if linkname != "" {
+			fileHeader.SetMode(os.ModeSymlink | 0777)
+			content = linkname
+		} else {
+			fileHeader.SetMode(0644)
+		}
+		fileWriter, err := zipWriter.CreateHeader(fileHeader)
+		require.NoError(t, err)
+		_, err = fileWriter.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, zipWriter.Close())
+		bucket := storagemem.NewBucket()
+		err = storageutil.Unzip(
+			context.Background(),
+			bytes.NewReader(buffer.Bytes()),
+			int64(buffer.Len()),
+			bucket,
+			allowSymlinks,
+		)
+		if expectPolicyError {
+			assert.True(t, storageutil.IsPolicyError(err), "expected policy error but got %v", err)
+			if err != nil {
+				assert.Contains(t, err.Error(), entryName)
+			}
+			// only links are allowed by allowSymlinks, bad entry names never are
+			assert.Equal(t, linkname != "" && !strings.Contains(entryName, ".."), storageutil.IsLinkPolicyError(err))
+		} else {
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, bucket.Close())
+	})
+}
+
 func testBasic(
 	t *testing.T,
 	dirPath string,
@@ -232,7 +466,7 @@ func testBasic(
 		testBasicMem(
 			t,
 			dirPath,
-			false,
+			archiveTypeNone,
 			walkPrefix,
 			expectedPathToContent,
 			transformerOptions...,
@@ -243,7 +477,7 @@ func testBasic(
 		testBasicOS(
 			t,
 			dirPath,
-			false,
+			archiveTypeNone,
 			walkPrefix,
 			expectedPathToContent,
 			transformerOptions...,
@@ -254,7 +488,7 @@ func testBasic(
 		testBasicMem(
 			t,
 			dirPath,
-			true,
+			archiveTypeTar,
 			walkPrefix,
 			expectedPathToContent,
 			transformerOptions...,
@@ -265,7 +499,51 @@ func testBasic(
 		testBasicOS(
 			t,
 			dirPath,
-			true,
+			archiveTypeTar,
+			walkPrefix,
+			expectedPathToContent,
+			transformerOptions...,
+		)
+	})
+	t.Run("mem-tarzst", func(t *testing.T) {
+		t.Parallel()
+		testBasicMem(
+			t,
+			dirPath,
+			archiveTypeTarZst,
+			walkPrefix,
+			expectedPathToContent,
+			transformerOptions...,
+		)
+	})
+	t.Run("os-tarzst", func(t *testing.T) {
+		t.Parallel()
+		testBasicOS(
+			t,
+			dirPath,
+			archiveTypeTarZst,
+			walkPrefix,
+			expectedPathToContent,
+			transformerOptions...,
+		)
+	})
+	t.Run("mem-zip", func(t *testing.T) {
+		t.Parallel()
+		testBasicMem(
+			t,
+			dirPath,
+			archiveTypeZip,
+			walkPrefix,
+			expectedPathToContent,
+			transformerOptions...,
+		)
+	})
+	t.Run("os-zip", func(t *testing.T) {
+		t.Parallel()
+		testBasicOS(
+			t,
+			dirPath,
+			archiveTypeZip,
 			walkPrefix,
 			expectedPathToContent,
 			transformerOptions...,
@@ -276,7 +554,7 @@ func testBasic(
 func testBasicMem(
 	t *testing.T,
 	dirPath string,
-	doAsTar bool,
+	archiveType archiveType,
 	walkPrefix string,
 	expectedPathToContent map[string]string,
 	transformerOptions ...storagepath.TransformerOption,
@@ -286,7 +564,7 @@ func testBasicMem(
 		t,
 		bucket,
 		dirPath,
-		doAsTar,
+		archiveType,
 		walkPrefix,
 		expectedPathToContent,
 		transformerOptions...,
@@ -296,7 +574,7 @@ func testBasicMem(
 func testBasicOS(
 	t *testing.T,
 	dirPath string,
-	doAsTar bool,
+	archiveType archiveType,
 	walkPrefix string,
 	expectedPathToContent map[string]string,
 	transformerOptions ...storagepath.TransformerOption,
@@ -314,7 +592,7 @@ func testBasicOS(
 		t,
 		bucket,
 		dirPath,
-		doAsTar,
+		archiveType,
 		walkPrefix,
 		expectedPathToContent,
 		transformerOptions...,
@@ -325,14 +603,15 @@ func testBasicBucket(
 	t *testing.T,
 	bucket storage.Bucket,
 	dirPath string,
-	doAsTar bool,
+	archiveType archiveType,
 	walkPrefix string,
 	expectedPathToContent map[string]string,
 	transformerOptions ...storagepath.TransformerOption,
 ) {
 	inputBucket, err := storageos.NewBucket(dirPath)
 	require.NoError(t, err)
-	if doAsTar {
+	switch archiveType {
+	case archiveTypeTar:
 		buffer := bytes.NewBuffer(nil)
 		require.NoError(t, storageutil.Targz(
 			context.Background(),
@@ -345,9 +624,44 @@ func testBasicBucket(
 			context.Background(),
 			buffer,
 			bucket,
+			false,
+			transformerOptions...,
+		))
+	case archiveTypeTarZst:
+		buffer := bytes.NewBuffer(nil)
+		zstdEncoder, err := zstd.NewWriter(buffer)
+		require.NoError(t, err)
+		require.NoError(t, storageutil.Tar(
+			context.Background(),
+			zstdEncoder,
+			inputBucket,
+			"",
+		))
+		require.NoError(t, zstdEncoder.Close())
+		require.NoError(t, storageutil.Untarzst(
+			context.Background(),
+			buffer,
+			bucket,
+			false,
+			transformerOptions...,
+		))
+	case archiveTypeZip:
+		buffer := bytes.NewBuffer(nil)
+		require.NoError(t, storageutil.Zip(
+			context.Background(),
+			buffer,
+			inputBucket,
+			"",
+		))
+		require.NoError(t, storageutil.Unzip(
+			context.Background(),
+			bytes.NewReader(buffer.Bytes()),
+			int64(buffer.Len()),
+			bucket,
+			false,
 			transformerOptions...,
 		))
-	} else {
+	default:
 		_, err := storageutil.Copy(
 			context.Background(),
 			inputBucket,
@@ -4,22 +4,31 @@
 package storagegit
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"math"
+	"net/url"
 	"os"
+	"os/exec"
+	"path"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/bufbuild/buf/internal/pkg/storage"
 	"github.com/bufbuild/buf/internal/pkg/storage/storagegit/storagegitplumbing"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagemem"
 	"github.com/bufbuild/buf/internal/pkg/storage/storagepath"
+	"github.com/bufbuild/buf/internal/pkg/util/utilio"
 	"github.com/bufbuild/buf/internal/pkg/util/utillog"
 	"go.uber.org/multierr"
 	"go.uber.org/zap"
@@ -27,21 +36,66 @@ import (
 	"gopkg.in/src-d/go-billy.v4"
 	"gopkg.in/src-d/go-billy.v4/memfs"
 	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/format/packfile"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/protocol/packp"
+	"gopkg.in/src-d/go-git.v4/plumbing/protocol/packp/capability"
+	"gopkg.in/src-d/go-git.v4/plumbing/protocol/packp/sideband"
 	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/client"
 	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
 	srcdssh "gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
 	"gopkg.in/src-d/go-git.v4/storage/memory"
 )
 
-var gitURLSSHRegex = regexp.MustCompile("^(ssh://)?([^/:]*?)@[^@]+$")
+// gitModulesFilePath is the path of the file that configures submodules.
+const gitModulesFilePath = ".gitmodules"
+
+var (
+	gitURLSSHRegex = regexp.MustCompile("^(ssh://)?([^/:]*?)@[^@]+$")
+	gitURLSCPRegex = regexp.MustCompile("^([^/:]*@[^/:]+):(.*)$")
+)
 
 // Clone clones the url into the bucket.
 //
-// This is roughly equivalent to git clone --branch gitBranch --single-branch --depth 1 gitUrl.
+// This is roughly equivalent to git clone --branch gitBranch --single-branch --depth depth gitUrl.
 // Only regular files are added to the bucket.
 //
-// Branch is required.
+// Branch is required. If depth is 0, a depth of 1 is used.
+//
+// If the ref is a full commit hash, the files of that commit are used, and the commit
+// is verified to match the hash exactly. For remote repositories, only the commit is
+// fetched if the server allows fetching commits by hash. Otherwise, all branches are
+// fetched, with the full history if depth is 0, and the commit must be within depth
+// commits of a branch.
+//
+// If the gitURL is a local path, the repository is not cloned. Instead, the files of the
+// commit the ref resolves to are read directly from the local repository, and the ref
+// can be any revision, such as a commit hash. Branches that only exist as remote-tracking
+// branches of origin are also resolved. The depth is ignored.
+//
+// If recurseSubmodules is true, the submodules of the commit are cloned recursively at
+// the commits recorded in the commit, and their files are added to the bucket under the
+// submodule paths. Relative submodule URLs are resolved against the gitURL.
+//
+// If sparsePaths is not empty, only the files at or under these paths are added to the
+// bucket, similar to git sparse-checkout, and only the submodules at or above these paths
+// are cloned. The files of the commit are read directly from the fetched objects instead
+// of checking out the whole tree, which is much faster for large repositories. For remote
+// branches and tags, if git is installed, this is a partial clone with the system git
+// that only fetches the files at or under the sparse paths, unless pureGoEnvKey is set
+// to true or SSH is used. Otherwise, all objects of the commit are fetched. Sparse paths that do not exist in the commit are ignored. The sparse paths are
+// matched before the options are applied.
 //
+// If the gitURL begins with file://, the repository is cloned as if it were remote,
+// which requires the git-upload-pack binary of a system git installation. If git is not
+// installed, or if the environment variable pureGoEnvKey is set to true, the repository
+// is instead read directly as for a local path, so that minimal containers without git
+// can still use file:// URLs. Set pureGoEnvKey to false to always use the system git.
+// The https:// and ssh:// transports never require a system git installation.
 // If the gitURL begins with https:// and there is an HTTPS username and password, basic auth will be used.
 // If the gitURL begins with ssh:// and there is a valid SSH configuration, ssh will be used.
 //
@@ -54,11 +108,15 @@ func Clone(
 	homeDirPath string,
 	gitURL string,
 	refName storagegitplumbing.RefName,
+	depth uint32,
+	recurseSubmodules bool,
+	sparsePaths []string,
 	httpsUsernameEnvKey string,
 	httpsPasswordEnvKey string,
 	sshKeyFileEnvKey string,
 	sshKeyPassphraseEnvKey string,
 	sshKnownHostsFilesEnvKey string,
+	pureGoEnvKey string,
 	bucket storage.Bucket,
 	options ...storagepath.TransformerOption,
 ) error {
@@ -68,7 +126,55 @@ func Clone(
 		// we detect this outside of this function so this is a system error
 		return errors.New("refName is nil")
 	}
-	gitURL, err := normalizeGitURL(gitURL)
+	sparsePaths, err := normalizeSparsePaths(sparsePaths)
+	if err != nil {
+		return err
+	}
+	var cloneSubmodule func(string, plumbing.Hash, storage.Bucket) error
+	if recurseSubmodules {
+		cloneSubmodule = func(submoduleURL string, hash plumbing.Hash, submoduleBucket storage.Bucket) error {
+			return Clone(
+				ctx,
+				logger,
+				getenv,
+				homeDirPath,
+				submoduleURL,
+				storagegitplumbing.NewRefName(hash.String()),
+				0,
+				true,
+				nil,
+				httpsUsernameEnvKey,
+				httpsPasswordEnvKey,
+				sshKeyFileEnvKey,
+				sshKeyPassphraseEnvKey,
+				sshKnownHostsFilesEnvKey,
+				pureGoEnvKey,
+				submoduleBucket,
+			)
+		}
+	}
+	if isLocalFileGitURL(gitURL) {
+		return copyLocalRepository(ctx, logger, gitURL, refName, sparsePaths, cloneSubmodule, bucket, options...)
+	}
+	isCommitHash := storagegitplumbing.IsCommitHash(refName)
+	if !isCommitHash && !strings.HasPrefix(refName.String(), "refs/") {
+		return fmt.Errorf("ref %q must be a full reference name such as refs/heads/master for remote repositories", refName.String())
+	}
+	if isFileGitURL(gitURL) {
+		pureGo, err := usePureGoFileTransport(getenv, pureGoEnvKey)
+		if err != nil {
+			return err
+		}
+		if pureGo {
+			gitPath, err := fileGitURLToPath(gitURL)
+			if err != nil {
+				return err
+			}
+			logger.Debug("git_pure_go", zap.String("path", gitPath))
+			return copyLocalRepository(ctx, logger, gitPath, refName, sparsePaths, cloneSubmodule, bucket, options...)
+		}
+	}
+	gitURL, err = normalizeGitURL(gitURL)
 	if err != nil {
 		return err
 	}
@@ -86,23 +192,818 @@ func Clone(
 	if err != nil {
 		return err
 	}
+	if isCommitHash {
+		return cloneCommit(ctx, logger, gitURL, authMethod, refName, depth, sparsePaths, cloneSubmodule, bucket, options...)
+	}
+	if depth == 0 {
+		depth = 1
+	}
 	cloneOptions := &git.CloneOptions{
 		URL:           gitURL,
 		Auth:          authMethod,
 		ReferenceName: refName.ReferenceName(),
 		SingleBranch:  true,
-		Depth:         1,
+		Depth:         int(depth),
+	}
+	if len(sparsePaths) > 0 {
+		return cloneSparse(ctx, logger, getenv, pureGoEnvKey, gitURL, cloneOptions, sparsePaths, cloneSubmodule, bucket, options...)
 	}
 	filesystem := memfs.New()
-	if _, err := git.CloneContext(ctx, memory.NewStorage(), filesystem, cloneOptions); err != nil {
+	repository, err := git.CloneContext(ctx, memory.NewStorage(), filesystem, cloneOptions)
+	if err != nil {
+		return err
+	}
+	if err := copyBillyFilesystemToBucket(ctx, logger, filesystem, bucket, options...); err != nil {
+		return err
+	}
+	if cloneSubmodule == nil {
+		return nil
+	}
+	head, err := repository.Head()
+	if err != nil {
+		return err
+	}
+	commit, err := repository.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+	return copySubmodulesToBucket(ctx, logger, gitURL, commit, nil, cloneSubmodule, bucket, options...)
+}
+
+// cloneSparse clones without checking out the working tree, and copies only the files
+// at or under the sparse paths from the commit.
+//
+// If git is installed, this is a partial clone with the system git that only fetches
+// the files at or under the sparse paths, see partialClone. Otherwise, all objects of
+// the commit are fetched.
+func cloneSparse(
+	ctx context.Context,
+	logger *zap.Logger,
+	getenv func(string) string,
+	pureGoEnvKey string,
+	gitURL string,
+	cloneOptions *git.CloneOptions,
+	sparsePaths []string,
+	cloneSubmodule func(string, plumbing.Hash, storage.Bucket) error,
+	bucket storage.Bucket,
+	options ...storagepath.TransformerOption,
+) error {
+	defer utillog.Defer(logger, "git_clone_sparse")()
+
+	gitBinaryPath, err := getSystemGitBinaryPath(getenv, pureGoEnvKey)
+	if err != nil {
+		return err
+	}
+	// only branches and tags can be cloned by name with the system git
+	if gitBinaryPath != "" && (cloneOptions.ReferenceName.IsBranch() || cloneOptions.ReferenceName.IsTag()) {
+		if partialCloneEnv, ok := getPartialCloneEnv(cloneOptions.Auth); ok {
+			return clonePartial(
+				ctx,
+				logger,
+				gitBinaryPath,
+				partialCloneEnv,
+				gitURL,
+				cloneOptions,
+				sparsePaths,
+				cloneSubmodule,
+				bucket,
+				options...,
+			)
+		}
+		logger.Debug("git_partial_clone_unsupported_auth", zap.String("url", gitURL))
+	}
+	cloneOptions.NoCheckout = true
+	repository, err := git.CloneContext(ctx, memory.NewStorage(), nil, cloneOptions)
+	if err != nil {
+		return err
+	}
+	return copySparseRepositoryToBucket(ctx, logger, gitURL, repository, sparsePaths, cloneSubmodule, bucket, options...)
+}
+
+// clonePartial clones with partialClone into a temporary directory, and copies
+// only the files at or under the sparse paths from the commit.
+func clonePartial(
+	ctx context.Context,
+	logger *zap.Logger,
+	gitBinaryPath string,
+	env []string,
+	gitURL string,
+	cloneOptions *git.CloneOptions,
+	sparsePaths []string,
+	cloneSubmodule func(string, plumbing.Hash, storage.Bucket) error,
+	bucket storage.Bucket,
+	options ...storagepath.TransformerOption,
+) (retErr error) {
+	defer utillog.Defer(logger, "git_partial_clone")()
+
+	tmpDirPath, err := ioutil.TempDir("", "buf-git-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, os.RemoveAll(tmpDirPath))
+	}()
+	// .gitmodules is needed to clone the submodules
+	fetchPaths := sparsePaths
+	if cloneSubmodule != nil {
+		fetchPaths = append([]string{gitModulesFilePath}, sparsePaths...)
+	}
+	if err := partialClone(
+		ctx,
+		gitBinaryPath,
+		env,
+		gitURL,
+		cloneOptions.ReferenceName,
+		uint32(cloneOptions.Depth),
+		fetchPaths,
+		tmpDirPath,
+	); err != nil {
+		return err
+	}
+	// the objects that were fetched are read with go-git, so that files are copied
+	// the same way regardless of how they were fetched
+	repository, err := git.PlainOpen(tmpDirPath)
+	if err != nil {
+		return err
+	}
+	return copySparseRepositoryToBucket(ctx, logger, gitURL, repository, sparsePaths, cloneSubmodule, bucket, options...)
+}
+
+// partialClone clones the branch or tag of the gitURL into the directory with the
+// system git, fetching only the blobs of the files at or under the paths.
+//
+// This is roughly equivalent to git clone --filter=blob:none --sparse followed by
+// git sparse-checkout set. All commits and trees within depth are fetched, but the
+// blobs of other files are not. If the server does not support partial clone, git
+// falls back to fetching all objects.
+func partialClone(
+	ctx context.Context,
+	gitBinaryPath string,
+	env []string,
+	gitURL string,
+	referenceName plumbing.ReferenceName,
+	depth uint32,
+	paths []string,
+	dirPath string,
+) error {
+	args := []string{
+		"clone",
+		"--quiet",
+		"--filter=blob:none",
+		"--no-checkout",
+		"--single-branch",
+		"--branch",
+		referenceName.Short(),
+	}
+	if depth > 0 {
+		args = append(args, "--depth", strconv.FormatUint(uint64(depth), 10))
+	}
+	args = append(args, "--", gitURL, dirPath)
+	if err := runGit(ctx, gitBinaryPath, env, "", args...); err != nil {
+		return err
+	}
+	// the patterns are written directly instead of with git sparse-checkout set,
+	// which changed between versions of git
+	sparseCheckoutPatterns := make([]string, len(paths))
+	for i, path := range paths {
+		sparseCheckoutPatterns[i] = "/" + escapeSparseCheckoutPattern(path) + "\n"
+	}
+	if err := ioutil.WriteFile(
+		filepath.Join(dirPath, ".git", "info", "sparse-checkout"),
+		[]byte(strings.Join(sparseCheckoutPatterns, "")),
+		0644,
+	); err != nil {
+		return err
+	}
+	if err := runGit(ctx, gitBinaryPath, env, dirPath, "config", "core.sparseCheckout", "true"); err != nil {
+		return err
+	}
+	// this fetches the missing blobs of the files matched by the patterns in one request
+	return runGit(ctx, gitBinaryPath, env, dirPath, "read-tree", "-mu", "HEAD")
+}
+
+func runGit(ctx context.Context, gitBinaryPath string, env []string, dirPath string, args ...string) error {
+	buffer := bytes.NewBuffer(nil)
+	cmd := exec.CommandContext(ctx, gitBinaryPath, args...)
+	cmd.Dir = dirPath
+	cmd.Env = env
+	cmd.Stdout = buffer
+	cmd.Stderr = buffer
+	if err := cmd.Run(); err != nil {
+		if output := strings.TrimSpace(buffer.String()); output != "" {
+			return fmt.Errorf("git %s: %v: %s", args[0], err, output)
+		}
+		return fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return nil
+}
+
+// escapeSparseCheckoutPattern escapes the characters of the path that have a
+// special meaning in sparse checkout patterns, which are the same as gitignore
+// patterns.
+func escapeSparseCheckoutPattern(path string) string {
+	var builder strings.Builder
+	for _, c := range path {
+		switch c {
+		case '\\', '*', '?', '[':
+			builder.WriteRune('\\')
+		}
+		builder.WriteRune(c)
+	}
+	return builder.String()
+}
+
+// getSystemGitBinaryPath returns the path of the system git binary, or empty
+// if git is not installed or the environment variable pureGoEnvKey is set to true.
+func getSystemGitBinaryPath(getenv func(string) string, pureGoEnvKey string) (string, error) {
+	if getenv != nil && pureGoEnvKey != "" {
+		if value := getenv(pureGoEnvKey); value != "" {
+			pureGo, err := strconv.ParseBool(value)
+			if err != nil {
+				return "", fmt.Errorf("invalid value for %s: %q", pureGoEnvKey, value)
+			}
+			if pureGo {
+				return "", nil
+			}
+		}
+	}
+	gitBinaryPath, err := exec.LookPath("git")
+	if err != nil {
+		return "", nil
+	}
+	return gitBinaryPath, nil
+}
+
+// getPartialCloneEnv returns the environment to run the system git with for
+// the auth method.
+//
+// Returns false if the auth method cannot be passed to the system git, which is
+// the case for SSH, as the system git uses its own SSH configuration.
+func getPartialCloneEnv(authMethod transport.AuthMethod) ([]string, bool) {
+	// never prompt for credentials
+	env := append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	switch t := authMethod.(type) {
+	case nil:
+		return env, true
+	case *http.BasicAuth:
+		// the header is passed with the environment instead of arguments so that
+		// the credentials are not visible to other processes
+		return append(
+			env,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.extraHeader",
+			"GIT_CONFIG_VALUE_0=Authorization: Basic "+base64.StdEncoding.EncodeToString([]byte(t.Username+":"+t.Password)),
+		), true
+	default:
+		return nil, false
+	}
+}
+
+// copySparseRepositoryToBucket copies the files at or under the sparse paths from the
+// commit of the HEAD of the repository.
+func copySparseRepositoryToBucket(
+	ctx context.Context,
+	logger *zap.Logger,
+	gitURL string,
+	repository *git.Repository,
+	sparsePaths []string,
+	cloneSubmodule func(string, plumbing.Hash, storage.Bucket) error,
+	bucket storage.Bucket,
+	options ...storagepath.TransformerOption,
+) error {
+	head, err := repository.Head()
+	if err != nil {
+		return err
+	}
+	commit, err := repository.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+	if err := copyCommitToBucket(ctx, commit, sparsePaths, bucket, options...); err != nil {
+		return err
+	}
+	if cloneSubmodule == nil {
+		return nil
+	}
+	return copySubmodulesToBucket(ctx, logger, gitURL, commit, sparsePaths, cloneSubmodule, bucket, options...)
+}
+
+func copyLocalRepository(
+	ctx context.Context,
+	logger *zap.Logger,
+	gitPath string,
+	refName storagegitplumbing.RefName,
+	sparsePaths []string,
+	cloneSubmodule func(string, plumbing.Hash, storage.Bucket) error,
+	bucket storage.Bucket,
+	options ...storagepath.TransformerOption,
+) error {
+	defer utillog.Defer(logger, "git_local_copy")()
+
+	repository, err := git.PlainOpen(gitPath)
+	if err != nil {
+		return err
+	}
+	commit, err := resolveLocalCommit(repository, refName)
+	if err != nil {
+		return err
+	}
+	if err := verifyCommit(commit, refName); err != nil {
+		return err
+	}
+	if err := copyCommitToBucket(ctx, commit, sparsePaths, bucket, options...); err != nil {
+		return err
+	}
+	if cloneSubmodule == nil {
+		return nil
+	}
+	return copySubmodulesToBucket(ctx, logger, gitPath, commit, sparsePaths, cloneSubmodule, bucket, options...)
+}
+
+func cloneCommit(
+	ctx context.Context,
+	logger *zap.Logger,
+	gitURL string,
+	authMethod transport.AuthMethod,
+	refName storagegitplumbing.RefName,
+	depth uint32,
+	sparsePaths []string,
+	cloneSubmodule func(string, plumbing.Hash, storage.Bucket) error,
+	bucket storage.Bucket,
+	options ...storagepath.TransformerOption,
+) error {
+	defer utillog.Defer(logger, "git_clone_commit")()
+
+	hash := plumbing.NewHash(refName.String())
+	storer := memory.NewStorage()
+	fetched, err := fetchCommit(ctx, gitURL, authMethod, hash, storer)
+	if err != nil {
+		return err
+	}
+	if !fetched {
+		logger.Debug("git_fetch_commit_unsupported", zap.String("commit", hash.String()))
+		if _, err := git.CloneContext(
+			ctx,
+			storer,
+			nil,
+			&git.CloneOptions{
+				URL:        gitURL,
+				Auth:       authMethod,
+				NoCheckout: true,
+				Depth:      int(depth),
+			},
+		); err != nil {
+			return err
+		}
+	}
+	commit, err := object.GetCommit(storer, hash)
+	if err != nil {
+		if depth > 0 && !fetched {
+			return fmt.Errorf("could not find commit %s within depth %d of a branch: %v", refName.String(), depth, err)
+		}
+		return fmt.Errorf("could not find commit %s: %v", refName.String(), err)
+	}
+	if err := verifyCommit(commit, refName); err != nil {
+		return err
+	}
+	if err := copyCommitToBucket(ctx, commit, sparsePaths, bucket, options...); err != nil {
+		return err
+	}
+	if cloneSubmodule == nil {
+		return nil
+	}
+	return copySubmodulesToBucket(ctx, logger, gitURL, commit, sparsePaths, cloneSubmodule, bucket, options...)
+}
+
+// fetchCommit fetches only the commit with the hash and its tree into the storer.
+//
+// Returns false if the server does not allow fetching the commit by hash, in which
+// case nothing is fetched.
+func fetchCommit(
+	ctx context.Context,
+	gitURL string,
+	authMethod transport.AuthMethod,
+	hash plumbing.Hash,
+	storer *memory.Storage,
+) (_ bool, retErr error) {
+	endpoint, err := transport.NewEndpoint(gitURL)
+	if err != nil {
+		return false, err
+	}
+	transportClient, err := client.NewClient(endpoint)
+	if err != nil {
+		return false, err
+	}
+	session, err := transportClient.NewUploadPackSession(endpoint, authMethod)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, session.Close())
+	}()
+	advRefs, err := session.AdvertisedReferences()
+	if err != nil {
+		return false, err
+	}
+	if !canFetchCommit(advRefs, hash) {
+		return false, nil
+	}
+	request := packp.NewUploadPackRequestFromCapabilities(advRefs.Capabilities)
+	request.Wants = []plumbing.Hash{hash}
+	if advRefs.Capabilities.Supports(capability.Shallow) {
+		request.Depth = packp.DepthCommits(1)
+		if err := request.Capabilities.Set(capability.Shallow); err != nil {
+			return false, err
+		}
+	}
+	response, err := session.UploadPack(ctx, request)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Close())
+	}()
+	var reader io.Reader = response
+	switch {
+	case request.Capabilities.Supports(capability.Sideband64k):
+		reader = sideband.NewDemuxer(sideband.Sideband64k, response)
+	case request.Capabilities.Supports(capability.Sideband):
+		reader = sideband.NewDemuxer(sideband.Sideband, response)
+	}
+	if err := packfile.UpdateObjectStorage(storer, reader); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// canFetchCommit returns true if the server allows fetching the commit by hash,
+// either because it allows fetching any reachable commit or because the commit is
+// the tip of an advertised reference.
+func canFetchCommit(advRefs *packp.AdvRefs, hash plumbing.Hash) bool {
+	if advRefs.Capabilities.Supports(capability.AllowReachableSHA1InWant) {
+		return true
+	}
+	if advRefs.Head != nil && *advRefs.Head == hash {
+		return true
+	}
+	for _, referenceHash := range advRefs.References {
+		if referenceHash == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// copyCommitToBucket copies the files of the commit to the bucket.
+//
+// If sparsePaths is not empty, only the files at or under the sparse paths are copied.
+func copyCommitToBucket(
+	ctx context.Context,
+	commit *object.Commit,
+	sparsePaths []string,
+	bucket storage.Bucket,
+	options ...storagepath.TransformerOption,
+) error {
+	tree, err := commit.Tree()
+	if err != nil {
+		return err
+	}
+	transformer := storagepath.NewTransformer(options...)
+	if len(sparsePaths) == 0 {
+		return copyTreeToBucket(ctx, tree, "", transformer, bucket)
+	}
+	for _, sparsePath := range sparsePaths {
+		if isInSubmodule(tree, sparsePath) {
+			// the files of submodules are copied by copySubmodulesToBucket
+			continue
+		}
+		entry, err := tree.FindEntry(sparsePath)
+		if err != nil {
+			if err == object.ErrEntryNotFound || err == object.ErrDirectoryNotFound {
+				continue
+			}
+			return err
+		}
+		if entry.Mode == filemode.Dir {
+			subTree, err := tree.Tree(sparsePath)
+			if err != nil {
+				return err
+			}
+			if err := copyTreeToBucket(ctx, subTree, sparsePath, transformer, bucket); err != nil {
+				return err
+			}
+			continue
+		}
+		file, err := tree.TreeEntryFile(entry)
+		if err != nil {
+			return err
+		}
+		file.Name = sparsePath
+		if err := copyTreeFileToBucket(ctx, file, "", transformer, bucket); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isInSubmodule returns true if the path is under a submodule of the tree.
+func isInSubmodule(tree *object.Tree, path string) bool {
+	for dirPath := storagepath.Dir(path); dirPath != "."; dirPath = storagepath.Dir(dirPath) {
+		entry, err := tree.FindEntry(dirPath)
+		if err == nil && entry.Mode == filemode.Submodule {
+			return true
+		}
+	}
+	return false
+}
+
+// copyTreeToBucket copies the files of the tree to the bucket, with the names
+// of the files joined to the dirPath.
+func copyTreeToBucket(
+	ctx context.Context,
+	tree *object.Tree,
+	dirPath string,
+	transformer storagepath.Transformer,
+	bucket storage.Bucket,
+) error {
+	return tree.Files().ForEach(func(file *object.File) error {
+		return copyTreeFileToBucket(ctx, file, dirPath, transformer, bucket)
+	})
+}
+
+func copyTreeFileToBucket(
+	ctx context.Context,
+	file *object.File,
+	dirPath string,
+	transformer storagepath.Transformer,
+	bucket storage.Bucket,
+) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+	// this skips symlinks, matching the behavior for cloned repositories
+	if !file.Mode.IsRegular() && file.Mode != filemode.Executable {
+		return nil
+	}
+	name := file.Name
+	if dirPath != "" {
+		name = storagepath.Join(dirPath, name)
+	}
+	path, err := storagepath.NormalizeAndValidate(name)
+	if err != nil {
+		return err
+	}
+	path, ok := transformer.Transform(path)
+	if !ok {
+		return nil
+	}
+	if file.Size > math.MaxUint32 {
+		return fmt.Errorf("size %d is greater than uint32", file.Size)
+	}
+	return copyGitFile(ctx, file, bucket, path)
+}
+
+// copySubmodulesToBucket clones the submodules of the commit with cloneSubmodule and
+// copies their files to the bucket under the submodule paths.
+//
+// Submodules listed in .gitmodules that are not in the commit are skipped.
+// If sparsePaths is not empty, submodules that are not at or above a sparse path,
+// and not under a sparse path, are skipped.
+func copySubmodulesToBucket(
+	ctx context.Context,
+	logger *zap.Logger,
+	gitURL string,
+	commit *object.Commit,
+	sparsePaths []string,
+	cloneSubmodule func(string, plumbing.Hash, storage.Bucket) error,
+	bucket storage.Bucket,
+	options ...storagepath.TransformerOption,
+) error {
+	defer utillog.Defer(logger, "git_clone_submodules")()
+
+	file, err := commit.File(gitModulesFilePath)
+	if err != nil {
+		if err == object.ErrFileNotFound {
+			return nil
+		}
+		return err
+	}
+	content, err := file.Contents()
+	if err != nil {
+		return err
+	}
+	modules := config.NewModules()
+	if err := modules.Unmarshal([]byte(content)); err != nil {
+		return fmt.Errorf("could not parse %s: %v", gitModulesFilePath, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
 		return err
 	}
-	return copyBillyFilesystemToBucket(ctx, logger, filesystem, bucket, options...)
+	names := make([]string, 0, len(modules.Submodules))
+	for name := range modules.Submodules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	transformer := storagepath.NewTransformer(options...)
+	for _, name := range names {
+		submodule := modules.Submodules[name]
+		submodulePath, err := storagepath.NormalizeAndValidate(submodule.Path)
+		if err != nil {
+			return fmt.Errorf("invalid path for submodule %q in %s: %v", name, gitModulesFilePath, err)
+		}
+		if !sparsePathsOverlap(sparsePaths, submodulePath) {
+			continue
+		}
+		entry, err := tree.FindEntry(submodulePath)
+		if err != nil || entry.Mode != filemode.Submodule {
+			continue
+		}
+		submoduleURL, err := resolveSubmoduleURL(gitURL, submodule.URL)
+		if err != nil {
+			return err
+		}
+		logger.Debug(
+			"git_clone_submodule",
+			zap.String("path", submodulePath),
+			zap.String("url", submoduleURL),
+			zap.String("commit", entry.Hash.String()),
+		)
+		submoduleBucket := storagemem.NewBucket()
+		if err := cloneSubmodule(submoduleURL, entry.Hash, submoduleBucket); err != nil {
+			return multierr.Append(
+				fmt.Errorf("could not clone submodule %s: %v", submodulePath, err),
+				submoduleBucket.Close(),
+			)
+		}
+		err = submoduleBucket.Walk(
+			ctx,
+			"",
+			func(path string) error {
+				joinedPath := storagepath.Join(submodulePath, path)
+				if !sparsePathsOverlap(sparsePaths, joinedPath) {
+					return nil
+				}
+				newPath, ok := transformer.Transform(joinedPath)
+				if !ok {
+					return nil
+				}
+				return copyBucketPath(ctx, submoduleBucket, bucket, path, newPath)
+			},
+		)
+		if err = multierr.Append(err, submoduleBucket.Close()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizeSparsePaths normalizes and validates the sparse paths.
+//
+// Returns nil if there are no sparse paths or any sparse path is the root.
+func normalizeSparsePaths(sparsePaths []string) ([]string, error) {
+	normalizedSparsePaths := make([]string, 0, len(sparsePaths))
+	for _, sparsePath := range sparsePaths {
+		normalizedSparsePath, err := storagepath.NormalizeAndValidate(sparsePath)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sparse path: %v", err)
+		}
+		if normalizedSparsePath == "." {
+			return nil, nil
+		}
+		normalizedSparsePaths = append(normalizedSparsePaths, normalizedSparsePath)
+	}
+	if len(normalizedSparsePaths) == 0 {
+		return nil, nil
+	}
+	sort.Strings(normalizedSparsePaths)
+	// remove sparse paths that are at or under other sparse paths so that files are only copied once
+	dedupedSparsePaths := make([]string, 0, len(normalizedSparsePaths))
+	for _, normalizedSparsePath := range normalizedSparsePaths {
+		if !isAtOrUnderAny(normalizedSparsePath, dedupedSparsePaths) {
+			dedupedSparsePaths = append(dedupedSparsePaths, normalizedSparsePath)
+		}
+	}
+	return dedupedSparsePaths, nil
+}
+
+func isAtOrUnderAny(path string, dirPaths []string) bool {
+	for _, dirPath := range dirPaths {
+		if path == dirPath || strings.HasPrefix(path, dirPath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// sparsePathsOverlap returns true if sparsePaths is empty, or if the path is at,
+// above, or under a sparse path.
+func sparsePathsOverlap(sparsePaths []string, path string) bool {
+	if len(sparsePaths) == 0 {
+		return true
+	}
+	for _, sparsePath := range sparsePaths {
+		if path == sparsePath || strings.HasPrefix(path, sparsePath+"/") || strings.HasPrefix(sparsePath, path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveSubmoduleURL resolves submodule URLs that begin with ./ or ../ against
+// the URL of the repository, as git does.
+func resolveSubmoduleURL(gitURL string, submoduleURL string) (string, error) {
+	if !strings.HasPrefix(submoduleURL, "./") && !strings.HasPrefix(submoduleURL, "../") {
+		return submoduleURL, nil
+	}
+	base := strings.TrimSuffix(strings.TrimSuffix(gitURL, "/"), "/.git")
+	if isLocalFileGitURL(base) {
+		if matches := gitURLSCPRegex.FindStringSubmatch(base); len(matches) > 2 {
+			// scp-like ssh syntax, such as git@github.com:bufbuild/buf.git
+			return matches[1] + ":" + strings.TrimPrefix(path.Join("/", matches[2], submoduleURL), "/"), nil
+		}
+		return filepath.Join(base, filepath.FromSlash(submoduleURL)), nil
+	}
+	parsedURL, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve submodule url %q: %v", submoduleURL, err)
+	}
+	parsedURL.Path = path.Join("/", parsedURL.Path, submoduleURL)
+	return parsedURL.String(), nil
+}
+
+func copyBucketPath(
+	ctx context.Context,
+	from storage.ReadBucket,
+	to storage.Bucket,
+	fromPath string,
+	toPath string,
+) error {
+	readObject, err := from.Get(ctx, fromPath)
+	if err != nil {
+		return err
+	}
+	writeObject, err := to.Put(ctx, toPath, readObject.Size())
+	if err != nil {
+		return multierr.Append(err, readObject.Close())
+	}
+	_, err = utilio.Copy(writeObject, readObject)
+	return multierr.Append(err, multierr.Append(writeObject.Close(), readObject.Close()))
+}
+
+func resolveLocalCommit(repository *git.Repository, refName storagegitplumbing.RefName) (*object.Commit, error) {
+	revisions := []plumbing.Revision{plumbing.Revision(refName.String())}
+	if referenceName := refName.ReferenceName(); referenceName.IsBranch() {
+		// CI checkouts frequently only have the remote-tracking branch
+		revisions = append(
+			revisions,
+			plumbing.Revision(plumbing.NewRemoteReferenceName("origin", referenceName.Short()).String()),
+		)
+	}
+	for _, revision := range revisions {
+		hash, err := repository.ResolveRevision(revision)
+		if err != nil {
+			continue
+		}
+		return repository.CommitObject(*hash)
+	}
+	return nil, fmt.Errorf("could not resolve %s to a commit", refName.String())
+}
+
+// verifyCommit verifies that the commit matches the RefName if the RefName is a full commit hash.
+func verifyCommit(commit *object.Commit, refName storagegitplumbing.RefName) error {
+	if !storagegitplumbing.IsCommitHash(refName) {
+		return nil
+	}
+	if !strings.EqualFold(commit.Hash.String(), refName.String()) {
+		return fmt.Errorf("resolved commit %s does not match requested commit %s", commit.Hash.String(), refName.String())
+	}
+	return nil
+}
+
+func copyGitFile(
+	ctx context.Context,
+	file *object.File,
+	bucket storage.Bucket,
+	path string,
+) error {
+	reader, err := file.Reader()
+	if err != nil {
+		return err
+	}
+	writeObject, err := bucket.Put(ctx, path, uint32(file.Size))
+	if err != nil {
+		return multierr.Append(err, reader.Close())
+	}
+	_, err = utilio.Copy(writeObject, reader)
+	return multierr.Append(err, multierr.Append(writeObject.Close(), reader.Close()))
 }
 
 func normalizeGitURL(gitURL string) (string, error) {
 	switch {
-	case isHTTPGitURL(gitURL), isHTTPSGitURL(gitURL), isSSHGitURL(gitURL):
+	case isHTTPGitURL(gitURL), isHTTPSGitURL(gitURL), isSSHGitURL(gitURL), isFileGitURL(gitURL):
 		return gitURL, nil
 	case isLocalFileGitURL(gitURL):
 		absGitPath, err := filepath.Abs(gitURL)
@@ -122,6 +1023,10 @@ func isHTTPSGitURL(gitURL string) bool {
 	return strings.HasPrefix(gitURL, "https://")
 }
 
+func isFileGitURL(gitURL string) bool {
+	return strings.HasPrefix(gitURL, "file://")
+}
+
 func isSSHGitURL(gitURL string) bool {
 	_, ok := getSSHGitUser(gitURL)
 	return ok
@@ -131,6 +1036,46 @@ func isLocalFileGitURL(gitURL string) bool {
 	return !strings.Contains(gitURL, "://")
 }
 
+// fileGitURLToPath returns the local path of the file:// URL.
+func fileGitURLToPath(gitURL string) (string, error) {
+	parsedURL, err := url.Parse(gitURL)
+	if err != nil {
+		return "", err
+	}
+	if parsedURL.Host != "" && parsedURL.Host != "localhost" {
+		return "", fmt.Errorf("%s: file:// URLs must not have a remote host", gitURL)
+	}
+	path := parsedURL.Path
+	// windows paths are of the form /C:/foo
+	if runtime.GOOS == "windows" && len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	return filepath.FromSlash(path), nil
+}
+
+// usePureGoFileTransport returns true if file:// URLs should be read directly
+// instead of with the git-upload-pack binary of the system git installation.
+//
+// This is the value of the environment variable pureGoEnvKey if set, and
+// otherwise true only if git is not installed.
+func usePureGoFileTransport(getenv func(string) string, pureGoEnvKey string) (bool, error) {
+	if getenv != nil && pureGoEnvKey != "" {
+		if value := getenv(pureGoEnvKey); value != "" {
+			pureGo, err := strconv.ParseBool(value)
+			if err != nil {
+				return false, fmt.Errorf("invalid value for %s: %q", pureGoEnvKey, value)
+			}
+			return pureGo, nil
+		}
+	}
+	if _, err := exec.LookPath(transport.UploadPackServiceName); err == nil {
+		return false, nil
+	}
+	// go-git also finds git-upload-pack with git --exec-path
+	_, err := exec.LookPath("git")
+	return err != nil, nil
+}
+
 func getSSHGitUser(gitURL string) (string, bool) {
 	if matches := gitURLSSHRegex.FindStringSubmatch(gitURL); len(matches) > 2 {
 		return matches[2], true
@@ -296,7 +1241,7 @@ func copyBillyPath(
 	if err != nil {
 		return multierr.Append(err, file.Close())
 	}
-	_, err = io.Copy(writeObject, file)
+	_, err = utilio.Copy(writeObject, file)
 	return multierr.Append(err, multierr.Append(writeObject.Close(), file.Close()))
 }
 
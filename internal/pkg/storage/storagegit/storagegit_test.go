@@ -0,0 +1,534 @@
+package storagegit
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/buf/internal/pkg/storage"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagegit/storagegitplumbing"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagemem"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagepath"
+	"github.com/bufbuild/buf/internal/pkg/storage/storageutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+const testPureGoEnvKey = "BUF_TEST_GIT_PURE_GO"
+
+func TestCloneLocal(t *testing.T) {
+	t.Parallel()
+	tmpDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tmpDirPath))
+	}()
+
+	repository, err := git.PlainInit(tmpDirPath, false)
+	require.NoError(t, err)
+	firstHash := testCommitFile(t, repository, tmpDirPath, "a.proto", "first")
+	require.NoError(t, repository.Storer.SetReference(plumbing.NewHashReference(plumbing.NewTagReferenceName("v1.0.0"), firstHash)))
+	secondHash := testCommitFile(t, repository, tmpDirPath, "a.proto", "second")
+	// the working tree is not used
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tmpDirPath, "a.proto"), []byte("third"), 0600))
+	require.NoError(t, repository.Storer.SetReference(plumbing.NewHashReference(plumbing.NewRemoteReferenceName("origin", "main"), secondHash)))
+
+	gitPath := filepath.Join(tmpDirPath, ".git")
+	testCloneLocal(t, gitPath, storagegitplumbing.NewBranchRefName("master"), "second")
+	testCloneLocal(t, gitPath, storagegitplumbing.NewBranchRefName("main"), "second")
+	testCloneLocal(t, gitPath, storagegitplumbing.NewTagRefName("v1.0.0"), "first")
+	testCloneLocal(t, gitPath, storagegitplumbing.NewRefName("HEAD~1"), "first")
+	testCloneLocal(t, gitPath, storagegitplumbing.NewRefName(secondHash.String()), "second")
+	testCloneLocal(t, gitPath, storagegitplumbing.NewRefName(firstHash.String()), "first")
+
+	testCloneError(t, gitPath, storagegitplumbing.NewBranchRefName("foo"), 0)
+	testCloneError(t, gitPath, storagegitplumbing.NewRefName("0123456789abcdef0123456789abcdef01234567"), 0)
+}
+
+func TestCloneRemoteCommit(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("cloning file:// urls requires git")
+	}
+	tmpDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tmpDirPath))
+	}()
+
+	repository, err := git.PlainInit(tmpDirPath, false)
+	require.NoError(t, err)
+	firstHash := testCommitFile(t, repository, tmpDirPath, "a.proto", "first")
+	secondHash := testCommitFile(t, repository, tmpDirPath, "a.proto", "second")
+	_ = testCommitFile(t, repository, tmpDirPath, "a.proto", "third")
+
+	gitURL := "file://" + filepath.Join(tmpDirPath, ".git")
+	// the server does not allow fetching commits by hash, so the branches are cloned
+	testCloneRemote(t, gitURL, storagegitplumbing.NewRefName(firstHash.String()), 0, "first")
+	testCloneRemote(t, gitURL, storagegitplumbing.NewRefName(secondHash.String()), 2, "second")
+	testCloneError(t, gitURL, storagegitplumbing.NewRefName(firstHash.String()), 2)
+	testCloneRemote(t, gitURL, storagegitplumbing.NewBranchRefName("master"), 0, "third")
+
+	config, err := repository.Config()
+	require.NoError(t, err)
+	config.Raw.Section("uploadpack").SetOption("allowReachableSHA1InWant", "true")
+	require.NoError(t, repository.Storer.SetConfig(config))
+	// only the commit is fetched, so the depth does not matter
+	testCloneRemote(t, gitURL, storagegitplumbing.NewRefName(firstHash.String()), 1, "first")
+	testCloneError(t, gitURL, storagegitplumbing.NewRefName("0123456789abcdef0123456789abcdef01234567"), 0)
+}
+
+func TestCloneRemotePureGo(t *testing.T) {
+	t.Parallel()
+	tmpDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tmpDirPath))
+	}()
+
+	repository, err := git.PlainInit(tmpDirPath, false)
+	require.NoError(t, err)
+	firstHash := testCommitFile(t, repository, tmpDirPath, "a.proto", "first")
+	require.NoError(t, repository.Storer.SetReference(plumbing.NewHashReference(plumbing.NewTagReferenceName("v1.0.0"), firstHash)))
+	secondHash := testCommitFile(t, repository, tmpDirPath, "a.proto", "second")
+	_ = testCommitFile(t, repository, tmpDirPath, "a.proto", "third")
+
+	// this does not require git to be installed
+	getenv := func(key string) string {
+		if key == testPureGoEnvKey {
+			return "true"
+		}
+		return ""
+	}
+	gitURL := "file://" + filepath.ToSlash(filepath.Join(tmpDirPath, ".git"))
+	testCloneRemoteGetenv(t, getenv, gitURL, storagegitplumbing.NewBranchRefName("master"), 0, "third")
+	testCloneRemoteGetenv(t, getenv, gitURL, storagegitplumbing.NewTagRefName("v1.0.0"), 0, "first")
+	testCloneRemoteGetenv(t, getenv, gitURL, storagegitplumbing.NewRefName(secondHash.String()), 0, "second")
+	testCloneRemoteGetenv(t, getenv, gitURL, storagegitplumbing.NewRefName(firstHash.String()), 1, "first")
+	testCloneErrorGetenv(t, getenv, gitURL, storagegitplumbing.NewBranchRefName("foo"), 0)
+	testCloneErrorGetenv(t, getenv, gitURL, storagegitplumbing.NewRefName("0123456789abcdef0123456789abcdef01234567"), 0)
+	// remote repositories still require full reference names
+	testCloneErrorGetenv(t, getenv, gitURL, storagegitplumbing.NewRefName("master"), 0)
+	testCloneErrorGetenv(
+		t,
+		func(key string) string {
+			if key == testPureGoEnvKey {
+				return "foo"
+			}
+			return ""
+		},
+		gitURL,
+		storagegitplumbing.NewBranchRefName("master"),
+		0,
+	)
+}
+
+func TestCloneSubmodules(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("creating submodules requires git")
+	}
+	tmpDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tmpDirPath))
+	}()
+
+	subDirPath := filepath.Join(tmpDirPath, "sub")
+	subRepository, err := git.PlainInit(subDirPath, false)
+	require.NoError(t, err)
+	_ = testCommitFile(t, subRepository, subDirPath, "b.proto", "sub")
+
+	mainDirPath := filepath.Join(tmpDirPath, "main")
+	repository, err := git.PlainInit(mainDirPath, false)
+	require.NoError(t, err)
+	_ = testCommitFile(t, repository, mainDirPath, "a.proto", "main")
+	testRunGit(t, mainDirPath, "-c", "protocol.file.allow=always", "submodule", "add", "../sub", "vendor/sub")
+	testRunGit(t, mainDirPath, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-m", "submodule")
+	head, err := repository.Head()
+	require.NoError(t, err)
+
+	gitPath := filepath.Join(mainDirPath, ".git")
+	gitURL := "file://" + gitPath
+	for _, refName := range []storagegitplumbing.RefName{
+		storagegitplumbing.NewBranchRefName("master"),
+		storagegitplumbing.NewRefName(head.Hash().String()),
+	} {
+		testCloneSubmodules(t, gitPath, refName, false, nil, map[string]string{"a.proto": "main"})
+		testCloneSubmodules(t, gitPath, refName, true, nil, map[string]string{"a.proto": "main", "vendor/sub/b.proto": "sub"})
+		testCloneSubmodules(t, gitURL, refName, true, nil, map[string]string{"a.proto": "main", "vendor/sub/b.proto": "sub"})
+		testCloneSubmodules(t, gitPath, refName, true, []string{"a.proto"}, map[string]string{"a.proto": "main"})
+		testCloneSubmodules(t, gitURL, refName, true, []string{"a.proto"}, map[string]string{"a.proto": "main"})
+		testCloneSubmodules(t, gitURL, refName, true, []string{"vendor"}, map[string]string{"vendor/sub/b.proto": "sub"})
+		testCloneSubmodules(t, gitURL, refName, true, []string{"vendor/sub/b.proto"}, map[string]string{"vendor/sub/b.proto": "sub"})
+	}
+}
+
+func TestCloneSparse(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("cloning file:// urls requires git")
+	}
+	tmpDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tmpDirPath))
+	}()
+
+	repository, err := git.PlainInit(tmpDirPath, false)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDirPath, "proto", "foo"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDirPath, "other"), 0755))
+	_ = testCommitFile(t, repository, tmpDirPath, "a.proto", "a")
+	_ = testCommitFile(t, repository, tmpDirPath, "proto/b.proto", "b")
+	_ = testCommitFile(t, repository, tmpDirPath, "proto/foo/c.proto", "c")
+	head := testCommitFile(t, repository, tmpDirPath, "other/d.proto", "d")
+
+	gitPath := filepath.Join(tmpDirPath, ".git")
+	gitURL := "file://" + gitPath
+	for _, refName := range []storagegitplumbing.RefName{
+		storagegitplumbing.NewBranchRefName("master"),
+		storagegitplumbing.NewRefName(head.String()),
+	} {
+		for _, url := range []string{gitPath, gitURL} {
+			testCloneSubmodules(t, url, refName, false, []string{"proto"}, map[string]string{"proto/b.proto": "b", "proto/foo/c.proto": "c"})
+			testCloneSubmodules(t, url, refName, false, []string{"proto/foo", "other/d.proto", "missing"}, map[string]string{"proto/foo/c.proto": "c", "other/d.proto": "d"})
+			testCloneSubmodules(t, url, refName, false, []string{"proto", "proto/foo"}, map[string]string{"proto/b.proto": "b", "proto/foo/c.proto": "c"})
+			testCloneSubmodules(t, url, refName, false, []string{"proto", "."}, map[string]string{"a.proto": "a", "proto/b.proto": "b", "proto/foo/c.proto": "c", "other/d.proto": "d"})
+		}
+	}
+}
+
+func TestCloneSparsePartial(t *testing.T) {
+	t.Parallel()
+	gitBinaryPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("partial clone requires git")
+	}
+	tmpDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tmpDirPath))
+	}()
+
+	srcDirPath := filepath.Join(tmpDirPath, "src")
+	repository, err := git.PlainInit(srcDirPath, false)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(srcDirPath, "proto"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(srcDirPath, "other"), 0755))
+	_ = testCommitFile(t, repository, srcDirPath, "proto/a.proto", "a")
+	_ = testCommitFile(t, repository, srcDirPath, "other/b.proto", "b")
+	// servers must allow filters for partial clone, which file:// URLs read from the config of the repository
+	testRunGit(t, srcDirPath, "config", "uploadpack.allowFilter", "true")
+	aBlobHash := testGitOutput(t, srcDirPath, "rev-parse", "HEAD:proto/a.proto")
+	bBlobHash := testGitOutput(t, srcDirPath, "rev-parse", "HEAD:other/b.proto")
+	gitURL := "file://" + filepath.ToSlash(filepath.Join(srcDirPath, ".git"))
+
+	dstDirPath := filepath.Join(tmpDirPath, "dst")
+	require.NoError(
+		t,
+		partialClone(
+			context.Background(),
+			gitBinaryPath,
+			os.Environ(),
+			gitURL,
+			plumbing.NewBranchReferenceName("master"),
+			1,
+			[]string{"proto"},
+			dstDirPath,
+		),
+	)
+	// the blob of the file outside of the sparse paths was never fetched
+	var missingHashes []string
+	var fetchedHashes []string
+	for _, line := range strings.Split(testGitOutput(t, dstDirPath, "rev-list", "--objects", "--all", "--missing=print"), "\n") {
+		if strings.HasPrefix(line, "?") {
+			missingHashes = append(missingHashes, strings.TrimPrefix(line, "?"))
+		} else {
+			fetchedHashes = append(fetchedHashes, strings.Fields(line)[0])
+		}
+	}
+	assert.Equal(t, []string{bBlobHash}, missingHashes)
+	assert.Contains(t, fetchedHashes, aBlobHash)
+	assert.NotContains(t, fetchedHashes, bBlobHash)
+
+	// Clone uses the partial clone for sparse paths
+	core, observedLogs := observer.New(zap.DebugLevel)
+	bucket := storagemem.NewBucket()
+	defer func() {
+		assert.NoError(t, bucket.Close())
+	}()
+	require.NoError(
+		t,
+		Clone(
+			context.Background(),
+			zap.New(core),
+			nil,
+			"",
+			gitURL,
+			storagegitplumbing.NewBranchRefName("master"),
+			0,
+			false,
+			[]string{"proto"},
+			"",
+			"",
+			"",
+			"",
+			"",
+			"",
+			bucket,
+		),
+	)
+	assert.Equal(t, 1, observedLogs.FilterMessage("git_partial_clone").Len())
+	data, err := storageutil.ReadPath(context.Background(), bucket, "proto/a.proto")
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(data))
+	_, err = bucket.Stat(context.Background(), "other/b.proto")
+	assert.True(t, storage.IsNotExist(err))
+
+	// pure go never uses the system git
+	core, observedLogs = observer.New(zap.DebugLevel)
+	pureGoBucket := storagemem.NewBucket()
+	defer func() {
+		assert.NoError(t, pureGoBucket.Close())
+	}()
+	require.NoError(
+		t,
+		Clone(
+			context.Background(),
+			zap.New(core),
+			func(key string) string {
+				if key == testPureGoEnvKey {
+					return "true"
+				}
+				return ""
+			},
+			"",
+			gitURL,
+			storagegitplumbing.NewBranchRefName("master"),
+			0,
+			false,
+			[]string{"proto"},
+			"",
+			"",
+			"",
+			"",
+			"",
+			testPureGoEnvKey,
+			pureGoBucket,
+		),
+	)
+	assert.Equal(t, 0, observedLogs.FilterMessage("git_partial_clone").Len())
+}
+
+func TestResolveSubmoduleURL(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		gitURL       string
+		submoduleURL string
+		expected     string
+	}{
+		{"https://github.com/foo/bar.git", "https://github.com/foo/baz.git", "https://github.com/foo/baz.git"},
+		{"https://github.com/foo/bar.git", "../baz.git", "https://github.com/foo/baz.git"},
+		{"https://github.com/foo/bar.git", "./baz.git", "https://github.com/foo/bar.git/baz.git"},
+		{"ssh://git@github.com/foo/bar.git", "../../baz/bat.git", "ssh://git@github.com/baz/bat.git"},
+		{"git@github.com:foo/bar.git", "../baz.git", "git@github.com:foo/baz.git"},
+		{"file:///tmp/foo/.git", "../bar", "file:///tmp/bar"},
+		{"/tmp/foo/.git", "../bar", filepath.FromSlash("/tmp/bar")},
+	} {
+		resolved, err := resolveSubmoduleURL(testCase.gitURL, testCase.submoduleURL)
+		require.NoError(t, err)
+		assert.Equal(t, testCase.expected, resolved, testCase.gitURL+" "+testCase.submoduleURL)
+	}
+}
+
+func TestIsCommitHash(t *testing.T) {
+	t.Parallel()
+	assert.True(t, storagegitplumbing.IsCommitHash(storagegitplumbing.NewRefName("0123456789abcdef0123456789abcdef01234567")))
+	assert.True(t, storagegitplumbing.IsCommitHash(storagegitplumbing.NewRefName("0123456789ABCDEF0123456789ABCDEF01234567")))
+	assert.False(t, storagegitplumbing.IsCommitHash(storagegitplumbing.NewRefName("0123456")))
+	assert.False(t, storagegitplumbing.IsCommitHash(storagegitplumbing.NewRefName("refs/heads/master")))
+	assert.False(t, storagegitplumbing.IsCommitHash(storagegitplumbing.NewBranchRefName("0123456789abcdef0123456789abcdef01234567")))
+	assert.False(t, storagegitplumbing.IsCommitHash(nil))
+}
+
+func testCloneError(t *testing.T, gitURL string, refName storagegitplumbing.RefName, depth uint32) {
+	testCloneErrorGetenv(t, nil, gitURL, refName, depth)
+}
+
+func testCloneErrorGetenv(t *testing.T, getenv func(string) string, gitURL string, refName storagegitplumbing.RefName, depth uint32) {
+	bucket := storagemem.NewBucket()
+	defer func() {
+		assert.NoError(t, bucket.Close())
+	}()
+	assert.Error(
+		t,
+		Clone(
+			context.Background(),
+			zap.NewNop(),
+			getenv,
+			"",
+			gitURL,
+			refName,
+			depth,
+			false,
+			nil,
+			"",
+			"",
+			"",
+			"",
+			"",
+			testPureGoEnvKey,
+			bucket,
+		),
+		refName.String(),
+	)
+}
+
+func testCloneSubmodules(
+	t *testing.T,
+	gitURL string,
+	refName storagegitplumbing.RefName,
+	recurseSubmodules bool,
+	sparsePaths []string,
+	expectedPathToContent map[string]string,
+) {
+	bucket := storagemem.NewBucket()
+	defer func() {
+		assert.NoError(t, bucket.Close())
+	}()
+	require.NoError(
+		t,
+		Clone(
+			context.Background(),
+			zap.NewNop(),
+			nil,
+			"",
+			gitURL,
+			refName,
+			0,
+			recurseSubmodules,
+			sparsePaths,
+			"",
+			"",
+			"",
+			"",
+			"",
+			"",
+			bucket,
+			storagepath.WithExt(".proto"),
+		),
+	)
+	pathToContent := make(map[string]string)
+	require.NoError(
+		t,
+		bucket.Walk(
+			context.Background(),
+			"",
+			func(path string) error {
+				data, err := storageutil.ReadPath(context.Background(), bucket, path)
+				if err != nil {
+					return err
+				}
+				pathToContent[path] = string(data)
+				return nil
+			},
+		),
+	)
+	assert.Equal(t, expectedPathToContent, pathToContent, gitURL+" "+refName.String())
+}
+
+func testRunGit(t *testing.T, dirPath string, args ...string) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dirPath
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+}
+
+func testGitOutput(t *testing.T, dirPath string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dirPath
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	return strings.TrimSpace(string(output))
+}
+
+func testCommitFile(t *testing.T, repository *git.Repository, dirPath string, path string, content string) plumbing.Hash {
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, path), []byte(content), 0600))
+	worktree, err := repository.Worktree()
+	require.NoError(t, err)
+	_, err = worktree.Add(path)
+	require.NoError(t, err)
+	hash, err := worktree.Commit(
+		content,
+		&git.CommitOptions{
+			Author: &object.Signature{
+				Name:  "test",
+				Email: "test@example.com",
+				When:  time.Now(),
+			},
+		},
+	)
+	require.NoError(t, err)
+	return hash
+}
+
+func testCloneLocal(t *testing.T, gitPath string, refName storagegitplumbing.RefName, expectedContent string) {
+	testCloneRemote(t, gitPath, refName, 0, expectedContent)
+}
+
+func testCloneRemote(t *testing.T, gitURL string, refName storagegitplumbing.RefName, depth uint32, expectedContent string) {
+	testCloneRemoteGetenv(t, nil, gitURL, refName, depth, expectedContent)
+}
+
+func testCloneRemoteGetenv(
+	t *testing.T,
+	getenv func(string) string,
+	gitURL string,
+	refName storagegitplumbing.RefName,
+	depth uint32,
+	expectedContent string,
+) {
+	bucket := storagemem.NewBucket()
+	defer func() {
+		assert.NoError(t, bucket.Close())
+	}()
+	require.NoError(
+		t,
+		Clone(
+			context.Background(),
+			zap.NewNop(),
+			getenv,
+			"",
+			gitURL,
+			refName,
+			depth,
+			false,
+			nil,
+			"",
+			"",
+			"",
+			"",
+			"",
+			testPureGoEnvKey,
+			bucket,
+			storagepath.WithExt(".proto"),
+		),
+	)
+	readObject, err := bucket.Get(context.Background(), "a.proto")
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(readObject)
+	require.NoError(t, err)
+	assert.NoError(t, readObject.Close())
+	assert.Equal(t, expectedContent, string(data), refName.String())
+}
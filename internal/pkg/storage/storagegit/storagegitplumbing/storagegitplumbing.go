@@ -1,6 +1,12 @@
 package storagegitplumbing
 
-import "gopkg.in/src-d/go-git.v4/plumbing"
+import (
+	"regexp"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+var commitHashRegex = regexp.MustCompile("^[0-9a-fA-F]{40}$")
 
 // RefName is a git reference name.
 type RefName interface {
@@ -19,6 +25,24 @@ func NewTagRefName(tag string) RefName {
 	return newRefName(plumbing.NewTagReferenceName(tag))
 }
 
+// NewRefName returns a new RefName for an arbitrary git revision.
+//
+// This can be a full reference name such as refs/heads/master, a short
+// reference name such as master, or a commit hash.
+func NewRefName(ref string) RefName {
+	return newRefName(plumbing.ReferenceName(ref))
+}
+
+// IsCommitHash returns true if the RefName is a full commit hash.
+//
+// A RefName that is a full commit hash always refers to the same commit.
+func IsCommitHash(refName RefName) bool {
+	if refName == nil {
+		return false
+	}
+	return commitHashRegex.MatchString(refName.String())
+}
+
 type refName struct {
 	referenceName plumbing.ReferenceName
 }
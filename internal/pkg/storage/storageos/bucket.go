@@ -14,11 +14,12 @@ import (
 )
 
 type bucket struct {
-	rootPath string
-	closed   bool
+	rootPath       string
+	followSymlinks bool
+	closed         bool
 }
 
-func newBucket(rootPath string) (*bucket, error) {
+func newBucket(rootPath string, options ...BucketOption) (*bucket, error) {
 	rootPath = storagepath.Unnormalize(rootPath)
 	fileInfo, err := os.Stat(rootPath)
 	if err != nil {
@@ -33,9 +34,13 @@ func newBucket(rootPath string) (*bucket, error) {
 	// allow anything with OS buckets including absolute paths
 	// and jumping context
 	rootPath = storagepath.Normalize(rootPath)
-	return &bucket{
+	bucket := &bucket{
 		rootPath: rootPath,
-	}, nil
+	}
+	for _, option := range options {
+		option(bucket)
+	}
+	return bucket, nil
 }
 
 func (b *bucket) Type() string {
@@ -122,7 +127,14 @@ func (b *bucket) Walk(ctx context.Context, prefix string, f func(string) error)
 	if b.closed {
 		return storage.ErrClosed
 	}
-	fileCount := 0
+	walker := &walker{
+		ctx:      ctx,
+		rootPath: b.rootPath,
+		f:        f,
+	}
+	if b.followSymlinks {
+		return walker.walkFollowSymlinks(prefix)
+	}
 	// Walk does not follow symlinks
 	return filepath.Walk(
 		prefix,
@@ -130,31 +142,7 @@ func (b *bucket) Walk(ctx context.Context, prefix string, f func(string) error)
 			if err != nil {
 				return err
 			}
-			fileCount++
-			select {
-			case <-ctx.Done():
-				err := ctx.Err()
-				if err == context.DeadlineExceeded {
-					return fmt.Errorf("timed out after walking %d files: %v", fileCount, err)
-				}
-				return err
-			default:
-			}
-			if fileInfo.Mode().IsRegular() {
-				rel, err := storagepath.Rel(b.rootPath, storagepath.Normalize(path))
-				if err != nil {
-					return err
-				}
-				// just in case
-				rel, err = storagepath.NormalizeAndValidate(rel)
-				if err != nil {
-					return err
-				}
-				if err := f(rel); err != nil {
-					return err
-				}
-			}
-			return nil
+			return walker.visit(path, fileInfo)
 		},
 	)
 }
@@ -29,8 +29,8 @@ func IsNotDir(err error) bool {
 // automatically calls Mkdir, and Walk only calls f on regular files.
 //
 // Not thread-safe.
-func NewBucket(rootPath string) (storage.Bucket, error) {
-	return newBucket(rootPath)
+func NewBucket(rootPath string, options ...BucketOption) (storage.Bucket, error) {
+	return newBucket(rootPath, options...)
 }
 
 // NewReadBucket returns a new read-only OS bucket.
@@ -43,6 +43,26 @@ func NewBucket(rootPath string) (storage.Bucket, error) {
 // automatically calls Mkdir, and Walk only calls f on regular files.
 //
 // Not thread-safe.
-func NewReadBucket(rootPath string) (storage.ReadBucket, error) {
-	return newBucket(rootPath)
+func NewReadBucket(rootPath string, options ...BucketOption) (storage.ReadBucket, error) {
+	return newBucket(rootPath, options...)
+}
+
+// BucketOption is an option for a new Bucket.
+type BucketOption func(*bucket)
+
+// BucketWithFollowSymlinks returns a new BucketOption that makes Walk follow
+// symlinks to regular files and directories.
+//
+// The paths of files within symlinked directories are the paths through the
+// symlinks. Symlinks to a directory that contains the symlink are skipped to
+// prevent cycles, as are symlinks that do not point to an existing file.
+// The same directory may still be walked more than once if it is the target
+// of multiple symlinks, as is common for symlink forests created by build
+// systems such as Bazel and Nix.
+//
+// The default is to skip all symlinks in Walk. Get and Stat always follow symlinks.
+func BucketWithFollowSymlinks() BucketOption {
+	return func(bucket *bucket) {
+		bucket.followSymlinks = true
+	}
 }
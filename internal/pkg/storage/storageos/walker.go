@@ -0,0 +1,150 @@
+package storageos
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/bufbuild/buf/internal/pkg/storage/storagepath"
+)
+
+type walker struct {
+	ctx       context.Context
+	rootPath  string
+	f         func(string) error
+	fileCount int
+}
+
+// visit checks the context, and calls f with the root-relative path if the file is a
+// regular file.
+//
+// The path is the unnormalized path of the file.
+func (w *walker) visit(path string, fileInfo os.FileInfo) error {
+	w.fileCount++
+	select {
+	case <-w.ctx.Done():
+		err := w.ctx.Err()
+		if err == context.DeadlineExceeded {
+			return fmt.Errorf("timed out after walking %d files: %v", w.fileCount, err)
+		}
+		return err
+	default:
+	}
+	if !fileInfo.Mode().IsRegular() {
+		return nil
+	}
+	rel, err := storagepath.Rel(w.rootPath, storagepath.Normalize(path))
+	if err != nil {
+		return err
+	}
+	// just in case
+	rel, err = storagepath.NormalizeAndValidate(rel)
+	if err != nil {
+		return err
+	}
+	return w.f(rel)
+}
+
+// walkFollowSymlinks walks the path in lexical order the same as filepath.Walk,
+// but follows symlinks.
+func (w *walker) walkFollowSymlinks(path string) error {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !fileInfo.IsDir() {
+		return w.visit(path, fileInfo)
+	}
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	return w.walkDir(path, realPath, map[string]struct{}{realPath: {}})
+}
+
+// walkDir walks the directory at dirPath, whose real path with all symlinks
+// resolved is realDirPath.
+//
+// The ancestors are the real paths of the directories that are being walked,
+// including the directory, and are used to detect cycles.
+func (w *walker) walkDir(dirPath string, realDirPath string, ancestors map[string]struct{}) error {
+	if err := w.visitDir(dirPath); err != nil {
+		return err
+	}
+	names, err := readDirNames(dirPath)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		path := filepath.Join(dirPath, name)
+		realPath := filepath.Join(realDirPath, name)
+		fileInfo, err := os.Lstat(path)
+		if err != nil {
+			// the file may have been removed since the directory was read
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		if fileInfo.Mode()&os.ModeSymlink != 0 {
+			fileInfo, err = os.Stat(path)
+			if err != nil {
+				// skip symlinks that do not point to an existing file
+				if os.IsNotExist(err) {
+					continue
+				}
+				return err
+			}
+			if fileInfo.IsDir() {
+				realPath, err = filepath.EvalSymlinks(path)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if !fileInfo.IsDir() {
+			if err := w.visit(path, fileInfo); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, ok := ancestors[realPath]; ok {
+			// a symlink to a directory being walked, following it would never end
+			continue
+		}
+		ancestors[realPath] = struct{}{}
+		err = w.walkDir(path, realPath, ancestors)
+		delete(ancestors, realPath)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// visitDir checks the context for a directory.
+func (w *walker) visitDir(dirPath string) error {
+	fileInfo, err := os.Stat(dirPath)
+	if err != nil {
+		return err
+	}
+	return w.visit(dirPath, fileInfo)
+}
+
+func readDirNames(dirPath string) ([]string, error) {
+	file, err := os.Open(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	names, err := file.Readdirnames(-1)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,220 @@
+package storages3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/bufbuild/buf/internal/pkg/storage"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagepath"
+	"go.uber.org/multierr"
+)
+
+type bucket struct {
+	client     s3iface.S3API
+	bucketName string
+	prefix     string
+	closed     bool
+}
+
+func newBucket(client s3iface.S3API, bucketName string, prefix string) *bucket {
+	return &bucket{
+		client:     client,
+		bucketName: bucketName,
+		prefix:     strings.Trim(prefix, "/"),
+	}
+}
+
+func (b *bucket) Type() string {
+	return BucketType
+}
+
+func (b *bucket) Get(ctx context.Context, path string) (storage.ReadObject, error) {
+	path, err := storagepath.NormalizeAndValidate(path)
+	if err != nil {
+		return nil, err
+	}
+	if path == "." {
+		return nil, errors.New("cannot get root")
+	}
+	if b.closed {
+		return nil, storage.ErrClosed
+	}
+	output, err := b.client.GetObjectWithContext(
+		ctx,
+		&s3.GetObjectInput{
+			Bucket: aws.String(b.bucketName),
+			Key:    aws.String(b.key(path)),
+		},
+	)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, storage.NewErrNotExist(path)
+		}
+		return nil, err
+	}
+	size := aws.Int64Value(output.ContentLength)
+	if size > int64(math.MaxUint32) {
+		return nil, multierr.Append(fmt.Errorf("file too large: %d", size), output.Body.Close())
+	}
+	return newReadObject(output.Body, uint32(size)), nil
+}
+
+func (b *bucket) Stat(ctx context.Context, path string) (storage.ObjectInfo, error) {
+	path, err := storagepath.NormalizeAndValidate(path)
+	if err != nil {
+		return storage.ObjectInfo{}, err
+	}
+	if path == "." {
+		return storage.ObjectInfo{}, errors.New("cannot check root")
+	}
+	if b.closed {
+		return storage.ObjectInfo{}, storage.ErrClosed
+	}
+	output, err := b.client.HeadObjectWithContext(
+		ctx,
+		&s3.HeadObjectInput{
+			Bucket: aws.String(b.bucketName),
+			Key:    aws.String(b.key(path)),
+		},
+	)
+	if err != nil {
+		if isNotFound(err) {
+			return storage.ObjectInfo{}, storage.NewErrNotExist(path)
+		}
+		return storage.ObjectInfo{}, err
+	}
+	return storage.ObjectInfo{
+		Size: uint32(aws.Int64Value(output.ContentLength)),
+	}, nil
+}
+
+func (b *bucket) Walk(ctx context.Context, prefix string, f func(string) error) error {
+	prefix, err := storagepath.NormalizeAndValidate(prefix)
+	if err != nil {
+		return err
+	}
+	if b.closed {
+		return storage.ErrClosed
+	}
+	// without the trailing slash, "internal/buf/proto" would call f for "internal/buf/protocompile"
+	keyPrefix := b.key(prefix)
+	if keyPrefix != "" {
+		keyPrefix = keyPrefix + "/"
+	}
+	fileCount := 0
+	var walkErr error
+	if err := b.client.ListObjectsV2PagesWithContext(
+		ctx,
+		&s3.ListObjectsV2Input{
+			Bucket: aws.String(b.bucketName),
+			Prefix: aws.String(keyPrefix),
+		},
+		func(output *s3.ListObjectsV2Output, lastPage bool) bool {
+			for _, object := range output.Contents {
+				fileCount++
+				select {
+				case <-ctx.Done():
+					walkErr = ctx.Err()
+					if walkErr == context.DeadlineExceeded {
+						walkErr = fmt.Errorf("timed out after walking %d files: %v", fileCount, walkErr)
+					}
+					return false
+				default:
+				}
+				key := aws.StringValue(object.Key)
+				if strings.HasSuffix(key, "/") {
+					// directory marker
+					continue
+				}
+				path, err := b.rel(key)
+				if err != nil {
+					walkErr = err
+					return false
+				}
+				if err := f(path); err != nil {
+					walkErr = err
+					return false
+				}
+			}
+			return true
+		},
+	); err != nil {
+		return err
+	}
+	return walkErr
+}
+
+func (b *bucket) Close() error {
+	if b.closed {
+		return storage.ErrClosed
+	}
+	b.closed = true
+	return nil
+}
+
+// key returns the S3 key for the normalized and validated path.
+func (b *bucket) key(path string) string {
+	if path == "." {
+		return b.prefix
+	}
+	if b.prefix == "" {
+		return path
+	}
+	return b.prefix + "/" + path
+}
+
+// rel returns the normalized and validated path for the S3 key.
+func (b *bucket) rel(key string) (string, error) {
+	if b.prefix != "" {
+		if !strings.HasPrefix(key, b.prefix+"/") {
+			return "", fmt.Errorf("key %q is not within prefix %q", key, b.prefix)
+		}
+		key = strings.TrimPrefix(key, b.prefix+"/")
+	}
+	return storagepath.NormalizeAndValidate(key)
+}
+
+type readObject struct {
+	readCloser io.ReadCloser
+	size       uint32
+}
+
+func newReadObject(readCloser io.ReadCloser, size uint32) *readObject {
+	return &readObject{
+		readCloser: readCloser,
+		size:       size,
+	}
+}
+
+func (r *readObject) Read(p []byte) (int, error) {
+	return r.readCloser.Read(p)
+}
+
+func (r *readObject) Close() error {
+	return r.readCloser.Close()
+}
+
+func (r *readObject) Size() uint32 {
+	return r.size
+}
+
+func isNotFound(err error) bool {
+	awsErr, ok := err.(awserr.Error)
+	if !ok {
+		return false
+	}
+	switch awsErr.Code() {
+	case s3.ErrCodeNoSuchKey, "NotFound":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,52 @@
+// Package storages3 implements an S3-backed storage ReadBucket.
+package storages3
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/bufbuild/buf/internal/pkg/storage"
+)
+
+// BucketType is the bucket type.
+const BucketType = "s3"
+
+// URLPrefix is the prefix for S3 URLs.
+const URLPrefix = "s3://"
+
+// IsURL returns true if the path is an S3 URL.
+func IsURL(path string) bool {
+	return strings.HasPrefix(path, URLPrefix)
+}
+
+// ParseURL parses an S3 URL of the form s3://bucket/prefix into the
+// bucket name and the key prefix.
+//
+// The prefix is normalized and will be empty if the URL only
+// contains a bucket name.
+func ParseURL(url string) (string, string, error) {
+	if !IsURL(url) {
+		return "", "", fmt.Errorf("%q is not an S3 URL", url)
+	}
+	split := strings.SplitN(strings.TrimPrefix(url, URLPrefix), "/", 2)
+	bucketName := split[0]
+	if bucketName == "" {
+		return "", "", fmt.Errorf("%q does not have a bucket name", url)
+	}
+	var prefix string
+	if len(split) == 2 {
+		prefix = strings.Trim(split[1], "/")
+	}
+	return bucketName, prefix, nil
+}
+
+// NewReadBucket returns a new read-only S3 bucket.
+//
+// All paths are relative to the given key prefix within the S3 bucket.
+// Keys that end in "/" are treated as directory markers and are skipped.
+//
+// Not thread-safe.
+func NewReadBucket(client s3iface.S3API, bucketName string, prefix string) storage.ReadBucket {
+	return newBucket(client, bucketName, prefix)
+}
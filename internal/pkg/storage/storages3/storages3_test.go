@@ -0,0 +1,52 @@
+package storages3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseURL(t *testing.T) {
+	testParseURL(t, "s3://bucket", "bucket", "")
+	testParseURL(t, "s3://bucket/", "bucket", "")
+	testParseURL(t, "s3://bucket/foo", "bucket", "foo")
+	testParseURL(t, "s3://bucket/foo/bar/", "bucket", "foo/bar")
+	testParseURLError(t, "s3://")
+	testParseURLError(t, "s3:///foo")
+	testParseURLError(t, "https://bucket/foo")
+}
+
+func TestKeyAndRel(t *testing.T) {
+	b := newBucket(nil, "bucket", "/foo/bar/")
+	assert.Equal(t, "foo/bar", b.key("."))
+	assert.Equal(t, "foo/bar/baz.proto", b.key("baz.proto"))
+	rel, err := b.rel("foo/bar/baz/bat.proto")
+	require.NoError(t, err)
+	assert.Equal(t, "baz/bat.proto", rel)
+	_, err = b.rel("foo/barbaz/bat.proto")
+	assert.Error(t, err)
+
+	b = newBucket(nil, "bucket", "")
+	assert.Equal(t, "", b.key("."))
+	assert.Equal(t, "baz.proto", b.key("baz.proto"))
+	rel, err = b.rel("baz/bat.proto")
+	require.NoError(t, err)
+	assert.Equal(t, "baz/bat.proto", rel)
+}
+
+func testParseURL(t *testing.T, url string, expectedBucketName string, expectedPrefix string) {
+	t.Run(url, func(t *testing.T) {
+		bucketName, prefix, err := ParseURL(url)
+		require.NoError(t, err)
+		assert.Equal(t, expectedBucketName, bucketName)
+		assert.Equal(t, expectedPrefix, prefix)
+	})
+}
+
+func testParseURLError(t *testing.T, url string) {
+	t.Run(url, func(t *testing.T) {
+		_, _, err := ParseURL(url)
+		assert.Error(t, err)
+	})
+}
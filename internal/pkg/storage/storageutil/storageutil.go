@@ -3,18 +3,61 @@ package storageutil
 
 import (
 	"archive/tar"
+	"archive/zip"
 	"compress/gzip"
 	"context"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"sync"
 
 	"github.com/bufbuild/buf/internal/pkg/storage"
 	"github.com/bufbuild/buf/internal/pkg/storage/storagepath"
+	"github.com/bufbuild/buf/internal/pkg/util/utilio"
+	"github.com/klauspost/compress/zstd"
 	"go.uber.org/multierr"
 )
 
+// maxZipSymlinkTargetSize is the maximum size of the target of a symlink within a zip archive.
+const maxZipSymlinkTargetSize = 4096
+
+// PolicyError is the error returned if an archive entry violates the extraction policy.
+//
+// Entries must not have absolute paths or contain ".." components, and symlinks and
+// hard links must not point outside of the extraction root unless allowed.
+type PolicyError struct {
+	// EntryName is the name of the entry as it appears in the archive.
+	EntryName string
+	// Reason is the reason the entry violated the policy.
+	Reason string
+	// Link is true if the entry is a symlink or hard link that points outside of
+	// the extraction root, which is allowed with allowSymlinks.
+	Link bool
+}
+
+// Error implements error.
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("policy error: archive entry %q %s", e.EntryName, e.Reason)
+}
+
+// IsPolicyError returns true if err is a *PolicyError.
+func IsPolicyError(err error) bool {
+	_, ok := err.(*PolicyError)
+	return ok
+}
+
+// IsLinkPolicyError returns true if err is a *PolicyError for a symlink or hard
+// link that points outside of the extraction root.
+func IsLinkPolicyError(err error) bool {
+	policyError, ok := err.(*PolicyError)
+	return ok && policyError.Link
+}
+
 // Copy copies the bucket at from to the bucket at to for the given prefix.
 //
 // Copies done concurrently.
@@ -121,36 +164,64 @@ func copyPath(
 	if err != nil {
 		return multierr.Append(err, readObject.Close())
 	}
-	_, err = io.Copy(writeObject, readObject)
+	_, err = utilio.Copy(writeObject, readObject)
 	return multierr.Append(err, multierr.Append(writeObject.Close(), readObject.Close()))
 }
 
 // Untar untars the given tar archive from the reader into the bucket.
 //
-// Only regular files are added to the bucket.
+// Only regular files are added to the bucket. Entries that violate the extraction
+// policy return a *PolicyError. If allowSymlinks is true, symlinks and hard links
+// that point outside of the extraction root are skipped instead.
 //
 // Paths from the tar archive will be transformed before adding to the bucket.
 func Untar(
 	ctx context.Context,
 	reader io.Reader,
 	bucket storage.Bucket,
+	allowSymlinks bool,
 	options ...storagepath.TransformerOption,
 ) error {
-	return untar(ctx, reader, bucket, false, options...)
+	return untar(ctx, reader, bucket, false, allowSymlinks, options...)
 }
 
 // Untargz untars the given targz archive from the reader into the bucket.
 //
-// Only regular files are added to the bucket.
+// Only regular files are added to the bucket. Entries that violate the extraction
+// policy return a *PolicyError. If allowSymlinks is true, symlinks and hard links
+// that point outside of the extraction root are skipped instead.
 //
 // Paths from the targz archive will be transformed before adding to the bucket.
 func Untargz(
 	ctx context.Context,
 	reader io.Reader,
 	bucket storage.Bucket,
+	allowSymlinks bool,
 	options ...storagepath.TransformerOption,
 ) error {
-	return untar(ctx, reader, bucket, true, options...)
+	return untar(ctx, reader, bucket, true, allowSymlinks, options...)
+}
+
+// Untarzst untars the given zstd-compressed tar archive from the reader into the bucket.
+//
+// Only regular files are added to the bucket. Entries that violate the extraction
+// policy return a *PolicyError. If allowSymlinks is true, symlinks and hard links
+// that point outside of the extraction root are skipped instead.
+//
+// Paths from the tar archive will be transformed before adding to the bucket.
+func Untarzst(
+	ctx context.Context,
+	reader io.Reader,
+	bucket storage.Bucket,
+	allowSymlinks bool,
+	options ...storagepath.TransformerOption,
+) error {
+	zstdDecoder, err := zstd.NewReader(reader)
+	if err != nil {
+		return err
+	}
+	defer zstdDecoder.Close()
+	return untar(ctx, zstdDecoder, bucket, false, allowSymlinks, options...)
 }
 
 func untar(
@@ -158,6 +229,7 @@ func untar(
 	reader io.Reader,
 	bucket storage.Bucket,
 	gzipped bool,
+	allowSymlinks bool,
 	options ...storagepath.TransformerOption,
 ) error {
 	transformer := storagepath.NewTransformer(options...)
@@ -180,6 +252,19 @@ func untar(
 			return ctx.Err()
 		default:
 		}
+		if err := checkEntryName(tarHeader.Name); err != nil {
+			return err
+		}
+		switch tarHeader.Typeflag {
+		case tar.TypeSymlink:
+			if err := checkSymlink(tarHeader.Name, tarHeader.Linkname, allowSymlinks); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			if err := checkHardLink(tarHeader.Name, tarHeader.Linkname, allowSymlinks); err != nil {
+				return err
+			}
+		}
 		path, err := storagepath.NormalizeAndValidate(tarHeader.Name)
 		if err != nil {
 			return err
@@ -196,7 +281,7 @@ func untar(
 			if err != nil {
 				return err
 			}
-			_, writeErr := io.Copy(writeObject, tarReader)
+			_, writeErr := utilio.Copy(writeObject, tarReader)
 			if err := writeObject.Close(); err != nil {
 				return err
 			}
@@ -283,7 +368,224 @@ func doTar(
 			); err != nil {
 				return multierr.Append(err, readObject.Close())
 			}
-			_, err = io.Copy(tarWriter, readObject)
+			_, err = utilio.Copy(tarWriter, readObject)
+			return multierr.Append(err, readObject.Close())
+		},
+	)
+}
+
+// Unzip unzips the given zip archive from the reader into the bucket.
+//
+// Only regular files are added to the bucket. Entries that violate the extraction
+// policy return a *PolicyError. If allowSymlinks is true, symlinks that point
+// outside of the extraction root are skipped instead.
+//
+// Paths from the zip archive will be transformed before adding to the bucket.
+func Unzip(
+	ctx context.Context,
+	readerAt io.ReaderAt,
+	size int64,
+	bucket storage.Bucket,
+	allowSymlinks bool,
+	options ...storagepath.TransformerOption,
+) error {
+	transformer := storagepath.NewTransformer(options...)
+	zipReader, err := zip.NewReader(readerAt, size)
+	if err != nil {
+		return err
+	}
+	for _, zipFile := range zipReader.File {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		if err := checkEntryName(zipFile.Name); err != nil {
+			return err
+		}
+		if zipFile.Mode()&os.ModeSymlink != 0 {
+			linkname, err := readZipSymlinkTarget(zipFile)
+			if err != nil {
+				return err
+			}
+			if err := checkSymlink(zipFile.Name, linkname, allowSymlinks); err != nil {
+				return err
+			}
+		}
+		path, err := storagepath.NormalizeAndValidate(zipFile.Name)
+		if err != nil {
+			return err
+		}
+		if path == "." {
+			continue
+		}
+		path, ok := transformer.Transform(path)
+		if !ok {
+			continue
+		}
+		if zipFile.Mode().IsRegular() {
+			if err := unzipFile(ctx, zipFile, bucket, path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func unzipFile(
+	ctx context.Context,
+	zipFile *zip.File,
+	bucket storage.Bucket,
+	path string,
+) (retErr error) {
+	readCloser, err := zipFile.Open()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, readCloser.Close())
+	}()
+	writeObject, err := bucket.Put(ctx, path, uint32(zipFile.UncompressedSize64))
+	if err != nil {
+		return err
+	}
+	_, err = utilio.Copy(writeObject, readCloser)
+	return multierr.Append(err, writeObject.Close())
+}
+
+func readZipSymlinkTarget(zipFile *zip.File) (_ string, retErr error) {
+	if zipFile.UncompressedSize64 > maxZipSymlinkTargetSize {
+		return "", &PolicyError{
+			EntryName: zipFile.Name,
+			Reason:    fmt.Sprintf("is a symlink with a target larger than %d bytes", maxZipSymlinkTargetSize),
+		}
+	}
+	readCloser, err := zipFile.Open()
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, readCloser.Close())
+	}()
+	data, err := ioutil.ReadAll(io.LimitReader(readCloser, maxZipSymlinkTargetSize))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// checkEntryName returns a *PolicyError if the archive entry name is absolute
+// or contains a ".." component.
+func checkEntryName(entryName string) error {
+	if isAbs(entryName) {
+		return &PolicyError{
+			EntryName: entryName,
+			Reason:    "has an absolute path",
+		}
+	}
+	for _, component := range strings.Split(filepath.ToSlash(entryName), "/") {
+		if component == ".." {
+			return &PolicyError{
+				EntryName: entryName,
+				Reason:    `contains a ".." path component`,
+			}
+		}
+	}
+	return nil
+}
+
+// checkSymlink returns a *PolicyError if the symlink target is outside of the
+// extraction root, unless allowSymlinks is true.
+//
+// Symlink targets are relative to the directory of the entry.
+func checkSymlink(entryName string, linkname string, allowSymlinks bool) error {
+	if allowSymlinks {
+		return nil
+	}
+	if isAbs(linkname) || escapesRoot(path.Join(path.Dir(filepath.ToSlash(entryName)), filepath.ToSlash(linkname))) {
+		return &PolicyError{
+			EntryName: entryName,
+			Reason:    fmt.Sprintf("is a symlink to %q outside of the extraction root", linkname),
+			Link:      true,
+		}
+	}
+	return nil
+}
+
+// checkHardLink returns a *PolicyError if the hard link target is outside of the
+// extraction root, unless allowSymlinks is true.
+//
+// Hard link targets are relative to the root of the archive.
+func checkHardLink(entryName string, linkname string, allowSymlinks bool) error {
+	if allowSymlinks {
+		return nil
+	}
+	if isAbs(linkname) || escapesRoot(filepath.ToSlash(linkname)) {
+		return &PolicyError{
+			EntryName: entryName,
+			Reason:    fmt.Sprintf("is a hard link to %q outside of the extraction root", linkname),
+			Link:      true,
+		}
+	}
+	return nil
+}
+
+// isAbs returns true if the path is absolute on any platform.
+func isAbs(value string) bool {
+	slashValue := filepath.ToSlash(value)
+	if strings.HasPrefix(slashValue, "/") || filepath.IsAbs(value) {
+		return true
+	}
+	// windows volume names such as C:/ or C:\
+	return len(slashValue) >= 2 && slashValue[1] == ':'
+}
+
+// escapesRoot returns true if the slash-separated relative path jumps context after clean.
+func escapesRoot(slashPath string) bool {
+	slashPath = path.Clean(slashPath)
+	return slashPath == ".." || strings.HasPrefix(slashPath, "../")
+}
+
+// Zip zips the given bucket to the writer.
+//
+// Only regular files are added to the writer.
+// All files are written as 0644.
+//
+// Paths from the bucket will be transformed before adding to the writer.
+func Zip(
+	ctx context.Context,
+	writer io.Writer,
+	bucket storage.Bucket,
+	prefix string,
+	options ...storagepath.TransformerOption,
+) (retErr error) {
+	transformer := storagepath.NewTransformer(options...)
+	zipWriter := zip.NewWriter(writer)
+	defer func() {
+		retErr = multierr.Append(retErr, zipWriter.Close())
+	}()
+	return bucket.Walk(
+		ctx,
+		prefix,
+		func(path string) error {
+			newPath, ok := transformer.Transform(path)
+			if !ok {
+				return nil
+			}
+			readObject, err := bucket.Get(ctx, path)
+			if err != nil {
+				return err
+			}
+			fileHeader := &zip.FileHeader{
+				Name:   newPath,
+				Method: zip.Deflate,
+			}
+			fileHeader.SetMode(0644)
+			fileWriter, err := zipWriter.CreateHeader(fileHeader)
+			if err != nil {
+				return multierr.Append(err, readObject.Close())
+			}
+			_, err = utilio.Copy(fileWriter, readObject)
 			return multierr.Append(err, readObject.Close())
 		},
 	)
@@ -304,3 +606,16 @@ func ReadPath(ctx context.Context, bucket storage.ReadBucket, path string) (_ []
 	}()
 	return ioutil.ReadAll(readObject)
 }
+
+// WritePath is analogous to ioutil.WriteFile.
+func WritePath(ctx context.Context, bucket storage.Bucket, path string, data []byte) (retErr error) {
+	writeObject, err := bucket.Put(ctx, path, uint32(len(data)))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, writeObject.Close())
+	}()
+	_, err = writeObject.Write(data)
+	return err
+}
@@ -1,6 +1,6 @@
 // Package utildiff implements diffing.
 //
-// Should primarily be used for testing.
+// Requires the diff command.
 //
 // Copied from https://github.com/golang/go/blob/master/src/cmd/gofmt/gofmt.go
 //
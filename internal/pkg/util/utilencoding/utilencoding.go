@@ -56,6 +56,46 @@ func UnmarshalJSONOrYAMLStrict(data []byte, v interface{}) error {
 	return nil
 }
 
+// JSONToYAML converts the JSON data to YAML.
+//
+// The YAML is in block style, and the order of keys is preserved.
+func JSONToYAML(data []byte) ([]byte, error) {
+	// JSON is valid YAML, so this only changes the style
+	node := &yaml.Node{}
+	if err := yaml.Unmarshal(data, node); err != nil {
+		return nil, fmt.Errorf("could not unmarshal as JSON: %v", err)
+	}
+	clearYAMLNodeStyle(node)
+	buffer := bytes.NewBuffer(nil)
+	yamlEncoder := yaml.NewEncoder(buffer)
+	yamlEncoder.SetIndent(2)
+	if err := yamlEncoder.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := yamlEncoder.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// YAMLToJSON converts the YAML data to JSON.
+func YAMLToJSON(data []byte) ([]byte, error) {
+	var value interface{}
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("could not unmarshal as YAML: %v", err)
+	}
+	return json.Marshal(value)
+}
+
+// clearYAMLNodeStyle clears the style of the node and its children, so that the
+// node is encoded in block style with scalars only quoted if necessary.
+func clearYAMLNodeStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearYAMLNodeStyle(child)
+	}
+}
+
 // GetJSONStringOrStringValue returns the JSON string for the RawMessage if the
 // RawMessage is a string, and the raw value as a string otherwise.
 //
@@ -80,6 +80,7 @@ func GetGithubArchive(
 		ctx,
 		response.Body,
 		bucket,
+		false,
 		storagepath.WithStripComponents(1),
 	)
 }
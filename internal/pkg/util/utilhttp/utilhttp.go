@@ -0,0 +1,195 @@
+// Package utilhttp implements HTTP utilities.
+package utilhttp
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/http/httpproxy"
+)
+
+const maxBackoff = 30 * time.Second
+
+// NewClient returns a new HTTP client.
+//
+// The client does not have an overall timeout, use ClientWithTimeout to set a
+// timeout for each attempt of a request, or use the context of the request.
+func NewClient(options ...ClientOption) *http.Client {
+	transport := &transport{
+		logger: zap.NewNop(),
+		base:   http.DefaultTransport.(*http.Transport).Clone(),
+	}
+	for _, option := range options {
+		option(transport)
+	}
+	return &http.Client{
+		Transport: transport,
+	}
+}
+
+// ClientOption is an option for a new HTTP client.
+type ClientOption func(*transport)
+
+// ClientWithLogger returns a new ClientOption that logs retries to the logger.
+//
+// The default is to not log.
+func ClientWithLogger(logger *zap.Logger) ClientOption {
+	return func(transport *transport) {
+		transport.logger = logger
+	}
+}
+
+// ClientWithTimeout returns a new ClientOption that limits each attempt of a
+// request to the timeout, including reading the response body.
+//
+// The default is no timeout. If timeout is 0, there is no timeout.
+func ClientWithTimeout(timeout time.Duration) ClientOption {
+	return func(transport *transport) {
+		transport.timeout = timeout
+	}
+}
+
+// ClientWithRetries returns a new ClientOption that retries requests that fail
+// with a network error, a timeout, or a 429, 500, 502, 503, or 504 status code,
+// up to the given number of times.
+//
+// The wait before each retry starts at initialBackoff and doubles after each
+// retry, up to 30 seconds. Requests with a body are only retried if the body can
+// be recreated, which is the case for bodies set with http.NewRequest from a
+// bytes.Buffer, bytes.Reader, or strings.Reader.
+//
+// The default is to not retry.
+func ClientWithRetries(retries int, initialBackoff time.Duration) ClientOption {
+	return func(transport *transport) {
+		transport.retries = retries
+		transport.initialBackoff = initialBackoff
+	}
+}
+
+// ClientWithProxyFromEnv returns a new ClientOption that uses the proxies
+// specified by the HTTP_PROXY, HTTPS_PROXY, and NO_PROXY environment variables,
+// or their lowercase versions, read with getenv.
+//
+// The default is to use the proxies specified by the environment of the process,
+// as with http.ProxyFromEnvironment.
+func ClientWithProxyFromEnv(getenv func(string) string) ClientOption {
+	return func(transport *transport) {
+		proxyFunc := (&httpproxy.Config{
+			HTTPProxy:  getenvAny(getenv, "HTTP_PROXY", "http_proxy"),
+			HTTPSProxy: getenvAny(getenv, "HTTPS_PROXY", "https_proxy"),
+			NoProxy:    getenvAny(getenv, "NO_PROXY", "no_proxy"),
+		}).ProxyFunc()
+		transport.base.Proxy = func(request *http.Request) (*url.URL, error) {
+			return proxyFunc(request.URL)
+		}
+	}
+}
+
+type transport struct {
+	logger         *zap.Logger
+	base           *http.Transport
+	timeout        time.Duration
+	retries        int
+	initialBackoff time.Duration
+}
+
+func (t *transport) RoundTrip(request *http.Request) (*http.Response, error) {
+	backoff := t.initialBackoff
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && request.Body != nil {
+			body, err := request.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			request = request.Clone(request.Context())
+			request.Body = body
+		}
+		response, err := t.roundTripAttempt(request)
+		if attempt >= t.retries || !shouldRetry(request, response, err) {
+			return response, err
+		}
+		if err != nil {
+			t.logger.Debug("http_retry", zap.String("host", request.URL.Host), zap.Int("attempt", attempt+1), zap.Error(err))
+		} else {
+			t.logger.Debug("http_retry", zap.String("host", request.URL.Host), zap.Int("attempt", attempt+1), zap.Int("status_code", response.StatusCode))
+			// drain the body so that the connection can be reused
+			_, _ = io.Copy(ioutil.Discard, response.Body)
+			_ = response.Body.Close()
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-request.Context().Done():
+			timer.Stop()
+			return nil, request.Context().Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func (t *transport) roundTripAttempt(request *http.Request) (*http.Response, error) {
+	if t.timeout == 0 {
+		return t.base.RoundTrip(request)
+	}
+	ctx, cancel := context.WithTimeout(request.Context(), t.timeout)
+	response, err := t.base.RoundTrip(request.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// the timeout also applies to reading the body
+	response.Body = &cancelReadCloser{ReadCloser: response.Body, cancel: cancel}
+	return response, nil
+}
+
+// shouldRetry returns true if the request can be retried after the response or error.
+func shouldRetry(request *http.Request, response *http.Response, err error) bool {
+	if request.Context().Err() != nil {
+		// the request itself was cancelled or timed out
+		return false
+	}
+	if request.Body != nil && request.GetBody == nil {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	switch response.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelReadCloser) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+func getenvAny(getenv func(string) string, keys ...string) string {
+	for _, key := range keys {
+		if value := getenv(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
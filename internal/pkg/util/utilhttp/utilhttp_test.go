@@ -0,0 +1,149 @@
+package utilhttp
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetries(t *testing.T) {
+	t.Parallel()
+	var count int32
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				data, err := ioutil.ReadAll(request.Body)
+				require.NoError(t, err)
+				assert.Equal(t, "foo", string(data))
+				if atomic.AddInt32(&count, 1) < 3 {
+					responseWriter.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				_, _ = responseWriter.Write([]byte("bar"))
+			},
+		),
+	)
+	defer server.Close()
+
+	response, err := NewClient(ClientWithRetries(2, time.Millisecond)).Post(server.URL, "text/plain", bytes.NewReader([]byte("foo")))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	data, err := ioutil.ReadAll(response.Body)
+	require.NoError(t, err)
+	assert.NoError(t, response.Body.Close())
+	assert.Equal(t, "bar", string(data))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&count))
+
+	atomic.StoreInt32(&count, 0)
+	response, err = NewClient(ClientWithRetries(1, time.Millisecond)).Post(server.URL, "text/plain", bytes.NewReader([]byte("foo")))
+	require.NoError(t, err)
+	assert.NoError(t, response.Body.Close())
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&count))
+
+	// the body cannot be recreated
+	atomic.StoreInt32(&count, 0)
+	response, err = NewClient(ClientWithRetries(2, time.Millisecond)).Post(server.URL, "text/plain", ioutil.NopCloser(bytes.NewReader([]byte("foo"))))
+	require.NoError(t, err)
+	assert.NoError(t, response.Body.Close())
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+}
+
+func TestRetriesNotFound(t *testing.T) {
+	t.Parallel()
+	var count int32
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				atomic.AddInt32(&count, 1)
+				responseWriter.WriteHeader(http.StatusNotFound)
+			},
+		),
+	)
+	defer server.Close()
+
+	response, err := NewClient(ClientWithRetries(2, time.Millisecond)).Get(server.URL)
+	require.NoError(t, err)
+	assert.NoError(t, response.Body.Close())
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+}
+
+func TestTimeout(t *testing.T) {
+	t.Parallel()
+	var count int32
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				if atomic.AddInt32(&count, 1) == 1 {
+					select {
+					case <-request.Context().Done():
+					case <-time.After(5 * time.Second):
+					}
+					return
+				}
+				_, _ = responseWriter.Write([]byte("bar"))
+			},
+		),
+	)
+	defer server.Close()
+
+	_, err := NewClient(ClientWithTimeout(50 * time.Millisecond)).Get(server.URL)
+	assert.Error(t, err)
+
+	atomic.StoreInt32(&count, 0)
+	response, err := NewClient(
+		ClientWithTimeout(50*time.Millisecond),
+		ClientWithRetries(1, time.Millisecond),
+	).Get(server.URL)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(response.Body)
+	require.NoError(t, err)
+	assert.NoError(t, response.Body.Close())
+	assert.Equal(t, "bar", string(data))
+}
+
+func TestProxyFromEnv(t *testing.T) {
+	t.Parallel()
+	proxyServer := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				// proxied requests have the absolute URL
+				_, _ = responseWriter.Write([]byte(request.URL.String()))
+			},
+		),
+	)
+	defer proxyServer.Close()
+
+	client := NewClient(
+		ClientWithProxyFromEnv(
+			func(key string) string {
+				switch key {
+				case "http_proxy":
+					return proxyServer.URL
+				case "NO_PROXY":
+					return "bar.invalid"
+				default:
+					return ""
+				}
+			},
+		),
+	)
+	response, err := client.Get("http://foo.com/baz")
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(response.Body)
+	require.NoError(t, err)
+	assert.NoError(t, response.Body.Close())
+	assert.Equal(t, "http://foo.com/baz", string(data))
+
+	_, err = client.Get("http://bar.invalid/baz")
+	assert.Error(t, err)
+}
@@ -0,0 +1,27 @@
+// Package utilio implements io utilities.
+package utilio
+
+import (
+	"io"
+	"sync"
+)
+
+const copyBufferSize = 32 * 1024
+
+var copyBufferPool = sync.Pool{
+	New: func() interface{} {
+		buffer := make([]byte, copyBufferSize)
+		return &buffer
+	},
+}
+
+// Copy is the same as io.Copy, but re-uses buffers between calls.
+//
+// io.Copy allocates a new 32KB buffer for every call unless the reader or
+// writer short-circuits the copy, which adds up for long-running processes
+// that copy many files.
+func Copy(writer io.Writer, reader io.Reader) (int64, error) {
+	buffer := copyBufferPool.Get().(*[]byte)
+	defer copyBufferPool.Put(buffer)
+	return io.CopyBuffer(writer, reader, *buffer)
+}
@@ -0,0 +1,40 @@
+package utilio
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopy(t *testing.T) {
+	t.Parallel()
+	for _, size := range []int{0, 1, copyBufferSize - 1, copyBufferSize, 3*copyBufferSize + 1} {
+		data := strings.Repeat("a", size)
+		buffer := bytes.NewBuffer(nil)
+		// hide the WriterTo and ReaderFrom implementations so that the buffer is used
+		n, err := Copy(testWriter{buffer}, testReader{strings.NewReader(data)})
+		require.NoError(t, err)
+		assert.Equal(t, int64(size), n)
+		assert.Equal(t, data, buffer.String())
+	}
+}
+
+type testWriter struct {
+	writer io.Writer
+}
+
+func (w testWriter) Write(p []byte) (int, error) {
+	return w.writer.Write(p)
+}
+
+type testReader struct {
+	reader io.Reader
+}
+
+func (r testReader) Read(p []byte) (int, error) {
+	return r.reader.Read(p)
+}
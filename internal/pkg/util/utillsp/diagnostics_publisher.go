@@ -0,0 +1,75 @@
+package utillsp
+
+import (
+	"sort"
+
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+	"github.com/bufbuild/buf/internal/pkg/ext/extfile"
+)
+
+type diagnosticsPublisher struct {
+	writer Writer
+	source string
+	// publishedURIs are the URIs that diagnostics were published for by the
+	// previous call to Publish, so that they can be cleared if they are fixed.
+	publishedURIs map[string]struct{}
+}
+
+func newDiagnosticsPublisher(writer Writer, source string) *diagnosticsPublisher {
+	return &diagnosticsPublisher{
+		writer:        writer,
+		source:        source,
+		publishedURIs: make(map[string]struct{}),
+	}
+}
+
+func (d *diagnosticsPublisher) Publish(fileAnnotations []*filev1beta1.FileAnnotation) error {
+	uriToDiagnostics := make(map[string][]*Diagnostic)
+	var uris []string
+	for _, fileAnnotation := range fileAnnotations {
+		if fileAnnotation.Path == "" {
+			if err := d.writer.Notify(
+				MethodShowMessage,
+				&ShowMessageParams{
+					Type:    MessageTypeError,
+					Message: extfile.FileAnnotationToString(fileAnnotation),
+				},
+			); err != nil {
+				return err
+			}
+			continue
+		}
+		uri, err := PathToURI(fileAnnotation.Path)
+		if err != nil {
+			return err
+		}
+		if _, ok := uriToDiagnostics[uri]; !ok {
+			uris = append(uris, uri)
+		}
+		uriToDiagnostics[uri] = append(uriToDiagnostics[uri], FileAnnotationToDiagnostic(fileAnnotation, d.source))
+	}
+	var clearedURIs []string
+	for uri := range d.publishedURIs {
+		if _, ok := uriToDiagnostics[uri]; !ok {
+			clearedURIs = append(clearedURIs, uri)
+		}
+	}
+	sort.Strings(clearedURIs)
+	for _, uri := range clearedURIs {
+		if err := d.writer.Notify(MethodPublishDiagnostics, &PublishDiagnosticsParams{URI: uri, Diagnostics: []*Diagnostic{}}); err != nil {
+			return err
+		}
+	}
+	d.publishedURIs = make(map[string]struct{}, len(uris))
+	for _, uri := range uris {
+		if err := d.writer.Notify(MethodPublishDiagnostics, &PublishDiagnosticsParams{URI: uri, Diagnostics: uriToDiagnostics[uri]}); err != nil {
+			return err
+		}
+		d.publishedURIs[uri] = struct{}{}
+	}
+	return nil
+}
+
+func (d *diagnosticsPublisher) NumPublishedURIs() int {
+	return len(d.publishedURIs)
+}
@@ -0,0 +1,261 @@
+// Package utillsp implements the JSON-RPC 2.0 messages and the diagnostics
+// of the Language Server Protocol.
+//
+// Messages can be written with the Content-Length headers of the Language Server
+// Protocol base protocol, or as newline-delimited JSON.
+package utillsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+)
+
+const (
+	// Version is the JSON-RPC version.
+	Version = "2.0"
+
+	// ParseErrorCode is the JSON-RPC error code for invalid JSON.
+	ParseErrorCode = -32700
+	// InvalidParamsCode is the JSON-RPC error code for invalid params.
+	InvalidParamsCode = -32602
+	// MethodNotFoundCode is the JSON-RPC error code for unknown methods.
+	MethodNotFoundCode = -32601
+
+	// MethodPublishDiagnostics is the method of the notification that publishes
+	// the diagnostics of a file.
+	MethodPublishDiagnostics = "textDocument/publishDiagnostics"
+	// MethodShowMessage is the method of the notification that shows a message.
+	MethodShowMessage = "window/showMessage"
+
+	// MessageTypeError is the MessageType for errors.
+	MessageTypeError = 1
+	// DiagnosticSeverityError is the DiagnosticSeverity for errors.
+	DiagnosticSeverityError = 1
+
+	contentLengthHeader = "Content-Length"
+)
+
+// Request is a request or notification from the client.
+//
+// Notifications do not have an ID.
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// PublishDiagnosticsParams are the params of MethodPublishDiagnostics.
+type PublishDiagnosticsParams struct {
+	URI         string        `json:"uri"`
+	Diagnostics []*Diagnostic `json:"diagnostics"`
+}
+
+// Diagnostic is a diagnostic within a file.
+type Diagnostic struct {
+	Range    *Range `json:"range"`
+	Severity int    `json:"severity"`
+	Code     string `json:"code,omitempty"`
+	Source   string `json:"source"`
+	Message  string `json:"message"`
+}
+
+// Range is a range within a file.
+type Range struct {
+	Start *Position `json:"start"`
+	End   *Position `json:"end"`
+}
+
+// Position is a position within a file.
+//
+// Lines and characters are zero-based.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// ShowMessageParams are the params of MethodShowMessage.
+type ShowMessageParams struct {
+	Type    int    `json:"type"`
+	Message string `json:"message"`
+}
+
+// Writer writes messages to the client.
+type Writer interface {
+	// Notify sends a notification.
+	Notify(method string, params interface{}) error
+	// Respond responds to a request with the result.
+	//
+	// Nothing is written if the request was a notification, that is if id is nil.
+	Respond(id json.RawMessage, result interface{}) error
+	// RespondError responds to a request with an error.
+	//
+	// Nothing is written if the request was a notification, that is if id is nil.
+	RespondError(id json.RawMessage, code int, message string) error
+}
+
+// NewWriter returns a new Writer that writes messages with the Content-Length header.
+func NewWriter(writer io.Writer) Writer {
+	return newWriter(writer, false)
+}
+
+// NewLineWriter returns a new Writer that writes messages as newline-delimited JSON.
+func NewLineWriter(writer io.Writer) Writer {
+	return newWriter(writer, true)
+}
+
+// ReadMessage reads the content of a message with the Content-Length header.
+//
+// Other headers are ignored. Returns io.EOF if the reader is closed between messages.
+func ReadMessage(reader *bufio.Reader) ([]byte, error) {
+	contentLength := -1
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && line != "" {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		split := strings.SplitN(line, ":", 2)
+		if len(split) != 2 || !strings.EqualFold(strings.TrimSpace(split[0]), contentLengthHeader) {
+			continue
+		}
+		contentLength, err = strconv.Atoi(strings.TrimSpace(split[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %q", contentLengthHeader, split[1])
+		}
+	}
+	if contentLength < 0 {
+		return nil, fmt.Errorf("no %s header", contentLengthHeader)
+	}
+	data := make([]byte, contentLength)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// DiagnosticsPublisher publishes the FileAnnotations of checks as diagnostics.
+//
+// Not thread-safe.
+type DiagnosticsPublisher interface {
+	// Publish publishes the FileAnnotations of a check.
+	//
+	// The diagnostics of each file with FileAnnotations are published, and the
+	// diagnostics of each file that had diagnostics published by the previous
+	// call but has no FileAnnotations now are cleared. FileAnnotations without
+	// a path are sent as error messages.
+	//
+	// The paths of the FileAnnotations must be real file paths, either absolute
+	// or relative to the current working directory.
+	Publish(fileAnnotations []*filev1beta1.FileAnnotation) error
+	// NumPublishedURIs returns the number of files with diagnostics published
+	// by the last call to Publish.
+	NumPublishedURIs() int
+}
+
+// NewDiagnosticsPublisher returns a new DiagnosticsPublisher.
+//
+// The source is the source of the diagnostics, such as the name of the tool.
+func NewDiagnosticsPublisher(writer Writer, source string) DiagnosticsPublisher {
+	return newDiagnosticsPublisher(writer, source)
+}
+
+// PathToURI returns the file URI of the path.
+//
+// Relative paths are relative to the current working directory.
+func PathToURI(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	absPath = filepath.ToSlash(absPath)
+	// windows paths start with the volume name
+	if !strings.HasPrefix(absPath, "/") {
+		absPath = "/" + absPath
+	}
+	return (&url.URL{Scheme: "file", Path: absPath}).String(), nil
+}
+
+// URIToPath returns the absolute path of the file URI.
+func URIToPath(uri string) (string, error) {
+	parsedURL, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if parsedURL.Scheme != "file" {
+		return "", fmt.Errorf("%q is not a file URI", uri)
+	}
+	if parsedURL.Path == "" {
+		return "", errors.New("file URI has no path")
+	}
+	path := parsedURL.Path
+	// windows paths start with a slash before the volume name
+	if runtime.GOOS == "windows" {
+		path = strings.TrimPrefix(path, "/")
+	}
+	return filepath.FromSlash(path), nil
+}
+
+// URIKey returns the key used to compare URIs, as clients may escape
+// characters in URIs differently.
+func URIKey(uri string) string {
+	parsedURL, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	return parsedURL.Path
+}
+
+// FileAnnotationToDiagnostic converts the FileAnnotation to a Diagnostic.
+//
+// FileAnnotation lines and columns are one-based, and zero if unknown.
+func FileAnnotationToDiagnostic(fileAnnotation *filev1beta1.FileAnnotation, source string) *Diagnostic {
+	start := &Position{
+		Line:      toZeroBased(fileAnnotation.StartLine),
+		Character: toZeroBased(fileAnnotation.StartColumn),
+	}
+	end := start
+	if fileAnnotation.EndLine != 0 {
+		end = &Position{
+			Line:      toZeroBased(fileAnnotation.EndLine),
+			Character: toZeroBased(fileAnnotation.EndColumn),
+		}
+	}
+	message := fileAnnotation.Message
+	if message == "" {
+		message = fileAnnotation.Type
+	}
+	return &Diagnostic{
+		Range: &Range{
+			Start: start,
+			End:   end,
+		},
+		Severity: DiagnosticSeverityError,
+		Code:     fileAnnotation.Type,
+		Source:   source,
+		Message:  message,
+	}
+}
+
+func toZeroBased(value uint32) int {
+	if value == 0 {
+		return 0
+	}
+	return int(value) - 1
+}
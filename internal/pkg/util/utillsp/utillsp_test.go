@@ -0,0 +1,173 @@
+package utillsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	filev1beta1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/file/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadMessage(t *testing.T) {
+	t.Parallel()
+	_, err := ReadMessage(bufio.NewReader(bytes.NewReader([]byte("Content-Type: foo\r\n\r\n{}"))))
+	assert.Error(t, err)
+	_, err = ReadMessage(bufio.NewReader(bytes.NewReader([]byte("Content-Length: 10\r\n\r\n{}"))))
+	assert.Error(t, err)
+	data, err := ReadMessage(bufio.NewReader(bytes.NewReader([]byte("content-length: 2\n\n{}"))))
+	require.NoError(t, err)
+	assert.Equal(t, "{}", string(data))
+}
+
+func TestWriter(t *testing.T) {
+	t.Parallel()
+	buffer := bytes.NewBuffer(nil)
+	writer := NewWriter(buffer)
+	require.NoError(t, writer.Notify("foo", &ShowMessageParams{Type: MessageTypeError, Message: "<foo>"}))
+	require.NoError(t, writer.Respond(json.RawMessage("1"), nil))
+	require.NoError(t, writer.RespondError(json.RawMessage(`"2"`), MethodNotFoundCode, "bar"))
+	// notifications are not responded to
+	require.NoError(t, writer.Respond(nil, nil))
+	require.NoError(t, writer.RespondError(nil, MethodNotFoundCode, "bar"))
+	reader := bufio.NewReader(bytes.NewReader(buffer.Bytes()))
+	for _, expected := range []string{
+		`{"jsonrpc":"2.0","method":"foo","params":{"type":1,"message":"<foo>"}}`,
+		`{"jsonrpc":"2.0","id":1,"result":null}`,
+		`{"jsonrpc":"2.0","id":"2","error":{"code":-32601,"message":"bar"}}`,
+	} {
+		data, err := ReadMessage(reader)
+		require.NoError(t, err)
+		assert.Equal(t, expected+"\n", string(data))
+	}
+	_, err := ReadMessage(reader)
+	assert.Error(t, err)
+
+	buffer = bytes.NewBuffer(nil)
+	writer = NewLineWriter(buffer)
+	require.NoError(t, writer.Notify("foo", nil))
+	require.NoError(t, writer.Respond(json.RawMessage("1"), nil))
+	assert.Equal(
+		t,
+		`{"jsonrpc":"2.0","method":"foo","params":null}
+{"jsonrpc":"2.0","id":1,"result":null}
+`,
+		buffer.String(),
+	)
+}
+
+func TestPathToURI(t *testing.T) {
+	t.Parallel()
+	uri, err := PathToURI(filepath.Join("foo bar", "a.proto"))
+	require.NoError(t, err)
+	assert.Contains(t, uri, "file:///")
+	assert.Contains(t, uri, "/foo%20bar/a.proto")
+	path, err := URIToPath(uri)
+	require.NoError(t, err)
+	absPath, err := filepath.Abs(filepath.Join("foo bar", "a.proto"))
+	require.NoError(t, err)
+	assert.Equal(t, absPath, path)
+	assert.Equal(t, URIKey(uri), URIKey(uri[:len(uri)-len("a.proto")]+"%61.proto"))
+	_, err = URIToPath("https://example.com/a.proto")
+	assert.Error(t, err)
+}
+
+func TestFileAnnotationToDiagnostic(t *testing.T) {
+	t.Parallel()
+	assert.Equal(
+		t,
+		&Diagnostic{
+			Range: &Range{
+				Start: &Position{Line: 1, Character: 2},
+				End:   &Position{Line: 3, Character: 4},
+			},
+			Severity: DiagnosticSeverityError,
+			Code:     "FOO",
+			Source:   "buf",
+			Message:  "Foo.",
+		},
+		FileAnnotationToDiagnostic(
+			&filev1beta1.FileAnnotation{
+				Path:        "a.proto",
+				StartLine:   2,
+				StartColumn: 3,
+				EndLine:     4,
+				EndColumn:   5,
+				Type:        "FOO",
+				Message:     "Foo.",
+			},
+			"buf",
+		),
+	)
+	// unknown positions are the start of the file, and the type is the message if there is none
+	assert.Equal(
+		t,
+		&Diagnostic{
+			Range: &Range{
+				Start: &Position{},
+				End:   &Position{},
+			},
+			Severity: DiagnosticSeverityError,
+			Code:     "FOO",
+			Source:   "buf",
+			Message:  "FOO",
+		},
+		FileAnnotationToDiagnostic(&filev1beta1.FileAnnotation{Path: "a.proto", Type: "FOO"}, "buf"),
+	)
+}
+
+func TestDiagnosticsPublisher(t *testing.T) {
+	t.Parallel()
+	aURI, err := PathToURI("a.proto")
+	require.NoError(t, err)
+	bURI, err := PathToURI("b.proto")
+	require.NoError(t, err)
+	buffer := bytes.NewBuffer(nil)
+	diagnosticsPublisher := NewDiagnosticsPublisher(NewLineWriter(buffer), "buf")
+
+	require.NoError(
+		t,
+		diagnosticsPublisher.Publish(
+			[]*filev1beta1.FileAnnotation{
+				{Path: "b.proto", Type: "FOO"},
+				{Path: "a.proto", Type: "FOO"},
+				{Path: "b.proto", Type: "BAR"},
+				{Type: "BAZ", Message: "Baz."},
+			},
+		),
+	)
+	assert.Equal(t, 2, diagnosticsPublisher.NumPublishedURIs())
+	assert.Equal(
+		t,
+		`{"jsonrpc":"2.0","method":"window/showMessage","params":{"type":1,"message":"<input>:1:1:Baz."}}
+{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"`+bURI+`","diagnostics":[{"range":{"start":{"line":0,"character":0},"end":{"line":0,"character":0}},"severity":1,"code":"FOO","source":"buf","message":"FOO"},{"range":{"start":{"line":0,"character":0},"end":{"line":0,"character":0}},"severity":1,"code":"BAR","source":"buf","message":"BAR"}]}}
+{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"`+aURI+`","diagnostics":[{"range":{"start":{"line":0,"character":0},"end":{"line":0,"character":0}},"severity":1,"code":"FOO","source":"buf","message":"FOO"}]}}
+`,
+		buffer.String(),
+	)
+
+	// files that no longer have diagnostics are cleared
+	buffer.Reset()
+	require.NoError(t, diagnosticsPublisher.Publish([]*filev1beta1.FileAnnotation{{Path: "a.proto", Type: "FOO"}}))
+	assert.Equal(t, 1, diagnosticsPublisher.NumPublishedURIs())
+	assert.Equal(
+		t,
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"`+bURI+`","diagnostics":[]}}
+{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"`+aURI+`","diagnostics":[{"range":{"start":{"line":0,"character":0},"end":{"line":0,"character":0}},"severity":1,"code":"FOO","source":"buf","message":"FOO"}]}}
+`,
+		buffer.String(),
+	)
+
+	buffer.Reset()
+	require.NoError(t, diagnosticsPublisher.Publish(nil))
+	assert.Equal(t, 0, diagnosticsPublisher.NumPublishedURIs())
+	assert.Equal(
+		t,
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"`+aURI+`","diagnostics":[]}}
+`,
+		buffer.String(),
+	)
+}
@@ -0,0 +1,99 @@
+package utillsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+type notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result"`
+}
+
+type errorResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   *responseError  `json:"error"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type writer struct {
+	writer        io.Writer
+	lineDelimited bool
+}
+
+func newWriter(w io.Writer, lineDelimited bool) *writer {
+	return &writer{
+		writer:        w,
+		lineDelimited: lineDelimited,
+	}
+}
+
+func (w *writer) Notify(method string, params interface{}) error {
+	return w.write(
+		&notification{
+			JSONRPC: Version,
+			Method:  method,
+			Params:  params,
+		},
+	)
+}
+
+func (w *writer) Respond(id json.RawMessage, result interface{}) error {
+	if id == nil {
+		return nil
+	}
+	return w.write(
+		&response{
+			JSONRPC: Version,
+			ID:      id,
+			Result:  result,
+		},
+	)
+}
+
+func (w *writer) RespondError(id json.RawMessage, code int, message string) error {
+	if id == nil {
+		return nil
+	}
+	return w.write(
+		&errorResponse{
+			JSONRPC: Version,
+			ID:      id,
+			Error: &responseError{
+				Code:    code,
+				Message: message,
+			},
+		},
+	)
+}
+
+// write writes the value as JSON, which the encoder always ends with a newline.
+func (w *writer) write(value interface{}) error {
+	buffer := bytes.NewBuffer(nil)
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return err
+	}
+	if !w.lineDelimited {
+		if _, err := fmt.Fprintf(w.writer, "%s: %d\r\n\r\n", contentLengthHeader, buffer.Len()); err != nil {
+			return err
+		}
+	}
+	_, err := w.writer.Write(buffer.Bytes())
+	return err
+}
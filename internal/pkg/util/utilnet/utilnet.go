@@ -0,0 +1,23 @@
+// Package utilnet implements network utilities.
+package utilnet
+
+import (
+	"net"
+	"strings"
+)
+
+// IsLocalhost returns true if the host is localhost or a loopback IP address.
+//
+// The host may or may not have a port, and IPv6 addresses may or may not be
+// within brackets, such as localhost:8080, ::1, or [::1]:8080.
+func IsLocalhost(host string) bool {
+	if splitHost, _, err := net.SplitHostPort(host); err == nil {
+		host = splitHost
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
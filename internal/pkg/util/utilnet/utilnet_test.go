@@ -0,0 +1,30 @@
+package utilnet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsLocalhost(t *testing.T) {
+	t.Parallel()
+	for host, expected := range map[string]bool{
+		"localhost":          true,
+		"localhost:8080":     true,
+		"127.0.0.1":          true,
+		"127.0.0.1:8080":     true,
+		"127.0.0.2:8080":     true,
+		"::1":                true,
+		"[::1]":              true,
+		"[::1]:8080":         true,
+		"buf.example.com":    false,
+		"buf.example.com:80": false,
+		"localhost.com:8080": false,
+		"10.0.0.1:8080":      false,
+		"[::2]:8080":         false,
+		"LOCALHOST":          true,
+		"":                   false,
+	} {
+		assert.Equal(t, expected, IsLocalhost(host), host)
+	}
+}
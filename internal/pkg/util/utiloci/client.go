@@ -0,0 +1,533 @@
+package utiloci
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go.uber.org/multierr"
+)
+
+// emptyConfigData is the data of the config of pushed artifacts.
+var emptyConfigData = []byte("{}")
+
+type manifest struct {
+	SchemaVersion int           `json:"schemaVersion"`
+	MediaType     string        `json:"mediaType,omitempty"`
+	Config        *descriptor   `json:"config,omitempty"`
+	Layers        []*descriptor `json:"layers,omitempty"`
+}
+
+type descriptor struct {
+	MediaType string `json:"mediaType,omitempty"`
+	Digest    string `json:"digest,omitempty"`
+	Size      int64  `json:"size"`
+}
+
+type dockerConfig struct {
+	Auths map[string]dockerConfigAuth `json:"auths,omitempty"`
+}
+
+type dockerConfigAuth struct {
+	Auth string `json:"auth,omitempty"`
+}
+
+type tokenResponse struct {
+	Token       string `json:"token,omitempty"`
+	AccessToken string `json:"access_token,omitempty"`
+}
+
+type client struct {
+	httpClient *http.Client
+	getenv     func(string) string
+
+	// registry to Authorization header value
+	registryToAuthorization map[string]string
+	lock                    sync.Mutex
+}
+
+func newClient(httpClient *http.Client, getenv func(string) string) *client {
+	return &client{
+		httpClient:              httpClient,
+		getenv:                  getenv,
+		registryToAuthorization: make(map[string]string),
+	}
+}
+
+func (c *client) Pull(ctx context.Context, reference *Reference, layerMediaType string) ([]byte, error) {
+	manifestReference := reference.Tag
+	if reference.Digest != "" {
+		manifestReference = reference.Digest
+	}
+	manifestData, err := c.get(
+		ctx,
+		reference,
+		"/manifests/"+manifestReference,
+		ManifestMediaType,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if reference.Digest != "" {
+		if err := verifyDigest(reference.Digest, manifestData); err != nil {
+			return nil, fmt.Errorf("manifest of %s: %v", reference.String(), err)
+		}
+	}
+	manifest := &manifest{}
+	if err := json.Unmarshal(manifestData, manifest); err != nil {
+		return nil, fmt.Errorf("could not parse manifest of %s: %v", reference.String(), err)
+	}
+	var layer *descriptor
+	for _, manifestLayer := range manifest.Layers {
+		if manifestLayer.MediaType == layerMediaType {
+			layer = manifestLayer
+			break
+		}
+	}
+	if layer == nil {
+		return nil, fmt.Errorf("%s does not have a layer with media type %s", reference.String(), layerMediaType)
+	}
+	data, err := c.get(ctx, reference, "/blobs/"+layer.Digest, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyDigest(layer.Digest, data); err != nil {
+		return nil, fmt.Errorf("layer of %s: %v", reference.String(), err)
+	}
+	return data, nil
+}
+
+func (c *client) Push(
+	ctx context.Context,
+	reference *Reference,
+	configMediaType string,
+	layerMediaType string,
+	data []byte,
+) error {
+	if reference.Tag == "" {
+		return fmt.Errorf("%s must have a tag to push", reference.String())
+	}
+	configDescriptor, err := c.pushBlob(ctx, reference, configMediaType, emptyConfigData)
+	if err != nil {
+		return err
+	}
+	layerDescriptor, err := c.pushBlob(ctx, reference, layerMediaType, data)
+	if err != nil {
+		return err
+	}
+	manifestData, err := json.Marshal(
+		&manifest{
+			SchemaVersion: 2,
+			MediaType:     ManifestMediaType,
+			Config:        configDescriptor,
+			Layers:        []*descriptor{layerDescriptor},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	response, err := c.do(
+		ctx,
+		reference,
+		http.MethodPut,
+		c.newURL(reference, "/manifests/"+reference.Tag),
+		ManifestMediaType,
+		manifestData,
+	)
+	if err != nil {
+		return err
+	}
+	return c.checkAndClose(response, reference, http.StatusCreated)
+}
+
+// pushBlob pushes the blob if it does not already exist in the repository.
+func (c *client) pushBlob(
+	ctx context.Context,
+	reference *Reference,
+	mediaType string,
+	data []byte,
+) (*descriptor, error) {
+	blobDescriptor := &descriptor{
+		MediaType: mediaType,
+		Digest:    digest(data),
+		Size:      int64(len(data)),
+	}
+	response, err := c.do(
+		ctx,
+		reference,
+		http.MethodHead,
+		c.newURL(reference, "/blobs/"+blobDescriptor.Digest),
+		"",
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := response.Body.Close(); err != nil {
+		return nil, err
+	}
+	if response.StatusCode == http.StatusOK {
+		return blobDescriptor, nil
+	}
+	response, err = c.do(
+		ctx,
+		reference,
+		http.MethodPost,
+		c.newURL(reference, "/blobs/uploads/"),
+		"",
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkAndClose(response, reference, http.StatusAccepted); err != nil {
+		return nil, err
+	}
+	location, err := response.Location()
+	if err != nil {
+		return nil, fmt.Errorf("could not get upload location for %s: %v", reference.String(), err)
+	}
+	query := location.Query()
+	query.Set("digest", blobDescriptor.Digest)
+	location.RawQuery = query.Encode()
+	response, err = c.do(
+		ctx,
+		reference,
+		http.MethodPut,
+		location.String(),
+		"application/octet-stream",
+		data,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkAndClose(response, reference, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return blobDescriptor, nil
+}
+
+func (c *client) get(
+	ctx context.Context,
+	reference *Reference,
+	path string,
+	accept string,
+) (_ []byte, retErr error) {
+	response, err := c.do(ctx, reference, http.MethodGet, c.newURL(reference, path), accept, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+	if response.StatusCode != http.StatusOK {
+		return nil, newStatusError(response, reference)
+	}
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", reference.String(), err)
+	}
+	return data, nil
+}
+
+// do sends the request, authenticating and retrying once if the registry
+// responds with an authentication challenge.
+//
+// For GET requests, header is the Accept header, otherwise it is the Content-Type header.
+func (c *client) do(
+	ctx context.Context,
+	reference *Reference,
+	method string,
+	rawURL string,
+	header string,
+	data []byte,
+) (*http.Response, error) {
+	newRequest := func() (*http.Request, error) {
+		var body io.Reader
+		if data != nil {
+			body = bytes.NewReader(data)
+		}
+		request, err := http.NewRequestWithContext(ctx, method, rawURL, body)
+		if err != nil {
+			return nil, err
+		}
+		if header != "" {
+			if method == http.MethodGet {
+				request.Header.Set("Accept", header)
+			} else {
+				request.Header.Set("Content-Type", header)
+			}
+		}
+		if authorization := c.getAuthorization(reference.Registry); authorization != "" {
+			request.Header.Set("Authorization", authorization)
+		}
+		return request, nil
+	}
+	request, err := newRequest()
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusUnauthorized {
+		return response, nil
+	}
+	challenge := response.Header.Get("WWW-Authenticate")
+	if err := response.Body.Close(); err != nil {
+		return nil, err
+	}
+	if err := c.authenticate(ctx, reference, method, challenge); err != nil {
+		return nil, err
+	}
+	request, err = newRequest()
+	if err != nil {
+		return nil, err
+	}
+	return c.httpClient.Do(request)
+}
+
+// authenticate sets the Authorization header value for the registry of the
+// reference per the challenge.
+func (c *client) authenticate(
+	ctx context.Context,
+	reference *Reference,
+	method string,
+	challenge string,
+) (retErr error) {
+	username, password, err := c.getCredentials(reference.Registry)
+	if err != nil {
+		return err
+	}
+	scheme, params := parseChallenge(challenge)
+	switch scheme {
+	case "basic":
+		if username == "" && password == "" {
+			return fmt.Errorf("%s requires credentials but none were found in the docker configuration", reference.Registry)
+		}
+		c.setAuthorization(reference.Registry, "Basic "+basicAuth(username, password))
+		return nil
+	case "bearer":
+	default:
+		return fmt.Errorf("%s returned unsupported authentication challenge %q", reference.Registry, challenge)
+	}
+	realm := params["realm"]
+	if realm == "" {
+		return fmt.Errorf("%s returned authentication challenge without realm", reference.Registry)
+	}
+	tokenURL, err := url.Parse(realm)
+	if err != nil {
+		return err
+	}
+	query := tokenURL.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	scope := params["scope"]
+	if scope == "" {
+		actions := "pull"
+		if method != http.MethodGet {
+			actions = "pull,push"
+		}
+		scope = "repository:" + reference.Repository + ":" + actions
+	}
+	query.Set("scope", scope)
+	tokenURL.RawQuery = query.Encode()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	if username != "" || password != "" {
+		request.SetBasicAuth(username, password)
+	}
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("got HTTP status code %d when authenticating to %s", response.StatusCode, reference.Registry)
+	}
+	tokenResponse := &tokenResponse{}
+	if err := json.NewDecoder(response.Body).Decode(tokenResponse); err != nil {
+		return fmt.Errorf("could not parse token from %s: %v", reference.Registry, err)
+	}
+	token := tokenResponse.Token
+	if token == "" {
+		token = tokenResponse.AccessToken
+	}
+	if token == "" {
+		return fmt.Errorf("%s did not return a token", reference.Registry)
+	}
+	c.setAuthorization(reference.Registry, "Bearer "+token)
+	return nil
+}
+
+// getCredentials gets the username and password for the registry from the
+// docker configuration file.
+//
+// Returns empty values if there is no configuration file or no credentials.
+func (c *client) getCredentials(registry string) (string, string, error) {
+	configDirPath := c.getenv("DOCKER_CONFIG")
+	if configDirPath == "" {
+		homeDirPath := c.getenv("HOME")
+		if homeDirPath == "" {
+			return "", "", nil
+		}
+		configDirPath = filepath.Join(homeDirPath, ".docker")
+	}
+	data, err := ioutil.ReadFile(filepath.Join(configDirPath, "config.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", "", nil
+		}
+		return "", "", err
+	}
+	dockerConfig := &dockerConfig{}
+	if err := json.Unmarshal(data, dockerConfig); err != nil {
+		return "", "", fmt.Errorf("could not parse docker configuration: %v", err)
+	}
+	for key, auth := range dockerConfig.Auths {
+		if normalizeRegistry(key) != normalizeRegistry(registry) || auth.Auth == "" {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err != nil {
+			return "", "", fmt.Errorf("could not decode docker configuration auth for %s: %v", key, err)
+		}
+		split := strings.SplitN(string(decoded), ":", 2)
+		if len(split) != 2 {
+			return "", "", fmt.Errorf("invalid docker configuration auth for %s", key)
+		}
+		return split[0], split[1], nil
+	}
+	return "", "", nil
+}
+
+func (c *client) getAuthorization(registry string) string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.registryToAuthorization[registry]
+}
+
+func (c *client) setAuthorization(registry string, authorization string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.registryToAuthorization[registry] = authorization
+}
+
+func (c *client) newURL(reference *Reference, path string) string {
+	scheme := "https"
+	if isLocalhost(reference.Registry) {
+		scheme = "http"
+	}
+	host := reference.Registry
+	if host == "docker.io" {
+		// docker.io is served from registry-1.docker.io
+		host = "registry-1.docker.io"
+	}
+	return scheme + "://" + host + "/v2/" + reference.Repository + path
+}
+
+func (c *client) checkAndClose(response *http.Response, reference *Reference, expectedStatusCode int) error {
+	if response.StatusCode != expectedStatusCode {
+		return multierr.Append(newStatusError(response, reference), response.Body.Close())
+	}
+	return response.Body.Close()
+}
+
+// parseChallenge parses a WWW-Authenticate header value into its lowercased
+// scheme and its parameters.
+func parseChallenge(challenge string) (string, map[string]string) {
+	challenge = strings.TrimSpace(challenge)
+	spaceIndex := strings.Index(challenge, " ")
+	if spaceIndex < 0 {
+		return strings.ToLower(challenge), nil
+	}
+	params := make(map[string]string)
+	for _, param := range splitParams(challenge[spaceIndex+1:]) {
+		split := strings.SplitN(param, "=", 2)
+		if len(split) != 2 {
+			continue
+		}
+		params[strings.ToLower(strings.TrimSpace(split[0]))] = strings.Trim(strings.TrimSpace(split[1]), `"`)
+	}
+	return strings.ToLower(challenge[:spaceIndex]), params
+}
+
+// splitParams splits on commas that are not within quotes.
+func splitParams(value string) []string {
+	var params []string
+	var inQuotes bool
+	start := 0
+	for i, c := range value {
+		switch c {
+		case '"':
+			inQuotes = !inQuotes
+		case ',':
+			if !inQuotes {
+				params = append(params, value[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(params, value[start:])
+}
+
+func newStatusError(response *http.Response, reference *Reference) error {
+	return fmt.Errorf("got HTTP status code %d for %s %s of %s", response.StatusCode, response.Request.Method, response.Request.URL.Path, reference.String())
+}
+
+func verifyDigest(expectedDigest string, data []byte) error {
+	if actualDigest := digest(data); actualDigest != expectedDigest {
+		return fmt.Errorf("expected digest %s but got %s", expectedDigest, actualDigest)
+	}
+	return nil
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func basicAuth(username string, password string) string {
+	return base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+}
+
+// normalizeRegistry normalizes registry keys of the docker configuration file,
+// which can be URLs such as https://index.docker.io/v1/.
+func normalizeRegistry(registry string) string {
+	registry = strings.TrimPrefix(strings.TrimPrefix(registry, "https://"), "http://")
+	if slashIndex := strings.Index(registry, "/"); slashIndex >= 0 {
+		registry = registry[:slashIndex]
+	}
+	switch registry {
+	case "index.docker.io", "registry-1.docker.io":
+		return "docker.io"
+	default:
+		return registry
+	}
+}
+
+func isLocalhost(registry string) bool {
+	host := registry
+	if colonIndex := strings.LastIndex(host, ":"); colonIndex >= 0 {
+		host = host[:colonIndex]
+	}
+	return host == "localhost" || host == "127.0.0.1"
+}
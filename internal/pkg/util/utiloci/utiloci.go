@@ -0,0 +1,104 @@
+// Package utiloci implements a minimal client for OCI distribution registries.
+//
+// Only single-layer artifacts are supported, which is enough to distribute
+// files such as images using existing container registries.
+package utiloci
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ManifestMediaType is the media type of OCI image manifests.
+const ManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+
+// Reference is a reference to an artifact within a registry.
+type Reference struct {
+	// Registry is the host of the registry, optionally with a port.
+	Registry string
+	// Repository is the repository within the registry.
+	Repository string
+	// Tag is the tag of the artifact.
+	//
+	// Only one of Tag and Digest is set.
+	Tag string
+	// Digest is the digest of the manifest of the artifact.
+	//
+	// Only one of Tag and Digest is set.
+	Digest string
+}
+
+// ParseReference parses a reference of the form registry/repository:tag
+// or registry/repository@digest.
+//
+// If neither a tag nor a digest is given, the tag "latest" is used.
+func ParseReference(value string) (*Reference, error) {
+	slashIndex := strings.Index(value, "/")
+	if slashIndex <= 0 || slashIndex == len(value)-1 {
+		return nil, fmt.Errorf("%q must be of the form registry/repository:tag", value)
+	}
+	reference := &Reference{
+		Registry: value[:slashIndex],
+	}
+	repository := value[slashIndex+1:]
+	if atIndex := strings.Index(repository, "@"); atIndex >= 0 {
+		reference.Digest = repository[atIndex+1:]
+		repository = repository[:atIndex]
+		if !strings.HasPrefix(reference.Digest, "sha256:") {
+			return nil, fmt.Errorf("%q has unsupported digest %q, only sha256 digests are supported", value, reference.Digest)
+		}
+	} else if colonIndex := strings.LastIndex(repository, ":"); colonIndex >= 0 {
+		reference.Tag = repository[colonIndex+1:]
+		repository = repository[:colonIndex]
+		if reference.Tag == "" {
+			return nil, fmt.Errorf("%q has an empty tag", value)
+		}
+	} else {
+		reference.Tag = "latest"
+	}
+	if repository == "" || repository != strings.ToLower(repository) || strings.Contains(repository, "//") {
+		return nil, fmt.Errorf("%q has invalid repository %q", value, repository)
+	}
+	reference.Repository = strings.Trim(repository, "/")
+	return reference, nil
+}
+
+// String returns the string value of r.
+func (r *Reference) String() string {
+	if r.Digest != "" {
+		return r.Registry + "/" + r.Repository + "@" + r.Digest
+	}
+	return r.Registry + "/" + r.Repository + ":" + r.Tag
+}
+
+// Client is a client for OCI distribution registries.
+type Client interface {
+	// Pull pulls the data of the layer with the given media type of the artifact
+	// at the reference.
+	//
+	// The digest of the manifest and the layer are verified.
+	Pull(ctx context.Context, reference *Reference, layerMediaType string) ([]byte, error)
+	// Push pushes the data as the single layer with the given media type of a
+	// new artifact, and tags the artifact with the tag of the reference.
+	//
+	// The config of the artifact is empty, with the given media type.
+	Push(
+		ctx context.Context,
+		reference *Reference,
+		configMediaType string,
+		layerMediaType string,
+		data []byte,
+	) error
+}
+
+// NewClient returns a new Client.
+//
+// Credentials are read from the docker configuration file at
+// $DOCKER_CONFIG/config.json, or $HOME/.docker/config.json if DOCKER_CONFIG
+// is not set. Both basic and token authentication are supported.
+// Registries on localhost are accessed over plain HTTP.
+func NewClient(httpClient *http.Client, getenv func(string) string) Client {
+	return newClient(httpClient, getenv)
+}
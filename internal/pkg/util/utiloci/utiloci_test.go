@@ -0,0 +1,187 @@
+package utiloci
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReference(t *testing.T) {
+	testParseReference(t, "localhost:5000/foo/bar:v1", &Reference{Registry: "localhost:5000", Repository: "foo/bar", Tag: "v1"})
+	testParseReference(t, "ghcr.io/foo/bar", &Reference{Registry: "ghcr.io", Repository: "foo/bar", Tag: "latest"})
+	testParseReference(
+		t,
+		"ghcr.io/foo/bar@sha256:abc",
+		&Reference{Registry: "ghcr.io", Repository: "foo/bar", Digest: "sha256:abc"},
+	)
+	testParseReferenceError(t, "foo")
+	testParseReferenceError(t, "/foo")
+	testParseReferenceError(t, "ghcr.io/")
+	testParseReferenceError(t, "ghcr.io/foo:")
+	testParseReferenceError(t, "ghcr.io/Foo:v1")
+	testParseReferenceError(t, "ghcr.io/foo@md5:abc")
+}
+
+func TestPushPull(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(newTestRegistry(t, "", ""))
+	defer server.Close()
+	testPushPull(t, server, nil)
+}
+
+func TestPushPullBasicAuth(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(newTestRegistry(t, "foo", "bar"))
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "http://")
+
+	reference, err := ParseReference(registry + "/foo/bar:v1")
+	require.NoError(t, err)
+	_, err = NewClient(server.Client(), func(string) string { return "" }).Pull(context.Background(), reference, "application/foo")
+	assert.Error(t, err)
+
+	dockerConfigDirPath, err := ioutil.TempDir("", "utiloci")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(dockerConfigDirPath))
+	}()
+	require.NoError(
+		t,
+		ioutil.WriteFile(
+			filepath.Join(dockerConfigDirPath, "config.json"),
+			[]byte(`{"auths":{"`+registry+`":{"auth":"`+base64.StdEncoding.EncodeToString([]byte("foo:bar"))+`"}}}`),
+			0600,
+		),
+	)
+	testPushPull(
+		t,
+		server,
+		func(key string) string {
+			if key == "DOCKER_CONFIG" {
+				return dockerConfigDirPath
+			}
+			return ""
+		},
+	)
+}
+
+func testPushPull(t *testing.T, server *httptest.Server, getenv func(string) string) {
+	if getenv == nil {
+		getenv = func(string) string { return "" }
+	}
+	client := NewClient(server.Client(), getenv)
+	reference, err := ParseReference(strings.TrimPrefix(server.URL, "http://") + "/foo/bar:v1")
+	require.NoError(t, err)
+	require.NoError(t, client.Push(context.Background(), reference, "application/foo.config", "application/foo", []byte("hello")))
+	// push again to make sure existing blobs are skipped
+	require.NoError(t, client.Push(context.Background(), reference, "application/foo.config", "application/foo", []byte("hello")))
+	data, err := client.Pull(context.Background(), reference, "application/foo")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+	_, err = client.Pull(context.Background(), reference, "application/bar")
+	assert.Error(t, err)
+	reference.Tag = "v2"
+	_, err = client.Pull(context.Background(), reference, "application/foo")
+	assert.Error(t, err)
+}
+
+func testParseReference(t *testing.T, value string, expected *Reference) {
+	t.Run(value, func(t *testing.T) {
+		reference, err := ParseReference(value)
+		require.NoError(t, err)
+		assert.Equal(t, expected, reference)
+		assert.True(t, strings.HasPrefix(reference.String(), value))
+	})
+}
+
+func testParseReferenceError(t *testing.T, value string) {
+	t.Run(value, func(t *testing.T) {
+		_, err := ParseReference(value)
+		assert.Error(t, err)
+	})
+}
+
+// testRegistry is a minimal in-memory registry for testing.
+type testRegistry struct {
+	t         *testing.T
+	username  string
+	password  string
+	blobs     map[string][]byte
+	manifests map[string][]byte
+	lock      sync.Mutex
+}
+
+func newTestRegistry(t *testing.T, username string, password string) *testRegistry {
+	return &testRegistry{
+		t:         t,
+		username:  username,
+		password:  password,
+		blobs:     make(map[string][]byte),
+		manifests: make(map[string][]byte),
+	}
+}
+
+func (r *testRegistry) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	if r.username != "" {
+		username, password, ok := request.BasicAuth()
+		if !ok || username != r.username || password != r.password {
+			responseWriter.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			responseWriter.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	path := strings.TrimPrefix(request.URL.Path, "/v2/foo/bar")
+	switch {
+	case request.Method == http.MethodPost && path == "/blobs/uploads/":
+		responseWriter.Header().Set("Location", "/v2/foo/bar/blobs/uploads/1?state=foo")
+		responseWriter.WriteHeader(http.StatusAccepted)
+	case request.Method == http.MethodPut && path == "/blobs/uploads/1":
+		assert.Equal(r.t, "foo", request.URL.Query().Get("state"))
+		data, err := ioutil.ReadAll(request.Body)
+		require.NoError(r.t, err)
+		digestValue := request.URL.Query().Get("digest")
+		if digest(data) != digestValue {
+			responseWriter.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.blobs[digestValue] = data
+		responseWriter.WriteHeader(http.StatusCreated)
+	case strings.HasPrefix(path, "/blobs/"):
+		data, ok := r.blobs[strings.TrimPrefix(path, "/blobs/")]
+		if !ok {
+			responseWriter.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if request.Method == http.MethodGet {
+			_, _ = responseWriter.Write(data)
+		}
+	case request.Method == http.MethodPut && strings.HasPrefix(path, "/manifests/"):
+		assert.Equal(r.t, ManifestMediaType, request.Header.Get("Content-Type"))
+		data, err := ioutil.ReadAll(request.Body)
+		require.NoError(r.t, err)
+		r.manifests[strings.TrimPrefix(path, "/manifests/")] = data
+		responseWriter.WriteHeader(http.StatusCreated)
+	case request.Method == http.MethodGet && strings.HasPrefix(path, "/manifests/"):
+		data, ok := r.manifests[strings.TrimPrefix(path, "/manifests/")]
+		if !ok {
+			responseWriter.WriteHeader(http.StatusNotFound)
+			return
+		}
+		responseWriter.Header().Set("Content-Type", ManifestMediaType)
+		_, _ = responseWriter.Write(data)
+	default:
+		responseWriter.WriteHeader(http.StatusNotFound)
+	}
+}
@@ -2,6 +2,8 @@ package utilproto
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
@@ -63,3 +65,93 @@ func UnmarshalJSON(data []byte, message proto.Message) error {
 func Equal(one proto.Message, two proto.Message) bool {
 	return proto.Equal(one, two)
 }
+
+// WireField is a single field within wire format data.
+type WireField struct {
+	// Number is the field number.
+	Number int
+	// Data is the tag and value of the field.
+	Data []byte
+}
+
+// SplitWire splits the wire format data into fields, in the order they appear.
+//
+// Fields with the same number, such as the elements of repeated fields, are not
+// merged. The Data of each WireField references the given data.
+func SplitWire(data []byte) ([]*WireField, error) {
+	var wireFields []*WireField
+	for len(data) > 0 {
+		number, n, err := getWireFieldLength(data)
+		if err != nil {
+			return nil, err
+		}
+		wireFields = append(
+			wireFields,
+			&WireField{
+				Number: number,
+				Data:   data[:n],
+			},
+		)
+		data = data[n:]
+	}
+	return wireFields, nil
+}
+
+// getWireFieldLength returns the field number and the length of the first field
+// within the data, including the tag.
+func getWireFieldLength(data []byte) (int, int, error) {
+	tag, n := proto.DecodeVarint(data)
+	if n == 0 {
+		return 0, 0, errors.New("invalid tag")
+	}
+	number := int(tag >> 3)
+	if number <= 0 {
+		return 0, 0, fmt.Errorf("invalid field number %d", number)
+	}
+	switch wireType := tag & 7; wireType {
+	case proto.WireVarint:
+		_, m := proto.DecodeVarint(data[n:])
+		if m == 0 {
+			return 0, 0, errors.New("invalid varint")
+		}
+		n += m
+	case proto.WireFixed64:
+		n += 8
+	case proto.WireBytes:
+		length, m := proto.DecodeVarint(data[n:])
+		if m == 0 {
+			return 0, 0, errors.New("invalid length")
+		}
+		if length > uint64(len(data)) {
+			return 0, 0, errors.New("length out of range")
+		}
+		n += m + int(length)
+	case proto.WireStartGroup:
+		for {
+			if n >= len(data) {
+				return 0, 0, errors.New("unterminated group")
+			}
+			endTag, m := proto.DecodeVarint(data[n:])
+			if m == 0 {
+				return 0, 0, errors.New("invalid tag")
+			}
+			if endTag == uint64(number)<<3|proto.WireEndGroup {
+				n += m
+				break
+			}
+			_, m, err := getWireFieldLength(data[n:])
+			if err != nil {
+				return 0, 0, err
+			}
+			n += m
+		}
+	case proto.WireFixed32:
+		n += 4
+	default:
+		return 0, 0, fmt.Errorf("invalid wire type %d", wireType)
+	}
+	if n > len(data) {
+		return 0, 0, errors.New("unexpected end of data")
+	}
+	return number, n, nil
+}
@@ -31,6 +31,17 @@ bufbreakinginternal:
 
 postlint:: buflint bufbreaking
 
+.PHONY: libbuf
+libbuf:
+	go build -buildmode=c-shared -o .build/libbuf/libbuf.so ./cmd/libbuf
+
+.PHONY: bufwasm
+bufwasm:
+	GOOS=js GOARCH=wasm go build -o .build/wasm/buf.wasm ./cmd/buf-wasm
+	@# wasm_exec.js moved from misc/wasm to lib/wasm in newer versions of Go
+	cp "$$(go env GOROOT)/misc/wasm/wasm_exec.js" .build/wasm/wasm_exec.js 2>/dev/null || \
+		cp "$$(go env GOROOT)/lib/wasm/wasm_exec.js" .build/wasm/wasm_exec.js
+
 .PHONY: bufrelease
 bufrelease: all
 	DOCKER_IMAGE=golang:1.13.7-buster bash make/buf/scripts/release.bash